@@ -0,0 +1,172 @@
+package convey
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// MissingKeyError indicates that a convey map does not contain a requested key or path
+type MissingKeyError struct {
+	// Key is the missing key.  For paths, this is the dotted path up to and including the missing element.
+	Key string
+}
+
+func (mke MissingKeyError) Error() string {
+	return fmt.Sprintf("Convey key %s is missing", mke.Key)
+}
+
+func (mke MissingKeyError) Compliance() Compliance {
+	return MissingFields
+}
+
+// TypeError indicates that a convey value is not of the requested type
+type TypeError struct {
+	// Key is the key or dotted path of the value
+	Key string
+
+	// Expected describes the requested type, e.g. "string" or "int"
+	Expected string
+
+	// Actual is the value that was found
+	Actual interface{}
+}
+
+func (te TypeError) Error() string {
+	return fmt.Sprintf("Convey key %s is a %T rather than the expected %s", te.Key, te.Actual, te.Expected)
+}
+
+func (te TypeError) Compliance() Compliance {
+	return Invalid
+}
+
+// value returns the value of a key, or a MissingKeyError if there is no such key
+func (c C) value(key string) (interface{}, error) {
+	v, ok := c.Get(key)
+	if !ok {
+		return nil, MissingKeyError{Key: key}
+	}
+
+	return v, nil
+}
+
+// GetStringE returns the string value of a key.  Unlike GetString, which is lenient in order to satisfy
+// wrpmeta.Source, this method returns a MissingKeyError if the key is absent and a TypeError if the value
+// is not a string.  No conversion of other types is done.
+func (c C) GetStringE(key string) (string, error) {
+	v, err := c.value(key)
+	if err != nil {
+		return "", err
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return "", TypeError{Key: key, Expected: "string", Actual: v}
+	}
+
+	return s, nil
+}
+
+// toInt converts any integral value, including floats with no fractional part as produced by some
+// JSON decoders, into an int
+func toInt(v interface{}) (int, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := rv.Int()
+		return int(i), int64(int(i)) == i
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u := rv.Uint()
+		return int(u), u <= math.MaxInt64 && int(u) >= 0 && uint64(int(u)) == u
+
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		return int(f), f == math.Trunc(f) && float64(int(f)) == f
+	}
+
+	return 0, false
+}
+
+// GetInt returns the integer value of a key.  Any integral number is accepted, but strings are not
+// parsed.  A MissingKeyError is returned if the key is absent, and a TypeError if the value is not an
+// integer that fits in an int.
+func (c C) GetInt(key string) (int, error) {
+	v, err := c.value(key)
+	if err != nil {
+		return 0, err
+	}
+
+	i, ok := toInt(v)
+	if !ok {
+		return 0, TypeError{Key: key, Expected: "int", Actual: v}
+	}
+
+	return i, nil
+}
+
+// GetBool returns the boolean value of a key.  A MissingKeyError is returned if the key is absent,
+// and a TypeError if the value is not a bool.
+func (c C) GetBool(key string) (bool, error) {
+	v, err := c.value(key)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return false, TypeError{Key: key, Expected: "bool", Actual: v}
+	}
+
+	return b, nil
+}
+
+// GetPath returns the value found by descending through nested objects and arrays, one path element
+// at a time.  Elements that address arrays must be indices, e.g. c.GetPath("interfaces", "0", "name").
+// Errors report the dotted path, in the same form as FieldError, up to the element that failed.
+func (c C) GetPath(path ...string) (interface{}, error) {
+	if len(path) == 0 {
+		return c, nil
+	}
+
+	var current interface{} = c
+	for i, element := range path {
+		key := strings.Join(path[:i+1], ".")
+		switch container := current.(type) {
+		case C:
+			v, ok := container[element]
+			if !ok {
+				return nil, MissingKeyError{Key: key}
+			}
+
+			current = v
+
+		case map[string]interface{}:
+			v, ok := container[element]
+			if !ok {
+				return nil, MissingKeyError{Key: key}
+			}
+
+			current = v
+
+		case []interface{}:
+			index, err := strconv.Atoi(element)
+			if err != nil {
+				return nil, TypeError{Key: strings.Join(path[:i], "."), Expected: "object", Actual: current}
+			}
+
+			if index < 0 || index >= len(container) {
+				return nil, MissingKeyError{Key: key}
+			}
+
+			current = container[index]
+
+		default:
+			return nil, TypeError{Key: strings.Join(path[:i], "."), Expected: "object or array", Actual: current}
+		}
+	}
+
+	return current, nil
+}
@@ -0,0 +1,143 @@
+package convey
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testGetStringE(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		c      = C{"name": "value", "number": 12}
+	)
+
+	s, err := c.GetStringE("name")
+	assert.Equal("value", s)
+	assert.NoError(err)
+
+	s, err = c.GetStringE("missing")
+	assert.Empty(s)
+	assert.Equal(MissingKeyError{Key: "missing"}, err)
+	assert.Equal(MissingFields, GetCompliance(err))
+
+	s, err = c.GetStringE("number")
+	assert.Empty(s)
+	assert.Equal(TypeError{Key: "number", Expected: "string", Actual: 12}, err)
+	assert.Equal(Invalid, GetCompliance(err))
+
+	s, err = C(nil).GetStringE("name")
+	assert.Empty(s)
+	assert.Equal(MissingKeyError{Key: "name"}, err)
+}
+
+func testGetInt(t *testing.T) {
+	testData := []struct {
+		value    interface{}
+		expected int
+		valid    bool
+	}{
+		{int64(123), 123, true},
+		{uint64(456), 456, true},
+		{int32(-7), -7, true},
+		{float64(42), 42, true},
+		{float64(4.5), 0, false},
+		{uint64(math.MaxUint64), 0, false},
+		{"123", 0, false},
+		{true, 0, false},
+	}
+
+	for i, record := range testData {
+		t.Logf("%d: %#v", i, record)
+
+		var (
+			assert = assert.New(t)
+			c      = C{"value": record.value}
+		)
+
+		actual, err := c.GetInt("value")
+		assert.Equal(record.expected, actual)
+		if record.valid {
+			assert.NoError(err)
+		} else {
+			assert.Equal(TypeError{Key: "value", Expected: "int", Actual: record.value}, err)
+		}
+	}
+
+	actual, err := C{}.GetInt("value")
+	assert.Zero(t, actual)
+	assert.Equal(t, MissingKeyError{Key: "value"}, err)
+}
+
+func testGetBool(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		c      = C{"flag": true, "text": "true"}
+	)
+
+	b, err := c.GetBool("flag")
+	assert.True(b)
+	assert.NoError(err)
+
+	b, err = c.GetBool("text")
+	assert.False(b)
+	assert.Equal(TypeError{Key: "text", Expected: "bool", Actual: "true"}, err)
+
+	b, err = c.GetBool("missing")
+	assert.False(b)
+	assert.Equal(MissingKeyError{Key: "missing"}, err)
+}
+
+func testGetPath(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		c      = C{
+			"hw-model": "TG1682",
+			"interfaces": []interface{}{
+				C{"name": "erouter0"},
+				map[string]interface{}{"name": "wan0"},
+			},
+		}
+	)
+
+	v, err := c.GetPath()
+	assert.Equal(c, v)
+	assert.NoError(err)
+
+	v, err = c.GetPath("hw-model")
+	assert.Equal("TG1682", v)
+	assert.NoError(err)
+
+	v, err = c.GetPath("interfaces", "0", "name")
+	assert.Equal("erouter0", v)
+	assert.NoError(err)
+
+	v, err = c.GetPath("interfaces", "1", "name")
+	assert.Equal("wan0", v)
+	assert.NoError(err)
+
+	v, err = c.GetPath("interfaces", "2", "name")
+	assert.Nil(v)
+	assert.Equal(MissingKeyError{Key: "interfaces.2"}, err)
+
+	v, err = c.GetPath("interfaces", "0", "mac")
+	assert.Nil(v)
+	assert.Equal(MissingKeyError{Key: "interfaces.0.mac"}, err)
+
+	v, err = c.GetPath("interfaces", "name")
+	assert.Nil(v)
+	assert.Equal(Invalid, GetCompliance(err))
+	assert.Equal("interfaces", err.(TypeError).Key)
+
+	v, err = c.GetPath("hw-model", "name")
+	assert.Nil(v)
+	assert.Equal(TypeError{Key: "hw-model", Expected: "object or array", Actual: "TG1682"}, err)
+}
+
+func TestAccessors(t *testing.T) {
+	t.Run("GetStringE", testGetStringE)
+	t.Run("GetInt", testGetInt)
+	t.Run("GetBool", testGetBool)
+	t.Run("GetPath", testGetPath)
+}
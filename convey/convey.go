@@ -1,7 +1,9 @@
 package convey
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"encoding/base64"
 	"io"
 	"reflect"
@@ -54,40 +56,76 @@ func (c C) Get(key string) (interface{}, bool) {
 // Translator provides translation between the on-the-wire representation of a convey map
 // and its runtime representation.  Instances of Translator are safe for concurrent usage.
 type Translator interface {
-	// ReadFrom extracts base64-encoded JSON from the supplied reader and produces a convey map.
-	// Any error in either base64 decoding or JSON unmarshaling results in an error.
+	// ReadFrom extracts a base64-encoded convey, in any Format, from the supplied reader and produces a convey map.
+	// Any error in base64 decoding, decompression, or unmarshaling results in an error.
 	ReadFrom(io.Reader) (C, error)
 
 	// WriteTo encodes the given convey map into its on-the-wire repesentation, which is base64-encoded
-	// JSON.  Any error in either base64 encoding or JSON marhsaling results in an error.
+	// JSON by default.  Any error in either base64 encoding or JSON marhsaling results in an error.
 	WriteTo(io.Writer, C) error
 }
 
 // translator is the internal Translator implementation
 type translator struct {
-	encoding *base64.Encoding
+	encoding       *base64.Encoding
+	format         Format
+	maxDecodedSize int
 }
 
 // NewTranslator produces a Translator which uses the specified base64 encoding.  If
-// the encoding is nil, base64.StdEncoding is used.
+// the encoding is nil, base64.StdEncoding is used.  The returned Translator writes FormatJSON.
 func NewTranslator(encoding *base64.Encoding) Translator {
-	if encoding == nil {
-		encoding = base64.StdEncoding
-	}
+	return NewFormatTranslator(encoding, FormatJSON)
+}
 
-	return &translator{
-		encoding: encoding,
-	}
+// NewFormatTranslator produces a Translator which writes convey maps in the given format.  Every Translator
+// reads all formats, distinguished by their prefix, so producers can switch formats without coordinating
+// with consumers as long as the consumers have been upgraded to this version.
+func NewFormatTranslator(encoding *base64.Encoding, format Format) Translator {
+	return NewLimitedTranslator(encoding, format, 0)
 }
 
 func (t *translator) ReadFrom(source io.Reader) (C, error) {
+	var (
+		buffered = bufio.NewReader(source)
+		format   = FormatJSON
+	)
+
+	if prefix, _ := buffered.Peek(prefixLength); len(prefix) == prefixLength {
+		if format = formatOf(prefix); format != FormatJSON {
+			buffered.Discard(prefixLength)
+		}
+	}
+
+	var decoded io.Reader = base64.NewDecoder(t.encoding, buffered)
+	if format == FormatGzip {
+		uncompressed, err := gzip.NewReader(decoded)
+		if err != nil {
+			return nil, Error{err, Invalid}
+		}
+
+		defer uncompressed.Close()
+		decoded = uncompressed
+	}
+
+	var limited *limitReader
+	if t.maxDecodedSize > 0 {
+		limited = newLimitReader(decoded, t.maxDecodedSize)
+		decoded = limited
+	}
+
 	decoder := codec.NewDecoder(
-		base64.NewDecoder(t.encoding, source),
-		conveyHandle,
+		decoded,
+		format.handle(),
 	)
 
 	var convey C
 	if err := decoder.Decode(&convey); err != nil {
+		if limited != nil && limited.exceeded {
+			// the codec may wrap or replace the reader's error
+			return nil, limited.err()
+		}
+
 		return nil, Error{err, Invalid}
 	}
 
@@ -95,12 +133,32 @@ func (t *translator) ReadFrom(source io.Reader) (C, error) {
 }
 
 func (t *translator) WriteTo(destination io.Writer, source C) error {
-	encoder := base64.NewEncoder(t.encoding, destination)
+	if _, err := io.WriteString(destination, t.format.prefix()); err != nil {
+		return Error{err, Invalid}
+	}
+
+	var (
+		encoder              = base64.NewEncoder(t.encoding, destination)
+		output     io.Writer = encoder
+		compressor *gzip.Writer
+	)
+
+	if t.format == FormatGzip {
+		compressor = gzip.NewWriter(encoder)
+		output = compressor
+	}
+
 	err := codec.NewEncoder(
-		encoder,
-		conveyHandle,
+		output,
+		t.format.handle(),
 	).Encode(source)
 
+	if compressor != nil {
+		if closeErr := compressor.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
 	encoder.Close()
 	if err != nil {
 		return Error{err, Invalid}
@@ -0,0 +1,29 @@
+package conveyhttp
+
+import (
+	"net/http"
+
+	"github.com/xmidt-org/webpa-common/convey"
+)
+
+// UseConvey produces an Alice-style constructor that extracts the convey map from each request using the given
+// HeaderTranslator and passes it to the delegate via the request Context, where it is available through
+// convey.FromContext.  Since convey data is optional, requests are never rejected.  A request whose convey header
+// is missing or cannot be parsed is passed along unchanged.  If ht is nil, NewHeaderTranslator("", nil) is used.
+func UseConvey(ht HeaderTranslator) func(http.Handler) http.Handler {
+	if ht == nil {
+		ht = NewHeaderTranslator("", nil)
+	}
+
+	return func(delegate http.Handler) http.Handler {
+		return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			// some translators, such as those produced by convey.NewValidatingTranslator,
+			// return both a convey map and an error
+			if c, _ := ht.FromHeader(request.Header); c != nil {
+				request = request.WithContext(convey.NewContext(request.Context(), c))
+			}
+
+			delegate.ServeHTTP(response, request)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package conveyhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/convey"
+)
+
+func testUseConvey(t *testing.T, ht HeaderTranslator, header string, expected convey.C) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		response = httptest.NewRecorder()
+		request  = httptest.NewRequest("GET", "/", nil)
+
+		delegateCalled = false
+		delegate       = http.HandlerFunc(func(_ http.ResponseWriter, request *http.Request) {
+			delegateCalled = true
+			c, ok := convey.FromContext(request.Context())
+			assert.Equal(expected, c)
+			assert.Equal(expected != nil, ok)
+		})
+	)
+
+	if len(header) > 0 {
+		request.Header.Set(DefaultHeaderName, header)
+	}
+
+	decorator := UseConvey(ht)
+	require.NotNil(decorator)
+
+	decorator(delegate).ServeHTTP(response, request)
+	assert.True(delegateCalled)
+	assert.Equal(http.StatusOK, response.Code)
+}
+
+func TestUseConvey(t *testing.T) {
+	value, err := convey.WriteString(convey.NewTranslator(nil), convey.C{"foo": "bar"})
+	require.NoError(t, err)
+
+	for _, ht := range []HeaderTranslator{nil, NewHeaderTranslator("", nil)} {
+		t.Run("Missing", func(t *testing.T) {
+			testUseConvey(t, ht, "", nil)
+		})
+
+		t.Run("Invalid", func(t *testing.T) {
+			testUseConvey(t, ht, "this is not valid", nil)
+		})
+
+		t.Run("Valid", func(t *testing.T) {
+			testUseConvey(t, ht, value, convey.C{"foo": "bar"})
+		})
+	}
+}
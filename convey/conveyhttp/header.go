@@ -3,15 +3,48 @@ package conveyhttp
 import (
 	"errors"
 	"net/http"
+	"strings"
 
+	"github.com/go-kit/kit/metrics"
 	"github.com/xmidt-org/webpa-common/convey"
 )
 
-// DefaultHeaderName is the HTTP header assumed to contain Convey data when no header is supplied
-const DefaultHeaderName = "X-Webpa-Convey"
+const (
+	// DefaultHeaderName is the HTTP header assumed to contain Convey data when no header is supplied
+	DefaultHeaderName = "X-Webpa-Convey"
 
-// ErrMissingHeader indicates that no HTTP header exists which contains convey information
-var ErrMissingHeader = errors.New("No convey header present")
+	// XmidtHeaderName is the newer name of the convey header, which devices migrate to from DefaultHeaderName
+	XmidtHeaderName = "X-Xmidt-Convey"
+
+	// HeaderLabel is the metric label whose value is the name of the header a convey map was extracted from
+	HeaderLabel = "header"
+
+	// HeaderLimit is the name of the limit on the length of a raw convey header value, as reported by
+	// convey.LimitError and the LimitLabel of the Oversized counter
+	HeaderLimit = "header"
+
+	// LimitLabel is the metric label whose value is the name of the size limit that convey data exceeded
+	LimitLabel = "limit"
+
+	// NotAvailableValue is the header value some devices send, instead of omitting the header, when they have
+	// no convey data to report
+	NotAvailableValue = "not-available"
+)
+
+var (
+	// ErrMissingHeader indicates that no HTTP header exists which contains convey information
+	ErrMissingHeader = errors.New("No convey header present")
+
+	// ErrNotAvailable indicates that the convey header contained NotAvailableValue rather than convey data
+	ErrNotAvailable = errors.New("Convey header is not-available")
+)
+
+// IsNotAvailable tests if an error returned by a HeaderTranslator indicates that the convey
+// header was NotAvailableValue
+func IsNotAvailable(err error) bool {
+	ce, ok := err.(convey.Error)
+	return ok && ce.Err == ErrNotAvailable
+}
 
 // HeaderTranslator is an analog to convey.Translator, except that this type works with http.Header.
 type HeaderTranslator interface {
@@ -22,42 +55,122 @@ type HeaderTranslator interface {
 	ToHeader(http.Header, convey.C) error
 }
 
+// HeaderOptions configures a HeaderTranslator which may consult several headers
+type HeaderOptions struct {
+	// HeaderNames are the headers which may contain convey data, in order of preference.  The first header present
+	// in a request is used, which allows devices to migrate from one header to another.  Convey data is always
+	// written to the first header.  If unset, DefaultHeaderName is used.
+	HeaderNames []string `json:"headerNames"`
+
+	// Translator is the convey.Translator used to parse and produce header values.  If unset,
+	// convey.NewTranslator(nil) is used.
+	Translator convey.Translator `json:"-"`
+
+	// HeaderUsed is an optional counter incremented each time convey data is extracted from a header,
+	// labeled with HeaderLabel
+	HeaderUsed metrics.Counter `json:"-"`
+
+	// MaxHeaderLength is the maximum length of a convey header value.  Longer values are rejected with a
+	// convey.LimitError before any decoding takes place.  If unset or nonpositive, values of any length are decoded.
+	// To also limit the decoded size of convey data, use a Translator created with convey.NewLimitedTranslator.
+	MaxHeaderLength int `json:"maxHeaderLength"`
+
+	// Oversized is an optional counter incremented each time convey data exceeds a size limit, labeled with LimitLabel
+	Oversized metrics.Counter `json:"-"`
+}
+
 // headerTranslator is the internal HeaderTranslator implementation
 type headerTranslator struct {
-	headerName string
-	translator convey.Translator
+	headerNames     []string
+	translator      convey.Translator
+	headerUsed      metrics.Counter
+	maxHeaderLength int
+	oversized       metrics.Counter
 }
 
 // NewHeaderTranslator creates a HeaderTranslator that uses a convey.Translator to produce
 // convey maps.
 func NewHeaderTranslator(headerName string, translator convey.Translator) HeaderTranslator {
-	if len(headerName) == 0 {
-		headerName = DefaultHeaderName
+	var headerNames []string
+	if len(headerName) > 0 {
+		headerNames = []string{headerName}
 	}
 
-	if translator == nil {
-		translator = convey.NewTranslator(nil)
+	return NewMultiHeaderTranslator(HeaderOptions{
+		HeaderNames: headerNames,
+		Translator:  translator,
+	})
+}
+
+// NewMultiHeaderTranslator creates a HeaderTranslator that falls back across several headers when extracting
+// convey maps.  This is useful during a migration window, e.g. from DefaultHeaderName to XmidtHeaderName,
+// and the optional HeaderUsed counter tracks how far the migration has progressed.
+func NewMultiHeaderTranslator(o HeaderOptions) HeaderTranslator {
+	ht := &headerTranslator{
+		translator:      o.Translator,
+		headerUsed:      o.HeaderUsed,
+		maxHeaderLength: o.MaxHeaderLength,
+		oversized:       o.Oversized,
+	}
+
+	for _, n := range o.HeaderNames {
+		if len(n) > 0 {
+			ht.headerNames = append(ht.headerNames, n)
+		}
 	}
 
-	return &headerTranslator{
-		headerName: headerName,
-		translator: translator,
+	if len(ht.headerNames) == 0 {
+		ht.headerNames = []string{DefaultHeaderName}
 	}
+
+	if ht.translator == nil {
+		ht.translator = convey.NewTranslator(nil)
+	}
+
+	return ht
 }
 
 func (ht *headerTranslator) FromHeader(h http.Header) (convey.C, error) {
-	v := h.Get(ht.headerName)
-	if len(v) == 0 {
-		return nil, convey.Error{ErrMissingHeader, convey.Missing}
+	for _, n := range ht.headerNames {
+		if v := h.Get(n); len(v) > 0 {
+			if ht.headerUsed != nil {
+				ht.headerUsed.With(HeaderLabel, http.CanonicalHeaderKey(n)).Add(1.0)
+			}
+
+			if strings.EqualFold(strings.TrimSpace(v), NotAvailableValue) {
+				// this is still invalid convey data, but is reported distinctly so that callers can apply a policy
+				return nil, convey.Error{ErrNotAvailable, convey.Invalid}
+			}
+
+			if ht.maxHeaderLength > 0 && len(v) > ht.maxHeaderLength {
+				return nil, ht.limitExceeded(convey.LimitError{Limit: HeaderLimit, Max: ht.maxHeaderLength})
+			}
+
+			c, err := convey.ReadString(ht.translator, v)
+			if le, ok := err.(convey.LimitError); ok {
+				ht.limitExceeded(le)
+			}
+
+			return c, err
+		}
+	}
+
+	return nil, convey.Error{ErrMissingHeader, convey.Missing}
+}
+
+// limitExceeded records that convey data exceeded a size limit, returning the given error
+func (ht *headerTranslator) limitExceeded(le convey.LimitError) error {
+	if ht.oversized != nil {
+		ht.oversized.With(LimitLabel, le.Limit).Add(1.0)
 	}
 
-	return convey.ReadString(ht.translator, v)
+	return le
 }
 
 func (ht *headerTranslator) ToHeader(h http.Header, c convey.C) error {
 	v, err := convey.WriteString(ht.translator, c)
 	if err == nil {
-		h.Set(ht.headerName, v)
+		h.Set(ht.headerNames[0], v)
 	}
 
 	return err
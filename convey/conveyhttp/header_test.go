@@ -3,11 +3,14 @@ package conveyhttp
 import (
 	"encoding/base64"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/xmidt-org/webpa-common/convey"
+	"github.com/xmidt-org/webpa-common/xmetrics"
+	"github.com/xmidt-org/webpa-common/xmetrics/xmetricstest"
 )
 
 func testHeaderTranslatorFromHeader(t *testing.T, actualHeaderName, expectedHeaderName string, actualTranslator, expectedTranslator convey.Translator) {
@@ -124,3 +127,104 @@ func TestHeaderTranslator(t *testing.T) {
 		)
 	})
 }
+
+func TestMultiHeaderTranslator(t *testing.T) {
+	var (
+		assert     = assert.New(t)
+		require    = require.New(t)
+		headerUsed = xmetricstest.NewCounter("headerUsed")
+
+		headerTranslator = NewMultiHeaderTranslator(HeaderOptions{
+			HeaderNames: []string{XmidtHeaderName, "", DefaultHeaderName},
+			HeaderUsed:  headerUsed,
+		})
+	)
+
+	c, err := headerTranslator.FromHeader(http.Header{})
+	assert.Empty(c)
+	assert.Equal(convey.Missing, convey.GetCompliance(err))
+
+	legacy, err := convey.WriteString(convey.NewTranslator(nil), convey.C{"foo": "legacy"})
+	require.NoError(err)
+
+	current, err := convey.WriteString(convey.NewTranslator(nil), convey.C{"foo": "current"})
+	require.NoError(err)
+
+	header := http.Header{}
+	header.Set(DefaultHeaderName, legacy)
+	c, err = headerTranslator.FromHeader(header)
+	assert.Equal(convey.C{"foo": "legacy"}, c)
+	assert.NoError(err)
+
+	header.Set(XmidtHeaderName, current)
+	c, err = headerTranslator.FromHeader(header)
+	assert.Equal(convey.C{"foo": "current"}, c)
+	assert.NoError(err)
+
+	assert.Equal(1.0, headerUsed.With(HeaderLabel, DefaultHeaderName).(xmetrics.Valuer).Value())
+	assert.Equal(1.0, headerUsed.With(HeaderLabel, XmidtHeaderName).(xmetrics.Valuer).Value())
+
+	written := http.Header{}
+	require.NoError(headerTranslator.ToHeader(written, convey.C{"foo": "bar"}))
+	assert.NotEmpty(written.Get(XmidtHeaderName))
+	assert.Empty(written.Get(DefaultHeaderName))
+}
+
+func TestHeaderTranslatorNotAvailable(t *testing.T) {
+	var (
+		assert           = assert.New(t)
+		headerTranslator = NewHeaderTranslator("", nil)
+	)
+
+	for _, v := range []string{NotAvailableValue, "Not-Available", " not-available "} {
+		c, err := headerTranslator.FromHeader(http.Header{DefaultHeaderName: {v}})
+		assert.Nil(c)
+		assert.True(IsNotAvailable(err))
+		assert.Equal(convey.Invalid, convey.GetCompliance(err))
+	}
+
+	_, err := headerTranslator.FromHeader(http.Header{DefaultHeaderName: {"this is not valid"}})
+	assert.False(IsNotAvailable(err))
+
+	_, err = headerTranslator.FromHeader(http.Header{})
+	assert.False(IsNotAvailable(err))
+	assert.False(IsNotAvailable(nil))
+}
+
+func TestHeaderTranslatorLimits(t *testing.T) {
+	var (
+		assert    = assert.New(t)
+		require   = require.New(t)
+		oversized = xmetricstest.NewCounter("oversized")
+
+		headerTranslator = NewMultiHeaderTranslator(HeaderOptions{
+			Translator:      convey.NewLimitedTranslator(nil, convey.FormatJSON, 64),
+			MaxHeaderLength: 128,
+			Oversized:       oversized,
+		})
+	)
+
+	value, err := convey.WriteString(convey.NewTranslator(nil), convey.C{"foo": "bar"})
+	require.NoError(err)
+
+	c, err := headerTranslator.FromHeader(http.Header{DefaultHeaderName: {value}})
+	assert.Equal(convey.C{"foo": "bar"}, c)
+	assert.NoError(err)
+
+	// fits within the header limit, but not the decoded limit
+	value, err = convey.WriteString(convey.NewTranslator(nil), convey.C{"foo": strings.Repeat("x", 64)})
+	require.NoError(err)
+	require.True(len(value) <= 128)
+
+	c, err = headerTranslator.FromHeader(http.Header{DefaultHeaderName: {value}})
+	assert.Nil(c)
+	assert.Equal(convey.LimitError{Limit: convey.DecodedLimit, Max: 64}, err)
+
+	c, err = headerTranslator.FromHeader(http.Header{DefaultHeaderName: {strings.Repeat("A", 129)}})
+	assert.Nil(c)
+	assert.Equal(convey.LimitError{Limit: HeaderLimit, Max: 128}, err)
+	assert.Equal(convey.Invalid, convey.GetCompliance(err))
+
+	assert.Equal(1.0, oversized.With(LimitLabel, convey.DecodedLimit).(xmetrics.Valuer).Value())
+	assert.Equal(1.0, oversized.With(LimitLabel, HeaderLimit).(xmetrics.Valuer).Value())
+}
@@ -0,0 +1,103 @@
+package conveymetric
+
+import (
+	"sync"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/xmidt-org/webpa-common/convey"
+)
+
+// OtherLabelValue is the label value used once a field has reached its cardinality cap
+const OtherLabelValue = "other"
+
+// Field describes a single convey field tracked by its own gauge.  Unlike the pairs given to NewConveyMetric,
+// which all label a single gauge, each field is independent.  The number of series is therefore the sum rather
+// than the product of each field's cardinality.
+type Field struct {
+	// Tag is the key in the convey JSON
+	Tag string
+
+	// Label is the label name on the gauge
+	Label string
+
+	// Gauge is the gauge for this field, which must have Label as one of its label names
+	Gauge metrics.Gauge
+
+	// MaxValues is the maximum number of distinct values tracked for this field.  Values encountered after
+	// the cap has been reached are tracked as OtherLabelValue.  If nonpositive, there is no cap.
+	MaxValues int
+}
+
+// fieldMetric tracks one Field along with the values seen so far
+type fieldMetric struct {
+	Field
+
+	lock sync.Mutex
+	seen map[string]bool
+}
+
+// labelValue determines the label value for the given convey, applying the unknown and cardinality fallbacks
+func (fm *fieldMetric) labelValue(data convey.C) string {
+	value, err := data.GetStringE(fm.Tag)
+	if err != nil {
+		return UnknownLabelValue
+	}
+
+	if fm.MaxValues <= 0 {
+		return value
+	}
+
+	fm.lock.Lock()
+	defer fm.lock.Unlock()
+
+	if !fm.seen[value] {
+		if len(fm.seen) >= fm.MaxValues {
+			return OtherLabelValue
+		}
+
+		fm.seen[value] = true
+	}
+
+	return value
+}
+
+// fieldsMetric is the Interface implementation for multiple, independent fields
+type fieldsMetric struct {
+	fields []*fieldMetric
+}
+
+func (m *fieldsMetric) Update(data convey.C, baseLabelPairs ...string) (Closure, error) {
+	gauges := make([]metrics.Gauge, len(m.fields))
+	for i, fm := range m.fields {
+		labelPairs := append(append(make([]string, 0, len(baseLabelPairs)+2), baseLabelPairs...), fm.Label, fm.labelValue(data))
+		gauges[i] = fm.Gauge.With(labelPairs...)
+		gauges[i].Add(1.0)
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			for _, g := range gauges {
+				g.Add(-1.0)
+			}
+		})
+	}, nil
+}
+
+// NewFieldsMetric produces an Interface which maintains a separate gauge for each field, e.g. one gauge labeled by
+// hw-model and another labeled by last-reboot-reason.  Fields missing from a convey, or whose values are not strings,
+// are tracked as UnknownLabelValue.
+func NewFieldsMetric(fields ...Field) Interface {
+	m := &fieldsMetric{
+		fields: make([]*fieldMetric, len(fields)),
+	}
+
+	for i, f := range fields {
+		m.fields[i] = &fieldMetric{
+			Field: f,
+			seen:  make(map[string]bool),
+		}
+	}
+
+	return m
+}
@@ -0,0 +1,68 @@
+package conveymetric
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/convey"
+	"github.com/xmidt-org/webpa-common/xmetrics"
+	"github.com/xmidt-org/webpa-common/xmetrics/xmetricstest"
+)
+
+func TestFieldsMetric(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		models   = xmetricstest.NewGauge("models")
+		firmware = xmetricstest.NewGauge("firmware")
+		reasons  = xmetricstest.NewGauge("reasons")
+
+		conveyMetric = NewFieldsMetric(
+			Field{Tag: "hw-model", Label: "model", Gauge: models},
+			Field{Tag: "fw-name", Label: "firmware", Gauge: firmware, MaxValues: 2},
+			Field{Tag: "last-reboot-reason", Label: "reason", Gauge: reasons},
+		)
+	)
+
+	require.NotNil(conveyMetric)
+
+	first, err := conveyMetric.Update(convey.C{"hw-model": "TG1682", "fw-name": "fw-1", "last-reboot-reason": "power-on"}, "partner", "comcast")
+	require.NoError(err)
+
+	second, err := conveyMetric.Update(convey.C{"hw-model": "TG1682", "fw-name": "fw-2", "last-reboot-reason": 12})
+	require.NoError(err)
+
+	third, err := conveyMetric.Update(convey.C{"fw-name": "fw-3"})
+	require.NoError(err)
+
+	assert.Equal(1.0, models.With("partner", "comcast", "model", "TG1682").(xmetrics.Valuer).Value())
+	assert.Equal(1.0, models.With("model", "TG1682").(xmetrics.Valuer).Value())
+	assert.Equal(1.0, models.With("model", UnknownLabelValue).(xmetrics.Valuer).Value())
+
+	assert.Equal(1.0, firmware.With("partner", "comcast", "firmware", "fw-1").(xmetrics.Valuer).Value())
+	assert.Equal(1.0, firmware.With("firmware", "fw-2").(xmetrics.Valuer).Value())
+	assert.Equal(1.0, firmware.With("firmware", OtherLabelValue).(xmetrics.Valuer).Value())
+
+	assert.Equal(1.0, reasons.With("partner", "comcast", "reason", "power-on").(xmetrics.Valuer).Value())
+	assert.Equal(2.0, reasons.With("reason", UnknownLabelValue).(xmetrics.Valuer).Value())
+
+	// values already seen are still tracked once the cap is reached
+	fourth, err := conveyMetric.Update(convey.C{"fw-name": "fw-1"})
+	require.NoError(err)
+	assert.Equal(1.0, firmware.With("firmware", "fw-1").(xmetrics.Valuer).Value())
+
+	for _, closure := range []Closure{first, second, third, fourth} {
+		closure()
+	}
+
+	// closures are idempotent
+	first()
+
+	assert.Equal(0.0, models.With("partner", "comcast", "model", "TG1682").(xmetrics.Valuer).Value())
+	assert.Equal(0.0, models.With("model", UnknownLabelValue).(xmetrics.Valuer).Value())
+	assert.Equal(0.0, firmware.With("firmware", OtherLabelValue).(xmetrics.Valuer).Value())
+	assert.Equal(0.0, firmware.With("firmware", "fw-1").(xmetrics.Valuer).Value())
+	assert.Equal(0.0, reasons.With("reason", UnknownLabelValue).(xmetrics.Valuer).Value())
+}
@@ -47,7 +47,7 @@ func (m *cMetric) Update(data convey.C, baseLabelPairs ...string) (Closure, erro
 	labelPairs := baseLabelPairs
 	for _, pair := range m.pairs {
 		labelValue := UnknownLabelValue
-		if item, ok := data[pair.Tag].(string); ok {
+		if item, err := data.GetStringE(pair.Tag); err == nil {
 			labelValue = item
 		}
 		labelPairs = append(labelPairs, pair.Label, labelValue)
@@ -0,0 +1,110 @@
+package convey
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ugorji/go/codec"
+)
+
+// Format is the on-the-wire encoding of a convey map, prior to base64 encoding
+type Format int
+
+const (
+	// FormatJSON is plain JSON, the original convey format.  Values in this format have no prefix.
+	FormatJSON Format = iota
+
+	// FormatGzip is gzip-compressed JSON, which is useful for large convey maps that would otherwise
+	// exceed HTTP header size limits
+	FormatGzip
+
+	// FormatMsgpack is msgpack, which is more compact than JSON without the cost of compression
+	FormatMsgpack
+)
+
+const (
+	// GzipPrefix precedes the base64 text of a FormatGzip convey
+	GzipPrefix = "gz:"
+
+	// MsgpackPrefix precedes the base64 text of a FormatMsgpack convey
+	MsgpackPrefix = "mp:"
+
+	// prefixLength is the length of all format prefixes.  Since ':' is not in any base64 alphabet,
+	// a prefix can never be confused with the start of a FormatJSON value.
+	prefixLength = 3
+)
+
+var (
+	// msgpackHandle is the internal package singleton used to parse msgpack conveys
+	msgpackHandle codec.Handle = &codec.MsgpackHandle{
+		BasicHandle: codec.BasicHandle{
+			DecodeOptions: codec.DecodeOptions{
+				MapType:     reflect.TypeOf((C)(nil)),
+				RawToString: true,
+			},
+		},
+		WriteExt: true,
+	}
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatJSON:
+		return "json"
+	case FormatGzip:
+		return "gzip"
+	case FormatMsgpack:
+		return "msgpack"
+	default:
+		return "*invalid*"
+	}
+}
+
+// prefix returns the text that precedes the base64 encoding of a convey in this format
+func (f Format) prefix() string {
+	switch f {
+	case FormatGzip:
+		return GzipPrefix
+	case FormatMsgpack:
+		return MsgpackPrefix
+	default:
+		return ""
+	}
+}
+
+// handle returns the codec used to marshal and unmarshal a convey in this format
+func (f Format) handle() codec.Handle {
+	if f == FormatMsgpack {
+		return msgpackHandle
+	}
+
+	return conveyHandle
+}
+
+// ParseFormat converts a configuration value into a Format.  The empty string is equivalent to "json".
+// Values are case-insensitive.
+func ParseFormat(v string) (Format, error) {
+	switch strings.ToLower(v) {
+	case "", "json":
+		return FormatJSON, nil
+	case "gzip":
+		return FormatGzip, nil
+	case "msgpack":
+		return FormatMsgpack, nil
+	default:
+		return FormatJSON, fmt.Errorf("Invalid convey format: %s", v)
+	}
+}
+
+// formatOf determines the Format of a convey from its prefix
+func formatOf(prefix []byte) Format {
+	switch string(prefix) {
+	case GzipPrefix:
+		return FormatGzip
+	case MsgpackPrefix:
+		return FormatMsgpack
+	default:
+		return FormatJSON
+	}
+}
@@ -0,0 +1,133 @@
+package convey
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatString(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("json", FormatJSON.String())
+	assert.Equal("gzip", FormatGzip.String())
+	assert.Equal("msgpack", FormatMsgpack.String())
+	assert.Equal("*invalid*", Format(-1).String())
+}
+
+func TestParseFormat(t *testing.T) {
+	testData := []struct {
+		value       string
+		expected    Format
+		expectError bool
+	}{
+		{"", FormatJSON, false},
+		{"json", FormatJSON, false},
+		{"JSON", FormatJSON, false},
+		{"gzip", FormatGzip, false},
+		{"Gzip", FormatGzip, false},
+		{"msgpack", FormatMsgpack, false},
+		{"xml", FormatJSON, true},
+	}
+
+	for _, record := range testData {
+		record := record
+		t.Run(record.value, func(t *testing.T) {
+			assert := assert.New(t)
+			actual, err := ParseFormat(record.value)
+			assert.Equal(record.expected, actual)
+			assert.Equal(record.expectError, err != nil)
+		})
+	}
+}
+
+func testNewFormatTranslatorRoundTrip(t *testing.T, encoding *base64.Encoding, format Format) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		expected = C{
+			"hw-model":     "TG1682",
+			"fw-name":      "TG1682_3.8p1s1_PROD_sey",
+			"webpa-uptime": "123",
+			"interfaces":   []interface{}{"erouter0", "brlan0"},
+			"nested":       C{"enabled": true},
+		}
+
+		writer = NewFormatTranslator(encoding, format)
+		reader = NewTranslator(encoding)
+	)
+
+	value, err := WriteString(writer, expected)
+	require.NoError(err)
+	assert.True(strings.HasPrefix(value, format.prefix()))
+
+	// any translator can read any format
+	actual, err := ReadString(reader, value)
+	require.NoError(err)
+	assert.Equal(expected, actual)
+
+	actual, err = ReadString(writer, value)
+	require.NoError(err)
+	assert.Equal(expected, actual)
+}
+
+func testNewFormatTranslatorCompression(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		large = C{}
+	)
+
+	for i := 0; i < 100; i++ {
+		large[strings.Repeat("x", i+1)] = "a repetitive value that compresses well"
+	}
+
+	plain, err := WriteString(NewTranslator(nil), large)
+	require.NoError(err)
+
+	compressed, err := WriteString(NewFormatTranslator(nil, FormatGzip), large)
+	require.NoError(err)
+
+	assert.True(len(compressed) < len(plain)/4, "compressed=%d plain=%d", len(compressed), len(plain))
+}
+
+func testNewFormatTranslatorInvalid(t *testing.T) {
+	translator := NewTranslator(nil)
+	for _, value := range []string{"gz:", "gz:bm90IGd6aXA=", "mp:", "mp:!!!!", "gz", ""} {
+		value := value
+		t.Run(value, func(t *testing.T) {
+			assert := assert.New(t)
+			c, err := ReadString(translator, value)
+			assert.Nil(c)
+			assert.Equal(Invalid, GetCompliance(err))
+		})
+	}
+}
+
+func TestNewFormatTranslator(t *testing.T) {
+	encodings := map[string]*base64.Encoding{
+		"NilEncoding":    nil,
+		"StdEncoding":    base64.StdEncoding,
+		"RawURLEncoding": base64.RawURLEncoding,
+	}
+
+	for name, encoding := range encodings {
+		encoding := encoding
+		t.Run(name, func(t *testing.T) {
+			for _, format := range []Format{FormatJSON, FormatGzip, FormatMsgpack} {
+				format := format
+				t.Run(format.String(), func(t *testing.T) {
+					testNewFormatTranslatorRoundTrip(t, encoding, format)
+				})
+			}
+		})
+	}
+
+	t.Run("Compression", testNewFormatTranslatorCompression)
+	t.Run("Invalid", testNewFormatTranslatorInvalid)
+}
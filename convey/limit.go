@@ -0,0 +1,88 @@
+package convey
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+const (
+	// DecodedLimit is the name of the limit on the size of a convey payload after base64 decoding
+	// and, for FormatGzip, decompression
+	DecodedLimit = "decoded"
+)
+
+// LimitError indicates that convey data exceeded a configured size limit.  Convey data that is too
+// large is never fully decoded, which protects consumers from maliciously large or highly compressed data.
+type LimitError struct {
+	// Limit is the name of the limit that was exceeded, e.g. DecodedLimit
+	Limit string
+
+	// Max is the configured maximum size, in bytes
+	Max int
+}
+
+func (le LimitError) Error() string {
+	return fmt.Sprintf("Convey %s size exceeds %d bytes", le.Limit, le.Max)
+}
+
+func (le LimitError) Compliance() Compliance {
+	return Invalid
+}
+
+// limitReader is an io.Reader that fails once more than max bytes have been read.  Unlike
+// io.LimitReader, exceeding the limit is distinguishable from the end of the input.
+type limitReader struct {
+	reader    io.Reader
+	max       int
+	remaining int64
+	exceeded  bool
+}
+
+func newLimitReader(reader io.Reader, max int) *limitReader {
+	return &limitReader{
+		reader:    reader,
+		max:       max,
+		remaining: int64(max),
+	}
+}
+
+// err returns the LimitError for this reader
+func (lr *limitReader) err() error {
+	return LimitError{Limit: DecodedLimit, Max: lr.max}
+}
+
+func (lr *limitReader) Read(p []byte) (int, error) {
+	if lr.exceeded {
+		return 0, lr.err()
+	}
+
+	// read at most one byte beyond the limit, so that an oversized source is detected without consuming it
+	if int64(len(p)) > lr.remaining+1 {
+		p = p[:lr.remaining+1]
+	}
+
+	n, err := lr.reader.Read(p)
+	if int64(n) > lr.remaining {
+		lr.exceeded = true
+		return int(lr.remaining), lr.err()
+	}
+
+	lr.remaining -= int64(n)
+	return n, err
+}
+
+// NewLimitedTranslator is like NewFormatTranslator, except that the returned Translator refuses to read convey
+// payloads larger than maxDecodedSize bytes after base64 decoding and decompression.  Such payloads result in a
+// LimitError.  If maxDecodedSize is nonpositive, payloads of any size are read.
+func NewLimitedTranslator(encoding *base64.Encoding, format Format, maxDecodedSize int) Translator {
+	if encoding == nil {
+		encoding = base64.StdEncoding
+	}
+
+	return &translator{
+		encoding:       encoding,
+		format:         format,
+		maxDecodedSize: maxDecodedSize,
+	}
+}
@@ -0,0 +1,64 @@
+package convey
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitError(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		err    = LimitError{Limit: DecodedLimit, Max: 100}
+	)
+
+	assert.Equal("Convey decoded size exceeds 100 bytes", err.Error())
+	assert.Equal(Invalid, GetCompliance(err))
+}
+
+func TestNewLimitedTranslator(t *testing.T) {
+	var (
+		small = C{"hw-model": "TG1682"}
+
+		// a highly compressible payload, which is small when encoded with FormatGzip
+		large = C{"padding": strings.Repeat("a", 1024*1024)}
+	)
+
+	for _, format := range []Format{FormatJSON, FormatGzip, FormatMsgpack} {
+		t.Run(format.String(), func(t *testing.T) {
+			var (
+				assert     = assert.New(t)
+				require    = require.New(t)
+				unlimited  = NewFormatTranslator(nil, format)
+				translator = NewLimitedTranslator(nil, format, 1024)
+			)
+
+			value, err := WriteString(unlimited, small)
+			require.NoError(err)
+
+			c, err := ReadString(translator, value)
+			assert.Equal(small, c)
+			assert.NoError(err)
+
+			value, err = WriteString(unlimited, large)
+			require.NoError(err)
+			if format == FormatGzip {
+				assert.True(len(value) < 1024*1024/100)
+			}
+
+			c, err = ReadString(translator, value)
+			assert.Nil(c)
+			assert.Equal(LimitError{Limit: DecodedLimit, Max: 1024}, err)
+
+			c, err = ReadString(unlimited, value)
+			assert.Equal(large, c)
+			assert.NoError(err)
+
+			c, err = ReadString(NewLimitedTranslator(nil, format, 0), value)
+			assert.Equal(large, c)
+			assert.NoError(err)
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package convey
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// rootField is the name gojsonschema uses for the top level of a document
+const rootField = "(root)"
+
+// FieldError describes a single convey field that failed validation
+type FieldError struct {
+	// Field is the dotted path to the field, e.g. "hw-model" or "interfaces.0.name"
+	Field string
+
+	// Description is the human-readable reason the field failed validation
+	Description string
+}
+
+func (fe FieldError) String() string {
+	return fmt.Sprintf("%s: %s", fe.Field, fe.Description)
+}
+
+// ValidationError indicates that a convey map does not conform to a schema.  The compliance is MissingFields
+// if the only failures are required fields that were absent, and Invalid otherwise.
+type ValidationError struct {
+	Fields []FieldError
+	C      Compliance
+}
+
+func (ve ValidationError) Error() string {
+	failures := make([]string, len(ve.Fields))
+	for i, fe := range ve.Fields {
+		failures[i] = fe.String()
+	}
+
+	return "Convey failed validation: " + strings.Join(failures, "; ")
+}
+
+func (ve ValidationError) Compliance() Compliance {
+	return ve.C
+}
+
+// Validator checks a convey map against a set of rules
+type Validator interface {
+	// Validate returns nil if the convey map is valid.  Otherwise, the returned error
+	// describes each failure and implements Comply.
+	Validate(C) error
+}
+
+// schemaValidator is a Validator backed by a JSON schema
+type schemaValidator struct {
+	schema *gojsonschema.Schema
+}
+
+// fieldPath converts a gojsonschema result into a dotted field path.  For missing fields, gojsonschema
+// reports the enclosing object, so the name of the missing property is appended.
+func fieldPath(re gojsonschema.ResultError) string {
+	field := re.Field()
+	if re.Type() == "required" {
+		if property, ok := re.Details()["property"].(string); ok {
+			if field == rootField {
+				return property
+			}
+
+			return field + "." + property
+		}
+	}
+
+	return field
+}
+
+func (sv *schemaValidator) Validate(c C) error {
+	if c == nil {
+		// an absent convey is validated as an empty object rather than as null
+		c = C{}
+	}
+
+	result, err := sv.schema.Validate(gojsonschema.NewGoLoader(c))
+	if err != nil {
+		return Error{err, Invalid}
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	ve := ValidationError{C: MissingFields}
+	for _, re := range result.Errors() {
+		if re.Type() != "required" {
+			ve.C = Invalid
+		}
+
+		ve.Fields = append(ve.Fields, FieldError{Field: fieldPath(re), Description: re.Description()})
+	}
+
+	return ve
+}
+
+// NewSchemaValidator produces a Validator from a JSON schema document.  An error is returned if
+// the schema is not valid JSON or is not a valid schema.
+func NewSchemaValidator(schema []byte) (Validator, error) {
+	compiled, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(schema))
+	if err != nil {
+		return nil, err
+	}
+
+	return &schemaValidator{schema: compiled}, nil
+}
+
+// validatingTranslator is a Translator decorator that validates each convey map it reads
+type validatingTranslator struct {
+	Translator
+	validator Validator
+}
+
+func (vt *validatingTranslator) ReadFrom(source io.Reader) (C, error) {
+	c, err := vt.Translator.ReadFrom(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, vt.validator.Validate(c)
+}
+
+// NewValidatingTranslator decorates a Translator so that each convey map read is also validated.  When validation
+// fails, ReadFrom returns both the decoded convey map and the validation error.  This allows callers to either reject
+// malformed metadata or simply flag it, e.g. with GetCompliance, while still using the fields that were supplied.
+//
+// Writing is not affected by validation.  If validator is nil, the translator is returned as is.
+func NewValidatingTranslator(t Translator, validator Validator) Translator {
+	if validator == nil {
+		return t
+	}
+
+	return &validatingTranslator{
+		Translator: t,
+		validator:  validator,
+	}
+}
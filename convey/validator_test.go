@@ -0,0 +1,154 @@
+package convey
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchema = `{
+	"type": "object",
+	"required": ["hw-model", "fw-name"],
+	"properties": {
+		"hw-model": {"type": "string", "minLength": 1},
+		"fw-name": {"type": "string"},
+		"boot-time": {"type": "integer", "minimum": 0},
+		"interfaces": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"required": ["name"],
+				"properties": {"name": {"type": "string"}}
+			}
+		}
+	}
+}`
+
+func TestNewSchemaValidator(t *testing.T) {
+	assert := assert.New(t)
+
+	v, err := NewSchemaValidator([]byte(testSchema))
+	assert.NotNil(v)
+	assert.NoError(err)
+
+	v, err = NewSchemaValidator([]byte(`{"type": "object"`))
+	assert.Nil(v)
+	assert.Error(err)
+
+	v, err = NewSchemaValidator([]byte(`{"type": "nosuchtype"}`))
+	assert.Nil(v)
+	assert.Error(err)
+}
+
+func testSchemaValidatorValid(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		v, err = NewSchemaValidator([]byte(testSchema))
+	)
+
+	require.NoError(err)
+	assert.NoError(v.Validate(C{"hw-model": "TG1682", "fw-name": "1.0", "boot-time": 1234567890}))
+	assert.NoError(v.Validate(C{"hw-model": "TG1682", "fw-name": "1.0", "interfaces": []interface{}{C{"name": "erouter0"}}}))
+}
+
+func testSchemaValidatorMissingFields(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		v, err = NewSchemaValidator([]byte(testSchema))
+	)
+
+	require.NoError(err)
+	for _, c := range []C{nil, {}} {
+		err := v.Validate(c)
+		require.Error(err)
+		assert.Equal(MissingFields, GetCompliance(err))
+
+		ve, ok := err.(ValidationError)
+		require.True(ok)
+
+		var fields []string
+		for _, fe := range ve.Fields {
+			fields = append(fields, fe.Field)
+			assert.NotEmpty(fe.Description)
+		}
+
+		assert.ElementsMatch([]string{"hw-model", "fw-name"}, fields)
+		assert.Contains(err.Error(), "hw-model")
+		assert.Contains(err.Error(), "fw-name")
+	}
+
+	err = v.Validate(C{"hw-model": "TG1682", "fw-name": "1.0", "interfaces": []interface{}{C{}}})
+	require.Error(err)
+	assert.Equal(MissingFields, GetCompliance(err))
+	assert.Equal([]FieldError{{Field: "interfaces.0.name", Description: "name is required"}}, err.(ValidationError).Fields)
+}
+
+func testSchemaValidatorInvalid(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		v, err = NewSchemaValidator([]byte(testSchema))
+	)
+
+	require.NoError(err)
+	err = v.Validate(C{"hw-model": "", "boot-time": -1})
+	require.Error(err)
+	assert.Equal(Invalid, GetCompliance(err))
+
+	var fields []string
+	for _, fe := range err.(ValidationError).Fields {
+		fields = append(fields, fe.Field)
+	}
+
+	assert.ElementsMatch([]string{"hw-model", "fw-name", "boot-time"}, fields)
+}
+
+func TestSchemaValidator(t *testing.T) {
+	t.Run("Valid", testSchemaValidatorValid)
+	t.Run("MissingFields", testSchemaValidatorMissingFields)
+	t.Run("Invalid", testSchemaValidatorInvalid)
+}
+
+func TestNewValidatingTranslator(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		translator = NewTranslator(nil)
+		v, err     = NewSchemaValidator([]byte(testSchema))
+	)
+
+	require.NoError(err)
+	assert.Equal(translator, NewValidatingTranslator(translator, nil))
+
+	validating := NewValidatingTranslator(translator, v)
+	require.NotNil(validating)
+
+	valid, err := WriteString(validating, C{"hw-model": "TG1682", "fw-name": "1.0"})
+	require.NoError(err)
+
+	c, err := ReadString(validating, valid)
+	assert.Equal(C{"hw-model": "TG1682", "fw-name": "1.0"}, c)
+	assert.NoError(err)
+
+	// writing is never validated
+	invalid, err := WriteString(validating, C{"hw-model": "TG1682"})
+	require.NoError(err)
+
+	// the convey is still available so that callers can choose to flag rather than reject it
+	c, err = ReadString(validating, invalid)
+	assert.Equal(C{"hw-model": "TG1682"}, c)
+	assert.Equal(MissingFields, GetCompliance(err))
+
+	c, err = ReadString(validating, "this is not base64")
+	assert.Nil(c)
+	assert.Equal(Invalid, GetCompliance(err))
+	assert.False(strings.Contains(err.Error(), "validation"))
+}
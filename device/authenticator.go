@@ -0,0 +1,42 @@
+package device
+
+import (
+	"net/http"
+	"time"
+)
+
+// Session describes the outcome of successfully authenticating a device connection
+type Session struct {
+	// Expires is the time at which the device's credentials expire.  If set, the device is disconnected
+	// with DisconnectAuthExpired at this time, and must reconnect to authenticate again.  The zero value
+	// indicates the session does not expire.
+	Expires time.Time
+}
+
+// Authenticator is consulted during each device connection, after the device ID has been parsed but
+// before the websocket upgrade.  The metadata is the same instance attached to the device, so an Authenticator
+// may annotate it, e.g. with claims or a session ID.
+//
+// A non-nil error rejects the connection.  If the error provides a StatusCode() int method, such as
+// *xhttp.Error does, that status is returned to the device.  Otherwise, the status is http.StatusUnauthorized.
+type Authenticator interface {
+	Authenticate(request *http.Request, id ID, metadata *Metadata) (Session, error)
+}
+
+// AuthenticatorFunc is a function type that implements Authenticator
+type AuthenticatorFunc func(*http.Request, ID, *Metadata) (Session, error)
+
+func (af AuthenticatorFunc) Authenticate(request *http.Request, id ID, metadata *Metadata) (Session, error) {
+	return af(request, id, metadata)
+}
+
+// authenticationStatus returns the HTTP status code for an error returned by an Authenticator
+func authenticationStatus(err error) int {
+	if sc, ok := err.(interface {
+		StatusCode() int
+	}); ok {
+		return sc.StatusCode()
+	}
+
+	return http.StatusUnauthorized
+}
@@ -0,0 +1,176 @@
+package device
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/xhttp"
+	"github.com/xmidt-org/webpa-common/xmetrics/xmetricstest"
+)
+
+func TestAuthenticatorFunc(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		expected = Session{Expires: time.Now()}
+		request  = &http.Request{}
+		metadata = new(Metadata)
+
+		af = AuthenticatorFunc(func(actualRequest *http.Request, actualID ID, actualMetadata *Metadata) (Session, error) {
+			assert.Equal(request, actualRequest)
+			assert.Equal(ID("mac:112233445566"), actualID)
+			assert.Equal(metadata, actualMetadata)
+			return expected, nil
+		})
+	)
+
+	actual, err := af.Authenticate(request, ID("mac:112233445566"), metadata)
+	assert.Equal(expected, actual)
+	assert.NoError(err)
+}
+
+func TestAuthenticationStatus(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(http.StatusUnauthorized, authenticationStatus(errors.New("expected")))
+	assert.Equal(http.StatusPaymentRequired, authenticationStatus(&xhttp.Error{Code: http.StatusPaymentRequired}))
+}
+
+func testManagerAuthenticatorRejected(t *testing.T, session Session, err error, expectedStatus int) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		p       = xmetricstest.NewProvider(nil, Metrics)
+
+		options = &Options{
+			Logger:          logging.NewTestLogger(nil, t),
+			MetricsProvider: p,
+			Authenticator: AuthenticatorFunc(func(*http.Request, ID, *Metadata) (Session, error) {
+				return session, err
+			}),
+		}
+	)
+
+	manager, server, connectURL := startWebsocketServer(options)
+	defer server.Close()
+
+	c, response, dialErr := DefaultDialer().DialDevice(string(testDeviceIDs[0]), connectURL, nil)
+	if c != nil {
+		c.Close()
+	}
+
+	assert.Equal(websocket.ErrBadHandshake, dialErr)
+	require.NotNil(response)
+	assert.Equal(expectedStatus, response.StatusCode)
+	assert.Zero(manager.Len())
+
+	p.Assert(t, AuthenticationCounter, "outcome", "rejected")(xmetricstest.Value(1.0))
+	p.Assert(t, AuthenticationCounter, "outcome", "accepted")(xmetricstest.Value(0.0))
+}
+
+func testManagerAuthenticatorAccepted(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		p       = xmetricstest.NewProvider(nil, Metrics)
+
+		connected = make(chan Interface, 1)
+
+		options = &Options{
+			Logger:          logging.NewTestLogger(nil, t),
+			MetricsProvider: p,
+			Authenticator: AuthenticatorFunc(func(request *http.Request, id ID, metadata *Metadata) (Session, error) {
+				metadata.SetSessionID("authenticated-session")
+				metadata.SetClaims(map[string]interface{}{PartnerIDClaimKey: "comcast"})
+				return Session{}, nil
+			}),
+			Listeners: []Listener{
+				func(event *Event) {
+					if event.Type == Connect {
+						connected <- event.Device
+					}
+				},
+			},
+		}
+	)
+
+	_, server, connectURL := startWebsocketServer(options)
+	defer server.Close()
+
+	c, _, err := DefaultDialer().DialDevice(string(testDeviceIDs[0]), connectURL, nil)
+	require.NoError(err)
+	defer c.Close()
+
+	select {
+	case d := <-connected:
+		assert.Equal("authenticated-session", d.Metadata().SessionID())
+		assert.Equal("comcast", d.Metadata().PartnerIDClaim())
+	case <-time.After(5 * time.Second):
+		require.Fail("The device did not connect")
+	}
+
+	p.Assert(t, AuthenticationCounter, "outcome", "accepted")(xmetricstest.Value(1.0))
+}
+
+func testManagerAuthenticatorSessionExpires(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		disconnected = make(chan CloseReason, 1)
+
+		options = &Options{
+			Logger: logging.NewTestLogger(nil, t),
+			Authenticator: AuthenticatorFunc(func(*http.Request, ID, *Metadata) (Session, error) {
+				return Session{Expires: time.Now().Add(100 * time.Millisecond)}, nil
+			}),
+			Listeners: []Listener{
+				func(event *Event) {
+					if event.Type == Disconnect {
+						disconnected <- event.CloseReason
+					}
+				},
+			},
+		}
+	)
+
+	_, server, connectURL := startWebsocketServer(options)
+	defer server.Close()
+
+	c, _, err := DefaultDialer().DialDevice(string(testDeviceIDs[0]), connectURL, nil)
+	require.NoError(err)
+	defer c.Close()
+
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = c.ReadMessage()
+	assert.True(websocket.IsCloseError(err, websocket.CloseServiceRestart), "expected a service restart close frame, got %s", err)
+
+	select {
+	case reason := <-disconnected:
+		assert.Equal(ErrorSessionExpired, reason.Err)
+		assert.Equal(DisconnectAuthExpired, reason.Reason)
+	case <-time.After(5 * time.Second):
+		require.Fail("The device was not disconnected")
+	}
+}
+
+func TestManagerAuthenticator(t *testing.T) {
+	t.Run("Rejected", func(t *testing.T) {
+		testManagerAuthenticatorRejected(t, Session{}, errors.New("expected"), http.StatusUnauthorized)
+	})
+
+	t.Run("RejectedWithStatus", func(t *testing.T) {
+		testManagerAuthenticatorRejected(t, Session{}, &xhttp.Error{Code: http.StatusForbidden, Text: "expected"}, http.StatusForbidden)
+	})
+
+	t.Run("AlreadyExpired", func(t *testing.T) {
+		testManagerAuthenticatorRejected(t, Session{Expires: time.Now().Add(-time.Minute)}, nil, http.StatusUnauthorized)
+	})
+
+	t.Run("Accepted", testManagerAuthenticatorAccepted)
+	t.Run("SessionExpires", testManagerAuthenticatorSessionExpires)
+}
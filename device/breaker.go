@@ -0,0 +1,162 @@
+package device
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/xmidt-org/wrp-go/v3"
+)
+
+// BreakerState is the state of the error circuit breaker of a device
+type BreakerState string
+
+const (
+	// BreakerClosed is the normal state, in which messages are routed to the device
+	BreakerClosed BreakerState = "closed"
+
+	// BreakerOpen indicates the device has caused too many consecutive errors.  Messages are
+	// not routed to the device until the BreakerCooldown elapses.
+	BreakerOpen BreakerState = "open"
+
+	// BreakerHalfOpen indicates the BreakerCooldown has elapsed.  Messages are routed to the device
+	// again, and the next success closes the breaker while the next error opens it again.
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// breaker is a per-device error circuit breaker.  A nil breaker is disabled, and always allows messages.
+type breaker struct {
+	lock      sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	maxTrips  int
+	now       func() time.Time
+
+	state    BreakerState
+	failures int
+	trips    int
+	openedAt time.Time
+
+	transitions metrics.Counter
+}
+
+// newBreaker creates a closed breaker.  If threshold is nonpositive, breakers are disabled and
+// this function returns nil.
+func newBreaker(threshold int, cooldown time.Duration, maxTrips int, now func() time.Time, transitions metrics.Counter) *breaker {
+	if threshold < 1 {
+		return nil
+	}
+
+	return &breaker{
+		threshold:   threshold,
+		cooldown:    cooldown,
+		maxTrips:    maxTrips,
+		now:         now,
+		state:       BreakerClosed,
+		transitions: transitions,
+	}
+}
+
+// transition moves this breaker into the given state.  The lock must be held.
+func (b *breaker) transition(state BreakerState) {
+	b.state = state
+	b.transitions.With("state", string(state)).Add(1.0)
+}
+
+// current returns the current state of this breaker
+func (b *breaker) current() BreakerState {
+	if b == nil {
+		return BreakerClosed
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.state
+}
+
+// allow tests if a message may be routed to the device.  An open breaker becomes half-open
+// once its cooldown elapses.
+func (b *breaker) allow() bool {
+	if b == nil {
+		return true
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.state == BreakerOpen {
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+
+		b.transition(BreakerHalfOpen)
+	}
+
+	return true
+}
+
+// success records a successful exchange with the device, which closes a half-open breaker
+func (b *breaker) success() {
+	if b == nil {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.failures = 0
+	if b.state == BreakerHalfOpen {
+		b.trips = 0
+		b.transition(BreakerClosed)
+	}
+}
+
+// failure records an error caused by the device, opening this breaker if the threshold of consecutive
+// errors is reached or if the breaker is half-open.  This method returns true if the breaker has opened
+// BreakerMaxTrips times without an intervening success, in which case the device should be disconnected.
+func (b *breaker) failure() bool {
+	if b == nil {
+		return false
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.failures++
+	switch {
+	case b.state == BreakerOpen:
+		return false
+
+	case b.state == BreakerHalfOpen, b.failures >= b.threshold:
+		b.failures = 0
+		b.trips++
+		b.openedAt = b.now()
+		b.transition(BreakerOpen)
+		return b.trips >= b.maxTrips
+	}
+
+	return false
+}
+
+// breakerOpenResponse answers a request routed to a device whose breaker is open.  Transactions are answered
+// with a WRP message having a 503 status, so that the caller receives a WRP error.  Other requests fail with
+// ErrorDeviceBreakerOpen.
+func breakerOpenResponse(d Interface, request *Request) (*Response, error) {
+	message, ok := request.Message.(*wrp.Message)
+	if !ok || !message.IsTransactionPart() {
+		return nil, ErrorDeviceBreakerOpen
+	}
+
+	response := &Response{
+		Device:  d,
+		Message: errorResponse(message, http.StatusServiceUnavailable, ErrorDeviceBreakerOpen),
+		Format:  wrp.Msgpack,
+	}
+
+	if err := wrp.NewEncoderBytes(&response.Contents, wrp.Msgpack).Encode(response.Message); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
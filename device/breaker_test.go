@@ -0,0 +1,169 @@
+package device
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/xmetrics/xmetricstest"
+	"github.com/xmidt-org/wrp-go/v3"
+)
+
+func testBreakerDisabled(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		b      = newBreaker(0, time.Minute, 3, time.Now, nil)
+	)
+
+	assert.Nil(b)
+	assert.True(b.allow())
+	assert.False(b.failure())
+	b.success()
+	assert.Equal(BreakerClosed, b.current())
+}
+
+func testBreakerTransitions(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		p      = xmetricstest.NewProvider(nil, Metrics)
+		now    = time.Now()
+		b      = newBreaker(2, time.Minute, 3, func() time.Time { return now }, NewMeasures(p).Breaker)
+	)
+
+	assert.Equal(BreakerClosed, b.current())
+	assert.False(b.failure())
+	b.success()
+	assert.False(b.failure())
+	assert.Equal(BreakerClosed, b.current(), "a success should reset the consecutive failures")
+
+	assert.False(b.failure())
+	assert.Equal(BreakerOpen, b.current())
+	assert.False(b.allow())
+	p.Assert(t, BreakerTransitionCounter, "state", string(BreakerOpen))(xmetricstest.Value(1.0))
+
+	now = now.Add(time.Minute)
+	assert.True(b.allow())
+	assert.Equal(BreakerHalfOpen, b.current())
+	p.Assert(t, BreakerTransitionCounter, "state", string(BreakerHalfOpen))(xmetricstest.Value(1.0))
+
+	b.success()
+	assert.Equal(BreakerClosed, b.current())
+	p.Assert(t, BreakerTransitionCounter, "state", string(BreakerClosed))(xmetricstest.Value(1.0))
+}
+
+func testBreakerMaxTrips(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		now    = time.Now()
+		b      = newBreaker(1, time.Minute, 2, func() time.Time { return now }, NewMeasures(xmetricstest.NewProvider(nil, Metrics)).Breaker)
+	)
+
+	assert.False(b.failure())
+	assert.Equal(BreakerOpen, b.current())
+	assert.False(b.failure(), "failures while open should not trip the breaker again")
+
+	now = now.Add(time.Minute)
+	assert.True(b.allow())
+	assert.True(b.failure(), "a failure while half-open should trip the breaker")
+	assert.Equal(BreakerOpen, b.current())
+}
+
+func TestBreaker(t *testing.T) {
+	t.Run("Disabled", testBreakerDisabled)
+	t.Run("Transitions", testBreakerTransitions)
+	t.Run("MaxTrips", testBreakerMaxTrips)
+}
+
+func testDeviceBreakerFailure(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		d      = newDevice(deviceOptions{ID: "mac:112233445566", Logger: logging.NewTestLogger(nil, t)})
+	)
+
+	d.breaker = newBreaker(1, time.Minute, 1, time.Now, NewMeasures(xmetricstest.NewProvider(nil, Metrics)).Breaker)
+	d.breakerFailure(errors.New("expected"))
+	assert.True(d.Closed())
+	assert.Equal(ErrorDeviceBreakerOpen, d.CloseReason().Err)
+	assert.Equal(DisconnectCircuitBreaker, d.CloseReason().Reason)
+}
+
+func TestDeviceBreakerFailure(t *testing.T) {
+	t.Run("Disconnect", testDeviceBreakerFailure)
+}
+
+func testManagerRouteBreakerOpenEvent(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		m       = NewManager(&Options{
+			Logger:           logging.NewTestLogger(nil, t),
+			BreakerThreshold: 1,
+		}).(*manager)
+
+		d = newDevice(deviceOptions{ID: ID("mac:112233445566"), Logger: m.logger})
+	)
+
+	d.breaker = newBreaker(m.breakerThreshold, m.breakerCooldown, m.breakerMaxTrips, m.now, m.measures.Breaker)
+	require.NoError(m.devices.add(d))
+	d.breaker.failure()
+
+	response, err := m.Route(&Request{
+		Message: &wrp.Message{
+			Type:        wrp.SimpleEventMessageType,
+			Destination: "mac:112233445566",
+		},
+	})
+
+	assert.Nil(response)
+	assert.Equal(ErrorDeviceBreakerOpen, err)
+}
+
+func testManagerRouteBreakerOpenTransaction(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		m       = NewManager(&Options{
+			Logger:           logging.NewTestLogger(nil, t),
+			BreakerThreshold: 1,
+		}).(*manager)
+
+		d = newDevice(deviceOptions{ID: ID("mac:112233445566"), Logger: m.logger})
+	)
+
+	d.breaker = newBreaker(m.breakerThreshold, m.breakerCooldown, m.breakerMaxTrips, m.now, m.measures.Breaker)
+	require.NoError(m.devices.add(d))
+	d.breaker.failure()
+
+	response, err := m.Route(&Request{
+		Message: &wrp.Message{
+			Type:            wrp.SimpleRequestResponseMessageType,
+			Source:          "dns:talaria",
+			Destination:     "mac:112233445566",
+			TransactionUUID: "test-transaction",
+		},
+	})
+
+	require.NoError(err)
+	require.NotNil(response)
+	assert.Equal(wrp.Msgpack, response.Format)
+	assert.NotEmpty(response.Contents)
+
+	message := response.Message
+	require.NotNil(message)
+	require.NotNil(message.Status)
+	assert.Equal(int64(http.StatusServiceUnavailable), *message.Status)
+	assert.Equal("test-transaction", message.TransactionUUID)
+
+	var decoded wrp.Message
+	require.NoError(wrp.NewDecoderBytes(response.Contents, wrp.Msgpack).Decode(&decoded))
+	assert.Equal(*message, decoded)
+}
+
+func TestManagerRouteBreakerOpen(t *testing.T) {
+	t.Run("Event", testManagerRouteBreakerOpenEvent)
+	t.Run("Transaction", testManagerRouteBreakerOpenTransaction)
+}
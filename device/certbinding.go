@@ -0,0 +1,65 @@
+package device
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+)
+
+// Reasons reported by the CertMismatchCounter
+const (
+	certificateMissingReason  = "missing_certificate"
+	certificateMismatchReason = "id_mismatch"
+)
+
+// certificateID parses a single certificate name as a device identifier.  Device certificates commonly carry
+// a bare MAC address, e.g. "112233445566" or "11:22:33:44:55:66", rather than a full device name, so names
+// without a recognized scheme prefix are also tried as MAC addresses.
+func certificateID(name string) (ID, bool) {
+	if id, err := ParseID(name); err == nil {
+		return id, true
+	}
+
+	if mac, err := normalizeMAC(name); err == nil {
+		return ID(macPrefix + ":" + mac), true
+	}
+
+	return invalidID, false
+}
+
+// certificateIDs returns the device identifiers present in a client certificate.  The subject common name
+// is examined along with the DNS, email, and URI subject alternative names.  Names which do not parse
+// as device identifiers or bare MAC addresses are ignored.
+func certificateIDs(cert *x509.Certificate) []ID {
+	names := make([]string, 0, 1+len(cert.DNSNames)+len(cert.EmailAddresses)+len(cert.URIs))
+	names = append(names, cert.Subject.CommonName)
+	names = append(names, cert.DNSNames...)
+	names = append(names, cert.EmailAddresses...)
+	for _, u := range cert.URIs {
+		names = append(names, u.String())
+	}
+
+	var ids []ID
+	for _, name := range names {
+		if id, ok := certificateID(name); ok {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// verifyCertificateBinding checks that the leaf client certificate of a TLS connection identifies
+// the given device.  The returned string is the metric reason for any failure.
+func verifyCertificateBinding(id ID, state *tls.ConnectionState) (string, error) {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return certificateMissingReason, ErrorMissingClientCertificate
+	}
+
+	for _, certID := range certificateIDs(state.PeerCertificates[0]) {
+		if certID == id {
+			return "", nil
+		}
+	}
+
+	return certificateMismatchReason, ErrorCertificateMismatch
+}
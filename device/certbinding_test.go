@@ -0,0 +1,83 @@
+package device
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCertificateIDs(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		cert   = &x509.Certificate{
+			Subject:        pkix.Name{CommonName: "mac:112233445566"},
+			DNSNames:       []string{"not a device", "serial:1234"},
+			EmailAddresses: []string{"uuid:abcd"},
+			URIs:           []*url.URL{{Scheme: "dns", Opaque: "example.com"}},
+		}
+	)
+
+	assert.Equal(
+		[]ID{ID("mac:112233445566"), ID("serial:1234"), ID("uuid:abcd"), ID("dns:example.com")},
+		certificateIDs(cert),
+	)
+
+	assert.Empty(certificateIDs(new(x509.Certificate)))
+
+	// bare MAC addresses, without a scheme prefix, are accepted
+	assert.Equal(
+		[]ID{ID("mac:112233445566"), ID("mac:aabbccddeeff"), ID("mac:0011223344556677")},
+		certificateIDs(&x509.Certificate{
+			Subject:  pkix.Name{CommonName: "112233445566"},
+			DNSNames: []string{"AA:BB:CC:DD:EE:FF", "00-11-22-33-44-55-66-77", "1122334455"},
+		}),
+	)
+}
+
+func TestVerifyCertificateBinding(t *testing.T) {
+	var (
+		id    = ID("mac:112233445566")
+		state = func(names ...string) *tls.ConnectionState {
+			return &tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{
+					{Subject: pkix.Name{CommonName: "some.host.com"}, DNSNames: names},
+				},
+			}
+		}
+
+		testData = []struct {
+			state          *tls.ConnectionState
+			expectedReason string
+			expectedError  error
+		}{
+			{nil, certificateMissingReason, ErrorMissingClientCertificate},
+			{new(tls.ConnectionState), certificateMissingReason, ErrorMissingClientCertificate},
+			{state(), certificateMismatchReason, ErrorCertificateMismatch},
+			{state("mac:665544332211"), certificateMismatchReason, ErrorCertificateMismatch},
+			{state("mac:11-22-33-44-55-66"), "", nil},
+			{state("serial:1234", "MAC:112233445566"), "", nil},
+			{state("665544332211"), certificateMismatchReason, ErrorCertificateMismatch},
+			{state("112233445566"), "", nil},
+			{state("11:22:33:44:55:66"), "", nil},
+		}
+	)
+
+	for _, record := range testData {
+		reason, err := verifyCertificateBinding(id, record.state)
+		assert.Equal(t, record.expectedReason, reason)
+		assert.Equal(t, record.expectedError, err)
+	}
+
+	for _, commonName := range []string{"112233445566", "11:22:33:44:55:66"} {
+		reason, err := verifyCertificateBinding(id, &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: commonName}}},
+		})
+
+		assert.Empty(t, reason)
+		assert.NoError(t, err)
+	}
+}
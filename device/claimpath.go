@@ -0,0 +1,122 @@
+package device
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrorInvalidClaimPath indicates that a claim path used in a metadata query was malformed
+var ErrorInvalidClaimPath = errors.New("Invalid claim path")
+
+// claimPathSegment is a single dotted segment of a claim path, e.g. "capabilities[*]".  An index of -1
+// denotes the wildcard.
+type claimPathSegment struct {
+	name    string
+	indices []int
+}
+
+var claimPathSegmentPattern = regexp.MustCompile(`^([^\[\]]+)((?:\[(?:\*|[0-9]+)\])*)$`)
+
+// parseClaimPath parses a claim path of the form "a.b[0].c[*]".  Each dotted segment names a key in a
+// JSON object, optionally followed by array indices or the wildcard "[*]", which matches every element.
+func parseClaimPath(path string) ([]claimPathSegment, error) {
+	if len(path) == 0 {
+		return nil, ErrorInvalidClaimPath
+	}
+
+	var segments []claimPathSegment
+	for _, s := range strings.Split(path, ".") {
+		match := claimPathSegmentPattern.FindStringSubmatch(s)
+		if match == nil {
+			return nil, ErrorInvalidClaimPath
+		}
+
+		segment := claimPathSegment{name: match[1]}
+		if len(match[2]) > 0 {
+			for _, index := range strings.Split(match[2][1:len(match[2])-1], "][") {
+				if index == "*" {
+					segment.indices = append(segment.indices, -1)
+					continue
+				}
+
+				i, err := strconv.Atoi(index)
+				if err != nil {
+					return nil, ErrorInvalidClaimPath
+				}
+
+				segment.indices = append(segment.indices, i)
+			}
+		}
+
+		segments = append(segments, segment)
+	}
+
+	return segments, nil
+}
+
+// claimValues resolves a claim path against a set of claims, returning every value the path refers to.
+// A key that exists verbatim in the claims, dots and brackets included, takes precedence over path
+// resolution.  A malformed path or one that refers to nothing yields an empty result.
+func claimValues(claims map[string]interface{}, path string) []interface{} {
+	if v, ok := claims[path]; ok {
+		return []interface{}{v}
+	}
+
+	segments, err := parseClaimPath(path)
+	if err != nil {
+		return nil
+	}
+
+	current := []interface{}{claims}
+	for _, segment := range segments {
+		var next []interface{}
+		for _, v := range current {
+			if object, ok := v.(map[string]interface{}); ok {
+				if child, ok := object[segment.name]; ok {
+					next = append(next, child)
+				}
+			}
+		}
+
+		for _, index := range segment.indices {
+			var elements []interface{}
+			for _, v := range next {
+				elements = append(elements, claimElements(v, index)...)
+			}
+
+			next = elements
+		}
+
+		current = next
+	}
+
+	return current
+}
+
+// claimElements returns the element at index of an array claim, or all elements if index is negative
+func claimElements(v interface{}, index int) []interface{} {
+	var array []interface{}
+	switch t := v.(type) {
+	case []interface{}:
+		array = t
+	case []string:
+		array = make([]interface{}, len(t))
+		for i, s := range t {
+			array[i] = s
+		}
+
+	default:
+		return nil
+	}
+
+	switch {
+	case index < 0:
+		return array
+	case index < len(array):
+		return array[index : index+1]
+	default:
+		return nil
+	}
+}
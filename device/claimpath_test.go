@@ -0,0 +1,70 @@
+package device
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseClaimPath(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		var (
+			assert  = assert.New(t)
+			require = require.New(t)
+		)
+
+		segments, err := parseClaimPath("a.b[0].c[*][2]")
+		require.NoError(err)
+		assert.Equal(
+			[]claimPathSegment{
+				{name: "a"},
+				{name: "b", indices: []int{0}},
+				{name: "c", indices: []int{-1, 2}},
+			},
+			segments,
+		)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, path := range []string{"", ".", "a.", "a[", "a[]", "a[x]", "[0]", "a]b", "a[0]b"} {
+			_, err := parseClaimPath(path)
+			assert.Equal(t, ErrorInvalidClaimPath, err, path)
+		}
+	})
+}
+
+func TestClaimValues(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		claims = map[string]interface{}{
+			"trust":        1000,
+			"dotted.claim": "verbatim",
+			"capabilities": []interface{}{"x1", "xb3"},
+			"tags":         []string{"beta"},
+			"device": map[string]interface{}{
+				"model": "TG1682",
+				"ports": []interface{}{
+					map[string]interface{}{"name": "eth0"},
+					map[string]interface{}{"name": "wlan0"},
+				},
+			},
+		}
+	)
+
+	assert.Equal([]interface{}{1000}, claimValues(claims, "trust"))
+	assert.Equal([]interface{}{"verbatim"}, claimValues(claims, "dotted.claim"))
+	assert.Equal([]interface{}{"x1", "xb3"}, claimValues(claims, "capabilities[*]"))
+	assert.Equal([]interface{}{"xb3"}, claimValues(claims, "capabilities[1]"))
+	assert.Equal([]interface{}{"beta"}, claimValues(claims, "tags[*]"))
+	assert.Equal([]interface{}{"TG1682"}, claimValues(claims, "device.model"))
+	assert.Equal([]interface{}{"eth0", "wlan0"}, claimValues(claims, "device.ports[*].name"))
+	assert.Equal([]interface{}{"wlan0"}, claimValues(claims, "device.ports[1].name"))
+
+	assert.Empty(claimValues(claims, "capabilities[2]"))
+	assert.Empty(claimValues(claims, "trust[*]"))
+	assert.Empty(claimValues(claims, "trust.nested"))
+	assert.Empty(claimValues(claims, "missing"))
+	assert.Empty(claimValues(claims, "a[x]"))
+	assert.Empty(claimValues(nil, "trust"))
+}
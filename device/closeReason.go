@@ -1,13 +1,133 @@
 package device
 
+import "github.com/gorilla/websocket"
+
+// DisconnectReason is the enumerated cause of a device disconnection.  It is carried by each CloseReason
+// and is the reason label of the DisconnectCounter.
+type DisconnectReason string
+
+const (
+	// DisconnectUnknown is used when no DisconnectReason was supplied
+	DisconnectUnknown DisconnectReason = "unknown"
+
+	// DisconnectIdle indicates the device sent nothing, not even a pong, within the idle period
+	DisconnectIdle DisconnectReason = "idle"
+
+	// DisconnectMissedPongs indicates the device did not answer MaxMissedPongs consecutive pings
+	DisconnectMissedPongs DisconnectReason = "missed-pongs"
+
+	// DisconnectCircuitBreaker indicates the device's error circuit breaker opened BreakerMaxTrips times
+	// without an intervening success
+	DisconnectCircuitBreaker DisconnectReason = "circuit-breaker"
+
+	// DisconnectApplicationIdle indicates the device sent no WRP messages within the ApplicationIdlePeriod,
+	// even though its connection may still have been answering pings
+	DisconnectApplicationIdle DisconnectReason = "application-idle"
+
+	// DisconnectDeviceClosed indicates the device closed its websocket
+	DisconnectDeviceClosed DisconnectReason = "device-closed"
+
+	// DisconnectReadError indicates an error reading from the device's websocket
+	DisconnectReadError DisconnectReason = "read-error"
+
+	// DisconnectWriteTimeout indicates a write to the device's websocket timed out
+	DisconnectWriteTimeout DisconnectReason = "write-timeout"
+
+	// DisconnectWriteError indicates an error, other than a timeout, writing to the device's websocket
+	DisconnectWriteError DisconnectReason = "write-error"
+
+	// DisconnectDuplicate indicates the device was replaced by a newer connection with the same ID
+	DisconnectDuplicate DisconnectReason = "duplicate"
+
+	// DisconnectDuplicateRejected indicates a new connection was refused because the device was already connected
+	DisconnectDuplicateRejected DisconnectReason = "duplicate-rejected"
+
+	// DisconnectDeviceLimit indicates the Manager's MaxDevices was reached
+	DisconnectDeviceLimit DisconnectReason = "device-limit-reached"
+
+	// DisconnectQueueFull indicates the device's outbound queue was full under the QueueDisconnect policy
+	DisconnectQueueFull DisconnectReason = "queue-full"
+
+	// DisconnectRateLimited indicates the device exceeded its outbound rate limit
+	DisconnectRateLimited DisconnectReason = "rate-limited"
+
+	// DisconnectInvalidMessage indicates the device sent a message that failed validation
+	DisconnectInvalidMessage DisconnectReason = "invalid-message"
+
+	// DisconnectFirstMessageTimeout indicates the device sent nothing within the FirstMessageTimeout
+	DisconnectFirstMessageTimeout DisconnectReason = "first-message-timeout"
+
+	// DisconnectMaxConnectionAge indicates the connection reached the MaxConnectionAge, and the device
+	// must reconnect and present its credentials again
+	DisconnectMaxConnectionAge DisconnectReason = "max-connection-age"
+
+	// DisconnectAuthExpired indicates the device's credentials expired or were revoked
+	DisconnectAuthExpired DisconnectReason = "auth-expired"
+
+	// DisconnectRehash indicates the device hashes to a different instance, e.g. after a service discovery change
+	DisconnectRehash DisconnectReason = "rehash"
+
+	// DisconnectDrain indicates the device was disconnected by a drain job
+	DisconnectDrain DisconnectReason = "drain"
+
+	// DisconnectServerShutdown indicates the Manager is shutting down
+	DisconnectServerShutdown DisconnectReason = "server-shutdown"
+
+	// DisconnectRequested indicates an explicit request, e.g. through an API, to disconnect the device
+	DisconnectRequested DisconnectReason = "requested"
+)
+
 // CloseReason exposes metadata around why a particular device was closed
 type CloseReason struct {
 	// Err is the optional field that specifies the underlying error that occurred, such as
 	// an I/O error.  If nil, the close reason is assumed to be due to application logic, e.g. a rehash
 	Err error
 
-	// Text is the required field indicating a JSON-friendly value describing the reason for closure.
+	// Reason is the enumerated cause of the closure.  If unset, DisconnectUnknown is used.
+	Reason DisconnectReason
+
+	// Text is a JSON-friendly value describing the closure in more detail than Reason.  If unset,
+	// the string value of Reason is used.
 	Text string
+
+	// Code is the optional websocket close status code, e.g. websocket.CloseServiceRestart, sent to the
+	// device in a close frame along with Text.  If zero, the connection is closed without a close frame.
+	Code int
+}
+
+// normalize fills in the defaults for Reason and Text
+func (c CloseReason) normalize() CloseReason {
+	if len(c.Reason) == 0 {
+		c.Reason = DisconnectUnknown
+	}
+
+	if len(c.Text) == 0 {
+		c.Text = string(c.Reason)
+	}
+
+	return c
+}
+
+// readCloseReason classifies the error which stopped a device's read pump
+func readCloseReason(err error) CloseReason {
+	if isTimeout(err) {
+		return CloseReason{Err: err, Reason: DisconnectIdle}
+	}
+
+	if _, ok := err.(*websocket.CloseError); ok {
+		return CloseReason{Err: err, Reason: DisconnectDeviceClosed}
+	}
+
+	return CloseReason{Err: err, Reason: DisconnectReadError}
+}
+
+// writeCloseReason classifies the error which stopped a device's write pump
+func writeCloseReason(err error) CloseReason {
+	if isTimeout(err) {
+		return CloseReason{Err: err, Reason: DisconnectWriteTimeout}
+	}
+
+	return CloseReason{Err: err, Reason: DisconnectWriteError}
 }
 
 func (c CloseReason) String() string {
@@ -0,0 +1,61 @@
+package device
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloseReasonNormalize(t *testing.T) {
+	testData := []struct {
+		reason   CloseReason
+		expected CloseReason
+	}{
+		{
+			CloseReason{},
+			CloseReason{Reason: DisconnectUnknown, Text: "unknown"},
+		},
+		{
+			CloseReason{Reason: DisconnectDrain},
+			CloseReason{Reason: DisconnectDrain, Text: "drain"},
+		},
+		{
+			CloseReason{Reason: DisconnectRehash, Text: "rehash-other-instance", Code: websocket.CloseServiceRestart},
+			CloseReason{Reason: DisconnectRehash, Text: "rehash-other-instance", Code: websocket.CloseServiceRestart},
+		},
+		{
+			CloseReason{Text: "custom"},
+			CloseReason{Reason: DisconnectUnknown, Text: "custom"},
+		},
+	}
+
+	for _, record := range testData {
+		t.Run(record.reason.String(), func(t *testing.T) {
+			assert.Equal(t, record.expected, record.reason.normalize())
+		})
+	}
+}
+
+func TestReadCloseReason(t *testing.T) {
+	var (
+		assert     = assert.New(t)
+		closeError = &websocket.CloseError{Code: websocket.CloseNormalClosure}
+		otherError = errors.New("expected")
+	)
+
+	assert.Equal(CloseReason{Err: testTimeoutError{}, Reason: DisconnectIdle}, readCloseReason(testTimeoutError{}))
+	assert.Equal(CloseReason{Err: closeError, Reason: DisconnectDeviceClosed}, readCloseReason(closeError))
+	assert.Equal(CloseReason{Err: otherError, Reason: DisconnectReadError}, readCloseReason(otherError))
+}
+
+func TestWriteCloseReason(t *testing.T) {
+	var (
+		assert     = assert.New(t)
+		otherError = errors.New("expected")
+	)
+
+	assert.Equal(CloseReason{Err: testTimeoutError{}, Reason: DisconnectWriteTimeout}, writeCloseReason(testTimeoutError{}))
+	assert.Equal(CloseReason{Err: otherError, Reason: DisconnectWriteError}, writeCloseReason(otherError))
+}
@@ -0,0 +1,87 @@
+package device
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// websocketExtensionsHeader is the handshake header clients use to offer websocket extensions
+	websocketExtensionsHeader = "Sec-Websocket-Extensions"
+
+	// permessageDeflate is the RFC 7692 extension token
+	permessageDeflate = "permessage-deflate"
+)
+
+var errHijackNotSupported = errors.New("The response does not implement http.Hijacker")
+
+// compressedConn is a net.Conn which reports the bytes written to it.  It sits beneath gorilla's framing and
+// compression, so it sees outbound data exactly as it appears on the wire.
+type compressedConn struct {
+	net.Conn
+
+	// sent receives the count of each write.  It is nil until the websocket handshake completes, so that
+	// the handshake response is not counted.
+	sent func(int)
+}
+
+func (cc *compressedConn) Write(p []byte) (int, error) {
+	n, err := cc.Conn.Write(p)
+	if cc.sent != nil {
+		cc.sent(n)
+	}
+
+	return n, err
+}
+
+// compressedResponseWriter decorates the http.ResponseWriter passed to the websocket upgrader, so that
+// the connection gorilla hijacks is a compressedConn
+type compressedResponseWriter struct {
+	http.ResponseWriter
+	conn *compressedConn
+}
+
+func (crw *compressedResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := crw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errHijackNotSupported
+	}
+
+	c, rw, err := h.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	crw.conn = &compressedConn{Conn: c}
+	return crw.conn, rw, nil
+}
+
+// compressionOffered tests if the given handshake header offers the permessage-deflate extension.
+func compressionOffered(h http.Header) bool {
+	for _, value := range h[http.CanonicalHeaderKey(websocketExtensionsHeader)] {
+		for _, extension := range strings.Split(value, ",") {
+			token := extension
+			if i := strings.IndexByte(extension, ';'); i >= 0 {
+				token = extension[:i]
+			}
+
+			if strings.EqualFold(strings.TrimSpace(token), permessageDeflate) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// compressionNegotiated determines whether the given upgrader will negotiate permessage-deflate
+// with a client that sent the given handshake header.  This mirrors the decision gorilla makes
+// internally, which is not otherwise exposed on a websocket.Conn.
+func compressionNegotiated(u *websocket.Upgrader, h http.Header) bool {
+	return u.EnableCompression && compressionOffered(h)
+}
@@ -0,0 +1,77 @@
+package device
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressionOffered(t *testing.T) {
+	testData := []struct {
+		header   http.Header
+		expected bool
+	}{
+		{nil, false},
+		{http.Header{}, false},
+		{http.Header{"Sec-Websocket-Extensions": {"x-webkit-deflate-frame"}}, false},
+		{http.Header{"Sec-Websocket-Extensions": {"permessage-deflate"}}, true},
+		{http.Header{"Sec-Websocket-Extensions": {"permessage-deflate; server_no_context_takeover; client_no_context_takeover"}}, true},
+		{http.Header{"Sec-Websocket-Extensions": {"foo, Permessage-Deflate ;client_max_window_bits"}}, true},
+		{http.Header{"Sec-Websocket-Extensions": {"foo", "permessage-deflate"}}, true},
+	}
+
+	for i, record := range testData {
+		t.Logf("#%d: %v", i, record.header)
+		assert.Equal(t, record.expected, compressionOffered(record.header))
+	}
+}
+
+func TestCompressionNegotiated(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		offered = http.Header{"Sec-Websocket-Extensions": {"permessage-deflate"}}
+	)
+
+	assert.False(compressionNegotiated(&websocket.Upgrader{}, offered))
+	assert.False(compressionNegotiated(&websocket.Upgrader{EnableCompression: true}, http.Header{}))
+	assert.True(compressionNegotiated(&websocket.Upgrader{EnableCompression: true}, offered))
+}
+
+func TestCompressedConn(t *testing.T) {
+	var (
+		assert       = assert.New(t)
+		client, conn = net.Pipe()
+		sent         []int
+		cc           = &compressedConn{Conn: conn}
+	)
+
+	defer client.Close()
+	defer conn.Close()
+	go ioutil.ReadAll(client)
+
+	// writes before counting is enabled, such as the handshake response, are not counted
+	_, err := cc.Write([]byte("handshake"))
+	assert.NoError(err)
+
+	cc.sent = func(n int) { sent = append(sent, n) }
+	_, err = cc.Write([]byte("frame"))
+	assert.NoError(err)
+	assert.Equal([]int{5}, sent)
+}
+
+func TestCompressedResponseWriterNotHijacker(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		crw    = &compressedResponseWriter{ResponseWriter: httptest.NewRecorder()}
+	)
+
+	c, rw, err := crw.Hijack()
+	assert.Nil(c)
+	assert.Nil(rw)
+	assert.Equal(errHijackNotSupported, err)
+}
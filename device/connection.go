@@ -4,6 +4,8 @@ import (
 	"io"
 	"time"
 
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
 	"github.com/gorilla/websocket"
 	"github.com/xmidt-org/webpa-common/xmetrics"
 )
@@ -90,9 +92,18 @@ func NewPinger(w Writer, pings xmetrics.Incrementer, data []byte, deadline func(
 // SetPongHandler establishes an instrumented pong handler for the given connection that enforces
 // the given read timeout.
 func SetPongHandler(r Reader, pongs xmetrics.Incrementer, deadline func() time.Time) {
+	setPongHandler(r, pongs, deadline, nil)
+}
+
+// setPongHandler is like SetPongHandler, but additionally invokes an optional closure for each pong received.
+func setPongHandler(r Reader, pongs xmetrics.Incrementer, deadline func() time.Time, onPong func()) {
 	r.SetPongHandler(func(_ string) error {
 		// increment up front, as this function is only called when a pong is actually received
 		pongs.Inc()
+		if onPong != nil {
+			onPong()
+		}
+
 		return r.SetReadDeadline(deadline())
 	})
 }
@@ -100,6 +111,8 @@ func SetPongHandler(r Reader, pongs xmetrics.Incrementer, deadline func() time.T
 type instrumentedReader struct {
 	ReadCloser
 	statistics Statistics
+	bytes      metrics.Counter
+	messages   metrics.Counter
 }
 
 func (ir *instrumentedReader) ReadMessage() (int, []byte, error) {
@@ -107,18 +120,28 @@ func (ir *instrumentedReader) ReadMessage() (int, []byte, error) {
 	if err == nil {
 		ir.statistics.AddBytesReceived(len(data))
 		ir.statistics.AddMessagesReceived(1)
+		ir.bytes.Add(float64(len(data)))
+		ir.messages.Add(1.0)
 	}
 
 	return messageType, data, err
 }
 
 func InstrumentReader(r ReadCloser, s Statistics) ReadCloser {
-	return &instrumentedReader{r, s}
+	return instrumentReader(r, s, discard.NewCounter(), discard.NewCounter())
+}
+
+// instrumentReader is like InstrumentReader, but additionally adds the bytes and messages received
+// to the given counters.
+func instrumentReader(r ReadCloser, s Statistics, bytes, messages metrics.Counter) ReadCloser {
+	return &instrumentedReader{r, s, bytes, messages}
 }
 
 type instrumentedWriter struct {
 	WriteCloser
 	statistics Statistics
+	bytes      metrics.Counter
+	messages   metrics.Counter
 }
 
 func (iw *instrumentedWriter) WriteMessage(messageType int, data []byte) error {
@@ -129,6 +152,8 @@ func (iw *instrumentedWriter) WriteMessage(messageType int, data []byte) error {
 
 	iw.statistics.AddBytesSent(len(data))
 	iw.statistics.AddMessagesSent(1)
+	iw.bytes.Add(float64(len(data)))
+	iw.messages.Add(1.0)
 	return nil
 }
 
@@ -141,9 +166,16 @@ func (iw *instrumentedWriter) WritePreparedMessage(pm *websocket.PreparedMessage
 	// TODO: There isn't any way to obtain the length of a prepared message, so there's not a way to instrument it
 	// at the moment
 	iw.statistics.AddMessagesSent(1)
+	iw.messages.Add(1.0)
 	return nil
 }
 
 func InstrumentWriter(w WriteCloser, s Statistics) WriteCloser {
-	return &instrumentedWriter{w, s}
+	return instrumentWriter(w, s, discard.NewCounter(), discard.NewCounter())
+}
+
+// instrumentWriter is like InstrumentWriter, but additionally adds the bytes and messages sent
+// to the given counters.
+func instrumentWriter(w WriteCloser, s Statistics, bytes, messages metrics.Counter) WriteCloser {
+	return &instrumentedWriter{w, s, bytes, messages}
 }
@@ -119,6 +119,35 @@ func TestSetPongHandler(t *testing.T) {
 
 		reader.AssertExpectations(t)
 	})
+
+	t.Run("OnPong", func(t *testing.T) {
+		var (
+			assert  = assert.New(t)
+			require = require.New(t)
+
+			now     = time.Now()
+			reader  = new(mockConnectionReader)
+			counter = generic.NewCounter("test")
+			pongs   = 0
+
+			pongHandler func(string) error
+		)
+
+		reader.On("SetPongHandler", mock.MatchedBy(func(func(string) error) bool { return true })).
+			Run(func(arguments mock.Arguments) {
+				pongHandler = arguments.Get(0).(func(string) error)
+			}).
+			Once()
+		reader.On("SetReadDeadline", now).Return((error)(nil)).Once()
+
+		setPongHandler(reader, xmetrics.NewIncrementer(counter), func() time.Time { return now }, func() { pongs++ })
+		require.NotNil(pongHandler)
+		assert.NoError(pongHandler("does not matter"))
+		assert.Equal(1.0, counter.Value())
+		assert.Equal(1, pongs)
+
+		reader.AssertExpectations(t)
+	})
 }
 
 func TestNewPinger(t *testing.T) {
@@ -339,3 +368,65 @@ func TestInstrumentWriter(t *testing.T) {
 		})
 	})
 }
+
+func TestInstrumentCounters(t *testing.T) {
+	t.Run("Reader", func(t *testing.T) {
+		var (
+			assert  = assert.New(t)
+			require = require.New(t)
+
+			statistics = NewStatistics(nil, time.Now())
+			reader     = new(mockConnectionReader)
+			bytes      = generic.NewCounter("bytes")
+			messages   = generic.NewCounter("messages")
+
+			instrumentedReader = instrumentReader(reader, statistics, bytes, messages)
+		)
+
+		require.NotNil(instrumentedReader)
+		reader.On("ReadMessage").Return(websocket.BinaryMessage, []byte{1, 2, 3}, (error)(nil)).Once()
+		reader.On("ReadMessage").Return(-1, []byte{}, errors.New("expected")).Once()
+
+		_, _, err := instrumentedReader.ReadMessage()
+		assert.NoError(err)
+		_, _, err = instrumentedReader.ReadMessage()
+		assert.Error(err)
+
+		assert.Equal(3.0, bytes.Value())
+		assert.Equal(1.0, messages.Value())
+		assert.Equal(3, statistics.BytesReceived())
+		reader.AssertExpectations(t)
+	})
+
+	t.Run("Writer", func(t *testing.T) {
+		var (
+			assert  = assert.New(t)
+			require = require.New(t)
+
+			statistics = NewStatistics(nil, time.Now())
+			writer     = new(mockConnectionWriter)
+			bytes      = generic.NewCounter("bytes")
+			messages   = generic.NewCounter("messages")
+			data       = []byte{43, 3, 74, 111, 89}
+
+			instrumentedWriter = instrumentWriter(writer, statistics, bytes, messages)
+		)
+
+		require.NotNil(instrumentedWriter)
+		writer.On("WriteMessage", websocket.BinaryMessage, data).Return((error)(nil)).Once()
+		writer.On("WriteMessage", websocket.BinaryMessage, []byte{1}).Return(errors.New("expected")).Once()
+		writer.On("WritePreparedMessage", mock.MatchedBy(func(*websocket.PreparedMessage) bool { return true })).Return((error)(nil)).Once()
+
+		pm, err := websocket.NewPreparedMessage(websocket.PingMessage, []byte("ping"))
+		require.NoError(err)
+
+		assert.NoError(instrumentedWriter.WriteMessage(websocket.BinaryMessage, data))
+		assert.Error(instrumentedWriter.WriteMessage(websocket.BinaryMessage, []byte{1}))
+		assert.NoError(instrumentedWriter.WritePreparedMessage(pm))
+
+		assert.Equal(float64(len(data)), bytes.Value())
+		assert.Equal(2.0, messages.Value())
+		assert.Equal(2, statistics.MessagesSent())
+		writer.AssertExpectations(t)
+	})
+}
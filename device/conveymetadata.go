@@ -0,0 +1,168 @@
+package device
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cast"
+	"github.com/xmidt-org/webpa-common/convey"
+)
+
+// MetadataType is the type that a convey value is coerced into before it is stored in a device's Metadata
+type MetadataType string
+
+const (
+	// MetadataString coerces convey values into strings.  This is the default.
+	MetadataString MetadataType = "string"
+
+	// MetadataInt coerces convey values into ints.  Numeric strings are accepted.
+	MetadataInt MetadataType = "int"
+
+	// MetadataFloat coerces convey values into float64s.  Numeric strings are accepted.
+	MetadataFloat MetadataType = "float"
+
+	// MetadataBool coerces convey values into bools.  Strings such as "true" and "0" are accepted.
+	MetadataBool MetadataType = "bool"
+
+	// MetadataRaw stores convey values as they were decoded, without any coercion
+	MetadataRaw MetadataType = "raw"
+)
+
+// coerce converts a convey value into this type
+func (mt MetadataType) coerce(v interface{}) (interface{}, error) {
+	switch mt {
+	case MetadataInt:
+		return cast.ToIntE(v)
+
+	case MetadataFloat:
+		return cast.ToFloat64E(v)
+
+	case MetadataBool:
+		return cast.ToBoolE(v)
+
+	case MetadataRaw:
+		return v, nil
+
+	default:
+		return cast.ToStringE(v)
+	}
+}
+
+// MetadataField describes how a single convey field is copied into a device's Metadata
+type MetadataField struct {
+	// Convey is the dotted path of the convey field, e.g. "hw-model" or "interfaces.0.name".  This field is required.
+	Convey string `json:"convey"`
+
+	// Key is the Metadata key the value is stored under.  If unset, the Convey path is used.
+	Key string `json:"key"`
+
+	// Type is the type the value is coerced into.  If unset, MetadataString is used.
+	Type MetadataType `json:"type"`
+}
+
+func (mf MetadataField) key() string {
+	if len(mf.Key) > 0 {
+		return mf.Key
+	}
+
+	return mf.Convey
+}
+
+func (mf MetadataField) metadataType() MetadataType {
+	if len(mf.Type) > 0 {
+		return mf.Type
+	}
+
+	return MetadataString
+}
+
+// ConveyMetadataTranslator copies convey fields into a device's Metadata, which makes convey data available
+// wherever Metadata is, e.g. to Authenticators, listeners, and handlers, in a form that has already been typed.
+type ConveyMetadataTranslator interface {
+	// Apply stores each mapped field present in the convey map into the given Metadata.  Fields missing from the
+	// convey map are skipped.  Fields that cannot be coerced are also skipped, and are reported via the returned
+	// error, which is a convey.ValidationError.  The remaining fields are stored regardless.
+	Apply(convey.C, *Metadata) error
+}
+
+type conveyMetadataField struct {
+	path         []string
+	key          string
+	metadataType MetadataType
+}
+
+type conveyMetadataTranslator struct {
+	fields []conveyMetadataField
+}
+
+// NewConveyMetadataTranslator produces a ConveyMetadataTranslator from a set of field mappings.  An error is
+// returned if any field has no convey path or an invalid type, or if any metadata key is reserved or mapped
+// more than once.  With no fields, the returned translator does nothing.
+func NewConveyMetadataTranslator(fields ...MetadataField) (ConveyMetadataTranslator, error) {
+	var (
+		cmt  = new(conveyMetadataTranslator)
+		keys = make(map[string]bool, len(fields))
+	)
+
+	for _, f := range fields {
+		if len(f.Convey) == 0 {
+			return nil, ErrorMissingConveyField
+		}
+
+		key, metadataType := f.key(), f.metadataType()
+		switch {
+		case reservedMetadataKeys[key]:
+			return nil, ErrorReservedMetadataKey
+
+		case keys[key]:
+			return nil, ErrorDuplicateMetadataKey
+		}
+
+		switch metadataType {
+		case MetadataString, MetadataInt, MetadataFloat, MetadataBool, MetadataRaw:
+		default:
+			return nil, ErrorInvalidMetadataType
+		}
+
+		keys[key] = true
+		cmt.fields = append(cmt.fields, conveyMetadataField{
+			path:         strings.Split(f.Convey, "."),
+			key:          key,
+			metadataType: metadataType,
+		})
+	}
+
+	return cmt, nil
+}
+
+func (cmt *conveyMetadataTranslator) Apply(c convey.C, metadata *Metadata) error {
+	var failures []convey.FieldError
+	for _, f := range cmt.fields {
+		v, err := c.GetPath(f.path...)
+		if err != nil {
+			if convey.GetCompliance(err) == convey.Invalid {
+				failures = append(failures, convey.FieldError{Field: strings.Join(f.path, "."), Description: err.Error()})
+			}
+
+			continue
+		}
+
+		coerced, err := f.metadataType.coerce(v)
+		if err != nil {
+			failures = append(failures, convey.FieldError{
+				Field:       strings.Join(f.path, "."),
+				Description: fmt.Sprintf("cannot be coerced to %s: %s", f.metadataType, err),
+			})
+
+			continue
+		}
+
+		metadata.Store(f.key, coerced)
+	}
+
+	if len(failures) > 0 {
+		return convey.ValidationError{Fields: failures, C: convey.Invalid}
+	}
+
+	return nil
+}
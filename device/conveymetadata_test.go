@@ -0,0 +1,97 @@
+package device
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/convey"
+)
+
+func TestNewConveyMetadataTranslator(t *testing.T) {
+	testData := []struct {
+		name     string
+		fields   []MetadataField
+		expected error
+	}{
+		{"Empty", nil, nil},
+		{"Valid", []MetadataField{{Convey: "hw-model"}, {Convey: "boot-time", Key: "bootTime", Type: MetadataInt}}, nil},
+		{"MissingConvey", []MetadataField{{Key: "model"}}, ErrorMissingConveyField},
+		{"Reserved", []MetadataField{{Convey: "session", Key: SessionIDKey}}, ErrorReservedMetadataKey},
+		{"Duplicate", []MetadataField{{Convey: "hw-model", Key: "model"}, {Convey: "model"}}, ErrorDuplicateMetadataKey},
+		{"InvalidType", []MetadataField{{Convey: "hw-model", Type: "nosuch"}}, ErrorInvalidMetadataType},
+	}
+
+	for _, record := range testData {
+		t.Run(record.name, func(t *testing.T) {
+			assert := assert.New(t)
+			cmt, err := NewConveyMetadataTranslator(record.fields...)
+			assert.Equal(record.expected, err)
+			assert.Equal(record.expected == nil, cmt != nil)
+		})
+	}
+}
+
+func TestConveyMetadataTranslatorApply(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		metadata = new(Metadata)
+		c        = convey.C{
+			"hw-model":             "TG1682",
+			"boot-time":            uint64(1565222406),
+			"webpa-uptime":         "123.5",
+			"webpa-interface-up":   "true",
+			"fw-name":              12,
+			"interfaces":           []interface{}{convey.C{"name": "erouter0"}},
+			"webpa-last-reconnect": "not a number",
+		}
+	)
+
+	cmt, err := NewConveyMetadataTranslator(
+		MetadataField{Convey: "hw-model", Key: "model"},
+		MetadataField{Convey: "boot-time", Key: "bootTime", Type: MetadataInt},
+		MetadataField{Convey: "webpa-uptime", Key: "uptime", Type: MetadataFloat},
+		MetadataField{Convey: "webpa-interface-up", Key: "interfaceUp", Type: MetadataBool},
+		MetadataField{Convey: "fw-name", Key: "firmware"},
+		MetadataField{Convey: "interfaces.0.name", Key: "interface"},
+		MetadataField{Convey: "interfaces", Key: "interfaces", Type: MetadataRaw},
+		MetadataField{Convey: "webpa-last-reconnect", Key: "lastReconnect", Type: MetadataInt},
+		MetadataField{Convey: "hw-model.name", Key: "invalidPath"},
+		MetadataField{Convey: "missing", Key: "missing"},
+	)
+
+	require.NoError(err)
+	require.NotNil(cmt)
+
+	err = cmt.Apply(c, metadata)
+	require.Error(err)
+	assert.Equal(convey.Invalid, convey.GetCompliance(err))
+
+	ve, ok := err.(convey.ValidationError)
+	require.True(ok)
+	require.Len(ve.Fields, 2)
+	assert.Equal("webpa-last-reconnect", ve.Fields[0].Field)
+	assert.Equal("hw-model.name", ve.Fields[1].Field)
+
+	assert.Equal("TG1682", metadata.Load("model"))
+	assert.Equal(1565222406, metadata.Load("bootTime"))
+	assert.Equal(123.5, metadata.Load("uptime"))
+	assert.Equal(true, metadata.Load("interfaceUp"))
+	assert.Equal("12", metadata.Load("firmware"))
+	assert.Equal("erouter0", metadata.Load("interface"))
+	assert.Equal([]interface{}{convey.C{"name": "erouter0"}}, metadata.Load("interfaces"))
+	assert.Nil(metadata.Load("lastReconnect"))
+	assert.Nil(metadata.Load("invalidPath"))
+	assert.Nil(metadata.Load("missing"))
+
+	other := new(Metadata)
+	err = cmt.Apply(convey.C{"hw-model": convey.C{"name": "TG3000"}, "fw-name": "fw-1"}, other)
+	require.IsType(convey.ValidationError{}, err)
+	require.Len(err.(convey.ValidationError).Fields, 1)
+	assert.Equal("hw-model", err.(convey.ValidationError).Fields[0].Field)
+	assert.Equal("TG3000", other.Load("invalidPath"))
+	assert.Equal("fw-1", other.Load("firmware"))
+	assert.Nil(other.Load("model"))
+}
@@ -0,0 +1,28 @@
+package device
+
+// ConveyNotAvailablePolicy describes what a Manager does when a device sends the literal value "not-available"
+// as its convey header rather than omitting the header.
+type ConveyNotAvailablePolicy string
+
+const (
+	// ConveyNotAvailableLog treats the header as invalid convey data, which is logged as an error.  This is the default.
+	ConveyNotAvailableLog ConveyNotAvailablePolicy = "log"
+
+	// ConveyNotAvailableIgnore treats the device as though it sent no convey header at all.
+	ConveyNotAvailableIgnore ConveyNotAvailablePolicy = "ignore"
+
+	// ConveyNotAvailableReject refuses the connection with a 400 status.
+	ConveyNotAvailableReject ConveyNotAvailablePolicy = "reject"
+
+	// ConveyNotAvailableAccept accepts the device with an empty, fully compliant convey map.
+	ConveyNotAvailableAccept ConveyNotAvailablePolicy = "accept"
+)
+
+func (cp ConveyNotAvailablePolicy) normalize() ConveyNotAvailablePolicy {
+	switch cp {
+	case ConveyNotAvailableIgnore, ConveyNotAvailableReject, ConveyNotAvailableAccept:
+		return cp
+	default:
+		return ConveyNotAvailableLog
+	}
+}
@@ -0,0 +1,96 @@
+package device
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru/simplelru"
+	"github.com/xmidt-org/wrp-go/v3"
+)
+
+// DefaultDedupTTL is the length of time a transaction UUID is remembered for deduplication
+// when no DedupTTL is configured.
+const DefaultDedupTTL = 5 * time.Minute
+
+// dedup is a bounded, least-recently-used record of the transaction UUIDs recently delivered
+// to a single device.  Entries older than the TTL are ignored.
+type dedup struct {
+	ttl  time.Duration
+	now  func() time.Time
+	lock sync.Mutex
+	seen *simplelru.LRU
+}
+
+// newDedup creates a dedup that remembers at most size transaction UUIDs.  If size is nonpositive,
+// this function returns nil, which disables deduplication.
+func newDedup(size int, ttl time.Duration, now func() time.Time) *dedup {
+	if size < 1 {
+		return nil
+	}
+
+	if ttl <= 0 {
+		ttl = DefaultDedupTTL
+	}
+
+	if now == nil {
+		now = time.Now
+	}
+
+	seen, err := simplelru.NewLRU(size, nil)
+	if err != nil {
+		// only happens if size is nonpositive
+		panic(err)
+	}
+
+	return &dedup{
+		ttl:  ttl,
+		now:  now,
+		seen: seen,
+	}
+}
+
+// claim records a transaction UUID as delivered.  If that UUID was already claimed within the TTL,
+// this method returns false and the message should not be delivered again.  A nil dedup claims everything.
+func (dd *dedup) claim(uuid string) bool {
+	if dd == nil {
+		return true
+	}
+
+	dd.lock.Lock()
+	defer dd.lock.Unlock()
+
+	now := dd.now()
+	if claimed, ok := dd.seen.Get(uuid); ok && now.Sub(claimed.(time.Time)) < dd.ttl {
+		return false
+	}
+
+	dd.seen.Add(uuid, now)
+	return true
+}
+
+// release forgets a transaction UUID, which allows a message whose delivery failed to be retried.
+// An empty UUID is ignored.
+func (dd *dedup) release(uuid string) {
+	if dd == nil || len(uuid) == 0 {
+		return
+	}
+
+	dd.lock.Lock()
+	dd.seen.Remove(uuid)
+	dd.lock.Unlock()
+}
+
+// dedupUUID returns the transaction UUID used to deduplicate a request.  If this device does not
+// deduplicate messages, or if the request has no routable message with a transaction UUID, this
+// method returns the empty string.
+func (d *device) dedupUUID(request *Request) string {
+	if d.dedup == nil {
+		return ""
+	}
+
+	if routable, ok := request.Message.(wrp.Routable); ok {
+		return routable.TransactionKey()
+	}
+
+	return ""
+}
@@ -0,0 +1,139 @@
+package device
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/xmetrics/xmetricstest"
+	"github.com/xmidt-org/wrp-go/v3"
+)
+
+func TestNewDedup(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(newDedup(0, time.Minute, nil))
+	assert.Nil(newDedup(-1, time.Minute, nil))
+
+	dd := newDedup(10, 0, nil)
+	if assert.NotNil(dd) {
+		assert.Equal(DefaultDedupTTL, dd.ttl)
+		assert.NotNil(dd.now)
+	}
+}
+
+func TestDedupClaim(t *testing.T) {
+	t.Run("Nil", func(t *testing.T) {
+		var dd *dedup
+		assert.True(t, dd.claim("test"))
+		assert.True(t, dd.claim("test"))
+		dd.release("test")
+	})
+
+	t.Run("TTL", func(t *testing.T) {
+		var (
+			assert  = assert.New(t)
+			current = time.Now()
+			dd      = newDedup(10, time.Minute, func() time.Time { return current })
+		)
+
+		assert.True(dd.claim("test"))
+		assert.False(dd.claim("test"))
+		assert.True(dd.claim("another"))
+
+		current = current.Add(time.Minute)
+		assert.True(dd.claim("test"))
+		assert.False(dd.claim("test"))
+	})
+
+	t.Run("Release", func(t *testing.T) {
+		assert := assert.New(t)
+		dd := newDedup(10, time.Minute, nil)
+
+		assert.True(dd.claim("test"))
+		dd.release("test")
+		dd.release("")
+		assert.True(dd.claim("test"))
+	})
+
+	t.Run("Evict", func(t *testing.T) {
+		assert := assert.New(t)
+		dd := newDedup(2, time.Minute, nil)
+
+		assert.True(dd.claim("first"))
+		assert.True(dd.claim("second"))
+		assert.True(dd.claim("third"))
+		assert.True(dd.claim("first"))
+		assert.False(dd.claim("third"))
+	})
+}
+
+func TestDeviceSendDedup(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		p       = xmetricstest.NewProvider(nil, Metrics)
+
+		d = newDevice(deviceOptions{
+			ID:        ID("mac:112233445566"),
+			Logger:    logging.NewTestLogger(nil, t),
+			DedupSize: 10,
+			DedupHits: p.NewCounter(DedupHitCounter),
+		})
+
+		newRequest = func(uuid string) *Request {
+			return &Request{
+				Message: &wrp.Message{
+					Type:            wrp.SimpleEventMessageType,
+					Destination:     "mac:112233445566",
+					TransactionUUID: uuid,
+				},
+			}
+		}
+
+		writeResults = make(chan error, 10)
+	)
+
+	// simulate a write pump that completes each message with the next result
+	go func() {
+		for result := range writeResults {
+			e := <-d.messages.tiers[QOSLow]
+			if result != nil {
+				e.complete <- result
+			}
+
+			close(e.complete)
+		}
+	}()
+
+	defer close(writeResults)
+
+	writeResults <- nil
+	_, err := d.Send(newRequest("abc"))
+	require.NoError(err)
+
+	_, err = d.Send(newRequest("abc"))
+	assert.Equal(ErrorDuplicateTransaction, err)
+	p.Assert(t, DedupHitCounter)(xmetricstest.Value(1.0))
+
+	// a failed delivery can be retried
+	writeResults <- ErrorDeviceBusy
+	_, err = d.Send(newRequest("def"))
+	assert.Equal(ErrorDeviceBusy, err)
+
+	writeResults <- nil
+	_, err = d.Send(newRequest("def"))
+	assert.NoError(err)
+
+	// messages without a transaction UUID are never deduplicated
+	writeResults <- nil
+	writeResults <- nil
+	_, err = d.Send(newRequest(""))
+	assert.NoError(err)
+	_, err = d.Send(newRequest(""))
+	assert.NoError(err)
+
+	p.Assert(t, DedupHitCounter)(xmetricstest.Value(1.0))
+}
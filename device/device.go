@@ -2,6 +2,7 @@ package device
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"sync/atomic"
@@ -11,7 +12,10 @@ import (
 	"github.com/xmidt-org/webpa-common/convey/conveymetric"
 
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
 	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/wrp-go/v3"
 )
 
 const (
@@ -98,11 +102,42 @@ type Interface interface {
 	CloseReason() CloseReason
 }
 
+// SubprotocolProvider is implemented by devices which expose their negotiated websocket subprotocol.
+// The devices created by a Manager implement this interface, which is kept separate from Interface
+// so that existing implementations of Interface are unaffected.
+type SubprotocolProvider interface {
+	// Subprotocol returns the websocket subprotocol negotiated when this device connected, or
+	// the empty string if no subprotocol was negotiated.
+	Subprotocol() string
+}
+
 // device is the internal Interface implementation.  This type holds the internal
 // metadata exposed publicly, and provides some internal data structures for housekeeping.
 type device struct {
+	// lastActivity is the time, in Unix nanoseconds, of the most recent application message received
+	// from this device.  It is accessed atomically, and is first so that it is 64-bit aligned.
+	lastActivity int64
+
 	id ID
 
+	// baseID is the ID the device connected with.  This differs from id only for
+	// duplicate connections registered under a connection instance suffix.
+	baseID ID
+
+	// format is the WRP encoding used on the wire for this device's connection
+	format wrp.Format
+
+	// subprotocol is the websocket subprotocol negotiated for this device's connection
+	subprotocol string
+
+	// sessionExpires is the expiry set by the Manager's Authenticator, if any
+	sessionExpires time.Time
+
+	missedPongs missedPongs
+
+	// breaker is this device's error circuit breaker, which is nil if breakers are disabled
+	breaker *breaker
+
 	errorLog log.Logger
 	infoLog  log.Logger
 	debugLog log.Logger
@@ -112,9 +147,17 @@ type device struct {
 	state int32
 
 	shutdown     chan struct{}
-	messages     chan *envelope
+	messages     *qosQueues
 	transactions *Transactions
 
+	queuePolicy       QueuePolicy
+	queueBlockTimeout time.Duration
+	queueFull         metrics.Counter
+	listener          Listener
+
+	dedup     *dedup
+	dedupHits metrics.Counter
+
 	c             convey.Interface
 	compliance    convey.Compliance
 	conveyClosure conveymetric.Closure
@@ -129,9 +172,27 @@ type deviceOptions struct {
 	C           convey.Interface
 	Compliance  convey.Compliance
 	QueueSize   int
+	QOSTiers    map[QOSLevel]QOSTier
 	ConnectedAt time.Time
 	Logger      log.Logger
 	Metadata    *Metadata
+
+	// QueuePolicy is applied when a message finds its queue full.  QOS tiers which
+	// drop when full do not use this policy.
+	QueuePolicy       QueuePolicy
+	QueueBlockTimeout time.Duration
+	QueueFull         metrics.Counter
+
+	// Listener receives a MessageFailed event for each message evicted from a queue under
+	// QueueDropOldest.  If unset, evicted messages are not reported.
+	Listener Listener
+
+	// DedupSize is the number of recent transaction UUIDs remembered in order to suppress
+	// duplicate deliveries.  If nonpositive, messages are not deduplicated.
+	DedupSize int
+	DedupTTL  time.Duration
+	DedupHits metrics.Counter
+	Now       func() time.Time
 }
 
 // newDevice is an internal factory function for devices
@@ -148,8 +209,22 @@ func newDevice(o deviceOptions) *device {
 		o.QueueSize = DefaultDeviceMessageQueueSize
 	}
 
+	if o.QueueBlockTimeout <= 0 {
+		o.QueueBlockTimeout = DefaultQueueBlockTimeout
+	}
+
+	if o.QueueFull == nil {
+		o.QueueFull = discard.NewCounter()
+	}
+
+	if o.DedupHits == nil {
+		o.DedupHits = discard.NewCounter()
+	}
+
 	return &device{
+		lastActivity: o.ConnectedAt.UnixNano(),
 		id:           o.ID,
+		baseID:       o.ID,
 		errorLog:     logging.Error(o.Logger, "id", o.ID),
 		infoLog:      logging.Info(o.Logger, "id", o.ID),
 		debugLog:     logging.Debug(o.Logger, "id", o.ID),
@@ -158,24 +233,48 @@ func newDevice(o deviceOptions) *device {
 		compliance:   o.Compliance,
 		state:        stateOpen,
 		shutdown:     make(chan struct{}),
-		messages:     make(chan *envelope, o.QueueSize),
+		messages:     newQOSQueues(o.QueueSize, o.QOSTiers),
 		transactions: NewTransactions(),
 		metadata:     o.Metadata,
+
+		queuePolicy:       o.QueuePolicy.normalize(),
+		queueBlockTimeout: o.QueueBlockTimeout,
+		queueFull:         o.QueueFull,
+		listener:          o.Listener,
+
+		dedup:     newDedup(o.DedupSize, o.DedupTTL, o.Now),
+		dedupHits: o.DedupHits,
 	}
 }
 
+// touch records the receipt of an application message from this device
+func (d *device) touch(t time.Time) {
+	atomic.StoreInt64(&d.lastActivity, t.UnixNano())
+}
+
+// lastActivityAt returns the time of the most recent application message received from this device,
+// or the connection time if no message has been received
+func (d *device) lastActivityAt() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&d.lastActivity))
+}
+
 // String returns the JSON representation of this device
 func (d *device) String() string {
 	return string(d.id)
 }
 
 func (d *device) MarshalJSON() ([]byte, error) {
+	id, err := json.Marshal(string(d.id))
+	if err != nil {
+		return nil, err
+	}
+
 	var output bytes.Buffer
-	_, err := fmt.Fprintf(
+	_, err = fmt.Fprintf(
 		&output,
-		`{"id": "%s", "pending": %d, "statistics": %s}`,
-		d.id,
-		len(d.messages),
+		`{"id": %s, "pending": %d, "statistics": %s}`,
+		id,
+		d.messages.len(),
 		d.statistics,
 	)
 
@@ -187,11 +286,7 @@ func (d *device) requestClose(reason CloseReason) error {
 		close(d.shutdown)
 		d.transactions.Close()
 
-		if len(reason.Text) == 0 {
-			reason.Text = "unknown"
-		}
-
-		d.closeReason.Store(reason)
+		d.closeReason.Store(reason.normalize())
 	}
 
 	return nil
@@ -202,7 +297,7 @@ func (d *device) ID() ID {
 }
 
 func (d *device) Pending() int {
-	return len(d.messages)
+	return d.messages.len()
 }
 
 func (d *device) Closed() bool {
@@ -224,13 +319,22 @@ func (d *device) sendRequest(request *Request) error {
 		}
 	)
 
-	// attempt to enqueue the message
-	select {
-	case <-done:
-		return request.Context().Err()
-	case <-d.shutdown:
-		return ErrorDeviceClosed
-	case d.messages <- envelope:
+	// attempt to enqueue the message into the tier for its QOS level
+	var (
+		level = request.QOS().Level()
+		queue = d.messages.tiers[level]
+	)
+
+	if d.messages.dropWhenFull[level] {
+		select {
+		case <-d.shutdown:
+			return ErrorDeviceClosed
+		case queue <- envelope:
+		default:
+			return ErrorDeviceBusy
+		}
+	} else if err := d.enqueue(queue, envelope, done); err != nil {
+		return err
 	}
 
 	// once enqueued, wait until the context is cancelled
@@ -245,12 +349,26 @@ func (d *device) sendRequest(request *Request) error {
 	}
 }
 
+// breakerFailure records an error caused by this device against its circuit breaker, closing this
+// device if the breaker has opened too many times
+func (d *device) breakerFailure(err error) {
+	if d.breaker.failure() {
+		d.errorLog.Log(logging.MessageKey(), "disconnecting device whose circuit breaker opened too many times", logging.ErrorKey(), err)
+		d.requestClose(CloseReason{Err: ErrorDeviceBreakerOpen, Reason: DisconnectCircuitBreaker})
+	}
+}
+
 // awaitResponse waits for the read pump to acquire a response that corresponds to the
 // request's transaction key.  The result channel will receive the response from the
 // read pump.
 func (d *device) awaitResponse(request *Request, result <-chan *Response) (*Response, error) {
 	select {
 	case <-request.Context().Done():
+		if request.Context().Err() == context.DeadlineExceeded {
+			// the device did not answer the transaction in time
+			d.breakerFailure(context.DeadlineExceeded)
+		}
+
 		return nil, request.Context().Err()
 	case <-d.shutdown:
 		return nil, ErrorDeviceClosed
@@ -268,6 +386,13 @@ func (d *device) Send(request *Request) (*Response, error) {
 		return nil, ErrorDeviceClosed
 	}
 
+	// suppress redelivery of messages, typically caused by upstream retries
+	uuid := d.dedupUUID(request)
+	if len(uuid) > 0 && !d.dedup.claim(uuid) {
+		d.dedupHits.Add(1.0)
+		return nil, ErrorDuplicateTransaction
+	}
+
 	var (
 		transactionKey, transactional = request.Transactional()
 		result                        <-chan *Response
@@ -278,6 +403,7 @@ func (d *device) Send(request *Request) (*Response, error) {
 		if result, err = d.transactions.Register(transactionKey); err != nil {
 			// if a transaction key cannot be registered, we don't want to proceed.
 			// this indicates some larger problem, most often a duplicate transaction key.
+			d.dedup.release(uuid)
 			return nil, err
 		}
 
@@ -286,6 +412,8 @@ func (d *device) Send(request *Request) (*Response, error) {
 	}
 
 	if err := d.sendRequest(request); err != nil {
+		// allow a retry of a message that was not delivered
+		d.dedup.release(uuid)
 		return nil, err
 	}
 
@@ -313,6 +441,10 @@ func (d *device) Metadata() *Metadata {
 	return d.metadata
 }
 
+func (d *device) Subprotocol() string {
+	return d.subprotocol
+}
+
 func (d *device) CloseReason() CloseReason {
 	if v, ok := d.closeReason.Load().(CloseReason); ok {
 		return v
@@ -78,7 +78,7 @@ func TestDevice(t *testing.T) {
 
 		assert.JSONEq(
 			fmt.Sprintf(
-				`{"id": "%s", "pending": 0, "statistics": {"duplications": 0, "bytesSent": 0, "messagesSent": 0, "bytesReceived": 0, "messagesReceived": 0, "connectedAt": "%s", "upTime": "%s"}}`,
+				`{"id": "%s", "pending": 0, "statistics": {"duplications": 0, "compressed": false, "compressedBytesSent": 0, "compressionRatio": 0, "roundTripTime": "0s", "missedPongs": 0, "bytesSent": 0, "messagesSent": 0, "bytesReceived": 0, "messagesReceived": 0, "connectedAt": "%s", "upTime": "%s"}}`,
 				record.expectedID,
 				expectedConnectedAt.UTC().Format(time.RFC3339Nano),
 				expectedUpTime,
@@ -0,0 +1,108 @@
+package devicesink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	DefaultKafkaWriteTimeout = 10 * time.Second
+
+	// kafkaBatchTimeout is the time the kafka writer waits to fill its own batches.  The sink Listener
+	// already batches records, so this is kept short.
+	kafkaBatchTimeout = 10 * time.Millisecond
+)
+
+var (
+	ErrNoKafkaBrokers = errors.New("At least one kafka broker is required")
+	ErrNoKafkaTopic   = errors.New("A kafka topic is required")
+)
+
+// KafkaConfig is the configuration for a Kafka Sink.  It is suitable for unmarshaling from Viper.
+type KafkaConfig struct {
+	// Brokers is the list of kafka bootstrap brokers.  This field is required.
+	Brokers []string
+
+	// Topic is the kafka topic to which records are written.  This field is required.
+	Topic string
+
+	// RequiredAcks is the number of acknowledgements required from the kafka cluster.  Zero
+	// means no acknowledgements, while -1 means all in-sync replicas.
+	RequiredAcks int
+
+	// WriteTimeout is the timeout for writes to kafka.  If unset, DefaultKafkaWriteTimeout is used.
+	WriteTimeout time.Duration
+}
+
+func (kc KafkaConfig) writeTimeout() time.Duration {
+	if kc.WriteTimeout > 0 {
+		return kc.WriteTimeout
+	}
+
+	return DefaultKafkaWriteTimeout
+}
+
+// kafkaWriter is the behavior of a kafka-go Writer used by KafkaSink
+type kafkaWriter interface {
+	WriteMessages(context.Context, ...kafka.Message) error
+	Close() error
+}
+
+// KafkaSink is a Sink which writes each record as a JSON kafka message, keyed by device ID so that
+// all events for a given device land on the same partition.
+type KafkaSink struct {
+	writer kafkaWriter
+}
+
+var _ Sink = (*KafkaSink)(nil)
+
+// NewKafkaSink creates a Sink which writes to kafka.  The returned sink should be closed when no
+// longer needed.
+func NewKafkaSink(kc KafkaConfig) (*KafkaSink, error) {
+	if len(kc.Brokers) == 0 {
+		return nil, ErrNoKafkaBrokers
+	}
+
+	if len(kc.Topic) == 0 {
+		return nil, ErrNoKafkaTopic
+	}
+
+	return &KafkaSink{
+		writer: kafka.NewWriter(kafka.WriterConfig{
+			Brokers:      kc.Brokers,
+			Topic:        kc.Topic,
+			Balancer:     &kafka.Hash{},
+			BatchTimeout: kafkaBatchTimeout,
+			RequiredAcks: kc.RequiredAcks,
+			WriteTimeout: kc.writeTimeout(),
+		}),
+	}, nil
+}
+
+// Publish writes a batch of records to kafka
+func (ks *KafkaSink) Publish(ctx context.Context, records []Record) error {
+	messages := make([]kafka.Message, 0, len(records))
+	for _, r := range records {
+		value, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+
+		messages = append(messages, kafka.Message{
+			Key:   []byte(r.DeviceID),
+			Value: value,
+			Time:  r.Timestamp,
+		})
+	}
+
+	return ks.writer.WriteMessages(ctx, messages...)
+}
+
+// Close releases the kafka connections held by this sink
+func (ks *KafkaSink) Close() error {
+	return ks.writer.Close()
+}
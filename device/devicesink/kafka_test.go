@@ -0,0 +1,81 @@
+package devicesink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testKafkaWriter struct {
+	err      error
+	messages []kafka.Message
+	closed   bool
+}
+
+func (w *testKafkaWriter) WriteMessages(_ context.Context, messages ...kafka.Message) error {
+	w.messages = append(w.messages, messages...)
+	return w.err
+}
+
+func (w *testKafkaWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestNewKafkaSink(t *testing.T) {
+	assert := assert.New(t)
+
+	ks, err := NewKafkaSink(KafkaConfig{Topic: "events"})
+	assert.Nil(ks)
+	assert.Equal(ErrNoKafkaBrokers, err)
+
+	ks, err = NewKafkaSink(KafkaConfig{Brokers: []string{"localhost:9092"}})
+	assert.Nil(ks)
+	assert.Equal(ErrNoKafkaTopic, err)
+
+	ks, err = NewKafkaSink(KafkaConfig{Brokers: []string{"localhost:9092"}, Topic: "events"})
+	assert.NotNil(ks)
+	assert.NoError(err)
+	assert.NoError(ks.Close())
+
+	assert.Equal(DefaultKafkaWriteTimeout, KafkaConfig{}.writeTimeout())
+	assert.Equal(time.Minute, KafkaConfig{WriteTimeout: time.Minute}.writeTimeout())
+}
+
+func TestKafkaSinkPublish(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		writer  = new(testKafkaWriter)
+		ks      = &KafkaSink{writer: writer}
+		now     = time.Now().UTC()
+		records = []Record{
+			{Type: "Connect", DeviceID: "mac:112233445566", Timestamp: now},
+			{Type: "Disconnect", DeviceID: "mac:665544332211", Timestamp: now},
+		}
+	)
+
+	require.NoError(ks.Publish(context.Background(), records))
+	require.Len(writer.messages, 2)
+	for i, m := range writer.messages {
+		assert.Equal(records[i].DeviceID, string(m.Key))
+		assert.Equal(now, m.Time)
+
+		var actual Record
+		require.NoError(json.Unmarshal(m.Value, &actual))
+		assert.Equal(records[i].Type, actual.Type)
+		assert.Equal(records[i].DeviceID, actual.DeviceID)
+	}
+
+	writer.err = errors.New("expected")
+	assert.Equal(writer.err, ks.Publish(context.Background(), records))
+
+	assert.NoError(ks.Close())
+	assert.True(writer.closed)
+}
@@ -0,0 +1,61 @@
+package devicesink
+
+import (
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/provider"
+	"github.com/xmidt-org/webpa-common/xmetrics"
+)
+
+const (
+	PublishedCounter = "device_sink_published_count"
+	FailedCounter    = "device_sink_failed_count"
+	DroppedCounter   = "device_sink_dropped_count"
+	RetryCounter     = "device_sink_retry_count"
+	BatchSizeGauge   = "device_sink_batch_size"
+)
+
+// Metrics is the device sink module function that adds default sink metrics
+func Metrics() []xmetrics.Metric {
+	return []xmetrics.Metric{
+		{
+			Name: PublishedCounter,
+			Type: "counter",
+		},
+		{
+			Name: FailedCounter,
+			Type: "counter",
+		},
+		{
+			Name: DroppedCounter,
+			Type: "counter",
+		},
+		{
+			Name: RetryCounter,
+			Type: "counter",
+		},
+		{
+			Name: BatchSizeGauge,
+			Type: "gauge",
+		},
+	}
+}
+
+// Measures holds the metric objects used by a sink Listener
+type Measures struct {
+	Published metrics.Counter
+	Failed    metrics.Counter
+	Dropped   metrics.Counter
+	Retries   metrics.Counter
+	BatchSize metrics.Gauge
+}
+
+// NewMeasures constructs a Measures given a go-kit metrics Provider
+func NewMeasures(p provider.Provider) Measures {
+	return Measures{
+		Published: p.NewCounter(PublishedCounter),
+		Failed:    p.NewCounter(FailedCounter),
+		Dropped:   p.NewCounter(DroppedCounter),
+		Retries:   p.NewCounter(RetryCounter),
+		BatchSize: p.NewGauge(BatchSizeGauge),
+	}
+}
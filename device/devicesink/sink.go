@@ -0,0 +1,393 @@
+package devicesink
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics/provider"
+	"github.com/xmidt-org/webpa-common/device"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+const (
+	DefaultQueueSize     = 1000
+	DefaultBatchSize     = 100
+	DefaultFlushInterval = time.Second
+	DefaultMaxRetries    = 3
+	DefaultRetryInterval = time.Second
+)
+
+var (
+	ErrListenerStarted    = errors.New("That sink listener has already been started")
+	ErrListenerNotStarted = errors.New("That sink listener has not been started")
+)
+
+// Record is the externally published form of a device event.  Records are safe to retain
+// after the originating device.Event has been reused.
+type Record struct {
+	Type        string    `json:"type"`
+	DeviceID    string    `json:"deviceID"`
+	Timestamp   time.Time `json:"timestamp"`
+	PartnerID   string    `json:"partnerID,omitempty"`
+	SessionID   string    `json:"sessionID,omitempty"`
+	CloseReason string    `json:"closeReason,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	Format      string    `json:"format,omitempty"`
+	Contents    []byte    `json:"contents,omitempty"`
+}
+
+// Sink is the strategy for publishing device event records to an external system.
+type Sink interface {
+	// Publish delivers a batch of records.  An error indicates the entire batch should be retried.
+	Publish(context.Context, []Record) error
+}
+
+// SinkFunc is a function type that implements Sink
+type SinkFunc func(context.Context, []Record) error
+
+func (sf SinkFunc) Publish(ctx context.Context, records []Record) error {
+	return sf(ctx, records)
+}
+
+// Option is a configuration option for a sink Listener
+type Option func(*Listener)
+
+// WithLogger configures a Listener with a logger, using the default logger if l is nil.
+func WithLogger(l log.Logger) Option {
+	return func(sl *Listener) {
+		if l == nil {
+			sl.logger = logging.DefaultLogger()
+		} else {
+			sl.logger = l
+		}
+	}
+}
+
+// WithMetricsProvider configures the metrics a Listener uses to report delivery.  A nil provider
+// discards all metrics.
+func WithMetricsProvider(p provider.Provider) Option {
+	return func(sl *Listener) {
+		if p == nil {
+			p = provider.NewDiscardProvider()
+		}
+
+		sl.measures = NewMeasures(p)
+	}
+}
+
+// WithEventTypes sets the device event types that are published.  By default, Connect, Disconnect,
+// and MessageFailed events are published.
+func WithEventTypes(types ...device.EventType) Option {
+	return func(sl *Listener) {
+		sl.eventTypes = make(map[device.EventType]bool, len(types))
+		for _, t := range types {
+			sl.eventTypes[t] = true
+		}
+	}
+}
+
+// WithQueueSize sets the number of records that may be waiting for publication.  Events that arrive
+// while the queue is full are dropped, since device listeners must never block.
+func WithQueueSize(v int) Option {
+	return func(sl *Listener) {
+		if v > 0 {
+			sl.queueSize = v
+		} else {
+			sl.queueSize = DefaultQueueSize
+		}
+	}
+}
+
+// WithBatch sets the maximum number of records in a batch and the maximum time a record waits
+// for its batch to fill before being published.
+func WithBatch(size int, flushInterval time.Duration) Option {
+	return func(sl *Listener) {
+		sl.batchSize = DefaultBatchSize
+		if size > 0 {
+			sl.batchSize = size
+		}
+
+		sl.flushInterval = DefaultFlushInterval
+		if flushInterval > 0 {
+			sl.flushInterval = flushInterval
+		}
+	}
+}
+
+// WithRetries sets the number of additional attempts made for a batch that fails to publish, along
+// with the initial interval between attempts.  The interval doubles after each failed attempt.
+func WithRetries(maxRetries int, interval time.Duration) Option {
+	return func(sl *Listener) {
+		sl.maxRetries = 0
+		if maxRetries > 0 {
+			sl.maxRetries = maxRetries
+		}
+
+		sl.retryInterval = DefaultRetryInterval
+		if interval > 0 {
+			sl.retryInterval = interval
+		}
+	}
+}
+
+// WithPublishTimeout bounds each attempt to publish a batch.  A nonpositive value, the default,
+// means that attempts are only canceled when Stop gives up waiting on the listener.
+func WithPublishTimeout(v time.Duration) Option {
+	return func(sl *Listener) {
+		sl.publishTimeout = 0
+		if v > 0 {
+			sl.publishTimeout = v
+		}
+	}
+}
+
+// WithNow sets the closure used to timestamp records.  If nil, time.Now is used.
+func WithNow(now func() time.Time) Option {
+	return func(sl *Listener) {
+		if now == nil {
+			sl.now = time.Now
+		} else {
+			sl.now = now
+		}
+	}
+}
+
+// Listener batches device events and publishes them to a Sink on a background goroutine.
+// OnDeviceEvent should be registered as a device.Listener.
+type Listener struct {
+	sink     Sink
+	logger   log.Logger
+	measures Measures
+	now      func() time.Time
+
+	eventTypes     map[device.EventType]bool
+	queueSize      int
+	batchSize      int
+	flushInterval  time.Duration
+	maxRetries     int
+	retryInterval  time.Duration
+	publishTimeout time.Duration
+
+	lock    sync.Mutex
+	records chan Record
+	stop    chan struct{}
+	done    chan struct{}
+	cancel  context.CancelFunc
+}
+
+// New creates a sink Listener that publishes to the given Sink.  This function panics if sink is nil.
+// The returned Listener must be started before any records are published.
+func New(sink Sink, options ...Option) *Listener {
+	if sink == nil {
+		panic("A Sink is required")
+	}
+
+	sl := &Listener{
+		sink: sink,
+	}
+
+	for _, o := range []Option{
+		WithLogger(nil),
+		WithMetricsProvider(nil),
+		WithEventTypes(device.Connect, device.Disconnect, device.MessageFailed),
+		WithQueueSize(DefaultQueueSize),
+		WithBatch(DefaultBatchSize, DefaultFlushInterval),
+		WithRetries(DefaultMaxRetries, DefaultRetryInterval),
+		WithPublishTimeout(0),
+		WithNow(nil),
+	} {
+		o(sl)
+	}
+
+	for _, o := range options {
+		o(sl)
+	}
+
+	return sl
+}
+
+// Start launches the background publishing goroutine.
+func (sl *Listener) Start() error {
+	defer sl.lock.Unlock()
+	sl.lock.Lock()
+
+	if sl.records != nil {
+		return ErrListenerStarted
+	}
+
+	var ctx context.Context
+	ctx, sl.cancel = context.WithCancel(context.Background())
+	sl.records = make(chan Record, sl.queueSize)
+	sl.stop = make(chan struct{})
+	sl.done = make(chan struct{})
+	go sl.run(ctx, sl.records, sl.stop, sl.done)
+	return nil
+}
+
+// Stop halts the background goroutine after attempting to publish any queued records.  The given
+// context bounds how long this method waits for that final publication.  If the context ends first,
+// any publication still in progress is canceled.
+func (sl *Listener) Stop(ctx context.Context) error {
+	sl.lock.Lock()
+	if sl.records == nil {
+		sl.lock.Unlock()
+		return ErrListenerNotStarted
+	}
+
+	stop, done, cancel := sl.stop, sl.done, sl.cancel
+	sl.records, sl.stop, sl.done, sl.cancel = nil, nil, nil, nil
+	sl.lock.Unlock()
+
+	defer cancel()
+	close(stop)
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// newRecord produces the published form of a device event
+func (sl *Listener) newRecord(e *device.Event) Record {
+	r := Record{
+		Type:      e.Type.String(),
+		DeviceID:  string(e.Device.ID()),
+		Timestamp: sl.now().UTC(),
+	}
+
+	if metadata := e.Device.Metadata(); metadata != nil {
+		r.PartnerID = metadata.PartnerIDClaim()
+		r.SessionID = metadata.SessionID()
+	}
+
+	if e.Type == device.Disconnect {
+		r.CloseReason = e.Device.CloseReason().String()
+	}
+
+	if e.Error != nil {
+		r.Error = e.Error.Error()
+	}
+
+	if len(e.Contents) > 0 {
+		r.Format = e.Format.ContentType()
+		r.Contents = make([]byte, len(e.Contents))
+		copy(r.Contents, e.Contents)
+	}
+
+	return r
+}
+
+// OnDeviceEvent is a device.Listener that queues selected events for publication.  This method never
+// blocks.  Events are dropped if this Listener is not started or if its queue is full.
+func (sl *Listener) OnDeviceEvent(e *device.Event) {
+	if !sl.eventTypes[e.Type] {
+		return
+	}
+
+	sl.lock.Lock()
+	records := sl.records
+	sl.lock.Unlock()
+
+	if records == nil {
+		sl.measures.Dropped.Add(1.0)
+		return
+	}
+
+	select {
+	case records <- sl.newRecord(e):
+	default:
+		sl.measures.Dropped.Add(1.0)
+	}
+}
+
+func (sl *Listener) run(ctx context.Context, records <-chan Record, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	var (
+		batch  = make([]Record, 0, sl.batchSize)
+		ticker = time.NewTicker(sl.flushInterval)
+	)
+
+	defer ticker.Stop()
+	for {
+		select {
+		case r := <-records:
+			batch = append(batch, r)
+			if len(batch) >= sl.batchSize {
+				sl.publish(ctx, batch, stop)
+				batch = batch[:0]
+			}
+
+		case <-ticker.C:
+			if len(batch) > 0 {
+				sl.publish(ctx, batch, stop)
+				batch = batch[:0]
+			}
+
+		case <-stop:
+			// drain whatever is left, making a single attempt at delivery
+			for {
+				select {
+				case r := <-records:
+					batch = append(batch, r)
+					if len(batch) >= sl.batchSize {
+						sl.publish(ctx, batch, nil)
+						batch = batch[:0]
+					}
+
+				default:
+					if len(batch) > 0 {
+						sl.publish(ctx, batch, nil)
+					}
+
+					return
+				}
+			}
+		}
+	}
+}
+
+// publish delivers a batch, retrying with an exponential backoff.  A nil stop channel disables retries.
+func (sl *Listener) publish(ctx context.Context, batch []Record, stop <-chan struct{}) {
+	sl.measures.BatchSize.Set(float64(len(batch)))
+	interval := sl.retryInterval
+	for attempt := 0; ; attempt++ {
+		err := sl.attempt(ctx, batch)
+		if err == nil {
+			sl.measures.Published.Add(float64(len(batch)))
+			return
+		}
+
+		if stop == nil || attempt >= sl.maxRetries {
+			logging.Error(sl.logger).Log(logging.MessageKey(), "unable to publish device events", "count", len(batch), logging.ErrorKey(), err)
+			sl.measures.Failed.Add(float64(len(batch)))
+			return
+		}
+
+		sl.measures.Retries.Add(1.0)
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-stop:
+			timer.Stop()
+			stop = nil
+		}
+
+		interval *= 2
+	}
+}
+
+// attempt makes a single call to the Sink, bounded by the publish timeout if one is configured
+func (sl *Listener) attempt(ctx context.Context, batch []Record) error {
+	if sl.publishTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, sl.publishTimeout)
+		defer cancel()
+	}
+
+	return sl.sink.Publish(ctx, batch)
+}
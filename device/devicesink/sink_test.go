@@ -0,0 +1,290 @@
+package devicesink
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/device"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/wrp-go/v3"
+)
+
+// captureSink records each published batch and fails the first failures attempts
+type captureSink struct {
+	lock     sync.Mutex
+	failures int
+	batches  [][]Record
+	attempts int
+}
+
+func (cs *captureSink) Publish(_ context.Context, records []Record) error {
+	defer cs.lock.Unlock()
+	cs.lock.Lock()
+
+	cs.attempts++
+	if cs.failures > 0 {
+		cs.failures--
+		return errors.New("expected")
+	}
+
+	batch := make([]Record, len(records))
+	copy(batch, records)
+	cs.batches = append(cs.batches, batch)
+	return nil
+}
+
+func (cs *captureSink) published() (batches [][]Record, attempts int) {
+	defer cs.lock.Unlock()
+	cs.lock.Lock()
+	return cs.batches, cs.attempts
+}
+
+func newTestDevice(id device.ID) *device.MockDevice {
+	var (
+		d        = new(device.MockDevice)
+		metadata = new(device.Metadata)
+	)
+
+	metadata.SetClaims(map[string]interface{}{device.PartnerIDClaimKey: "comcast"})
+	metadata.SetSessionID("session")
+	d.On("ID").Return(id)
+	d.On("Metadata").Return(metadata)
+	d.On("CloseReason").Return(device.CloseReason{Text: "test"})
+	return d
+}
+
+func TestNewPanics(t *testing.T) {
+	assert.Panics(t, func() { New(nil) })
+}
+
+func TestListenerNewRecord(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		now      = time.Now()
+		listener = New(new(captureSink), WithNow(func() time.Time { return now }))
+		d        = newTestDevice(device.ID("mac:112233445566"))
+		contents = []byte("contents")
+	)
+
+	r := listener.newRecord(&device.Event{
+		Type:     device.Disconnect,
+		Device:   d,
+		Format:   wrp.Msgpack,
+		Contents: contents,
+		Error:    errors.New("expected"),
+	})
+
+	contents[0] = 'X'
+	assert.Equal(
+		Record{
+			Type:        "Disconnect",
+			DeviceID:    "mac:112233445566",
+			Timestamp:   now.UTC(),
+			PartnerID:   "comcast",
+			SessionID:   "session",
+			CloseReason: "*no error*:test",
+			Error:       "expected",
+			Format:      wrp.Msgpack.ContentType(),
+			Contents:    []byte("contents"),
+		},
+		r,
+	)
+}
+
+func TestListenerBatching(t *testing.T) {
+	var (
+		assert    = assert.New(t)
+		require   = require.New(t)
+		sink      = new(captureSink)
+		published = generic.NewCounter("published")
+		listener  = New(sink, WithLogger(logging.NewTestLogger(nil, t)), WithBatch(2, time.Hour))
+		d         = newTestDevice(device.ID("mac:112233445566"))
+	)
+
+	listener.measures.Published = published
+	require.Equal(ErrListenerNotStarted, listener.Stop(context.Background()))
+	require.NoError(listener.Start())
+	require.Equal(ErrListenerStarted, listener.Start())
+
+	listener.OnDeviceEvent(&device.Event{Type: device.Connect, Device: d})
+	listener.OnDeviceEvent(&device.Event{Type: device.MessageSent, Device: d})
+	listener.OnDeviceEvent(&device.Event{Type: device.MessageFailed, Device: d})
+	listener.OnDeviceEvent(&device.Event{Type: device.Disconnect, Device: d})
+
+	require.NoError(listener.Stop(context.Background()))
+	batches, _ := sink.published()
+	require.Len(batches, 2)
+	assert.Equal("Connect", batches[0][0].Type)
+	assert.Equal("MessageFailed", batches[0][1].Type)
+	assert.Equal("Disconnect", batches[1][0].Type)
+	assert.Equal(3.0, published.Value())
+
+	// events after stopping are dropped
+	dropped := generic.NewCounter("dropped")
+	listener.measures.Dropped = dropped
+	listener.OnDeviceEvent(&device.Event{Type: device.Connect, Device: d})
+	assert.Equal(1.0, dropped.Value())
+}
+
+func TestListenerFlushInterval(t *testing.T) {
+	var (
+		require  = require.New(t)
+		sink     = new(captureSink)
+		listener = New(sink, WithBatch(100, 10*time.Millisecond), WithEventTypes(device.Connect))
+		d        = newTestDevice(device.ID("mac:112233445566"))
+	)
+
+	require.NoError(listener.Start())
+	defer listener.Stop(context.Background())
+
+	listener.OnDeviceEvent(&device.Event{Type: device.Connect, Device: d})
+	require.Eventually(
+		func() bool {
+			batches, _ := sink.published()
+			return len(batches) == 1
+		},
+		5*time.Second,
+		10*time.Millisecond,
+	)
+}
+
+func TestListenerRetry(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		require  = require.New(t)
+		sink     = &captureSink{failures: 2}
+		retries  = generic.NewCounter("retries")
+		failed   = generic.NewCounter("failed")
+		listener = New(sink, WithLogger(logging.NewTestLogger(nil, t)), WithBatch(1, time.Hour), WithRetries(1, time.Millisecond))
+		d        = newTestDevice(device.ID("mac:112233445566"))
+	)
+
+	listener.measures.Retries = retries
+	listener.measures.Failed = failed
+	require.NoError(listener.Start())
+
+	// the first record exhausts its retries, the second is delivered
+	listener.OnDeviceEvent(&device.Event{Type: device.Connect, Device: d})
+	listener.OnDeviceEvent(&device.Event{Type: device.Disconnect, Device: d})
+	require.Eventually(
+		func() bool {
+			batches, _ := sink.published()
+			return len(batches) == 1
+		},
+		5*time.Second,
+		time.Millisecond,
+	)
+
+	require.NoError(listener.Stop(context.Background()))
+	batches, attempts := sink.published()
+	assert.Equal(3, attempts)
+	assert.Equal("Disconnect", batches[0][0].Type)
+	assert.Equal(1.0, retries.Value())
+	assert.Equal(1.0, failed.Value())
+}
+
+func TestListenerQueueFull(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		dropped  = generic.NewCounter("dropped")
+		listener = New(new(captureSink), WithQueueSize(1))
+		d        = newTestDevice(device.ID("mac:112233445566"))
+	)
+
+	// simulate a started listener whose goroutine isn't consuming
+	listener.measures.Dropped = dropped
+	listener.records = make(chan Record, 1)
+
+	listener.OnDeviceEvent(&device.Event{Type: device.Connect, Device: d})
+	assert.Zero(dropped.Value())
+	listener.OnDeviceEvent(&device.Event{Type: device.Connect, Device: d})
+	assert.Equal(1.0, dropped.Value())
+}
+
+// blockingSink blocks each Publish until its context ends, reporting the context's error
+type blockingSink struct {
+	called chan struct{}
+	errs   chan error
+}
+
+func newBlockingSink() *blockingSink {
+	return &blockingSink{
+		called: make(chan struct{}, 10),
+		errs:   make(chan error, 10),
+	}
+}
+
+func (bs *blockingSink) Publish(ctx context.Context, _ []Record) error {
+	bs.called <- struct{}{}
+	<-ctx.Done()
+	bs.errs <- ctx.Err()
+	return ctx.Err()
+}
+
+func TestListenerStopCancelsPublish(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		require  = require.New(t)
+		sink     = newBlockingSink()
+		listener = New(sink, WithLogger(logging.NewTestLogger(nil, t)), WithBatch(1, time.Hour), WithRetries(0, time.Millisecond))
+		d        = newTestDevice(device.ID("mac:112233445566"))
+	)
+
+	require.NoError(listener.Start())
+	listener.OnDeviceEvent(&device.Event{Type: device.Connect, Device: d})
+
+	select {
+	case <-sink.called:
+	case <-time.After(5 * time.Second):
+		require.Fail("Publish was not called")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(context.DeadlineExceeded, listener.Stop(ctx))
+
+	select {
+	case err := <-sink.errs:
+		assert.Equal(context.Canceled, err)
+	case <-time.After(5 * time.Second):
+		assert.Fail("Stop did not cancel the publication in progress")
+	}
+}
+
+func TestListenerPublishTimeout(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		require  = require.New(t)
+		sink     = newBlockingSink()
+		failed   = generic.NewCounter("failed")
+		listener = New(
+			sink,
+			WithLogger(logging.NewTestLogger(nil, t)),
+			WithBatch(1, time.Hour),
+			WithRetries(0, time.Millisecond),
+			WithPublishTimeout(10*time.Millisecond),
+		)
+
+		d = newTestDevice(device.ID("mac:112233445566"))
+	)
+
+	listener.measures.Failed = failed
+	require.NoError(listener.Start())
+	listener.OnDeviceEvent(&device.Event{Type: device.Connect, Device: d})
+
+	select {
+	case err := <-sink.errs:
+		assert.Equal(context.DeadlineExceeded, err)
+	case <-time.After(5 * time.Second):
+		assert.Fail("The publish timeout was not applied")
+	}
+
+	require.NoError(listener.Stop(context.Background()))
+	assert.Equal(1.0, failed.Value())
+}
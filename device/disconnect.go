@@ -0,0 +1,90 @@
+package device
+
+import (
+	"context"
+
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+// DisconnectProgress describes how far an asynchronous DisconnectIf has gotten
+type DisconnectProgress struct {
+	// Total is the number of devices connected when the operation started
+	Total int `json:"total"`
+
+	// Visited is the number of devices to which the predicate has been applied so far
+	Visited int `json:"visited"`
+
+	// Disconnected is the number of devices disconnected so far
+	Disconnected int `json:"disconnected"`
+
+	// Done is true for the final progress report
+	Done bool `json:"done"`
+
+	// Err is the context's error if the operation was cancelled before all devices were visited
+	Err error `json:"-"`
+}
+
+// reportProgress sends the latest progress, replacing any report that hasn't been received yet.  The
+// channel must have a buffer of 1, and this goroutine must be the channel's only sender.
+func reportProgress(progress chan DisconnectProgress, p DisconnectProgress) {
+	select {
+	case progress <- p:
+	default:
+		select {
+		case <-progress:
+		default:
+		}
+
+		progress <- p
+	}
+}
+
+func (m *manager) DisconnectIfAsync(ctx context.Context, filter func(ID) (CloseReason, bool)) <-chan DisconnectProgress {
+	var ids []ID
+	m.devices.visit(func(d *device) bool {
+		ids = append(ids, d.id)
+		return true
+	})
+
+	progress := make(chan DisconnectProgress, 1)
+	go func() {
+		defer close(progress)
+
+		p := DisconnectProgress{Total: len(ids)}
+		for len(ids) > 0 {
+			select {
+			case <-ctx.Done():
+				p.Done = true
+				p.Err = ctx.Err()
+				m.logger.Log(logging.MessageKey(), "asynchronous disconnect cancelled", "total", p.Total, "visited", p.Visited, "disconnected", p.Disconnected)
+				reportProgress(progress, p)
+				return
+			default:
+			}
+
+			batch := ids
+			if len(batch) > m.disconnectBatchSize {
+				batch = batch[:m.disconnectBatchSize]
+			}
+
+			ids = ids[len(batch):]
+			for _, id := range batch {
+				p.Visited++
+				if reason, ok := filter(id); ok {
+					if _, removed := m.devices.remove(id, reason); removed {
+						p.Disconnected++
+					}
+				}
+			}
+
+			if len(ids) > 0 {
+				reportProgress(progress, p)
+			}
+		}
+
+		p.Done = true
+		reportProgress(progress, p)
+	}()
+
+	return progress
+}
@@ -0,0 +1,105 @@
+package device
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+func newDisconnectTestManager(t *testing.T, count int) *manager {
+	m := NewManager(&Options{
+		Logger:              logging.NewTestLogger(nil, t),
+		DisconnectBatchSize: 2,
+	}).(*manager)
+
+	for i := 0; i < count; i++ {
+		require.NoError(t, m.devices.add(newDevice(deviceOptions{ID: ID(strconv.Itoa(i)), Logger: m.logger})))
+	}
+
+	return m
+}
+
+func TestReportProgress(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		progress = make(chan DisconnectProgress, 1)
+	)
+
+	reportProgress(progress, DisconnectProgress{Visited: 1})
+	reportProgress(progress, DisconnectProgress{Visited: 2})
+	assert.Equal(DisconnectProgress{Visited: 2}, <-progress)
+	assert.Empty(progress)
+}
+
+func testDisconnectIfAsyncComplete(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		m      = newDisconnectTestManager(t, 5)
+
+		progress = m.DisconnectIfAsync(context.Background(), func(id ID) (CloseReason, bool) {
+			i, _ := strconv.Atoi(string(id))
+			return CloseReason{Reason: DisconnectRequested}, i%2 == 0
+		})
+
+		last DisconnectProgress
+	)
+
+	for p := range progress {
+		assert.True(p.Visited >= last.Visited)
+		assert.False(last.Done)
+		last = p
+	}
+
+	assert.Equal(DisconnectProgress{Total: 5, Visited: 5, Disconnected: 3, Done: true}, last)
+	assert.Equal(2, m.Len())
+
+	for _, id := range []ID{"1", "3"} {
+		_, ok := m.Get(id)
+		assert.True(ok)
+	}
+}
+
+func testDisconnectIfAsyncCancelled(t *testing.T) {
+	var (
+		assert      = assert.New(t)
+		m           = newDisconnectTestManager(t, 5)
+		ctx, cancel = context.WithCancel(context.Background())
+	)
+
+	cancel()
+	progress := m.DisconnectIfAsync(ctx, func(ID) (CloseReason, bool) {
+		return CloseReason{}, true
+	})
+
+	p, ok := <-progress
+	assert.True(ok)
+	assert.Equal(DisconnectProgress{Total: 5, Done: true, Err: context.Canceled}, p)
+
+	_, ok = <-progress
+	assert.False(ok)
+	assert.Equal(5, m.Len())
+}
+
+func testDisconnectIfAsyncEmpty(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		m      = newDisconnectTestManager(t, 0)
+	)
+
+	progress := m.DisconnectIfAsync(context.Background(), func(ID) (CloseReason, bool) {
+		assert.Fail("The predicate should not be called")
+		return CloseReason{}, false
+	})
+
+	assert.Equal(DisconnectProgress{Done: true}, <-progress)
+}
+
+func TestDisconnectIfAsync(t *testing.T) {
+	t.Run("Complete", testDisconnectIfAsyncComplete)
+	t.Run("Cancelled", testDisconnectIfAsyncCancelled)
+	t.Run("Empty", testDisconnectIfAsyncEmpty)
+}
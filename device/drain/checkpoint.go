@@ -0,0 +1,100 @@
+package drain
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Checkpoint is the persisted state of a drain job, sufficient to resume that job after a restart.
+type Checkpoint struct {
+	Job      Job      `json:"job"`
+	Progress Progress `json:"progress"`
+}
+
+// Store is the strategy for persisting drain job checkpoints.  A drainer saves a checkpoint after each
+// batch of devices and when paused, and clears it when a job finishes or is cancelled.
+type Store interface {
+	// Save persists the given checkpoint, replacing any previous checkpoint
+	Save(Checkpoint) error
+
+	// Load returns the most recently saved checkpoint.  If there is no checkpoint, this method
+	// returns nil with no error.
+	Load() (*Checkpoint, error)
+
+	// Clear removes any saved checkpoint
+	Clear() error
+}
+
+// FileStore is a Store which writes checkpoints as JSON to a file.  Saves are atomic with respect to
+// crashes, as each checkpoint is written to a temporary file that then replaces the original.
+type FileStore struct {
+	// Path is the file in which checkpoints are stored.  This field is required.
+	Path string
+
+	lock sync.Mutex
+}
+
+var _ Store = (*FileStore)(nil)
+
+func (fs *FileStore) Save(c Checkpoint) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	temp, err := ioutil.TempFile(filepath.Dir(fs.Path), filepath.Base(fs.Path)+".*")
+	if err != nil {
+		return err
+	}
+
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(temp.Name(), fs.Path)
+	}
+
+	if err != nil {
+		os.Remove(temp.Name())
+	}
+
+	return err
+}
+
+func (fs *FileStore) Load() (*Checkpoint, error) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	data, err := ioutil.ReadFile(fs.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	c := new(Checkpoint)
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (fs *FileStore) Clear() error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	if err := os.Remove(fs.Path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
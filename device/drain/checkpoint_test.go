@@ -0,0 +1,65 @@
+package drain
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+	)
+
+	dir, err := ioutil.TempDir("", "drain")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	var (
+		fs       = &FileStore{Path: filepath.Join(dir, "checkpoint.json")}
+		started  = time.Date(2019, 11, 5, 12, 30, 0, 0, time.UTC)
+		expected = Checkpoint{
+			Job:      Job{Count: 100, Percent: 10, Rate: 5, Tick: time.Minute},
+			Progress: Progress{Visited: 35, Drained: 30, Started: started, Paused: true},
+		}
+	)
+
+	c, err := fs.Load()
+	assert.Nil(c)
+	assert.NoError(err)
+	assert.NoError(fs.Clear())
+
+	require.NoError(fs.Save(expected))
+	c, err = fs.Load()
+	require.NoError(err)
+	require.NotNil(c)
+	assert.Equal(expected, *c)
+
+	expected.Progress.Visited = 40
+	require.NoError(fs.Save(expected))
+	c, err = fs.Load()
+	require.NoError(err)
+	require.NotNil(c)
+	assert.Equal(expected, *c)
+
+	// no temporary files should be left behind
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(err)
+	assert.Len(files, 1)
+
+	require.NoError(fs.Clear())
+	c, err = fs.Load()
+	assert.Nil(c)
+	assert.NoError(err)
+
+	require.NoError(ioutil.WriteFile(fs.Path, []byte("this is not JSON"), 0644))
+	c, err = fs.Load()
+	assert.Nil(c)
+	assert.Error(err)
+}
@@ -18,11 +18,14 @@ import (
 var (
 	ErrActive    error = errors.New("A drain operation is already running")
 	ErrNotActive error = errors.New("No drain operation is running")
+	ErrPaused    error = errors.New("A drain operation is paused")
+	ErrNotPaused error = errors.New("No drain operation is paused")
 )
 
 const (
 	StateNotActive uint32 = 0
 	StateActive    uint32 = 1
+	StatePaused    uint32 = 2
 
 	MetricNotDraining float64 = 0.0
 	MetricDraining    float64 = 1.0
@@ -97,6 +100,14 @@ func WithDrainCounter(a xmetrics.Adder) Option {
 	}
 }
 
+// WithStore configures the Store used to checkpoint drain jobs.  When a drainer is created with a Store
+// that holds a checkpoint, the checkpointed job is restored in a paused state and can be resumed.
+func WithStore(s Store) Option {
+	return func(dr *drainer) {
+		dr.store = s
+	}
+}
+
 type Job struct {
 	// Count is the total number of devices to disconnect.  If this field is nonpositive and percent is unset,
 	// the count of connected devices at the start of job execution is used.  If Percent is set, this field's
@@ -115,6 +126,10 @@ type Job struct {
 	// Tick is the time unit for the Rate field.  If Rate is set but this field is not set,
 	// a tick of 1 second is used as the default.
 	Tick time.Duration `json:"tick,omitempty" schema:"tick"`
+
+	// Filter restricts this job to devices whose metadata matches.  When set, Count and Percent
+	// are relative to the number of matching devices rather than all connected devices.
+	Filter *device.MetadataQuery `json:"filter,omitempty" schema:"-"`
 }
 
 // ToMap returns a map representation of this Job appropriate for marshaling to formats like JSON.
@@ -136,9 +151,18 @@ func (j Job) ToMap() map[string]interface{} {
 		m["tick"] = j.Tick.String()
 	}
 
+	if j.Filter != nil {
+		m["filter"] = j.Filter
+	}
+
 	return m
 }
 
+// matches tests if the given device is subject to this job's Filter
+func (j Job) matches(d device.Interface) bool {
+	return j.Filter == nil || j.Filter.Matches(d)
+}
+
 // normalize applies some basic logic to interpret defaults and set values appropriately for a given device count
 func (j *Job) normalize(deviceCount int) {
 	if j.Percent > 0 {
@@ -172,9 +196,17 @@ type Interface interface {
 	// may be computed or defaulted.
 	Status() (bool, Job, Progress)
 
-	// Cancel asynchronously halts any running drain job.  The returned channel can be used to wait for the job to actually exit.
-	// If no job is running, an error is returned along with a nil channel.
+	// Cancel asynchronously halts any running or paused drain job.  The returned channel can be used to wait for the job to
+	// actually exit.  If no job is running or paused, an error is returned along with a nil channel.
 	Cancel() (<-chan struct{}, error)
+
+	// Pause asynchronously halts the running drain job, retaining its progress so that it can be resumed.  The returned
+	// channel can be used to wait for the job to actually stop.  If no job is running, an error is returned along with a nil channel.
+	Pause() (<-chan struct{}, error)
+
+	// Resume continues a paused drain job from where it left off.  The returned channel and Job have the same
+	// semantics as Start.  If no job is paused, an error is returned.
+	Resume() (<-chan struct{}, Job, error)
 }
 
 func defaultNewTicker(d time.Duration) (<-chan time.Time, func()) {
@@ -207,6 +239,7 @@ func New(options ...Option) Interface {
 	}
 
 	dr.m.state.Set(MetricNotDraining)
+	dr.restore()
 	return dr
 }
 
@@ -236,6 +269,7 @@ type drainer struct {
 	now       func() time.Time
 	newTicker func(time.Duration) (<-chan time.Time, func())
 	m         metrics
+	store     Store
 
 	controlLock sync.RWMutex
 	active      uint32
@@ -251,6 +285,10 @@ func (dr *drainer) nextBatch(jc jobContext, batch chan device.ID) (more bool, vi
 
 	more = true
 	dr.registry.VisitAll(func(d device.Interface) bool {
+		if !jc.j.matches(d) {
+			return true
+		}
+
 		select {
 		case batch <- d.ID():
 			return true
@@ -274,7 +312,7 @@ func (dr *drainer) nextBatch(jc jobContext, batch chan device.ID) (more bool, vi
 		for finished := false; more && !finished; {
 			select {
 			case id := <-batch:
-				if dr.connector.Disconnect(id, device.CloseReason{Text: Drained}) {
+				if dr.connector.Disconnect(id, device.CloseReason{Reason: device.DisconnectDrain, Text: Drained}) {
 					drained++
 				}
 			case <-jc.cancel:
@@ -288,6 +326,13 @@ func (dr *drainer) nextBatch(jc jobContext, batch chan device.ID) (more bool, vi
 		jc.logger.Log(level.Key(), level.DebugValue(), logging.MessageKey(), "nextBatch", "visited", visited, "drained", drained)
 		jc.t.addVisited(visited)
 		jc.t.addDrained(drained)
+
+		dr.controlLock.RLock()
+		if jc.id == dr.currentID {
+			dr.saveCheckpoint(jc)
+		}
+
+		dr.controlLock.RUnlock()
 	} else {
 		// if no devices were visited (or enqueued), then we must be done.
 		// either a cancellation occurred or no devices are left
@@ -298,17 +343,80 @@ func (dr *drainer) nextBatch(jc jobContext, batch chan device.ID) (more bool, vi
 	return
 }
 
+// restore loads any checkpointed job from the store, placing this drainer into the paused state
+func (dr *drainer) restore() {
+	if dr.store == nil {
+		return
+	}
+
+	c, err := dr.store.Load()
+	if err != nil {
+		dr.logger.Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "unable to load drain checkpoint", logging.ErrorKey(), err)
+		return
+	} else if c == nil {
+		return
+	}
+
+	c.Progress.Paused = true
+	c.Progress.Finished = nil
+
+	// the restored job has no goroutine, so its done channel is already closed
+	done := make(chan struct{})
+	close(done)
+
+	dr.currentID++
+	dr.current.Store(jobContext{
+		id:     dr.currentID,
+		logger: log.With(dr.logger, "id", dr.currentID),
+		t:      restoreTracker(c.Progress, dr.m.counter),
+		j:      c.Job,
+		done:   done,
+	})
+
+	atomic.StoreUint32(&dr.active, StatePaused)
+	dr.logger.Log(level.Key(), level.InfoValue(), logging.MessageKey(), "restored paused drain job from checkpoint", "count", c.Job.Count, "visited", c.Progress.Visited)
+}
+
+// saveCheckpoint persists the state of the given job, if a Store is configured.  The control lock must be held.
+func (dr *drainer) saveCheckpoint(jc jobContext) {
+	if dr.store == nil {
+		return
+	}
+
+	if err := dr.store.Save(Checkpoint{Job: jc.j, Progress: jc.t.Progress()}); err != nil {
+		jc.logger.Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "unable to save drain checkpoint", logging.ErrorKey(), err)
+	}
+}
+
+// clearCheckpoint removes any persisted job state, if a Store is configured.  The control lock must be held.
+func (dr *drainer) clearCheckpoint(jc jobContext) {
+	if dr.store == nil {
+		return
+	}
+
+	if err := dr.store.Clear(); err != nil {
+		jc.logger.Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "unable to clear drain checkpoint", logging.ErrorKey(), err)
+	}
+}
+
 func (dr *drainer) jobFinished(jc jobContext) {
 	if jc.stop != nil {
 		jc.stop()
 	}
 
-	jc.t.done(dr.now().UTC())
-
-	// we need to contend on the control lock to avoid clobbering state from Start/Cancel code
+	// we need to contend on the control lock to avoid clobbering state from Start/Cancel/Pause code
 	dr.controlLock.Lock()
-	if jc.id == dr.currentID && atomic.CompareAndSwapUint32(&dr.active, StateActive, StateNotActive) {
-		dr.m.state.Set(MetricNotDraining)
+	paused := jc.id == dr.currentID && atomic.LoadUint32(&dr.active) == StatePaused
+	if paused {
+		dr.saveCheckpoint(jc)
+	} else {
+		jc.t.done(dr.now().UTC())
+		if jc.id == dr.currentID {
+			dr.clearCheckpoint(jc)
+			if atomic.CompareAndSwapUint32(&dr.active, StateActive, StateNotActive) {
+				dr.m.state.Set(MetricNotDraining)
+			}
+		}
 	}
 
 	dr.controlLock.Unlock()
@@ -317,7 +425,11 @@ func (dr *drainer) jobFinished(jc jobContext) {
 	close(jc.done)
 
 	p := jc.t.Progress()
-	jc.logger.Log(level.Key(), level.InfoValue(), logging.MessageKey(), "drain complete", "visited", p.Visited, "drained", p.Drained)
+	if paused {
+		jc.logger.Log(level.Key(), level.InfoValue(), logging.MessageKey(), "drain paused", "visited", p.Visited, "drained", p.Drained)
+	} else {
+		jc.logger.Log(level.Key(), level.InfoValue(), logging.MessageKey(), "drain complete", "visited", p.Visited, "drained", p.Drained)
+	}
 }
 
 // drain is run as a goroutine to drain devices at a particular rate
@@ -326,7 +438,7 @@ func (dr *drainer) drain(jc jobContext) {
 	jc.logger.Log(level.Key(), level.InfoValue(), logging.MessageKey(), "drain starting", "count", jc.j.Count, "rate", jc.j.Rate, "tick", jc.j.Tick)
 
 	var (
-		remaining = jc.j.Count
+		remaining = jc.j.Count - jc.t.Progress().Visited
 		visited   = 0
 		more      = true
 		batch     = make(chan device.ID, jc.j.Rate)
@@ -354,7 +466,7 @@ func (dr *drainer) disconnect(jc jobContext) {
 	jc.logger.Log(level.Key(), level.InfoValue(), logging.MessageKey(), "drain starting", "count", jc.j.Count)
 
 	var (
-		remaining = jc.j.Count
+		remaining = jc.j.Count - jc.t.Progress().Visited
 		visited   = 0
 		more      = true
 		batch     = make(chan device.ID, jc.batchSize)
@@ -370,13 +482,35 @@ func (dr *drainer) disconnect(jc jobContext) {
 	}
 }
 
+// deviceCount returns the number of connected devices subject to the given job
+func (dr *drainer) deviceCount(j Job) int {
+	if j.Filter == nil {
+		return dr.registry.Len()
+	}
+
+	count := 0
+	dr.registry.VisitAll(func(d device.Interface) bool {
+		if j.Filter.Matches(d) {
+			count++
+		}
+
+		return true
+	})
+
+	return count
+}
+
 func (dr *drainer) Start(j Job) (<-chan struct{}, Job, error) {
-	j.normalize(dr.registry.Len())
+	j.normalize(dr.deviceCount(j))
 
 	defer dr.controlLock.Unlock()
 	dr.controlLock.Lock()
 
 	if !atomic.CompareAndSwapUint32(&dr.active, StateNotActive, StateActive) {
+		if atomic.LoadUint32(&dr.active) == StatePaused {
+			return nil, Job{}, ErrPaused
+		}
+
 		return nil, Job{}, ErrActive
 	}
 
@@ -393,17 +527,22 @@ func (dr *drainer) Start(j Job) (<-chan struct{}, Job, error) {
 		done:   make(chan struct{}),
 	}
 
+	dr.launch(&jc)
+	return jc.done, jc.j, nil
+}
+
+// launch starts the goroutine for a job.  The control lock must be held.
+func (dr *drainer) launch(jc *jobContext) {
 	if jc.j.Rate > 0 {
-		jc.ticker, jc.stop = dr.newTicker(j.Tick)
-		go dr.drain(jc)
+		jc.ticker, jc.stop = dr.newTicker(jc.j.Tick)
+		go dr.drain(*jc)
 	} else {
 		jc.batchSize = disconnectBatchSize
-		go dr.disconnect(jc)
+		go dr.disconnect(*jc)
 	}
 
 	dr.m.state.Set(MetricDraining)
-	dr.current.Store(jc)
-	return jc.done, jc.j, nil
+	dr.current.Store(*jc)
 }
 
 func (dr *drainer) Status() (bool, Job, Progress) {
@@ -424,6 +563,15 @@ func (dr *drainer) Cancel() (<-chan struct{}, error) {
 	defer dr.controlLock.Unlock()
 	dr.controlLock.Lock()
 
+	if atomic.CompareAndSwapUint32(&dr.active, StatePaused, StateNotActive) {
+		// a paused job has already been stopped, so it only needs to be finalized
+		jc := dr.current.Load().(jobContext)
+		jc.t.setPaused(false)
+		jc.t.done(dr.now().UTC())
+		dr.clearCheckpoint(jc)
+		return jc.done, nil
+	}
+
 	if !atomic.CompareAndSwapUint32(&dr.active, StateActive, StateNotActive) {
 		return nil, ErrNotActive
 	}
@@ -433,3 +581,51 @@ func (dr *drainer) Cancel() (<-chan struct{}, error) {
 	close(jc.cancel)
 	return jc.done, nil
 }
+
+func (dr *drainer) Pause() (<-chan struct{}, error) {
+	defer dr.controlLock.Unlock()
+	dr.controlLock.Lock()
+
+	if !atomic.CompareAndSwapUint32(&dr.active, StateActive, StatePaused) {
+		return nil, ErrNotActive
+	}
+
+	dr.m.state.Set(MetricNotDraining)
+	jc := dr.current.Load().(jobContext)
+	jc.t.setPaused(true)
+	close(jc.cancel)
+	return jc.done, nil
+}
+
+func (dr *drainer) Resume() (<-chan struct{}, Job, error) {
+	defer dr.controlLock.Unlock()
+	dr.controlLock.Lock()
+
+	if atomic.LoadUint32(&dr.active) != StatePaused {
+		return nil, Job{}, ErrNotPaused
+	}
+
+	previous := dr.current.Load().(jobContext)
+	select {
+	case <-previous.done:
+	default:
+		// the paused job's goroutine is still winding down
+		return nil, Job{}, ErrActive
+	}
+
+	atomic.StoreUint32(&dr.active, StateActive)
+	previous.t.setPaused(false)
+
+	dr.currentID++
+	jc := jobContext{
+		id:     dr.currentID,
+		logger: log.With(dr.logger, "id", dr.currentID),
+		t:      previous.t,
+		j:      previous.j,
+		cancel: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	dr.launch(&jc)
+	return jc.done, jc.j, nil
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/device"
 	"github.com/xmidt-org/webpa-common/logging"
 	"github.com/xmidt-org/webpa-common/xmetrics/xmetricstest"
 )
@@ -40,8 +41,22 @@ func testJobNormalize(t *testing.T) {
 	}
 }
 
+func testJobToMap(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		filter = &device.MetadataQuery{Claims: map[string]string{device.PartnerIDClaimKey: "comcast"}}
+	)
+
+	assert.Equal(map[string]interface{}{"count": 0}, Job{}.ToMap())
+	assert.Equal(
+		map[string]interface{}{"count": 10, "percent": 5, "rate": 2, "tick": "1m0s", "filter": filter},
+		Job{Count: 10, Percent: 5, Rate: 2, Tick: time.Minute, Filter: filter}.ToMap(),
+	)
+}
+
 func TestJob(t *testing.T) {
 	t.Run("Normalize", testJobNormalize)
+	t.Run("ToMap", testJobToMap)
 }
 
 func testWithLoggerDefault(t *testing.T) {
@@ -582,6 +597,256 @@ func testDrainerDrainCancel(t *testing.T) {
 	assert.True(stopCalled)
 }
 
+func testDrainerPauseResume(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		require  = require.New(t)
+		provider = xmetricstest.NewProvider(nil)
+		logger   = logging.NewTestLogger(nil, t)
+		store    = new(memoryStore)
+
+		manager = generateManager(assert, 20)
+		ticker  = make(chan time.Time, 1)
+
+		d = New(
+			WithLogger(logger),
+			WithManager(manager),
+			WithStateGauge(provider.NewGauge("state")),
+			WithDrainCounter(provider.NewCounter("counter")),
+			WithStore(store),
+		)
+	)
+
+	require.NotNil(d)
+	defer d.Cancel()
+
+	close(manager.pauseVisit)
+	close(manager.pauseDisconnect)
+	d.(*drainer).newTicker = func(time.Duration) (<-chan time.Time, func()) {
+		return ticker, func() {}
+	}
+
+	_, err := d.Pause()
+	assert.Equal(ErrNotActive, err)
+
+	_, _, err = d.Resume()
+	assert.Equal(ErrNotPaused, err)
+
+	_, job, err := d.Start(Job{Count: 10, Rate: 5})
+	require.NoError(err)
+
+	ticker <- time.Time{}
+	require.Eventually(
+		func() bool {
+			_, _, p := d.Status()
+			return p.Visited == 5
+		},
+		5*time.Second,
+		10*time.Millisecond,
+	)
+
+	done, err := d.Pause()
+	require.NoError(err)
+	select {
+	case <-done:
+		// passing
+	case <-time.After(5 * time.Second):
+		assert.Fail("Drain failed to pause")
+		return
+	}
+
+	active, pausedJob, p := d.Status()
+	assert.False(active)
+	assert.Equal(job, pausedJob)
+	assert.True(p.Paused)
+	assert.Nil(p.Finished)
+	assert.Equal(5, p.Visited)
+	assert.Equal(5, p.Drained)
+	provider.Assert(t, "state")(xmetricstest.Value(MetricNotDraining))
+
+	checkpoint, err := store.Load()
+	require.NoError(err)
+	require.NotNil(checkpoint)
+	assert.Equal(job, checkpoint.Job)
+	assert.Equal(p, checkpoint.Progress)
+
+	_, _, err = d.Start(Job{Count: 10})
+	assert.Equal(ErrPaused, err)
+
+	_, err = d.Pause()
+	assert.Equal(ErrNotActive, err)
+
+	done, resumedJob, err := d.Resume()
+	require.NoError(err)
+	assert.Equal(job, resumedJob)
+	provider.Assert(t, "state")(xmetricstest.Value(MetricDraining))
+
+	ticker <- time.Time{}
+	select {
+	case <-done:
+		// passing
+	case <-time.After(5 * time.Second):
+		assert.Fail("Drain failed to complete")
+		return
+	}
+
+	active, _, p = d.Status()
+	assert.False(active)
+	assert.False(p.Paused)
+	assert.NotNil(p.Finished)
+	assert.Equal(10, p.Visited)
+	assert.Equal(10, p.Drained)
+	assert.Equal(10, manager.Len())
+	provider.Assert(t, "state")(xmetricstest.Value(MetricNotDraining))
+	provider.Assert(t, "counter")(xmetricstest.Value(10.0))
+
+	checkpoint, err = store.Load()
+	assert.Nil(checkpoint)
+	assert.NoError(err)
+}
+
+func testDrainerRestore(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		logger  = logging.NewTestLogger(nil, t)
+		started = time.Now().UTC()
+		store   = &memoryStore{
+			checkpoint: &Checkpoint{
+				Job:      Job{Count: 15},
+				Progress: Progress{Visited: 10, Drained: 9, Started: started},
+			},
+		}
+
+		manager = generateManager(assert, 20)
+
+		d = New(
+			WithLogger(logger),
+			WithManager(manager),
+			WithStore(store),
+		)
+	)
+
+	require.NotNil(d)
+	close(manager.pauseVisit)
+	close(manager.pauseDisconnect)
+
+	active, job, p := d.Status()
+	assert.False(active)
+	assert.Equal(Job{Count: 15}, job)
+	assert.Equal(Progress{Visited: 10, Drained: 9, Started: started, Paused: true}, p)
+
+	_, _, err := d.Start(Job{Count: 10})
+	assert.Equal(ErrPaused, err)
+
+	done, job, err := d.Resume()
+	require.NoError(err)
+	assert.Equal(Job{Count: 15}, job)
+
+	select {
+	case <-done:
+		// passing
+	case <-time.After(5 * time.Second):
+		assert.Fail("Drain failed to complete")
+		return
+	}
+
+	_, _, p = d.Status()
+	assert.Equal(15, p.Visited)
+	assert.Equal(14, p.Drained)
+	assert.Equal(15, manager.Len())
+	assert.NotNil(p.Finished)
+	assert.Nil(store.checkpoint)
+}
+
+func testDrainerCancelPaused(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		store   = &memoryStore{
+			checkpoint: &Checkpoint{
+				Job:      Job{Count: 15},
+				Progress: Progress{Visited: 10, Drained: 9, Started: time.Now().UTC()},
+			},
+		}
+
+		d = New(
+			WithLogger(logging.NewTestLogger(nil, t)),
+			WithManager(generateManager(assert, 20)),
+			WithStore(store),
+		)
+	)
+
+	require.NotNil(d)
+	done, err := d.Cancel()
+	require.NoError(err)
+	require.NotNil(done)
+	<-done
+
+	active, _, p := d.Status()
+	assert.False(active)
+	assert.False(p.Paused)
+	assert.NotNil(p.Finished)
+	assert.Nil(store.checkpoint)
+
+	_, _, err = d.Resume()
+	assert.Equal(ErrNotPaused, err)
+
+	_, err = d.Cancel()
+	assert.Equal(ErrNotActive, err)
+}
+
+func testDrainerFiltered(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		logger  = logging.NewTestLogger(nil, t)
+
+		manager = generateManager(assert, 20)
+
+		d = New(
+			WithLogger(logger),
+			WithManager(manager),
+		)
+	)
+
+	require.NotNil(d)
+	close(manager.pauseVisit)
+	close(manager.pauseDisconnect)
+
+	for id, v := range manager.devices {
+		partnerID := "other"
+		if id < device.IntToMAC(5) {
+			partnerID = "comcast"
+		}
+
+		metadata := new(device.Metadata)
+		metadata.SetClaims(map[string]interface{}{device.PartnerIDClaimKey: partnerID})
+		v.(*device.MockDevice).On("Metadata").Return(metadata)
+	}
+
+	filter := &device.MetadataQuery{Claims: map[string]string{device.PartnerIDClaimKey: "comcast"}}
+	done, job, err := d.Start(Job{Filter: filter})
+	require.NoError(err)
+	assert.Equal(Job{Count: 5, Filter: filter}, job)
+
+	select {
+	case <-done:
+		// passing
+	case <-time.After(5 * time.Second):
+		assert.Fail("Drain failed to complete")
+		return
+	}
+
+	_, _, p := d.Status()
+	assert.Equal(5, p.Visited)
+	assert.Equal(5, p.Drained)
+	assert.Equal(15, manager.Len())
+	for id := range manager.devices {
+		assert.True(id >= device.IntToMAC(5))
+	}
+}
+
 func TestDrainer(t *testing.T) {
 	deviceCounts := []int{0, 1, 2, disconnectBatchSize - 1, disconnectBatchSize, disconnectBatchSize + 1, 1709}
 
@@ -604,4 +869,8 @@ func TestDrainer(t *testing.T) {
 	t.Run("VisitCancel", testDrainerVisitCancel)
 	t.Run("DisconnectCancel", testDrainerDisconnectCancel)
 	t.Run("DrainCancel", testDrainerDrainCancel)
+	t.Run("PauseResume", testDrainerPauseResume)
+	t.Run("Restore", testDrainerRestore)
+	t.Run("CancelPaused", testDrainerCancelPaused)
+	t.Run("Filtered", testDrainerFiltered)
 }
@@ -1,12 +1,14 @@
 package drain
 
 import (
+	"context"
 	"net/http"
 	"sync"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/xmidt-org/webpa-common/device"
+	"github.com/xmidt-org/wrp-go/v3"
 )
 
 type mockDrainer struct {
@@ -28,6 +30,42 @@ func (m *mockDrainer) Cancel() (<-chan struct{}, error) {
 	return arguments.Get(0).(<-chan struct{}), arguments.Error(1)
 }
 
+func (m *mockDrainer) Pause() (<-chan struct{}, error) {
+	arguments := m.Called()
+	return arguments.Get(0).(<-chan struct{}), arguments.Error(1)
+}
+
+func (m *mockDrainer) Resume() (<-chan struct{}, Job, error) {
+	arguments := m.Called()
+	return arguments.Get(0).(<-chan struct{}), arguments.Get(1).(Job), arguments.Error(2)
+}
+
+// memoryStore is an in-memory Store for testing
+type memoryStore struct {
+	lock       sync.Mutex
+	checkpoint *Checkpoint
+}
+
+func (ms *memoryStore) Save(c Checkpoint) error {
+	ms.lock.Lock()
+	ms.checkpoint = &c
+	ms.lock.Unlock()
+	return nil
+}
+
+func (ms *memoryStore) Load() (*Checkpoint, error) {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+	return ms.checkpoint, nil
+}
+
+func (ms *memoryStore) Clear() error {
+	ms.lock.Lock()
+	ms.checkpoint = nil
+	ms.lock.Unlock()
+	return nil
+}
+
 type stubManager struct {
 	lock    sync.RWMutex
 	assert  *assert.Assertions
@@ -110,6 +148,21 @@ func (sm *stubManager) Route(*device.Request) (*device.Response, error) {
 	return nil, nil
 }
 
+func (sm *stubManager) Broadcast(context.Context, *wrp.Message, func(device.Interface) bool) (int, error) {
+	sm.assert.Fail("Broadcast is not supported")
+	return 0, nil
+}
+
+func (sm *stubManager) DisconnectIfAsync(context.Context, func(device.ID) (device.CloseReason, bool)) <-chan device.DisconnectProgress {
+	sm.assert.Fail("DisconnectIfAsync is not supported")
+	return nil
+}
+
+func (sm *stubManager) Shutdown(context.Context) error {
+	sm.assert.Fail("Shutdown is not supported")
+	return nil
+}
+
 func generateManager(assert *assert.Assertions, count uint64) *stubManager {
 	sm := &stubManager{
 		assert:          assert,
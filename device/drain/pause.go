@@ -0,0 +1,21 @@
+package drain
+
+import "net/http"
+
+// Pause is an HTTP handler that allows pausing drain jobs
+type Pause struct {
+	Drainer Interface
+}
+
+func (p *Pause) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	done, err := p.Drainer.Pause()
+	if err != nil {
+		response.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	select {
+	case <-done:
+	case <-request.Context().Done():
+	}
+}
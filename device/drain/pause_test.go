@@ -0,0 +1,68 @@
+package drain
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testPauseNotActive(t *testing.T) {
+	var (
+		assert = assert.New(t)
+
+		d     = new(mockDrainer)
+		pause = Pause{d}
+
+		response = httptest.NewRecorder()
+		request  = httptest.NewRequest("GET", "/", nil)
+	)
+
+	d.On("Pause").Return((<-chan struct{})(nil), ErrNotActive).Once()
+	pause.ServeHTTP(response, request)
+	assert.Equal(http.StatusConflict, response.Code)
+
+	d.AssertExpectations(t)
+}
+
+func testPauseSuccess(t *testing.T) {
+	var (
+		assert = assert.New(t)
+
+		d         = new(mockDrainer)
+		pause     = Pause{d}
+		done      = make(chan struct{})
+		pauseWait = make(chan time.Time)
+		serveHTTP = make(chan struct{})
+
+		response = httptest.NewRecorder()
+		request  = httptest.NewRequest("GET", "/", nil)
+	)
+
+	d.On("Pause").WaitUntil(pauseWait).Return((<-chan struct{})(done), error(nil)).Once()
+
+	go func() {
+		defer close(serveHTTP)
+		pause.ServeHTTP(response, request)
+	}()
+
+	pauseWait <- time.Time{}
+	close(done)
+	select {
+	case <-serveHTTP:
+		// passing
+	case <-time.After(5 * time.Second):
+		assert.Fail("ServeHTTP did not return")
+		return
+	}
+
+	assert.Equal(http.StatusOK, response.Code)
+	d.AssertExpectations(t)
+}
+
+func TestPause(t *testing.T) {
+	t.Run("NotActive", testPauseNotActive)
+	t.Run("Success", testPauseSuccess)
+}
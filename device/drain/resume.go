@@ -0,0 +1,32 @@
+package drain
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/xhttp"
+)
+
+// Resume is an HTTP handler that allows paused drain jobs to continue
+type Resume struct {
+	Drainer Interface
+}
+
+func (r *Resume) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	logger := logging.GetLogger(request.Context())
+	_, output, err := r.Drainer.Resume()
+	if err != nil {
+		logger.Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "unable to resume drain job", logging.ErrorKey(), err)
+		xhttp.WriteError(response, http.StatusConflict, err)
+		return
+	}
+
+	if message, err := json.Marshal(output.ToMap()); err != nil {
+		logger.Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "unable to marshal response", logging.ErrorKey(), err)
+	} else {
+		response.Header().Set("Content-Type", "application/json")
+		response.Write(message)
+	}
+}
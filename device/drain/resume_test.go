@@ -0,0 +1,53 @@
+package drain
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testResumeNotPaused(t *testing.T) {
+	var (
+		assert = assert.New(t)
+
+		d      = new(mockDrainer)
+		resume = Resume{d}
+
+		response = httptest.NewRecorder()
+		request  = httptest.NewRequest("POST", "/", nil)
+	)
+
+	d.On("Resume").Return((<-chan struct{})(nil), Job{}, ErrNotPaused).Once()
+	resume.ServeHTTP(response, request)
+	assert.Equal(http.StatusConflict, response.Code)
+
+	d.AssertExpectations(t)
+}
+
+func testResumeSuccess(t *testing.T) {
+	var (
+		assert = assert.New(t)
+
+		d      = new(mockDrainer)
+		resume = Resume{d}
+
+		response = httptest.NewRecorder()
+		request  = httptest.NewRequest("POST", "/", nil)
+	)
+
+	d.On("Resume").Return((<-chan struct{})(make(chan struct{})), Job{Count: 100, Rate: 10, Tick: time.Minute}, error(nil)).Once()
+	resume.ServeHTTP(response, request)
+	assert.Equal(http.StatusOK, response.Code)
+	assert.Equal("application/json", response.HeaderMap.Get("Content-Type"))
+	assert.JSONEq(`{"count": 100, "rate": 10, "tick": "1m0s"}`, response.Body.String())
+
+	d.AssertExpectations(t)
+}
+
+func TestResume(t *testing.T) {
+	t.Run("NotPaused", testResumeNotPaused)
+	t.Run("Success", testResumeSuccess)
+}
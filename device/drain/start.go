@@ -7,6 +7,7 @@ import (
 
 	"github.com/go-kit/kit/log/level"
 	"github.com/gorilla/schema"
+	"github.com/xmidt-org/webpa-common/device"
 	"github.com/xmidt-org/webpa-common/logging"
 	"github.com/xmidt-org/webpa-common/xhttp"
 	"github.com/xmidt-org/webpa-common/xhttp/converter"
@@ -29,6 +30,8 @@ func (s *Start) ServeHTTP(response http.ResponseWriter, request *http.Request) {
 		input   Job
 	)
 
+	// metadata filter parameters are parsed separately
+	decoder.IgnoreUnknownKeys(true)
 	decoder.RegisterConverter(time.Duration(0), converter.Duration)
 	if err := decoder.Decode(&input, request.Form); err != nil {
 		logger.Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "unable to decode request", logging.ErrorKey(), err)
@@ -36,6 +39,19 @@ func (s *Start) ServeHTTP(response http.ResponseWriter, request *http.Request) {
 		return
 	}
 
+	switch filter, err := device.ParseMetadataQuery(request.Form); err {
+	case nil:
+		input.Filter = &filter
+
+	case device.ErrorEmptyMetadataQuery:
+		// no filter was supplied, so all devices are drained
+
+	default:
+		logger.Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "invalid drain filter", logging.ErrorKey(), err)
+		xhttp.WriteError(response, http.StatusBadRequest, err)
+		return
+	}
+
 	_, output, err := s.Drainer.Start(input)
 	if err != nil {
 		logger.Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "unable to start drain job", logging.ErrorKey(), err)
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/xmidt-org/webpa-common/device"
 	"github.com/xmidt-org/webpa-common/logging"
 )
 
@@ -61,6 +62,30 @@ func testStartServeHTTPValid(t *testing.T) {
 			"/foo?count=22&rate=10&tick=20s",
 			Job{Count: 22, Rate: 10, Tick: 20 * time.Second},
 		},
+		{
+			"/foo?count=5&partnerID=comcast&firmware=fw-1",
+			Job{
+				Count: 5,
+				Filter: &device.MetadataQuery{
+					Convey:         map[string]string{device.FirmwareConveyKey: "fw-1"},
+					Claims:         map[string]string{device.PartnerIDClaimKey: "comcast"},
+					ConveyPatterns: map[string]device.Pattern{},
+					ClaimPatterns:  map[string]device.Pattern{},
+				},
+			},
+		},
+		{
+			"/foo?percent=10&claim.trust=1000",
+			Job{
+				Percent: 10,
+				Filter: &device.MetadataQuery{
+					Convey:         map[string]string{},
+					Claims:         map[string]string{"trust": "1000"},
+					ConveyPatterns: map[string]device.Pattern{},
+					ClaimPatterns:  map[string]device.Pattern{},
+				},
+			},
+		},
 	}
 
 	for _, record := range testData {
@@ -125,6 +150,23 @@ func testStartServeHTTPInvalidQuery(t *testing.T) {
 	d.AssertExpectations(t)
 }
 
+func testStartServeHTTPInvalidFilter(t *testing.T) {
+	var (
+		assert = assert.New(t)
+
+		d     = new(mockDrainer)
+		start = Start{d}
+
+		ctx      = logging.WithLogger(context.Background(), logging.NewTestLogger(nil, t))
+		response = httptest.NewRecorder()
+		request  = httptest.NewRequest("POST", "/foo?count=5&convey~.fw-name=%5B", nil).WithContext(ctx)
+	)
+
+	start.ServeHTTP(response, request)
+	assert.Equal(http.StatusBadRequest, response.Code)
+	d.AssertExpectations(t)
+}
+
 func testStartServeHTTPStartError(t *testing.T) {
 	var (
 		assert = assert.New(t)
@@ -151,6 +193,7 @@ func TestStart(t *testing.T) {
 		t.Run("Valid", testStartServeHTTPValid)
 		t.Run("ParseFormError", testStartServeHTTPParseFormError)
 		t.Run("InvalidQuery", testStartServeHTTPInvalidQuery)
+		t.Run("InvalidFilter", testStartServeHTTPInvalidFilter)
 		t.Run("StartError", testStartServeHTTPStartError)
 	})
 }
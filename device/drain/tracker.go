@@ -22,23 +22,41 @@ type Progress struct {
 	Started time.Time `json:"started"`
 
 	// Finished is the UTC system time at which the drain job finished or was canceled.
-	// If the job is running, this field will be nil.
+	// If the job is running or paused, this field will be nil.
 	Finished *time.Time `json:"finished,omitempty"`
+
+	// Paused indicates whether the drain job is currently paused.  A paused job can be resumed.
+	Paused bool `json:"paused,omitempty"`
 }
 
 type tracker struct {
 	visited  int32
 	drained  int32
+	paused   int32
 	started  time.Time
 	finished atomic.Value
 	counter  xmetrics.Adder
 }
 
+// restoreTracker creates a tracker that continues from previously checkpointed progress
+func restoreTracker(p Progress, counter xmetrics.Adder) *tracker {
+	t := &tracker{
+		visited: int32(p.Visited),
+		drained: int32(p.Drained),
+		started: p.Started,
+		counter: counter,
+	}
+
+	t.setPaused(p.Paused)
+	return t
+}
+
 func (t *tracker) Progress() Progress {
 	p := Progress{
 		Visited: int(atomic.LoadInt32(&t.visited)),
 		Drained: int(atomic.LoadInt32(&t.drained)),
 		Started: t.started,
+		Paused:  atomic.LoadInt32(&t.paused) != 0,
 	}
 
 	if finished, ok := t.finished.Load().(time.Time); ok && !finished.IsZero() {
@@ -57,6 +75,14 @@ func (t *tracker) addDrained(delta int) {
 	t.counter.Add(float64(delta))
 }
 
+func (t *tracker) setPaused(paused bool) {
+	if paused {
+		atomic.StoreInt32(&t.paused, 1)
+	} else {
+		atomic.StoreInt32(&t.paused, 0)
+	}
+}
+
 func (t *tracker) done(timestamp time.Time) {
 	t.finished.Store(timestamp)
 }
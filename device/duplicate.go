@@ -0,0 +1,39 @@
+package device
+
+import "fmt"
+
+// DuplicatePolicy describes what a Manager does when a device connects with the same ID as a device
+// that is already connected.
+type DuplicatePolicy string
+
+const (
+	// DuplicateTerminateOld disconnects the existing device in favor of the new connection.  This is the default.
+	DuplicateTerminateOld DuplicatePolicy = "terminate-old"
+
+	// DuplicateRejectNew refuses the new connection, leaving the existing device connected.
+	DuplicateRejectNew DuplicatePolicy = "reject-new"
+
+	// DuplicateAllowBoth keeps both connections.  The new device is registered under its ID with a
+	// connection instance suffix, e.g. mac:112233445566#2.  Messages routed to the unsuffixed ID are
+	// delivered to the original connection.
+	DuplicateAllowBoth DuplicatePolicy = "allow-both"
+)
+
+// InstanceSeparator separates a device ID from the connection instance suffix assigned to duplicate
+// connections under DuplicateAllowBoth.
+const InstanceSeparator = "#"
+
+func (dp DuplicatePolicy) normalize() DuplicatePolicy {
+	switch dp {
+	case DuplicateRejectNew, DuplicateAllowBoth:
+		return dp
+	default:
+		return DuplicateTerminateOld
+	}
+}
+
+// instanceID produces the ID of the given connection instance of a device.  Instances are numbered
+// starting at 2, as the first connection uses the unsuffixed ID.
+func instanceID(id ID, instance int) ID {
+	return ID(fmt.Sprintf("%s%s%d", id, InstanceSeparator, instance))
+}
@@ -11,6 +11,8 @@ var (
 	ErrorMissingDeviceNameVar         = errors.New("Missing device name path variable")
 	ErrorMissingPathVars              = errors.New("Missing URI path variables")
 	ErrorInvalidDeviceName            = errors.New("Invalid device name")
+	ErrorInvalidServiceName           = errors.New("Invalid service name")
+	ErrorExtraDeviceNameSegments      = errors.New("Device name has segments after the service")
 	ErrorDeviceNotFound               = errors.New("The device does not exist")
 	ErrorNonUniqueID                  = errors.New("More than once device with that identifier is connected")
 	ErrorDuplicateKey                 = errors.New("That key is a duplicate")
@@ -24,4 +26,29 @@ var (
 	ErrorDeviceClosed                 = errors.New("That device has been closed")
 	ErrorTransactionsClosed           = errors.New("Transactions are closed for that device")
 	ErrorTransactionsAlreadyClosed    = errors.New("That Transactions is already closed")
+	ErrorRateLimited                  = errors.New("That device has exceeded its outbound rate limit")
+	ErrorMissingClientCertificate     = errors.New("No client certificate was presented")
+	ErrorCertificateMismatch          = errors.New("The client certificate does not match the device name")
+	ErrorInvalidIDScheme              = errors.New("Invalid device ID scheme")
+	ErrorDuplicateIDScheme            = errors.New("That device ID scheme is already registered")
+	ErrorQueueFull                    = errors.New("That device's message queue is full")
+	ErrorMessageDropped               = errors.New("The message was dropped to make room in the device's message queue")
+	ErrorManagerShutdown              = errors.New("The device manager is shutting down")
+	ErrorSourceMismatch               = errors.New("The message source does not match the device")
+	ErrorPayloadTooLarge              = errors.New("The message payload is too large")
+	ErrorInvalidUTF8Payload           = errors.New("The message payload is not valid UTF-8")
+	ErrorUnsupportedWRPFormat         = errors.New("Unsupported WRP format")
+	ErrorUnsupportedSubprotocol       = errors.New("None of the requested websocket subprotocols are supported")
+	ErrorBroadcastNotEvent            = errors.New("Only simple events can be broadcast")
+	ErrorFirstMessageTimeout          = errors.New("Timed out waiting for the device's first message")
+	ErrorMaxConnectionAge             = errors.New("The device connection reached its maximum age")
+	ErrorSessionExpired               = errors.New("The device session has expired")
+	ErrorMissedPongs                  = errors.New("The device did not answer too many consecutive pings")
+	ErrorDeviceBreakerOpen            = errors.New("The device circuit breaker is open")
+	ErrorInvalidPartnerID             = errors.New("Invalid partner ID")
+	ErrorInvalidMetadataType          = errors.New("Invalid convey metadata type")
+	ErrorMissingConveyField           = errors.New("A convey metadata field must name a convey field")
+	ErrorReservedMetadataKey          = errors.New("That metadata key is reserved")
+	ErrorDuplicateMetadataKey         = errors.New("That metadata key is mapped more than once")
+	ErrorDuplicateTransaction         = errors.New("A message with that transaction UUID was already delivered to that device")
 )
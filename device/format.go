@@ -0,0 +1,78 @@
+package device
+
+import (
+	"net/http"
+
+	"github.com/gorilla/websocket"
+	"github.com/xmidt-org/wrp-go/v3"
+)
+
+const (
+	// WRPFormatHeader is the optional HTTP header a device uses at connect time to request the WRP
+	// format used on its connection.  The value is a content type, e.g. application/json.  If omitted,
+	// msgpack is used.
+	WRPFormatHeader = "X-Webpa-Wrp-Format"
+
+	// MsgpackSubprotocol is the websocket subprotocol which selects msgpack-encoded WRP frames
+	MsgpackSubprotocol = "wrp.msgpack"
+
+	// JSONSubprotocol is the websocket subprotocol which selects JSON-encoded WRP frames.  Browser clients,
+	// which cannot set arbitrary headers on websocket requests, can use this to request JSON.
+	JSONSubprotocol = "wrp.json"
+)
+
+// subprotocolFormats maps each WRP subprotocol onto the format it selects
+var subprotocolFormats = map[string]wrp.Format{
+	MsgpackSubprotocol: wrp.Msgpack,
+	JSONSubprotocol:    wrp.JSON,
+}
+
+// requestedFormat examines the WRPFormatHeader of a connect request
+func requestedFormat(request *http.Request) (wrp.Format, error) {
+	format, err := wrp.FormatFromContentType(request.Header.Get(WRPFormatHeader), wrp.Msgpack)
+	if err != nil {
+		return wrp.Msgpack, ErrorUnsupportedWRPFormat
+	}
+
+	return format, nil
+}
+
+// requestedSubprotocol returns the first WRP subprotocol offered by a connect request, if any
+func requestedSubprotocol(request *http.Request) (string, bool) {
+	for _, subprotocol := range websocket.Subprotocols(request) {
+		if _, ok := subprotocolFormats[subprotocol]; ok {
+			return subprotocol, true
+		}
+	}
+
+	return "", false
+}
+
+// negotiateSubprotocol returns the subprotocol that an upgrade will select for a connect request.  When
+// the server supports particular subprotocols, the first of those, in the server's order of preference,
+// which the request offers is selected.  Otherwise, the first WRP subprotocol offered is selected.
+func negotiateSubprotocol(supported []string, request *http.Request) (string, bool) {
+	if len(supported) == 0 {
+		return requestedSubprotocol(request)
+	}
+
+	offered := websocket.Subprotocols(request)
+	for _, s := range supported {
+		for _, o := range offered {
+			if s == o {
+				return s, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// frameType returns the websocket message type used for frames in the given format
+func frameType(format wrp.Format) int {
+	if format == wrp.JSON {
+		return websocket.TextMessage
+	}
+
+	return websocket.BinaryMessage
+}
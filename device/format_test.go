@@ -0,0 +1,111 @@
+package device
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/xmidt-org/wrp-go/v3"
+)
+
+func TestRequestedFormat(t *testing.T) {
+	testData := []struct {
+		header        string
+		expected      wrp.Format
+		expectedError error
+	}{
+		{"", wrp.Msgpack, nil},
+		{"application/msgpack", wrp.Msgpack, nil},
+		{"application/json", wrp.JSON, nil},
+		{"text/plain", wrp.Msgpack, ErrorUnsupportedWRPFormat},
+	}
+
+	for _, record := range testData {
+		t.Run(record.header, func(t *testing.T) {
+			var (
+				assert  = assert.New(t)
+				request = httptest.NewRequest("GET", "/", nil)
+			)
+
+			if len(record.header) > 0 {
+				request.Header.Set(WRPFormatHeader, record.header)
+			}
+
+			format, err := requestedFormat(request)
+			assert.Equal(record.expected, format)
+			assert.Equal(record.expectedError, err)
+		})
+	}
+}
+
+func TestRequestedSubprotocol(t *testing.T) {
+	testData := []struct {
+		header     string
+		expected   string
+		expectedOK bool
+	}{
+		{"", "", false},
+		{"chat, superchat", "", false},
+		{JSONSubprotocol, JSONSubprotocol, true},
+		{"chat, " + MsgpackSubprotocol + ", " + JSONSubprotocol, MsgpackSubprotocol, true},
+	}
+
+	for _, record := range testData {
+		t.Run(record.header, func(t *testing.T) {
+			var (
+				assert  = assert.New(t)
+				request = httptest.NewRequest("GET", "/", nil)
+			)
+
+			if len(record.header) > 0 {
+				request.Header.Set("Sec-Websocket-Protocol", record.header)
+			}
+
+			subprotocol, ok := requestedSubprotocol(request)
+			assert.Equal(record.expected, subprotocol)
+			assert.Equal(record.expectedOK, ok)
+		})
+	}
+}
+
+func TestNegotiateSubprotocol(t *testing.T) {
+	testData := []struct {
+		supported  []string
+		header     string
+		expected   string
+		expectedOK bool
+	}{
+		{nil, "", "", false},
+		{nil, "chat", "", false},
+		{nil, "chat, " + JSONSubprotocol, JSONSubprotocol, true},
+		{[]string{"wrp.v2", MsgpackSubprotocol}, "", "", false},
+		{[]string{"wrp.v2", MsgpackSubprotocol}, "chat", "", false},
+		{[]string{"wrp.v2", MsgpackSubprotocol}, JSONSubprotocol, "", false},
+		{[]string{"wrp.v2", MsgpackSubprotocol}, MsgpackSubprotocol + ", wrp.v2", "wrp.v2", true},
+		{[]string{"wrp.v2", MsgpackSubprotocol}, "chat, " + MsgpackSubprotocol, MsgpackSubprotocol, true},
+	}
+
+	for _, record := range testData {
+		t.Run(record.header, func(t *testing.T) {
+			var (
+				assert  = assert.New(t)
+				request = httptest.NewRequest("GET", "/", nil)
+			)
+
+			if len(record.header) > 0 {
+				request.Header.Set("Sec-Websocket-Protocol", record.header)
+			}
+
+			subprotocol, ok := negotiateSubprotocol(record.supported, request)
+			assert.Equal(record.expected, subprotocol)
+			assert.Equal(record.expectedOK, ok)
+		})
+	}
+}
+
+func TestFrameType(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(websocket.BinaryMessage, frameType(wrp.Msgpack))
+	assert.Equal(websocket.TextMessage, frameType(wrp.JSON))
+}
@@ -3,7 +3,9 @@ package device
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"sync"
 	"time"
@@ -19,6 +21,9 @@ import (
 const (
 	DefaultMessageTimeout time.Duration = 2 * time.Minute
 	DefaultListRefresh    time.Duration = 10 * time.Second
+
+	// listStreamFlushCount is the number of devices written between flushes when streaming a device list
+	listStreamFlushCount = 100
 )
 
 // Timeout returns an Alice-style constructor which enforces a timeout for all device request contexts.
@@ -180,6 +185,12 @@ func (mh *MessageHandler) ServeHTTP(httpResponse http.ResponseWriter, httpReques
 			code = http.StatusBadRequest
 		case ErrorTransactionAlreadyRegistered:
 			code = http.StatusBadRequest
+		case ErrorRateLimited:
+			code = http.StatusTooManyRequests
+		case ErrorQueueFull, ErrorMessageDropped, ErrorDeviceBreakerOpen:
+			code = http.StatusServiceUnavailable
+		case ErrorDuplicateTransaction:
+			code = http.StatusConflict
 		}
 
 		mh.logger().Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "Could not process device request", logging.ErrorKey(), err, "code", code)
@@ -224,7 +235,10 @@ func (ch *ConnectHandler) ServeHTTP(response http.ResponseWriter, request *http.
 	}
 }
 
-// ListHandler is an HTTP handler which can take updated JSON device lists.
+// ListHandler is an HTTP handler which can take updated JSON device lists.  Requests without
+// query parameters receive a periodically refreshed, cached list of every device.  Requests may
+// instead supply the List*Parameter query parameters to filter the list, page through it using
+// a cursor, or stream it as chunked JSON.  Such requests are never cached.
 type ListHandler struct {
 	Logger   log.Logger
 	Registry Registry
@@ -277,14 +291,7 @@ func (lh *ListHandler) updateCache() []byte {
 				lh.cache.WriteString(`,`)
 			}
 
-			if data, err := d.MarshalJSON(); err != nil {
-				lh.cache.WriteString(
-					fmt.Sprintf(`{"id": "%s", "error": "%s"}`, d.ID(), err),
-				)
-			} else {
-				lh.cache.Write(data)
-			}
-
+			writeDeviceJSON(&lh.cache, d)
 			needsSeparator = true
 			return true
 		})
@@ -297,9 +304,119 @@ func (lh *ListHandler) updateCache() []byte {
 	return lh.cacheBytes
 }
 
+// writeDeviceJSON writes the JSON representation of a device, substituting an error object
+// if the device cannot be marshaled.
+func writeDeviceJSON(w io.Writer, d Interface) {
+	if data, err := d.MarshalJSON(); err != nil {
+		id, _ := json.Marshal(string(d.ID()))
+		text, _ := json.Marshal(err.Error())
+		fmt.Fprintf(w, `{"id": %s, "error": %s}`, id, text)
+	} else {
+		w.Write(data)
+	}
+}
+
+// writeListEnd completes a device list, including the cursor for the next page if there is one
+func writeListEnd(w io.Writer, next ID) {
+	io.WriteString(w, `]`)
+	if len(next) > 0 {
+		// device IDs may contain characters which must be escaped
+		cursor, _ := json.Marshal(string(next))
+		io.WriteString(w, `,"next":`)
+		w.Write(cursor)
+	}
+
+	io.WriteString(w, `}`)
+}
+
+// serveQuery handles list requests that carry query parameters.  These requests bypass the cache.
+func (lh *ListHandler) serveQuery(response http.ResponseWriter, q listQuery) {
+	if q.stream {
+		lh.streamQuery(response, q)
+		return
+	}
+
+	var (
+		devices, next = q.page(lh.Registry)
+		buffer        bytes.Buffer
+	)
+
+	io.WriteString(&buffer, `{"devices":[`)
+	for i, d := range devices {
+		if i > 0 {
+			io.WriteString(&buffer, `,`)
+		}
+
+		writeDeviceJSON(&buffer, d)
+	}
+
+	writeListEnd(&buffer, next)
+	response.Write(buffer.Bytes())
+}
+
+// streamQuery writes a device list in batches of listStreamFlushCount devices, flushing after each batch.
+// Each batch is selected with a separate pass over the registry, so neither the whole list nor the whole
+// response is ever held in memory, and no registry locks are held while writing to the client.  As with
+// cursor paging, devices which connect or disconnect while the response is streamed may or may not appear.
+func (lh *ListHandler) streamQuery(response http.ResponseWriter, q listQuery) {
+	var (
+		flusher, _ = response.(http.Flusher)
+		after      = q.cursor
+		next       ID
+		written    = 0
+	)
+
+	io.WriteString(response, `{"devices":[`)
+	for {
+		count := listStreamFlushCount
+		if q.limit > 0 && q.limit-written < count {
+			count = q.limit - written
+		}
+
+		devices, more := q.lowest(lh.Registry, after, count)
+		for _, d := range devices {
+			if written > 0 {
+				io.WriteString(response, `,`)
+			}
+
+			writeDeviceJSON(response, d)
+			written++
+		}
+
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		if !more {
+			break
+		}
+
+		after = devices[len(devices)-1].ID()
+		if q.limit > 0 && written >= q.limit {
+			next = after
+			break
+		}
+	}
+
+	writeListEnd(response, next)
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
 func (lh *ListHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
 	lh.Logger.Log(level.Key(), level.DebugValue(), "handler", "ListHandler", logging.MessageKey(), "ServeHTTP")
+	q, present, err := parseListQuery(request.URL.Query())
+	if err != nil {
+		xhttp.WriteError(response, http.StatusBadRequest, err)
+		return
+	}
+
 	response.Header().Set("Content-Type", "application/json")
+	if present {
+		lh.serveQuery(response, q)
+		return
+	}
 
 	if cacheBytes, expired := lh.tryCache(); expired {
 		response.Write(lh.updateCache())
@@ -355,3 +472,34 @@ func (sh *StatHandler) ServeHTTP(response http.ResponseWriter, request *http.Req
 	response.Header().Set("Content-Type", "application/json")
 	response.Write(data)
 }
+
+// SnapshotFormatParameter is the query parameter which selects the SnapshotFormat written by SnapshotHandler
+const SnapshotFormatParameter = "format"
+
+// SnapshotHandler writes a Snapshot of the connected devices.  By default, the snapshot is written as JSON.
+// Supplying format=gob writes the snapshot in gob format instead.
+type SnapshotHandler struct {
+	Logger   log.Logger
+	Registry Registry
+}
+
+func (sh *SnapshotHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	format := SnapshotFormat(request.FormValue(SnapshotFormatParameter))
+	switch format {
+	case "", SnapshotJSON:
+		format = SnapshotJSON
+		response.Header().Set("Content-Type", "application/json")
+
+	case SnapshotGob:
+		response.Header().Set("Content-Type", "application/octet-stream")
+
+	default:
+		xhttp.WriteError(response, http.StatusBadRequest, ErrorUnsupportedSnapshotFormat)
+		return
+	}
+
+	snapshot := NewSnapshot(sh.Registry)
+	if err := snapshot.Encode(response, format); err != nil {
+		sh.Logger.Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "unable to write snapshot", "format", format, logging.ErrorKey(), err)
+	}
+}
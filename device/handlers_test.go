@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -512,6 +513,10 @@ func TestMessageHandler(t *testing.T) {
 			testMessageHandlerServeHTTPRouteError(t, ErrorNonUniqueID, http.StatusBadRequest)
 			testMessageHandlerServeHTTPRouteError(t, ErrorInvalidTransactionKey, http.StatusBadRequest)
 			testMessageHandlerServeHTTPRouteError(t, ErrorTransactionAlreadyRegistered, http.StatusBadRequest)
+			testMessageHandlerServeHTTPRouteError(t, ErrorRateLimited, http.StatusTooManyRequests)
+			testMessageHandlerServeHTTPRouteError(t, ErrorQueueFull, http.StatusServiceUnavailable)
+			testMessageHandlerServeHTTPRouteError(t, ErrorMessageDropped, http.StatusServiceUnavailable)
+			testMessageHandlerServeHTTPRouteError(t, ErrorDuplicateTransaction, http.StatusConflict)
 			testMessageHandlerServeHTTPRouteError(t, errors.New("random error"), http.StatusGatewayTimeout)
 		})
 
@@ -710,9 +715,178 @@ func testListHandlerServeHTTP(t *testing.T) {
 	registry.AssertExpectations(t)
 }
 
+func testListHandlerServeHTTPQuery(t *testing.T, stream bool) {
+	var (
+		assert   = assert.New(t)
+		require  = require.New(t)
+		registry = new(MockRegistry)
+		first    = newListTestDevice(t, ID("mac:000000000001"), "comcast", "fw-1", time.Hour)
+		second   = newListTestDevice(t, ID("mac:000000000002"), "comcast", "fw-1", time.Hour)
+		third    = newListTestDevice(t, ID("mac:000000000003"), "other", "fw-1", time.Hour)
+
+		handler = ListHandler{
+			Logger:   logging.NewTestLogger(nil, t),
+			Registry: registry,
+		}
+
+		request  = httptest.NewRequest("GET", fmt.Sprintf("/?partnerID=comcast&limit=1&stream=%t", stream), nil)
+		response = httptest.NewRecorder()
+	)
+
+	registry.On("VisitAll", mock.MatchedBy(func(func(Interface) bool) bool { return true })).
+		Run(func(arguments mock.Arguments) {
+			visitor := arguments.Get(0).(func(Interface) bool)
+			visitor(third)
+			visitor(second)
+			visitor(first)
+		}).
+		Return(3).Once()
+
+	handler.ServeHTTP(response, request)
+	assert.Equal(http.StatusOK, response.Code)
+	assert.Equal("application/json", response.Header().Get("Content-Type"))
+	assert.Equal(stream, response.Flushed)
+
+	data, err := first.MarshalJSON()
+	require.NoError(err)
+	assert.JSONEq(
+		fmt.Sprintf(`{"devices":[%s],"next":"mac:000000000001"}`, data),
+		response.Body.String(),
+	)
+
+	assert.True(handler.cacheExpiry.IsZero())
+	registry.AssertExpectations(t)
+}
+
+func testListHandlerServeHTTPStreamBatches(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		require  = require.New(t)
+		registry = new(MockRegistry)
+		devices  []*device
+
+		handler = ListHandler{
+			Logger:   logging.NewTestLogger(nil, t),
+			Registry: registry,
+		}
+	)
+
+	// enough devices, in reverse order, to require several batches
+	deviceCount := 2*listStreamFlushCount + 50
+	for i := deviceCount; i > 0; i-- {
+		devices = append(devices, newListTestDevice(t, IntToMAC(uint64(i)), "comcast", "fw-1", time.Hour))
+	}
+
+	registry.On("VisitAll", mock.MatchedBy(func(func(Interface) bool) bool { return true })).
+		Run(func(arguments mock.Arguments) {
+			visitor := arguments.Get(0).(func(Interface) bool)
+			for _, d := range devices {
+				visitor(d)
+			}
+		}).
+		Return(len(devices))
+
+	for _, record := range []struct {
+		query         string
+		expectedCount int
+		expectedNext  string
+	}{
+		{"/?stream=true", deviceCount, ""},
+		{fmt.Sprintf("/?stream=true&limit=%d", listStreamFlushCount+10), listStreamFlushCount + 10, string(IntToMAC(uint64(listStreamFlushCount + 10)))},
+		{fmt.Sprintf("/?stream=true&cursor=%s", IntToMAC(10)), deviceCount - 10, ""},
+	} {
+		t.Run(record.query, func(t *testing.T) {
+			var (
+				request  = httptest.NewRequest("GET", record.query, nil)
+				response = httptest.NewRecorder()
+			)
+
+			handler.ServeHTTP(response, request)
+			assert.Equal(http.StatusOK, response.Code)
+			assert.True(response.Flushed)
+
+			var list struct {
+				Devices []struct {
+					ID string `json:"id"`
+				} `json:"devices"`
+				Next string `json:"next"`
+			}
+
+			require.NoError(json.Unmarshal(response.Body.Bytes(), &list))
+			require.Len(list.Devices, record.expectedCount)
+			for i := 1; i < len(list.Devices); i++ {
+				assert.True(list.Devices[i-1].ID < list.Devices[i].ID)
+			}
+
+			assert.Equal(record.expectedNext, list.Next)
+		})
+	}
+}
+
+func testListHandlerServeHTTPEscapeCursor(t *testing.T) {
+	for _, stream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stream=%t", stream), func(t *testing.T) {
+			var (
+				assert   = assert.New(t)
+				require  = require.New(t)
+				registry = new(MockRegistry)
+				first    = newListTestDevice(t, ID(`dns:quote"back\\slash`), "comcast", "fw-1", time.Hour)
+				second   = newListTestDevice(t, ID("dns:zzz"), "comcast", "fw-1", time.Hour)
+
+				handler = ListHandler{
+					Logger:   logging.NewTestLogger(nil, t),
+					Registry: registry,
+				}
+
+				request  = httptest.NewRequest("GET", fmt.Sprintf("/?limit=1&stream=%t", stream), nil)
+				response = httptest.NewRecorder()
+			)
+
+			registry.On("VisitAll", mock.MatchedBy(func(func(Interface) bool) bool { return true })).
+				Run(func(arguments mock.Arguments) {
+					visitor := arguments.Get(0).(func(Interface) bool)
+					visitor(second)
+					visitor(first)
+				}).
+				Return(2)
+
+			handler.ServeHTTP(response, request)
+			assert.Equal(http.StatusOK, response.Code)
+
+			var list map[string]interface{}
+			require.NoError(json.Unmarshal(response.Body.Bytes(), &list))
+			assert.Equal(`dns:quote"back\\slash`, list["next"])
+		})
+	}
+}
+
+func testListHandlerServeHTTPBadQuery(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		registry = new(MockRegistry)
+
+		handler = ListHandler{
+			Logger:   logging.NewTestLogger(nil, t),
+			Registry: registry,
+		}
+
+		request  = httptest.NewRequest("GET", "/?limit=-1", nil)
+		response = httptest.NewRecorder()
+	)
+
+	handler.ServeHTTP(response, request)
+	assert.Equal(http.StatusBadRequest, response.Code)
+	registry.AssertExpectations(t)
+}
+
 func TestListHandler(t *testing.T) {
 	t.Run("Refresh", testListHandlerRefresh)
 	t.Run("ServeHTTP", testListHandlerServeHTTP)
+	t.Run("ServeHTTPQuery", func(t *testing.T) { testListHandlerServeHTTPQuery(t, false) })
+	t.Run("ServeHTTPStream", func(t *testing.T) { testListHandlerServeHTTPQuery(t, true) })
+	t.Run("ServeHTTPStreamBatches", testListHandlerServeHTTPStreamBatches)
+	t.Run("ServeHTTPEscapeCursor", testListHandlerServeHTTPEscapeCursor)
+	t.Run("ServeHTTPBadQuery", testListHandlerServeHTTPBadQuery)
 }
 
 func testStatHandlerNoPathVariables(t *testing.T) {
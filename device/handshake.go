@@ -0,0 +1,76 @@
+package device
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+)
+
+// The phases of a device connection attempt, used as the phase label of the HandshakeTimeoutCounter
+const (
+	TLSHandshakePhase = "tls"
+	UpgradePhase      = "upgrade"
+	FirstMessagePhase = "first-message"
+)
+
+// isTimeout tests if an error is a network timeout
+func isTimeout(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
+
+// TLSHandshakeTimer measures and bounds the TLS handshakes of device connections.  Its ConnContext method
+// is intended for http.Server.ConnContext on servers which terminate TLS for devices.
+type TLSHandshakeTimer struct {
+	timeout  time.Duration
+	now      func() time.Time
+	duration metrics.Histogram
+	timeouts metrics.Counter
+}
+
+// NewTLSHandshakeTimer creates a TLSHandshakeTimer using the TLSHandshakeTimeout and Now of the given options.
+// The measures must be the same ones used by the Manager, which can be arranged by creating them with NewMeasures
+// and setting Options.Measures.  Creating a second set from the same provider registers every device metric twice.
+func NewTLSHandshakeTimer(o *Options, measures Measures) *TLSHandshakeTimer {
+	return &TLSHandshakeTimer{
+		timeout:  o.tlsHandshakeTimeout(),
+		now:      o.now(),
+		duration: measures.TLSHandshake,
+		timeouts: measures.PhaseTimeout.With("phase", TLSHandshakePhase),
+	}
+}
+
+// ConnContext starts the TLS handshake of each accepted TLS connection in the background, recording
+// how long the handshake takes.  If a TLSHandshakeTimeout is configured, the read deadline of the connection
+// is set so that clients which stall during the handshake are disconnected.  Note that the http.Server
+// replaces this deadline when its ReadTimeout is set.  Non-TLS connections are ignored.
+//
+// The http.Server performs its own handshake on each connection.  Since tls.Conn handshakes are
+// serialized and only happen once, that handshake simply waits on the one started here.
+func (t *TLSHandshakeTimer) ConnContext(ctx context.Context, c net.Conn) context.Context {
+	tlsConn, ok := c.(*tls.Conn)
+	if !ok {
+		return ctx
+	}
+
+	start := t.now()
+	if t.timeout > 0 {
+		tlsConn.SetReadDeadline(start.Add(t.timeout))
+	}
+
+	go func() {
+		err := tlsConn.Handshake()
+		switch {
+		case err == nil:
+			t.duration.Observe(t.now().Sub(start).Seconds())
+
+		case isTimeout(err):
+			t.timeouts.Add(1.0)
+		}
+	}()
+
+	return ctx
+}
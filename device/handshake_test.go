@@ -0,0 +1,170 @@
+package device
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/xmetrics"
+	"github.com/xmidt-org/webpa-common/xmetrics/xmetricstest"
+)
+
+// observingHistogram is a metrics.Histogram that sends each observation to a channel
+type observingHistogram chan float64
+
+func (oh observingHistogram) With(...string) metrics.Histogram { return oh }
+func (oh observingHistogram) Observe(value float64)            { oh <- value }
+
+// uniqueProvider is a provider.Provider which, like many real providers, fails when a metric is created twice
+type uniqueProvider struct {
+	provider.Provider
+	t     *testing.T
+	names map[string]bool
+}
+
+func newUniqueProvider(t *testing.T) *uniqueProvider {
+	return &uniqueProvider{Provider: provider.NewDiscardProvider(), t: t, names: make(map[string]bool)}
+}
+
+func (up *uniqueProvider) register(name string) {
+	assert.False(up.t, up.names[name], "metric %s was created more than once", name)
+	up.names[name] = true
+}
+
+func (up *uniqueProvider) NewCounter(name string) metrics.Counter {
+	up.register(name)
+	return up.Provider.NewCounter(name)
+}
+
+func (up *uniqueProvider) NewGauge(name string) metrics.Gauge {
+	up.register(name)
+	return up.Provider.NewGauge(name)
+}
+
+func (up *uniqueProvider) NewHistogram(name string, buckets int) metrics.Histogram {
+	up.register(name)
+	return up.Provider.NewHistogram(name, buckets)
+}
+
+type testTimeoutError struct{}
+
+func (testTimeoutError) Error() string   { return "expected" }
+func (testTimeoutError) Timeout() bool   { return true }
+func (testTimeoutError) Temporary() bool { return true }
+
+func TestIsTimeout(t *testing.T) {
+	assert := assert.New(t)
+	assert.False(isTimeout(nil))
+	assert.False(isTimeout(errors.New("expected")))
+	assert.True(isTimeout(testTimeoutError{}))
+}
+
+func testTLSHandshakeTimerNonTLS(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		timer  = NewTLSHandshakeTimer(nil, NewMeasures(provider.NewDiscardProvider()))
+
+		ctx          = context.Background()
+		client, conn = net.Pipe()
+	)
+
+	defer client.Close()
+	defer conn.Close()
+
+	assert.Equal(ctx, timer.ConnContext(ctx, conn))
+}
+
+func testTLSHandshakeTimerComplete(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		require  = require.New(t)
+		duration = make(observingHistogram, 1)
+		timer    = NewTLSHandshakeTimer(&Options{TLSHandshakeTimeout: 5 * time.Second}, NewMeasures(provider.NewDiscardProvider()))
+
+		server = httptest.NewUnstartedServer(http.HandlerFunc(func(response http.ResponseWriter, _ *http.Request) {
+			response.WriteHeader(http.StatusOK)
+		}))
+	)
+
+	timer.duration = duration
+	server.Config.ConnContext = timer.ConnContext
+	server.StartTLS()
+	defer server.Close()
+
+	response, err := server.Client().Get(server.URL)
+	require.NoError(err)
+	response.Body.Close()
+	assert.Equal(http.StatusOK, response.StatusCode)
+
+	select {
+	case seconds := <-duration:
+		assert.True(seconds >= 0.0)
+	case <-time.After(5 * time.Second):
+		assert.Fail("The TLS handshake was not timed")
+	}
+}
+
+func testTLSHandshakeTimerTimeout(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		p       = xmetricstest.NewProvider(nil, Metrics)
+		timer   = NewTLSHandshakeTimer(&Options{TLSHandshakeTimeout: 100 * time.Millisecond}, NewMeasures(p))
+
+		server = httptest.NewUnstartedServer(http.HandlerFunc(func(response http.ResponseWriter, _ *http.Request) {
+			response.WriteHeader(http.StatusOK)
+		}))
+	)
+
+	server.Config.ConnContext = timer.ConnContext
+	server.StartTLS()
+	defer server.Close()
+
+	// a client that never sends its ClientHello
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	require.NoError(err)
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	assert.Error(err)
+	assert.False(isTimeout(err), "the server should have closed the connection")
+
+	require.Eventually(
+		func() bool {
+			return timer.timeouts.(xmetrics.Valuer).Value() == 1.0
+		},
+		5*time.Second,
+		10*time.Millisecond,
+	)
+
+	p.Assert(t, HandshakeTimeoutCounter, "phase", TLSHandshakePhase)(xmetricstest.Value(1.0))
+}
+
+func testTLSHandshakeTimerSharedMeasures(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		p        = newUniqueProvider(t)
+		measures = NewMeasures(p)
+		o        = &Options{MetricsProvider: p, Measures: &measures}
+	)
+
+	assert.NotNil(NewManager(o))
+	timer := NewTLSHandshakeTimer(o, measures)
+	assert.Equal(measures.TLSHandshake, timer.duration)
+}
+
+func TestTLSHandshakeTimer(t *testing.T) {
+	t.Run("NonTLS", testTLSHandshakeTimerNonTLS)
+	t.Run("Complete", testTLSHandshakeTimerComplete)
+	t.Run("Timeout", testTLSHandshakeTimerTimeout)
+	t.Run("SharedMeasures", testTLSHandshakeTimerSharedMeasures)
+}
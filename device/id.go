@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"regexp"
 	"strings"
+	"sync"
 	"unicode"
 )
 
@@ -21,18 +22,95 @@ const (
 	macDelimiters = ":-.,"
 	macPrefix     = "mac"
 	macLength     = 12
+	eui64Length   = 16
 )
 
 var (
 	invalidID = ID("")
 
 	// idPattern is the precompiled regular expression that all device identifiers must match.
-	// Matching is partial, as everything after the service is ignored.
+	// Matching is partial, as everything after the service is ignored.  The prefix must be
+	// a registered IDScheme.
 	idPattern = regexp.MustCompile(
-		`^(?P<prefix>(?i)mac|uuid|dns|serial):(?P<id>[^/]+)(?P<service>/[^/]+)?`,
+		`^(?P<prefix>[a-zA-Z][a-zA-Z0-9_-]*):(?P<id>[^/]+)(?P<service>/[^/]+)?`,
 	)
+
+	// servicePattern is the precompiled regular expression that service names must match when parsing strictly
+	servicePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+	// schemePattern is the precompiled regular expression that all IDScheme names must match
+	schemePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+	schemesLock sync.RWMutex
+
+	// schemes holds the registered ID schemes, keyed by lowercased prefix
+	schemes = map[string]IDScheme{
+		macPrefix: normalizeMAC,
+		"uuid":    nil,
+		"dns":     nil,
+		"serial":  nil,
+	}
 )
 
+// IDScheme validates and normalizes the portion of a device name after the scheme prefix, e.g. the
+// "112233445566" in "mac:112233445566".  A nil IDScheme accepts any value as is.  Any error returned
+// by an IDScheme causes ParseID to fail with ErrorInvalidDeviceName.
+type IDScheme func(string) (string, error)
+
+// RegisterIDScheme adds a scheme which ParseID will accept.  Scheme names are case-insensitive and
+// must start with a letter, followed by letters, digits, underscores, or hyphens.  The builtin schemes
+// are mac, uuid, dns, and serial.  An attempt to register a scheme more than once results in an error.
+func RegisterIDScheme(prefix string, scheme IDScheme) error {
+	prefix = strings.ToLower(prefix)
+	if !schemePattern.MatchString(prefix) {
+		return ErrorInvalidIDScheme
+	}
+
+	schemesLock.Lock()
+	defer schemesLock.Unlock()
+
+	if _, ok := schemes[prefix]; ok {
+		return ErrorDuplicateIDScheme
+	}
+
+	schemes[prefix] = scheme
+	return nil
+}
+
+// getIDScheme returns the IDScheme for a lowercased prefix
+func getIDScheme(prefix string) (IDScheme, bool) {
+	schemesLock.RLock()
+	scheme, ok := schemes[prefix]
+	schemesLock.RUnlock()
+	return scheme, ok
+}
+
+// normalizeMAC is the IDScheme for MAC addresses.  Both 48-bit MACs and EUI-64 identifiers
+// are accepted.  Delimiters are removed, and hexadecimal digits are lowercased.
+func normalizeMAC(value string) (string, error) {
+	var invalidCharacter rune = -1
+	value = strings.Map(
+		func(r rune) rune {
+			switch {
+			case strings.ContainsRune(hexDigits, r):
+				return unicode.ToLower(r)
+			case strings.ContainsRune(macDelimiters, r):
+				return -1
+			default:
+				invalidCharacter = r
+				return -1
+			}
+		},
+		value,
+	)
+
+	if invalidCharacter != -1 || (len(value) != macLength && len(value) != eui64Length) {
+		return "", ErrorInvalidDeviceName
+	}
+
+	return value, nil
+}
+
 // IntToMAC accepts a 64-bit integer and formats that as a device MAC address identifier
 // The returned ID will be of the form mac:XXXXXXXXXXXX, where X is a hexadecimal digit using
 // lowercased letters.
@@ -40,41 +118,86 @@ func IntToMAC(value uint64) ID {
 	return ID(fmt.Sprintf("mac:%012x", value&0x0000FFFFFFFFFFFF))
 }
 
-// ParseID parses a raw device name into a canonicalized identifier.
+// IntToEUI64 accepts a 64-bit integer and formats that as a device EUI-64 identifier.
+// The returned ID will be of the form mac:XXXXXXXXXXXXXXXX, where X is a hexadecimal digit using
+// lowercased letters.
+func IntToEUI64(value uint64) ID {
+	return ID(fmt.Sprintf("mac:%016x", value))
+}
+
+// ParseMode determines how strictly the service segment of a device name is checked
+type ParseMode int
+
+const (
+	// ParseLenient accepts any service segment.  This is the mode used by ParseID.
+	ParseLenient ParseMode = iota
+
+	// ParseStrict requires the service segment, if present, to start with a letter or digit followed by
+	// letters, digits, dots, underscores, or hyphens.  An empty service segment, as in "mac:112233445566//", is rejected.
+	ParseStrict
+)
+
+// ParseOptions control how ParseDeviceName treats the portion of a device name after the ID
+type ParseOptions struct {
+	// Mode determines how the service segment is checked.  The zero value is ParseLenient.
+	Mode ParseMode
+
+	// RejectExtraSegments causes device names with segments after the service, e.g. "mac:112233445566/service/ignoreMe",
+	// to be rejected with ErrorExtraDeviceNameSegments.  A single trailing slash is not considered a segment.
+	// By default, extra segments are ignored.
+	RejectExtraSegments bool
+}
+
+// ParseID parses a raw device name into a canonicalized identifier.  The device name's prefix
+// must be a registered IDScheme.  Everything after the ID is ignored.
 func ParseID(deviceName string) (ID, error) {
+	id, _, err := ParseDeviceName(deviceName, ParseOptions{})
+	return id, err
+}
+
+// ParseDeviceName parses a raw device name into a canonicalized identifier and service name, checking
+// the portion after the ID according to the given options.  The service is returned without its slashes,
+// e.g. "mac:112233445566/config/" yields a service of "config", and is empty if the device name has no service.
+func ParseDeviceName(deviceName string, o ParseOptions) (ID, string, error) {
 	match := idPattern.FindStringSubmatch(deviceName)
 	if match == nil {
-		return invalidID, ErrorInvalidDeviceName
+		return invalidID, "", ErrorInvalidDeviceName
 	}
 
 	var (
-		prefix = strings.ToLower(match[1])
-		idPart = match[2]
+		prefix  = strings.ToLower(match[1])
+		idPart  = match[2]
+		service = strings.TrimPrefix(match[3], "/")
+		rest    = deviceName[len(match[0]):]
 	)
 
-	if prefix == macPrefix {
-		var invalidCharacter rune = -1
-		idPart = strings.Map(
-			func(r rune) rune {
-				switch {
-				case strings.ContainsRune(hexDigits, r):
-					return unicode.ToLower(r)
-				case strings.ContainsRune(macDelimiters, r):
-					return -1
-				default:
-					invalidCharacter = r
-					return -1
-				}
-			},
-			idPart,
-		)
-
-		if invalidCharacter != -1 || len(idPart) != macLength {
-			return invalidID, ErrorInvalidDeviceName
+	scheme, ok := getIDScheme(prefix)
+	if !ok {
+		return invalidID, "", ErrorInvalidDeviceName
+	}
+
+	if scheme != nil {
+		var err error
+		if idPart, err = scheme(idPart); err != nil {
+			return invalidID, "", ErrorInvalidDeviceName
+		}
+	}
+
+	if rest != "/" && len(rest) > 0 {
+		if o.Mode == ParseStrict && len(service) == 0 {
+			return invalidID, "", ErrorInvalidServiceName
+		}
+
+		if o.RejectExtraSegments {
+			return invalidID, "", ErrorExtraDeviceNameSegments
 		}
 	}
 
-	return ID(fmt.Sprintf("%s:%s", prefix, idPart)), nil
+	if o.Mode == ParseStrict && len(service) > 0 && !servicePattern.MatchString(service) {
+		return invalidID, "", ErrorInvalidServiceName
+	}
+
+	return ID(fmt.Sprintf("%s:%s", prefix, idPart)), service, nil
 }
 
 // IDHashParser is a parsing function that examines an HTTP request to produce
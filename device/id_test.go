@@ -1,10 +1,12 @@
 package device
 
 import (
+	"errors"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestIntToMAC(t *testing.T) {
@@ -26,6 +28,25 @@ func TestIntToMAC(t *testing.T) {
 	}
 }
 
+func TestIntToEUI64(t *testing.T) {
+	assert := assert.New(t)
+	testData := []struct {
+		integer    uint64
+		expectedID ID
+	}{
+		{0, "mac:0000000000000000"},
+		{0x112233445566, "mac:0000112233445566"},
+		{0x0011223344556677, "mac:0011223344556677"},
+		{0xF1A293C46570ABCD, "mac:f1a293c46570abcd"},
+	}
+
+	for _, record := range testData {
+		t.Logf("%v", record)
+		actualID := IntToEUI64(record.integer)
+		assert.Equal(record.expectedID, actualID)
+	}
+}
+
 func TestParseID(t *testing.T) {
 	assert := assert.New(t)
 	testData := []struct {
@@ -49,6 +70,12 @@ func TestParseID(t *testing.T) {
 		{"MAC:invalid45566", "", true},
 		{"mac:481d70187fef", "mac:481d70187fef", false},
 		{"mac:481d70187fef/parodus/tag/test0", "mac:481d70187fef", false},
+		{"MAC:00:11:22:FF:FE:33:44:55", "mac:001122fffe334455", false},
+		{"mac:00-11-22-ff-fe-33-44-55", "mac:001122fffe334455", false},
+		{"mac:0011.22ff.fe33.4455", "mac:001122fffe334455", false},
+		{"mac:001122fffe334455/service", "mac:001122fffe334455", false},
+		{"mac:001122fffe3344", "", true},
+		{"mac:001122fffe33445566", "", true},
 	}
 
 	for _, record := range testData {
@@ -61,6 +88,52 @@ func TestParseID(t *testing.T) {
 	}
 }
 
+func TestParseDeviceName(t *testing.T) {
+	var (
+		lenient       = ParseOptions{}
+		strict        = ParseOptions{Mode: ParseStrict}
+		lenientNoTail = ParseOptions{RejectExtraSegments: true}
+		strictNoTail  = ParseOptions{Mode: ParseStrict, RejectExtraSegments: true}
+
+		testData = []struct {
+			deviceName      string
+			options         ParseOptions
+			expectedID      ID
+			expectedService string
+			expectedErr     error
+		}{
+			{"mac:112233445566", lenient, "mac:112233445566", "", nil},
+			{"mac:112233445566", strictNoTail, "mac:112233445566", "", nil},
+			{"mac:112233445566/", strictNoTail, "mac:112233445566", "", nil},
+			{"mac:112233445566/config", strictNoTail, "mac:112233445566", "config", nil},
+			{"mac:112233445566/config/", strictNoTail, "mac:112233445566", "config", nil},
+			{"mac:112233445566/service/ignoreMe", lenient, "mac:112233445566", "service", nil},
+			{"mac:112233445566/service/ignoreMe", strict, "mac:112233445566", "service", nil},
+			{"mac:112233445566/service/ignoreMe", lenientNoTail, invalidID, "", ErrorExtraDeviceNameSegments},
+			{"mac:112233445566/service/ignoreMe", strictNoTail, invalidID, "", ErrorExtraDeviceNameSegments},
+			{"mac:112233445566/parodus.v2_test-1", strict, "mac:112233445566", "parodus.v2_test-1", nil},
+			{"mac:112233445566/bad service!", lenient, "mac:112233445566", "bad service!", nil},
+			{"mac:112233445566/bad service!", strict, invalidID, "", ErrorInvalidServiceName},
+			{"mac:112233445566/.hidden", strict, invalidID, "", ErrorInvalidServiceName},
+			{"mac:112233445566//service", lenient, "mac:112233445566", "", nil},
+			{"mac:112233445566//service", lenientNoTail, invalidID, "", ErrorExtraDeviceNameSegments},
+			{"mac:112233445566//service", strict, invalidID, "", ErrorInvalidServiceName},
+			{"mac:invalid/service", strictNoTail, invalidID, "", ErrorInvalidDeviceName},
+			{"nosuchscheme:1234/service", lenient, invalidID, "", ErrorInvalidDeviceName},
+		}
+	)
+
+	for _, record := range testData {
+		t.Run(record.deviceName, func(t *testing.T) {
+			assert := assert.New(t)
+			id, service, err := ParseDeviceName(record.deviceName, record.options)
+			assert.Equal(record.expectedID, id)
+			assert.Equal(record.expectedService, service)
+			assert.Equal(record.expectedErr, err)
+		})
+	}
+}
+
 func TestIDHashParser(t *testing.T) {
 	var (
 		assert            = assert.New(t)
@@ -102,3 +175,48 @@ func TestIDHashParser(t *testing.T) {
 		}
 	}
 }
+
+func TestRegisterIDScheme(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+	)
+
+	defer func() {
+		schemesLock.Lock()
+		delete(schemes, "imei")
+		delete(schemes, "self")
+		schemesLock.Unlock()
+	}()
+
+	assert.Equal(ErrorInvalidIDScheme, RegisterIDScheme("", nil))
+	assert.Equal(ErrorInvalidIDScheme, RegisterIDScheme("1abc", nil))
+	assert.Equal(ErrorInvalidIDScheme, RegisterIDScheme("a:b", nil))
+	assert.Equal(ErrorDuplicateIDScheme, RegisterIDScheme("MAC", nil))
+
+	_, err := ParseID("imei:490154203237518")
+	assert.Equal(ErrorInvalidDeviceName, err)
+
+	require.NoError(RegisterIDScheme("IMEI", func(v string) (string, error) {
+		if len(v) != 15 {
+			return "", errors.New("expected")
+		}
+
+		return v, nil
+	}))
+
+	require.NoError(RegisterIDScheme("self", nil))
+	assert.Equal(ErrorDuplicateIDScheme, RegisterIDScheme("imei", nil))
+
+	id, err := ParseID("Imei:490154203237518/service")
+	assert.Equal(ID("imei:490154203237518"), id)
+	assert.NoError(err)
+
+	id, err = ParseID("imei:1234")
+	assert.Equal(invalidID, id)
+	assert.Equal(ErrorInvalidDeviceName, err)
+
+	id, err = ParseID("self:anything")
+	assert.Equal(ID("self:anything"), id)
+	assert.NoError(err)
+}
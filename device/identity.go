@@ -0,0 +1,153 @@
+package device
+
+import (
+	"encoding"
+	"hash/fnv"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const (
+	// DefaultPartnerIDHeader is the HTTP header consulted by the hash parser returned from NewIdentityHashParser
+	// when no header is configured
+	DefaultPartnerIDHeader = "X-Xmidt-Partner-Id"
+
+	// identitySeparator separates the partner ID from the device ID in the canonical form of an Identity.
+	// Device IDs never contain this character, and partner IDs are not allowed to.
+	identitySeparator = "/"
+)
+
+// partnerIDPattern is the precompiled regular expression that all partner IDs must match.  In particular,
+// partner IDs cannot contain the identitySeparator or the colon that separates an ID scheme from its value.
+var partnerIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+var (
+	_ encoding.TextMarshaler   = Identity{}
+	_ encoding.TextUnmarshaler = (*Identity)(nil)
+)
+
+// Identity is the partner-aware identity of a device, composed of the device's ID and the partner
+// it belongs to.  Identity is comparable, so it may be used directly as a map key.  Its canonical form,
+// returned by String, is "partnerID/deviceID", e.g. "comcast/mac:112233445566".  An Identity with no
+// partner has the same canonical form as its ID, so that hashing is unchanged for devices without partners.
+type Identity struct {
+	ID        ID
+	PartnerID string
+}
+
+// NewIdentity produces an Identity from an already parsed device ID and a raw partner ID.  Partner IDs
+// are case-insensitive and are lowercased.  ErrorInvalidPartnerID is returned if the partner ID is not valid.
+// A blank partner ID is allowed, and produces an Identity with no partner.
+func NewIdentity(id ID, partnerID string) (Identity, error) {
+	partnerID = strings.ToLower(strings.TrimSpace(partnerID))
+	if len(partnerID) > 0 && !partnerIDPattern.MatchString(partnerID) {
+		return Identity{}, ErrorInvalidPartnerID
+	}
+
+	return Identity{ID: id, PartnerID: partnerID}, nil
+}
+
+// ParseIdentity parses the canonical form of an Identity.  A bare device name, without a partner,
+// is also accepted.  The device ID is parsed with ParseID, so any service after the ID is ignored.
+func ParseIdentity(value string) (Identity, error) {
+	// a device name always has a scheme prefix, so a first segment with no colon is a partner ID
+	if i := strings.Index(value, identitySeparator); i >= 0 && !strings.Contains(value[:i], ":") {
+		id, err := ParseID(value[i+1:])
+		if err != nil {
+			return Identity{}, err
+		}
+
+		if i == 0 {
+			return Identity{}, ErrorInvalidPartnerID
+		}
+
+		return NewIdentity(id, value[:i])
+	}
+
+	id, err := ParseID(value)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	return Identity{ID: id}, nil
+}
+
+// String returns the canonical form of this Identity
+func (i Identity) String() string {
+	if len(i.PartnerID) == 0 {
+		return string(i.ID)
+	}
+
+	return i.PartnerID + identitySeparator + string(i.ID)
+}
+
+// Bytes returns the canonical form of this Identity as a []byte, suitable as the key for a service.Accessor
+func (i Identity) Bytes() []byte {
+	return []byte(i.String())
+}
+
+// Hash returns a 64-bit FNV-1a hash of the canonical form of this Identity
+func (i Identity) Hash() uint64 {
+	h := fnv.New64a()
+	h.Write(i.Bytes())
+	return h.Sum64()
+}
+
+// MarshalText produces the canonical form of this Identity
+func (i Identity) MarshalText() ([]byte, error) {
+	return i.Bytes(), nil
+}
+
+// UnmarshalText parses the canonical form of an Identity via ParseIdentity
+func (i *Identity) UnmarshalText(text []byte) error {
+	parsed, err := ParseIdentity(string(text))
+	if err != nil {
+		return err
+	}
+
+	*i = parsed
+	return nil
+}
+
+// IdentityOf returns the Identity of a connected device, using the partner ID claim from its metadata.
+// Partner ID claims which are not valid are ignored.
+func IdentityOf(d Interface) Identity {
+	i := Identity{ID: d.ID()}
+	if metadata := d.Metadata(); metadata != nil {
+		if withPartner, err := NewIdentity(i.ID, metadata.PartnerIDClaim()); err == nil {
+			i = withPartner
+		}
+	}
+
+	return i
+}
+
+// NewIdentityHashParser produces a parsing function, analogous to IDHashParser, which uses the canonical form
+// of a device's Identity as the key for consistent hashing.  The device name is taken from DeviceNameHeader and
+// the partner ID from the given header, which is DefaultPartnerIDHeader if blank.  Requests without a partner ID
+// hash exactly as they would with IDHashParser.
+func NewIdentityHashParser(partnerIDHeader string) func(*http.Request) ([]byte, error) {
+	if len(partnerIDHeader) == 0 {
+		partnerIDHeader = DefaultPartnerIDHeader
+	}
+
+	return func(request *http.Request) ([]byte, error) {
+		deviceName := request.Header.Get(DeviceNameHeader)
+		if len(deviceName) == 0 {
+			return nil, ErrorMissingDeviceNameHeader
+		}
+
+		id, err := ParseID(deviceName)
+		if err != nil {
+			return nil, err
+		}
+
+		identity, err := NewIdentity(id, request.Header.Get(partnerIDHeader))
+		if err != nil {
+			return nil, err
+		}
+
+		return identity.Bytes(), nil
+	}
+}
@@ -0,0 +1,161 @@
+package device
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewIdentity(t *testing.T) {
+	testData := []struct {
+		partnerID    string
+		expected     Identity
+		expectsError bool
+	}{
+		{"", Identity{ID: "mac:112233445566"}, false},
+		{"comcast", Identity{ID: "mac:112233445566", PartnerID: "comcast"}, false},
+		{" Comcast ", Identity{ID: "mac:112233445566", PartnerID: "comcast"}, false},
+		{"partner.one_2-x", Identity{ID: "mac:112233445566", PartnerID: "partner.one_2-x"}, false},
+		{"bad/partner", Identity{}, true},
+		{"bad:partner", Identity{}, true},
+		{"-bad", Identity{}, true},
+	}
+
+	for _, record := range testData {
+		t.Run(record.partnerID, func(t *testing.T) {
+			assert := assert.New(t)
+			actual, err := NewIdentity("mac:112233445566", record.partnerID)
+			assert.Equal(record.expected, actual)
+			if record.expectsError {
+				assert.Equal(ErrorInvalidPartnerID, err)
+			} else {
+				assert.NoError(err)
+			}
+		})
+	}
+}
+
+func TestParseIdentity(t *testing.T) {
+	testData := []struct {
+		value        string
+		expected     Identity
+		expectsError bool
+	}{
+		{"mac:112233445566", Identity{ID: "mac:112233445566"}, false},
+		{"MAC:11:22:33:44:55:66/service", Identity{ID: "mac:112233445566"}, false},
+		{"comcast/mac:112233445566", Identity{ID: "mac:112233445566", PartnerID: "comcast"}, false},
+		{"Comcast/MAC:11-22-33-44-55-66/config", Identity{ID: "mac:112233445566", PartnerID: "comcast"}, false},
+		{"/mac:112233445566", Identity{}, true},
+		{"bad partner/mac:112233445566", Identity{}, true},
+		{"comcast/not a device", Identity{}, true},
+		{"comcast", Identity{}, true},
+		{"", Identity{}, true},
+	}
+
+	for _, record := range testData {
+		t.Run(record.value, func(t *testing.T) {
+			assert := assert.New(t)
+			actual, err := ParseIdentity(record.value)
+			assert.Equal(record.expected, actual)
+			assert.Equal(record.expectsError, err != nil)
+		})
+	}
+}
+
+func TestIdentityCanonicalForm(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		bare        = Identity{ID: "mac:112233445566"}
+		withPartner = Identity{ID: "mac:112233445566", PartnerID: "comcast"}
+	)
+
+	assert.Equal("mac:112233445566", bare.String())
+	assert.Equal([]byte("mac:112233445566"), bare.Bytes())
+	assert.Equal(ID("mac:112233445566").Bytes(), bare.Bytes())
+	assert.Equal("comcast/mac:112233445566", withPartner.String())
+	assert.Equal([]byte("comcast/mac:112233445566"), withPartner.Bytes())
+
+	assert.Equal(withPartner.Hash(), Identity{ID: "mac:112233445566", PartnerID: "comcast"}.Hash())
+	assert.NotEqual(bare.Hash(), withPartner.Hash())
+
+	for _, i := range []Identity{bare, withPartner} {
+		parsed, err := ParseIdentity(i.String())
+		require.NoError(err)
+		assert.Equal(i, parsed)
+	}
+
+	data, err := json.Marshal(map[Identity]int{withPartner: 1})
+	require.NoError(err)
+	assert.JSONEq(`{"comcast/mac:112233445566": 1}`, string(data))
+
+	var unmarshaled map[Identity]int
+	require.NoError(json.Unmarshal(data, &unmarshaled))
+	assert.Equal(map[Identity]int{withPartner: 1}, unmarshaled)
+
+	var invalid Identity
+	assert.Error(invalid.UnmarshalText([]byte("not valid")))
+	assert.Equal(Identity{}, invalid)
+}
+
+func TestIdentityOf(t *testing.T) {
+	var (
+		assert = assert.New(t)
+
+		withPartner    = newListTestDevice(t, ID("mac:112233445566"), "Comcast", "fw-1", time.Hour)
+		invalidPartner = newListTestDevice(t, ID("mac:112233445566"), "not/valid", "fw-1", time.Hour)
+		noPartner      = newListTestDevice(t, ID("mac:112233445566"), "", "fw-1", time.Hour)
+	)
+
+	assert.Equal(Identity{ID: "mac:112233445566", PartnerID: "comcast"}, IdentityOf(withPartner))
+	assert.Equal(Identity{ID: "mac:112233445566"}, IdentityOf(invalidPartner))
+	assert.Equal(Identity{ID: "mac:112233445566"}, IdentityOf(noPartner))
+}
+
+func TestNewIdentityHashParser(t *testing.T) {
+	testData := []struct {
+		partnerIDHeader string
+		deviceName      string
+		partnerID       string
+		expectedKey     string
+		expectsError    bool
+	}{
+		{"", "mac:112233445566", "", "mac:112233445566", false},
+		{"", "mac:112233445566", "comcast", "comcast/mac:112233445566", false},
+		{"X-Custom-Partner", "MAC:112233445566/service", "Comcast", "comcast/mac:112233445566", false},
+		{"", "mac:112233445566", "bad/partner", "", true},
+		{"", "this is not valid", "comcast", "", true},
+		{"", "", "comcast", "", true},
+	}
+
+	for i, record := range testData {
+		t.Logf("%d: %#v", i, record)
+
+		var (
+			assert  = assert.New(t)
+			header  = record.partnerIDHeader
+			request = httptest.NewRequest("GET", "http://burrito-sightings.net", nil)
+		)
+
+		if len(header) == 0 {
+			header = DefaultPartnerIDHeader
+		}
+
+		request.Header.Set(DeviceNameHeader, record.deviceName)
+		request.Header.Set(header, record.partnerID)
+
+		actualKey, err := NewIdentityHashParser(record.partnerIDHeader)(request)
+		if record.expectsError {
+			assert.Empty(actualKey)
+			assert.Error(err)
+		} else {
+			assert.Equal(record.expectedKey, string(actualKey))
+			assert.NoError(err)
+		}
+	}
+}
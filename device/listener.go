@@ -95,6 +95,10 @@ type Event struct {
 	// data structure.
 	Contents []byte
 
+	// CloseReason describes why the device was disconnected.  This field is only populated for
+	// Disconnect events.
+	CloseReason CloseReason
+
 	// Error is the error which occurred during an attempt to send a message.  This field is only populated
 	// for MessageFailed events when there was an actual error.  For MessageFailed events that indicate a
 	// device was disconnected with enqueued messages, this field will be nil.
@@ -0,0 +1,206 @@
+package device
+
+import (
+	"container/heap"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Query parameters understood by ListHandler.  A request with none of these parameters
+// is served from the handler's cache.
+const (
+	// ListLimitParameter is the maximum number of devices to return in a single page.
+	ListLimitParameter = "limit"
+
+	// ListCursorParameter is the opaque cursor returned as "next" from a previous page.
+	ListCursorParameter = "cursor"
+
+	// ListPartnerIDParameter restricts results to devices with the given partner ID claim.
+	ListPartnerIDParameter = "partnerID"
+
+	// ListFirmwareParameter restricts results to devices reporting the given firmware in their convey.
+	ListFirmwareParameter = "firmware"
+
+	// ListMinAgeParameter restricts results to devices connected for at least the given duration.
+	ListMinAgeParameter = "minAge"
+
+	// ListMaxAgeParameter restricts results to devices connected for at most the given duration.
+	ListMaxAgeParameter = "maxAge"
+
+	// ListStreamParameter, when true, writes the response as chunked JSON instead of buffering it.
+	ListStreamParameter = "stream"
+)
+
+// FirmwareConveyKey is the convey field which carries a device's firmware name
+const FirmwareConveyKey = "fw-name"
+
+// listQuery is the parsed set of ListHandler query parameters
+type listQuery struct {
+	limit     int
+	cursor    ID
+	partnerID string
+	firmware  string
+	minAge    time.Duration
+	maxAge    time.Duration
+	stream    bool
+}
+
+// parseListQuery extracts a listQuery from URL query values.  The returned flag is false
+// if none of the ListHandler query parameters were present.
+func parseListQuery(values url.Values) (q listQuery, present bool, err error) {
+	for _, name := range []string{ListLimitParameter, ListCursorParameter, ListPartnerIDParameter, ListFirmwareParameter, ListMinAgeParameter, ListMaxAgeParameter, ListStreamParameter} {
+		if _, ok := values[name]; ok {
+			present = true
+			break
+		}
+	}
+
+	if !present {
+		return
+	}
+
+	if v := values.Get(ListLimitParameter); len(v) > 0 {
+		if q.limit, err = strconv.Atoi(v); err != nil || q.limit < 1 {
+			err = fmt.Errorf("Invalid %s: %s", ListLimitParameter, v)
+			return
+		}
+	}
+
+	if v := values.Get(ListMinAgeParameter); len(v) > 0 {
+		if q.minAge, err = time.ParseDuration(v); err != nil {
+			err = fmt.Errorf("Invalid %s: %s", ListMinAgeParameter, err)
+			return
+		}
+	}
+
+	if v := values.Get(ListMaxAgeParameter); len(v) > 0 {
+		if q.maxAge, err = time.ParseDuration(v); err != nil {
+			err = fmt.Errorf("Invalid %s: %s", ListMaxAgeParameter, err)
+			return
+		}
+	}
+
+	if v := values.Get(ListStreamParameter); len(v) > 0 {
+		if q.stream, err = strconv.ParseBool(v); err != nil {
+			err = fmt.Errorf("Invalid %s: %s", ListStreamParameter, v)
+			return
+		}
+	}
+
+	q.cursor = ID(values.Get(ListCursorParameter))
+	q.partnerID = values.Get(ListPartnerIDParameter)
+	q.firmware = values.Get(ListFirmwareParameter)
+	return
+}
+
+// matches applies this query's filters to a device.  The cursor and limit are not considered.
+func (q listQuery) matches(d Interface) bool {
+	if len(q.partnerID) > 0 {
+		if metadata := d.Metadata(); metadata == nil || metadata.PartnerIDClaim() != q.partnerID {
+			return false
+		}
+	}
+
+	if len(q.firmware) > 0 {
+		c := d.Convey()
+		if c == nil {
+			return false
+		}
+
+		if firmware, _ := c.GetString(FirmwareConveyKey); firmware != q.firmware {
+			return false
+		}
+	}
+
+	if q.minAge > 0 || q.maxAge > 0 {
+		upTime := d.Statistics().UpTime()
+		if upTime < q.minAge || (q.maxAge > 0 && upTime > q.maxAge) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// deviceHeap is a max-heap of devices ordered by ID.  It holds the lowest IDs seen so far, with
+// the highest of those at the root, so that the lowest IDs can be selected without sorting every device.
+type deviceHeap []Interface
+
+func (dh deviceHeap) Len() int           { return len(dh) }
+func (dh deviceHeap) Less(i, j int) bool { return dh[i].ID() > dh[j].ID() }
+func (dh deviceHeap) Swap(i, j int)      { dh[i], dh[j] = dh[j], dh[i] }
+
+func (dh *deviceHeap) Push(x interface{}) {
+	*dh = append(*dh, x.(Interface))
+}
+
+func (dh *deviceHeap) Pop() interface{} {
+	old := *dh
+	last := old[len(old)-1]
+	*dh = old[:len(old)-1]
+	return last
+}
+
+// lowest selects, ordered by ID, up to count devices from a registry which match this query and whose IDs
+// sort after the given ID.  No more than count devices are held at once, so memory use does not grow with the
+// size of the registry.  The returned flag is true if more matching devices exist beyond those returned.
+func (q listQuery) lowest(r Registry, after ID, count int) ([]Interface, bool) {
+	var (
+		dh   = make(deviceHeap, 0, count)
+		more = false
+	)
+
+	r.VisitAll(func(d Interface) bool {
+		if (len(after) > 0 && d.ID() <= after) || !q.matches(d) {
+			return true
+		}
+
+		switch {
+		case len(dh) < count:
+			heap.Push(&dh, d)
+
+		case d.ID() < dh[0].ID():
+			dh[0] = d
+			heap.Fix(&dh, 0)
+			more = true
+
+		default:
+			more = true
+		}
+
+		return true
+	})
+
+	sort.Sort(sort.Reverse(dh))
+	return dh, more
+}
+
+// page selects the devices from a registry that match this query, ordered by ID.  If there are more
+// matching devices than the limit allows, the ID to use as the cursor for the next page is returned.
+func (q listQuery) page(r Registry) (devices []Interface, next ID) {
+	if q.limit > 0 {
+		var more bool
+		if devices, more = q.lowest(r, q.cursor, q.limit); more {
+			next = devices[len(devices)-1].ID()
+		}
+
+		return
+	}
+
+	r.VisitAll(func(d Interface) bool {
+		if (len(q.cursor) == 0 || d.ID() > q.cursor) && q.matches(d) {
+			devices = append(devices, d)
+		}
+
+		return true
+	})
+
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].ID() < devices[j].ID()
+	})
+
+	return
+}
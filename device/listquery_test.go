@@ -0,0 +1,173 @@
+package device
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/convey"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+func TestParseListQuery(t *testing.T) {
+	t.Run("Empty", func(t *testing.T) {
+		q, present, err := parseListQuery(url.Values{})
+		assert.Equal(t, listQuery{}, q)
+		assert.False(t, present)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Full", func(t *testing.T) {
+		q, present, err := parseListQuery(url.Values{
+			ListLimitParameter:     {"10"},
+			ListCursorParameter:    {"mac:112233445566"},
+			ListPartnerIDParameter: {"comcast"},
+			ListFirmwareParameter:  {"fw-1"},
+			ListMinAgeParameter:    {"1m"},
+			ListMaxAgeParameter:    {"1h"},
+			ListStreamParameter:    {"true"},
+		})
+
+		assert.True(t, present)
+		assert.NoError(t, err)
+		assert.Equal(t,
+			listQuery{
+				limit:     10,
+				cursor:    ID("mac:112233445566"),
+				partnerID: "comcast",
+				firmware:  "fw-1",
+				minAge:    time.Minute,
+				maxAge:    time.Hour,
+				stream:    true,
+			},
+			q,
+		)
+	})
+
+	for _, values := range []url.Values{
+		{ListLimitParameter: {"abc"}},
+		{ListLimitParameter: {"0"}},
+		{ListMinAgeParameter: {"abc"}},
+		{ListMaxAgeParameter: {"abc"}},
+		{ListStreamParameter: {"abc"}},
+	} {
+		t.Run("Invalid", func(t *testing.T) {
+			_, present, err := parseListQuery(values)
+			assert.True(t, present)
+			assert.Error(t, err)
+		})
+	}
+}
+
+// newListTestDevice creates a device with the given partner and firmware that has been connected for upTime
+func newListTestDevice(t *testing.T, id ID, partnerID, firmware string, upTime time.Duration) *device {
+	var (
+		connectedAt = time.Now()
+		metadata    = new(Metadata)
+		d           = newDevice(deviceOptions{
+			ID:          id,
+			C:           convey.C{FirmwareConveyKey: firmware},
+			ConnectedAt: connectedAt,
+			Logger:      logging.NewTestLogger(nil, t),
+			Metadata:    metadata,
+		})
+	)
+
+	metadata.SetClaims(map[string]interface{}{PartnerIDClaimKey: partnerID})
+	d.statistics = NewStatistics(func() time.Time { return connectedAt.Add(upTime) }, connectedAt)
+	return d
+}
+
+func TestListQueryMatches(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		d      = newListTestDevice(t, ID("mac:112233445566"), "comcast", "fw-1", time.Hour)
+	)
+
+	assert.True(listQuery{}.matches(d))
+	assert.True(listQuery{partnerID: "comcast", firmware: "fw-1", minAge: time.Minute, maxAge: 2 * time.Hour}.matches(d))
+	assert.False(listQuery{partnerID: "other"}.matches(d))
+	assert.False(listQuery{firmware: "fw-2"}.matches(d))
+	assert.False(listQuery{minAge: 2 * time.Hour}.matches(d))
+	assert.False(listQuery{maxAge: time.Minute}.matches(d))
+
+	noConvey := newDevice(deviceOptions{ID: ID("mac:665544332211"), Logger: logging.NewTestLogger(nil, t)})
+	assert.False(listQuery{firmware: "fw-1"}.matches(noConvey))
+}
+
+func TestListQueryPage(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		require  = require.New(t)
+		registry = new(MockRegistry)
+		devices  = []*device{
+			newListTestDevice(t, ID("mac:000000000003"), "comcast", "fw-1", time.Hour),
+			newListTestDevice(t, ID("mac:000000000001"), "comcast", "fw-1", time.Hour),
+			newListTestDevice(t, ID("mac:000000000004"), "other", "fw-1", time.Hour),
+			newListTestDevice(t, ID("mac:000000000002"), "comcast", "fw-1", time.Hour),
+		}
+	)
+
+	registry.On("VisitAll", mock.MatchedBy(func(func(Interface) bool) bool { return true })).
+		Run(func(arguments mock.Arguments) {
+			visitor := arguments.Get(0).(func(Interface) bool)
+			for _, d := range devices {
+				visitor(d)
+			}
+		}).
+		Return(len(devices))
+
+	page, next := listQuery{partnerID: "comcast", limit: 2}.page(registry)
+	require.Len(page, 2)
+	assert.Equal(ID("mac:000000000001"), page[0].ID())
+	assert.Equal(ID("mac:000000000002"), page[1].ID())
+	assert.Equal(ID("mac:000000000002"), next)
+
+	page, next = listQuery{partnerID: "comcast", limit: 2, cursor: next}.page(registry)
+	require.Len(page, 1)
+	assert.Equal(ID("mac:000000000003"), page[0].ID())
+	assert.Empty(next)
+
+	page, next = listQuery{}.page(registry)
+	assert.Len(page, 4)
+	assert.Empty(next)
+}
+
+func TestListQueryLowest(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		require  = require.New(t)
+		registry = new(MockRegistry)
+		devices  []*device
+	)
+
+	for _, i := range []int{7, 3, 9, 1, 5, 8, 2, 6, 4} {
+		devices = append(devices, newListTestDevice(t, ID(fmt.Sprintf("mac:00000000000%d", i)), "comcast", "fw-1", time.Hour))
+	}
+
+	registry.On("VisitAll", mock.MatchedBy(func(func(Interface) bool) bool { return true })).
+		Run(func(arguments mock.Arguments) {
+			visitor := arguments.Get(0).(func(Interface) bool)
+			for _, d := range devices {
+				visitor(d)
+			}
+		}).
+		Return(len(devices))
+
+	lowest, more := listQuery{}.lowest(registry, "", 3)
+	require.Len(lowest, 3)
+	assert.True(more)
+	assert.Equal(ID("mac:000000000001"), lowest[0].ID())
+	assert.Equal(ID("mac:000000000002"), lowest[1].ID())
+	assert.Equal(ID("mac:000000000003"), lowest[2].ID())
+
+	lowest, more = listQuery{}.lowest(registry, ID("mac:000000000006"), 3)
+	require.Len(lowest, 3)
+	assert.False(more)
+	assert.Equal(ID("mac:000000000007"), lowest[0].ID())
+	assert.Equal(ID("mac:000000000009"), lowest[2].ID())
+}
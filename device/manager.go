@@ -1,6 +1,7 @@
 package device
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -25,6 +26,9 @@ const MaxDevicesHeader = "X-Xmidt-Max-Devices"
 // DefaultWRPContentType is the content type used on inbound WRP messages which don't provide one.
 const DefaultWRPContentType = "application/octet-stream"
 
+// shutdownCloseReason is the reason for every disconnection made by Manager.Shutdown
+var shutdownCloseReason = CloseReason{Reason: DisconnectServerShutdown, Code: websocket.CloseServiceRestart}
+
 // Connector is a strategy interface for managing device connections to a server.
 // Implementations are responsible for upgrading websocket connections and providing
 // for explicit disconnection.
@@ -62,6 +66,20 @@ type Router interface {
 	Route(*Request) (*Response, error)
 }
 
+// Broadcaster handles dispatching a single message to many devices.
+type Broadcaster interface {
+	// Broadcast sends a simple event to each connected device for which the filter returns true.  A nil
+	// filter selects all devices.  Devices are sent the event no faster than the configured broadcast
+	// rate, so this method can take some time to complete.
+	//
+	// The filter is applied under the registry's read lock.  No methods on this Manager should be called
+	// from within the filter, or a deadlock will likely occur.
+	//
+	// This method returns the number of devices to which the event was successfully queued.  If the context
+	// is cancelled or its deadline expires before all devices were sent the event, the context's error is returned.
+	Broadcast(context.Context, *wrp.Message, func(Interface) bool) (int, error)
+}
+
 // Registry is the strategy interface for querying the set of connected devices.  Methods
 // in this interface follow the Visitor pattern and are typically executed under a read lock.
 type Registry interface {
@@ -78,8 +96,37 @@ type Registry interface {
 	VisitAll(func(Interface) bool) int
 }
 
+// Shutdowner gracefully disconnects all devices.
+type Shutdowner interface {
+	// Shutdown gracefully disconnects all devices.  Once this method is called, all subsequent
+	// connection attempts are rejected.  Each connected device is sent a close frame with the
+	// websocket.CloseServiceRestart status, which hints that the device should reconnect, and devices
+	// are disconnected no faster than the configured shutdown rate.
+	//
+	// This method returns nil once all connections have closed.  If the context is cancelled or its
+	// deadline expires first, the context's error is returned.
+	Shutdown(context.Context) error
+}
+
+// AsyncDisconnector disconnects devices in batches without holding the registry's lock.
+type AsyncDisconnector interface {
+	// DisconnectIfAsync is like DisconnectIf, except that devices are visited in batches on a separate
+	// goroutine.  Unlike DisconnectIf, the predicate is not executed under the registry's lock, and
+	// devices which connect after this method is called are not visited.
+	//
+	// The returned channel reports progress after each batch.  A report that has not been received
+	// is replaced by the next one, so slow readers only see the most recent progress.  The final report
+	// has its Done field set, after which the channel is closed.  Cancelling the context stops the operation
+	// after the current batch, and the final report carries the context's error.
+	DisconnectIfAsync(context.Context, func(ID) (CloseReason, bool)) <-chan DisconnectProgress
+}
+
 // Manager supplies a hub for connecting and disconnecting devices as well as
 // an access point for obtaining device metadata.
+//
+// The Manager returned by NewManager also implements Broadcaster, Shutdowner, and AsyncDisconnector.
+// Those capabilities are separate interfaces, obtained with a type assertion, so that existing
+// implementations of Manager are unaffected.
 type Manager interface {
 	Connector
 	Router
@@ -92,24 +139,45 @@ func NewManager(o *Options) Manager {
 	var (
 		logger      = o.logger()
 		debugLogger = logging.Debug(logger)
-		measures    = NewMeasures(o.metricsProvider())
+		measures    = o.measures()
 		wrpCheck    = o.wrpCheck()
 	)
 
 	debugLogger.Log(logging.MessageKey(), "source check configuration", "type", wrpCheck.Type)
 
-	return &manager{
+	conveyMetadata, err := o.conveyMetadata()
+	if err != nil {
+		logging.Error(logger).Log(logging.MessageKey(), "invalid convey metadata mapping", logging.ErrorKey(), err)
+	}
+
+	idlePeriod := o.idlePeriod()
+	if o.maxMissedPongs() > 0 {
+		// consecutive missed pongs, rather than a single read deadline, determine when a silent device is disconnected
+		idlePeriod = 0
+	}
+
+	m := &manager{
 		logger:           logger,
 		errorLog:         logging.Error(logger),
 		debugLog:         debugLogger,
-		readDeadline:     NewDeadline(o.idlePeriod(), o.now()),
+		now:              o.now(),
+		readDeadline:     NewDeadline(idlePeriod, o.now()),
 		writeDeadline:    NewDeadline(o.writeTimeout(), o.now()),
 		upgrader:         o.upgrader(),
-		conveyTranslator: conveyhttp.NewHeaderTranslator("", nil),
+		compressionLevel: o.compressionLevel(),
+		conveyTranslator: conveyhttp.NewMultiHeaderTranslator(conveyhttp.HeaderOptions{
+			HeaderNames:     o.conveyHeaders(),
+			Translator:      convey.NewLimitedTranslator(nil, convey.FormatJSON, o.conveyMaxDecodedSize()),
+			HeaderUsed:      measures.ConveyHeader,
+			MaxHeaderLength: o.conveyMaxHeaderLength(),
+			Oversized:       measures.ConveyOversized,
+		}),
 		devices: newRegistry(registryOptions{
-			Logger:   logger,
-			Limit:    o.maxDevices(),
-			Measures: measures,
+			Logger:          logger,
+			Limit:           o.maxDevices(),
+			Shards:          o.registryShards(),
+			DuplicatePolicy: o.duplicatePolicy(),
+			Measures:        measures,
 		}),
 		conveyHWMetric: conveymetric.NewConveyMetric(measures.Models, []conveymetric.TagLabelPair{
 			{
@@ -122,12 +190,45 @@ func NewManager(o *Options) Manager {
 			}}...),
 
 		deviceMessageQueueSize: o.deviceMessageQueueSize(),
+		qosTiers:               o.qosTiers(),
+		queuePolicy:            o.queuePolicy(),
+		queueBlockTimeout:      o.queueBlockTimeout(),
+		dedupSize:              o.dedupSize(),
+		dedupTTL:               o.dedupTTL(),
+		rateLimit:              o.rateLimit(),
+		bindClientCertificates: o.bindClientCertificates(),
+		requireSubprotocol:     o.requireSubprotocol(),
 		pingPeriod:             o.pingPeriod(),
+		missedPongThreshold:    o.missedPongThreshold(),
+		breakerThreshold:       o.breakerThreshold(),
+		breakerCooldown:        o.breakerCooldown(),
+		breakerMaxTrips:        o.breakerMaxTrips(),
+		maxMissedPongs:         o.maxMissedPongs(),
+		shutdownRate:           o.shutdownRate(),
+		broadcastRate:          o.broadcastRate(),
+		disconnectBatchSize:    o.disconnectBatchSize(),
+		firstMessageTimeout:    o.firstMessageTimeout(),
+		maxConnectionAge:       o.maxConnectionAge(),
 
 		listeners:             o.listeners(),
 		measures:              measures,
 		enforceWRPSourceCheck: wrpCheck.Type == CheckTypeEnforce,
+		destinationParsing:    o.destinationParsing(),
+		authenticator:         o.authenticator(),
+		validators:            o.validators(),
+		invalidMessagePolicy:  o.invalidMessagePolicy(),
+		conveyNotAvailable:    o.conveyNotAvailablePolicy(),
+		conveyMetadata:        conveyMetadata,
+
+		applicationIdlePeriod: o.applicationIdlePeriod(),
+		reaperStop:            make(chan struct{}),
 	}
+
+	if m.applicationIdlePeriod > 0 {
+		go m.reapIdle(time.NewTicker(o.reapInterval()))
+	}
+
+	return m
 }
 
 // manager is the internal Manager implementation.
@@ -136,24 +237,97 @@ type manager struct {
 	errorLog log.Logger
 	debugLog log.Logger
 
+	now              func() time.Time
 	readDeadline     func() time.Time
 	writeDeadline    func() time.Time
 	upgrader         *websocket.Upgrader
+	compressionLevel int
 	conveyTranslator conveyhttp.HeaderTranslator
 
 	devices        *registry
 	conveyHWMetric conveymetric.Interface
 
 	deviceMessageQueueSize int
+	qosTiers               map[QOSLevel]QOSTier
+	queuePolicy            QueuePolicy
+	queueBlockTimeout      time.Duration
+	dedupSize              int
+	dedupTTL               time.Duration
+	rateLimit              RateLimit
+	bindClientCertificates bool
+	requireSubprotocol     bool
 	pingPeriod             time.Duration
+	missedPongThreshold    int
+	breakerThreshold       int
+	breakerCooldown        time.Duration
+	breakerMaxTrips        int
+	maxMissedPongs         int
+	shutdownRate           int
+	broadcastRate          int
+	disconnectBatchSize    int
+	firstMessageTimeout    time.Duration
+	maxConnectionAge       time.Duration
+
+	// applicationIdlePeriod is the ApplicationIdlePeriod.  When positive, a goroutine
+	// periodically reaps idle devices until reaperStop is closed.
+	applicationIdlePeriod time.Duration
+	reaperStop            chan struct{}
+	stopReaper            sync.Once
+
+	// shutdownLock guards shuttingDown and ensures that no connection is added to
+	// connections once shutdown has begun
+	shutdownLock sync.RWMutex
+	shuttingDown bool
+	connections  sync.WaitGroup
 
 	listeners             []Listener
 	measures              Measures
 	enforceWRPSourceCheck bool
+	destinationParsing    ParseOptions
+	authenticator         Authenticator
+	validators            Validators
+	invalidMessagePolicy  InvalidMessagePolicy
+	conveyNotAvailable    ConveyNotAvailablePolicy
+	conveyMetadata        ConveyMetadataTranslator
+}
+
+// beginConnect registers a connection attempt, returning false if this manager is shutting down.
+// Each successful call must be matched by a call to m.connections.Done().
+func (m *manager) beginConnect() bool {
+	m.shutdownLock.RLock()
+	defer m.shutdownLock.RUnlock()
+
+	if m.shuttingDown {
+		return false
+	}
+
+	m.connections.Add(1)
+	return true
+}
+
+// isShuttingDown tests if Shutdown has been called on this manager
+func (m *manager) isShuttingDown() bool {
+	m.shutdownLock.RLock()
+	defer m.shutdownLock.RUnlock()
+	return m.shuttingDown
 }
 
 func (m *manager) Connect(response http.ResponseWriter, request *http.Request, responseHeader http.Header) (Interface, error) {
 	m.debugLog.Log(logging.MessageKey(), "device connect", "url", request.URL)
+	start := m.now()
+	if !m.beginConnect() {
+		xhttp.WriteError(response, http.StatusServiceUnavailable, ErrorManagerShutdown)
+		return nil, ErrorManagerShutdown
+	}
+
+	// once the pumps start, pumpClose is responsible for releasing this connection
+	pumping := false
+	defer func() {
+		if !pumping {
+			m.connections.Done()
+		}
+	}()
+
 	ctx := request.Context()
 	id, ok := GetID(ctx)
 	if !ok {
@@ -166,38 +340,169 @@ func (m *manager) Connect(response http.ResponseWriter, request *http.Request, r
 		return nil, ErrorMissingDeviceNameContext
 	}
 
+	if m.bindClientCertificates {
+		if reason, err := verifyCertificateBinding(id, request.TLS); err != nil {
+			m.errorLog.Log(logging.MessageKey(), "client certificate does not match device", "id", id, logging.ErrorKey(), err)
+			m.measures.CertMismatch.With("reason", reason).Add(1.0)
+			xhttp.WriteError(response, http.StatusForbidden, err)
+			return nil, err
+		}
+	}
+
+	// reject duplicates before upgrading.  registry.add still enforces this policy, since
+	// another connection for this device may complete in the meantime.
+	if m.devices.duplicatePolicy == DuplicateRejectNew {
+		if _, exists := m.devices.get(id); exists {
+			m.errorLog.Log(logging.MessageKey(), "rejecting duplicate device connection", "id", id)
+			m.devices.duplicates.Inc()
+			m.measures.DuplicatePolicy.With("policy", string(DuplicateRejectNew)).Add(1.0)
+			xhttp.WriteError(response, http.StatusConflict, ErrorDuplicateDevice)
+			return nil, ErrorDuplicateDevice
+		}
+	}
+
+	format, err := requestedFormat(request)
+	if err != nil {
+		m.errorLog.Log(logging.MessageKey(), "unsupported WRP format", "id", id, WRPFormatHeader, request.Header.Get(WRPFormatHeader))
+		xhttp.WriteError(response, http.StatusBadRequest, err)
+		return nil, err
+	}
+
+	subprotocol, negotiated := negotiateSubprotocol(m.upgrader.Subprotocols, request)
+	if !negotiated && (m.requireSubprotocol || (len(m.upgrader.Subprotocols) > 0 && len(websocket.Subprotocols(request)) > 0)) {
+		m.errorLog.Log(logging.MessageKey(), "unsupported websocket subprotocol", "id", id,
+			"requested", websocket.Subprotocols(request), "supported", m.upgrader.Subprotocols)
+		xhttp.WriteError(response, http.StatusBadRequest, ErrorUnsupportedSubprotocol)
+		return nil, ErrorUnsupportedSubprotocol
+	}
+
+	// when the upgrader does not select subprotocols itself, accept a requested WRP subprotocol
+	if negotiated && len(m.upgrader.Subprotocols) == 0 {
+		header := make(http.Header, len(responseHeader)+1)
+		for name, values := range responseHeader {
+			header[name] = values
+		}
+
+		header.Set("Sec-Websocket-Protocol", subprotocol)
+		responseHeader = header
+	}
+
 	metadata, ok := GetDeviceMetadata(ctx)
 	if !ok {
 		metadata = new(Metadata)
 	}
 
+	var session Session
+	if m.authenticator != nil {
+		session, err = m.authenticator.Authenticate(request, id, metadata)
+		if err == nil && !session.Expires.IsZero() && !session.Expires.After(m.now()) {
+			err = ErrorSessionExpired
+		}
+
+		if err != nil {
+			m.errorLog.Log(logging.MessageKey(), "device authentication failed", "id", id, logging.ErrorKey(), err)
+			m.measures.Authentication.With("outcome", "rejected").Add(1.0)
+			xhttp.WriteError(response, authenticationStatus(err), err)
+			return nil, err
+		}
+
+		m.measures.Authentication.With("outcome", "accepted").Add(1.0)
+	}
+
 	cvy, cvyErr := m.conveyTranslator.FromHeader(request.Header)
+	compliance, logConveyErr := convey.GetCompliance(cvyErr), true
+	if conveyhttp.IsNotAvailable(cvyErr) {
+		m.measures.NotAvailable.With("policy", string(m.conveyNotAvailable)).Add(1.0)
+		switch m.conveyNotAvailable {
+		case ConveyNotAvailableReject:
+			m.errorLog.Log(logging.MessageKey(), "rejecting device with not-available convey", "id", id)
+			xhttp.WriteError(response, http.StatusBadRequest, cvyErr)
+			return nil, cvyErr
+
+		case ConveyNotAvailableIgnore:
+			compliance, logConveyErr = convey.Missing, false
+
+		case ConveyNotAvailableAccept:
+			cvy, cvyErr, compliance = convey.C{}, nil, convey.Full
+		}
+	}
+
+	if m.conveyMetadata != nil && cvy != nil {
+		if err := m.conveyMetadata.Apply(cvy, metadata); err != nil {
+			m.errorLog.Log(logging.MessageKey(), "unable to map convey fields into metadata", "id", id, logging.ErrorKey(), err)
+		}
+	}
+
 	d := newDevice(deviceOptions{
 		ID:         id,
 		C:          cvy,
-		Compliance: convey.GetCompliance(cvyErr),
+		Compliance: compliance,
 		QueueSize:  m.deviceMessageQueueSize,
+		QOSTiers:   m.qosTiers,
 		Metadata:   metadata,
 		Logger:     m.logger,
+
+		QueuePolicy:       m.queuePolicy,
+		QueueBlockTimeout: m.queueBlockTimeout,
+		QueueFull:         m.measures.QueueFull,
+		Listener:          m.dispatch,
+
+		DedupSize: m.dedupSize,
+		DedupTTL:  m.dedupTTL,
+		DedupHits: m.measures.DedupHits,
+		Now:       m.now,
 	})
 
+	d.breaker = newBreaker(m.breakerThreshold, m.breakerCooldown, m.breakerMaxTrips, m.now, m.measures.Breaker)
 	if len(metadata.Claims()) < 1 {
 		d.errorLog.Log(logging.MessageKey(), "missing security information")
 	}
 
 	if cvyErr == nil {
 		d.infoLog.Log("convey", cvy)
-	} else {
+	} else if logConveyErr {
 		d.errorLog.Log(logging.MessageKey(), "bad or missing convey data", logging.ErrorKey(), cvyErr)
 	}
 
+	// for compressed connections, wire bytes are counted beneath gorilla so that the effect of compression is known
+	var compressed *compressedResponseWriter
+	if compressionNegotiated(m.upgrader, request.Header) {
+		compressed = &compressedResponseWriter{ResponseWriter: response}
+		response = compressed
+	}
+
 	c, err := m.upgrader.Upgrade(response, request, responseHeader)
 	if err != nil {
 		d.errorLog.Log(logging.MessageKey(), "failed websocket upgrade", logging.ErrorKey(), err)
+		if isTimeout(err) {
+			m.measures.PhaseTimeout.With("phase", UpgradePhase).Add(1.0)
+		}
+
 		return nil, err
 	}
 
-	d.debugLog.Log(logging.MessageKey(), "websocket upgrade complete", "localAddress", c.LocalAddr().String())
+	upgraded := m.now()
+	m.measures.UpgradeDuration.Observe(upgraded.Sub(start).Seconds())
+
+	// a negotiated WRP subprotocol takes precedence over the format header
+	if subprotocolFormat, ok := subprotocolFormats[c.Subprotocol()]; ok {
+		format = subprotocolFormat
+	}
+
+	d.format = format
+	d.subprotocol = c.Subprotocol()
+	d.sessionExpires = session.Expires
+	d.debugLog.Log(logging.MessageKey(), "websocket upgrade complete", "localAddress", c.LocalAddr().String(), "format", format)
+
+	if compressed != nil {
+		compressed.conn.sent = d.statistics.AddCompressedBytesSent
+		if err := c.SetCompressionLevel(m.compressionLevel); err != nil {
+			d.errorLog.Log(logging.MessageKey(), "unable to set compression level", "level", m.compressionLevel, logging.ErrorKey(), err)
+		}
+
+		d.statistics.SetCompressed(true)
+		m.measures.Compression.Inc()
+	}
 
 	pinger, err := NewPinger(c, m.measures.Ping, []byte(d.ID()), m.writeDeadline)
 	if err != nil {
@@ -212,6 +517,12 @@ func (m *manager) Connect(response http.ResponseWriter, request *http.Request, r
 		return nil, err
 	}
 
+	if m.isShuttingDown() {
+		// Shutdown may have gathered its devices before this one was added, so it would never disconnect
+		// this device.  The pumps still start, which sends the close frame and releases this connection.
+		m.devices.remove(d.id, shutdownCloseReason)
+	}
+
 	event := &Event{
 		Type:   Connect,
 		Device: d,
@@ -234,10 +545,41 @@ func (m *manager) Connect(response http.ResponseWriter, request *http.Request, r
 	d.conveyClosure = metricClosure
 	m.dispatch(event)
 
-	SetPongHandler(c, m.measures.Pong, m.readDeadline)
+	var (
+		rtt       = newRoundTripTimer(m.now)
+		roundTrip = m.measures.RoundTrip.With("partnerid", metadata.PartnerIDClaim())
+	)
+
+	pinger = m.missedPongPinger(d, rtt, rtt.pinger(pinger))
+	setPongHandler(c, m.measures.Pong, m.readDeadline, func() {
+		m.pongReceived(d)
+		if elapsed, ok := rtt.pong(); ok {
+			d.statistics.SetRoundTripTime(elapsed)
+			roundTrip.Observe(elapsed.Seconds())
+		}
+	})
+
+	var (
+		partnerID = metadata.PartnerIDClaim()
+		reader    = instrumentReader(
+			c,
+			d.statistics,
+			m.measures.TrafficBytes.With("partnerid", partnerID, "direction", "received"),
+			m.measures.TrafficMessages.With("partnerid", partnerID, "direction", "received"),
+		)
+
+		writer = instrumentWriter(
+			c,
+			d.statistics,
+			m.measures.TrafficBytes.With("partnerid", partnerID, "direction", "sent"),
+			m.measures.TrafficMessages.With("partnerid", partnerID, "direction", "sent"),
+		)
+	)
+
 	closeOnce := new(sync.Once)
-	go m.readPump(d, InstrumentReader(c, d.statistics), closeOnce)
-	go m.writePump(d, InstrumentWriter(c, d.statistics), pinger, closeOnce)
+	pumping = true
+	go m.readPump(d, reader, closeOnce, upgraded)
+	go m.writePump(d, writer, pinger, closeOnce)
 
 	return d, nil
 }
@@ -256,26 +598,32 @@ func (m *manager) dispatch(e *Event) {
 // dispatches message failed events for any messages that were waiting to be delivered
 // at the time of pump closure.
 func (m *manager) pumpClose(d *device, c io.Closer, reason CloseReason) {
-	// remove will invoke requestClose()
-	m.devices.remove(d.id, reason)
+	// the device may have been closed earlier, e.g. when replaced by a duplicate or when a timer
+	// requested the close, in which case the original reason stands
+	d.requestClose(reason)
+	reason = d.CloseReason()
 
+	m.devices.remove(d.id, reason)
+	m.stopMissedPongs(d)
 	closeError := c.Close()
 
 	d.errorLog.Log(logging.MessageKey(), "Closed device connection",
-		"closeError", closeError, "reasonError", reason.Err, "reason", reason.Text,
+		"closeError", closeError, "reasonError", reason.Err, "reason", reason.Reason, "reasonText", reason.Text,
 		"finalStatistics", d.Statistics().String())
 
 	m.dispatch(
 		&Event{
-			Type:   Disconnect,
-			Device: d,
+			Type:        Disconnect,
+			Device:      d,
+			CloseReason: reason,
 		},
 	)
 	d.conveyClosure()
+	m.connections.Done()
 }
 
 func (m *manager) wrpSourceIsValid(message *wrp.Message, d *device) bool {
-	expectedID := d.ID()
+	expectedID := d.baseID
 	if len(strings.TrimSpace(message.Source)) == 0 {
 		d.errorLog.Log(logging.MessageKey(), "WRP source was empty", "trustLevel", d.Metadata().TrustClaim())
 		if m.enforceWRPSourceCheck {
@@ -311,6 +659,27 @@ func (m *manager) wrpSourceIsValid(message *wrp.Message, d *device) bool {
 	return true
 }
 
+// rejectMessage applies the InvalidMessagePolicy to a message which failed validation
+func (m *manager) rejectMessage(d *device, message *wrp.Message, err error) {
+	d.errorLog.Log(logging.MessageKey(), "rejecting invalid WRP message", "policy", m.invalidMessagePolicy, logging.ErrorKey(), err)
+	m.measures.InvalidMessage.With("policy", string(m.invalidMessagePolicy)).Add(1.0)
+
+	switch m.invalidMessagePolicy {
+	case InvalidMessageRespond:
+		if message.IsTransactionPart() {
+			// the response is queued like any other message, so avoid blocking the read pump
+			go func() {
+				if err := d.sendRequest(&Request{Message: errorResponse(message, http.StatusBadRequest, err), Format: d.format}); err != nil {
+					d.errorLog.Log(logging.MessageKey(), "unable to send error response", logging.ErrorKey(), err)
+				}
+			}()
+		}
+
+	case InvalidMessageDisconnect:
+		d.requestClose(CloseReason{Err: err, Reason: DisconnectInvalidMessage})
+	}
+}
+
 func addDeviceMetadataContext(message *wrp.Message, deviceMetadata *Metadata) {
 	message.PartnerIDs = []string{deviceMetadata.PartnerIDClaim()}
 
@@ -321,31 +690,75 @@ func addDeviceMetadataContext(message *wrp.Message, deviceMetadata *Metadata) {
 
 // readPump is the goroutine which handles the stream of WRP messages from a device.
 // This goroutine exits when any error occurs on the connection.
-func (m *manager) readPump(d *device, r ReadCloser, closeOnce *sync.Once) {
+func (m *manager) readPump(d *device, r ReadCloser, closeOnce *sync.Once, upgraded time.Time) {
 	defer d.debugLog.Log(logging.MessageKey(), "readPump exiting")
 	d.debugLog.Log(logging.MessageKey(), "readPump starting")
 
 	var (
 		readError error
-		decoder   = wrp.NewDecoder(nil, wrp.Msgpack)
+		decoder   = wrp.NewDecoder(nil, d.format)
 		encoder   = wrp.NewEncoder(nil, wrp.Msgpack)
+
+		firstMessage      = true
+		firstMessageTimer *time.Timer
 	)
 
+	if m.firstMessageTimeout > 0 {
+		firstMessageTimer = time.AfterFunc(m.firstMessageTimeout, func() {
+			d.errorLog.Log(logging.MessageKey(), "timed out waiting for first message", "timeout", m.firstMessageTimeout)
+			m.measures.PhaseTimeout.With("phase", FirstMessagePhase).Add(1.0)
+			d.requestClose(CloseReason{Err: ErrorFirstMessageTimeout, Reason: DisconnectFirstMessageTimeout})
+		})
+
+		defer firstMessageTimer.Stop()
+	}
+
+	if m.maxConnectionAge > 0 {
+		// the device is asked to reconnect, which forces it to authenticate again
+		maxAgeTimer := time.AfterFunc(m.maxConnectionAge, func() {
+			d.debugLog.Log(logging.MessageKey(), "connection reached its maximum age", "maxConnectionAge", m.maxConnectionAge)
+			d.requestClose(CloseReason{Err: ErrorMaxConnectionAge, Reason: DisconnectMaxConnectionAge, Code: websocket.CloseServiceRestart})
+		})
+
+		defer maxAgeTimer.Stop()
+	}
+
+	if !d.sessionExpires.IsZero() {
+		expiryTimer := time.AfterFunc(d.sessionExpires.Sub(m.now()), func() {
+			d.debugLog.Log(logging.MessageKey(), "device session expired", "expires", d.sessionExpires)
+			d.requestClose(CloseReason{Err: ErrorSessionExpired, Reason: DisconnectAuthExpired, Code: websocket.CloseServiceRestart})
+		})
+
+		defer expiryTimer.Stop()
+	}
+
 	// all the read pump has to do is ensure the device and the connection are closed
 	// it is the write pump's responsibility to do further cleanup
 	defer func() {
-		closeOnce.Do(func() { m.pumpClose(d, r, CloseReason{Err: readError, Text: "readerror"}) })
+		closeOnce.Do(func() { m.pumpClose(d, r, readCloseReason(readError)) })
 	}()
 
 	for {
-		messageType, data, readError := r.ReadMessage()
+		var (
+			messageType int
+			data        []byte
+		)
+
+		messageType, data, readError = r.ReadMessage()
 		if readError != nil {
 			d.errorLog.Log(logging.MessageKey(), "read error", logging.ErrorKey(), readError)
 			return
 		}
 
-		if messageType != websocket.BinaryMessage {
-			d.errorLog.Log(logging.MessageKey(), "skipping non-binary frame", "messageType", messageType)
+		if firstMessage {
+			firstMessage = false
+			if firstMessageTimer == nil || firstMessageTimer.Stop() {
+				m.measures.FirstMessage.Observe(m.now().Sub(upgraded).Seconds())
+			}
+		}
+
+		if messageType != frameType(d.format) {
+			d.errorLog.Log(logging.MessageKey(), "skipping frame of unexpected type", "messageType", messageType, "format", d.format)
 			continue
 		}
 
@@ -364,14 +777,23 @@ func (m *manager) readPump(d *device, r ReadCloser, closeOnce *sync.Once) {
 		err := decoder.Decode(message)
 		if err != nil {
 			d.errorLog.Log(logging.MessageKey(), "skipping malformed WRP message", logging.ErrorKey(), err)
+			d.breakerFailure(err)
 			continue
 		}
 
+		d.touch(m.now())
+		d.breaker.success()
+
 		if !m.wrpSourceIsValid(message, d) {
 			d.errorLog.Log(logging.MessageKey(), "skipping WRP message with invalid source")
 			continue
 		}
 
+		if err := m.validators.Validate(d, message); err != nil {
+			m.rejectMessage(d, message, err)
+			continue
+		}
+
 		if len(strings.TrimSpace(message.ContentType)) == 0 {
 			message.ContentType = DefaultWRPContentType
 		}
@@ -423,10 +845,11 @@ func (m *manager) writePump(d *device, w WriteCloser, pinger func() error, close
 
 	var (
 		envelope   *envelope
-		encoder    = wrp.NewEncoder(nil, wrp.Msgpack)
+		encoder    = wrp.NewEncoder(nil, d.format)
 		writeError error
 
 		pingTicker = time.NewTicker(m.pingPeriod)
+		limiter    = m.rateLimit.newLimiter()
 	)
 
 	// cleanup: we not only ensure that the device and connection are closed but also
@@ -434,7 +857,7 @@ func (m *manager) writePump(d *device, w WriteCloser, pinger func() error, close
 	// the configured listener
 	defer func() {
 		pingTicker.Stop()
-		closeOnce.Do(func() { m.pumpClose(d, w, CloseReason{Err: writeError, Text: "write-error"}) })
+		closeOnce.Do(func() { m.pumpClose(d, w, writeCloseReason(writeError)) })
 
 		// notify listener of any message that just now failed
 		// any writeError is passed via this event
@@ -454,70 +877,176 @@ func (m *manager) writePump(d *device, w WriteCloser, pinger func() error, close
 		//
 		// Nil is passed explicitly as the error to indicate that these messages failed due
 		// to the device disconnecting, not due to an actual I/O error.
-		for {
-			select {
-			case undeliverable := <-d.messages:
-				d.errorLog.Log(logging.MessageKey(), "undeliverable message", "deviceMessage", undeliverable)
-				m.dispatch(&Event{
-					Type:     MessageFailed,
-					Device:   d,
-					Message:  undeliverable.request.Message,
-					Format:   undeliverable.request.Format,
-					Contents: undeliverable.request.Contents,
-					Error:    writeError,
-				})
-			default:
-				return
-			}
+		for undeliverable := d.messages.poll(); undeliverable != nil; undeliverable = d.messages.poll() {
+			d.errorLog.Log(logging.MessageKey(), "undeliverable message", "deviceMessage", undeliverable)
+			m.dispatch(&Event{
+				Type:     MessageFailed,
+				Device:   d,
+				Message:  undeliverable.request.Message,
+				Format:   undeliverable.request.Format,
+				Contents: undeliverable.request.Contents,
+				Error:    writeError,
+			})
 		}
 	}()
 
 	for writeError == nil {
 		envelope = nil
 
+		// check for shutdown first, then prefer higher QOS tiers whenever more than one has messages waiting
 		select {
 		case <-d.shutdown:
-			d.debugLog.Log(logging.MessageKey(), "explicit shutdown")
-			writeError = w.Close()
-			return
+		default:
+			envelope = d.messages.poll()
+		}
 
-		case envelope = <-d.messages:
-			var frameContents []byte
-			if envelope.request.Format == wrp.Msgpack && len(envelope.request.Contents) > 0 {
-				frameContents = envelope.request.Contents
-			} else {
-				// if the request was in a format other than Msgpack, or if the caller did not pass
-				// Contents, then do the encoding here.
-				encoder.ResetBytes(&frameContents)
-				writeError = encoder.Encode(envelope.request.Message)
-				encoder.ResetBytes(nil)
-			}
+		if envelope == nil {
+			select {
+			case <-d.shutdown:
+				d.debugLog.Log(logging.MessageKey(), "explicit shutdown")
+				m.writeCloseFrame(d, w)
+				writeError = w.Close()
+				return
 
-			if writeError == nil {
-				writeError = w.WriteMessage(websocket.BinaryMessage, frameContents)
+			case envelope = <-d.messages.tiers[QOSCritical]:
+			case envelope = <-d.messages.tiers[QOSHigh]:
+			case envelope = <-d.messages.tiers[QOSMedium]:
+			case envelope = <-d.messages.tiers[QOSLow]:
+
+			case <-pingTicker.C:
+				writeError = pinger()
+				continue
 			}
+		}
 
-			event := Event{
-				Device:   d,
-				Message:  envelope.request.Message,
-				Format:   envelope.request.Format,
-				Contents: envelope.request.Contents,
-				Error:    writeError,
+		if throttleError := m.throttle(d, limiter, envelope); throttleError != nil {
+			m.failEnvelope(d, envelope, throttleError)
+			if throttleError == ErrorRateLimited && m.rateLimit.policy() == RateLimitDisconnect {
+				d.errorLog.Log(logging.MessageKey(), "disconnecting device which exceeded its outbound rate limit")
+				d.requestClose(CloseReason{Err: throttleError, Reason: DisconnectRateLimited})
 			}
 
-			if writeError != nil {
-				envelope.complete <- writeError
-				event.Type = MessageFailed
-			} else {
-				event.Type = MessageSent
+			continue
+		}
+
+		var frameContents []byte
+		if envelope.request.Format == d.format && len(envelope.request.Contents) > 0 {
+			frameContents = envelope.request.Contents
+		} else {
+			// if the request was in a format other than the connection's format, or if the caller did not pass
+			// Contents, then do the encoding here.
+			encoder.ResetBytes(&frameContents)
+			encodeError := encoder.Encode(envelope.request.Message)
+			encoder.ResetBytes(nil)
+
+			if encodeError != nil && d.breaker != nil {
+				// with a breaker, a message which cannot be encoded fails on its own rather than closing the connection
+				d.errorLog.Log(logging.MessageKey(), "unable to encode message", logging.ErrorKey(), encodeError)
+				m.failEnvelope(d, envelope, encodeError)
+				d.breakerFailure(encodeError)
+				continue
 			}
 
-			close(envelope.complete)
-			m.dispatch(&event)
+			writeError = encodeError
+		}
+
+		if writeError == nil {
+			writeError = w.WriteMessage(frameType(d.format), frameContents)
+		}
+
+		event := Event{
+			Device:   d,
+			Message:  envelope.request.Message,
+			Format:   envelope.request.Format,
+			Contents: envelope.request.Contents,
+			Error:    writeError,
+		}
+
+		if writeError != nil {
+			envelope.complete <- writeError
+			event.Type = MessageFailed
+		} else {
+			event.Type = MessageSent
+		}
+
+		close(envelope.complete)
+		m.dispatch(&event)
+	}
+}
+
+// failEnvelope completes a message which could not be sent to a device, without closing the connection
+func (m *manager) failEnvelope(d *device, envelope *envelope, err error) {
+	envelope.complete <- err
+	close(envelope.complete)
+	m.dispatch(&Event{
+		Type:     MessageFailed,
+		Device:   d,
+		Message:  envelope.request.Message,
+		Format:   envelope.request.Format,
+		Contents: envelope.request.Contents,
+		Error:    err,
+	})
+}
+
+// writeCloseFrame sends a websocket close frame to a device, if its CloseReason has a Code.
+// Any error is logged but otherwise ignored, as the connection is being closed anyway.
+func (m *manager) writeCloseFrame(d *device, w Writer) {
+	reason := d.CloseReason()
+	if reason.Code == 0 {
+		return
+	}
+
+	err := w.SetWriteDeadline(m.writeDeadline())
+	if err == nil {
+		err = w.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(reason.Code, reason.Text))
+	}
+
+	if err != nil {
+		d.errorLog.Log(logging.MessageKey(), "unable to write close frame", "code", reason.Code, logging.ErrorKey(), err)
+	}
+}
 
-		case <-pingTicker.C:
-			writeError = pinger()
+func (m *manager) Shutdown(ctx context.Context) error {
+	m.shutdownLock.Lock()
+	m.shuttingDown = true
+	m.shutdownLock.Unlock()
+
+	m.stopReaper.Do(func() { close(m.reaperStop) })
+
+	var ids []ID
+	m.devices.visit(func(d *device) bool {
+		ids = append(ids, d.id)
+		return true
+	})
+
+	m.logger.Log(logging.MessageKey(), "shutting down", "deviceCount", len(ids), "rate", m.shutdownRate)
+
+	ticker := time.NewTicker(time.Second / time.Duration(m.shutdownRate))
+
+	defer ticker.Stop()
+	for i, id := range ids {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+			}
 		}
+
+		m.devices.remove(id, shutdownCloseReason)
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		m.connections.Wait()
+		close(closed)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-closed:
+		return nil
 	}
 }
 
@@ -551,11 +1080,71 @@ func (m *manager) VisitAll(visitor func(Interface) bool) int {
 }
 
 func (m *manager) Route(request *Request) (*Response, error) {
-	if destination, err := request.ID(); err != nil {
+	if destination, err := request.ParseID(m.destinationParsing); err != nil {
 		return nil, err
 	} else if d, ok := m.devices.get(destination); ok {
-		return d.Send(request)
+		if !d.breaker.allow() {
+			return breakerOpenResponse(d, request)
+		}
+
+		response, err := d.Send(request)
+		if err == ErrorDeviceBusy {
+			m.measures.QOSDropped.With("qos", request.QOS().Level().String()).Add(1.0)
+		}
+
+		return response, err
 	} else {
 		return nil, ErrorDeviceNotFound
 	}
 }
+
+func (m *manager) Broadcast(ctx context.Context, message *wrp.Message, filter func(Interface) bool) (int, error) {
+	if message.Type != wrp.SimpleEventMessageType {
+		return 0, ErrorBroadcastNotEvent
+	}
+
+	// encode once, rather than once per device
+	var contents []byte
+	if err := wrp.NewEncoderBytes(&contents, wrp.Msgpack).Encode(message); err != nil {
+		return 0, err
+	}
+
+	var targets []*device
+	m.devices.visit(func(d *device) bool {
+		if filter == nil || filter(d) {
+			targets = append(targets, d)
+		}
+
+		return true
+	})
+
+	m.debugLog.Log(logging.MessageKey(), "broadcasting", "destination", message.Destination, "deviceCount", len(targets), "rate", m.broadcastRate)
+
+	var (
+		request = (&Request{Message: message, Format: wrp.Msgpack, Contents: contents}).WithContext(ctx)
+		ticker  = time.NewTicker(time.Second / time.Duration(m.broadcastRate))
+		sent    = 0
+	)
+
+	defer ticker.Stop()
+	for i, d := range targets {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return sent, ctx.Err()
+			case <-ticker.C:
+			}
+		}
+
+		if _, err := d.Send(request); err != nil {
+			d.errorLog.Log(logging.MessageKey(), "unable to broadcast to device", logging.ErrorKey(), err)
+			m.measures.Broadcast.With("outcome", "failed").Add(1.0)
+			continue
+		}
+
+		sent++
+		m.measures.Broadcast.With("outcome", "sent").Add(1.0)
+	}
+
+	return sent, nil
+}
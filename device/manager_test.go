@@ -1,11 +1,17 @@
 package device
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -14,8 +20,11 @@ import (
 	"github.com/go-kit/kit/metrics"
 
 	"github.com/xmidt-org/webpa-common/convey"
+	"github.com/xmidt-org/webpa-common/convey/conveyhttp"
 	"github.com/xmidt-org/webpa-common/xmetrics"
+	"github.com/xmidt-org/webpa-common/xmetrics/xmetricstest"
 
+	"github.com/gorilla/websocket"
 	"github.com/justinas/alice"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -118,6 +127,53 @@ func testManagerConnectUpgradeError(t *testing.T) {
 	assert.Error(actualError)
 }
 
+func testManagerConnectCertificateBinding(t *testing.T) {
+	testData := []struct {
+		state          *tls.ConnectionState
+		expectedError  error
+		expectedReason string
+	}{
+		{nil, ErrorMissingClientCertificate, certificateMissingReason},
+		{&tls.ConnectionState{}, ErrorMissingClientCertificate, certificateMissingReason},
+		{
+			&tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "mac:000000000000"}}},
+			},
+			ErrorCertificateMismatch,
+			certificateMismatchReason,
+		},
+	}
+
+	for i, record := range testData {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var (
+				assert   = assert.New(t)
+				provider = xmetricstest.NewProvider(nil, Metrics)
+				manager  = NewManager(&Options{
+					Logger:                 log.NewNopLogger(),
+					BindClientCertificates: true,
+					MetricsProvider:        provider,
+					Listeners: []Listener{
+						func(e *Event) {
+							assert.Fail("The listener should not have been called")
+						},
+					},
+				})
+
+				response = httptest.NewRecorder()
+				request  = WithIDRequest(ID("mac:123412341234"), httptest.NewRequest("GET", "http://localhost.com", nil))
+			)
+
+			request.TLS = record.state
+			device, actualError := manager.Connect(response, request, nil)
+			assert.Nil(device)
+			assert.Equal(record.expectedError, actualError)
+			assert.Equal(http.StatusForbidden, response.Code)
+			provider.Assert(t, CertMismatchCounter, "reason", record.expectedReason)(xmetricstest.Value(1.0))
+		})
+	}
+}
+
 func testManagerConnectVisit(t *testing.T) {
 	var (
 		assert      = assert.New(t)
@@ -279,6 +335,25 @@ func testManagerRouteBadDestination(t *testing.T) {
 	assert.Error(err)
 }
 
+func testManagerRouteStrictDestination(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		request = &Request{
+			Message: &wrp.Message{
+				Destination: "mac:112233445566/bad service!",
+			},
+		}
+
+		manager = NewManager(&Options{
+			DestinationParsing: ParseOptions{Mode: ParseStrict},
+		})
+	)
+
+	response, err := manager.Route(request)
+	assert.Nil(response)
+	assert.Equal(ErrorInvalidServiceName, err)
+}
+
 func testManagerRouteDeviceNotFound(t *testing.T) {
 	var (
 		assert  = assert.New(t)
@@ -296,6 +371,148 @@ func testManagerRouteDeviceNotFound(t *testing.T) {
 	assert.Equal(ErrorDeviceNotFound, err)
 }
 
+func testManagerRouteQOSDropped(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		counter = newTestCounter()
+		m       = NewManager(&Options{
+			Logger: logging.NewTestLogger(nil, t),
+			QOSTiers: map[string]QOSTier{
+				"low": {QueueSize: 1, DropWhenFull: true},
+			},
+		}).(*manager)
+
+		d = newDevice(deviceOptions{ID: ID("mac:112233445566"), QOSTiers: m.qosTiers, Logger: m.logger})
+	)
+
+	m.measures.QOSDropped = counter
+	require.NoError(m.devices.add(d))
+	d.messages.tiers[QOSLow] <- new(envelope)
+
+	response, err := m.Route(&Request{
+		Message: &wrp.Message{
+			Type:        wrp.SimpleEventMessageType,
+			Destination: "mac:112233445566",
+			Metadata:    map[string]string{QOSMetadataKey: "10"},
+		},
+	})
+
+	assert.Nil(response)
+	assert.Equal(ErrorDeviceBusy, err)
+	assert.Equal(1.0, counter.count)
+	assert.Equal(map[string]string{"qos": "low"}, counter.labelPairs)
+}
+
+func testManagerBroadcastNotEvent(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		manager = NewManager(nil)
+	)
+
+	sent, err := manager.(Broadcaster).Broadcast(
+		context.Background(),
+		&wrp.Message{Type: wrp.SimpleRequestResponseMessageType, Destination: "mac:112233445566"},
+		nil,
+	)
+
+	assert.Zero(sent)
+	assert.Equal(ErrorBroadcastNotEvent, err)
+}
+
+func testManagerBroadcastFiltered(t *testing.T) {
+	var (
+		assert      = assert.New(t)
+		require     = require.New(t)
+		p           = xmetricstest.NewProvider(nil, Metrics)
+		connectWait = new(sync.WaitGroup)
+
+		options = &Options{
+			Logger:          logging.NewTestLogger(nil, t),
+			MetricsProvider: p,
+			BroadcastRate:   1000,
+			Listeners: []Listener{
+				func(event *Event) {
+					if event.Type == Connect {
+						connectWait.Done()
+					}
+				},
+			},
+		}
+
+		excluded = testDeviceIDs[0]
+	)
+
+	connectWait.Add(len(testDeviceIDs))
+	manager, server, connectURL := startWebsocketServer(options)
+	defer server.Close()
+
+	testDevices := connectTestDevices(t, DefaultDialer(), connectURL)
+	defer closeTestDevices(assert, testDevices)
+	connectWait.Wait()
+
+	sent, err := manager.(Broadcaster).Broadcast(
+		context.Background(),
+		&wrp.Message{Type: wrp.SimpleEventMessageType, Source: "test", Destination: "event:firmware", Payload: []byte("announcement")},
+		func(d Interface) bool { return d.ID() != excluded },
+	)
+
+	require.NoError(err)
+	assert.Equal(len(testDeviceIDs)-1, sent)
+	p.Assert(t, BroadcastCounter, "outcome", "sent")(xmetricstest.Value(float64(len(testDeviceIDs) - 1)))
+	p.Assert(t, BroadcastCounter, "outcome", "failed")(xmetricstest.Value(0.0))
+
+	for id, c := range testDevices {
+		if id == excluded {
+			continue
+		}
+
+		messageType, data, err := c.ReadMessage()
+		require.NoError(err)
+		assert.Equal(websocket.BinaryMessage, messageType)
+
+		var message wrp.Message
+		require.NoError(wrp.NewDecoderBytes(data, wrp.Msgpack).Decode(&message))
+		assert.Equal("event:firmware", message.Destination)
+		assert.Equal("announcement", string(message.Payload))
+	}
+}
+
+func testManagerBroadcastCancelled(t *testing.T) {
+	var (
+		assert      = assert.New(t)
+		require     = require.New(t)
+		connectWait = new(sync.WaitGroup)
+
+		options = &Options{
+			Logger:        logging.NewTestLogger(nil, t),
+			BroadcastRate: 1,
+			Listeners: []Listener{
+				func(event *Event) {
+					if event.Type == Connect {
+						connectWait.Done()
+					}
+				},
+			},
+		}
+	)
+
+	connectWait.Add(len(testDeviceIDs))
+	manager, server, connectURL := startWebsocketServer(options)
+	defer server.Close()
+
+	testDevices := connectTestDevices(t, DefaultDialer(), connectURL)
+	defer closeTestDevices(assert, testDevices)
+	connectWait.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	sent, err := manager.(Broadcaster).Broadcast(ctx, &wrp.Message{Type: wrp.SimpleEventMessageType, Destination: "event:test"}, nil)
+	require.Equal(context.DeadlineExceeded, err)
+	assert.Equal(1, sent)
+}
+
 func testManagerConnectIncludesConvey(t *testing.T) {
 	var (
 		assert      = assert.New(t)
@@ -359,21 +576,927 @@ func testManagerConnectIncludesConvey(t *testing.T) {
 	assert.Equal("WebPA-1.6", convey["webpa-protocol"])
 }
 
+func testManagerConnectCompression(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("enabled=%t", enabled), func(t *testing.T) {
+			var (
+				assert      = assert.New(t)
+				require     = require.New(t)
+				connectWait = new(sync.WaitGroup)
+				devices     = make(chan Interface, 1)
+
+				options = &Options{
+					Logger:            log.NewNopLogger(),
+					EnableCompression: enabled,
+					Listeners: []Listener{
+						func(event *Event) {
+							if event.Type == Connect {
+								defer connectWait.Done()
+								devices <- event.Device
+							}
+						},
+					},
+				}
+
+				manager, server, connectURL = startWebsocketServer(options)
+				dialer                      = NewDialer(DialerOptions{
+					WSDialer: &websocket.Dialer{EnableCompression: true},
+				})
+			)
+
+			defer server.Close()
+			connectWait.Add(1)
+
+			deviceConnection, _, err := dialer.DialDevice(string(testDeviceIDs[0]), connectURL, nil)
+			require.NoError(err)
+			defer deviceConnection.Close()
+
+			connectWait.Wait()
+			d := <-devices
+			assert.Equal(enabled, d.Statistics().Compressed())
+
+			_, err = manager.Route(&Request{
+				Message: &wrp.Message{
+					Type:        wrp.SimpleEventMessageType,
+					Source:      "test",
+					Destination: string(testDeviceIDs[0]),
+					Payload:     []byte(strings.Repeat("highly compressible ", 100)),
+				},
+			})
+
+			require.NoError(err)
+			_, _, err = deviceConnection.ReadMessage()
+			require.NoError(err)
+
+			statistics := d.Statistics()
+			require.Eventually(
+				func() bool { return statistics.MessagesSent() == 1 },
+				5*time.Second,
+				10*time.Millisecond,
+			)
+
+			if enabled {
+				// the wire bytes include the frame header, but the payload compresses well
+				assert.True(statistics.CompressedBytesSent() > 0)
+				assert.True(statistics.CompressedBytesSent() < statistics.BytesSent())
+				assert.True(statistics.CompressionRatio() > 0.0)
+				assert.True(statistics.CompressionRatio() < 0.5)
+			} else {
+				assert.Zero(statistics.CompressedBytesSent())
+				assert.Zero(statistics.CompressionRatio())
+			}
+		})
+	}
+}
+
+func testManagerConnectRejectDuplicate(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		p       = xmetricstest.NewProvider(nil, Metrics)
+
+		connectWait = make(chan struct{}, 1)
+		options     = &Options{
+			Logger:          logging.NewTestLogger(nil, t),
+			MetricsProvider: p,
+			DuplicatePolicy: DuplicateRejectNew,
+			Listeners: []Listener{
+				func(event *Event) {
+					if event.Type == Connect {
+						connectWait <- struct{}{}
+					}
+				},
+			},
+		}
+	)
+
+	manager, server, connectURL := startWebsocketServer(options)
+	defer server.Close()
+
+	initial, _, err := DefaultDialer().DialDevice(string(testDeviceIDs[0]), connectURL, nil)
+	require.NoError(err)
+	defer initial.Close()
+	<-connectWait
+
+	_, response, err := DefaultDialer().DialDevice(string(testDeviceIDs[0]), connectURL, nil)
+	assert.Error(err)
+	require.NotNil(response)
+	assert.Equal(http.StatusConflict, response.StatusCode)
+	assert.Equal(1, manager.Len())
+	p.Assert(t, DuplicatePolicyCounter, "policy", string(DuplicateRejectNew))(xmetricstest.Value(1.0))
+	p.Assert(t, DuplicatesCounter)(xmetricstest.Value(1.0))
+}
+
+func testManagerConnectConveyNotAvailable(t *testing.T) {
+	testData := []struct {
+		policy             ConveyNotAvailablePolicy
+		expectedConvey     convey.C
+		expectedCompliance convey.Compliance
+	}{
+		{"", nil, convey.Invalid},
+		{ConveyNotAvailableLog, nil, convey.Invalid},
+		{ConveyNotAvailableIgnore, nil, convey.Missing},
+		{ConveyNotAvailableAccept, convey.C{}, convey.Full},
+	}
+
+	for _, record := range testData {
+		t.Run(string(record.policy), func(t *testing.T) {
+			var (
+				assert  = assert.New(t)
+				require = require.New(t)
+				p       = xmetricstest.NewProvider(nil, Metrics)
+				devices = make(chan Interface, 1)
+
+				options = &Options{
+					Logger:                   logging.NewTestLogger(nil, t),
+					MetricsProvider:          p,
+					ConveyNotAvailablePolicy: record.policy,
+					Listeners: []Listener{
+						func(event *Event) {
+							if event.Type == Connect {
+								devices <- event.Device
+							}
+						},
+					},
+				}
+
+				_, server, connectURL = startWebsocketServer(options)
+			)
+
+			defer server.Close()
+
+			deviceConnection, _, err := DefaultDialer().DialDevice(
+				string(testDeviceIDs[0]),
+				connectURL,
+				http.Header{ConveyHeader: {conveyhttp.NotAvailableValue}},
+			)
+
+			require.NoError(err)
+			defer deviceConnection.Close()
+
+			d := <-devices
+			assert.Equal(record.expectedConvey, d.Convey())
+			assert.Equal(record.expectedCompliance, d.ConveyCompliance())
+			p.Assert(t, ConveyNotAvailableCounter, "policy", string(record.policy.normalize()))(xmetricstest.Value(1.0))
+		})
+	}
+
+	t.Run(string(ConveyNotAvailableReject), func(t *testing.T) {
+		var (
+			assert  = assert.New(t)
+			require = require.New(t)
+			p       = xmetricstest.NewProvider(nil, Metrics)
+
+			options = &Options{
+				Logger:                   logging.NewTestLogger(nil, t),
+				MetricsProvider:          p,
+				ConveyNotAvailablePolicy: ConveyNotAvailableReject,
+			}
+
+			manager, server, connectURL = startWebsocketServer(options)
+		)
+
+		defer server.Close()
+
+		_, response, err := DefaultDialer().DialDevice(
+			string(testDeviceIDs[0]),
+			connectURL,
+			http.Header{ConveyHeader: {conveyhttp.NotAvailableValue}},
+		)
+
+		assert.Error(err)
+		require.NotNil(response)
+		assert.Equal(http.StatusBadRequest, response.StatusCode)
+		assert.Equal(0, manager.Len())
+		p.Assert(t, ConveyNotAvailableCounter, "policy", string(ConveyNotAvailableReject))(xmetricstest.Value(1.0))
+	})
+}
+
+func testManagerConnectConveyMetadata(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		devices = make(chan Interface, 1)
+
+		options = &Options{
+			Logger: logging.NewTestLogger(nil, t),
+			ConveyMetadata: []MetadataField{
+				{Convey: "hw-serial-number", Key: "serial", Type: MetadataInt},
+				{Convey: "webpa-protocol", Key: "protocol"},
+			},
+			Listeners: []Listener{
+				func(event *Event) {
+					if event.Type == Connect {
+						devices <- event.Device
+					}
+				},
+			},
+		}
+
+		_, server, connectURL = startWebsocketServer(options)
+	)
+
+	defer server.Close()
+
+	value, err := convey.WriteString(convey.NewTranslator(nil), convey.C{"hw-serial-number": 123456789, "webpa-protocol": "WebPA-1.6"})
+	require.NoError(err)
+
+	deviceConnection, _, err := DefaultDialer().DialDevice(string(testDeviceIDs[0]), connectURL, http.Header{ConveyHeader: {value}})
+	require.NoError(err)
+	defer deviceConnection.Close()
+
+	d := <-devices
+	assert.Equal(123456789, d.Metadata().Load("serial"))
+	assert.Equal("WebPA-1.6", d.Metadata().Load("protocol"))
+}
+
+func testManagerConnectConveyOversized(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		p       = xmetricstest.NewProvider(nil, Metrics)
+		devices = make(chan Interface, 1)
+
+		options = &Options{
+			Logger:                logging.NewTestLogger(nil, t),
+			MetricsProvider:       p,
+			ConveyMaxHeaderLength: 16,
+			Listeners: []Listener{
+				func(event *Event) {
+					if event.Type == Connect {
+						devices <- event.Device
+					}
+				},
+			},
+		}
+
+		_, server, connectURL = startWebsocketServer(options)
+	)
+
+	defer server.Close()
+
+	value, err := convey.WriteString(convey.NewTranslator(nil), convey.C{"webpa-protocol": "WebPA-1.6"})
+	require.NoError(err)
+	require.True(len(value) > 16)
+
+	deviceConnection, _, err := DefaultDialer().DialDevice(string(testDeviceIDs[0]), connectURL, http.Header{ConveyHeader: {value}})
+	require.NoError(err)
+	defer deviceConnection.Close()
+
+	d := <-devices
+	assert.Equal(convey.C(nil), d.Convey())
+	assert.Equal(convey.Invalid, d.ConveyCompliance())
+	p.Assert(t, ConveyOversizedCounter, conveyhttp.LimitLabel, conveyhttp.HeaderLimit)(xmetricstest.Value(1.0))
+}
+
+func testManagerConnectWRPFormat(t *testing.T) {
+	testData := []struct {
+		name         string
+		subprotocols []string
+		header       string
+		expected     wrp.Format
+	}{
+		{"Default", nil, "", wrp.Msgpack},
+		{"JSONSubprotocol", []string{"other", JSONSubprotocol}, "", wrp.JSON},
+		{"MsgpackSubprotocol", []string{MsgpackSubprotocol}, "application/json", wrp.Msgpack},
+		{"JSONHeader", nil, "application/json", wrp.JSON},
+		{"MsgpackHeader", nil, "application/msgpack", wrp.Msgpack},
+	}
+
+	for _, record := range testData {
+		t.Run(record.name, func(t *testing.T) {
+			var (
+				assert  = assert.New(t)
+				require = require.New(t)
+
+				connected = make(chan struct{}, 1)
+				received  = make(chan *wrp.Message, 1)
+
+				options = &Options{
+					Logger: logging.NewTestLogger(nil, t),
+					Listeners: []Listener{
+						func(event *Event) {
+							switch event.Type {
+							case Connect:
+								connected <- struct{}{}
+							case MessageReceived:
+								received <- event.Message.(*wrp.Message)
+							}
+						},
+					},
+				}
+
+				dialer = NewDialer(DialerOptions{
+					WSDialer: &websocket.Dialer{Subprotocols: record.subprotocols},
+				})
+
+				extra http.Header
+			)
+
+			if len(record.header) > 0 {
+				extra = http.Header{WRPFormatHeader: []string{record.header}}
+			}
+
+			manager, server, connectURL := startWebsocketServer(options)
+			defer server.Close()
+
+			c, _, err := dialer.DialDevice(string(testDeviceIDs[0]), connectURL, extra)
+			require.NoError(err)
+			defer c.Close()
+			<-connected
+
+			var data []byte
+			require.NoError(wrp.NewEncoderBytes(&data, record.expected).Encode(&wrp.Message{
+				Type:        wrp.SimpleEventMessageType,
+				Source:      string(testDeviceIDs[0]),
+				Destination: "event:test",
+				Payload:     []byte("inbound"),
+			}))
+
+			require.NoError(c.WriteMessage(frameType(record.expected), data))
+			select {
+			case message := <-received:
+				assert.Equal("inbound", string(message.Payload))
+			case <-time.After(5 * time.Second):
+				require.Fail("The inbound message was not dispatched")
+			}
+
+			_, err = manager.Route(&Request{
+				Message: &wrp.Message{
+					Type:        wrp.SimpleEventMessageType,
+					Source:      "test",
+					Destination: string(testDeviceIDs[0]),
+					Payload:     []byte("outbound"),
+				},
+			})
+
+			require.NoError(err)
+			messageType, data, err := c.ReadMessage()
+			require.NoError(err)
+			assert.Equal(frameType(record.expected), messageType)
+
+			var message wrp.Message
+			require.NoError(wrp.NewDecoderBytes(data, record.expected).Decode(&message))
+			assert.Equal("outbound", string(message.Payload))
+		})
+	}
+}
+
+func testManagerConnectUnsupportedWRPFormat(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		manager, server, connectURL = startWebsocketServer(&Options{Logger: logging.NewTestLogger(nil, t)})
+	)
+
+	defer server.Close()
+
+	_, response, err := DefaultDialer().DialDevice(
+		string(testDeviceIDs[0]),
+		connectURL,
+		http.Header{WRPFormatHeader: []string{"text/plain"}},
+	)
+
+	assert.Error(err)
+	require.NotNil(response)
+	assert.Equal(http.StatusBadRequest, response.StatusCode)
+	assert.Equal(0, manager.Len())
+}
+
+func testManagerConnectSubprotocol(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		connected = make(chan Interface, 1)
+
+		options = &Options{
+			Logger:       logging.NewTestLogger(nil, t),
+			Subprotocols: []string{"wrp.v2", MsgpackSubprotocol},
+			Listeners: []Listener{
+				func(event *Event) {
+					if event.Type == Connect {
+						connected <- event.Device
+					}
+				},
+			},
+		}
+	)
+
+	_, server, connectURL := startWebsocketServer(options)
+	defer server.Close()
+
+	c, _, err := DefaultDialer().DialDevice(
+		string(testDeviceIDs[0]),
+		connectURL,
+		http.Header{"Sec-Websocket-Protocol": []string{MsgpackSubprotocol + ", wrp.v2"}},
+	)
+
+	require.NoError(err)
+	defer c.Close()
+	assert.Equal("wrp.v2", c.Subprotocol())
+
+	select {
+	case d := <-connected:
+		assert.Equal("wrp.v2", d.(SubprotocolProvider).Subprotocol())
+	case <-time.After(5 * time.Second):
+		require.Fail("The device did not connect")
+	}
+}
+
+func testManagerConnectUnsupportedSubprotocol(t *testing.T) {
+	testData := []struct {
+		name    string
+		options Options
+		header  http.Header
+	}{
+		{
+			name:    "Unsupported",
+			options: Options{Subprotocols: []string{"wrp.v2"}},
+			header:  http.Header{"Sec-Websocket-Protocol": []string{"wrp.v1"}},
+		},
+		{
+			name:    "Required",
+			options: Options{Subprotocols: []string{"wrp.v2"}, RequireSubprotocol: true},
+		},
+		{
+			name:    "RequiredWRP",
+			options: Options{RequireSubprotocol: true},
+			header:  http.Header{"Sec-Websocket-Protocol": []string{"chat"}},
+		},
+	}
+
+	for _, record := range testData {
+		t.Run(record.name, func(t *testing.T) {
+			var (
+				assert  = assert.New(t)
+				require = require.New(t)
+				options = record.options
+			)
+
+			options.Logger = logging.NewTestLogger(nil, t)
+			manager, server, connectURL := startWebsocketServer(&options)
+			defer server.Close()
+
+			_, response, err := DefaultDialer().DialDevice(string(testDeviceIDs[0]), connectURL, record.header)
+			assert.Error(err)
+			require.NotNil(response)
+			assert.Equal(http.StatusBadRequest, response.StatusCode)
+			assert.Equal(0, manager.Len())
+		})
+	}
+}
+
+func testManagerFirstMessageTimeout(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		p       = xmetricstest.NewProvider(nil, Metrics)
+
+		connected    = make(chan struct{}, 1)
+		disconnected = make(chan CloseReason, 1)
+
+		options = &Options{
+			Logger:              logging.NewTestLogger(nil, t),
+			MetricsProvider:     p,
+			FirstMessageTimeout: 100 * time.Millisecond,
+			Listeners: []Listener{
+				func(event *Event) {
+					switch event.Type {
+					case Connect:
+						connected <- struct{}{}
+					case Disconnect:
+						disconnected <- event.CloseReason
+					}
+				},
+			},
+		}
+	)
+
+	_, server, connectURL := startWebsocketServer(options)
+	defer server.Close()
+
+	c, _, err := DefaultDialer().DialDevice(string(testDeviceIDs[0]), connectURL, nil)
+	require.NoError(err)
+	defer c.Close()
+	<-connected
+
+	select {
+	case reason := <-disconnected:
+		assert.Equal(CloseReason{Err: ErrorFirstMessageTimeout, Reason: DisconnectFirstMessageTimeout, Text: "first-message-timeout"}, reason)
+	case <-time.After(5 * time.Second):
+		require.Fail("The device was not disconnected")
+	}
+
+	p.Assert(t, HandshakeTimeoutCounter, "phase", FirstMessagePhase)(xmetricstest.Value(1.0))
+}
+
+func testManagerMaxConnectionAge(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		p       = xmetricstest.NewProvider(nil, Metrics)
+
+		connected    = make(chan struct{}, 1)
+		disconnected = make(chan CloseReason, 1)
+
+		options = &Options{
+			Logger:           logging.NewTestLogger(nil, t),
+			MetricsProvider:  p,
+			MaxConnectionAge: 100 * time.Millisecond,
+			Listeners: []Listener{
+				func(event *Event) {
+					switch event.Type {
+					case Connect:
+						connected <- struct{}{}
+					case Disconnect:
+						disconnected <- event.CloseReason
+					}
+				},
+			},
+		}
+	)
+
+	_, server, connectURL := startWebsocketServer(options)
+	defer server.Close()
+
+	c, _, err := DefaultDialer().DialDevice(string(testDeviceIDs[0]), connectURL, nil)
+	require.NoError(err)
+	defer c.Close()
+	<-connected
+
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = c.ReadMessage()
+	require.Error(err)
+	assert.True(websocket.IsCloseError(err, websocket.CloseServiceRestart), "expected a service restart close frame, got %s", err)
+
+	select {
+	case reason := <-disconnected:
+		assert.Equal(ErrorMaxConnectionAge, reason.Err)
+		assert.Equal(DisconnectMaxConnectionAge, reason.Reason)
+		assert.Equal(websocket.CloseServiceRestart, reason.Code)
+	case <-time.After(5 * time.Second):
+		require.Fail("The device was not disconnected")
+	}
+
+	p.Assert(t, DisconnectCounter, "reason", string(DisconnectMaxConnectionAge))(xmetricstest.Value(1.0))
+}
+
+func testManagerDisconnectReason(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		p       = xmetricstest.NewProvider(nil, Metrics)
+
+		connected    = make(chan struct{}, 1)
+		disconnected = make(chan CloseReason, 1)
+
+		options = &Options{
+			Logger:          logging.NewTestLogger(nil, t),
+			MetricsProvider: p,
+			Listeners: []Listener{
+				func(event *Event) {
+					switch event.Type {
+					case Connect:
+						connected <- struct{}{}
+					case Disconnect:
+						disconnected <- event.CloseReason
+					}
+				},
+			},
+		}
+	)
+
+	_, server, connectURL := startWebsocketServer(options)
+	defer server.Close()
+
+	c, _, err := DefaultDialer().DialDevice(string(testDeviceIDs[0]), connectURL, nil)
+	require.NoError(err)
+	defer c.Close()
+	<-connected
+
+	require.NoError(c.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")))
+	select {
+	case reason := <-disconnected:
+		assert.Equal(DisconnectDeviceClosed, reason.Reason)
+		assert.Equal("device-closed", reason.Text)
+		assert.Error(reason.Err)
+	case <-time.After(5 * time.Second):
+		require.Fail("The device was not disconnected")
+	}
+
+	p.Assert(t, DisconnectCounter, "reason", string(DisconnectDeviceClosed))(xmetricstest.Value(1.0))
+}
+
+func testManagerReadPumpValidators(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		connected    = make(chan struct{}, 1)
+		disconnected = make(chan CloseReason, 1)
+		received     = make(chan *wrp.Message, 2)
+
+		options = &Options{
+			Logger:               logging.NewTestLogger(nil, t),
+			Validators:           []Validator{MaxPayloadValidator(4)},
+			InvalidMessagePolicy: InvalidMessageDisconnect,
+			Listeners: []Listener{
+				func(event *Event) {
+					switch event.Type {
+					case Connect:
+						connected <- struct{}{}
+					case Disconnect:
+						disconnected <- event.CloseReason
+					case MessageReceived:
+						received <- event.Message.(*wrp.Message)
+					}
+				},
+			},
+		}
+	)
+
+	_, server, connectURL := startWebsocketServer(options)
+	defer server.Close()
+
+	c, _, err := DefaultDialer().DialDevice(string(testDeviceIDs[0]), connectURL, nil)
+	require.NoError(err)
+	defer c.Close()
+	<-connected
+
+	send := func(payload string) {
+		var data []byte
+		require.NoError(wrp.NewEncoderBytes(&data, wrp.Msgpack).Encode(&wrp.Message{
+			Type:        wrp.SimpleEventMessageType,
+			Source:      string(testDeviceIDs[0]),
+			Destination: "event:test",
+			Payload:     []byte(payload),
+		}))
+
+		require.NoError(c.WriteMessage(websocket.BinaryMessage, data))
+	}
+
+	send("1234")
+	select {
+	case message := <-received:
+		assert.Equal("1234", string(message.Payload))
+	case <-time.After(5 * time.Second):
+		require.Fail("The valid message was not dispatched")
+	}
+
+	send("12345")
+	select {
+	case reason := <-disconnected:
+		assert.Equal(CloseReason{Err: ErrorPayloadTooLarge, Reason: DisconnectInvalidMessage, Text: "invalid-message"}, reason)
+	case <-time.After(5 * time.Second):
+		require.Fail("The device was not disconnected")
+	}
+
+	assert.Empty(received)
+}
+
+func testManagerShutdown(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		connectWait = new(sync.WaitGroup)
+		options     = &Options{
+			Logger:       logging.NewTestLogger(nil, t),
+			ShutdownRate: 1000,
+			Listeners: []Listener{
+				func(event *Event) {
+					if event.Type == Connect {
+						connectWait.Done()
+					}
+				},
+			},
+		}
+	)
+
+	connectWait.Add(len(testDeviceIDs))
+	manager, server, connectURL := startWebsocketServer(options)
+	defer server.Close()
+
+	testDevices := connectTestDevices(t, DefaultDialer(), connectURL)
+	defer closeTestDevices(assert, testDevices)
+	connectWait.Wait()
+
+	closeCodes := make(chan int, len(testDevices))
+	for _, c := range testDevices {
+		go func(c Connection) {
+			for {
+				if _, _, err := c.ReadMessage(); err != nil {
+					if closeError, ok := err.(*websocket.CloseError); ok {
+						closeCodes <- closeError.Code
+					} else {
+						closeCodes <- -1
+					}
+
+					return
+				}
+			}
+		}(c)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	require.NoError(manager.(Shutdowner).Shutdown(ctx))
+	assert.Zero(manager.Len())
+	for range testDevices {
+		select {
+		case code := <-closeCodes:
+			assert.Equal(websocket.CloseServiceRestart, code)
+		case <-time.After(5 * time.Second):
+			require.Fail("No close frame was received")
+		}
+	}
+
+	_, response, err := DefaultDialer().DialDevice(string(testDeviceIDs[0]), connectURL, nil)
+	assert.Error(err)
+	require.NotNil(response)
+	assert.Equal(http.StatusServiceUnavailable, response.StatusCode)
+}
+
+func testManagerShutdownDuringConnect(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		testLogger     = logging.NewTestLogger(nil, t)
+		shutdownLogged = make(chan struct{})
+		authenticating = make(chan struct{})
+		proceed        = make(chan struct{})
+
+		options = &Options{
+			Logger: log.LoggerFunc(func(keyvals ...interface{}) error {
+				for i := 0; i+1 < len(keyvals); i += 2 {
+					if keyvals[i] == logging.MessageKey() && keyvals[i+1] == "shutting down" {
+						close(shutdownLogged)
+					}
+				}
+
+				return testLogger.Log(keyvals...)
+			}),
+			ShutdownRate: 1000,
+			Authenticator: AuthenticatorFunc(func(*http.Request, ID, *Metadata) (Session, error) {
+				close(authenticating)
+				<-proceed
+				return Session{}, nil
+			}),
+		}
+	)
+
+	manager, server, connectURL := startWebsocketServer(options)
+	defer server.Close()
+
+	dialed := make(chan Connection, 1)
+	go func() {
+		c, _, err := DefaultDialer().DialDevice(string(testDeviceIDs[0]), connectURL, nil)
+		assert.NoError(err)
+		dialed <- c
+	}()
+
+	select {
+	case <-authenticating:
+	case <-time.After(5 * time.Second):
+		require.Fail("The device did not begin connecting")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	shutdownResult := make(chan error, 1)
+	go func() {
+		shutdownResult <- manager.(Shutdowner).Shutdown(ctx)
+	}()
+
+	// the device is added only after Shutdown has gathered the devices it will disconnect
+	select {
+	case <-shutdownLogged:
+	case <-time.After(5 * time.Second):
+		require.Fail("Shutdown did not start")
+	}
+
+	close(proceed)
+
+	var c Connection
+	select {
+	case c = <-dialed:
+		require.NotNil(c)
+		defer c.Close()
+	case <-time.After(5 * time.Second):
+		require.Fail("The device did not connect")
+	}
+
+	require.NoError(c.SetReadDeadline(time.Now().Add(5 * time.Second)))
+	_, _, err := c.ReadMessage()
+	closeError, ok := err.(*websocket.CloseError)
+	require.True(ok, "No close frame was received: %s", err)
+	assert.Equal(websocket.CloseServiceRestart, closeError.Code)
+
+	select {
+	case err := <-shutdownResult:
+		assert.NoError(err)
+	case <-time.After(5 * time.Second):
+		require.Fail("Shutdown did not complete")
+	}
+
+	assert.Zero(manager.Len())
+}
+
+func testManagerShutdownDeadline(t *testing.T) {
+	var (
+		assert = assert.New(t)
+
+		connectWait = new(sync.WaitGroup)
+		options     = &Options{
+			Logger:       logging.NewTestLogger(nil, t),
+			ShutdownRate: 1,
+			Listeners: []Listener{
+				func(event *Event) {
+					if event.Type == Connect {
+						connectWait.Done()
+					}
+				},
+			},
+		}
+	)
+
+	connectWait.Add(len(testDeviceIDs))
+	manager, server, connectURL := startWebsocketServer(options)
+	defer server.Close()
+
+	testDevices := connectTestDevices(t, DefaultDialer(), connectURL)
+	defer closeTestDevices(assert, testDevices)
+	connectWait.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	assert.Equal(context.DeadlineExceeded, manager.(Shutdowner).Shutdown(ctx))
+	assert.Equal(len(testDeviceIDs)-1, manager.Len())
+	manager.DisconnectAll(CloseReason{})
+}
+
+func testManagerOptionalInterfaces(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		manager = NewManager(&Options{Logger: logging.NewTestLogger(nil, t)})
+	)
+
+	assert.Implements((*Broadcaster)(nil), manager)
+	assert.Implements((*Shutdowner)(nil), manager)
+	assert.Implements((*AsyncDisconnector)(nil), manager)
+	assert.Implements((*SubprotocolProvider)(nil), newDevice(deviceOptions{ID: ID("mac:112233445566")}))
+}
+
 func TestManager(t *testing.T) {
+	t.Run("OptionalInterfaces", testManagerOptionalInterfaces)
 	t.Run("Connect", func(t *testing.T) {
 		t.Run("MissingDeviceContext", testManagerConnectMissingDeviceContext)
 		t.Run("UpgradeError", testManagerConnectUpgradeError)
+		t.Run("CertificateBinding", testManagerConnectCertificateBinding)
 		t.Run("Visit", testManagerConnectVisit)
 		t.Run("IncludesConvey", testManagerConnectIncludesConvey)
+		t.Run("Compression", testManagerConnectCompression)
+		t.Run("RejectDuplicate", testManagerConnectRejectDuplicate)
+		t.Run("ConveyNotAvailable", testManagerConnectConveyNotAvailable)
+		t.Run("ConveyMetadata", testManagerConnectConveyMetadata)
+		t.Run("ConveyOversized", testManagerConnectConveyOversized)
+		t.Run("WRPFormat", testManagerConnectWRPFormat)
+		t.Run("UnsupportedWRPFormat", testManagerConnectUnsupportedWRPFormat)
+		t.Run("Subprotocol", testManagerConnectSubprotocol)
+		t.Run("UnsupportedSubprotocol", testManagerConnectUnsupportedSubprotocol)
 	})
 
 	t.Run("Route", func(t *testing.T) {
 		t.Run("BadDestination", testManagerRouteBadDestination)
+		t.Run("StrictDestination", testManagerRouteStrictDestination)
 		t.Run("DeviceNotFound", testManagerRouteDeviceNotFound)
+		t.Run("QOSDropped", testManagerRouteQOSDropped)
+	})
+
+	t.Run("ReadPumpValidators", testManagerReadPumpValidators)
+	t.Run("FirstMessageTimeout", testManagerFirstMessageTimeout)
+	t.Run("MaxConnectionAge", testManagerMaxConnectionAge)
+	t.Run("Broadcast", func(t *testing.T) {
+		t.Run("NotEvent", testManagerBroadcastNotEvent)
+		t.Run("Filtered", testManagerBroadcastFiltered)
+		t.Run("Cancelled", testManagerBroadcastCancelled)
 	})
 
 	t.Run("Disconnect", testManagerDisconnect)
+	t.Run("DisconnectReason", testManagerDisconnectReason)
 	t.Run("DisconnectIf", testManagerDisconnectIf)
+
+	t.Run("Shutdown", func(t *testing.T) {
+		t.Run("Complete", testManagerShutdown)
+		t.Run("Deadline", testManagerShutdownDeadline)
+		t.Run("DuringConnect", testManagerShutdownDuringConnect)
+	})
 }
 
 func TestGaugeCardinality(t *testing.T) {
@@ -439,6 +1562,7 @@ func TestWRPSourceIsValid(t *testing.T) {
 
 			d := new(device)
 			d.id = canonicalID
+			d.baseID = canonicalID
 			d.errorLog = log.WithPrefix(logging.NewTestLogger(nil, t), "id", canonicalID)
 			d.metadata = new(Metadata)
 
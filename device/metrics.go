@@ -3,6 +3,7 @@ package device
 import (
 	"github.com/go-kit/kit/metrics"
 	"github.com/go-kit/kit/metrics/provider"
+	"github.com/xmidt-org/webpa-common/convey/conveyhttp"
 	"github.com/xmidt-org/webpa-common/xmetrics"
 )
 
@@ -17,6 +18,29 @@ const (
 	DeviceLimitReachedCounter = "device_limit_reached_count"
 	ModelGauge                = "hardware_model"
 	WRPSourceCheck            = "wrp_source_check"
+	CompressionCounter        = "compression_count"
+	RateLimitedCounter        = "outbound_rate_limited_count"
+	QOSDroppedCounter         = "qos_dropped_count"
+	CertMismatchCounter       = "cert_mismatch_count"
+	QueueFullCounter          = "queue_full_count"
+	RoundTripHistogram        = "ping_round_trip_seconds"
+	DuplicatePolicyCounter    = "duplicate_policy_count"
+	DedupHitCounter           = "dedup_hit_count"
+	TrafficBytesCounter       = "traffic_bytes_count"
+	TrafficMessagesCounter    = "traffic_messages_count"
+	InvalidMessageCounter     = "invalid_message_count"
+	UpgradeDurationHistogram  = "upgrade_duration_seconds"
+	TLSHandshakeHistogram     = "tls_handshake_duration_seconds"
+	FirstMessageHistogram     = "first_message_seconds"
+	HandshakeTimeoutCounter   = "handshake_timeout_count"
+	BroadcastCounter          = "broadcast_count"
+	IdleReapedCounter         = "idle_reaped_count"
+	AuthenticationCounter     = "authentication_count"
+	MissedPongGauge           = "missed_pong_devices"
+	BreakerTransitionCounter  = "breaker_transition_count"
+	ConveyHeaderCounter       = "convey_header_count"
+	ConveyNotAvailableCounter = "convey_not_available_count"
+	ConveyOversizedCounter    = "convey_oversized_count"
 )
 
 // Metrics is the device module function that adds default device metrics
@@ -47,8 +71,9 @@ func Metrics() []xmetrics.Metric {
 			Type: "counter",
 		},
 		{
-			Name: DisconnectCounter,
-			Type: "counter",
+			Name:       DisconnectCounter,
+			Type:       "counter",
+			LabelNames: []string{"reason"},
 		},
 		{
 			Name: DeviceLimitReachedCounter,
@@ -64,6 +89,118 @@ func Metrics() []xmetrics.Metric {
 			Type:       "counter",
 			LabelNames: []string{"outcome", "reason"},
 		},
+		{
+			Name: CompressionCounter,
+			Type: "counter",
+		},
+		{
+			Name:       RateLimitedCounter,
+			Type:       "counter",
+			LabelNames: []string{"policy"},
+		},
+		{
+			Name:       QOSDroppedCounter,
+			Type:       "counter",
+			LabelNames: []string{"qos"},
+		},
+		{
+			Name:       CertMismatchCounter,
+			Type:       "counter",
+			LabelNames: []string{"reason"},
+		},
+		{
+			Name:       QueueFullCounter,
+			Type:       "counter",
+			LabelNames: []string{"policy"},
+		},
+		{
+			Name:       RoundTripHistogram,
+			Type:       "histogram",
+			Buckets:    []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+			LabelNames: []string{"partnerid"},
+		},
+		{
+			Name:       DuplicatePolicyCounter,
+			Type:       "counter",
+			LabelNames: []string{"policy"},
+		},
+		{
+			Name: DedupHitCounter,
+			Type: "counter",
+		},
+		{
+			Name:       TrafficBytesCounter,
+			Type:       "counter",
+			LabelNames: []string{"partnerid", "direction"},
+		},
+		{
+			Name:       TrafficMessagesCounter,
+			Type:       "counter",
+			LabelNames: []string{"partnerid", "direction"},
+		},
+		{
+			Name:       InvalidMessageCounter,
+			Type:       "counter",
+			LabelNames: []string{"policy"},
+		},
+		{
+			Name:    UpgradeDurationHistogram,
+			Type:    "histogram",
+			Buckets: []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+		},
+		{
+			Name:    TLSHandshakeHistogram,
+			Type:    "histogram",
+			Buckets: []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+		},
+		{
+			Name:    FirstMessageHistogram,
+			Type:    "histogram",
+			Buckets: []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		},
+		{
+			Name:       HandshakeTimeoutCounter,
+			Type:       "counter",
+			LabelNames: []string{"phase"},
+		},
+		{
+			Name:       BroadcastCounter,
+			Type:       "counter",
+			LabelNames: []string{"outcome"},
+		},
+		{
+			Name: IdleReapedCounter,
+			Type: "counter",
+		},
+		{
+			Name:       AuthenticationCounter,
+			Type:       "counter",
+			LabelNames: []string{"outcome"},
+		},
+		{
+			Name: MissedPongGauge,
+			Type: "gauge",
+		},
+		{
+			Name:       BreakerTransitionCounter,
+			Type:       "counter",
+			LabelNames: []string{"state"},
+		},
+		{
+			Name:       ConveyHeaderCounter,
+			Type:       "counter",
+			LabelNames: []string{conveyhttp.HeaderLabel},
+		},
+		{
+			Name:       ConveyNotAvailableCounter,
+			Type:       "counter",
+			LabelNames: []string{"policy"},
+		},
+		{
+			Name:       ConveyOversizedCounter,
+			Type:       "counter",
+			LabelNames: []string{conveyhttp.LimitLabel},
+		},
 	}
 }
 
@@ -76,9 +213,32 @@ type Measures struct {
 	Ping            xmetrics.Incrementer
 	Pong            xmetrics.Incrementer
 	Connect         xmetrics.Incrementer
-	Disconnect      xmetrics.Adder
+	Disconnect      metrics.Counter
 	Models          metrics.Gauge
 	WRPSourceCheck  metrics.Counter
+	Compression     xmetrics.Incrementer
+	RateLimited     metrics.Counter
+	QOSDropped      metrics.Counter
+	CertMismatch    metrics.Counter
+	QueueFull       metrics.Counter
+	RoundTrip       metrics.Histogram
+	DuplicatePolicy metrics.Counter
+	DedupHits       metrics.Counter
+	TrafficBytes    metrics.Counter
+	TrafficMessages metrics.Counter
+	InvalidMessage  metrics.Counter
+	UpgradeDuration metrics.Histogram
+	TLSHandshake    metrics.Histogram
+	FirstMessage    metrics.Histogram
+	PhaseTimeout    metrics.Counter
+	Broadcast       metrics.Counter
+	IdleReaped      metrics.Counter
+	Authentication  metrics.Counter
+	MissedPongs     metrics.Gauge
+	Breaker         metrics.Counter
+	ConveyHeader    metrics.Counter
+	NotAvailable    metrics.Counter
+	ConveyOversized metrics.Counter
 }
 
 // NewMeasures constructs a Measures given a go-kit metrics Provider
@@ -94,5 +254,28 @@ func NewMeasures(p provider.Provider) Measures {
 		Disconnect:      p.NewCounter(DisconnectCounter),
 		Models:          p.NewGauge(ModelGauge),
 		WRPSourceCheck:  p.NewCounter(WRPSourceCheck),
+		Compression:     xmetrics.NewIncrementer(p.NewCounter(CompressionCounter)),
+		RateLimited:     p.NewCounter(RateLimitedCounter),
+		QOSDropped:      p.NewCounter(QOSDroppedCounter),
+		CertMismatch:    p.NewCounter(CertMismatchCounter),
+		QueueFull:       p.NewCounter(QueueFullCounter),
+		RoundTrip:       p.NewHistogram(RoundTripHistogram, 10),
+		DuplicatePolicy: p.NewCounter(DuplicatePolicyCounter),
+		DedupHits:       p.NewCounter(DedupHitCounter),
+		TrafficBytes:    p.NewCounter(TrafficBytesCounter),
+		TrafficMessages: p.NewCounter(TrafficMessagesCounter),
+		InvalidMessage:  p.NewCounter(InvalidMessageCounter),
+		UpgradeDuration: p.NewHistogram(UpgradeDurationHistogram, 10),
+		TLSHandshake:    p.NewHistogram(TLSHandshakeHistogram, 10),
+		FirstMessage:    p.NewHistogram(FirstMessageHistogram, 10),
+		PhaseTimeout:    p.NewCounter(HandshakeTimeoutCounter),
+		Broadcast:       p.NewCounter(BroadcastCounter),
+		IdleReaped:      p.NewCounter(IdleReapedCounter),
+		Authentication:  p.NewCounter(AuthenticationCounter),
+		MissedPongs:     p.NewGauge(MissedPongGauge),
+		Breaker:         p.NewCounter(BreakerTransitionCounter),
+		ConveyHeader:    p.NewCounter(ConveyHeaderCounter),
+		NotAvailable:    p.NewCounter(ConveyNotAvailableCounter),
+		ConveyOversized: p.NewCounter(ConveyOversizedCounter),
 	}
 }
@@ -24,10 +24,12 @@ func TestMetrics(t *testing.T) {
 		gauge.Add(-1.0)
 	}
 
-	for _, counterName := range []string{RequestResponseCounter, PingCounter, PongCounter, ConnectCounter, DisconnectCounter} {
+	for _, counterName := range []string{RequestResponseCounter, PingCounter, PongCounter, ConnectCounter, CompressionCounter} {
 		counter := r.NewCounter(counterName)
 		counter.Add(1.0)
 	}
+
+	r.NewCounter(DisconnectCounter).With("reason", string(DisconnectIdle)).Add(1.0)
 }
 
 func TestNewMeasures(t *testing.T) {
@@ -42,4 +44,24 @@ func TestNewMeasures(t *testing.T) {
 	assert.NotNil(m.Pong)
 	assert.NotNil(m.Connect)
 	assert.NotNil(m.Disconnect)
+	assert.NotNil(m.Compression)
+	assert.NotNil(m.RateLimited)
+	assert.NotNil(m.QOSDropped)
+	assert.NotNil(m.CertMismatch)
+	assert.NotNil(m.QueueFull)
+	assert.NotNil(m.RoundTrip)
+	assert.NotNil(m.DuplicatePolicy)
+	assert.NotNil(m.DedupHits)
+	assert.NotNil(m.TrafficBytes)
+	assert.NotNil(m.TrafficMessages)
+	assert.NotNil(m.InvalidMessage)
+	assert.NotNil(m.UpgradeDuration)
+	assert.NotNil(m.TLSHandshake)
+	assert.NotNil(m.FirstMessage)
+	assert.NotNil(m.PhaseTimeout)
+	assert.NotNil(m.Broadcast)
+	assert.NotNil(m.IdleReaped)
+	assert.NotNil(m.Authentication)
+	assert.NotNil(m.MissedPongs)
+	assert.NotNil(m.Breaker)
 }
@@ -0,0 +1,94 @@
+package device
+
+import (
+	"sync/atomic"
+
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+// missedPongs counts the consecutive pings a device has not answered.  Once closed, the count
+// no longer changes, so that a device is removed from the MissedPongGauge exactly once.
+type missedPongs struct {
+	// count is accessed atomically, and is negative once closed
+	count int32
+}
+
+// miss records a ping that went unanswered, returning the new count.  If closed, this method returns false.
+func (mp *missedPongs) miss() (int, bool) {
+	for {
+		count := atomic.LoadInt32(&mp.count)
+		if count < 0 {
+			return 0, false
+		}
+
+		if atomic.CompareAndSwapInt32(&mp.count, count, count+1) {
+			return int(count + 1), true
+		}
+	}
+}
+
+// reset records a pong, returning the count prior to the reset.  If closed, this method returns false.
+func (mp *missedPongs) reset() (int, bool) {
+	for {
+		count := atomic.LoadInt32(&mp.count)
+		if count < 0 {
+			return 0, false
+		}
+
+		if atomic.CompareAndSwapInt32(&mp.count, count, 0) {
+			return int(count), true
+		}
+	}
+}
+
+// close stops tracking, returning the count prior to closing.  If already closed, this method returns false.
+func (mp *missedPongs) close() (int, bool) {
+	count := atomic.SwapInt32(&mp.count, -1)
+	return int(count), count >= 0
+}
+
+// missedPongPinger decorates a ping closure so that each ping sent while the previous ping is still
+// outstanding counts as a missed pong.  When MaxMissedPongs is set and reached, the device is closed
+// rather than pinged again.
+func (m *manager) missedPongPinger(d *device, rtt *roundTripTimer, ping func() error) func() error {
+	return func() error {
+		if !rtt.outstanding() {
+			return ping()
+		}
+
+		missed, ok := d.missedPongs.miss()
+		if !ok {
+			return ping()
+		}
+
+		d.statistics.SetMissedPongs(missed)
+		if missed == m.missedPongThreshold {
+			m.measures.MissedPongs.Add(1.0)
+		}
+
+		if m.maxMissedPongs > 0 && missed >= m.maxMissedPongs {
+			d.errorLog.Log(logging.MessageKey(), "too many missed pongs", "missedPongs", missed, "maxMissedPongs", m.maxMissedPongs)
+			d.requestClose(CloseReason{Err: ErrorMissedPongs, Reason: DisconnectMissedPongs})
+			return nil
+		}
+
+		return ping()
+	}
+}
+
+// pongReceived resets the missed pong count of a device
+func (m *manager) pongReceived(d *device) {
+	if missed, ok := d.missedPongs.reset(); ok && missed > 0 {
+		d.statistics.SetMissedPongs(0)
+		if missed >= m.missedPongThreshold {
+			m.measures.MissedPongs.Add(-1.0)
+		}
+	}
+}
+
+// stopMissedPongs stops tracking missed pongs for a disconnected device
+func (m *manager) stopMissedPongs(d *device) {
+	if missed, ok := d.missedPongs.close(); ok && missed >= m.missedPongThreshold {
+		m.measures.MissedPongs.Add(-1.0)
+	}
+}
@@ -0,0 +1,182 @@
+package device
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/xmetrics/xmetricstest"
+)
+
+func TestMissedPongs(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		mp     missedPongs
+	)
+
+	missed, ok := mp.miss()
+	assert.Equal(1, missed)
+	assert.True(ok)
+
+	missed, ok = mp.miss()
+	assert.Equal(2, missed)
+	assert.True(ok)
+
+	missed, ok = mp.reset()
+	assert.Equal(2, missed)
+	assert.True(ok)
+
+	missed, ok = mp.miss()
+	assert.Equal(1, missed)
+	assert.True(ok)
+
+	missed, ok = mp.close()
+	assert.Equal(1, missed)
+	assert.True(ok)
+
+	_, ok = mp.miss()
+	assert.False(ok)
+
+	_, ok = mp.reset()
+	assert.False(ok)
+
+	_, ok = mp.close()
+	assert.False(ok)
+}
+
+func testManagerMissedPongPingerThreshold(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		p      = xmetricstest.NewProvider(nil, Metrics)
+		m      = NewManager(&Options{
+			Logger:              logging.NewTestLogger(nil, t),
+			MetricsProvider:     p,
+			MissedPongThreshold: 2,
+		}).(*manager)
+
+		d     = newDevice(deviceOptions{ID: "mac:112233445566", Logger: m.logger})
+		rtt   = newRoundTripTimer(nil)
+		pings = 0
+
+		pinger = m.missedPongPinger(d, rtt, rtt.pinger(func() error {
+			pings++
+			return nil
+		}))
+	)
+
+	// the first ping is not a miss, as there is no outstanding ping
+	assert.NoError(pinger())
+	assert.Zero(d.Statistics().MissedPongs())
+
+	assert.NoError(pinger())
+	assert.Equal(1, d.Statistics().MissedPongs())
+	p.Assert(t, MissedPongGauge)(xmetricstest.Value(0.0))
+
+	assert.NoError(pinger())
+	assert.Equal(2, d.Statistics().MissedPongs())
+	p.Assert(t, MissedPongGauge)(xmetricstest.Value(1.0))
+
+	assert.NoError(pinger())
+	assert.Equal(3, d.Statistics().MissedPongs())
+	p.Assert(t, MissedPongGauge)(xmetricstest.Value(1.0))
+	assert.Equal(4, pings)
+	assert.False(d.Closed())
+
+	rtt.pong()
+	m.pongReceived(d)
+	assert.Zero(d.Statistics().MissedPongs())
+	p.Assert(t, MissedPongGauge)(xmetricstest.Value(0.0))
+
+	assert.NoError(pinger())
+	assert.NoError(pinger())
+	assert.NoError(pinger())
+	p.Assert(t, MissedPongGauge)(xmetricstest.Value(1.0))
+
+	m.stopMissedPongs(d)
+	p.Assert(t, MissedPongGauge)(xmetricstest.Value(0.0))
+
+	// once stopped, neither pings nor pongs affect the gauge
+	assert.NoError(pinger())
+	m.pongReceived(d)
+	m.stopMissedPongs(d)
+	p.Assert(t, MissedPongGauge)(xmetricstest.Value(0.0))
+}
+
+func testManagerMissedPongPingerMax(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		m      = NewManager(&Options{
+			Logger:         logging.NewTestLogger(nil, t),
+			MaxMissedPongs: 2,
+		}).(*manager)
+
+		d     = newDevice(deviceOptions{ID: "mac:112233445566", Logger: m.logger})
+		rtt   = newRoundTripTimer(nil)
+		pings = 0
+
+		pinger = m.missedPongPinger(d, rtt, rtt.pinger(func() error {
+			pings++
+			return nil
+		}))
+	)
+
+	assert.NoError(pinger())
+	assert.NoError(pinger())
+	assert.False(d.Closed())
+
+	assert.NoError(pinger())
+	assert.True(d.Closed())
+	assert.Equal(2, pings, "the device should not be pinged once it has missed too many pongs")
+	assert.Equal(CloseReason{Err: ErrorMissedPongs, Reason: DisconnectMissedPongs, Text: string(DisconnectMissedPongs)}, d.CloseReason())
+}
+
+func testManagerMissedPongsConnected(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		p       = xmetricstest.NewProvider(nil, Metrics)
+
+		disconnected = make(chan CloseReason, 1)
+
+		options = &Options{
+			Logger:          logging.NewTestLogger(nil, t),
+			MetricsProvider: p,
+			PingPeriod:      20 * time.Millisecond,
+			IdlePeriod:      time.Millisecond,
+			MaxMissedPongs:  3,
+			Listeners: []Listener{
+				func(event *Event) {
+					if event.Type == Disconnect {
+						disconnected <- event.CloseReason
+					}
+				},
+			},
+		}
+	)
+
+	_, server, connectURL := startWebsocketServer(options)
+	defer server.Close()
+
+	// the client never reads, so it never answers pings
+	c, _, err := DefaultDialer().DialDevice(string(testDeviceIDs[0]), connectURL, nil)
+	require.NoError(err)
+	defer c.Close()
+
+	select {
+	case reason := <-disconnected:
+		assert.Equal(DisconnectMissedPongs, reason.Reason)
+	case <-time.After(5 * time.Second):
+		require.Fail("The device was not disconnected")
+	}
+
+	p.Assert(t, MissedPongGauge)(xmetricstest.Value(0.0))
+	p.Assert(t, DisconnectCounter, "reason", string(DisconnectMissedPongs))(xmetricstest.Value(1.0))
+}
+
+func TestManagerMissedPongs(t *testing.T) {
+	t.Run("Threshold", testManagerMissedPongPingerThreshold)
+	t.Run("MaxMissedPongs", testManagerMissedPongPingerMax)
+	t.Run("Connected", testManagerMissedPongsConnected)
+}
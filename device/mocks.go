@@ -111,6 +111,10 @@ func (m *MockDevice) CloseReason() CloseReason {
 	return first
 }
 
+func (m *MockDevice) Subprotocol() string {
+	return m.Called().String(0)
+}
+
 func (m *MockDevice) Send(request *Request) (*Response, error) {
 	arguments := m.Called(request)
 	first, _ := arguments.Get(0).(*Response)
@@ -31,6 +31,51 @@ const (
 	DefaultReadBufferSize         = 0
 	DefaultWriteBufferSize        = 0
 	DefaultDeviceMessageQueueSize = 100
+
+	// DefaultConveyMaxHeaderLength is the maximum length of a device's convey header when no limit is configured
+	DefaultConveyMaxHeaderLength = 16 * 1024
+
+	// DefaultConveyMaxDecodedSize is the maximum size of a device's decoded, and possibly decompressed,
+	// convey data when no limit is configured
+	DefaultConveyMaxDecodedSize = 64 * 1024
+
+	// DefaultShutdownRate is the number of devices per second disconnected by Manager.Shutdown
+	// when no ShutdownRate is configured.
+	DefaultShutdownRate = 100
+
+	// DefaultBroadcastRate is the number of devices per second sent a message by Manager.Broadcast
+	// when no BroadcastRate is configured.
+	DefaultBroadcastRate = 500
+
+	// DefaultDisconnectBatchSize is the number of devices visited in each batch by Manager.DisconnectIfAsync
+	// when no DisconnectBatchSize is configured.
+	DefaultDisconnectBatchSize = 100
+
+	// DefaultBreakerCooldown is how long a device's circuit breaker stays open when no BreakerCooldown is configured
+	DefaultBreakerCooldown = 30 * time.Second
+
+	// DefaultBreakerMaxTrips is the number of times a device's circuit breaker may open without an intervening
+	// success before the device is disconnected, when no BreakerMaxTrips is configured.
+	DefaultBreakerMaxTrips = 3
+
+	// DefaultRegistryShards is the number of shards the device registry is split into when no
+	// RegistryShards is configured.
+	DefaultRegistryShards = 16
+
+	// DefaultMissedPongThreshold is the number of consecutive missed pongs at which a device is counted
+	// by the MissedPongGauge when no MissedPongThreshold is configured.
+	DefaultMissedPongThreshold = 2
+
+	// DefaultReapInterval is how often devices are checked against the ApplicationIdlePeriod when
+	// no ReapInterval is configured.
+	DefaultReapInterval = time.Minute
+
+	// DefaultCompressionLevel is the flate level used for outbound frames when permessage-deflate
+	// has been negotiated and no CompressionLevel is configured.  This matches gorilla's default.
+	DefaultCompressionLevel = 1
+
+	minCompressionLevel = -2
+	maxCompressionLevel = 9
 )
 
 // WRPSourceCheckType is used to define the different modes
@@ -47,21 +92,182 @@ type Options struct {
 	// Upgrader is the gorilla websocket.Upgrader injected into these options.
 	Upgrader websocket.Upgrader
 
+	// EnableCompression turns on negotiation of the permessage-deflate websocket extension (RFC 7692)
+	// for device connections.  Devices which do not offer the extension are unaffected.  This is
+	// equivalent to setting Upgrader.EnableCompression.
+	EnableCompression bool
+
+	// CompressionLevel is the flate compression level applied to outbound frames on connections
+	// which negotiated permessage-deflate.  Valid values are -2 through 9, including 0, which is
+	// flate.NoCompression.  If unset or invalid, DefaultCompressionLevel is used.
+	CompressionLevel *int
+
 	// MaxDevices is the maximum number of devices allowed to connect to any one Manager.
 	// If unset (i.e. zero), math.MaxUint32 is used as the maximum.
 	MaxDevices int
 
+	// RegistryShards is the number of shards the device registry is split into.  Each shard has its own
+	// lock, which reduces contention when many devices connect and disconnect at once.  If unset,
+	// DefaultRegistryShards is used.
+	RegistryShards int
+
+	// DuplicatePolicy determines what happens when a device connects with the same ID as a device that
+	// is already connected.  If unset, DuplicateTerminateOld is used.
+	DuplicatePolicy DuplicatePolicy
+
 	// DeviceMessageQueueSize is the capacity of the channel which stores messages waiting
 	// to be transmitted to a device.  If not supplied, DefaultDeviceMessageQueueSize is used.
 	DeviceMessageQueueSize int
 
+	// QOSTiers splits each device's outbound queue into tiers by the QOS level of each message,
+	// keyed by level name:  "low", "medium", "high", or "critical".  Higher tiers are always serviced
+	// before lower ones.  Levels without an entry use a tier of DeviceMessageQueueSize.  If no tiers
+	// are configured, all messages share a single queue in FIFO order.
+	QOSTiers map[string]QOSTier
+
+	// QueuePolicy determines what happens to an outbound message when the device's queue, or the
+	// QOS tier of its queue, is full.  If unset, QueueBlock is used.  QOS tiers configured to drop when
+	// full ignore this policy.
+	QueuePolicy QueuePolicy
+
+	// QueueBlockTimeout is the maximum time a message waits for room in a full queue when the
+	// QueuePolicy is QueueBlockTimeout.  If unset, DefaultQueueBlockTimeout is used.
+	QueueBlockTimeout time.Duration
+
+	// DedupSize is the number of recently delivered transaction UUIDs remembered for each device.
+	// A message whose transaction UUID was delivered to the same device within DedupTTL fails with
+	// ErrorDuplicateTransaction rather than being delivered twice.  If unset, messages are not deduplicated.
+	DedupSize int
+
+	// DedupTTL is how long a delivered transaction UUID is remembered.  If unset, DefaultDedupTTL is used.
+	DedupTTL time.Duration
+
+	// RateLimit configures per-device rate limiting of outbound messages.  By default,
+	// outbound messages are not rate limited.
+	RateLimit RateLimit
+
+	// BindClientCertificates requires each device to present a TLS client certificate whose subject
+	// common name or subject alternative names identify the same device as the DeviceNameHeader.
+	// Connections that fail this check are rejected before the websocket upgrade.  This option is
+	// only useful when TLS is terminated by the server hosting the Manager.
+	BindClientCertificates bool
+
+	// ShutdownRate is the maximum number of devices per second disconnected by Manager.Shutdown.
+	// If unset, DefaultShutdownRate is used.
+	ShutdownRate int
+
+	// BroadcastRate is the maximum number of devices per second sent a message by Manager.Broadcast.
+	// If unset, DefaultBroadcastRate is used.
+	BroadcastRate int
+
+	// DisconnectBatchSize is the number of devices visited between progress reports by Manager.DisconnectIfAsync.
+	// If unset, DefaultDisconnectBatchSize is used.
+	DisconnectBatchSize int
+
+	// DestinationParsing controls how the destinations of routed messages are parsed.  By default,
+	// destinations are parsed leniently, as with ParseID.
+	DestinationParsing ParseOptions
+
+	// Authenticator, if set, is consulted for each device connection before the websocket upgrade.
+	// It may reject the connection, annotate the device's metadata, or set an expiry for the session.
+	Authenticator Authenticator
+
+	// Validators is the ordered chain applied to each WRP message received from a device, after
+	// any WRPSourceCheck.  Messages which fail validation are not dispatched to Listeners.
+	Validators []Validator
+
+	// InvalidMessagePolicy determines what happens when a message fails validation.  If unset,
+	// InvalidMessageDrop is used.
+	InvalidMessagePolicy InvalidMessagePolicy
+
+	// TLSHandshakeTimeout is the maximum time a device has to complete a TLS handshake.  It is enforced
+	// by a TLSHandshakeTimer.  If unset, TLS handshakes are not bounded by this package.
+	TLSHandshakeTimeout time.Duration
+
+	// Subprotocols are the websocket subprotocols supported by the Manager, in order of preference.  This is
+	// equivalent to setting Upgrader.Subprotocols, which takes precedence if set.  Devices which offer subprotocols,
+	// none of which are supported, are rejected with a 400 status.  If no subprotocols are configured, the
+	// WRP subprotocols MsgpackSubprotocol and JSONSubprotocol are accepted.
+	Subprotocols []string
+
+	// ConveyHeaders are the HTTP headers which may contain the encoded convey JSON, in order of preference.
+	// Listing more than one header, e.g. X-Xmidt-Convey followed by X-Webpa-Convey, allows devices to migrate
+	// between header names.  If unset, ConveyHeader is used.
+	ConveyHeaders []string
+
+	// ConveyMaxHeaderLength is the maximum length of a device's convey header.  Devices with longer headers
+	// are treated as having invalid convey data.  If unset or nonpositive, DefaultConveyMaxHeaderLength is used.
+	ConveyMaxHeaderLength int
+
+	// ConveyMaxDecodedSize is the maximum size of a device's convey data after base64 decoding and decompression,
+	// which protects against decompression bombs.  If unset or nonpositive, DefaultConveyMaxDecodedSize is used.
+	ConveyMaxDecodedSize int
+
+	// ConveyNotAvailablePolicy determines what happens when a device sends "not-available" as its convey header.
+	// If unset, ConveyNotAvailableLog is used.
+	ConveyNotAvailablePolicy ConveyNotAvailablePolicy
+
+	// ConveyMetadata maps convey fields into each device's Metadata at connect time, coercing them into the
+	// configured types.  If unset, convey data is not copied into Metadata.
+	ConveyMetadata []MetadataField
+
+	// RequireSubprotocol rejects devices which do not negotiate a subprotocol, with a 400 status.
+	// By default, devices need not offer any subprotocol.
+	RequireSubprotocol bool
+
+	// UpgradeTimeout is the maximum time allowed to write the websocket upgrade response to a device.
+	// This is equivalent to setting Upgrader.HandshakeTimeout, which takes precedence if set.
+	UpgradeTimeout time.Duration
+
+	// FirstMessageTimeout is the maximum time a device has to send its first websocket frame after
+	// the upgrade completes.  Devices which take longer are disconnected.  If unset, there is no limit.
+	FirstMessageTimeout time.Duration
+
+	// MaxConnectionAge is the maximum length of time a device may remain connected.  When a connection
+	// reaches this age, it is closed with a websocket.CloseServiceRestart status so that the device reconnects
+	// and presents its credentials again.  This bounds how long any credentials remain in use.  If unset,
+	// there is no limit.
+	MaxConnectionAge time.Duration
+
+	// ApplicationIdlePeriod is the length of time a device may go without sending a WRP message before
+	// it is disconnected with DisconnectApplicationIdle.  Unlike IdlePeriod, pongs and other control frames
+	// do not count as activity.  If unset, devices are not disconnected for application inactivity.
+	ApplicationIdlePeriod time.Duration
+
+	// ReapInterval is how often devices are checked against the ApplicationIdlePeriod.  If unset,
+	// DefaultReapInterval is used.
+	ReapInterval time.Duration
+
 	// PingPeriod is the time between pings sent to each device
 	PingPeriod time.Duration
 
 	// IdlePeriod is the length of time a device connection is allowed to be idle,
 	// with no traffic coming from the device.  If not supplied, DefaultIdlePeriod is used.
+	// IdlePeriod is not enforced when MaxMissedPongs is set.
 	IdlePeriod time.Duration
 
+	// BreakerThreshold, if set, enables a circuit breaker for each device.  After this many consecutive errors
+	// caused by a device, i.e. malformed messages, messages which cannot be encoded, or transaction timeouts,
+	// the breaker opens and messages are no longer routed to the device.  If unset, there is no breaker.
+	BreakerThreshold int
+
+	// BreakerCooldown is how long a device's breaker stays open before messages are routed to the device again.
+	// If unset, DefaultBreakerCooldown is used.
+	BreakerCooldown time.Duration
+
+	// BreakerMaxTrips is the number of times a device's breaker may open without an intervening success before
+	// the device is disconnected.  If unset, DefaultBreakerMaxTrips is used.
+	BreakerMaxTrips int
+
+	// MissedPongThreshold is the number of consecutive unanswered pings at which a device is counted
+	// by the MissedPongGauge.  If unset, DefaultMissedPongThreshold is used.
+	MissedPongThreshold int
+
+	// MaxMissedPongs, if set, is the number of consecutive unanswered pings after which a device is
+	// disconnected with DisconnectMissedPongs.  This replaces the single IdlePeriod timeout, so that
+	// devices are tolerant of transient network problems.  If unset, the IdlePeriod is used.
+	MaxMissedPongs int
+
 	// RequestTimeout is the timeout for all inbound HTTP requests
 	RequestTimeout time.Duration
 
@@ -79,6 +285,11 @@ type Options struct {
 	// MetricsProvider is the go-kit factory for metrics
 	MetricsProvider provider.Provider
 
+	// Measures, if set, are the metrics used by the Manager rather than metrics created from MetricsProvider.
+	// Many go-kit providers do not allow a metric to be created twice, so this is how the Manager shares its
+	// metrics with a TLSHandshakeTimer.
+	Measures *Measures
+
 	// Now is the closure used to determine the current time.  If not set, time.Now is used.
 	Now func() time.Time
 
@@ -96,11 +307,71 @@ func (o *Options) upgrader() *websocket.Upgrader {
 	upgrader := new(websocket.Upgrader)
 	if o != nil {
 		*upgrader = o.Upgrader
+		upgrader.EnableCompression = upgrader.EnableCompression || o.EnableCompression
+		if upgrader.HandshakeTimeout <= 0 && o.UpgradeTimeout > 0 {
+			upgrader.HandshakeTimeout = o.UpgradeTimeout
+		}
+
+		if len(upgrader.Subprotocols) == 0 && len(o.Subprotocols) > 0 {
+			upgrader.Subprotocols = append([]string(nil), o.Subprotocols...)
+		}
 	}
 
 	return upgrader
 }
 
+func (o *Options) requireSubprotocol() bool {
+	return o != nil && o.RequireSubprotocol
+}
+
+func (o *Options) conveyHeaders() []string {
+	if o != nil && len(o.ConveyHeaders) > 0 {
+		return o.ConveyHeaders
+	}
+
+	return []string{ConveyHeader}
+}
+
+func (o *Options) conveyMaxHeaderLength() int {
+	if o != nil && o.ConveyMaxHeaderLength > 0 {
+		return o.ConveyMaxHeaderLength
+	}
+
+	return DefaultConveyMaxHeaderLength
+}
+
+func (o *Options) conveyMaxDecodedSize() int {
+	if o != nil && o.ConveyMaxDecodedSize > 0 {
+		return o.ConveyMaxDecodedSize
+	}
+
+	return DefaultConveyMaxDecodedSize
+}
+
+func (o *Options) conveyNotAvailablePolicy() ConveyNotAvailablePolicy {
+	if o != nil {
+		return o.ConveyNotAvailablePolicy.normalize()
+	}
+
+	return ConveyNotAvailableLog
+}
+
+func (o *Options) conveyMetadata() (ConveyMetadataTranslator, error) {
+	if o != nil && len(o.ConveyMetadata) > 0 {
+		return NewConveyMetadataTranslator(o.ConveyMetadata...)
+	}
+
+	return nil, nil
+}
+
+func (o *Options) compressionLevel() int {
+	if o != nil && o.CompressionLevel != nil && *o.CompressionLevel >= minCompressionLevel && *o.CompressionLevel <= maxCompressionLevel {
+		return *o.CompressionLevel
+	}
+
+	return DefaultCompressionLevel
+}
+
 func (o *Options) deviceMessageQueueSize() int {
 	if o != nil && o.DeviceMessageQueueSize > 0 {
 		return o.DeviceMessageQueueSize
@@ -109,6 +380,177 @@ func (o *Options) deviceMessageQueueSize() int {
 	return DefaultDeviceMessageQueueSize
 }
 
+func (o *Options) qosTiers() map[QOSLevel]QOSTier {
+	if o == nil || len(o.QOSTiers) == 0 {
+		return nil
+	}
+
+	tiers := make(map[QOSLevel]QOSTier, len(o.QOSTiers))
+	for name, tier := range o.QOSTiers {
+		if level, ok := ParseQOSLevel(name); ok {
+			tiers[level] = tier
+		}
+	}
+
+	return tiers
+}
+
+func (o *Options) queuePolicy() QueuePolicy {
+	if o != nil {
+		return o.QueuePolicy.normalize()
+	}
+
+	return QueueBlock
+}
+
+func (o *Options) queueBlockTimeout() time.Duration {
+	if o != nil && o.QueueBlockTimeout > 0 {
+		return o.QueueBlockTimeout
+	}
+
+	return DefaultQueueBlockTimeout
+}
+
+func (o *Options) dedupSize() int {
+	if o != nil && o.DedupSize > 0 {
+		return o.DedupSize
+	}
+
+	return 0
+}
+
+func (o *Options) dedupTTL() time.Duration {
+	if o != nil && o.DedupTTL > 0 {
+		return o.DedupTTL
+	}
+
+	return DefaultDedupTTL
+}
+
+func (o *Options) rateLimit() RateLimit {
+	if o != nil {
+		return o.RateLimit
+	}
+
+	return RateLimit{}
+}
+
+func (o *Options) bindClientCertificates() bool {
+	return o != nil && o.BindClientCertificates
+}
+
+func (o *Options) shutdownRate() int {
+	if o != nil && o.ShutdownRate > 0 {
+		return o.ShutdownRate
+	}
+
+	return DefaultShutdownRate
+}
+
+func (o *Options) broadcastRate() int {
+	if o != nil && o.BroadcastRate > 0 {
+		return o.BroadcastRate
+	}
+
+	return DefaultBroadcastRate
+}
+
+func (o *Options) disconnectBatchSize() int {
+	if o != nil && o.DisconnectBatchSize > 0 {
+		return o.DisconnectBatchSize
+	}
+
+	return DefaultDisconnectBatchSize
+}
+
+func (o *Options) duplicatePolicy() DuplicatePolicy {
+	if o != nil {
+		return o.DuplicatePolicy.normalize()
+	}
+
+	return DuplicateTerminateOld
+}
+
+func (o *Options) destinationParsing() ParseOptions {
+	if o != nil {
+		return o.DestinationParsing
+	}
+
+	return ParseOptions{}
+}
+
+func (o *Options) authenticator() Authenticator {
+	if o != nil {
+		return o.Authenticator
+	}
+
+	return nil
+}
+
+func (o *Options) validators() Validators {
+	if o != nil {
+		return Validators(o.Validators)
+	}
+
+	return nil
+}
+
+func (o *Options) invalidMessagePolicy() InvalidMessagePolicy {
+	if o != nil {
+		return o.InvalidMessagePolicy.normalize()
+	}
+
+	return InvalidMessageDrop
+}
+
+func (o *Options) tlsHandshakeTimeout() time.Duration {
+	if o != nil && o.TLSHandshakeTimeout > 0 {
+		return o.TLSHandshakeTimeout
+	}
+
+	return 0
+}
+
+func (o *Options) firstMessageTimeout() time.Duration {
+	if o != nil && o.FirstMessageTimeout > 0 {
+		return o.FirstMessageTimeout
+	}
+
+	return 0
+}
+
+func (o *Options) maxConnectionAge() time.Duration {
+	if o != nil && o.MaxConnectionAge > 0 {
+		return o.MaxConnectionAge
+	}
+
+	return 0
+}
+
+func (o *Options) applicationIdlePeriod() time.Duration {
+	if o != nil && o.ApplicationIdlePeriod > 0 {
+		return o.ApplicationIdlePeriod
+	}
+
+	return 0
+}
+
+func (o *Options) reapInterval() time.Duration {
+	if o != nil && o.ReapInterval > 0 {
+		return o.ReapInterval
+	}
+
+	return DefaultReapInterval
+}
+
+func (o *Options) registryShards() int {
+	if o != nil && o.RegistryShards > 0 {
+		return o.RegistryShards
+	}
+
+	return DefaultRegistryShards
+}
+
 func (o *Options) maxDevices() int {
 	if o != nil && o.MaxDevices > 0 {
 		return o.MaxDevices
@@ -125,6 +567,46 @@ func (o *Options) idlePeriod() time.Duration {
 	return DefaultIdlePeriod
 }
 
+func (o *Options) breakerThreshold() int {
+	if o != nil && o.BreakerThreshold > 0 {
+		return o.BreakerThreshold
+	}
+
+	return 0
+}
+
+func (o *Options) breakerCooldown() time.Duration {
+	if o != nil && o.BreakerCooldown > 0 {
+		return o.BreakerCooldown
+	}
+
+	return DefaultBreakerCooldown
+}
+
+func (o *Options) breakerMaxTrips() int {
+	if o != nil && o.BreakerMaxTrips > 0 {
+		return o.BreakerMaxTrips
+	}
+
+	return DefaultBreakerMaxTrips
+}
+
+func (o *Options) missedPongThreshold() int {
+	if o != nil && o.MissedPongThreshold > 0 {
+		return o.MissedPongThreshold
+	}
+
+	return DefaultMissedPongThreshold
+}
+
+func (o *Options) maxMissedPongs() int {
+	if o != nil && o.MaxMissedPongs > 0 {
+		return o.MaxMissedPongs
+	}
+
+	return 0
+}
+
 func (o *Options) pingPeriod() time.Duration {
 	if o != nil && o.PingPeriod > 0 {
 		return o.PingPeriod
@@ -173,6 +655,14 @@ func (o *Options) metricsProvider() provider.Provider {
 	return provider.NewDiscardProvider()
 }
 
+func (o *Options) measures() Measures {
+	if o != nil && o.Measures != nil {
+		return *o.Measures
+	}
+
+	return NewMeasures(o.metricsProvider())
+}
+
 func (o *Options) now() func() time.Time {
 	if o != nil && o.Now != nil {
 		return o.Now
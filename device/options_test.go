@@ -1,6 +1,8 @@
 package device
 
 import (
+	"compress/flate"
+	"net/http"
 	"testing"
 	"time"
 
@@ -18,6 +20,45 @@ func TestOptionsDefault(t *testing.T) {
 
 		assert.Equal(DefaultDeviceMessageQueueSize, o.deviceMessageQueueSize())
 		assert.NotNil(o.upgrader())
+		assert.False(o.upgrader().EnableCompression)
+		assert.Equal(DefaultCompressionLevel, o.compressionLevel())
+		assert.Equal([]string{ConveyHeader}, o.conveyHeaders())
+		assert.Equal(ConveyNotAvailableLog, o.conveyNotAvailablePolicy())
+		assert.Equal(DefaultConveyMaxHeaderLength, o.conveyMaxHeaderLength())
+		assert.Equal(DefaultConveyMaxDecodedSize, o.conveyMaxDecodedSize())
+
+		conveyMetadata, err := o.conveyMetadata()
+		assert.Nil(conveyMetadata)
+		assert.NoError(err)
+		assert.Equal(RateLimit{}, o.rateLimit())
+		assert.Nil(o.qosTiers())
+		assert.Equal(QueueBlock, o.queuePolicy())
+		assert.Equal(DefaultQueueBlockTimeout, o.queueBlockTimeout())
+		assert.False(o.bindClientCertificates())
+		assert.Equal(DefaultShutdownRate, o.shutdownRate())
+		assert.Equal(DefaultBroadcastRate, o.broadcastRate())
+		assert.Equal(DefaultDisconnectBatchSize, o.disconnectBatchSize())
+		assert.Zero(o.applicationIdlePeriod())
+		assert.Equal(DefaultReapInterval, o.reapInterval())
+		assert.Equal(DuplicateTerminateOld, o.duplicatePolicy())
+		assert.Equal(0, o.dedupSize())
+		assert.Equal(DefaultDedupTTL, o.dedupTTL())
+		assert.Equal(ParseOptions{}, o.destinationParsing())
+		assert.Equal(DefaultMissedPongThreshold, o.missedPongThreshold())
+		assert.Zero(o.breakerThreshold())
+		assert.Equal(DefaultBreakerCooldown, o.breakerCooldown())
+		assert.Equal(DefaultBreakerMaxTrips, o.breakerMaxTrips())
+		assert.Equal(DefaultRegistryShards, o.registryShards())
+		assert.Zero(o.maxMissedPongs())
+		assert.Nil(o.authenticator())
+		assert.Empty(o.validators())
+		assert.Equal(InvalidMessageDrop, o.invalidMessagePolicy())
+		assert.Zero(o.upgrader().HandshakeTimeout)
+		assert.Empty(o.upgrader().Subprotocols)
+		assert.False(o.requireSubprotocol())
+		assert.Zero(o.tlsHandshakeTimeout())
+		assert.Zero(o.firstMessageTimeout())
+		assert.Zero(o.maxConnectionAge())
 		assert.Equal(0, o.maxDevices())
 		assert.Equal(DefaultIdlePeriod, o.idlePeriod())
 		assert.Equal(DefaultPingPeriod, o.pingPeriod())
@@ -33,6 +74,7 @@ func TestOptions(t *testing.T) {
 		assert                  = assert.New(t)
 		expectedLogger          = logging.DefaultLogger()
 		expectedMetricsProvider = provider.NewPrometheusProvider("test", "test")
+		compressionLevel        = 9
 
 		o = Options{
 			Upgrader: websocket.Upgrader{
@@ -41,6 +83,38 @@ func TestOptions(t *testing.T) {
 				WriteBufferSize:  DefaultWriteBufferSize + 926,
 				Subprotocols:     []string{"foobar"},
 			},
+			EnableCompression: true,
+			CompressionLevel:  &compressionLevel,
+			QOSTiers: map[string]QOSTier{
+				"low":    {QueueSize: 10, DropWhenFull: true},
+				"HIGH":   {QueueSize: 50},
+				"nosuch": {QueueSize: 1},
+			},
+			QueuePolicy:            QueueDropOldest,
+			QueueBlockTimeout:      17 * time.Second,
+			BindClientCertificates: true,
+			ShutdownRate:           250,
+			BroadcastRate:          750,
+			DisconnectBatchSize:    25,
+			ApplicationIdlePeriod:  10 * time.Minute,
+			ReapInterval:           30 * time.Second,
+			DuplicatePolicy:        DuplicateAllowBoth,
+			DedupSize:              500,
+			DedupTTL:               time.Hour,
+			MissedPongThreshold:    4,
+			BreakerThreshold:       5,
+			BreakerCooldown:        time.Minute,
+			BreakerMaxTrips:        7,
+			RegistryShards:         64,
+			MaxMissedPongs:         6,
+			DestinationParsing:     ParseOptions{Mode: ParseStrict, RejectExtraSegments: true},
+			Authenticator:          AuthenticatorFunc(func(*http.Request, ID, *Metadata) (Session, error) { return Session{}, nil }),
+			Validators:             []Validator{SourceValidator()},
+			InvalidMessagePolicy:   InvalidMessageDisconnect,
+			TLSHandshakeTimeout:    5 * time.Second,
+			UpgradeTimeout:         3 * time.Second,
+			FirstMessageTimeout:    10 * time.Second,
+			MaxConnectionAge:       24 * time.Hour,
 			MaxDevices:             20000,
 			DeviceMessageQueueSize: DefaultDeviceMessageQueueSize + 287342,
 			IdlePeriod:             DefaultIdlePeriod + 3472*time.Minute,
@@ -55,14 +129,80 @@ func TestOptions(t *testing.T) {
 	assert.Equal(o.DeviceMessageQueueSize, o.deviceMessageQueueSize())
 	assert.Equal(
 		websocket.Upgrader{
-			HandshakeTimeout: 12377123 * time.Second,
-			ReadBufferSize:   DefaultReadBufferSize + 48729,
-			WriteBufferSize:  DefaultWriteBufferSize + 926,
-			Subprotocols:     []string{"foobar"},
+			HandshakeTimeout:  12377123 * time.Second,
+			ReadBufferSize:    DefaultReadBufferSize + 48729,
+			WriteBufferSize:   DefaultWriteBufferSize + 926,
+			Subprotocols:      []string{"foobar"},
+			EnableCompression: true,
 		},
 		*o.upgrader(),
 	)
 
+	o.Upgrader.HandshakeTimeout = 0
+	assert.Equal(3*time.Second, o.upgrader().HandshakeTimeout)
+
+	o.Subprotocols = []string{"wrp.v2"}
+	assert.Equal([]string{"foobar"}, o.upgrader().Subprotocols)
+	o.Upgrader.Subprotocols = nil
+	assert.Equal([]string{"wrp.v2"}, o.upgrader().Subprotocols)
+
+	assert.False(o.requireSubprotocol())
+	o.RequireSubprotocol = true
+	assert.True(o.requireSubprotocol())
+
+	o.ConveyHeaders = []string{"X-Xmidt-Convey", ConveyHeader}
+	assert.Equal([]string{"X-Xmidt-Convey", ConveyHeader}, o.conveyHeaders())
+
+	o.ConveyMaxHeaderLength = 1024
+	o.ConveyMaxDecodedSize = 2048
+	assert.Equal(1024, o.conveyMaxHeaderLength())
+	assert.Equal(2048, o.conveyMaxDecodedSize())
+
+	o.ConveyNotAvailablePolicy = ConveyNotAvailableReject
+	assert.Equal(ConveyNotAvailableReject, o.conveyNotAvailablePolicy())
+	o.ConveyNotAvailablePolicy = "nosuch"
+	assert.Equal(ConveyNotAvailableLog, o.conveyNotAvailablePolicy())
+
+	assert.Equal(9, o.compressionLevel())
+	compressionLevel = 47
+	assert.Equal(DefaultCompressionLevel, o.compressionLevel())
+
+	// flate.NoCompression must be distinguishable from an unset level
+	compressionLevel = flate.NoCompression
+	assert.Equal(flate.NoCompression, o.compressionLevel())
+
+	assert.Equal(
+		map[QOSLevel]QOSTier{
+			QOSLow:  {QueueSize: 10, DropWhenFull: true},
+			QOSHigh: {QueueSize: 50},
+		},
+		o.qosTiers(),
+	)
+
+	assert.Equal(QueueDropOldest, o.queuePolicy())
+	assert.Equal(17*time.Second, o.queueBlockTimeout())
+	assert.True(o.bindClientCertificates())
+	assert.Equal(250, o.shutdownRate())
+	assert.Equal(750, o.broadcastRate())
+	assert.Equal(25, o.disconnectBatchSize())
+	assert.Equal(10*time.Minute, o.applicationIdlePeriod())
+	assert.Equal(30*time.Second, o.reapInterval())
+	assert.Equal(DuplicateAllowBoth, o.duplicatePolicy())
+	assert.Equal(500, o.dedupSize())
+	assert.Equal(time.Hour, o.dedupTTL())
+	assert.Equal(ParseOptions{Mode: ParseStrict, RejectExtraSegments: true}, o.destinationParsing())
+	assert.Equal(4, o.missedPongThreshold())
+	assert.Equal(5, o.breakerThreshold())
+	assert.Equal(time.Minute, o.breakerCooldown())
+	assert.Equal(7, o.breakerMaxTrips())
+	assert.Equal(64, o.registryShards())
+	assert.Equal(6, o.maxMissedPongs())
+	assert.NotNil(o.authenticator())
+	assert.Len(o.validators(), 1)
+	assert.Equal(InvalidMessageDisconnect, o.invalidMessagePolicy())
+	assert.Equal(5*time.Second, o.tlsHandshakeTimeout())
+	assert.Equal(10*time.Second, o.firstMessageTimeout())
+	assert.Equal(24*time.Hour, o.maxConnectionAge())
 	assert.Equal(20000, o.maxDevices())
 	assert.Equal(o.IdlePeriod, o.idlePeriod())
 	assert.Equal(o.PingPeriod, o.pingPeriod())
@@ -0,0 +1,161 @@
+package device
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/xmidt-org/wrp-go/v3"
+)
+
+// QOSMetadataKey is the WRP metadata entry which carries a message's quality of service value.
+// The version of WRP used by this package has no dedicated field for QOS.
+const QOSMetadataKey = "qos"
+
+// QOSValue is a WRP quality of service value.  Valid values lie in the range [0, 99], with
+// higher values indicating more important traffic.
+type QOSValue int
+
+// QOSLevel is the class of service a QOSValue falls into.  Each level can be assigned its own
+// tier of a device's outbound queue.
+type QOSLevel int
+
+const (
+	QOSLow QOSLevel = iota
+	QOSMedium
+	QOSHigh
+	QOSCritical
+
+	// qosLevelCount is the number of distinct QOS levels
+	qosLevelCount = int(QOSCritical) + 1
+)
+
+// Level returns the QOSLevel for this value.  Values outside the valid range are clamped.
+func (v QOSValue) Level() QOSLevel {
+	switch {
+	case v < 25:
+		return QOSLow
+	case v < 50:
+		return QOSMedium
+	case v < 75:
+		return QOSHigh
+	default:
+		return QOSCritical
+	}
+}
+
+func (l QOSLevel) String() string {
+	switch l {
+	case QOSLow:
+		return "low"
+	case QOSMedium:
+		return "medium"
+	case QOSHigh:
+		return "high"
+	case QOSCritical:
+		return "critical"
+	default:
+		return "invalid"
+	}
+}
+
+// ParseQOSLevel parses the string form of a QOSLevel, ignoring case.
+func ParseQOSLevel(v string) (QOSLevel, bool) {
+	for l := QOSLow; l <= QOSCritical; l++ {
+		if strings.EqualFold(v, l.String()) {
+			return l, true
+		}
+	}
+
+	return QOSLow, false
+}
+
+// QOS returns the quality of service of this request, taken from the QOSMetadataKey metadata of
+// the request's message.  Requests with no QOS, or an unparseable QOS, have a QOSValue of zero.
+func (r *Request) QOS() QOSValue {
+	if m, ok := r.Message.(*wrp.Message); ok {
+		if v, err := strconv.Atoi(m.Metadata[QOSMetadataKey]); err == nil {
+			return QOSValue(v)
+		}
+	}
+
+	return 0
+}
+
+// QOSTier configures the tier of each device's outbound queue which services one QOSLevel.
+type QOSTier struct {
+	// QueueSize is the capacity of this tier.  If unset, the DeviceMessageQueueSize is used.
+	QueueSize int
+
+	// DropWhenFull causes messages to be rejected immediately with ErrorDeviceBusy when this
+	// tier is full, rather than waiting for room in the queue.
+	DropWhenFull bool
+}
+
+// qosQueues holds the outbound queue tiers for a device, indexed by QOSLevel.  When QOS tiers
+// are not configured, every level shares the same channel.
+type qosQueues struct {
+	tiers        [qosLevelCount]chan *envelope
+	dropWhenFull [qosLevelCount]bool
+}
+
+// newQOSQueues creates the queue tiers for a device.  If tiers is empty, a single queue of the
+// given default size is shared by all levels.
+func newQOSQueues(defaultSize int, tiers map[QOSLevel]QOSTier) *qosQueues {
+	q := new(qosQueues)
+	if len(tiers) == 0 {
+		shared := make(chan *envelope, defaultSize)
+		for i := range q.tiers {
+			q.tiers[i] = shared
+		}
+
+		return q
+	}
+
+	for i := range q.tiers {
+		tier := tiers[QOSLevel(i)]
+		size := tier.QueueSize
+		if size < 1 {
+			size = defaultSize
+		}
+
+		q.tiers[i] = make(chan *envelope, size)
+		q.dropWhenFull[i] = tier.DropWhenFull
+	}
+
+	return q
+}
+
+// distinct returns the set of unique channels backing these tiers, highest priority first
+func (q *qosQueues) distinct() []chan *envelope {
+	channels := make([]chan *envelope, 0, qosLevelCount)
+	for i := qosLevelCount - 1; i >= 0; i-- {
+		if i == qosLevelCount-1 || q.tiers[i] != q.tiers[i+1] {
+			channels = append(channels, q.tiers[i])
+		}
+	}
+
+	return channels
+}
+
+// len returns the total number of enqueued envelopes across all tiers
+func (q *qosQueues) len() (n int) {
+	for _, c := range q.distinct() {
+		n += len(c)
+	}
+
+	return
+}
+
+// poll performs a nonblocking dequeue, preferring higher priority tiers.  If all tiers are
+// empty, this method returns nil.
+func (q *qosQueues) poll() *envelope {
+	for i := qosLevelCount - 1; i >= 0; i-- {
+		select {
+		case e := <-q.tiers[i]:
+			return e
+		default:
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package device
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/wrp-go/v3"
+)
+
+func TestQOSValueLevel(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(QOSLow, QOSValue(-1).Level())
+	assert.Equal(QOSLow, QOSValue(0).Level())
+	assert.Equal(QOSLow, QOSValue(24).Level())
+	assert.Equal(QOSMedium, QOSValue(25).Level())
+	assert.Equal(QOSMedium, QOSValue(49).Level())
+	assert.Equal(QOSHigh, QOSValue(50).Level())
+	assert.Equal(QOSHigh, QOSValue(74).Level())
+	assert.Equal(QOSCritical, QOSValue(75).Level())
+	assert.Equal(QOSCritical, QOSValue(99).Level())
+	assert.Equal(QOSCritical, QOSValue(1000).Level())
+}
+
+func TestParseQOSLevel(t *testing.T) {
+	assert := assert.New(t)
+
+	for l := QOSLow; l <= QOSCritical; l++ {
+		actual, ok := ParseQOSLevel(l.String())
+		assert.True(ok)
+		assert.Equal(l, actual)
+	}
+
+	actual, ok := ParseQOSLevel("HIGH")
+	assert.True(ok)
+	assert.Equal(QOSHigh, actual)
+
+	_, ok = ParseQOSLevel("nosuch")
+	assert.False(ok)
+	assert.Equal("invalid", QOSLevel(-1).String())
+}
+
+func TestRequestQOS(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(QOSValue(0), (&Request{}).QOS())
+	assert.Equal(QOSValue(0), (&Request{Message: new(wrp.Message)}).QOS())
+	assert.Equal(QOSValue(0), (&Request{Message: &wrp.Message{Metadata: map[string]string{QOSMetadataKey: "abc"}}}).QOS())
+	assert.Equal(QOSValue(63), (&Request{Message: &wrp.Message{Metadata: map[string]string{QOSMetadataKey: "63"}}}).QOS())
+}
+
+func TestQOSQueues(t *testing.T) {
+	t.Run("Shared", func(t *testing.T) {
+		var (
+			assert = assert.New(t)
+			q      = newQOSQueues(5, nil)
+		)
+
+		assert.Len(q.distinct(), 1)
+		for i := range q.tiers {
+			assert.Equal(5, cap(q.tiers[i]))
+			assert.False(q.dropWhenFull[i])
+		}
+
+		first, second := new(envelope), new(envelope)
+		q.tiers[QOSLow] <- first
+		q.tiers[QOSCritical] <- second
+		assert.Equal(2, q.len())
+
+		// a shared queue is FIFO regardless of level
+		assert.True(first == q.poll())
+		assert.True(second == q.poll())
+		assert.Nil(q.poll())
+	})
+
+	t.Run("Tiered", func(t *testing.T) {
+		var (
+			assert = assert.New(t)
+			q      = newQOSQueues(5, map[QOSLevel]QOSTier{
+				QOSLow:      {QueueSize: 2, DropWhenFull: true},
+				QOSCritical: {QueueSize: 10},
+			})
+		)
+
+		assert.Len(q.distinct(), qosLevelCount)
+		assert.Equal(2, cap(q.tiers[QOSLow]))
+		assert.True(q.dropWhenFull[QOSLow])
+		assert.Equal(5, cap(q.tiers[QOSMedium]))
+		assert.Equal(5, cap(q.tiers[QOSHigh]))
+		assert.Equal(10, cap(q.tiers[QOSCritical]))
+		assert.False(q.dropWhenFull[QOSCritical])
+
+		low, medium, critical := new(envelope), new(envelope), new(envelope)
+		q.tiers[QOSLow] <- low
+		q.tiers[QOSMedium] <- medium
+		q.tiers[QOSCritical] <- critical
+		assert.Equal(3, q.len())
+
+		assert.True(critical == q.poll())
+		assert.True(medium == q.poll())
+		assert.True(low == q.poll())
+		assert.Nil(q.poll())
+	})
+}
+
+func TestDeviceSendDropWhenFull(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		d       = newDevice(deviceOptions{
+			ID:     ID("mac:112233445566"),
+			Logger: logging.NewTestLogger(nil, t),
+			QOSTiers: map[QOSLevel]QOSTier{
+				QOSLow: {QueueSize: 1, DropWhenFull: true},
+			},
+		})
+
+		lowRequest = func() *Request {
+			return &Request{Message: &wrp.Message{Type: wrp.SimpleEventMessageType, Destination: "mac:112233445566"}}
+		}
+	)
+
+	require.NotNil(d)
+	d.messages.tiers[QOSLow] <- new(envelope)
+
+	response, err := d.Send(lowRequest())
+	assert.Nil(response)
+	assert.Equal(ErrorDeviceBusy, err)
+}
@@ -0,0 +1,272 @@
+package device
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/spf13/cast"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/xhttp"
+)
+
+const (
+	// ModelConveyKey is the convey field which carries a device's hardware model
+	ModelConveyKey = "hw-model"
+
+	// ConveyQueryPrefix is the query parameter prefix used to match arbitrary convey fields,
+	// e.g. convey.hw-manufacturer=foo
+	ConveyQueryPrefix = "convey."
+
+	// ClaimQueryPrefix is the query parameter prefix used to match arbitrary JWT claims, e.g. claim.trust=1000
+	ClaimQueryPrefix = "claim."
+
+	// ConveyPatternQueryPrefix is the query parameter prefix used to match convey fields against
+	// regular expressions, e.g. convey~.fw-name=^TG1682_3\.
+	ConveyPatternQueryPrefix = "convey~."
+
+	// ClaimPatternQueryPrefix is the query parameter prefix used to match JWT claims against
+	// regular expressions, e.g. claim~.capabilities[*]=^x1
+	ClaimPatternQueryPrefix = "claim~."
+)
+
+// ErrorEmptyMetadataQuery indicates that a metadata query had no criteria
+var ErrorEmptyMetadataQuery = errors.New("At least one metadata criterion is required")
+
+// metadataQueryAliases maps the well-known query parameters onto the fields they match
+var metadataQueryAliases = map[string]string{
+	"model":     ConveyQueryPrefix + ModelConveyKey,
+	"firmware":  ConveyQueryPrefix + FirmwareConveyKey,
+	"partnerID": ClaimQueryPrefix + PartnerIDClaimKey,
+}
+
+// Pattern is a regular expression used as a metadata criterion.  Patterns marshal to and from
+// their source text.
+type Pattern struct {
+	*regexp.Regexp
+}
+
+// CompilePattern compiles a regular expression into a Pattern
+func CompilePattern(expr string) (Pattern, error) {
+	r, err := regexp.Compile(expr)
+	if err != nil {
+		return Pattern{}, err
+	}
+
+	return Pattern{r}, nil
+}
+
+func (p Pattern) MarshalText() ([]byte, error) {
+	if p.Regexp == nil {
+		return nil, nil
+	}
+
+	return []byte(p.String()), nil
+}
+
+func (p *Pattern) UnmarshalText(text []byte) error {
+	r, err := regexp.Compile(string(text))
+	if err != nil {
+		return err
+	}
+
+	p.Regexp = r
+	return nil
+}
+
+// MetadataQuery is a set of criteria matched against the convey information and JWT claims
+// of connected devices.  A device matches only if it matches every criterion.
+//
+// Claim criteria are keyed by claim paths.  A path is either a top-level claim name or a dotted path
+// into nested claims, where array elements are selected with "[n]" or, for any element, "[*]".
+// For example, "capabilities[*]" matches if any element of the capabilities claim matches.
+type MetadataQuery struct {
+	// Convey holds the required values of convey fields
+	Convey map[string]string `json:"convey,omitempty"`
+
+	// Claims holds the required values of JWT claims.  Non-string claims are compared using
+	// their string form.
+	Claims map[string]string `json:"claims,omitempty"`
+
+	// ConveyPatterns holds regular expressions that convey fields must match
+	ConveyPatterns map[string]Pattern `json:"conveyPatterns,omitempty"`
+
+	// ClaimPatterns holds regular expressions that JWT claims must match, using the claim's string form
+	ClaimPatterns map[string]Pattern `json:"claimPatterns,omitempty"`
+}
+
+func (mq MetadataQuery) empty() bool {
+	return len(mq.Convey) == 0 && len(mq.Claims) == 0 && len(mq.ConveyPatterns) == 0 && len(mq.ClaimPatterns) == 0
+}
+
+// ParseMetadataQuery builds a MetadataQuery from URL query values.  The parameters "model", "firmware",
+// and "partnerID" are recognized, as are parameters using ConveyQueryPrefix, ClaimQueryPrefix,
+// ConveyPatternQueryPrefix, and ClaimPatternQueryPrefix.  Other parameters are ignored.
+//
+// An invalid regular expression or claim path results in an error.
+func ParseMetadataQuery(values url.Values) (MetadataQuery, error) {
+	mq := MetadataQuery{
+		Convey:         make(map[string]string),
+		Claims:         make(map[string]string),
+		ConveyPatterns: make(map[string]Pattern),
+		ClaimPatterns:  make(map[string]Pattern),
+	}
+
+	for name := range values {
+		field := name
+		if alias, ok := metadataQueryAliases[name]; ok {
+			field = alias
+		}
+
+		switch {
+		case strings.HasPrefix(field, ConveyQueryPrefix) && len(field) > len(ConveyQueryPrefix):
+			mq.Convey[field[len(ConveyQueryPrefix):]] = values.Get(name)
+		case strings.HasPrefix(field, ClaimQueryPrefix) && len(field) > len(ClaimQueryPrefix):
+			path := field[len(ClaimQueryPrefix):]
+			if _, err := parseClaimPath(path); err != nil {
+				return mq, err
+			}
+
+			mq.Claims[path] = values.Get(name)
+
+		case strings.HasPrefix(field, ConveyPatternQueryPrefix) && len(field) > len(ConveyPatternQueryPrefix):
+			p, err := CompilePattern(values.Get(name))
+			if err != nil {
+				return mq, err
+			}
+
+			mq.ConveyPatterns[field[len(ConveyPatternQueryPrefix):]] = p
+
+		case strings.HasPrefix(field, ClaimPatternQueryPrefix) && len(field) > len(ClaimPatternQueryPrefix):
+			path := field[len(ClaimPatternQueryPrefix):]
+			if _, err := parseClaimPath(path); err != nil {
+				return mq, err
+			}
+
+			p, err := CompilePattern(values.Get(name))
+			if err != nil {
+				return mq, err
+			}
+
+			mq.ClaimPatterns[path] = p
+		}
+	}
+
+	if mq.empty() {
+		return mq, ErrorEmptyMetadataQuery
+	}
+
+	return mq, nil
+}
+
+// Matches tests if the given device satisfies all the criteria of this query
+func (mq MetadataQuery) Matches(d Interface) bool {
+	if len(mq.Convey) > 0 || len(mq.ConveyPatterns) > 0 {
+		c := d.Convey()
+		if c == nil {
+			return false
+		}
+
+		for key, expected := range mq.Convey {
+			if actual, ok := c.Get(key); !ok || cast.ToString(actual) != expected {
+				return false
+			}
+		}
+
+		for key, p := range mq.ConveyPatterns {
+			if actual, ok := c.Get(key); !ok || p.Regexp == nil || !p.MatchString(cast.ToString(actual)) {
+				return false
+			}
+		}
+	}
+
+	if len(mq.Claims) > 0 || len(mq.ClaimPatterns) > 0 {
+		var claims map[string]interface{}
+		if metadata := d.Metadata(); metadata != nil {
+			claims = metadata.Claims()
+		}
+
+		for path, expected := range mq.Claims {
+			if !anyClaim(claims, path, func(v string) bool { return v == expected }) {
+				return false
+			}
+		}
+
+		for path, p := range mq.ClaimPatterns {
+			if p.Regexp == nil || !anyClaim(claims, path, p.MatchString) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// anyClaim tests if any value referred to by the given claim path satisfies a predicate
+func anyClaim(claims map[string]interface{}, path string, f func(string) bool) bool {
+	for _, v := range claimValues(claims, path) {
+		if f(cast.ToString(v)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MetadataHandler is an http.Handler which returns the connected devices whose convey information
+// and JWT claims match the criteria in the request's query.  See ParseMetadataQuery.
+type MetadataHandler struct {
+	Logger   log.Logger
+	Registry Registry
+}
+
+func (mh *MetadataHandler) logger() log.Logger {
+	if mh.Logger != nil {
+		return mh.Logger
+	}
+
+	return logging.DefaultLogger()
+}
+
+func (mh *MetadataHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	mq, err := ParseMetadataQuery(request.URL.Query())
+	if err != nil {
+		mh.logger().Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "invalid metadata query", logging.ErrorKey(), err)
+		xhttp.WriteError(response, http.StatusBadRequest, err)
+		return
+	}
+
+	var matched []Interface
+	mh.Registry.VisitAll(func(d Interface) bool {
+		if mq.Matches(d) {
+			matched = append(matched, d)
+		}
+
+		return true
+	})
+
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].ID() < matched[j].ID()
+	})
+
+	var output bytes.Buffer
+	output.WriteString(`{"devices":[`)
+	for i, d := range matched {
+		if i > 0 {
+			output.WriteString(`,`)
+		}
+
+		fmt.Fprintf(&output, `{"id": "%s", "statistics": %s}`, d.ID(), d.Statistics())
+	}
+
+	fmt.Fprintf(&output, `],"count":%d}`, len(matched))
+	response.Header().Set("Content-Type", "application/json")
+	response.Write(output.Bytes())
+}
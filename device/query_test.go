@@ -0,0 +1,196 @@
+package device
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/convey"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+func newMetadataTestDevice(t *testing.T, id ID, partnerID, firmware, model string) *device {
+	metadata := new(Metadata)
+	metadata.SetClaims(map[string]interface{}{PartnerIDClaimKey: partnerID, "trust": 1000})
+	return newDevice(deviceOptions{
+		ID:          id,
+		C:           convey.C{FirmwareConveyKey: firmware, ModelConveyKey: model},
+		ConnectedAt: time.Now(),
+		Logger:      logging.NewTestLogger(nil, t),
+		Metadata:    metadata,
+	})
+}
+
+func TestParseMetadataQuery(t *testing.T) {
+	t.Run("Empty", func(t *testing.T) {
+		_, err := ParseMetadataQuery(url.Values{"unrelated": {"x"}, "convey.": {"x"}})
+		assert.Equal(t, ErrorEmptyMetadataQuery, err)
+	})
+
+	t.Run("Aliases", func(t *testing.T) {
+		mq, err := ParseMetadataQuery(url.Values{"model": {"TG1682"}, "firmware": {"fw-1"}, "partnerID": {"comcast"}})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{ModelConveyKey: "TG1682", FirmwareConveyKey: "fw-1"}, mq.Convey)
+		assert.Equal(t, map[string]string{PartnerIDClaimKey: "comcast"}, mq.Claims)
+	})
+
+	t.Run("Prefixed", func(t *testing.T) {
+		mq, err := ParseMetadataQuery(url.Values{"convey.hw-manufacturer": {"ARRIS"}, "claim.trust": {"1000"}})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"hw-manufacturer": "ARRIS"}, mq.Convey)
+		assert.Equal(t, map[string]string{"trust": "1000"}, mq.Claims)
+	})
+
+	t.Run("Patterns", func(t *testing.T) {
+		mq, err := ParseMetadataQuery(url.Values{"convey~.fw-name": {"^TG1682_"}, "claim~.capabilities[*]": {"^x"}})
+		require.NoError(t, err)
+		assert.Empty(t, mq.Convey)
+		assert.Empty(t, mq.Claims)
+		require.Contains(t, mq.ConveyPatterns, "fw-name")
+		assert.Equal(t, "^TG1682_", mq.ConveyPatterns["fw-name"].String())
+		require.Contains(t, mq.ClaimPatterns, "capabilities[*]")
+		assert.Equal(t, "^x", mq.ClaimPatterns["capabilities[*]"].String())
+	})
+
+	t.Run("InvalidPattern", func(t *testing.T) {
+		_, err := ParseMetadataQuery(url.Values{"convey~.fw-name": {"["}})
+		assert.Error(t, err)
+
+		_, err = ParseMetadataQuery(url.Values{"claim~.trust": {"("}})
+		assert.Error(t, err)
+	})
+
+	t.Run("InvalidClaimPath", func(t *testing.T) {
+		_, err := ParseMetadataQuery(url.Values{"claim.capabilities[x]": {"x1"}})
+		assert.Equal(t, ErrorInvalidClaimPath, err)
+
+		_, err = ParseMetadataQuery(url.Values{"claim~.a..b": {"x1"}})
+		assert.Equal(t, ErrorInvalidClaimPath, err)
+	})
+}
+
+func TestPatternJSON(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		mq = MetadataQuery{ClaimPatterns: map[string]Pattern{"capabilities[*]": {regexp.MustCompile("^x[0-9]$")}}}
+	)
+
+	data, err := json.Marshal(mq)
+	require.NoError(err)
+	assert.JSONEq(`{"claimPatterns": {"capabilities[*]": "^x[0-9]$"}}`, string(data))
+
+	var actual MetadataQuery
+	require.NoError(json.Unmarshal(data, &actual))
+	require.Contains(actual.ClaimPatterns, "capabilities[*]")
+	assert.Equal("^x[0-9]$", actual.ClaimPatterns["capabilities[*]"].String())
+
+	assert.Error(json.Unmarshal([]byte(`{"conveyPatterns": {"fw-name": "["}}`), &actual))
+}
+
+func TestMetadataQueryMatches(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		d      = newMetadataTestDevice(t, ID("mac:112233445566"), "comcast", "fw-1", "TG1682")
+	)
+
+	assert.True(MetadataQuery{}.Matches(d))
+	assert.True(MetadataQuery{Convey: map[string]string{ModelConveyKey: "TG1682", FirmwareConveyKey: "fw-1"}}.Matches(d))
+	assert.True(MetadataQuery{Claims: map[string]string{PartnerIDClaimKey: "comcast", "trust": "1000"}}.Matches(d))
+	assert.False(MetadataQuery{Convey: map[string]string{ModelConveyKey: "other"}}.Matches(d))
+	assert.False(MetadataQuery{Convey: map[string]string{"missing": "x"}}.Matches(d))
+	assert.False(MetadataQuery{Claims: map[string]string{"trust": "0"}}.Matches(d))
+
+	assert.True(MetadataQuery{ConveyPatterns: map[string]Pattern{ModelConveyKey: {regexp.MustCompile("^TG16")}}}.Matches(d))
+	assert.False(MetadataQuery{ConveyPatterns: map[string]Pattern{ModelConveyKey: {regexp.MustCompile("^XB")}}}.Matches(d))
+	assert.False(MetadataQuery{ConveyPatterns: map[string]Pattern{"missing": {regexp.MustCompile(".*")}}}.Matches(d))
+	assert.True(MetadataQuery{ClaimPatterns: map[string]Pattern{"trust": {regexp.MustCompile("^[0-9]+$")}}}.Matches(d))
+	assert.False(MetadataQuery{ClaimPatterns: map[string]Pattern{PartnerIDClaimKey: {regexp.MustCompile("^cox$")}}}.Matches(d))
+
+	nested := newMetadataTestDevice(t, ID("mac:112233445577"), "comcast", "fw-1", "TG1682")
+	nested.Metadata().SetClaims(map[string]interface{}{
+		"capabilities": []interface{}{"x1", "docsis"},
+		"location":     map[string]interface{}{"region": "us-east"},
+	})
+
+	assert.True(MetadataQuery{Claims: map[string]string{"capabilities[*]": "docsis"}}.Matches(nested))
+	assert.False(MetadataQuery{Claims: map[string]string{"capabilities[0]": "docsis"}}.Matches(nested))
+	assert.True(MetadataQuery{Claims: map[string]string{"location.region": "us-east"}}.Matches(nested))
+	assert.True(MetadataQuery{ClaimPatterns: map[string]Pattern{"capabilities[*]": {regexp.MustCompile("^x[0-9]$")}}}.Matches(nested))
+	assert.False(MetadataQuery{ClaimPatterns: map[string]Pattern{"capabilities[*]": {regexp.MustCompile("^xb")}}}.Matches(nested))
+
+	noMetadata := newDevice(deviceOptions{ID: ID("mac:665544332211"), Logger: logging.NewTestLogger(nil, t)})
+	assert.False(MetadataQuery{Convey: map[string]string{ModelConveyKey: "TG1682"}}.Matches(noMetadata))
+	assert.False(MetadataQuery{Claims: map[string]string{PartnerIDClaimKey: "comcast"}}.Matches(noMetadata))
+}
+
+func testMetadataHandlerBadQuery(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		registry = new(MockRegistry)
+		handler  = MetadataHandler{Logger: logging.NewTestLogger(nil, t), Registry: registry}
+		response = httptest.NewRecorder()
+	)
+
+	handler.ServeHTTP(response, httptest.NewRequest("GET", "/devices/query", nil))
+	assert.Equal(http.StatusBadRequest, response.Code)
+	registry.AssertExpectations(t)
+}
+
+func testMetadataHandlerSuccess(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		require  = require.New(t)
+		registry = new(MockRegistry)
+		handler  = MetadataHandler{Logger: logging.NewTestLogger(nil, t), Registry: registry}
+		response = httptest.NewRecorder()
+		devices  = []*device{
+			newMetadataTestDevice(t, ID("mac:000000000003"), "comcast", "fw-1", "TG1682"),
+			newMetadataTestDevice(t, ID("mac:000000000001"), "comcast", "fw-1", "TG1682"),
+			newMetadataTestDevice(t, ID("mac:000000000002"), "comcast", "fw-2", "TG1682"),
+			newMetadataTestDevice(t, ID("mac:000000000004"), "other", "fw-1", "TG1682"),
+		}
+	)
+
+	registry.On("VisitAll", mock.MatchedBy(func(func(Interface) bool) bool { return true })).
+		Run(func(arguments mock.Arguments) {
+			visitor := arguments.Get(0).(func(Interface) bool)
+			for _, d := range devices {
+				visitor(d)
+			}
+		}).
+		Return(len(devices))
+
+	handler.ServeHTTP(response, httptest.NewRequest("GET", "/devices/query?model=TG1682&firmware=fw-1&partnerID=comcast", nil))
+	assert.Equal(http.StatusOK, response.Code)
+	assert.Equal("application/json", response.HeaderMap.Get("Content-Type"))
+
+	var result struct {
+		Devices []struct {
+			ID         string                 `json:"id"`
+			Statistics map[string]interface{} `json:"statistics"`
+		} `json:"devices"`
+		Count int `json:"count"`
+	}
+
+	require.NoError(json.Unmarshal(response.Body.Bytes(), &result))
+	assert.Equal(2, result.Count)
+	require.Len(result.Devices, 2)
+	assert.Equal("mac:000000000001", result.Devices[0].ID)
+	assert.Equal("mac:000000000003", result.Devices[1].ID)
+	assert.Contains(result.Devices[0].Statistics, "upTime")
+	registry.AssertExpectations(t)
+}
+
+func TestMetadataHandler(t *testing.T) {
+	t.Run("BadQuery", testMetadataHandlerBadQuery)
+	t.Run("Success", testMetadataHandlerSuccess)
+}
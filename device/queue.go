@@ -0,0 +1,124 @@
+package device
+
+import (
+	"time"
+
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+// QueuePolicy describes what a device does with an outbound message when its queue is full.
+type QueuePolicy string
+
+const (
+	// QueueBlock waits for room in the queue until the request's context is cancelled.  This is the default.
+	QueueBlock QueuePolicy = "block"
+
+	// QueueBlockTimeout waits for room in the queue for at most the configured block timeout,
+	// after which the message fails with ErrorQueueFull.
+	QueueBlockTimeout QueuePolicy = "block-timeout"
+
+	// QueueDropOldest discards the oldest enqueued messages, which fail with ErrorMessageDropped,
+	// to make room for the new message.  Each discarded message is dispatched as a MessageFailed event.
+	QueueDropOldest QueuePolicy = "drop-oldest"
+
+	// QueueDropNewest fails the new message immediately with ErrorQueueFull.
+	QueueDropNewest QueuePolicy = "drop-newest"
+
+	// QueueDisconnect fails the new message with ErrorQueueFull and disconnects the device.
+	QueueDisconnect QueuePolicy = "disconnect"
+)
+
+// DefaultQueueBlockTimeout is the time a message waits for room in a full queue under QueueBlockTimeout
+// when no timeout is configured.
+const DefaultQueueBlockTimeout = 5 * time.Second
+
+func (qp QueuePolicy) normalize() QueuePolicy {
+	switch qp {
+	case QueueBlockTimeout, QueueDropOldest, QueueDropNewest, QueueDisconnect:
+		return qp
+	default:
+		return QueueBlock
+	}
+}
+
+// enqueue places an envelope into one of this device's queues, applying this device's QueuePolicy
+// if that queue is full.
+func (d *device) enqueue(queue chan *envelope, e *envelope, done <-chan struct{}) error {
+	select {
+	case <-d.shutdown:
+		return ErrorDeviceClosed
+	case queue <- e:
+		return nil
+	default:
+	}
+
+	d.queueFull.With("policy", string(d.queuePolicy)).Add(1.0)
+	switch d.queuePolicy {
+	case QueueBlockTimeout:
+		timer := time.NewTimer(d.queueBlockTimeout)
+		defer timer.Stop()
+
+		select {
+		case <-done:
+			return e.request.Context().Err()
+		case <-d.shutdown:
+			return ErrorDeviceClosed
+		case queue <- e:
+			return nil
+		case <-timer.C:
+			return ErrorQueueFull
+		}
+
+	case QueueDropOldest:
+		for {
+			select {
+			case dropped := <-queue:
+				dropped.complete <- ErrorMessageDropped
+				d.messageDropped(dropped)
+			default:
+			}
+
+			select {
+			case <-d.shutdown:
+				return ErrorDeviceClosed
+			case queue <- e:
+				return nil
+			default:
+			}
+		}
+
+	case QueueDropNewest:
+		return ErrorQueueFull
+
+	case QueueDisconnect:
+		d.errorLog.Log(logging.MessageKey(), "disconnecting device with a full queue")
+		d.requestClose(CloseReason{Err: ErrorQueueFull, Reason: DisconnectQueueFull})
+		return ErrorQueueFull
+
+	default:
+		select {
+		case <-done:
+			return e.request.Context().Err()
+		case <-d.shutdown:
+			return ErrorDeviceClosed
+		case queue <- e:
+			return nil
+		}
+	}
+}
+
+// messageDropped reports a message evicted from one of this device's queues as a MessageFailed event
+func (d *device) messageDropped(e *envelope) {
+	if d.listener == nil {
+		return
+	}
+
+	d.listener(&Event{
+		Type:     MessageFailed,
+		Device:   d,
+		Message:  e.request.Message,
+		Format:   e.request.Format,
+		Contents: e.request.Contents,
+		Error:    ErrorMessageDropped,
+	})
+}
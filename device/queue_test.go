@@ -0,0 +1,159 @@
+package device
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/xmetrics/xmetricstest"
+	"github.com/xmidt-org/wrp-go/v3"
+)
+
+func TestQueuePolicyNormalize(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(QueueBlock, QueuePolicy("").normalize())
+	assert.Equal(QueueBlock, QueuePolicy("nosuch").normalize())
+	assert.Equal(QueueBlock, QueueBlock.normalize())
+	assert.Equal(QueueBlockTimeout, QueueBlockTimeout.normalize())
+	assert.Equal(QueueDropOldest, QueueDropOldest.normalize())
+	assert.Equal(QueueDropNewest, QueueDropNewest.normalize())
+	assert.Equal(QueueDisconnect, QueueDisconnect.normalize())
+}
+
+// newQueueTestDevice creates a device with a full queue of size 1.  The completion channel of the
+// envelope occupying the queue is returned.
+func newQueueTestDevice(t *testing.T, policy QueuePolicy, p xmetricstest.Provider) (*device, chan error) {
+	d := newDevice(deviceOptions{
+		ID:                ID("mac:112233445566"),
+		QueueSize:         1,
+		Logger:            logging.NewTestLogger(nil, t),
+		QueuePolicy:       policy,
+		QueueBlockTimeout: 10 * time.Millisecond,
+		QueueFull:         p.NewCounter(QueueFullCounter),
+	})
+
+	complete := make(chan error, 1)
+	d.messages.tiers[QOSLow] <- &envelope{request: newQueueTestRequest(), complete: complete}
+	return d, complete
+}
+
+func newQueueTestRequest() *Request {
+	return &Request{Message: &wrp.Message{Type: wrp.SimpleEventMessageType, Destination: "mac:112233445566"}}
+}
+
+func testDeviceEnqueueBlock(t *testing.T) {
+	var (
+		assert      = assert.New(t)
+		p           = xmetricstest.NewProvider(nil, Metrics)
+		d, _        = newQueueTestDevice(t, "", p)
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	)
+
+	defer cancel()
+	err := d.sendRequest(newQueueTestRequest().WithContext(ctx))
+	assert.Equal(context.DeadlineExceeded, err)
+	assert.False(d.Closed())
+	p.Assert(t, QueueFullCounter, "policy", string(QueueBlock))(xmetricstest.Value(1.0))
+}
+
+func testDeviceEnqueueBlockTimeout(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		p      = xmetricstest.NewProvider(nil, Metrics)
+		d, _   = newQueueTestDevice(t, QueueBlockTimeout, p)
+	)
+
+	assert.Equal(ErrorQueueFull, d.sendRequest(newQueueTestRequest()))
+	assert.False(d.Closed())
+	assert.Equal(1, d.Pending())
+	p.Assert(t, QueueFullCounter, "policy", string(QueueBlockTimeout))(xmetricstest.Value(1.0))
+}
+
+func testDeviceEnqueueDropOldest(t *testing.T) {
+	var (
+		assert         = assert.New(t)
+		require        = require.New(t)
+		p              = xmetricstest.NewProvider(nil, Metrics)
+		d, oldComplete = newQueueTestDevice(t, QueueDropOldest, p)
+		request        = newQueueTestRequest()
+		result         = make(chan error, 1)
+		events         = make(chan *Event, 1)
+	)
+
+	d.listener = func(e *Event) { events <- e }
+	go func() {
+		result <- d.sendRequest(request)
+	}()
+
+	select {
+	case err := <-oldComplete:
+		assert.Equal(ErrorMessageDropped, err)
+	case <-time.After(time.Second):
+		require.Fail("The oldest message was not dropped")
+	}
+
+	select {
+	case event := <-events:
+		assert.Equal(MessageFailed, event.Type)
+		assert.True(d == event.Device)
+		assert.Equal(newQueueTestRequest().Message, event.Message)
+		assert.Equal(ErrorMessageDropped, event.Error)
+	case <-time.After(time.Second):
+		require.Fail("No MessageFailed event was dispatched for the dropped message")
+	}
+
+	e := d.messages.poll()
+	require.NotNil(e)
+	assert.True(request == e.request)
+	e.complete <- nil
+
+	select {
+	case err := <-result:
+		assert.NoError(err)
+	case <-time.After(time.Second):
+		require.Fail("sendRequest did not return")
+	}
+
+	assert.False(d.Closed())
+	p.Assert(t, QueueFullCounter, "policy", string(QueueDropOldest))(xmetricstest.Value(1.0))
+}
+
+func testDeviceEnqueueDropNewest(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		p      = xmetricstest.NewProvider(nil, Metrics)
+		d, _   = newQueueTestDevice(t, QueueDropNewest, p)
+	)
+
+	response, err := d.Send(newQueueTestRequest())
+	assert.Nil(response)
+	assert.Equal(ErrorQueueFull, err)
+	assert.False(d.Closed())
+	p.Assert(t, QueueFullCounter, "policy", string(QueueDropNewest))(xmetricstest.Value(1.0))
+}
+
+func testDeviceEnqueueDisconnect(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		p      = xmetricstest.NewProvider(nil, Metrics)
+		d, _   = newQueueTestDevice(t, QueueDisconnect, p)
+	)
+
+	assert.Equal(ErrorQueueFull, d.sendRequest(newQueueTestRequest()))
+	assert.True(d.Closed())
+	assert.Equal(ErrorQueueFull, d.CloseReason().Err)
+	assert.Equal("queue-full", d.CloseReason().Text)
+	p.Assert(t, QueueFullCounter, "policy", string(QueueDisconnect))(xmetricstest.Value(1.0))
+}
+
+func TestDeviceEnqueue(t *testing.T) {
+	t.Run("Block", testDeviceEnqueueBlock)
+	t.Run("BlockTimeout", testDeviceEnqueueBlockTimeout)
+	t.Run("DropOldest", testDeviceEnqueueDropOldest)
+	t.Run("DropNewest", testDeviceEnqueueDropNewest)
+	t.Run("Disconnect", testDeviceEnqueueDisconnect)
+}
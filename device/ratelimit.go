@@ -0,0 +1,109 @@
+package device
+
+import (
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimitPolicy describes what a Manager does with an outbound message that exceeds
+// a device's rate limit.
+type RateLimitPolicy string
+
+const (
+	// RateLimitDrop fails any message that exceeds the rate limit with ErrorRateLimited.
+	RateLimitDrop RateLimitPolicy = "drop"
+
+	// RateLimitQueue holds messages in the write pump until the rate limit allows them to be sent.
+	// While a message is held, no other traffic, including pings, is written to that device.
+	RateLimitQueue RateLimitPolicy = "queue"
+
+	// RateLimitDisconnect fails the offending message and disconnects the device.
+	RateLimitDisconnect RateLimitPolicy = "disconnect"
+)
+
+// RateLimit configures token bucket rate limiting for messages sent to each device.
+type RateLimit struct {
+	// Rate is the sustained number of messages per second that may be sent to any one device.
+	// If nonpositive, outbound rate limiting is disabled.
+	Rate float64
+
+	// Burst is the maximum number of messages that may be sent to a device at once.  If unset,
+	// a burst of 1 is used.
+	Burst int
+
+	// Policy determines how messages that exceed the rate limit are handled.  If unset,
+	// RateLimitDrop is used.
+	Policy RateLimitPolicy
+}
+
+func (rl RateLimit) enabled() bool {
+	return rl.Rate > 0.0
+}
+
+func (rl RateLimit) burst() int {
+	if rl.Burst > 0 {
+		return rl.Burst
+	}
+
+	return 1
+}
+
+func (rl RateLimit) policy() RateLimitPolicy {
+	switch rl.Policy {
+	case RateLimitQueue, RateLimitDisconnect:
+		return rl.Policy
+	default:
+		return RateLimitDrop
+	}
+}
+
+// newLimiter creates the token bucket for a single device.  If rate limiting is disabled,
+// this function returns nil.
+func (rl RateLimit) newLimiter() *rate.Limiter {
+	if !rl.enabled() {
+		return nil
+	}
+
+	return rate.NewLimiter(rate.Limit(rl.Rate), rl.burst())
+}
+
+// throttle applies the outbound rate limit to a single envelope.  A nil limiter indicates that
+// rate limiting is disabled.  If this method returns an error, the envelope must not be written
+// to the device.
+func (m *manager) throttle(d *device, limiter *rate.Limiter, e *envelope) error {
+	if limiter == nil {
+		return nil
+	}
+
+	policy := m.rateLimit.policy()
+	if policy != RateLimitQueue {
+		if limiter.Allow() {
+			return nil
+		}
+
+		m.measures.RateLimited.With("policy", string(policy)).Add(1.0)
+		return ErrorRateLimited
+	}
+
+	reservation := limiter.Reserve()
+	delay := reservation.Delay()
+	if delay <= 0 {
+		return nil
+	}
+
+	m.measures.RateLimited.With("policy", string(policy)).Add(1.0)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-e.request.Context().Done():
+		reservation.Cancel()
+		return e.request.Context().Err()
+	case <-d.shutdown:
+		reservation.Cancel()
+		return ErrorDeviceClosed
+	}
+}
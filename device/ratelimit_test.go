@@ -0,0 +1,125 @@
+package device
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/wrp-go/v3"
+)
+
+func newThrottleTest(t *testing.T, rl RateLimit) (*manager, *device, *testCounter) {
+	counter := newTestCounter()
+	measures := NewMeasures(provider.NewDiscardProvider())
+	measures.RateLimited = counter
+
+	return &manager{rateLimit: rl, measures: measures},
+		newDevice(deviceOptions{ID: ID("mac:112233445566"), Logger: logging.NewTestLogger(nil, t)}),
+		counter
+}
+
+func newTestEnvelope(ctx context.Context) *envelope {
+	return &envelope{
+		request:  (&Request{Message: new(wrp.Message)}).WithContext(ctx),
+		complete: make(chan error, 1),
+	}
+}
+
+func TestRateLimitDefaults(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.False(RateLimit{}.enabled())
+	assert.Nil(RateLimit{}.newLimiter())
+	assert.Equal(1, RateLimit{}.burst())
+	assert.Equal(RateLimitDrop, RateLimit{}.policy())
+	assert.Equal(RateLimitDrop, RateLimit{Policy: "nosuch"}.policy())
+
+	rl := RateLimit{Rate: 10.0, Burst: 5, Policy: RateLimitQueue}
+	assert.True(rl.enabled())
+	assert.Equal(5, rl.burst())
+	assert.Equal(RateLimitQueue, rl.policy())
+	assert.NotNil(rl.newLimiter())
+}
+
+func testThrottleDisabled(t *testing.T) {
+	m, d, counter := newThrottleTest(t, RateLimit{})
+	assert.NoError(t, m.throttle(d, nil, newTestEnvelope(context.Background())))
+	assert.Zero(t, counter.count)
+}
+
+func testThrottleDrop(t *testing.T) {
+	for _, policy := range []RateLimitPolicy{RateLimitDrop, RateLimitDisconnect} {
+		t.Run(string(policy), func(t *testing.T) {
+			var (
+				assert        = assert.New(t)
+				rl            = RateLimit{Rate: 0.001, Burst: 2, Policy: policy}
+				m, d, counter = newThrottleTest(t, rl)
+				limiter       = rl.newLimiter()
+			)
+
+			assert.NoError(m.throttle(d, limiter, newTestEnvelope(context.Background())))
+			assert.NoError(m.throttle(d, limiter, newTestEnvelope(context.Background())))
+			assert.Zero(counter.count)
+
+			assert.Equal(ErrorRateLimited, m.throttle(d, limiter, newTestEnvelope(context.Background())))
+			assert.Equal(1.0, counter.count)
+			assert.Equal(map[string]string{"policy": string(policy)}, counter.labelPairs)
+		})
+	}
+}
+
+func testThrottleQueue(t *testing.T) {
+	var (
+		assert        = assert.New(t)
+		rl            = RateLimit{Rate: 1000.0, Policy: RateLimitQueue}
+		m, d, counter = newThrottleTest(t, rl)
+		limiter       = rl.newLimiter()
+	)
+
+	assert.NoError(m.throttle(d, limiter, newTestEnvelope(context.Background())))
+	assert.Zero(counter.count)
+
+	assert.NoError(m.throttle(d, limiter, newTestEnvelope(context.Background())))
+	assert.Equal(1.0, counter.count)
+	assert.Equal(map[string]string{"policy": string(RateLimitQueue)}, counter.labelPairs)
+}
+
+func testThrottleQueueCancelled(t *testing.T) {
+	var (
+		assert      = assert.New(t)
+		rl          = RateLimit{Rate: 0.001, Policy: RateLimitQueue}
+		m, d, _     = newThrottleTest(t, rl)
+		limiter     = rl.newLimiter()
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	)
+
+	defer cancel()
+	assert.NoError(m.throttle(d, limiter, newTestEnvelope(context.Background())))
+	assert.Equal(context.DeadlineExceeded, m.throttle(d, limiter, newTestEnvelope(ctx)))
+}
+
+func testThrottleQueueShutdown(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		rl      = RateLimit{Rate: 0.001, Policy: RateLimitQueue}
+		m, d, _ = newThrottleTest(t, rl)
+		limiter = rl.newLimiter()
+	)
+
+	require.NoError(m.throttle(d, limiter, newTestEnvelope(context.Background())))
+	d.requestClose(CloseReason{Text: "test"})
+	assert.Equal(ErrorDeviceClosed, m.throttle(d, limiter, newTestEnvelope(context.Background())))
+}
+
+func TestThrottle(t *testing.T) {
+	t.Run("Disabled", testThrottleDisabled)
+	t.Run("Drop", testThrottleDrop)
+	t.Run("Queue", testThrottleQueue)
+	t.Run("QueueCancelled", testThrottleQueueCancelled)
+	t.Run("QueueShutdown", testThrottleQueueShutdown)
+}
@@ -0,0 +1,37 @@
+package device
+
+import (
+	"time"
+
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+// reapIdle disconnects idle devices each time the ticker fires, until this manager is shut down
+func (m *manager) reapIdle(ticker *time.Ticker) {
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.reaperStop:
+			return
+		case <-ticker.C:
+			m.reap(m.now())
+		}
+	}
+}
+
+// reap disconnects each device whose most recent application message, or whose connection if it has sent
+// no messages, is older than the ApplicationIdlePeriod as of the given time.  Pings and pongs do not count
+// as activity, so this clears connections which are alive at the websocket level but otherwise unused.
+func (m *manager) reap(now time.Time) int {
+	reason := CloseReason{Reason: DisconnectApplicationIdle}
+	count := m.devices.removeIf(func(d *device) (CloseReason, bool) {
+		return reason, now.Sub(d.lastActivityAt()) > m.applicationIdlePeriod
+	})
+
+	if count > 0 {
+		m.measures.IdleReaped.Add(float64(count))
+		m.logger.Log(logging.MessageKey(), "reaped idle devices", "count", count, "applicationIdlePeriod", m.applicationIdlePeriod)
+	}
+
+	return count
+}
@@ -0,0 +1,119 @@
+package device
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/xmetrics/xmetricstest"
+)
+
+func testManagerReapNoIdleDevices(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		p      = xmetricstest.NewProvider(nil, Metrics)
+		now    = time.Now()
+		m      = NewManager(&Options{
+			Logger:                logging.NewTestLogger(nil, t),
+			MetricsProvider:       p,
+			ApplicationIdlePeriod: time.Minute,
+			ReapInterval:          time.Hour,
+		}).(*manager)
+	)
+
+	defer m.Shutdown(context.Background())
+	m.devices.add(newDevice(deviceOptions{ID: "mac:112233445566", ConnectedAt: now, Logger: m.logger}))
+
+	assert.Zero(m.reap(now.Add(time.Minute)))
+	assert.Equal(1, m.Len())
+	p.Assert(t, IdleReapedCounter)(xmetricstest.Value(0.0))
+}
+
+func testManagerReapIdleDevices(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		p      = xmetricstest.NewProvider(nil, Metrics)
+		now    = time.Now()
+		m      = NewManager(&Options{
+			Logger:                logging.NewTestLogger(nil, t),
+			MetricsProvider:       p,
+			ApplicationIdlePeriod: time.Minute,
+			ReapInterval:          time.Hour,
+		}).(*manager)
+
+		idle   = newDevice(deviceOptions{ID: "mac:112233445566", ConnectedAt: now.Add(-time.Hour), Logger: m.logger})
+		active = newDevice(deviceOptions{ID: "mac:665544332211", ConnectedAt: now.Add(-time.Hour), Logger: m.logger})
+	)
+
+	defer m.Shutdown(context.Background())
+	m.devices.add(idle)
+	m.devices.add(active)
+	active.touch(now.Add(-time.Second))
+
+	assert.Equal(1, m.reap(now))
+	assert.Equal(1, m.Len())
+
+	_, ok := m.Get(active.ID())
+	assert.True(ok)
+
+	assert.True(idle.Closed())
+	assert.Equal(DisconnectApplicationIdle, idle.CloseReason().Reason)
+
+	p.Assert(t, IdleReapedCounter)(xmetricstest.Value(1.0))
+	p.Assert(t, DisconnectCounter, "reason", string(DisconnectApplicationIdle))(xmetricstest.Value(1.0))
+}
+
+func testManagerReapConnected(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		p       = xmetricstest.NewProvider(nil, Metrics)
+
+		connected    = make(chan struct{}, 1)
+		disconnected = make(chan CloseReason, 1)
+
+		options = &Options{
+			Logger:                logging.NewTestLogger(nil, t),
+			MetricsProvider:       p,
+			ApplicationIdlePeriod: 100 * time.Millisecond,
+			ReapInterval:          20 * time.Millisecond,
+			Listeners: []Listener{
+				func(event *Event) {
+					switch event.Type {
+					case Connect:
+						connected <- struct{}{}
+					case Disconnect:
+						disconnected <- event.CloseReason
+					}
+				},
+			},
+		}
+	)
+
+	m, server, connectURL := startWebsocketServer(options)
+	defer server.Close()
+	defer m.(Shutdowner).Shutdown(context.Background())
+
+	c, _, err := DefaultDialer().DialDevice(string(testDeviceIDs[0]), connectURL, nil)
+	require.NoError(err)
+	defer c.Close()
+	<-connected
+
+	select {
+	case reason := <-disconnected:
+		assert.Equal(DisconnectApplicationIdle, reason.Reason)
+	case <-time.After(5 * time.Second):
+		require.Fail("The idle device was not reaped")
+	}
+
+	p.Assert(t, IdleReapedCounter)(xmetricstest.Value(1.0))
+}
+
+func TestManagerReap(t *testing.T) {
+	t.Run("NoIdleDevices", testManagerReapNoIdleDevices)
+	t.Run("IdleDevices", testManagerReapIdleDevices)
+	t.Run("Connected", testManagerReapConnected)
+}
@@ -2,9 +2,14 @@ package device
 
 import (
 	"errors"
+	"hash/fnv"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
 	"github.com/xmidt-org/webpa-common/xmetrics"
 )
 
@@ -14,23 +19,40 @@ type registryOptions struct {
 	Logger          log.Logger
 	Limit           int
 	InitialCapacity int
+	Shards          int
+	DuplicatePolicy DuplicatePolicy
 	Measures        Measures
 }
 
+// registryShard is one stripe of the registry, holding the devices whose IDs hash to it
+type registryShard struct {
+	lock sync.RWMutex
+	data map[ID]*device
+}
+
 // registry is the internal lookup map for devices.  it is bounded by an optional maximum number
 // of connected devices.
+//
+// Devices are partitioned into shards by a hash of their ID, each with its own lock, so that
+// connections and lookups for different devices rarely contend.  Duplicate connection instances,
+// e.g. mac:112233445566#2, are kept in the same shard as the device they duplicate.
 type registry struct {
+	// size is the total number of devices across all shards.  It is accessed atomically,
+	// and is first so that it is 64-bit aligned.
+	size int64
+
 	logger          log.Logger
-	lock            sync.RWMutex
 	limit           int
 	initialCapacity int
-	data            map[ID]*device
-
-	count        xmetrics.Setter
-	limitReached xmetrics.Incrementer
-	connect      xmetrics.Incrementer
-	disconnect   xmetrics.Adder
-	duplicates   xmetrics.Incrementer
+	duplicatePolicy DuplicatePolicy
+	shards          []registryShard
+
+	count              xmetrics.Setter
+	limitReached       xmetrics.Incrementer
+	connect            xmetrics.Incrementer
+	disconnect         metrics.Counter
+	duplicates         xmetrics.Incrementer
+	duplicateDecisions metrics.Counter
 }
 
 func newRegistry(o registryOptions) *registry {
@@ -38,54 +60,125 @@ func newRegistry(o registryOptions) *registry {
 		o.InitialCapacity = 10
 	}
 
-	return &registry{
+	if o.Shards < 1 {
+		o.Shards = 1
+	}
+
+	r := &registry{
 		logger:          o.Logger,
 		initialCapacity: o.InitialCapacity,
-		data:            make(map[ID]*device, o.InitialCapacity),
+		duplicatePolicy: o.DuplicatePolicy.normalize(),
+		shards:          make([]registryShard, o.Shards),
 		limit:           o.Limit,
-		count:           o.Measures.Device,
-		limitReached:    o.Measures.LimitReached,
-		connect:         o.Measures.Connect,
-		disconnect:      o.Measures.Disconnect,
-		duplicates:      o.Measures.Duplicates,
+
+		count:              o.Measures.Device,
+		limitReached:       o.Measures.LimitReached,
+		connect:            o.Measures.Connect,
+		disconnect:         o.Measures.Disconnect,
+		duplicates:         o.Measures.Duplicates,
+		duplicateDecisions: o.Measures.DuplicatePolicy,
+	}
+
+	for i := range r.shards {
+		r.shards[i].data = make(map[ID]*device, r.shardCapacity())
+	}
+
+	return r
+}
+
+// shardCapacity is the initial capacity of each shard's map
+func (r *registry) shardCapacity() int {
+	return r.initialCapacity/len(r.shards) + 1
+}
+
+// shard returns the shard which holds the given device ID.  Any connection instance suffix is
+// ignored, so that all instances of a device share a shard.
+func (r *registry) shard(id ID) *registryShard {
+	if len(r.shards) == 1 {
+		return &r.shards[0]
 	}
+
+	key := string(id)
+	if i := strings.LastIndex(key, InstanceSeparator); i >= 0 {
+		if _, err := strconv.Atoi(key[i+len(InstanceSeparator):]); err == nil {
+			key = key[:i]
+		}
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return &r.shards[h.Sum32()%uint32(len(r.shards))]
+}
+
+// grow adjusts the total device count by delta, updating the device gauge
+func (r *registry) grow(delta int64) {
+	r.count.Set(float64(atomic.AddInt64(&r.size, delta)))
 }
 
 // len returns the size of this registry
 func (r *registry) len() int {
-	r.lock.RLock()
-	l := len(r.data)
-	r.lock.RUnlock()
-
-	return l
+	return int(atomic.LoadInt64(&r.size))
 }
 
 // add uses a factory function to create a new device atomically with modifying
-// the registry
+// the registry.  If a device with the same ID is already present, the registry's
+// DuplicatePolicy determines the outcome.
 func (r *registry) add(newDevice *device) error {
 	id := newDevice.ID()
-	r.lock.Lock()
+	shard := r.shard(id)
+	shard.lock.Lock()
 
-	existing := r.data[id]
-	if existing == nil && r.limit > 0 && (len(r.data)+1) > r.limit {
-		// adding this would result in exceeding the limit
-		r.lock.Unlock()
-		r.limitReached.Inc()
-		r.disconnect.Add(1.0)
-		newDevice.requestClose(CloseReason{Err: errDeviceLimitReached, Text: "device-limit-reached"})
-		return errDeviceLimitReached
+	existing := shard.data[id]
+	if existing != nil && r.duplicatePolicy == DuplicateRejectNew {
+		shard.lock.Unlock()
+		r.duplicates.Inc()
+		r.duplicateDecisions.With("policy", string(DuplicateRejectNew)).Add(1.0)
+		r.disconnect.With("reason", string(DisconnectDuplicateRejected)).Add(1.0)
+		newDevice.requestClose(CloseReason{Err: ErrorDuplicateDevice, Reason: DisconnectDuplicateRejected})
+		return ErrorDuplicateDevice
 	}
 
-	// this will either leave the count the same or add 1 to it ...
-	r.data[id] = newDevice
-	r.count.Set(float64(len(r.data)))
-	r.lock.Unlock()
+	// under DuplicateAllowBoth, the new device is stored under the next free instance ID
+	// and the existing device is left alone
+	var original *device
+	if existing != nil && r.duplicatePolicy == DuplicateAllowBoth {
+		original = existing
+		for instance := 2; existing != nil; instance++ {
+			id = instanceID(newDevice.baseID, instance)
+			existing = shard.data[id]
+		}
+	}
 
-	if existing != nil {
-		r.disconnect.Add(1.0)
+	if existing == nil {
+		// reserve a slot in the registry, backing out if that would exceed the limit
+		if size := atomic.AddInt64(&r.size, 1); r.limit > 0 && size > int64(r.limit) {
+			atomic.AddInt64(&r.size, -1)
+			shard.lock.Unlock()
+			r.limitReached.Inc()
+			r.disconnect.With("reason", string(DisconnectDeviceLimit)).Add(1.0)
+			newDevice.requestClose(CloseReason{Err: errDeviceLimitReached, Reason: DisconnectDeviceLimit})
+			return errDeviceLimitReached
+		}
+	}
+
+	// this will either leave the count the same or add 1 to it ...
+	newDevice.id = id
+	shard.data[id] = newDevice
+	r.count.Set(float64(atomic.LoadInt64(&r.size)))
+	shard.lock.Unlock()
+
+	switch {
+	case existing != nil:
+		r.disconnect.With("reason", string(DisconnectDuplicate)).Add(1.0)
 		r.duplicates.Inc()
+		r.duplicateDecisions.With("policy", string(DuplicateTerminateOld)).Add(1.0)
 		newDevice.Statistics().AddDuplications(existing.Statistics().Duplications() + 1)
-		existing.requestClose(CloseReason{Text: "duplicate"})
+		existing.requestClose(CloseReason{Reason: DisconnectDuplicate})
+
+	case original != nil:
+		r.duplicates.Inc()
+		r.duplicateDecisions.With("policy", string(DuplicateAllowBoth)).Add(1.0)
+		original.Statistics().AddDuplications(1)
 	}
 
 	r.connect.Inc()
@@ -93,17 +186,19 @@ func (r *registry) add(newDevice *device) error {
 }
 
 func (r *registry) remove(id ID, reason CloseReason) (*device, bool) {
-	r.lock.Lock()
-	existing, ok := r.data[id]
+	shard := r.shard(id)
+	shard.lock.Lock()
+	existing, ok := shard.data[id]
 	if ok {
-		delete(r.data, id)
+		delete(shard.data, id)
+		r.grow(-1)
 	}
 
-	r.count.Set(float64(len(r.data)))
-	r.lock.Unlock()
+	shard.lock.Unlock()
 
 	if existing != nil {
-		r.disconnect.Add(1.0)
+		reason = reason.normalize()
+		r.disconnect.With("reason", string(reason.Reason)).Add(1.0)
 		existing.requestClose(reason)
 	}
 
@@ -115,15 +210,18 @@ func (r *registry) removeIf(f func(d *device) (CloseReason, bool)) int {
 	matched := make([]*device, 0, 100)
 	reasons := make([]CloseReason, 0, 100)
 
-	r.lock.RLock()
-	for _, d := range r.data {
-		if reason, ok := f(d); ok {
-			matched = append(matched, d)
-			reasons = append(reasons, reason)
+	for i := range r.shards {
+		shard := &r.shards[i]
+		shard.lock.RLock()
+		for _, d := range shard.data {
+			if reason, ok := f(d); ok {
+				matched = append(matched, d)
+				reasons = append(reasons, reason)
+			}
 		}
-	}
 
-	r.lock.RUnlock()
+		shard.lock.RUnlock()
+	}
 
 	if len(matched) == 0 {
 		return 0
@@ -133,55 +231,70 @@ func (r *registry) removeIf(f func(d *device) (CloseReason, bool)) int {
 	// lock in between
 	count := 0
 	for i, d := range matched {
-		r.lock.Lock()
+		shard := r.shard(d.ID())
+		shard.lock.Lock()
 
 		// allow for barging
-		_, ok := r.data[d.ID()]
+		_, ok := shard.data[d.ID()]
 		if ok {
-			delete(r.data, d.ID())
-			r.count.Set(float64(len(r.data)))
+			delete(shard.data, d.ID())
+			r.grow(-1)
 		}
 
-		r.lock.Unlock()
+		shard.lock.Unlock()
 
 		if ok {
 			count++
-			d.requestClose(reasons[i])
+			reason := reasons[i].normalize()
+			r.disconnect.With("reason", string(reason.Reason)).Add(1.0)
+			d.requestClose(reason)
 		}
 	}
 
-	if count > 0 {
-		r.disconnect.Add(float64(count))
-	}
-
 	return count
 }
 
 func (r *registry) removeAll(reason CloseReason) int {
-	r.lock.Lock()
-	original := r.data
-	r.data = make(map[ID]*device, r.initialCapacity)
-	r.count.Set(0.0)
-	r.lock.Unlock()
-
-	count := len(original)
-	for _, d := range original {
-		d.requestClose(reason)
+	reason = reason.normalize()
+	count := 0
+	for i := range r.shards {
+		shard := &r.shards[i]
+		shard.lock.Lock()
+		original := shard.data
+		shard.data = make(map[ID]*device, r.shardCapacity())
+		r.grow(-int64(len(original)))
+		shard.lock.Unlock()
+
+		count += len(original)
+		for _, d := range original {
+			d.requestClose(reason)
+		}
 	}
 
-	r.disconnect.Add(float64(count))
+	r.disconnect.With("reason", string(reason.Reason)).Add(float64(count))
 	return count
 }
 
+// visit applies a closure to each device until the closure returns false.  All shards are read locked
+// for the duration, so visitors see a consistent view of the registry.
 func (r *registry) visit(f func(d *device) bool) int {
-	defer r.lock.RUnlock()
-	r.lock.RLock()
+	for i := range r.shards {
+		r.shards[i].lock.RLock()
+	}
+
+	defer func() {
+		for i := range r.shards {
+			r.shards[i].lock.RUnlock()
+		}
+	}()
 
 	visited := 0
-	for _, d := range r.data {
-		visited++
-		if !f(d) {
-			break
+	for i := range r.shards {
+		for _, d := range r.shards[i].data {
+			visited++
+			if !f(d) {
+				return visited
+			}
 		}
 	}
 
@@ -189,9 +302,10 @@ func (r *registry) visit(f func(d *device) bool) int {
 }
 
 func (r *registry) get(id ID) (*device, bool) {
-	r.lock.RLock()
-	existing, ok := r.data[id]
-	r.lock.RUnlock()
+	shard := r.shard(id)
+	shard.lock.RLock()
+	existing, ok := shard.data[id]
+	shard.lock.RUnlock()
 
 	return existing, ok
 }
@@ -2,6 +2,7 @@ package device
 
 import (
 	"strconv"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -60,7 +61,7 @@ func testRegistryAdd(t *testing.T) {
 		r.add(duplicate)
 		p.Assert(t, DeviceCounter)(xmetricstest.Value(10.0))
 		p.Assert(t, ConnectCounter)(xmetricstest.Value(11.0))
-		p.Assert(t, DisconnectCounter)(xmetricstest.Value(1.0))
+		p.Assert(t, DisconnectCounter, "reason", string(DisconnectDuplicate))(xmetricstest.Value(1.0))
 		p.Assert(t, DeviceLimitReachedCounter)(xmetricstest.Value(0.0))
 		p.Assert(t, DuplicatesCounter)(xmetricstest.Value(1.0))
 
@@ -112,7 +113,7 @@ func testRegistryAdd(t *testing.T) {
 		assert.True(cantAdd.Closed())
 		p.Assert(t, DeviceCounter)(xmetricstest.Value(1.0))
 		p.Assert(t, ConnectCounter)(xmetricstest.Value(1.0))
-		p.Assert(t, DisconnectCounter)(xmetricstest.Value(1.0))
+		p.Assert(t, DisconnectCounter, "reason", string(DisconnectDeviceLimit))(xmetricstest.Value(1.0))
 		p.Assert(t, DeviceLimitReachedCounter)(xmetricstest.Value(1.0))
 		p.Assert(t, DuplicatesCounter)(xmetricstest.Value(0.0))
 
@@ -126,7 +127,8 @@ func testRegistryAdd(t *testing.T) {
 		assert.False(duplicate.Closed())
 		p.Assert(t, DeviceCounter)(xmetricstest.Value(1.0))
 		p.Assert(t, ConnectCounter)(xmetricstest.Value(2.0))
-		p.Assert(t, DisconnectCounter)(xmetricstest.Value(2.0))
+		p.Assert(t, DisconnectCounter, "reason", string(DisconnectDeviceLimit))(xmetricstest.Value(1.0))
+		p.Assert(t, DisconnectCounter, "reason", string(DisconnectDuplicate))(xmetricstest.Value(1.0))
 		p.Assert(t, DeviceLimitReachedCounter)(xmetricstest.Value(1.0))
 		p.Assert(t, DuplicatesCounter)(xmetricstest.Value(1.0))
 	})
@@ -195,7 +197,7 @@ func testRegistryRemoveAndGet(t *testing.T) {
 	assert.True(initial.Closed())
 	p.Assert(t, DeviceCounter)(xmetricstest.Value(0.0))
 	p.Assert(t, ConnectCounter)(xmetricstest.Value(1.0))
-	p.Assert(t, DisconnectCounter)(xmetricstest.Value(1.0))
+	p.Assert(t, DisconnectCounter, "reason", string(DisconnectUnknown))(xmetricstest.Value(1.0))
 	p.Assert(t, DeviceLimitReachedCounter)(xmetricstest.Value(0.0))
 	p.Assert(t, DuplicatesCounter)(xmetricstest.Value(0.0))
 
@@ -204,7 +206,7 @@ func testRegistryRemoveAndGet(t *testing.T) {
 	assert.False(ok)
 	p.Assert(t, DeviceCounter)(xmetricstest.Value(0.0))
 	p.Assert(t, ConnectCounter)(xmetricstest.Value(1.0))
-	p.Assert(t, DisconnectCounter)(xmetricstest.Value(1.0))
+	p.Assert(t, DisconnectCounter, "reason", string(DisconnectUnknown))(xmetricstest.Value(1.0))
 	p.Assert(t, DeviceLimitReachedCounter)(xmetricstest.Value(0.0))
 	p.Assert(t, DuplicatesCounter)(xmetricstest.Value(0.0))
 }
@@ -261,7 +263,7 @@ func testRegistryRemoveIf(t *testing.T) {
 	assert.True(initial.Closed())
 	p.Assert(t, DeviceCounter)(xmetricstest.Value(0.0))
 	p.Assert(t, ConnectCounter)(xmetricstest.Value(1.0))
-	p.Assert(t, DisconnectCounter)(xmetricstest.Value(1.0))
+	p.Assert(t, DisconnectCounter, "reason", string(DisconnectUnknown))(xmetricstest.Value(1.0))
 	p.Assert(t, DeviceLimitReachedCounter)(xmetricstest.Value(0.0))
 	p.Assert(t, DuplicatesCounter)(xmetricstest.Value(0.0))
 }
@@ -293,7 +295,7 @@ func testRegistryRemoveAll(t *testing.T) {
 	r.removeAll(CloseReason{})
 	p.Assert(t, DeviceCounter)(xmetricstest.Value(0.0))
 	p.Assert(t, ConnectCounter)(xmetricstest.Value(3.0))
-	p.Assert(t, DisconnectCounter)(xmetricstest.Value(3.0))
+	p.Assert(t, DisconnectCounter, "reason", string(DisconnectUnknown))(xmetricstest.Value(3.0))
 	p.Assert(t, DeviceLimitReachedCounter)(xmetricstest.Value(0.0))
 	p.Assert(t, DuplicatesCounter)(xmetricstest.Value(0.0))
 
@@ -350,10 +352,263 @@ func testRegistryVisit(t *testing.T) {
 	p.Assert(t, DuplicatesCounter)(xmetricstest.Value(0.0))
 }
 
+func testRegistryAddDuplicatePolicy(t *testing.T) {
+	t.Run("TerminateOld", func(t *testing.T) {
+		var (
+			assert  = assert.New(t)
+			require = require.New(t)
+			logger  = logging.NewTestLogger(nil, t)
+
+			p = xmetricstest.NewProvider(nil, Metrics)
+			r = newRegistry(registryOptions{
+				Logger:          logger,
+				DuplicatePolicy: "nosuch",
+				Measures:        NewMeasures(p),
+			})
+
+			initial   = newDevice(deviceOptions{ID: ID("mac:112233445566"), Logger: logger})
+			duplicate = newDevice(deviceOptions{ID: ID("mac:112233445566"), Logger: logger})
+		)
+
+		require.NoError(r.add(initial))
+		require.NoError(r.add(duplicate))
+		assert.True(initial.Closed())
+		assert.Equal(CloseReason{Reason: DisconnectDuplicate, Text: "duplicate"}, initial.CloseReason())
+		assert.False(duplicate.Closed())
+		assert.Equal(1, duplicate.Statistics().Duplications())
+		assert.Equal(1, r.len())
+
+		p.Assert(t, DuplicatesCounter)(xmetricstest.Value(1.0))
+		p.Assert(t, DuplicatePolicyCounter, "policy", string(DuplicateTerminateOld))(xmetricstest.Value(1.0))
+	})
+
+	t.Run("RejectNew", func(t *testing.T) {
+		var (
+			assert  = assert.New(t)
+			require = require.New(t)
+			logger  = logging.NewTestLogger(nil, t)
+
+			p = xmetricstest.NewProvider(nil, Metrics)
+			r = newRegistry(registryOptions{
+				Logger:          logger,
+				DuplicatePolicy: DuplicateRejectNew,
+				Measures:        NewMeasures(p),
+			})
+
+			initial   = newDevice(deviceOptions{ID: ID("mac:112233445566"), Logger: logger})
+			duplicate = newDevice(deviceOptions{ID: ID("mac:112233445566"), Logger: logger})
+		)
+
+		require.NoError(r.add(initial))
+		assert.Equal(ErrorDuplicateDevice, r.add(duplicate))
+		assert.False(initial.Closed())
+		assert.True(duplicate.Closed())
+		assert.Equal(ErrorDuplicateDevice, duplicate.CloseReason().Err)
+
+		existing, ok := r.get(ID("mac:112233445566"))
+		assert.True(ok)
+		assert.True(existing == initial)
+		assert.Equal(1, r.len())
+
+		p.Assert(t, ConnectCounter)(xmetricstest.Value(1.0))
+		p.Assert(t, DisconnectCounter, "reason", string(DisconnectDuplicateRejected))(xmetricstest.Value(1.0))
+		p.Assert(t, DuplicatesCounter)(xmetricstest.Value(1.0))
+		p.Assert(t, DuplicatePolicyCounter, "policy", string(DuplicateRejectNew))(xmetricstest.Value(1.0))
+	})
+
+	t.Run("AllowBoth", func(t *testing.T) {
+		var (
+			assert  = assert.New(t)
+			require = require.New(t)
+			logger  = logging.NewTestLogger(nil, t)
+
+			p = xmetricstest.NewProvider(nil, Metrics)
+			r = newRegistry(registryOptions{
+				Logger:          logger,
+				DuplicatePolicy: DuplicateAllowBoth,
+				Measures:        NewMeasures(p),
+			})
+
+			initial = newDevice(deviceOptions{ID: ID("mac:112233445566"), Logger: logger})
+			second  = newDevice(deviceOptions{ID: ID("mac:112233445566"), Logger: logger})
+			third   = newDevice(deviceOptions{ID: ID("mac:112233445566"), Logger: logger})
+		)
+
+		require.NoError(r.add(initial))
+		require.NoError(r.add(second))
+		require.NoError(r.add(third))
+
+		assert.False(initial.Closed())
+		assert.False(second.Closed())
+		assert.False(third.Closed())
+		assert.Equal(ID("mac:112233445566"), initial.ID())
+		assert.Equal(ID("mac:112233445566#2"), second.ID())
+		assert.Equal(ID("mac:112233445566#3"), third.ID())
+		assert.Equal(ID("mac:112233445566"), third.baseID)
+		assert.Equal(2, initial.Statistics().Duplications())
+		assert.Equal(3, r.len())
+
+		existing, ok := r.get(ID("mac:112233445566"))
+		assert.True(ok)
+		assert.True(existing == initial)
+
+		// a freed instance is reused
+		_, ok = r.remove(ID("mac:112233445566#2"), CloseReason{})
+		assert.True(ok)
+
+		fourth := newDevice(deviceOptions{ID: ID("mac:112233445566"), Logger: logger})
+		require.NoError(r.add(fourth))
+		assert.Equal(ID("mac:112233445566#2"), fourth.ID())
+
+		p.Assert(t, ConnectCounter)(xmetricstest.Value(4.0))
+		p.Assert(t, DuplicatesCounter)(xmetricstest.Value(3.0))
+		p.Assert(t, DuplicatePolicyCounter, "policy", string(DuplicateAllowBoth))(xmetricstest.Value(3.0))
+	})
+
+	t.Run("AllowBothLimited", func(t *testing.T) {
+		var (
+			assert  = assert.New(t)
+			require = require.New(t)
+			logger  = logging.NewTestLogger(nil, t)
+
+			p = xmetricstest.NewProvider(nil, Metrics)
+			r = newRegistry(registryOptions{
+				Logger:          logger,
+				Limit:           1,
+				DuplicatePolicy: DuplicateAllowBoth,
+				Measures:        NewMeasures(p),
+			})
+
+			initial   = newDevice(deviceOptions{ID: ID("mac:112233445566"), Logger: logger})
+			duplicate = newDevice(deviceOptions{ID: ID("mac:112233445566"), Logger: logger})
+		)
+
+		require.NoError(r.add(initial))
+		assert.Equal(errDeviceLimitReached, r.add(duplicate))
+		assert.False(initial.Closed())
+		assert.True(duplicate.Closed())
+		p.Assert(t, DeviceLimitReachedCounter)(xmetricstest.Value(1.0))
+	})
+}
+
+func testRegistrySharded(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		logger  = logging.NewTestLogger(nil, t)
+
+		p = xmetricstest.NewProvider(nil, Metrics)
+		r = newRegistry(registryOptions{
+			Logger:          logger,
+			Shards:          8,
+			DuplicatePolicy: DuplicateAllowBoth,
+			Measures:        NewMeasures(p),
+		})
+	)
+
+	for i := 0; i < 100; i++ {
+		require.NoError(r.add(newDevice(deviceOptions{ID: IntToMAC(uint64(i)), Logger: logger})))
+	}
+
+	assert.Equal(100, r.len())
+	p.Assert(t, DeviceCounter)(xmetricstest.Value(100.0))
+
+	nonEmpty := 0
+	for i := range r.shards {
+		if len(r.shards[i].data) > 0 {
+			nonEmpty++
+		}
+	}
+
+	assert.True(nonEmpty > 1, "devices should be spread across shards")
+
+	for i := 0; i < 100; i++ {
+		d, ok := r.get(IntToMAC(uint64(i)))
+		assert.True(ok)
+		assert.Equal(IntToMAC(uint64(i)), d.ID())
+	}
+
+	// duplicate instances are kept in the same shard as the original
+	duplicate := newDevice(deviceOptions{ID: IntToMAC(7), Logger: logger})
+	require.NoError(r.add(duplicate))
+	assert.Equal(instanceID(IntToMAC(7), 2), duplicate.ID())
+	assert.True(r.shard(IntToMAC(7)) == r.shard(duplicate.ID()))
+	assert.Equal(101, r.len())
+
+	assert.Equal(101, r.visit(func(*device) bool { return true }))
+	assert.Equal(1, r.visit(func(*device) bool { return false }))
+
+	removed := r.removeIf(func(d *device) (CloseReason, bool) {
+		return CloseReason{Reason: DisconnectRequested}, d.baseID == IntToMAC(7)
+	})
+
+	assert.Equal(2, removed)
+	assert.Equal(99, r.len())
+	assert.True(duplicate.Closed())
+
+	assert.Equal(99, r.removeAll(CloseReason{Reason: DisconnectServerShutdown}))
+	assert.Zero(r.len())
+	assert.Zero(r.visit(func(*device) bool { return true }))
+	p.Assert(t, DeviceCounter)(xmetricstest.Value(0.0))
+}
+
+func testRegistryShardedLimit(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		logger = logging.NewTestLogger(nil, t)
+
+		r = newRegistry(registryOptions{
+			Logger:   logger,
+			Limit:    10,
+			Shards:   4,
+			Measures: NewMeasures(xmetricstest.NewProvider(nil, Metrics)),
+		})
+
+		added = make(chan error, 50)
+		wg    sync.WaitGroup
+	)
+
+	for i := 0; i < cap(added); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			added <- r.add(newDevice(deviceOptions{ID: IntToMAC(uint64(i)), Logger: logger}))
+		}(i)
+	}
+
+	wg.Wait()
+	close(added)
+
+	successes := 0
+	for err := range added {
+		if err == nil {
+			successes++
+		} else {
+			assert.Equal(errDeviceLimitReached, err)
+		}
+	}
+
+	assert.Equal(10, successes)
+	assert.Equal(10, r.len())
+}
+
+func TestDuplicatePolicyNormalize(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(DuplicateTerminateOld, DuplicatePolicy("").normalize())
+	assert.Equal(DuplicateTerminateOld, DuplicatePolicy("nosuch").normalize())
+	assert.Equal(DuplicateTerminateOld, DuplicateTerminateOld.normalize())
+	assert.Equal(DuplicateRejectNew, DuplicateRejectNew.normalize())
+	assert.Equal(DuplicateAllowBoth, DuplicateAllowBoth.normalize())
+}
+
 func TestRegistry(t *testing.T) {
 	t.Run("Add", testRegistryAdd)
+	t.Run("AddDuplicatePolicy", testRegistryAddDuplicatePolicy)
 	t.Run("RemoveAndGet", testRegistryRemoveAndGet)
 	t.Run("RemoveIf", testRegistryRemoveIf)
 	t.Run("RemoveAll", testRegistryRemoveAll)
 	t.Run("Visit", testRegistryVisit)
+	t.Run("Sharded", testRegistrySharded)
+	t.Run("ShardedLimit", testRegistryShardedLimit)
 }
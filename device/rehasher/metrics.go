@@ -12,6 +12,10 @@ const (
 	RehashTimestamp            = "rehash_timestamp"
 	RehashDurationMilliseconds = "rehash_duration_ms"
 
+	RehashPendingDisconnects     = "rehash_pending_disconnects"
+	RehashDisconnectBatchCounter = "rehash_disconnect_batch_count"
+	RehashPacedDisconnectCounter = "rehash_paced_disconnect_count"
+
 	ReasonLabel = "reason"
 
 	DisconnectAllServiceDiscoveryError       = "sd_error"
@@ -47,5 +51,20 @@ func Metrics() []xmetrics.Metric {
 			Type:       "gauge",
 			LabelNames: []string{service.ServiceLabel},
 		},
+		{
+			Name:       RehashPendingDisconnects,
+			Type:       "gauge",
+			LabelNames: []string{service.ServiceLabel},
+		},
+		{
+			Name:       RehashDisconnectBatchCounter,
+			Type:       "counter",
+			LabelNames: []string{service.ServiceLabel},
+		},
+		{
+			Name:       RehashPacedDisconnectCounter,
+			Type:       "counter",
+			LabelNames: []string{service.ServiceLabel},
+		},
 	}
 }
@@ -1,6 +1,8 @@
 package rehasher
 
 import (
+	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/go-kit/kit/log"
@@ -70,6 +72,24 @@ func WithMetricsProvider(p provider.Provider) Option {
 		r.disconnectAllCounter = p.NewCounter(RehashDisconnectAllCounter)
 		r.timestamp = p.NewGauge(RehashTimestamp)
 		r.duration = p.NewGauge(RehashDurationMilliseconds)
+		r.pendingDisconnects = p.NewGauge(RehashPendingDisconnects)
+		r.disconnectBatches = p.NewCounter(RehashDisconnectBatchCounter)
+		r.pacedDisconnects = p.NewCounter(RehashPacedDisconnectCounter)
+	}
+}
+
+// WithPacing configures a rehasher to spread rehash-triggered disconnects over time rather than
+// disconnecting all affected devices at once, which avoids reconnect storms.  Devices are disconnected
+// in batches of at most batchSize, with interval between batches.  Each batch, including the first,
+// is additionally delayed by a random duration in [0, jitter).
+//
+// If batchSize is nonpositive, pacing is disabled and devices are disconnected immediately.  This is the default.
+// Disconnects due to service discovery errors or the loss of all instances are never paced.
+func WithPacing(batchSize int, interval, jitter time.Duration) Option {
+	return func(r *rehasher) {
+		r.batchSize = batchSize
+		r.interval = interval
+		r.jitter = jitter
 	}
 }
 
@@ -105,6 +125,13 @@ func New(connector device.Connector, services []string, options ...Option) monit
 			disconnectAllCounter: defaultProvider.NewCounter(RehashDisconnectAllCounter),
 			timestamp:            defaultProvider.NewGauge(RehashTimestamp),
 			duration:             defaultProvider.NewGauge(RehashDurationMilliseconds),
+			pendingDisconnects:   defaultProvider.NewGauge(RehashPendingDisconnects),
+			disconnectBatches:    defaultProvider.NewCounter(RehashDisconnectBatchCounter),
+			pacedDisconnects:     defaultProvider.NewCounter(RehashPacedDisconnectCounter),
+
+			after:  time.After,
+			random: rand.Int63n,
+			pacing: make(map[string]chan struct{}),
 		}
 	)
 
@@ -138,6 +165,127 @@ type rehasher struct {
 	disconnectAllCounter metrics.Counter
 	timestamp            metrics.Gauge
 	duration             metrics.Gauge
+	pendingDisconnects   metrics.Gauge
+	disconnectBatches    metrics.Counter
+	pacedDisconnects     metrics.Counter
+
+	batchSize int
+	interval  time.Duration
+	jitter    time.Duration
+	after     func(time.Duration) <-chan time.Time
+	random    func(int64) int64
+
+	pacingLock sync.Mutex
+	pacing     map[string]chan struct{}
+}
+
+// pendingDisconnect is a device selected for disconnection by a paced rehash
+type pendingDisconnect struct {
+	id     device.ID
+	reason device.CloseReason
+}
+
+// delay computes the wait before a batch of paced disconnects
+func (r *rehasher) delay(base time.Duration) time.Duration {
+	if r.jitter > 0 {
+		return base + time.Duration(r.random(int64(r.jitter)))
+	}
+
+	return base
+}
+
+// wait blocks for the given duration, returning false if stop was closed first
+func (r *rehasher) wait(d time.Duration, stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return false
+	default:
+	}
+
+	if d <= 0 {
+		return true
+	}
+
+	select {
+	case <-stop:
+		return false
+	case <-r.after(d):
+		return true
+	}
+}
+
+// startPacing cancels any paced disconnects in progress for the given service and returns the stop
+// channel for a new pacing run.  A nil pending slice simply cancels.
+func (r *rehasher) startPacing(svc string, pending []pendingDisconnect) <-chan struct{} {
+	r.pacingLock.Lock()
+	defer r.pacingLock.Unlock()
+
+	if stop, ok := r.pacing[svc]; ok {
+		close(stop)
+		delete(r.pacing, svc)
+	}
+
+	if len(pending) == 0 {
+		r.pendingDisconnects.With(service.ServiceLabel, svc).Set(0.0)
+		return nil
+	}
+
+	stop := make(chan struct{})
+	r.pacing[svc] = stop
+	r.pendingDisconnects.With(service.ServiceLabel, svc).Set(float64(len(pending)))
+	return stop
+}
+
+// finishPacing removes the bookkeeping for a pacing run, provided that run was not already cancelled
+func (r *rehasher) finishPacing(svc string, stop <-chan struct{}) {
+	r.pacingLock.Lock()
+	defer r.pacingLock.Unlock()
+
+	if current, ok := r.pacing[svc]; ok && current == stop {
+		delete(r.pacing, svc)
+	}
+}
+
+// pace disconnects the pending devices in batches.  This method returns early if stop is closed,
+// which happens when a subsequent service discovery event supersedes this rehash.
+func (r *rehasher) pace(svc string, logger log.Logger, pending []pendingDisconnect, stop <-chan struct{}) {
+	defer r.finishPacing(svc, stop)
+
+	var (
+		pendingGauge = r.pendingDisconnects.With(service.ServiceLabel, svc)
+		batches      = r.disconnectBatches.With(service.ServiceLabel, svc)
+		disconnects  = r.pacedDisconnects.With(service.ServiceLabel, svc)
+		wait         = r.delay(0)
+	)
+
+	for len(pending) > 0 {
+		if !r.wait(wait, stop) {
+			logger.Log(level.Key(), level.InfoValue(), logging.MessageKey(), "paced disconnects cancelled", "remaining", len(pending))
+			return
+		}
+
+		size := r.batchSize
+		if size > len(pending) {
+			size = len(pending)
+		}
+
+		count := 0
+		for _, pd := range pending[:size] {
+			if r.connector.Disconnect(pd.id, pd.reason) {
+				count++
+			}
+		}
+
+		pending = pending[size:]
+		batches.Add(1.0)
+		disconnects.Add(float64(count))
+		pendingGauge.Set(float64(len(pending)))
+		logger.Log(level.Key(), level.DebugValue(), logging.MessageKey(), "disconnected batch", "disconnectCount", count, "remaining", len(pending))
+
+		wait = r.delay(r.interval)
+	}
+
+	logger.Log(level.Key(), level.InfoValue(), logging.MessageKey(), "paced disconnects complete")
 }
 
 func (r *rehasher) rehash(svc string, logger log.Logger, accessor service.Accessor) {
@@ -148,6 +296,18 @@ func (r *rehasher) rehash(svc string, logger log.Logger, accessor service.Access
 
 	var (
 		keepCount = 0
+		paced     = r.batchSize > 0
+		pending   []pendingDisconnect
+
+		// when pacing, devices are collected rather than disconnected immediately
+		disconnect = func(candidate device.ID, reason device.CloseReason) (device.CloseReason, bool) {
+			if paced {
+				pending = append(pending, pendingDisconnect{id: candidate, reason: reason})
+				return device.CloseReason{}, false
+			}
+
+			return reason, true
+		}
 
 		disconnectCount = r.connector.DisconnectIf(func(candidate device.ID) (device.CloseReason, bool) {
 			instance, err := accessor.Get(candidate.Bytes())
@@ -159,7 +319,7 @@ func (r *rehasher) rehash(svc string, logger log.Logger, accessor service.Access
 					"id", candidate,
 				)
 
-				return device.CloseReason{Err: err, Text: RehashError}, true
+				return disconnect(candidate, device.CloseReason{Err: err, Reason: device.DisconnectRehash, Text: RehashError})
 
 			case !r.isRegistered(instance):
 				logger.Log(level.Key(), level.InfoValue(),
@@ -168,7 +328,7 @@ func (r *rehasher) rehash(svc string, logger log.Logger, accessor service.Access
 					"id", candidate,
 				)
 
-				return device.CloseReason{Text: RehashOtherInstance}, true
+				return disconnect(candidate, device.CloseReason{Reason: device.DisconnectRehash, Text: RehashOtherInstance})
 
 			default:
 				logger.Log(level.Key(), level.DebugValue(), logging.MessageKey(), "device hashed to this instance", "id", candidate)
@@ -180,6 +340,13 @@ func (r *rehasher) rehash(svc string, logger log.Logger, accessor service.Access
 		duration = r.now().Sub(start)
 	)
 
+	if paced {
+		disconnectCount = len(pending)
+		if stop := r.startPacing(svc, pending); stop != nil {
+			go r.pace(svc, logger, pending, stop)
+		}
+	}
+
 	r.keep.With(service.ServiceLabel, svc).Set(float64(keepCount))
 	r.disconnect.With(service.ServiceLabel, svc).Set(float64(disconnectCount))
 	r.duration.With(service.ServiceLabel, svc).Set(float64(duration / time.Millisecond))
@@ -202,12 +369,14 @@ func (r *rehasher) MonitorEvent(e monitor.Event) {
 	switch {
 	case e.Err != nil:
 		logger.Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "disconnecting all devices: service discovery error", logging.ErrorKey(), e.Err)
-		r.connector.DisconnectAll(device.CloseReason{Err: e.Err, Text: ServiceDiscoveryError})
+		r.startPacing(e.Service, nil)
+		r.connector.DisconnectAll(device.CloseReason{Err: e.Err, Reason: device.DisconnectRehash, Text: ServiceDiscoveryError})
 		r.disconnectAllCounter.With(service.ServiceLabel, e.Service, ReasonLabel, DisconnectAllServiceDiscoveryError).Add(1.0)
 
 	case e.Stopped:
 		logger.Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "disconnecting all devices: service discovery monitor being stopped")
-		r.connector.DisconnectAll(device.CloseReason{Text: ServiceDiscoveryStopped})
+		r.startPacing(e.Service, nil)
+		r.connector.DisconnectAll(device.CloseReason{Reason: device.DisconnectRehash, Text: ServiceDiscoveryStopped})
 		r.disconnectAllCounter.With(service.ServiceLabel, e.Service, ReasonLabel, DisconnectAllServiceDiscoveryStopped).Add(1.0)
 
 	case e.EventCount == 1:
@@ -218,7 +387,8 @@ func (r *rehasher) MonitorEvent(e monitor.Event) {
 
 	default:
 		logger.Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "disconnecting all devices: service discovery updated with no instances")
-		r.connector.DisconnectAll(device.CloseReason{Text: ServiceDiscoveryNoInstances})
+		r.startPacing(e.Service, nil)
+		r.connector.DisconnectAll(device.CloseReason{Reason: device.DisconnectRehash, Text: ServiceDiscoveryNoInstances})
 		r.disconnectAllCounter.With(service.ServiceLabel, e.Service, ReasonLabel, DisconnectAllServiceDiscoveryNoInstances).Add(1.0)
 	}
 }
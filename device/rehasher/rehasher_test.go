@@ -119,7 +119,7 @@ func testRehasherServiceDiscoveryError(t *testing.T) {
 	)
 
 	require.NotNil(r)
-	connector.On("DisconnectAll", device.CloseReason{Err: serviceDiscoveryError, Text: ServiceDiscoveryError}).Return(12)
+	connector.On("DisconnectAll", device.CloseReason{Err: serviceDiscoveryError, Reason: device.DisconnectRehash, Text: ServiceDiscoveryError}).Return(12)
 	provider.Expect(RehashKeepDevice, service.ServiceLabel, "talaria")(xmetricstest.Gauge, xmetricstest.Value(0.0))
 	provider.Expect(RehashDisconnectDevice, service.ServiceLabel, "talaria")(xmetricstest.Gauge, xmetricstest.Value(0.0))
 	provider.Expect(RehashDisconnectAllCounter, service.ServiceLabel, "talaria", ReasonLabel, DisconnectAllServiceDiscoveryError)(
@@ -157,7 +157,7 @@ func testRehasherServiceDiscoveryStopped(t *testing.T) {
 	)
 
 	require.NotNil(r)
-	connector.On("DisconnectAll", device.CloseReason{Text: ServiceDiscoveryStopped}).Return(0)
+	connector.On("DisconnectAll", device.CloseReason{Reason: device.DisconnectRehash, Text: ServiceDiscoveryStopped}).Return(0)
 	provider.Expect(RehashKeepDevice, service.ServiceLabel, "caduceus")(xmetricstest.Gauge, xmetricstest.Value(0.0))
 	provider.Expect(RehashDisconnectDevice, service.ServiceLabel, "caduceus")(xmetricstest.Gauge, xmetricstest.Value(0.0))
 	provider.Expect(RehashDisconnectAllCounter, service.ServiceLabel, "caduceus", ReasonLabel, DisconnectAllServiceDiscoveryStopped)(
@@ -267,7 +267,7 @@ func testRehasherNoInstances(t *testing.T) {
 	)
 
 	require.NotNil(r)
-	connector.On("DisconnectAll", device.CloseReason{Text: ServiceDiscoveryNoInstances}).Return(0)
+	connector.On("DisconnectAll", device.CloseReason{Reason: device.DisconnectRehash, Text: ServiceDiscoveryNoInstances}).Return(0)
 	provider.Expect(RehashKeepDevice, service.ServiceLabel, "caduceus")(xmetricstest.Gauge, xmetricstest.Value(0.0))
 	provider.Expect(RehashDisconnectDevice, service.ServiceLabel, "caduceus")(xmetricstest.Gauge, xmetricstest.Value(0.0))
 	provider.Expect(RehashDisconnectAllCounter, service.ServiceLabel, "caduceus", ReasonLabel, DisconnectAllServiceDiscoveryNoInstances)(
@@ -360,11 +360,11 @@ func testRehasherRehash(t *testing.T) {
 			assert.False(closed)
 
 			reason, closed = f(rehashedID)
-			assert.Equal(device.CloseReason{Text: RehashOtherInstance}, reason)
+			assert.Equal(device.CloseReason{Reason: device.DisconnectRehash, Text: RehashOtherInstance}, reason)
 			assert.True(closed)
 
 			reason, closed = f(accessorErrorID)
-			assert.Equal(device.CloseReason{Err: accessorError, Text: RehashError}, reason)
+			assert.Equal(device.CloseReason{Err: accessorError, Reason: device.DisconnectRehash, Text: RehashError}, reason)
 			assert.True(closed)
 		}).
 		Return(2)
@@ -387,11 +387,189 @@ func testRehasherRehash(t *testing.T) {
 	provider.AssertExpectations(t)
 }
 
+func testRehasherPacedRehash(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		require  = require.New(t)
+		provider = xmetricstest.NewProvider(nil, Metrics)
+
+		keepNode   = "keep.xfinity.net"
+		rehashNode = "rehash.xfinity.net"
+		candidates = []device.ID{"keep", "rehashed1", "rehashed2", "rehashed3"}
+
+		accessorFactory = service.AccessorFactory(func([]string) service.Accessor {
+			return service.AccessorFunc(func(key []byte) (string, error) {
+				if string(key) == "keep" {
+					return keepNode, nil
+				}
+
+				return rehashNode, nil
+			})
+		})
+
+		waits = make(chan time.Duration, 10)
+		timer = make(chan time.Time)
+		done  = make(chan struct{})
+
+		connector = new(device.MockConnector)
+		r         = New(
+			connector,
+			[]string{"caduceus"},
+			WithLogger(logging.NewTestLogger(nil, t)),
+			WithIsRegistered(func(v string) bool { return keepNode == v }),
+			WithAccessorFactory(accessorFactory),
+			WithMetricsProvider(provider),
+			WithPacing(2, time.Minute, 10*time.Second),
+		)
+	)
+
+	require.NotNil(r)
+	r.(*rehasher).random = func(n int64) int64 {
+		assert.Equal(int64(10*time.Second), n)
+		return int64(time.Second)
+	}
+
+	r.(*rehasher).after = func(d time.Duration) <-chan time.Time {
+		waits <- d
+		return timer
+	}
+
+	connector.On("DisconnectIf", mock.MatchedBy(
+		func(func(device.ID) (device.CloseReason, bool)) bool { return true },
+	)).
+		Run(func(arguments mock.Arguments) {
+			f := arguments.Get(0).(func(device.ID) (device.CloseReason, bool))
+			for _, c := range candidates {
+				_, closed := f(c)
+				assert.False(closed)
+			}
+		}).
+		Return(0).Once()
+
+	connector.On("Disconnect", device.ID("rehashed1"), device.CloseReason{Reason: device.DisconnectRehash, Text: RehashOtherInstance}).Return(true).Once()
+	connector.On("Disconnect", device.ID("rehashed2"), device.CloseReason{Reason: device.DisconnectRehash, Text: RehashOtherInstance}).Return(false).Once()
+	connector.On("Disconnect", device.ID("rehashed3"), device.CloseReason{Reason: device.DisconnectRehash, Text: RehashOtherInstance}).Return(true).Once().
+		Run(func(mock.Arguments) { close(done) })
+
+	r.MonitorEvent(monitor.Event{Key: "test", Service: "caduceus", EventCount: 10, Instances: []string{keepNode, rehashNode}})
+	provider.Assert(t, RehashKeepDevice, service.ServiceLabel, "caduceus")(xmetricstest.Value(1.0))
+	provider.Assert(t, RehashDisconnectDevice, service.ServiceLabel, "caduceus")(xmetricstest.Value(3.0))
+
+	select {
+	case d := <-waits:
+		assert.Equal(time.Second, d)
+	case <-time.After(time.Second):
+		require.Fail("The first batch was not delayed by jitter")
+	}
+
+	timer <- time.Time{}
+
+	select {
+	case d := <-waits:
+		assert.Equal(time.Minute+time.Second, d)
+	case <-time.After(time.Second):
+		require.Fail("The second batch was not delayed by the interval plus jitter")
+	}
+
+	provider.Assert(t, RehashPendingDisconnects, service.ServiceLabel, "caduceus")(xmetricstest.Value(1.0))
+	timer <- time.Time{}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.Fail("The final batch was not disconnected")
+	}
+
+	// wait for the pacing goroutine to finish its bookkeeping
+	require.Eventually(func() bool {
+		r.(*rehasher).pacingLock.Lock()
+		defer r.(*rehasher).pacingLock.Unlock()
+		return len(r.(*rehasher).pacing) == 0
+	}, time.Second, time.Millisecond)
+
+	connector.AssertExpectations(t)
+	provider.Assert(t, RehashPendingDisconnects, service.ServiceLabel, "caduceus")(xmetricstest.Value(0.0))
+	provider.Assert(t, RehashDisconnectBatchCounter, service.ServiceLabel, "caduceus")(xmetricstest.Value(2.0))
+	provider.Assert(t, RehashPacedDisconnectCounter, service.ServiceLabel, "caduceus")(xmetricstest.Value(2.0))
+}
+
+func testRehasherPacedCancel(t *testing.T) {
+	var (
+		require  = require.New(t)
+		provider = xmetricstest.NewProvider(nil, Metrics)
+
+		accessorFactory = service.AccessorFactory(func([]string) service.Accessor {
+			return service.AccessorFunc(func([]byte) (string, error) {
+				return "other.xfinity.net", nil
+			})
+		})
+
+		waiting = make(chan struct{})
+
+		connector = new(device.MockConnector)
+		r         = New(
+			connector,
+			[]string{"caduceus"},
+			WithLogger(logging.NewTestLogger(nil, t)),
+			WithIsRegistered(func(string) bool { return false }),
+			WithAccessorFactory(accessorFactory),
+			WithMetricsProvider(provider),
+			WithPacing(1, time.Minute, 0),
+		)
+	)
+
+	require.NotNil(r)
+	r.(*rehasher).after = func(time.Duration) <-chan time.Time {
+		close(waiting)
+		return make(chan time.Time)
+	}
+
+	connector.On("DisconnectIf", mock.MatchedBy(
+		func(func(device.ID) (device.CloseReason, bool)) bool { return true },
+	)).
+		Run(func(arguments mock.Arguments) {
+			f := arguments.Get(0).(func(device.ID) (device.CloseReason, bool))
+			f(device.ID("first"))
+			f(device.ID("second"))
+		}).
+		Return(0).Once()
+
+	disconnected := make(chan struct{})
+	connector.On("Disconnect", device.ID("first"), device.CloseReason{Reason: device.DisconnectRehash, Text: RehashOtherInstance}).Return(true).Once().
+		Run(func(mock.Arguments) { close(disconnected) })
+
+	connector.On("DisconnectAll", device.CloseReason{Reason: device.DisconnectRehash, Text: ServiceDiscoveryStopped}).Return(0).Once()
+
+	r.MonitorEvent(monitor.Event{Key: "test", Service: "caduceus", EventCount: 10, Instances: []string{"other.xfinity.net"}})
+
+	select {
+	case <-waiting:
+	case <-time.After(time.Second):
+		require.Fail("The second batch did not wait")
+	}
+
+	<-disconnected
+	r.MonitorEvent(monitor.Event{Key: "test", Service: "caduceus", EventCount: 11, Stopped: true})
+
+	require.Eventually(func() bool {
+		r.(*rehasher).pacingLock.Lock()
+		defer r.(*rehasher).pacingLock.Unlock()
+		return len(r.(*rehasher).pacing) == 0
+	}, time.Second, time.Millisecond)
+
+	connector.AssertExpectations(t)
+	connector.AssertNotCalled(t, "Disconnect", device.ID("second"), mock.Anything)
+	provider.Assert(t, RehashPendingDisconnects, service.ServiceLabel, "caduceus")(xmetricstest.Value(0.0))
+	provider.Assert(t, RehashPacedDisconnectCounter, service.ServiceLabel, "caduceus")(xmetricstest.Value(1.0))
+}
+
 func TestRehasher(t *testing.T) {
 	t.Run("ServiceDiscoveryError", testRehasherServiceDiscoveryError)
 	t.Run("ServiceDiscoveryStopped", testRehasherServiceDiscoveryStopped)
 	t.Run("InitialEvent", testRehasherInitialEvent)
 	t.Run("NoInstances", testRehasherNoInstances)
 	t.Run("Rehash", testRehasherRehash)
+	t.Run("PacedRehash", testRehasherPacedRehash)
+	t.Run("PacedCancel", testRehasherPacedCancel)
 	t.Run("SkippedServicee", testRehasherSkippedService)
 }
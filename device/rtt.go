@@ -0,0 +1,54 @@
+package device
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// roundTripTimer measures the latency between a ping written to a device and the pong that answers it.
+// Only the most recent ping is tracked, which is sufficient since pings are sent periodically and
+// pongs are answered in order.
+type roundTripTimer struct {
+	now func() time.Time
+
+	// sent is the UnixNano time of the outstanding ping, or zero if there is no outstanding ping
+	sent int64
+}
+
+func newRoundTripTimer(now func() time.Time) *roundTripTimer {
+	if now == nil {
+		now = time.Now
+	}
+
+	return &roundTripTimer{now: now}
+}
+
+// pinger decorates a ping closure, as returned by NewPinger, so that the time of each successful
+// ping is recorded.
+func (rt *roundTripTimer) pinger(ping func() error) func() error {
+	return func() error {
+		sent := rt.now().UnixNano()
+		if err := ping(); err != nil {
+			return err
+		}
+
+		atomic.StoreInt64(&rt.sent, sent)
+		return nil
+	}
+}
+
+// pong computes the round trip time of the outstanding ping.  If there is no outstanding ping,
+// e.g. for an unsolicited pong, this method returns false.
+func (rt *roundTripTimer) pong() (time.Duration, bool) {
+	sent := atomic.SwapInt64(&rt.sent, 0)
+	if sent == 0 {
+		return 0, false
+	}
+
+	return time.Duration(rt.now().UnixNano() - sent), true
+}
+
+// outstanding tests if a ping has been sent which has not yet been answered by a pong
+func (rt *roundTripTimer) outstanding() bool {
+	return atomic.LoadInt64(&rt.sent) != 0
+}
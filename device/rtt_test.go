@@ -0,0 +1,58 @@
+package device
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoundTripTimer(t *testing.T) {
+	t.Run("DefaultNow", func(t *testing.T) {
+		assert := assert.New(t)
+		rt := newRoundTripTimer(nil)
+		assert.NotNil(rt.now)
+
+		_, ok := rt.pong()
+		assert.False(ok)
+	})
+
+	t.Run("PingPong", func(t *testing.T) {
+		var (
+			assert  = assert.New(t)
+			current = time.Now()
+			rt      = newRoundTripTimer(func() time.Time { return current })
+			pings   = 0
+			pinger  = rt.pinger(func() error { pings++; return nil })
+		)
+
+		_, ok := rt.pong()
+		assert.False(ok)
+
+		assert.NoError(pinger())
+		assert.Equal(1, pings)
+		current = current.Add(150 * time.Millisecond)
+
+		elapsed, ok := rt.pong()
+		assert.True(ok)
+		assert.Equal(150*time.Millisecond, elapsed)
+
+		// a second pong for the same ping is unsolicited
+		_, ok = rt.pong()
+		assert.False(ok)
+	})
+
+	t.Run("PingError", func(t *testing.T) {
+		var (
+			assert        = assert.New(t)
+			expectedError = errors.New("expected")
+			rt            = newRoundTripTimer(nil)
+			pinger        = rt.pinger(func() error { return expectedError })
+		)
+
+		assert.Equal(expectedError, pinger())
+		_, ok := rt.pong()
+		assert.False(ok)
+	})
+}
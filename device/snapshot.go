@@ -0,0 +1,202 @@
+package device
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/xmidt-org/webpa-common/convey"
+)
+
+// ErrorUnsupportedSnapshotFormat indicates that a SnapshotFormat other than SnapshotJSON or SnapshotGob was used
+var ErrorUnsupportedSnapshotFormat = errors.New("Unsupported snapshot format")
+
+func init() {
+	// claims and convey values are arbitrary JSON, which gob must know about in order to encode
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+	gob.Register(convey.C{})
+}
+
+// SnapshotFormat is the encoding used for a registry Snapshot
+type SnapshotFormat string
+
+const (
+	SnapshotJSON SnapshotFormat = "json"
+	SnapshotGob  SnapshotFormat = "gob"
+)
+
+// StatisticsSnapshot is a point-in-time copy of a device's Statistics
+type StatisticsSnapshot struct {
+	BytesReceived       int           `json:"bytesReceived"`
+	MessagesReceived    int           `json:"messagesReceived"`
+	BytesSent           int           `json:"bytesSent"`
+	MessagesSent        int           `json:"messagesSent"`
+	Duplications        int           `json:"duplications"`
+	Compressed          bool          `json:"compressed"`
+	CompressedBytesSent int           `json:"compressedBytesSent"`
+	RoundTripTime       time.Duration `json:"roundTripTime"`
+	MissedPongs         int           `json:"missedPongs"`
+	ConnectedAt         time.Time     `json:"connectedAt"`
+}
+
+// DeviceSnapshot is a point-in-time copy of a single connected device
+type DeviceSnapshot struct {
+	ID               ID                     `json:"id"`
+	Pending          int                    `json:"pending"`
+	SessionID        string                 `json:"sessionID,omitempty"`
+	Subprotocol      string                 `json:"subprotocol,omitempty"`
+	Claims           map[string]interface{} `json:"claims,omitempty"`
+	Convey           convey.C               `json:"convey,omitempty"`
+	ConveyCompliance convey.Compliance      `json:"conveyCompliance"`
+	Statistics       StatisticsSnapshot     `json:"statistics"`
+}
+
+// Snapshot is a point-in-time copy of the devices in a Registry, suitable for offline analysis
+type Snapshot struct {
+	// Taken is the time at which this snapshot was created
+	Taken time.Time `json:"taken"`
+
+	// Devices holds a copy of each device that was connected when this snapshot was taken
+	Devices []DeviceSnapshot `json:"devices"`
+}
+
+// NewSnapshot copies the devices in the given Registry.  All devices are visited under a single
+// call to VisitAll, so for a Manager the snapshot is consistent with respect to connections and disconnections.
+func NewSnapshot(r Registry) *Snapshot {
+	s := &Snapshot{
+		Taken: time.Now().UTC(),
+	}
+
+	r.VisitAll(func(d Interface) bool {
+		var (
+			metadata   = d.Metadata()
+			statistics = d.Statistics()
+			ds         = DeviceSnapshot{
+				ID:               d.ID(),
+				Pending:          d.Pending(),
+				ConveyCompliance: d.ConveyCompliance(),
+				Statistics: StatisticsSnapshot{
+					BytesReceived:       statistics.BytesReceived(),
+					MessagesReceived:    statistics.MessagesReceived(),
+					BytesSent:           statistics.BytesSent(),
+					MessagesSent:        statistics.MessagesSent(),
+					Duplications:        statistics.Duplications(),
+					Compressed:          statistics.Compressed(),
+					CompressedBytesSent: statistics.CompressedBytesSent(),
+					RoundTripTime:       statistics.RoundTripTime(),
+					MissedPongs:         statistics.MissedPongs(),
+					ConnectedAt:         statistics.ConnectedAt(),
+				},
+			}
+		)
+
+		if metadata != nil {
+			ds.SessionID = metadata.SessionID()
+			ds.Claims = metadata.ClaimsCopy()
+		}
+
+		if c, ok := d.Convey().(convey.C); ok {
+			ds.Convey = c
+		}
+
+		if sp, ok := d.(SubprotocolProvider); ok {
+			ds.Subprotocol = sp.Subprotocol()
+		}
+
+		s.Devices = append(s.Devices, ds)
+		return true
+	})
+
+	return s
+}
+
+// Encode writes this snapshot in the given format
+func (s *Snapshot) Encode(w io.Writer, f SnapshotFormat) error {
+	switch f {
+	case SnapshotJSON:
+		return json.NewEncoder(w).Encode(s)
+
+	case SnapshotGob:
+		return gob.NewEncoder(w).Encode(s)
+
+	default:
+		return ErrorUnsupportedSnapshotFormat
+	}
+}
+
+// DecodeSnapshot reads a snapshot in the given format
+func DecodeSnapshot(r io.Reader, f SnapshotFormat) (*Snapshot, error) {
+	s := new(Snapshot)
+
+	var err error
+	switch f {
+	case SnapshotJSON:
+		err = json.NewDecoder(r).Decode(s)
+
+	case SnapshotGob:
+		err = gob.NewDecoder(r).Decode(s)
+
+	default:
+		err = ErrorUnsupportedSnapshotFormat
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// LoadSnapshot creates a Manager whose registry holds the devices in the given snapshot.  The returned
+// Manager supports querying, listing, and disconnecting devices, which allows handlers, drain filters,
+// and the like to be exercised offline against production data.
+//
+// Devices loaded from a snapshot have no websocket connection.  Messages routed to them are queued but
+// never delivered, and their statistics, including up time, remain as they were when the snapshot was taken.
+func LoadSnapshot(s *Snapshot, o *Options) (Manager, error) {
+	m := NewManager(o).(*manager)
+	for _, ds := range s.Devices {
+		metadata := new(Metadata)
+		metadata.SetClaims(ds.Claims)
+		if len(ds.SessionID) > 0 {
+			metadata.SetSessionID(ds.SessionID)
+		}
+
+		d := newDevice(deviceOptions{
+			ID:          ds.ID,
+			C:           ds.Convey,
+			Compliance:  ds.ConveyCompliance,
+			QueueSize:   m.deviceMessageQueueSize,
+			QOSTiers:    m.qosTiers,
+			ConnectedAt: ds.Statistics.ConnectedAt,
+			Metadata:    metadata,
+			Logger:      m.logger,
+		})
+
+		d.subprotocol = ds.Subprotocol
+		d.statistics = ds.Statistics.restore(s.Taken)
+		if err := m.devices.add(d); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// restore creates a Statistics with the values from this snapshot, frozen at the given time
+func (ss StatisticsSnapshot) restore(taken time.Time) Statistics {
+	s := NewStatistics(func() time.Time { return taken }, ss.ConnectedAt)
+	s.AddBytesReceived(ss.BytesReceived)
+	s.AddMessagesReceived(ss.MessagesReceived)
+	s.AddBytesSent(ss.BytesSent)
+	s.AddMessagesSent(ss.MessagesSent)
+	s.AddDuplications(ss.Duplications)
+	s.SetCompressed(ss.Compressed)
+	s.AddCompressedBytesSent(ss.CompressedBytesSent)
+	s.SetRoundTripTime(ss.RoundTripTime)
+	s.SetMissedPongs(ss.MissedPongs)
+	return s
+}
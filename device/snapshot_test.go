@@ -0,0 +1,168 @@
+package device
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/convey"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+func newSnapshotTestManager(t *testing.T) Manager {
+	var (
+		m           = NewManager(&Options{Logger: logging.NewTestLogger(nil, t)}).(*manager)
+		connectedAt = time.Date(2020, time.March, 4, 10, 30, 0, 0, time.UTC)
+	)
+
+	for i, id := range []ID{"mac:112233445566", "mac:aabbccddeeff"} {
+		metadata := new(Metadata)
+		metadata.SetSessionID("session-" + string(id))
+		metadata.SetClaims(map[string]interface{}{
+			PartnerIDClaimKey: "comcast",
+			TrustClaimKey:     1000,
+			"capabilities":    []interface{}{"x1", "xb6"},
+		})
+
+		d := newDevice(deviceOptions{
+			ID:          id,
+			C:           convey.C{"fw-name": "fw-1", "hw-model": "xb6"},
+			ConnectedAt: connectedAt.Add(time.Duration(i) * time.Minute),
+			Metadata:    metadata,
+			Logger:      m.logger,
+		})
+
+		d.statistics.AddBytesReceived(100 * (i + 1))
+		d.statistics.AddMessagesReceived(i + 1)
+		d.statistics.AddBytesSent(200 * (i + 1))
+		d.statistics.AddMessagesSent(2 * (i + 1))
+		d.statistics.SetRoundTripTime(time.Duration(i+1) * time.Millisecond)
+		d.statistics.SetMissedPongs(i)
+		d.subprotocol = MsgpackSubprotocol
+		require.NoError(t, m.devices.add(d))
+	}
+
+	return m
+}
+
+func testSnapshotRoundTrip(t *testing.T, format SnapshotFormat) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		original = newSnapshotTestManager(t)
+		snapshot = NewSnapshot(original)
+		buffer   bytes.Buffer
+	)
+
+	require.Len(snapshot.Devices, 2)
+	require.NoError(snapshot.Encode(&buffer, format))
+
+	decoded, err := DecodeSnapshot(&buffer, format)
+	require.NoError(err)
+	require.NotNil(decoded)
+	assert.True(snapshot.Taken.Equal(decoded.Taken))
+
+	loaded, err := LoadSnapshot(decoded, &Options{Logger: logging.NewTestLogger(nil, t)})
+	require.NoError(err)
+	require.NotNil(loaded)
+	assert.Equal(original.Len(), loaded.Len())
+
+	original.VisitAll(func(expected Interface) bool {
+		actual, ok := loaded.Get(expected.ID())
+		require.True(ok)
+
+		assert.Equal(expected.Metadata().SessionID(), actual.Metadata().SessionID())
+		assert.Equal(expected.Metadata().PartnerIDClaim(), actual.Metadata().PartnerIDClaim())
+		assert.Equal(expected.Metadata().TrustClaim(), actual.Metadata().TrustClaim())
+		assert.Equal(expected.ConveyCompliance(), actual.ConveyCompliance())
+		assert.Equal(expected.(SubprotocolProvider).Subprotocol(), actual.(SubprotocolProvider).Subprotocol())
+
+		firmware, _ := actual.Convey().GetString("fw-name")
+		assert.Equal("fw-1", firmware)
+
+		var (
+			es = expected.Statistics()
+			as = actual.Statistics()
+		)
+
+		assert.Equal(es.BytesReceived(), as.BytesReceived())
+		assert.Equal(es.MessagesReceived(), as.MessagesReceived())
+		assert.Equal(es.BytesSent(), as.BytesSent())
+		assert.Equal(es.MessagesSent(), as.MessagesSent())
+		assert.Equal(es.RoundTripTime(), as.RoundTripTime())
+		assert.Equal(es.MissedPongs(), as.MissedPongs())
+		assert.True(es.ConnectedAt().Equal(as.ConnectedAt()))
+		assert.Equal(snapshot.Taken.Sub(es.ConnectedAt()), as.UpTime())
+		return true
+	})
+
+	// devices loaded from a snapshot can be disconnected, e.g. to try out a drain filter
+	assert.True(loaded.Disconnect("mac:112233445566", CloseReason{Reason: DisconnectDrain}))
+	assert.Equal(1, loaded.Len())
+}
+
+func testSnapshotUnsupportedFormat(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		buffer bytes.Buffer
+	)
+
+	assert.Equal(ErrorUnsupportedSnapshotFormat, new(Snapshot).Encode(&buffer, "xml"))
+
+	s, err := DecodeSnapshot(&buffer, "xml")
+	assert.Nil(s)
+	assert.Equal(ErrorUnsupportedSnapshotFormat, err)
+}
+
+func TestSnapshot(t *testing.T) {
+	t.Run("JSON", func(t *testing.T) { testSnapshotRoundTrip(t, SnapshotJSON) })
+	t.Run("Gob", func(t *testing.T) { testSnapshotRoundTrip(t, SnapshotGob) })
+	t.Run("UnsupportedFormat", testSnapshotUnsupportedFormat)
+}
+
+func TestSnapshotHandler(t *testing.T) {
+	testData := []struct {
+		uri                 string
+		expectedStatus      int
+		expectedContentType string
+		format              SnapshotFormat
+	}{
+		{"/", http.StatusOK, "application/json", SnapshotJSON},
+		{"/?format=json", http.StatusOK, "application/json", SnapshotJSON},
+		{"/?format=gob", http.StatusOK, "application/octet-stream", SnapshotGob},
+		{"/?format=xml", http.StatusBadRequest, "", ""},
+	}
+
+	for _, record := range testData {
+		t.Run(record.uri, func(t *testing.T) {
+			var (
+				assert  = assert.New(t)
+				require = require.New(t)
+
+				handler = SnapshotHandler{
+					Logger:   logging.NewTestLogger(nil, t),
+					Registry: newSnapshotTestManager(t),
+				}
+
+				response = httptest.NewRecorder()
+				request  = httptest.NewRequest("GET", record.uri, nil)
+			)
+
+			handler.ServeHTTP(response, request)
+			assert.Equal(record.expectedStatus, response.Code)
+			if record.expectedStatus != http.StatusOK {
+				return
+			}
+
+			assert.Equal(record.expectedContentType, response.HeaderMap.Get("Content-Type"))
+			snapshot, err := DecodeSnapshot(response.Body, record.format)
+			require.NoError(err)
+			assert.Len(snapshot.Devices, 2)
+		})
+	}
+}
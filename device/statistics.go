@@ -43,6 +43,37 @@ type Statistics interface {
 	// AddDuplications increments the count of duplications
 	AddDuplications(int)
 
+	// Compressed tests if the permessage-deflate extension was negotiated for this connection
+	Compressed() bool
+
+	// SetCompressed records whether the permessage-deflate extension was negotiated
+	SetCompressed(bool)
+
+	// CompressedBytesSent returns the total bytes written to the network for a compressed connection, i.e. after
+	// compression and including websocket framing.  This is only tracked for connections which negotiated
+	// permessage-deflate, and is zero otherwise.
+	CompressedBytesSent() int
+
+	// AddCompressedBytesSent increments the CompressedBytesSent count
+	AddCompressedBytesSent(int)
+
+	// CompressionRatio returns CompressedBytesSent divided by BytesSent, so values below 1.0 indicate savings.
+	// Zero is returned if the connection is not compressed or nothing has been sent.
+	CompressionRatio() float64
+
+	// RoundTripTime returns the most recently measured ping/pong latency.  If no pong has been
+	// received, this method returns zero.
+	RoundTripTime() time.Duration
+
+	// SetRoundTripTime records a ping/pong latency measurement
+	SetRoundTripTime(time.Duration)
+
+	// MissedPongs returns the number of consecutive pings the device has not answered
+	MissedPongs() int
+
+	// SetMissedPongs records the number of consecutive pings the device has not answered
+	SetMissedPongs(int)
+
 	// ConnectedAt returns the connection time at which this statistics began tracking
 	ConnectedAt() time.Time
 
@@ -74,6 +105,10 @@ type statistics struct {
 	messagesReceived int
 	messagesSent     int
 	duplications     int
+	compressed       bool
+	compressedSent   int
+	roundTripTime    time.Duration
+	missedPongs      int
 
 	now                  func() time.Time
 	connectedAt          time.Time
@@ -150,6 +185,79 @@ func (s *statistics) AddDuplications(delta int) {
 	s.lock.Unlock()
 }
 
+func (s *statistics) Compressed() bool {
+	s.lock.RLock()
+	var result = s.compressed
+	s.lock.RUnlock()
+
+	return result
+}
+
+func (s *statistics) SetCompressed(compressed bool) {
+	s.lock.Lock()
+	s.compressed = compressed
+	s.lock.Unlock()
+}
+
+func (s *statistics) CompressedBytesSent() int {
+	s.lock.RLock()
+	var result = s.compressedSent
+	s.lock.RUnlock()
+
+	return result
+}
+
+func (s *statistics) AddCompressedBytesSent(delta int) {
+	s.lock.Lock()
+	s.compressedSent += delta
+	s.lock.Unlock()
+}
+
+// compressionRatio computes the CompressionRatio.  The lock must be held.
+func (s *statistics) compressionRatio() float64 {
+	if !s.compressed || s.bytesSent == 0 {
+		return 0.0
+	}
+
+	return float64(s.compressedSent) / float64(s.bytesSent)
+}
+
+func (s *statistics) CompressionRatio() float64 {
+	s.lock.RLock()
+	var result = s.compressionRatio()
+	s.lock.RUnlock()
+
+	return result
+}
+
+func (s *statistics) RoundTripTime() time.Duration {
+	s.lock.RLock()
+	var result = s.roundTripTime
+	s.lock.RUnlock()
+
+	return result
+}
+
+func (s *statistics) SetRoundTripTime(roundTripTime time.Duration) {
+	s.lock.Lock()
+	s.roundTripTime = roundTripTime
+	s.lock.Unlock()
+}
+
+func (s *statistics) MissedPongs() int {
+	s.lock.RLock()
+	var result = s.missedPongs
+	s.lock.RUnlock()
+
+	return result
+}
+
+func (s *statistics) SetMissedPongs(missedPongs int) {
+	s.lock.Lock()
+	s.missedPongs = missedPongs
+	s.lock.Unlock()
+}
+
 func (s *statistics) ConnectedAt() time.Time {
 	return s.connectedAt
 }
@@ -169,12 +277,17 @@ func (s *statistics) String() string {
 func (s *statistics) MarshalJSON() ([]byte, error) {
 	s.lock.RLock()
 	output := []byte(fmt.Sprintf(
-		`{"bytesSent": %d, "messagesSent": %d, "bytesReceived": %d, "messagesReceived": %d, "duplications": %d, "connectedAt": "%s", "upTime": "%s"}`,
+		`{"bytesSent": %d, "messagesSent": %d, "bytesReceived": %d, "messagesReceived": %d, "duplications": %d, "compressed": %t, "compressedBytesSent": %d, "compressionRatio": %.3f, "roundTripTime": "%s", "missedPongs": %d, "connectedAt": "%s", "upTime": "%s"}`,
 		s.bytesSent,
 		s.messagesSent,
 		s.bytesReceived,
 		s.messagesReceived,
 		s.duplications,
+		s.compressed,
+		s.compressedSent,
+		s.compressionRatio(),
+		s.roundTripTime,
+		s.missedPongs,
 		s.formattedConnectedAt,
 		s.UpTime(),
 	))
@@ -28,6 +28,11 @@ func testStatisticsInitialStateDefaultNow(t *testing.T) {
 	assert.Zero(statistics.MessagesSent())
 	assert.Zero(statistics.MessagesReceived())
 	assert.Zero(statistics.Duplications())
+	assert.False(statistics.Compressed())
+	assert.Zero(statistics.CompressedBytesSent())
+	assert.Zero(statistics.CompressionRatio())
+	assert.Zero(statistics.RoundTripTime())
+	assert.Zero(statistics.MissedPongs())
 	assert.Equal(expectedConnectedAt.UTC(), statistics.ConnectedAt())
 
 	data, err := statistics.MarshalJSON()
@@ -41,6 +46,11 @@ func testStatisticsInitialStateDefaultNow(t *testing.T) {
 	assert.Equal(float64(0), actualJSON["bytesReceived"])
 	assert.Equal(float64(0), actualJSON["messagesReceived"])
 	assert.Equal(float64(0), actualJSON["duplications"])
+	assert.Equal(false, actualJSON["compressed"])
+	assert.Equal(float64(0), actualJSON["compressedBytesSent"])
+	assert.Equal(float64(0), actualJSON["compressionRatio"])
+	assert.Equal("0s", actualJSON["roundTripTime"])
+	assert.Equal(float64(0), actualJSON["missedPongs"])
 
 	actualConnectedAt, err := time.Parse(time.RFC3339Nano, actualJSON["connectedAt"].(string))
 	require.NoError(err)
@@ -85,7 +95,7 @@ func testStatisticsInitialStateCustomNow(t *testing.T) {
 
 	assert.JSONEq(
 		fmt.Sprintf(
-			`{"duplications": 0, "bytesSent": 0, "messagesSent": 0, "bytesReceived": 0, "messagesReceived": 0, "connectedAt": "%s", "upTime": "%s"}`,
+			`{"duplications": 0, "compressed": false, "compressedBytesSent": 0, "compressionRatio": 0, "roundTripTime": "0s", "missedPongs": 0, "bytesSent": 0, "messagesSent": 0, "bytesReceived": 0, "messagesReceived": 0, "connectedAt": "%s", "upTime": "%s"}`,
 			expectedConnectedAt.UTC().Format(time.RFC3339Nano),
 			expectedUpTime,
 		),
@@ -126,17 +136,26 @@ func testStatisticsConcurrency(t *testing.T) {
 			statistics.AddBytesReceived(v)
 			statistics.AddMessagesReceived(v)
 			statistics.AddDuplications(v)
+			statistics.AddCompressedBytesSent(v)
 		}(v)
 	}
 
 	gate.Done()
 	done.Wait()
+	statistics.SetCompressed(true)
+	statistics.SetRoundTripTime(250 * time.Millisecond)
+	statistics.SetMissedPongs(2)
 
 	assert.Equal(expectedValue, statistics.BytesSent())
 	assert.Equal(expectedValue, statistics.MessagesSent())
 	assert.Equal(expectedValue, statistics.BytesReceived())
 	assert.Equal(expectedValue, statistics.MessagesReceived())
 	assert.Equal(expectedValue, statistics.Duplications())
+	assert.True(statistics.Compressed())
+	assert.Equal(expectedValue, statistics.CompressedBytesSent())
+	assert.Equal(1.0, statistics.CompressionRatio())
+	assert.Equal(250*time.Millisecond, statistics.RoundTripTime())
+	assert.Equal(2, statistics.MissedPongs())
 	assert.Equal(expectedConnectedAt.UTC(), statistics.ConnectedAt())
 	assert.Equal(expectedUpTime, statistics.UpTime())
 
@@ -146,7 +165,8 @@ func testStatisticsConcurrency(t *testing.T) {
 
 	assert.JSONEq(
 		fmt.Sprintf(
-			`{"duplications": %d, "bytesSent": %d, "messagesSent": %d, "bytesReceived": %d, "messagesReceived": %d, "connectedAt": "%s", "upTime": "%s"}`,
+			`{"duplications": %d, "compressed": true, "compressedBytesSent": %d, "compressionRatio": 1, "roundTripTime": "250ms", "missedPongs": 2, "bytesSent": %d, "messagesSent": %d, "bytesReceived": %d, "messagesReceived": %d, "connectedAt": "%s", "upTime": "%s"}`,
+			expectedValue,
 			expectedValue,
 			expectedValue,
 			expectedValue,
@@ -159,6 +179,25 @@ func testStatisticsConcurrency(t *testing.T) {
 	)
 }
 
+func testStatisticsCompressionRatio(t *testing.T) {
+	var (
+		assert     = assert.New(t)
+		statistics = NewStatistics(nil, time.Now())
+	)
+
+	statistics.AddBytesSent(1000)
+	statistics.AddCompressedBytesSent(250)
+	assert.Zero(statistics.CompressionRatio())
+
+	statistics.SetCompressed(true)
+	assert.Equal(0.25, statistics.CompressionRatio())
+
+	var actualJSON map[string]interface{}
+	assert.NoError(json.Unmarshal([]byte(statistics.String()), &actualJSON))
+	assert.Equal(float64(250), actualJSON["compressedBytesSent"])
+	assert.Equal(0.25, actualJSON["compressionRatio"])
+}
+
 func TestStatistics(t *testing.T) {
 	t.Run("InitialState", func(t *testing.T) {
 		t.Run("DefaultNow", testStatisticsInitialStateDefaultNow)
@@ -166,4 +205,5 @@ func TestStatistics(t *testing.T) {
 	})
 
 	t.Run("Concurrency", testStatisticsConcurrency)
+	t.Run("CompressionRatio", testStatisticsCompressionRatio)
 }
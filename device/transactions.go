@@ -67,9 +67,14 @@ func (r *Request) WithContext(ctx context.Context) *Request {
 
 // ID returns the device id for this request.  If Message is nil or does not implement
 // wrp.Routable, this method returns an empty identifier.
-func (r *Request) ID() (i ID, err error) {
+func (r *Request) ID() (ID, error) {
+	return r.ParseID(ParseOptions{})
+}
+
+// ParseID is like ID, but parses the destination of this request with the given options
+func (r *Request) ParseID(o ParseOptions) (i ID, err error) {
 	if routable, ok := r.Message.(wrp.Routable); ok {
-		i, err = ParseID(routable.To())
+		i, _, err = ParseDeviceName(routable.To(), o)
 	}
 
 	return
@@ -59,9 +59,29 @@ func testRequestID(t *testing.T) {
 	assert.Error(err)
 }
 
+func testRequestParseID(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		request = &Request{
+			Message: &wrp.Message{
+				Destination: "mac:123412341234/service/extra",
+			},
+		}
+	)
+
+	id, err := request.ParseID(ParseOptions{})
+	assert.Equal(ID("mac:123412341234"), id)
+	assert.NoError(err)
+
+	id, err = request.ParseID(ParseOptions{RejectExtraSegments: true})
+	assert.Empty(string(id))
+	assert.Equal(ErrorExtraDeviceNameSegments, err)
+}
+
 func TestRequest(t *testing.T) {
 	t.Run("Context", testRequestContext)
 	t.Run("ID", testRequestID)
+	t.Run("ParseID", testRequestParseID)
 }
 
 func testDecodeRequest(t *testing.T, message wrp.Routable, format wrp.Format) {
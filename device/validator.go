@@ -0,0 +1,126 @@
+package device
+
+import (
+	"unicode/utf8"
+
+	"github.com/xmidt-org/wrp-go/v3"
+)
+
+// Validator checks a WRP message received from a device before that message is dispatched to listeners.
+// A non-nil error indicates the message is invalid, and the Manager's InvalidMessagePolicy is applied.
+type Validator interface {
+	Validate(Interface, *wrp.Message) error
+}
+
+// ValidatorFunc is a function type that implements Validator
+type ValidatorFunc func(Interface, *wrp.Message) error
+
+func (vf ValidatorFunc) Validate(d Interface, m *wrp.Message) error {
+	return vf(d, m)
+}
+
+// Validators is an ordered chain of Validator instances
+type Validators []Validator
+
+// Validate applies each Validator in order, returning the first error encountered
+func (vs Validators) Validate(d Interface, m *wrp.Message) error {
+	for _, v := range vs {
+		if err := v.Validate(d, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SourceValidator returns a Validator which requires the source of each message to identify the
+// device which sent it.  Unlike the WRPSourceCheck option, an empty source is also rejected.
+func SourceValidator() Validator {
+	return ValidatorFunc(func(d Interface, m *wrp.Message) error {
+		id, err := ParseID(m.Source)
+		if err != nil || id != baseID(d) {
+			return ErrorSourceMismatch
+		}
+
+		return nil
+	})
+}
+
+// MaxPayloadValidator returns a Validator which rejects messages with payloads larger than the given size
+func MaxPayloadValidator(size int) Validator {
+	return ValidatorFunc(func(_ Interface, m *wrp.Message) error {
+		if len(m.Payload) > size {
+			return ErrorPayloadTooLarge
+		}
+
+		return nil
+	})
+}
+
+// UTF8PayloadValidator returns a Validator which requires the payloads of messages of the given types
+// to be valid UTF-8.  If no types are supplied, all messages are checked.
+func UTF8PayloadValidator(types ...wrp.MessageType) Validator {
+	return ValidatorFunc(func(_ Interface, m *wrp.Message) error {
+		checked := len(types) == 0
+		for _, t := range types {
+			if m.Type == t {
+				checked = true
+				break
+			}
+		}
+
+		if checked && !utf8.Valid(m.Payload) {
+			return ErrorInvalidUTF8Payload
+		}
+
+		return nil
+	})
+}
+
+// baseID returns the ID a device connected with, ignoring any connection instance suffix
+func baseID(d Interface) ID {
+	if internal, ok := d.(*device); ok {
+		return internal.baseID
+	}
+
+	return d.ID()
+}
+
+// InvalidMessagePolicy describes what a Manager does with a message that fails validation
+type InvalidMessagePolicy string
+
+const (
+	// InvalidMessageDrop discards the message.  This is the default.
+	InvalidMessageDrop InvalidMessagePolicy = "drop"
+
+	// InvalidMessageRespond discards the message and, if the message is part of a transaction, sends the
+	// device a response with a 400 status and the validation error as its payload.
+	InvalidMessageRespond InvalidMessagePolicy = "respond"
+
+	// InvalidMessageDisconnect discards the message and disconnects the device.
+	InvalidMessageDisconnect InvalidMessagePolicy = "disconnect"
+)
+
+func (imp InvalidMessagePolicy) normalize() InvalidMessagePolicy {
+	switch imp {
+	case InvalidMessageRespond, InvalidMessageDisconnect:
+		return imp
+	default:
+		return InvalidMessageDrop
+	}
+}
+
+// errorResponse creates a WRP message with the given status in response to a message, e.g. one sent
+// to a device in response to an invalid message
+func errorResponse(m *wrp.Message, status int, err error) *wrp.Message {
+	response := &wrp.Message{
+		Type:            m.Type,
+		Source:          m.Destination,
+		Destination:     m.Source,
+		TransactionUUID: m.TransactionUUID,
+		ContentType:     "text/plain",
+		Payload:         []byte(err.Error()),
+	}
+
+	return response.SetStatus(int64(status))
+}
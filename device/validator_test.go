@@ -0,0 +1,252 @@
+package device
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/xmetrics/xmetricstest"
+	"github.com/xmidt-org/wrp-go/v3"
+)
+
+func TestValidators(t *testing.T) {
+	var (
+		assert        = assert.New(t)
+		d             = newDevice(deviceOptions{ID: ID("mac:112233445566"), Logger: logging.NewTestLogger(nil, t)})
+		message       = new(wrp.Message)
+		expectedError = errors.New("expected")
+		calls         []int
+	)
+
+	assert.NoError(Validators(nil).Validate(d, message))
+
+	chain := Validators{
+		ValidatorFunc(func(actual Interface, m *wrp.Message) error {
+			assert.True(d == actual)
+			assert.True(message == m)
+			calls = append(calls, 1)
+			return nil
+		}),
+		ValidatorFunc(func(Interface, *wrp.Message) error {
+			calls = append(calls, 2)
+			return expectedError
+		}),
+		ValidatorFunc(func(Interface, *wrp.Message) error {
+			calls = append(calls, 3)
+			return nil
+		}),
+	}
+
+	assert.Equal(expectedError, chain.Validate(d, message))
+	assert.Equal([]int{1, 2}, calls)
+}
+
+func TestSourceValidator(t *testing.T) {
+	var (
+		assert    = assert.New(t)
+		logger    = logging.NewTestLogger(nil, t)
+		d         = newDevice(deviceOptions{ID: ID("mac:112233445566"), Logger: logger})
+		validator = SourceValidator()
+	)
+
+	assert.NoError(validator.Validate(d, &wrp.Message{Source: "mac:112233445566/service"}))
+	assert.NoError(validator.Validate(d, &wrp.Message{Source: "MAC:11-22-33-44-55-66"}))
+	assert.Equal(ErrorSourceMismatch, validator.Validate(d, &wrp.Message{Source: "mac:665544332211"}))
+	assert.Equal(ErrorSourceMismatch, validator.Validate(d, &wrp.Message{Source: ""}))
+	assert.Equal(ErrorSourceMismatch, validator.Validate(d, &wrp.Message{Source: "invalid"}))
+
+	// duplicate connection instances are validated against the ID they connected with
+	d.id = instanceID(d.baseID, 2)
+	assert.NoError(validator.Validate(d, &wrp.Message{Source: "mac:112233445566"}))
+
+	mockDevice := new(MockDevice)
+	mockDevice.On("ID").Return(ID("mac:112233445566"))
+	assert.NoError(validator.Validate(mockDevice, &wrp.Message{Source: "mac:112233445566"}))
+	mockDevice.AssertExpectations(t)
+}
+
+func TestMaxPayloadValidator(t *testing.T) {
+	var (
+		assert    = assert.New(t)
+		validator = MaxPayloadValidator(4)
+	)
+
+	assert.NoError(validator.Validate(nil, &wrp.Message{}))
+	assert.NoError(validator.Validate(nil, &wrp.Message{Payload: []byte("1234")}))
+	assert.Equal(ErrorPayloadTooLarge, validator.Validate(nil, &wrp.Message{Payload: []byte("12345")}))
+}
+
+func TestUTF8PayloadValidator(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		invalid = []byte{0xff, 0xfe}
+	)
+
+	all := UTF8PayloadValidator()
+	assert.NoError(all.Validate(nil, &wrp.Message{Type: wrp.SimpleEventMessageType, Payload: []byte("héllo")}))
+	assert.Equal(ErrorInvalidUTF8Payload, all.Validate(nil, &wrp.Message{Type: wrp.SimpleEventMessageType, Payload: invalid}))
+
+	events := UTF8PayloadValidator(wrp.SimpleEventMessageType)
+	assert.Equal(ErrorInvalidUTF8Payload, events.Validate(nil, &wrp.Message{Type: wrp.SimpleEventMessageType, Payload: invalid}))
+	assert.NoError(events.Validate(nil, &wrp.Message{Type: wrp.SimpleRequestResponseMessageType, Payload: invalid}))
+}
+
+func TestInvalidMessagePolicyNormalize(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(InvalidMessageDrop, InvalidMessagePolicy("").normalize())
+	assert.Equal(InvalidMessageDrop, InvalidMessagePolicy("nosuch").normalize())
+	assert.Equal(InvalidMessageDrop, InvalidMessageDrop.normalize())
+	assert.Equal(InvalidMessageRespond, InvalidMessageRespond.normalize())
+	assert.Equal(InvalidMessageDisconnect, InvalidMessageDisconnect.normalize())
+}
+
+func newRejectTestManager(t *testing.T, policy InvalidMessagePolicy) (*manager, *device, xmetricstest.Provider) {
+	var (
+		p = xmetricstest.NewProvider(nil, Metrics)
+		m = &manager{
+			measures:             NewMeasures(p),
+			invalidMessagePolicy: policy,
+		}
+
+		d = newDevice(deviceOptions{ID: ID("mac:112233445566"), Logger: logging.NewTestLogger(nil, t)})
+	)
+
+	return m, d, p
+}
+
+func testManagerRejectMessageDrop(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		m, d, p = newRejectTestManager(t, InvalidMessageDrop)
+	)
+
+	m.rejectMessage(d, &wrp.Message{Type: wrp.SimpleRequestResponseMessageType, TransactionUUID: "abc"}, ErrorPayloadTooLarge)
+	assert.False(d.Closed())
+	assert.Zero(d.Pending())
+	p.Assert(t, InvalidMessageCounter, "policy", string(InvalidMessageDrop))(xmetricstest.Value(1.0))
+}
+
+func testManagerRejectMessageRespond(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		m, d, p = newRejectTestManager(t, InvalidMessageRespond)
+
+		message = &wrp.Message{
+			Type:            wrp.SimpleRequestResponseMessageType,
+			Source:          "mac:112233445566/config",
+			Destination:     "dns:talaria.xmidt.comcast.net/config",
+			TransactionUUID: "abc",
+		}
+	)
+
+	// the response uses the device's negotiated format
+	d.format = wrp.JSON
+
+	// events cannot be answered
+	m.rejectMessage(d, &wrp.Message{Type: wrp.SimpleEventMessageType}, ErrorPayloadTooLarge)
+
+	m.rejectMessage(d, message, ErrorPayloadTooLarge)
+	assert.False(d.Closed())
+
+	select {
+	case e := <-d.messages.tiers[QOSLow]:
+		assert.Equal(wrp.JSON, e.request.Format)
+		response, ok := e.request.Message.(*wrp.Message)
+		require.True(ok)
+		assert.Equal(wrp.SimpleRequestResponseMessageType, response.Type)
+		assert.Equal(message.Destination, response.Source)
+		assert.Equal(message.Source, response.Destination)
+		assert.Equal("abc", response.TransactionUUID)
+		require.NotNil(response.Status)
+		assert.Equal(int64(http.StatusBadRequest), *response.Status)
+		assert.Equal(ErrorPayloadTooLarge.Error(), string(response.Payload))
+		close(e.complete)
+
+	case <-time.After(time.Second):
+		require.Fail("No error response was sent")
+	}
+
+	assert.Zero(d.Pending())
+	p.Assert(t, InvalidMessageCounter, "policy", string(InvalidMessageRespond))(xmetricstest.Value(2.0))
+}
+
+func testManagerRejectMessageDisconnect(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		m, d, p = newRejectTestManager(t, InvalidMessageDisconnect)
+	)
+
+	m.rejectMessage(d, &wrp.Message{Type: wrp.SimpleEventMessageType}, ErrorInvalidUTF8Payload)
+	assert.True(d.Closed())
+	assert.Equal(CloseReason{Err: ErrorInvalidUTF8Payload, Reason: DisconnectInvalidMessage, Text: "invalid-message"}, d.CloseReason())
+	p.Assert(t, InvalidMessageCounter, "policy", string(InvalidMessageDisconnect))(xmetricstest.Value(1.0))
+}
+
+func testManagerRejectMessageRespondJSON(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		connected = make(chan struct{}, 1)
+		options   = &Options{
+			Logger:               logging.NewTestLogger(nil, t),
+			Validators:           []Validator{MaxPayloadValidator(4)},
+			InvalidMessagePolicy: InvalidMessageRespond,
+			Listeners: []Listener{
+				func(event *Event) {
+					if event.Type == Connect {
+						connected <- struct{}{}
+					}
+				},
+			},
+		}
+
+		dialer = NewDialer(DialerOptions{
+			WSDialer: &websocket.Dialer{Subprotocols: []string{JSONSubprotocol}},
+		})
+	)
+
+	_, server, connectURL := startWebsocketServer(options)
+	defer server.Close()
+
+	c, _, err := dialer.DialDevice(string(testDeviceIDs[0]), connectURL, nil)
+	require.NoError(err)
+	defer c.Close()
+	<-connected
+
+	var data []byte
+	require.NoError(wrp.NewEncoderBytes(&data, wrp.JSON).Encode(&wrp.Message{
+		Type:            wrp.SimpleRequestResponseMessageType,
+		Source:          string(testDeviceIDs[0]) + "/config",
+		Destination:     "dns:talaria.xmidt.comcast.net/config",
+		TransactionUUID: "abc",
+		Payload:         []byte("too large"),
+	}))
+
+	require.NoError(c.WriteMessage(websocket.TextMessage, data))
+	require.NoError(c.SetReadDeadline(time.Now().Add(5 * time.Second)))
+	messageType, data, err := c.ReadMessage()
+	require.NoError(err)
+	assert.Equal(websocket.TextMessage, messageType)
+
+	var response wrp.Message
+	require.NoError(wrp.NewDecoderBytes(data, wrp.JSON).Decode(&response))
+	assert.Equal("abc", response.TransactionUUID)
+	require.NotNil(response.Status)
+	assert.Equal(int64(http.StatusBadRequest), *response.Status)
+	assert.Equal(ErrorPayloadTooLarge.Error(), string(response.Payload))
+}
+
+func TestManagerRejectMessage(t *testing.T) {
+	t.Run("Drop", testManagerRejectMessageDrop)
+	t.Run("Respond", testManagerRejectMessageRespond)
+	t.Run("RespondJSON", testManagerRejectMessageRespondJSON)
+	t.Run("Disconnect", testManagerRejectMessageDisconnect)
+}
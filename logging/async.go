@@ -0,0 +1,167 @@
+package logging
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/go-kit/kit/metrics"
+)
+
+const (
+	DefaultAsyncBufferSize = 1024
+	asyncWriteBufferSize   = 64 * 1024
+)
+
+var errAsyncWriterClosed = errors.New("The asynchronous log writer has been closed")
+
+// AsyncOptions configures asynchronous log output.  Records are copied into a bounded buffer and written by a
+// background goroutine, so that slow disks never block the goroutines that log, such as device read and write pumps.
+type AsyncOptions struct {
+	// BufferSize is the maximum number of records waiting to be written.  When the buffer is full, records
+	// are dropped rather than blocking the caller.  If unset or nonpositive, DefaultAsyncBufferSize is used.
+	BufferSize int `json:"bufferSize"`
+
+	// Dropped is an optional counter incremented for each record that is discarded, either because the buffer
+	// was full or because the underlying writer returned an error
+	Dropped metrics.Counter `json:"-"`
+}
+
+func (ao *AsyncOptions) bufferSize() int {
+	if ao != nil && ao.BufferSize > 0 {
+		return ao.BufferSize
+	}
+
+	return DefaultAsyncBufferSize
+}
+
+func (ao *AsyncOptions) dropped() metrics.Counter {
+	if ao != nil {
+		return ao.Dropped
+	}
+
+	return nil
+}
+
+type asyncWriter struct {
+	next    io.Writer
+	dropped metrics.Counter
+
+	lock    sync.RWMutex
+	closed  bool
+	records chan []byte
+	done    chan struct{}
+}
+
+func (aw *asyncWriter) drop(count int) {
+	if aw.dropped != nil {
+		aw.dropped.Add(float64(count))
+	}
+}
+
+// Write never blocks on the underlying writer.  Since each go-kit Log call results in exactly one Write,
+// each record is either written whole or dropped whole.
+func (aw *asyncWriter) Write(p []byte) (int, error) {
+	aw.lock.RLock()
+	defer aw.lock.RUnlock()
+
+	if aw.closed {
+		aw.drop(1)
+		return 0, errAsyncWriterClosed
+	}
+
+	// the caller is free to reuse p once Write returns
+	record := make([]byte, len(p))
+	copy(record, p)
+
+	select {
+	case aw.records <- record:
+	default:
+		aw.drop(1)
+	}
+
+	return len(p), nil
+}
+
+// run writes records until the records channel is closed.  Records are accumulated in a bufio.Writer,
+// which is flushed whenever there are no more records waiting.
+func (aw *asyncWriter) run() {
+	defer close(aw.done)
+
+	var (
+		output  = bufio.NewWriterSize(aw.next, asyncWriteBufferSize)
+		pending int
+	)
+
+	write := func(record []byte) {
+		if _, err := output.Write(record); err != nil {
+			aw.drop(pending + 1)
+			pending = 0
+			output.Reset(aw.next)
+		} else {
+			pending++
+		}
+	}
+
+	flush := func() {
+		if err := output.Flush(); err != nil {
+			aw.drop(pending)
+			output.Reset(aw.next)
+		}
+
+		pending = 0
+	}
+
+	for record := range aw.records {
+		write(record)
+
+	Drain:
+		for {
+			select {
+			case more, ok := <-aw.records:
+				if !ok {
+					break Drain
+				}
+
+				write(more)
+
+			default:
+				break Drain
+			}
+		}
+
+		flush()
+	}
+
+	flush()
+}
+
+// Close stops accepting records and waits for the buffered records to be written.  The underlying
+// writer is not closed.  This method is idempotent.
+func (aw *asyncWriter) Close() error {
+	aw.lock.Lock()
+	if !aw.closed {
+		aw.closed = true
+		close(aw.records)
+	}
+
+	aw.lock.Unlock()
+	<-aw.done
+	return nil
+}
+
+// NewAsyncWriter decorates an io.Writer so that writes are performed by a background goroutine.  The options
+// may be nil, in which case defaults are used.  Close should be called before the application exits so that
+// buffered records are written.
+func NewAsyncWriter(next io.Writer, o *AsyncOptions) io.WriteCloser {
+	aw := &asyncWriter{
+		next:    next,
+		dropped: o.dropped(),
+		records: make(chan []byte, o.bufferSize()),
+		done:    make(chan struct{}),
+	}
+
+	go aw.run()
+	return aw
+}
@@ -0,0 +1,135 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingWriter blocks each Write until released, then appends to its buffer
+type blockingWriter struct {
+	writing chan struct{}
+	release chan struct{}
+
+	lock   sync.Mutex
+	output bytes.Buffer
+	err    error
+}
+
+func (bw *blockingWriter) Write(p []byte) (int, error) {
+	if bw.writing != nil {
+		bw.writing <- struct{}{}
+		<-bw.release
+	}
+
+	bw.lock.Lock()
+	defer bw.lock.Unlock()
+
+	if bw.err != nil {
+		return 0, bw.err
+	}
+
+	return bw.output.Write(p)
+}
+
+func (bw *blockingWriter) String() string {
+	bw.lock.Lock()
+	defer bw.lock.Unlock()
+	return bw.output.String()
+}
+
+func testNewAsyncWriterOrdering(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		next   = new(blockingWriter)
+		writer = NewAsyncWriter(next, nil)
+		record = []byte("record 0\n")
+	)
+
+	require.NotNil(writer)
+	for i := 0; i < 100; i++ {
+		record[7] = byte('0' + i%10)
+		n, err := writer.Write(record)
+		assert.Equal(len(record), n)
+		assert.NoError(err)
+	}
+
+	assert.NoError(writer.Close())
+	assert.NoError(writer.Close())
+
+	var expected bytes.Buffer
+	for i := 0; i < 100; i++ {
+		expected.WriteString("record ")
+		expected.WriteByte(byte('0' + i%10))
+		expected.WriteString("\n")
+	}
+
+	assert.Equal(expected.String(), next.String())
+
+	n, err := writer.Write([]byte("closed\n"))
+	assert.Zero(n)
+	assert.Equal(errAsyncWriterClosed, err)
+}
+
+func testNewAsyncWriterOverflow(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		next = &blockingWriter{
+			writing: make(chan struct{}, 10),
+			release: make(chan struct{}),
+		}
+
+		dropped = generic.NewCounter("dropped")
+		writer  = NewAsyncWriter(next, &AsyncOptions{BufferSize: 1, Dropped: dropped})
+	)
+
+	writer.Write([]byte("first\n"))
+	select {
+	case <-next.writing:
+	case <-time.After(5 * time.Second):
+		require.Fail("The first record was not written")
+	}
+
+	// the background goroutine is now blocked, so the buffer fills
+	_, err := writer.Write([]byte("second\n"))
+	assert.NoError(err)
+	_, err = writer.Write([]byte("third\n"))
+	assert.NoError(err)
+	assert.Equal(1.0, dropped.Value())
+
+	close(next.release)
+	assert.NoError(writer.Close())
+	assert.Equal("first\nsecond\n", next.String())
+	assert.Equal(1.0, dropped.Value())
+}
+
+func testNewAsyncWriterError(t *testing.T) {
+	var (
+		assert = assert.New(t)
+
+		next    = &blockingWriter{err: errors.New("expected")}
+		dropped = generic.NewCounter("dropped")
+		writer  = NewAsyncWriter(next, &AsyncOptions{Dropped: dropped})
+	)
+
+	writer.Write([]byte("first\n"))
+	writer.Write([]byte("second\n"))
+	assert.NoError(writer.Close())
+	assert.Equal(2.0, dropped.Value())
+}
+
+func TestNewAsyncWriter(t *testing.T) {
+	t.Run("Ordering", testNewAsyncWriterOrdering)
+	t.Run("Overflow", testNewAsyncWriterOverflow)
+	t.Run("Error", testNewAsyncWriterError)
+}
@@ -0,0 +1,41 @@
+package logging
+
+import (
+	"io"
+	"sync"
+)
+
+// closers holds the background writers and exporters created from Options, so that Close can
+// flush them when the application exits
+var closers struct {
+	lock sync.Mutex
+	list []io.Closer
+}
+
+// registerCloser tracks a background writer or exporter created from Options
+func registerCloser(c io.Closer) {
+	closers.lock.Lock()
+	closers.list = append(closers.list, c)
+	closers.lock.Unlock()
+}
+
+// Close flushes and stops every asynchronous writer and exporter created by New or NewZap, in the reverse
+// order of their creation, so that an exporter is closed before the output it reports errors to.  Applications
+// that configure Async or Export should call this function before exiting.  Records logged afterward through
+// those loggers are dropped.  The first error encountered is returned, but every closer is always invoked.
+// Subsequent calls only close what has been created since the previous call.
+func Close() error {
+	closers.lock.Lock()
+	list := closers.list
+	closers.list = nil
+	closers.lock.Unlock()
+
+	var first error
+	for i := len(list) - 1; i >= 0; i-- {
+		if err := list[i].Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
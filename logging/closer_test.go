@@ -0,0 +1,107 @@
+package logging
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// closeRecorder is an io.Closer that records the order in which it was closed
+type closeRecorder struct {
+	name   string
+	err    error
+	closed *[]string
+}
+
+func (cr closeRecorder) Close() error {
+	*cr.closed = append(*cr.closed, cr.name)
+	return cr.err
+}
+
+func testCloseOrder(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		closed []string
+
+		firstErr  = errors.New("first")
+		secondErr = errors.New("second")
+	)
+
+	// discard anything registered by other tests
+	Close()
+
+	registerCloser(closeRecorder{name: "one", err: secondErr, closed: &closed})
+	registerCloser(closeRecorder{name: "two", closed: &closed})
+	registerCloser(closeRecorder{name: "three", err: firstErr, closed: &closed})
+
+	assert.Equal(firstErr, Close())
+	assert.Equal([]string{"three", "two", "one"}, closed)
+
+	assert.NoError(Close())
+	assert.Len(closed, 3)
+}
+
+func testCloseAsync(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+	)
+
+	dir, err := ioutil.TempDir("", "closer")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "test.log")
+	logger := New(&Options{File: file, Async: new(AsyncOptions)})
+	Error(logger).Log(MessageKey(), "flushed")
+
+	require.NoError(Close())
+	contents, err := ioutil.ReadFile(file)
+	require.NoError(err)
+	assert.Contains(string(contents), "flushed")
+
+	// records logged after Close are dropped
+	assert.Error(Error(logger).Log(MessageKey(), "dropped"))
+}
+
+func testCloseExport(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		server, requests = newExportServer(http.StatusNoContent)
+	)
+
+	defer server.Close()
+	logger := New(&Options{
+		File: os.DevNull,
+		Export: &ExportOptions{
+			Type:          ExportLoki,
+			URL:           server.URL,
+			FlushInterval: time.Hour,
+		},
+	})
+
+	Error(logger).Log(MessageKey(), "exported")
+	require.NoError(Close())
+
+	select {
+	case r := <-requests:
+		assert.Contains(string(r.body), "exported")
+	default:
+		assert.Fail("Close did not send the queued records")
+	}
+}
+
+func TestClose(t *testing.T) {
+	t.Run("Order", testCloseOrder)
+	t.Run("Async", testCloseAsync)
+	t.Run("Export", testCloseExport)
+}
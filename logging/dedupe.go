@@ -0,0 +1,159 @@
+package logging
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+const DefaultDedupeWindow = 10 * time.Second
+
+var repeatedKey interface{} = "repeated"
+
+// RepeatedKey returns the logging key for the number of times a log entry was repeated
+// and suppressed by a logger created with NewDedupe
+func RepeatedKey() interface{} {
+	return repeatedKey
+}
+
+// DedupeOptions configures the suppression of identical, consecutive log entries
+type DedupeOptions struct {
+	// Window is the period, starting with the first of a series of identical entries, during which
+	// repeats are suppressed.  If unset or nonpositive, DefaultDedupeWindow is used.
+	Window time.Duration `json:"window"`
+
+	// Fields are the logging keys whose values determine whether two entries are identical.  If unset,
+	// the level and message are used, so that entries which differ only in details such as timestamps,
+	// callers, or error instances are considered identical.
+	Fields []string `json:"fields"`
+}
+
+func (do DedupeOptions) window() time.Duration {
+	if do.Window > 0 {
+		return do.Window
+	}
+
+	return DefaultDedupeWindow
+}
+
+// identity returns a function that computes the identity of a log entry from the given fields
+func (do DedupeOptions) identity() func([]interface{}) string {
+	if len(do.Fields) == 0 {
+		return DefaultSamplingKey
+	}
+
+	fields := append([]string(nil), do.Fields...)
+	return func(keyvals []interface{}) string {
+		values := make([]interface{}, len(fields))
+		for i := 0; i+1 < len(keyvals); i += 2 {
+			key := fmt.Sprint(keyvals[i])
+			for j, f := range fields {
+				if key == f {
+					values[j] = keyvals[i+1]
+				}
+			}
+		}
+
+		var identity strings.Builder
+		for _, v := range values {
+			fmt.Fprintf(&identity, "%v\x00", v)
+		}
+
+		return identity.String()
+	}
+}
+
+// dedupe is a go-kit Logger that collapses consecutive identical entries
+type dedupe struct {
+	next      log.Logger
+	window    time.Duration
+	identity  func([]interface{}) string
+	now       func() time.Time
+	afterFunc func(time.Duration, func()) func() bool
+
+	lock       sync.Mutex
+	generation uint64
+	active     bool
+	last       string
+	windowEnd  time.Time
+	repeated   int
+	repeat     []interface{}
+	stopTimer  func() bool
+}
+
+// flush writes the summary of any suppressed entries.  This method must be invoked under the lock.
+func (d *dedupe) flush() {
+	if d.stopTimer != nil {
+		d.stopTimer()
+		d.stopTimer = nil
+	}
+
+	if d.repeated > 0 {
+		summary := append(append(make([]interface{}, 0, len(d.repeat)+2), d.repeat...), RepeatedKey(), d.repeated)
+		d.repeated = 0
+		d.repeat = d.repeat[:0]
+		d.next.Log(summary...)
+	}
+}
+
+// expire is invoked when a window elapses with suppressed entries still outstanding
+func (d *dedupe) expire(generation uint64) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.generation == generation {
+		d.stopTimer = nil
+		d.flush()
+		d.active = false
+	}
+}
+
+func (d *dedupe) Log(keyvals ...interface{}) error {
+	var (
+		identity = d.identity(keyvals)
+		now      = d.now()
+	)
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.active && identity == d.last && now.Before(d.windowEnd) {
+		d.repeated++
+		d.repeat = append(d.repeat[:0], keyvals...)
+		if d.stopTimer == nil {
+			generation := d.generation
+			d.stopTimer = d.afterFunc(d.windowEnd.Sub(now), func() { d.expire(generation) })
+		}
+
+		return nil
+	}
+
+	d.flush()
+	d.generation++
+	d.active = true
+	d.last = identity
+	d.windowEnd = now.Add(d.window)
+	return d.next.Log(keyvals...)
+}
+
+// NewDedupe decorates a logger so that identical, consecutive entries are collapsed.  The first entry of a series
+// is logged immediately.  Repeats within the window are suppressed, and the last repeat is logged with RepeatedKey()
+// set to the number of suppressed entries, either when a different entry is logged or when the window elapses.
+// This tames repetitive logs such as reconnect attempts or service discovery errors.
+//
+// As with NewSampler, the returned logger should be decorated with log.With and the level functions in this package,
+// so that the fields used to identify entries are visible to it.
+func NewDedupe(next log.Logger, o DedupeOptions) log.Logger {
+	return &dedupe{
+		next:     next,
+		window:   o.window(),
+		identity: o.identity(),
+		now:      time.Now,
+		afterFunc: func(d time.Duration, f func()) func() bool {
+			return time.AfterFunc(d, f).Stop
+		},
+	}
+}
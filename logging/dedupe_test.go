@@ -0,0 +1,150 @@
+package logging
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepeatedKey(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(repeatedKey, RepeatedKey())
+}
+
+// newTestDedupe creates a dedupe logger whose clock and timers are controlled by the test
+func newTestDedupe(o DedupeOptions) (*dedupe, CaptureLogger, *time.Time, *[]func()) {
+	var (
+		next    = NewCaptureLogger()
+		now     = time.Unix(1000, 0)
+		expires []func()
+		d       = NewDedupe(next, o).(*dedupe)
+	)
+
+	d.now = func() time.Time { return now }
+	d.afterFunc = func(_ time.Duration, f func()) func() bool {
+		expires = append(expires, f)
+		return func() bool { return true }
+	}
+
+	return d, next, &now, &expires
+}
+
+func testNewDedupeDefaults(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		logger  = NewDedupe(NewCaptureLogger(), DedupeOptions{})
+	)
+
+	require.NotNil(logger)
+	d := logger.(*dedupe)
+	assert.Equal(DefaultDedupeWindow, d.window)
+	assert.NotNil(d.identity)
+}
+
+func testNewDedupeCollapse(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		d, next, now, expires = newTestDedupe(DedupeOptions{Window: time.Minute})
+		reconnect             = Error(log.With(d, "url", "http://talaria:8080"))
+	)
+
+	for i := 0; i < 4; i++ {
+		reconnect.Log(MessageKey(), "unable to connect", "attempt", i)
+	}
+
+	entry := <-next.Output()
+	assert.Equal("unable to connect", entry[MessageKey()])
+	assert.Equal(0, entry["attempt"])
+	assert.Equal("http://talaria:8080", entry["url"])
+	assert.NotContains(entry, RepeatedKey())
+	assert.Empty(next.Output())
+	require.Len(*expires, 1)
+
+	// a different message flushes the summary first
+	*now = now.Add(time.Second)
+	Info(d).Log(MessageKey(), "connected")
+
+	entry = <-next.Output()
+	assert.Equal("unable to connect", entry[MessageKey()])
+	assert.Equal(3, entry["attempt"])
+	assert.Equal(3, entry[RepeatedKey()])
+
+	entry = <-next.Output()
+	assert.Equal("connected", entry[MessageKey()])
+	assert.NotContains(entry, RepeatedKey())
+
+	// the timer from the previous series is stale
+	(*expires)[0]()
+	assert.Empty(next.Output())
+}
+
+func testNewDedupeWindow(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		d, next, now, expires = newTestDedupe(DedupeOptions{Window: time.Minute})
+		consul                = Error(d)
+	)
+
+	consul.Log(MessageKey(), "watch failed")
+	consul.Log(MessageKey(), "watch failed")
+	consul.Log(MessageKey(), "watch failed")
+	assert.Equal("watch failed", (<-next.Output())[MessageKey()])
+	assert.Empty(next.Output())
+
+	// the summary is written when the window elapses, even if nothing else is logged
+	require.Len(*expires, 1)
+	*now = now.Add(time.Minute)
+	(*expires)[0]()
+
+	entry := <-next.Output()
+	assert.Equal("watch failed", entry[MessageKey()])
+	assert.Equal(2, entry[RepeatedKey()])
+
+	// a new series starts after the window
+	consul.Log(MessageKey(), "watch failed")
+	entry = <-next.Output()
+	assert.NotContains(entry, RepeatedKey())
+
+	// a repeat after the window, without a timer firing, also starts a new series
+	*now = now.Add(2 * time.Minute)
+	consul.Log(MessageKey(), "watch failed")
+	entry = <-next.Output()
+	assert.NotContains(entry, RepeatedKey())
+	assert.Empty(next.Output())
+}
+
+func testNewDedupeFields(t *testing.T) {
+	var (
+		assert = assert.New(t)
+
+		d, next, _, _ = newTestDedupe(DedupeOptions{Fields: []string{"msg", "deviceID"}})
+	)
+
+	d.Log(MessageKey(), "disconnected", "deviceID", "mac:112233445566")
+	d.Log(MessageKey(), "disconnected", "deviceID", "mac:112233445566")
+	d.Log(MessageKey(), "disconnected", "deviceID", "mac:665544332211")
+
+	assert.Equal("mac:112233445566", (<-next.Output())["deviceID"])
+
+	entry := <-next.Output()
+	assert.Equal("mac:112233445566", entry["deviceID"])
+	assert.Equal(1, entry[RepeatedKey()])
+
+	assert.Equal("mac:665544332211", (<-next.Output())["deviceID"])
+	assert.Empty(next.Output())
+}
+
+func TestNewDedupe(t *testing.T) {
+	t.Run("Defaults", testNewDedupeDefaults)
+	t.Run("Collapse", testNewDedupeCollapse)
+	t.Run("Window", testNewDedupeWindow)
+	t.Run("Fields", testNewDedupeFields)
+}
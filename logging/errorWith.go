@@ -0,0 +1,134 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+var (
+	errorChainKey interface{} = "errorChain"
+	statusCodeKey interface{} = "statusCode"
+	deviceIDKey   interface{} = "deviceID"
+	stackKey      interface{} = "stack"
+)
+
+// ErrorChainKey returns the logging key for the messages of an error and each error it wraps
+func ErrorChainKey() interface{} {
+	return errorChainKey
+}
+
+// StatusCodeKey returns the logging key for the HTTP status code carried by an error
+func StatusCodeKey() interface{} {
+	return statusCodeKey
+}
+
+// DeviceIDKey returns the logging key for the device ID carried by an error
+func DeviceIDKey() interface{} {
+	return deviceIDKey
+}
+
+// StackKey returns the logging key for a captured stack trace
+func StackKey() interface{} {
+	return stackKey
+}
+
+// maxStackDepth is the maximum number of frames captured by ErrorWithStack
+const maxStackDepth = 32
+
+// statusCoder is implemented by errors that carry an HTTP status, such as xhttp.Error and go-kit's StatusCoder
+type statusCoder interface {
+	StatusCode() int
+}
+
+// deviceIDer is implemented by errors that pertain to a particular device
+type deviceIDer interface {
+	DeviceID() string
+}
+
+// errorKeyvals produces the structured fields that describe an error.  Each error in the chain, as
+// traversed by errors.Unwrap, is examined for typed fields.  The outermost error wins if more than one
+// error in the chain supplies the same field.
+func errorKeyvals(err error) []interface{} {
+	if err == nil {
+		return []interface{}{ErrorKey(), nil}
+	}
+
+	var (
+		chain                []string
+		keyvals              []interface{}
+		hasStatus, hasDevice bool
+	)
+
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		chain = append(chain, e.Error())
+
+		if sc, ok := e.(statusCoder); ok && !hasStatus {
+			hasStatus = true
+			keyvals = append(keyvals, StatusCodeKey(), sc.StatusCode())
+		}
+
+		if d, ok := e.(deviceIDer); ok && !hasDevice {
+			hasDevice = true
+			keyvals = append(keyvals, DeviceIDKey(), d.DeviceID())
+		}
+
+		if c, ok := e.(Contextual); ok {
+			for k, v := range c.Metadata() {
+				keyvals = append(keyvals, k, v)
+			}
+		}
+	}
+
+	return append([]interface{}{ErrorKey(), err.Error(), ErrorChainKey(), chain}, keyvals...)
+}
+
+// stack captures the call stack as a slice of "function file:line" strings, starting skip frames above the caller
+func stack(skip int) []string {
+	var (
+		pcs    = make([]uintptr, maxStackDepth)
+		frames = runtime.CallersFrames(pcs[:runtime.Callers(skip+2, pcs)])
+		trace  []string
+	)
+
+	for {
+		frame, more := frames.Next()
+		trace = append(trace, fmt.Sprintf("%s %s:%d", frame.Function, frame.File, frame.Line))
+		if !more {
+			return trace
+		}
+	}
+}
+
+// errorWith builds the prefix shared by ErrorWith and ErrorWithStack
+func errorWith(next log.Logger, err error, trace []string, keyvals []interface{}) log.Logger {
+	prefix := append([]interface{}{CallerKey(), log.DefaultCaller, level.Key(), level.ErrorValue()}, errorKeyvals(err)...)
+	if len(trace) > 0 {
+		prefix = append(prefix, StackKey(), trace)
+	}
+
+	return log.WithPrefix(next, append(prefix, keyvals...)...)
+}
+
+// ErrorWith is like Error, except that the returned logger also describes the given error with structured fields.
+// The fields are:
+//
+//   - ErrorKey(), the error's message
+//   - ErrorChainKey(), the messages of the error and each error it wraps, outermost first
+//   - StatusCodeKey(), if an error in the chain has a StatusCode() int method, e.g. xhttp.Error
+//   - DeviceIDKey(), if an error in the chain has a DeviceID() string method
+//   - the metadata of any error in the chain that implements Contextual
+//
+// Additional key value pairs may also be added.
+func ErrorWith(next log.Logger, err error, keyvals ...interface{}) log.Logger {
+	return errorWith(next, err, nil, keyvals)
+}
+
+// ErrorWithStack is like ErrorWith, but also includes the stack of the code that called this function under StackKey().
+// Capturing a stack is comparatively expensive, so this function should be reserved for unexpected errors.
+func ErrorWithStack(next log.Logger, err error, keyvals ...interface{}) log.Logger {
+	return errorWith(next, err, stack(1), keyvals)
+}
@@ -0,0 +1,133 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testStatusError struct {
+	code int
+}
+
+func (e testStatusError) Error() string {
+	return fmt.Sprintf("status %d", e.code)
+}
+
+func (e testStatusError) StatusCode() int {
+	return e.code
+}
+
+type testDeviceError struct {
+	id    string
+	cause error
+}
+
+func (e testDeviceError) Error() string {
+	return fmt.Sprintf("device %s: %s", e.id, e.cause)
+}
+
+func (e testDeviceError) Unwrap() error {
+	return e.cause
+}
+
+func (e testDeviceError) DeviceID() string {
+	return e.id
+}
+
+func (e testDeviceError) Metadata() map[string]interface{} {
+	return map[string]interface{}{"partner": "comcast"}
+}
+
+func TestErrorWithKeys(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(errorChainKey, ErrorChainKey())
+	assert.Equal(statusCodeKey, StatusCodeKey())
+	assert.Equal(deviceIDKey, DeviceIDKey())
+	assert.Equal(stackKey, StackKey())
+}
+
+func testErrorWithNil(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		logger  = NewCaptureLogger()
+	)
+
+	ErrorWith(logger, nil).Log(MessageKey(), "nil error")
+	entry := <-logger.Output()
+	require.Contains(entry, ErrorKey())
+	assert.Nil(entry[ErrorKey()])
+	assert.NotContains(entry, ErrorChainKey())
+}
+
+func testErrorWithChain(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		logger  = NewCaptureLogger()
+
+		err = fmt.Errorf("request failed: %w", testDeviceError{id: "mac:112233445566", cause: testStatusError{code: 503}})
+	)
+
+	ErrorWith(logger, err, "component", "test").Log(MessageKey(), "unable to route")
+	entry := <-logger.Output()
+
+	assert.Equal("unable to route", entry[MessageKey()])
+	assert.Equal("error", fmt.Sprint(entry["level"]))
+	assert.NotNil(entry[CallerKey()])
+	assert.Equal(err.Error(), entry[ErrorKey()])
+	assert.Equal(
+		[]string{
+			err.Error(),
+			"device mac:112233445566: status 503",
+			"status 503",
+		},
+		entry[ErrorChainKey()],
+	)
+
+	assert.Equal(503, entry[StatusCodeKey()])
+	assert.Equal("mac:112233445566", entry[DeviceIDKey()])
+	assert.Equal("comcast", entry["partner"])
+	assert.Equal("test", entry["component"])
+	require.NotContains(entry, StackKey())
+}
+
+func testErrorWithOutermostWins(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		logger = NewCaptureLogger()
+	)
+
+	ErrorWith(logger, testDeviceError{id: "outer", cause: testDeviceError{id: "inner", cause: errors.New("expected")}}).Log()
+	assert.Equal("outer", (<-logger.Output())[DeviceIDKey()])
+}
+
+func testErrorWithStack(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		logger  = NewCaptureLogger()
+	)
+
+	ErrorWithStack(logger, errors.New("expected")).Log(MessageKey(), "unexpected")
+	entry := <-logger.Output()
+
+	assert.Equal("expected", entry[ErrorKey()])
+	trace, ok := entry[StackKey()].([]string)
+	require.True(ok)
+	require.NotEmpty(trace)
+	assert.True(strings.HasPrefix(trace[0], "github.com/xmidt-org/webpa-common/logging.testErrorWithStack "), trace[0])
+	assert.Contains(trace[0], "errorWith_test.go")
+}
+
+func TestErrorWith(t *testing.T) {
+	t.Run("Nil", testErrorWithNil)
+	t.Run("Chain", testErrorWithChain)
+	t.Run("OutermostWins", testErrorWithOutermostWins)
+	t.Run("Stack", testErrorWithStack)
+}
@@ -0,0 +1,398 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/metrics"
+)
+
+const (
+	// ExportLoki selects the Loki push API, e.g. http://loki:3100/loki/api/v1/push
+	ExportLoki = "loki"
+
+	// ExportOTLP selects the OTLP/HTTP logs API using JSON encoding, e.g. http://collector:4318/v1/logs
+	ExportOTLP = "otlp"
+
+	DefaultExportBatchSize     = 100
+	DefaultExportQueueSize     = 1000
+	DefaultExportFlushInterval = time.Second
+	DefaultExportTimeout       = 10 * time.Second
+)
+
+var errExportQueueFull = errors.New("The log export queue is full")
+
+// ExportOptions configures the shipping of log records to a remote log aggregator in addition to the
+// file or stdout output configured by Options.  Records are queued, then sent in batches by a background goroutine.
+type ExportOptions struct {
+	// Type is the kind of endpoint, either ExportLoki or ExportOTLP
+	Type string `json:"type"`
+
+	// URL is the full URL of the endpoint's push API
+	URL string `json:"url"`
+
+	// Labels are attached to every record.  For Loki, these are the stream labels and should have low cardinality.
+	// For OTLP, these are the resource attributes, e.g. service.name.
+	Labels map[string]string `json:"labels" mapstructure:"-"`
+
+	// Header holds any additional HTTP headers sent with each batch, such as Authorization or X-Scope-OrgID
+	Header map[string]string `json:"header"`
+
+	// BatchSize is the maximum number of records sent in one request.  If unset, DefaultExportBatchSize is used.
+	BatchSize int `json:"batchSize"`
+
+	// QueueSize is the maximum number of records waiting to be sent.  If unset, DefaultExportQueueSize is used.
+	QueueSize int `json:"queueSize"`
+
+	// FlushInterval is the longest time a record waits for a batch to fill before being sent.
+	// If unset, DefaultExportFlushInterval is used.
+	FlushInterval time.Duration `json:"flushInterval"`
+
+	// Timeout is the HTTP client timeout for each request.  If unset, DefaultExportTimeout is used.
+	Timeout time.Duration `json:"timeout"`
+
+	// Block controls backpressure when the queue is full.  If true, logging blocks until there is room in the queue.
+	// The default is to drop records, so that an unavailable endpoint never stalls the application.
+	Block bool `json:"block"`
+
+	// Dropped is an optional counter incremented for each record that is discarded, either because
+	// the queue was full or because the batch containing it could not be sent
+	Dropped metrics.Counter `json:"-"`
+}
+
+func (eo *ExportOptions) batchSize() int {
+	if eo.BatchSize > 0 {
+		return eo.BatchSize
+	}
+
+	return DefaultExportBatchSize
+}
+
+func (eo *ExportOptions) queueSize() int {
+	if eo.QueueSize > 0 {
+		return eo.QueueSize
+	}
+
+	return DefaultExportQueueSize
+}
+
+func (eo *ExportOptions) flushInterval() time.Duration {
+	if eo.FlushInterval > 0 {
+		return eo.FlushInterval
+	}
+
+	return DefaultExportFlushInterval
+}
+
+func (eo *ExportOptions) timeout() time.Duration {
+	if eo.Timeout > 0 {
+		return eo.Timeout
+	}
+
+	return DefaultExportTimeout
+}
+
+// exportRecord is a single log entry, rendered at the time it was logged
+type exportRecord struct {
+	timestamp time.Time
+	level     string
+	message   string
+
+	// line is the JSON rendering of the entry's key/value pairs
+	line string
+
+	// attributes are the entry's key/value pairs, other than the level and message, as strings
+	attributes [][2]string
+}
+
+// newExportRecord renders a set of key/value pairs.  Values are rendered immediately, since
+// they may be modified by the application after Log returns.
+func newExportRecord(timestamp time.Time, keyvals []interface{}) (exportRecord, error) {
+	var line bytes.Buffer
+	if err := log.NewJSONLogger(&line).Log(keyvals...); err != nil {
+		return exportRecord{}, err
+	}
+
+	r := exportRecord{
+		timestamp: timestamp,
+		line:      strings.TrimSuffix(line.String(), "\n"),
+	}
+
+	for i := 0; i < len(keyvals); i += 2 {
+		var (
+			key   = fmt.Sprint(keyvals[i])
+			value interface{}
+		)
+
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		} else {
+			value = log.ErrMissingValue
+		}
+
+		switch keyvals[i] {
+		case level.Key():
+			r.level = fmt.Sprint(value)
+
+		case MessageKey():
+			r.message = fmt.Sprint(value)
+
+		default:
+			r.attributes = append(r.attributes, [2]string{key, fmt.Sprint(value)})
+		}
+	}
+
+	return r, nil
+}
+
+// exportEncoder produces the HTTP request body for a batch of records
+type exportEncoder func(labels map[string]string, batch []exportRecord) ([]byte, error)
+
+// exporter is a go-kit Logger that queues records for a background goroutine, which sends them in batches
+type exporter struct {
+	url      string
+	header   http.Header
+	labels   map[string]string
+	encode   exportEncoder
+	client   *http.Client
+	block    bool
+	dropped  metrics.Counter
+	errorLog log.Logger
+	now      func() time.Time
+
+	batchSize     int
+	flushInterval time.Duration
+
+	queue    chan exportRecord
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func (e *exporter) drop(count int) {
+	if e.dropped != nil {
+		e.dropped.Add(float64(count))
+	}
+}
+
+func (e *exporter) Log(keyvals ...interface{}) error {
+	select {
+	case <-e.stop:
+		e.drop(1)
+		return nil
+	default:
+	}
+
+	r, err := newExportRecord(e.now(), keyvals)
+	if err != nil {
+		return err
+	}
+
+	if e.block {
+		select {
+		case e.queue <- r:
+			return nil
+		case <-e.stop:
+			e.drop(1)
+			return nil
+		}
+	}
+
+	select {
+	case e.queue <- r:
+		return nil
+	default:
+		e.drop(1)
+		return errExportQueueFull
+	}
+}
+
+// send posts a batch to the endpoint.  Failed batches are dropped rather than retried, so that a struggling
+// endpoint is not overwhelmed when it recovers.
+func (e *exporter) send(batch []exportRecord) {
+	body, err := e.encode(e.labels, batch)
+	if err == nil {
+		var request *http.Request
+		request, err = http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+		if err == nil {
+			for name, values := range e.header {
+				request.Header[name] = values
+			}
+
+			var response *http.Response
+			response, err = e.client.Do(request)
+			if err == nil {
+				response.Body.Close()
+				if response.StatusCode < 200 || response.StatusCode > 299 {
+					err = fmt.Errorf("The log export endpoint responded with status %d", response.StatusCode)
+				}
+			}
+		}
+	}
+
+	if err != nil {
+		e.drop(len(batch))
+		e.errorLog.Log(level.Key(), level.ErrorValue(), MessageKey(), "unable to export log records", "count", len(batch), ErrorKey(), err)
+	}
+}
+
+func (e *exporter) run() {
+	defer close(e.done)
+
+	var (
+		ticker = time.NewTicker(e.flushInterval)
+		batch  = make([]exportRecord, 0, e.batchSize)
+	)
+
+	defer ticker.Stop()
+
+	flush := func() {
+		if len(batch) > 0 {
+			e.send(batch)
+			batch = make([]exportRecord, 0, e.batchSize)
+		}
+	}
+
+	for {
+		select {
+		case r := <-e.queue:
+			batch = append(batch, r)
+			if len(batch) >= e.batchSize {
+				flush()
+			}
+
+		case <-ticker.C:
+			flush()
+
+		case <-e.stop:
+			// drain whatever has already been queued, then send the final batches
+			for {
+				select {
+				case r := <-e.queue:
+					batch = append(batch, r)
+					if len(batch) >= e.batchSize {
+						flush()
+					}
+
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// Close stops the background goroutine after sending any queued records.  Records logged
+// after Close are dropped.  This method is idempotent.
+func (e *exporter) Close() error {
+	e.stopOnce.Do(func() {
+		close(e.stop)
+	})
+
+	<-e.done
+	return nil
+}
+
+// ExportLogger is a go-kit Logger that ships records to a remote endpoint.  Close should be called before
+// the application exits, so that queued records are sent.
+type ExportLogger interface {
+	log.Logger
+	Close() error
+}
+
+// NewExporter creates a go-kit Logger which ships each log record to a Loki or OTLP endpoint.  Errors encountered
+// while sending are written to errorLog, which must not itself be an exporter for the same endpoint.  If errorLog
+// is nil, DefaultLogger is used.
+//
+// The returned logger does not filter or add timestamps.  Most code should use Options.Export instead, which tees
+// the output of New into an exporter.
+func NewExporter(o ExportOptions, errorLog log.Logger) (ExportLogger, error) {
+	var encode exportEncoder
+	switch strings.ToLower(o.Type) {
+	case ExportLoki:
+		encode = encodeLoki
+
+	case ExportOTLP:
+		encode = encodeOTLP
+
+	default:
+		return nil, fmt.Errorf("Invalid log export type: %s", o.Type)
+	}
+
+	u, err := url.Parse(o.URL)
+	if err != nil {
+		return nil, err
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("Invalid log export URL: %s", o.URL)
+	}
+
+	if errorLog == nil {
+		errorLog = DefaultLogger()
+	}
+
+	header := http.Header{"Content-Type": {"application/json"}}
+	for name, value := range o.Header {
+		header.Set(name, value)
+	}
+
+	e := &exporter{
+		url:           u.String(),
+		header:        header,
+		labels:        o.Labels,
+		encode:        encode,
+		client:        &http.Client{Timeout: o.timeout()},
+		block:         o.Block,
+		dropped:       o.Dropped,
+		errorLog:      errorLog,
+		now:           time.Now,
+		batchSize:     o.batchSize(),
+		flushInterval: o.flushInterval(),
+		queue:         make(chan exportRecord, o.queueSize()),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+
+	go e.run()
+	return e, nil
+}
+
+// teeLogger dispatches each log entry to multiple loggers.  The first error is returned,
+// but every logger is always invoked.
+type teeLogger []log.Logger
+
+func (tl teeLogger) Log(keyvals ...interface{}) error {
+	var first error
+	for _, l := range tl {
+		if err := l.Log(keyvals...); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+// withExport tees the given logger into an exporter when export is configured.  A misconfigured
+// export is reported to the given logger rather than preventing logging altogether.
+func (o *Options) withExport(next log.Logger) log.Logger {
+	if o == nil || o.Export == nil {
+		return next
+	}
+
+	e, err := NewExporter(*o.Export, next)
+	if err != nil {
+		next.Log(level.Key(), level.ErrorValue(), MessageKey(), "unable to create log exporter", ErrorKey(), err)
+		return next
+	}
+
+	registerCloser(e)
+	return teeLogger{next, e}
+}
@@ -0,0 +1,128 @@
+package logging
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// lokiPush is the body of a Loki push API request
+type lokiPush struct {
+	Streams []lokiStream `json:"streams"`
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// encodeLoki sends each batch as a single stream.  The line of each record is its JSON rendering, which
+// Loki's json parser can extract fields from at query time.
+func encodeLoki(labels map[string]string, batch []exportRecord) ([]byte, error) {
+	stream := lokiStream{
+		Stream: labels,
+		Values: make([][2]string, len(batch)),
+	}
+
+	if stream.Stream == nil {
+		stream.Stream = map[string]string{}
+	}
+
+	for i, r := range batch {
+		stream.Values[i] = [2]string{strconv.FormatInt(r.timestamp.UnixNano(), 10), r.line}
+	}
+
+	return json.Marshal(lokiPush{Streams: []lokiStream{stream}})
+}
+
+// otlpLogs is the body of an OTLP/HTTP logs request, using the JSON protobuf mapping
+type otlpLogs struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano   string          `json:"timeUnixNano"`
+	SeverityNumber int             `json:"severityNumber,omitempty"`
+	SeverityText   string          `json:"severityText,omitempty"`
+	Body           otlpValue       `json:"body"`
+	Attributes     []otlpAttribute `json:"attributes,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+// otlpSeverity maps go-kit levels onto the base OTLP severity number of each range
+func otlpSeverity(lvl string) int {
+	switch strings.ToUpper(lvl) {
+	case "DEBUG":
+		return 5
+
+	case "INFO":
+		return 9
+
+	case "WARN":
+		return 13
+
+	case "ERROR":
+		return 17
+
+	default:
+		return 0
+	}
+}
+
+// encodeOTLP sends each batch as a single resource and scope.  The message becomes the body of each
+// record, and the remaining key/value pairs become attributes.
+func encodeOTLP(labels map[string]string, batch []exportRecord) ([]byte, error) {
+	resource := otlpResource{Attributes: make([]otlpAttribute, 0, len(labels))}
+	for k, v := range labels {
+		resource.Attributes = append(resource.Attributes, otlpAttribute{Key: k, Value: otlpValue{StringValue: v}})
+	}
+
+	sort.Slice(resource.Attributes, func(i, j int) bool {
+		return resource.Attributes[i].Key < resource.Attributes[j].Key
+	})
+
+	records := make([]otlpLogRecord, len(batch))
+	for i, r := range batch {
+		records[i] = otlpLogRecord{
+			TimeUnixNano:   strconv.FormatInt(r.timestamp.UnixNano(), 10),
+			SeverityNumber: otlpSeverity(r.level),
+			SeverityText:   strings.ToUpper(r.level),
+			Body:           otlpValue{StringValue: r.message},
+		}
+
+		for _, a := range r.attributes {
+			records[i].Attributes = append(records[i].Attributes, otlpAttribute{Key: a[0], Value: otlpValue{StringValue: a[1]}})
+		}
+	}
+
+	return json.Marshal(otlpLogs{
+		ResourceLogs: []otlpResourceLogs{
+			{
+				Resource:  resource,
+				ScopeLogs: []otlpScopeLogs{{LogRecords: records}},
+			},
+		},
+	})
+}
@@ -0,0 +1,324 @@
+package logging
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exportRequest is a request received by an exportServer
+type exportRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// newExportServer starts a server that records each request and responds with the given status code
+func newExportServer(status int) (*httptest.Server, <-chan exportRequest) {
+	requests := make(chan exportRequest, 100)
+	server := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		body, _ := ioutil.ReadAll(request.Body)
+		requests <- exportRequest{header: request.Header, body: body}
+		response.WriteHeader(status)
+	}))
+
+	return server, requests
+}
+
+func TestNewExporterInvalid(t *testing.T) {
+	assert := assert.New(t)
+
+	e, err := NewExporter(ExportOptions{Type: "splunk", URL: "http://localhost/"}, nil)
+	assert.Nil(e)
+	assert.Error(err)
+
+	e, err = NewExporter(ExportOptions{Type: ExportLoki, URL: "localhost:3100"}, nil)
+	assert.Nil(e)
+	assert.Error(err)
+
+	e, err = NewExporter(ExportOptions{Type: ExportOTLP, URL: "%%"}, nil)
+	assert.Nil(e)
+	assert.Error(err)
+}
+
+func testNewExporterLoki(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		server, requests = newExportServer(http.StatusNoContent)
+	)
+
+	defer server.Close()
+	e, err := NewExporter(
+		ExportOptions{
+			Type:          ExportLoki,
+			URL:           server.URL + "/loki/api/v1/push",
+			Labels:        map[string]string{"app": "talaria"},
+			Header:        map[string]string{"X-Scope-OrgID": "xmidt"},
+			BatchSize:     2,
+			FlushInterval: time.Hour,
+		},
+		nil,
+	)
+
+	require.NoError(err)
+	require.NotNil(e)
+
+	assert.NoError(e.Log(level.Key(), level.InfoValue(), MessageKey(), "first"))
+	assert.NoError(e.Log(level.Key(), level.InfoValue(), MessageKey(), "second", "count", 2))
+	assert.NoError(e.Log(level.Key(), level.ErrorValue(), MessageKey(), "third"))
+	assert.NoError(e.Close())
+	assert.NoError(e.Close())
+
+	// logging after Close is harmless
+	assert.NoError(e.Log(MessageKey(), "dropped"))
+
+	var pushes []lokiPush
+	for len(requests) > 0 {
+		r := <-requests
+		assert.Equal("application/json", r.header.Get("Content-Type"))
+		assert.Equal("xmidt", r.header.Get("X-Scope-OrgID"))
+
+		var push lokiPush
+		require.NoError(json.Unmarshal(r.body, &push))
+		pushes = append(pushes, push)
+	}
+
+	require.Len(pushes, 2)
+	require.Len(pushes[0].Streams, 1)
+	assert.Equal(map[string]string{"app": "talaria"}, pushes[0].Streams[0].Stream)
+	require.Len(pushes[0].Streams[0].Values, 2)
+	assert.NotEmpty(pushes[0].Streams[0].Values[0][0])
+
+	var line map[string]interface{}
+	require.NoError(json.Unmarshal([]byte(pushes[0].Streams[0].Values[1][1]), &line))
+	assert.Equal(map[string]interface{}{"level": "info", "msg": "second", "count": 2.0}, line)
+
+	require.Len(pushes[1].Streams, 1)
+	require.Len(pushes[1].Streams[0].Values, 1)
+	assert.Contains(pushes[1].Streams[0].Values[0][1], `"msg":"third"`)
+}
+
+func testNewExporterOTLP(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		server, requests = newExportServer(http.StatusOK)
+	)
+
+	defer server.Close()
+	e, err := NewExporter(
+		ExportOptions{
+			Type:          ExportOTLP,
+			URL:           server.URL + "/v1/logs",
+			Labels:        map[string]string{"service.name": "scytale", "deployment.environment": "test"},
+			FlushInterval: 10 * time.Millisecond,
+		},
+		nil,
+	)
+
+	require.NoError(err)
+	require.NotNil(e)
+	defer e.Close()
+
+	e.Log(level.Key(), level.WarnValue(), MessageKey(), "slow response", "deviceID", "mac:112233445566")
+
+	var logs otlpLogs
+	select {
+	case r := <-requests:
+		require.NoError(json.Unmarshal(r.body, &logs))
+	case <-time.After(5 * time.Second):
+		require.Fail("No batch was sent")
+	}
+
+	require.Len(logs.ResourceLogs, 1)
+	assert.Equal(
+		[]otlpAttribute{
+			{Key: "deployment.environment", Value: otlpValue{StringValue: "test"}},
+			{Key: "service.name", Value: otlpValue{StringValue: "scytale"}},
+		},
+		logs.ResourceLogs[0].Resource.Attributes,
+	)
+
+	require.Len(logs.ResourceLogs[0].ScopeLogs, 1)
+	require.Len(logs.ResourceLogs[0].ScopeLogs[0].LogRecords, 1)
+
+	record := logs.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	assert.NotEmpty(record.TimeUnixNano)
+	assert.Equal(13, record.SeverityNumber)
+	assert.Equal("WARN", record.SeverityText)
+	assert.Equal("slow response", record.Body.StringValue)
+	assert.Equal(
+		[]otlpAttribute{{Key: "deviceID", Value: otlpValue{StringValue: "mac:112233445566"}}},
+		record.Attributes,
+	)
+}
+
+func testNewExporterFailure(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		server, _ = newExportServer(http.StatusInternalServerError)
+		dropped   = generic.NewCounter("dropped")
+		errorLog  = NewCaptureLogger()
+	)
+
+	defer server.Close()
+	e, err := NewExporter(
+		ExportOptions{
+			Type:          ExportLoki,
+			URL:           server.URL,
+			FlushInterval: time.Hour,
+			Dropped:       dropped,
+		},
+		errorLog,
+	)
+
+	require.NoError(err)
+	e.Log(MessageKey(), "first")
+	e.Log(MessageKey(), "second")
+	e.Close()
+
+	assert.Equal(2.0, dropped.Value())
+	select {
+	case entry := <-errorLog.Output():
+		assert.Equal(level.ErrorValue(), entry[level.Key()])
+		assert.Equal(2, entry["count"])
+		assert.Contains(entry[ErrorKey()].(error).Error(), "500")
+	default:
+		assert.Fail("The failure was not logged")
+	}
+}
+
+func testExporterQueueFull(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		dropped = generic.NewCounter("dropped")
+
+		// no goroutine services this exporter, so the queue fills immediately
+		e = &exporter{
+			dropped: dropped,
+			now:     time.Now,
+			queue:   make(chan exportRecord, 1),
+			stop:    make(chan struct{}),
+		}
+	)
+
+	assert.NoError(e.Log(MessageKey(), "queued"))
+	assert.Equal(errExportQueueFull, e.Log(MessageKey(), "dropped"))
+	assert.Equal(1.0, dropped.Value())
+}
+
+func testExporterBlock(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		e      = &exporter{
+			block: true,
+			now:   time.Now,
+			queue: make(chan exportRecord, 1),
+			stop:  make(chan struct{}),
+		}
+
+		logged = make(chan error, 1)
+	)
+
+	assert.NoError(e.Log(MessageKey(), "queued"))
+	go func() {
+		logged <- e.Log(MessageKey(), "blocked")
+	}()
+
+	select {
+	case <-logged:
+		assert.Fail("Log should block while the queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	r := <-e.queue
+	assert.Equal("queued", r.message)
+
+	select {
+	case err := <-logged:
+		assert.NoError(err)
+	case <-time.After(5 * time.Second):
+		assert.Fail("Log did not unblock")
+	}
+
+	assert.Equal("blocked", (<-e.queue).message)
+}
+
+func TestNewExporter(t *testing.T) {
+	t.Run("Loki", testNewExporterLoki)
+	t.Run("OTLP", testNewExporterOTLP)
+	t.Run("Failure", testNewExporterFailure)
+	t.Run("QueueFull", testExporterQueueFull)
+	t.Run("Block", testExporterBlock)
+}
+
+func testNewExport(t *testing.T, factory func(*Options) string) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		server, requests = newExportServer(http.StatusNoContent)
+	)
+
+	defer server.Close()
+	output := factory(&Options{
+		Level: "INFO",
+		Export: &ExportOptions{
+			Type:          ExportLoki,
+			URL:           server.URL,
+			FlushInterval: 10 * time.Millisecond,
+		},
+	})
+
+	assert.Contains(output, "exported")
+
+	select {
+	case r := <-requests:
+		var push lokiPush
+		require.NoError(json.Unmarshal(r.body, &push))
+		require.Len(push.Streams, 1)
+		require.Len(push.Streams[0].Values, 1)
+		assert.Contains(push.Streams[0].Values[0][1], `"msg":"exported"`)
+		assert.NotContains(push.Streams[0].Values[0][1], "filtered")
+	case <-time.After(5 * time.Second):
+		require.Fail("No batch was sent")
+	}
+}
+
+func testNewExportInvalid(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		output strings.Builder
+		logger = newZap(&Options{Export: &ExportOptions{Type: "unknown"}}, &output)
+	)
+
+	assert.NotNil(logger)
+	assert.Contains(output.String(), "unable to create log exporter")
+}
+
+func TestOptionsExport(t *testing.T) {
+	t.Run("Zap", func(t *testing.T) {
+		testNewExport(t, func(o *Options) string {
+			var output strings.Builder
+			logger := newZap(o, &output)
+			Debug(logger).Log(MessageKey(), "filtered")
+			Info(logger).Log(MessageKey(), "exported")
+			return output.String()
+		})
+	})
+
+	t.Run("Invalid", testNewExportInvalid)
+}
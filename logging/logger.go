@@ -45,7 +45,10 @@ func DefaultLogger() log.Logger {
 
 // New creates a go-kit Logger from a set of options.  The options object can be nil,
 // in which case a default logger that logs to os.Stdout is returned.  The returned logger
-// includes the timestamp in UTC format and will filter according to the Level field.
+// includes the timestamp in UTC format and will filter according to the Level field.  If Export
+// is configured, each record is also shipped to the configured endpoint.  If Redact is configured,
+// sensitive values are masked in all output.  If either Async or Export is configured, Close should
+// be called before the application exits so that buffered records are not lost.
 //
 // In order to allow arbitrary decoration, this function does not insert the caller information.
 // Use either DefaultCaller in this package or the go-kit/kit/log API to add a Caller to the
@@ -53,7 +56,7 @@ func DefaultLogger() log.Logger {
 func New(o *Options) log.Logger {
 	return NewFilter(
 		log.WithPrefix(
-			o.loggerFactory()(o.output()),
+			o.withRedact(o.withExport(o.loggerFactory()(o.output()))),
 			TimestampKey(), log.DefaultTimestampUTC,
 		),
 		o,
@@ -61,7 +64,12 @@ func New(o *Options) log.Logger {
 }
 
 // NewFilter applies the Options filtering rules in the package to an arbitrary go-kit Logger.
+// If any Modules are configured, the level of each log entry is checked against the level for its module.
 func NewFilter(next log.Logger, o *Options) log.Logger {
+	if modules := o.modules(); len(modules) > 0 {
+		return newModuleFilter(next, o.level(), modules)
+	}
+
 	switch strings.ToUpper(o.level()) {
 	case "DEBUG":
 		return level.NewFilter(next, level.AllowDebug())
@@ -0,0 +1,277 @@
+package logginghttp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/spf13/cast"
+	"github.com/xmidt-org/bascule"
+	"github.com/xmidt-org/webpa-common/basculechecks"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+const (
+	// DeviceNameHeader is the header carrying the device name, which is the same as device.DeviceNameHeader
+	DeviceNameHeader = "X-Webpa-Device-Name"
+
+	accessLogMessage   = "request"
+	slowRequestMessage = "slow request"
+)
+
+var (
+	statusKey     interface{} = "status"
+	bytesKey      interface{} = "bytes"
+	latencyKey    interface{} = "latency"
+	deviceNameKey interface{} = "deviceName"
+	partnerIDKey  interface{} = "partnerID"
+
+	errHijackNotSupported = errors.New("The underlying ResponseWriter does not support hijacking")
+)
+
+// StatusKey returns the access log key for the response status code
+func StatusKey() interface{} {
+	return statusKey
+}
+
+// BytesKey returns the access log key for the number of response body bytes written
+func BytesKey() interface{} {
+	return bytesKey
+}
+
+// LatencyKey returns the access log key for the time taken to serve a request
+func LatencyKey() interface{} {
+	return latencyKey
+}
+
+// DeviceNameKey returns the logging key for the device name header
+func DeviceNameKey() interface{} {
+	return deviceNameKey
+}
+
+// PartnerIDKey returns the logging key for the partner IDs of the request's bascule token
+func PartnerIDKey() interface{} {
+	return partnerIDKey
+}
+
+// DeviceName is a LoggerFunc that adds the value of DeviceNameHeader, which is blank if the header is not present.
+func DeviceName(kv []interface{}, request *http.Request) []interface{} {
+	return append(kv, deviceNameKey, request.Header.Get(DeviceNameHeader))
+}
+
+// PartnerID is a LoggerFunc that adds the partner IDs from the bascule token in the request's context.  Nothing is
+// added if the request has not been authenticated, which means that an access log must be decorated by the bascule
+// middleware to use this function.  Alternatively, handlers may use AddFields to contribute the partner IDs.
+func PartnerID(kv []interface{}, request *http.Request) []interface{} {
+	auth, ok := bascule.FromContext(request.Context())
+	if !ok || auth.Token == nil || auth.Token.Attributes() == nil {
+		return kv
+	}
+
+	if partners, ok := bascule.GetNestedAttribute(auth.Token.Attributes(), basculechecks.PartnerKeys()...); ok {
+		if partnerIDs, err := cast.ToStringSliceE(partners); err == nil {
+			return append(kv, partnerIDKey, partnerIDs)
+		}
+	}
+
+	return kv
+}
+
+// ResponseInfo describes the outcome of serving a request
+type ResponseInfo struct {
+	// Code is the status code written by the handler, which is http.StatusOK if the handler didn't write one
+	Code int
+
+	// Bytes is the number of response body bytes written
+	Bytes int64
+
+	// Latency is the time taken to serve the request
+	Latency time.Duration
+}
+
+// ResponseFunc is a strategy for adding key/value pairs based on the outcome of serving an HTTP request.
+// As with LoggerFunc, functions of this type must append key/value pairs to the supplied slice.
+type ResponseFunc func([]interface{}, ResponseInfo) []interface{}
+
+// Status is a ResponseFunc that adds the response status code
+func Status(kv []interface{}, ri ResponseInfo) []interface{} {
+	return append(kv, statusKey, ri.Code)
+}
+
+// Bytes is a ResponseFunc that adds the number of response body bytes written
+func Bytes(kv []interface{}, ri ResponseInfo) []interface{} {
+	return append(kv, bytesKey, ri.Bytes)
+}
+
+// Latency is a ResponseFunc that adds the time taken to serve the request
+func Latency(kv []interface{}, ri ResponseInfo) []interface{} {
+	return append(kv, latencyKey, ri.Latency)
+}
+
+// AccessLogOptions configures the access log decorator produced by NewAccessLog
+type AccessLogOptions struct {
+	// Logger is the logger to which each access log record is written.  This field is required.
+	Logger log.Logger
+
+	// RequestFields select the request information in each record.  If unset, RequestInfo is used.
+	RequestFields []LoggerFunc
+
+	// ResponseFields select the response information in each record.  If unset, Status, Bytes, and Latency are used.
+	ResponseFields []ResponseFunc
+
+	// Sampling optionally samples access log records.  Records are sampled separately for each level, so that
+	// errors and slow requests are not crowded out by successful requests.
+	Sampling *logging.SamplingOptions
+
+	// SlowThreshold is the latency above which requests are logged at the warn level, using a distinct message.
+	// If unset, requests are never considered slow.
+	SlowThreshold time.Duration
+
+	// Now is the optional clock used to compute latency.  If unset, time.Now is used.
+	Now func() time.Time
+}
+
+// accessLogFieldsKey is the context key for the fields contributed by AddFields
+type accessLogFieldsKey struct{}
+
+// accessLogFields holds the key/value pairs added while a request is served
+type accessLogFields struct {
+	lock sync.Mutex
+	kv   []interface{}
+}
+
+// AddFields adds key/value pairs to the access log record of the request that the context belongs to.  This
+// allows handlers to log information that is only known after the access log decorator has run, such as the
+// partner IDs of an authenticated request.  If the request isn't being access logged, this function does nothing.
+func AddFields(ctx context.Context, keyvals ...interface{}) {
+	if fields, ok := ctx.Value(accessLogFieldsKey{}).(*accessLogFields); ok {
+		fields.lock.Lock()
+		fields.kv = append(fields.kv, keyvals...)
+		fields.lock.Unlock()
+	}
+}
+
+// accessLogWriter records the status code and body length of a response
+type accessLogWriter struct {
+	http.ResponseWriter
+	code  int
+	bytes int64
+}
+
+func (alw *accessLogWriter) WriteHeader(code int) {
+	if alw.code == 0 {
+		alw.code = code
+	}
+
+	alw.ResponseWriter.WriteHeader(code)
+}
+
+func (alw *accessLogWriter) Write(p []byte) (int, error) {
+	if alw.code == 0 {
+		alw.code = http.StatusOK
+	}
+
+	n, err := alw.ResponseWriter.Write(p)
+	alw.bytes += int64(n)
+	return n, err
+}
+
+func (alw *accessLogWriter) Flush() {
+	if f, ok := alw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (alw *accessLogWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := alw.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+
+	return nil, nil, errHijackNotSupported
+}
+
+// NewAccessLog produces an Alice-style decorator that writes one structured record for each request.  Requests
+// that result in a 5xx status are logged at the error level, requests slower than SlowThreshold at the warn
+// level, and all other requests at the info level.  As with SetLogger, the default redaction rules are applied.
+func NewAccessLog(o AccessLogOptions) func(http.Handler) http.Handler {
+	if o.Logger == nil {
+		panic("The access log Logger cannot be nil")
+	}
+
+	var (
+		logger         = logging.Redact(o.Logger)
+		requestFields  = o.RequestFields
+		responseFields = o.ResponseFields
+		now            = o.Now
+	)
+
+	if o.Sampling != nil {
+		logger = logging.NewSampler(logger, *o.Sampling)
+	}
+
+	if len(requestFields) == 0 {
+		requestFields = []LoggerFunc{RequestInfo}
+	}
+
+	if len(responseFields) == 0 {
+		responseFields = []ResponseFunc{Status, Bytes, Latency}
+	}
+
+	if now == nil {
+		now = time.Now
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			var (
+				start  = now()
+				writer = &accessLogWriter{ResponseWriter: response}
+				fields = new(accessLogFields)
+			)
+
+			kv := []interface{}{}
+			for _, f := range requestFields {
+				kv = f(kv, request)
+			}
+
+			next.ServeHTTP(writer, request.WithContext(context.WithValue(request.Context(), accessLogFieldsKey{}, fields)))
+
+			ri := ResponseInfo{
+				Code:    writer.code,
+				Bytes:   writer.bytes,
+				Latency: now().Sub(start),
+			}
+
+			if ri.Code == 0 {
+				ri.Code = http.StatusOK
+			}
+
+			for _, f := range responseFields {
+				kv = f(kv, ri)
+			}
+
+			fields.lock.Lock()
+			kv = append(kv, fields.kv...)
+			fields.lock.Unlock()
+
+			switch {
+			case ri.Code >= 500:
+				kv = append(kv, level.Key(), level.ErrorValue(), logging.MessageKey(), accessLogMessage)
+
+			case o.SlowThreshold > 0 && ri.Latency > o.SlowThreshold:
+				kv = append(kv, level.Key(), level.WarnValue(), logging.MessageKey(), slowRequestMessage)
+
+			default:
+				kv = append(kv, level.Key(), level.InfoValue(), logging.MessageKey(), accessLogMessage)
+			}
+
+			logger.Log(kv...)
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package logginghttp
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/bascule"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+func TestAccessLogKeys(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(statusKey, StatusKey())
+	assert.Equal(bytesKey, BytesKey())
+	assert.Equal(latencyKey, LatencyKey())
+	assert.Equal(deviceNameKey, DeviceNameKey())
+	assert.Equal(partnerIDKey, PartnerIDKey())
+}
+
+func TestDeviceName(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		request = httptest.NewRequest("GET", "/", nil)
+	)
+
+	assert.Equal([]interface{}{deviceNameKey, ""}, DeviceName(nil, request))
+
+	request.Header.Set(DeviceNameHeader, "mac:112233445566")
+	assert.Equal([]interface{}{deviceNameKey, "mac:112233445566"}, DeviceName(nil, request))
+}
+
+func TestPartnerID(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		request = httptest.NewRequest("GET", "/", nil)
+	)
+
+	assert.Empty(PartnerID(nil, request))
+
+	request = request.WithContext(bascule.WithAuthentication(request.Context(), bascule.Authentication{}))
+	assert.Empty(PartnerID(nil, request))
+
+	token := bascule.NewToken("jwt", "client", bascule.NewAttributes(map[string]interface{}{
+		"allowedResources": map[string]interface{}{
+			"allowedPartners": []string{"comcast", "sky"},
+		},
+	}))
+
+	request = request.WithContext(bascule.WithAuthentication(request.Context(), bascule.Authentication{Token: token}))
+	assert.Equal([]interface{}{partnerIDKey, []string{"comcast", "sky"}}, PartnerID(nil, request))
+}
+
+func testNewAccessLogNilLogger(t *testing.T) {
+	assert := assert.New(t)
+	assert.Panics(func() {
+		NewAccessLog(AccessLogOptions{})
+	})
+}
+
+// steppingClock returns a clock that advances by step each time it is called
+func steppingClock(step time.Duration) func() time.Time {
+	current := time.Unix(1000, 0)
+	return func() time.Time {
+		now := current
+		current = current.Add(step)
+		return now
+	}
+}
+
+func testNewAccessLogDefaults(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		logger    = logging.NewCaptureLogger()
+		decorator = NewAccessLog(AccessLogOptions{Logger: logger, Now: steppingClock(50 * time.Millisecond)})
+		handler   = decorator(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			AddFields(request.Context(), "partnerID", "comcast")
+			response.WriteHeader(http.StatusAccepted)
+			response.Write([]byte("hello"))
+		}))
+
+		request  = httptest.NewRequest("POST", "/api/v2/device", nil)
+		response = httptest.NewRecorder()
+	)
+
+	require.NotNil(handler)
+	request.RemoteAddr = "10.0.0.1:7777"
+	handler.ServeHTTP(response, request)
+	assert.Equal(http.StatusAccepted, response.Code)
+
+	entry := <-logger.Output()
+	assert.Equal(level.InfoValue(), entry[level.Key()])
+	assert.Equal("request", entry[logging.MessageKey()])
+	assert.Equal("POST", entry[requestMethodKey])
+	assert.Equal("/api/v2/device", entry[requestURIKey])
+	assert.Equal("10.0.0.1:7777", entry[remoteAddrKey])
+	assert.Equal(http.StatusAccepted, entry[statusKey])
+	assert.Equal(int64(5), entry[bytesKey])
+	assert.Equal(50*time.Millisecond, entry[latencyKey])
+	assert.Equal("comcast", entry["partnerID"])
+}
+
+func testNewAccessLogCustom(t *testing.T) {
+	var (
+		assert = assert.New(t)
+
+		logger    = logging.NewCaptureLogger()
+		decorator = NewAccessLog(AccessLogOptions{
+			Logger:         logger,
+			RequestFields:  []LoggerFunc{DeviceName, Header("Authorization", "auth")},
+			ResponseFields: []ResponseFunc{Status},
+		})
+
+		handler = decorator(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			response.Write([]byte("implicit status"))
+		}))
+
+		request = httptest.NewRequest("GET", "/", nil)
+	)
+
+	request.Header.Set(DeviceNameHeader, "mac:112233445566")
+	request.Header.Set("Authorization", "Bearer secret")
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+
+	entry := <-logger.Output()
+	assert.Equal(
+		map[interface{}]interface{}{
+			deviceNameKey:        "mac:112233445566",
+			"auth":               logging.RedactedValue,
+			statusKey:            http.StatusOK,
+			level.Key():          level.InfoValue(),
+			logging.MessageKey(): "request",
+		},
+		entry,
+	)
+}
+
+func testNewAccessLogLevels(t *testing.T) {
+	var (
+		assert = assert.New(t)
+
+		logger    = logging.NewCaptureLogger()
+		decorator = NewAccessLog(AccessLogOptions{
+			Logger:        logger,
+			SlowThreshold: time.Second,
+			Now:           steppingClock(2 * time.Second),
+		})
+	)
+
+	decorator(http.HandlerFunc(func(response http.ResponseWriter, _ *http.Request) {
+		response.WriteHeader(http.StatusServiceUnavailable)
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	entry := <-logger.Output()
+	assert.Equal(level.ErrorValue(), entry[level.Key()])
+	assert.Equal("request", entry[logging.MessageKey()])
+
+	decorator(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})).
+		ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	entry = <-logger.Output()
+	assert.Equal(level.WarnValue(), entry[level.Key()])
+	assert.Equal("slow request", entry[logging.MessageKey()])
+	assert.Equal(http.StatusOK, entry[statusKey])
+	assert.Equal(int64(0), entry[bytesKey])
+}
+
+func testNewAccessLogSampling(t *testing.T) {
+	var (
+		assert = assert.New(t)
+
+		logger    = logging.NewCaptureLogger()
+		decorator = NewAccessLog(AccessLogOptions{
+			Logger:   logger,
+			Sampling: &logging.SamplingOptions{Initial: 2, Thereafter: 100, Interval: time.Hour},
+		})
+
+		ok     = decorator(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+		failed = decorator(http.HandlerFunc(func(response http.ResponseWriter, _ *http.Request) {
+			response.WriteHeader(http.StatusInternalServerError)
+		}))
+	)
+
+	for i := 0; i < 5; i++ {
+		ok.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}
+
+	failed.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	var levels []interface{}
+	for len(logger.Output()) > 0 {
+		levels = append(levels, (<-logger.Output())[level.Key()])
+	}
+
+	assert.Equal([]interface{}{level.InfoValue(), level.InfoValue(), level.ErrorValue()}, levels)
+}
+
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (hr *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hr.hijacked = true
+	return nil, nil, nil
+}
+
+func testNewAccessLogWriter(t *testing.T) {
+	var (
+		assert = assert.New(t)
+
+		logger    = logging.NewCaptureLogger()
+		decorator = NewAccessLog(AccessLogOptions{Logger: logger})
+		recorder  = &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	)
+
+	decorator(http.HandlerFunc(func(response http.ResponseWriter, _ *http.Request) {
+		response.(http.Flusher).Flush()
+		_, _, err := response.(http.Hijacker).Hijack()
+		assert.NoError(err)
+	})).ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+
+	<-logger.Output()
+	assert.True(recorder.Flushed)
+	assert.True(recorder.hijacked)
+
+	decorator(http.HandlerFunc(func(response http.ResponseWriter, _ *http.Request) {
+		_, _, err := response.(http.Hijacker).Hijack()
+		assert.Equal(errHijackNotSupported, err)
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	<-logger.Output()
+}
+
+func TestNewAccessLog(t *testing.T) {
+	t.Run("NilLogger", testNewAccessLogNilLogger)
+	t.Run("Defaults", testNewAccessLogDefaults)
+	t.Run("Custom", testNewAccessLogCustom)
+	t.Run("Levels", testNewAccessLogLevels)
+	t.Run("Sampling", testNewAccessLogSampling)
+	t.Run("Writer", testNewAccessLogWriter)
+}
+
+func TestAddFieldsNoAccessLog(t *testing.T) {
+	assert := assert.New(t)
+	assert.NotPanics(func() {
+		AddFields(context.Background(), "key", "value")
+	})
+}
@@ -87,6 +87,9 @@ func PathVariable(variableName, keyName string) LoggerFunc {
 //
 // The base logger must be non-nil.  There is no default applied.
 //
+// When LoggerFuncs are supplied, the returned logger applies the default redaction rules of
+// logging.Redact, so that values such as Authorization headers are never written.
+//
 // The returned function can be used with xcontext.Populate.
 func SetLogger(base log.Logger, lf ...LoggerFunc) func(context.Context, *http.Request) context.Context {
 	if base == nil {
@@ -94,6 +97,7 @@ func SetLogger(base log.Logger, lf ...LoggerFunc) func(context.Context, *http.Re
 	}
 
 	if len(lf) > 0 {
+		redacted := logging.Redact(base)
 		return func(ctx context.Context, request *http.Request) context.Context {
 			kv := []interface{}{}
 			for _, f := range lf {
@@ -102,7 +106,7 @@ func SetLogger(base log.Logger, lf ...LoggerFunc) func(context.Context, *http.Re
 
 			return logging.WithLogger(
 				ctx,
-				log.With(base, kv...),
+				log.With(redacted, kv...),
 			)
 		}
 	}
@@ -195,8 +195,27 @@ func testSetLoggerCustom(t *testing.T) {
 	assert.Equal("test message", entry[logging.MessageKey()])
 }
 
+func testSetLoggerRedacted(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		request = httptest.NewRequest("GET", "/test/uri", nil)
+		base    = logging.NewCaptureLogger()
+	)
+
+	request.Header.Set("Authorization", "Basic dGVzdDp0ZXN0Cg==")
+	logger := logging.GetLogger(
+		SetLogger(base, Header("Authorization", "auth"))(context.Background(), request),
+	)
+
+	logger.Log(logging.MessageKey(), "test message")
+	entry := <-base.Output()
+	assert.Equal(logging.RedactedValue, entry["auth"])
+	assert.Equal("test message", entry[logging.MessageKey()])
+}
+
 func TestSetLogger(t *testing.T) {
 	t.Run("NilBase", testSetLoggerNilBase)
 	t.Run("BaseOnly", testSetLoggerBaseOnly)
+	t.Run("Redacted", testSetLoggerRedacted)
 	t.Run("Custom", testSetLoggerCustom)
 }
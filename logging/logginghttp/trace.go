@@ -0,0 +1,122 @@
+package logginghttp
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+const (
+	// TraceParentHeader is the W3C Trace Context header
+	TraceParentHeader = "traceparent"
+
+	// B3Header is the single header form of B3 propagation
+	B3Header = "b3"
+
+	// B3TraceIDHeader and B3SpanIDHeader are the multiple header form of B3 propagation
+	B3TraceIDHeader = "X-B3-TraceId"
+	B3SpanIDHeader  = "X-B3-SpanId"
+)
+
+// isHexID tests if v is a nonzero, lowercase hexadecimal identifier of the given length
+func isHexID(v string, length int) bool {
+	if len(v) != length {
+		return false
+	}
+
+	nonzero := false
+	for _, c := range v {
+		switch {
+		case c == '0':
+		case (c >= '1' && c <= '9') || (c >= 'a' && c <= 'f'):
+			nonzero = true
+		default:
+			return false
+		}
+	}
+
+	return nonzero
+}
+
+// parseTraceParent parses a W3C traceparent value, e.g. "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+func parseTraceParent(v string) (logging.TraceContext, bool) {
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return logging.TraceContext{}, false
+	}
+
+	if !isHexID(parts[1], 32) || !isHexID(parts[2], 16) {
+		return logging.TraceContext{}, false
+	}
+
+	return logging.TraceContext{TraceID: parts[1], SpanID: parts[2]}, true
+}
+
+// parseB3 parses a single header B3 value, e.g. "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1".
+// A value which only carries a sampling decision has no identifiers.
+func parseB3(v string) (logging.TraceContext, bool) {
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) < 2 {
+		return logging.TraceContext{}, false
+	}
+
+	traceID := strings.ToLower(parts[0])
+	if !isHexID(traceID, 16) && !isHexID(traceID, 32) {
+		return logging.TraceContext{}, false
+	}
+
+	spanID := strings.ToLower(parts[1])
+	if !isHexID(spanID, 16) {
+		return logging.TraceContext{}, false
+	}
+
+	return logging.TraceContext{TraceID: traceID, SpanID: spanID}, true
+}
+
+// ParseTraceContext extracts the trace and span IDs from a request's headers.  The W3C traceparent header
+// takes precedence, followed by the single b3 header and then the X-B3-TraceId and X-B3-SpanId headers.
+// If no header carries valid identifiers, this function returns false.
+func ParseTraceContext(h http.Header) (logging.TraceContext, bool) {
+	if v := h.Get(TraceParentHeader); len(v) > 0 {
+		if tc, ok := parseTraceParent(v); ok {
+			return tc, true
+		}
+	}
+
+	if v := h.Get(B3Header); len(v) > 0 {
+		if tc, ok := parseB3(v); ok {
+			return tc, true
+		}
+	}
+
+	if traceID := h.Get(B3TraceIDHeader); len(traceID) > 0 {
+		return parseB3(traceID + "-" + h.Get(B3SpanIDHeader))
+	}
+
+	return logging.TraceContext{}, false
+}
+
+// TraceInfo is a LoggerFunc that adds the trace and span IDs from the request's headers.  Nothing is added
+// if the request does not carry a trace.
+func TraceInfo(kv []interface{}, request *http.Request) []interface{} {
+	if tc, ok := ParseTraceContext(request.Header); ok {
+		return append(kv, logging.TraceIDKey(), tc.TraceID, logging.SpanIDKey(), tc.SpanID)
+	}
+
+	return kv
+}
+
+// SetTraceContext is a go-kit RequestFunc that stores the request's trace identifiers in the context,
+// so that logging.WithTrace can add them to loggers further down the call stack.  The context is returned
+// as is if the request does not carry a trace.
+//
+// This function can be used with xcontext.Populate.
+func SetTraceContext(ctx context.Context, request *http.Request) context.Context {
+	if tc, ok := ParseTraceContext(request.Header); ok {
+		return logging.WithTraceContext(ctx, tc)
+	}
+
+	return ctx
+}
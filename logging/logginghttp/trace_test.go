@@ -0,0 +1,131 @@
+package logginghttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+func TestParseTraceContext(t *testing.T) {
+	testData := []struct {
+		description string
+		header      http.Header
+		expected    logging.TraceContext
+		ok          bool
+	}{
+		{
+			description: "Empty",
+			header:      http.Header{},
+		},
+		{
+			description: "TraceParent",
+			header:      http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}},
+			expected:    logging.TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"},
+			ok:          true,
+		},
+		{
+			description: "TraceParentFutureVersion",
+			header:      http.Header{"Traceparent": {"cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"}},
+			expected:    logging.TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"},
+			ok:          true,
+		},
+		{
+			description: "TraceParentInvalidVersion",
+			header:      http.Header{"Traceparent": {"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}},
+		},
+		{
+			description: "TraceParentZeroTraceID",
+			header:      http.Header{"Traceparent": {"00-00000000000000000000000000000000-00f067aa0ba902b7-01"}},
+		},
+		{
+			description: "TraceParentUppercase",
+			header:      http.Header{"Traceparent": {"00-4BF92F3577B34DA6A3CE929D0E0E4736-00F067AA0BA902B7-01"}},
+		},
+		{
+			description: "TraceParentTakesPrecedence",
+			header: http.Header{
+				"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+				"B3":          {"80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1"},
+			},
+			expected: logging.TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"},
+			ok:       true,
+		},
+		{
+			description: "InvalidTraceParentFallsBack",
+			header: http.Header{
+				"Traceparent": {"garbage"},
+				"B3":          {"80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1"},
+			},
+			expected: logging.TraceContext{TraceID: "80f198ee56343ba864fe8b2a57d3eff7", SpanID: "e457b5a2e4d86bd1"},
+			ok:       true,
+		},
+		{
+			description: "B3Single",
+			header:      http.Header{"B3": {"80f198ee56343ba8-e457b5a2e4d86bd1-1-05e3ac9a4f6e3b90"}},
+			expected:    logging.TraceContext{TraceID: "80f198ee56343ba8", SpanID: "e457b5a2e4d86bd1"},
+			ok:          true,
+		},
+		{
+			description: "B3SingleSamplingOnly",
+			header:      http.Header{"B3": {"0"}},
+		},
+		{
+			description: "B3Multiple",
+			header: http.Header{
+				"X-B3-Traceid": {"80F198EE56343BA864FE8B2A57D3EFF7"},
+				"X-B3-Spanid":  {"E457B5A2E4D86BD1"},
+			},
+			expected: logging.TraceContext{TraceID: "80f198ee56343ba864fe8b2a57d3eff7", SpanID: "e457b5a2e4d86bd1"},
+			ok:       true,
+		},
+		{
+			description: "B3MultipleMissingSpanID",
+			header:      http.Header{"X-B3-Traceid": {"80f198ee56343ba864fe8b2a57d3eff7"}},
+		},
+	}
+
+	for _, record := range testData {
+		record := record
+		t.Run(record.description, func(t *testing.T) {
+			assert := assert.New(t)
+			tc, ok := ParseTraceContext(record.header)
+			assert.Equal(record.ok, ok)
+			assert.Equal(record.expected, tc)
+		})
+	}
+}
+
+func TestTraceInfo(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		request = httptest.NewRequest("GET", "/", nil)
+	)
+
+	assert.Empty(TraceInfo(nil, request))
+
+	request.Header.Set(TraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.Equal(
+		[]interface{}{"existing", "value", logging.TraceIDKey(), "4bf92f3577b34da6a3ce929d0e0e4736", logging.SpanIDKey(), "00f067aa0ba902b7"},
+		TraceInfo([]interface{}{"existing", "value"}, request),
+	)
+}
+
+func TestSetTraceContext(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		request = httptest.NewRequest("GET", "/", nil)
+		ctx     = context.Background()
+	)
+
+	assert.Equal(ctx, SetTraceContext(ctx, request))
+
+	request.Header.Set(B3TraceIDHeader, "80f198ee56343ba8")
+	request.Header.Set(B3SpanIDHeader, "e457b5a2e4d86bd1")
+	tc, ok := logging.GetTraceContext(SetTraceContext(ctx, request))
+	assert.True(ok)
+	assert.Equal(logging.TraceContext{TraceID: "80f198ee56343ba8", SpanID: "e457b5a2e4d86bd1"}, tc)
+}
@@ -0,0 +1,105 @@
+package logging
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+var moduleKey interface{} = "module"
+
+// ModuleKey returns the logging key that identifies the component which produced a log entry.
+// Per-module levels configured with Options.Modules are keyed on this field.
+func ModuleKey() interface{} {
+	return moduleKey
+}
+
+// Module uses log.With to tag a logger with a module name, e.g. "device" or "service.consul"
+func Module(next log.Logger, name string) log.Logger {
+	return log.With(next, ModuleKey(), name)
+}
+
+// levelRank converts a textual level, as used in Options, into a rank that can be compared
+// against a filter threshold.  As with Options.Level, unrecognized strings are treated as ERROR.
+func levelRank(v string) int {
+	switch strings.ToUpper(v) {
+	case "DEBUG":
+		return 0
+
+	case "INFO":
+		return 1
+
+	case "WARN":
+		return 2
+
+	default:
+		return 3
+	}
+}
+
+// moduleFilter is a go-kit Logger that filters by level, using a separate threshold for each configured module
+type moduleFilter struct {
+	next      log.Logger
+	threshold int
+	modules   map[string]int
+}
+
+// thresholdFor returns the threshold for the given module.  The most specific configured module wins, so that
+// "service.consul" applies to "service.consul.watch" and takes precedence over "service".  Modules without
+// a configured level use the default threshold.
+func (mf *moduleFilter) thresholdFor(module string) int {
+	for m := strings.ToLower(module); len(m) > 0; {
+		if t, ok := mf.modules[m]; ok {
+			return t
+		}
+
+		i := strings.LastIndexByte(m, '.')
+		if i < 0 {
+			break
+		}
+
+		m = m[:i]
+	}
+
+	return mf.threshold
+}
+
+func (mf *moduleFilter) Log(keyvals ...interface{}) error {
+	var (
+		lvl    level.Value
+		module string
+	)
+
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		switch keyvals[i] {
+		case level.Key():
+			lvl, _ = keyvals[i+1].(level.Value)
+
+		case ModuleKey():
+			module = fmt.Sprint(keyvals[i+1])
+		}
+	}
+
+	// as with go-kit's level filter, entries without a level are always logged
+	if lvl != nil && levelRank(lvl.String()) < mf.thresholdFor(module) {
+		return nil
+	}
+
+	return mf.next.Log(keyvals...)
+}
+
+func newModuleFilter(next log.Logger, defaultLevel string, modules map[string]string) log.Logger {
+	mf := &moduleFilter{
+		next:      next,
+		threshold: levelRank(defaultLevel),
+		modules:   make(map[string]int, len(modules)),
+	}
+
+	for m, l := range modules {
+		mf.modules[strings.ToLower(m)] = levelRank(l)
+	}
+
+	return mf
+}
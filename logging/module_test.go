@@ -0,0 +1,76 @@
+package logging
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModuleKey(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(moduleKey, ModuleKey())
+}
+
+func TestModule(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		next   = NewCaptureLogger()
+	)
+
+	Module(next, "device").Log(MessageKey(), "connected")
+	entry := <-next.Output()
+	assert.Equal("device", entry[ModuleKey()])
+	assert.Equal("connected", entry[MessageKey()])
+}
+
+func TestNewFilterModules(t *testing.T) {
+	var (
+		next = make(countingLogger)
+
+		filter = NewFilter(next, &Options{
+			Level: "error",
+			Modules: map[string]string{
+				"device":         "debug",
+				"Service":        "info",
+				"service.consul": "warn",
+			},
+		})
+	)
+
+	testData := []struct {
+		module   string
+		level    level.Value
+		expected bool
+	}{
+		{"", level.ErrorValue(), true},
+		{"", level.WarnValue(), false},
+		{"device", level.DebugValue(), true},
+		{"device.manager", level.DebugValue(), true},
+		{"devices", level.InfoValue(), false},
+		{"service", level.InfoValue(), true},
+		{"service", level.DebugValue(), false},
+		{"service.zk", level.InfoValue(), true},
+		{"service.consul", level.InfoValue(), false},
+		{"SERVICE.CONSUL.watch", level.WarnValue(), true},
+		{"xhttp", level.WarnValue(), false},
+		{"xhttp", nil, true},
+	}
+
+	for i, record := range testData {
+		record := record
+		message := fmt.Sprintf("%d:%s", i, record.module)
+		t.Run(message, func(t *testing.T) {
+			assert := assert.New(t)
+			logger := log.With(filter, ModuleKey(), record.module)
+			if record.level != nil {
+				logger = log.With(logger, level.Key(), record.level)
+			}
+
+			logger.Log(MessageKey(), message)
+			assert.Equal(record.expected, next[message] == 1)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package logging
 
 import (
+	"errors"
 	"io"
 	"os"
 
@@ -12,21 +13,30 @@ const (
 	StdoutFile = "stdout"
 )
 
-// Options stores the configuration of a Logger.  Lumberjack is used for rolling files.
+var errNegativeRotation = errors.New("MaxSize, MaxAge, and MaxBackups cannot be negative")
+
+// Options stores the configuration of a Logger.  Lumberjack is used for rolling files, so log files are rotated
+// by size and pruned by age and count without any external logrotate configuration.
 type Options struct {
 	// File is the system file path for the log file.  If set to "stdout", this will log to os.Stdout.
 	// Otherwise, a lumberjack.Logger is created
 	File string `json:"file"`
 
-	// MaxSize is the lumberjack MaxSize
+	// MaxSize is the size in megabytes at which the log file is rotated.  If unset, lumberjack's default of 100 megabytes is used.
 	MaxSize int `json:"maxsize"`
 
-	// MaxAge is the lumberjack MaxAge
+	// MaxAge is the number of days rotated log files are retained.  If unset, rotated files are not removed based on age.
 	MaxAge int `json:"maxage"`
 
-	// MaxBackups is the lumberjack MaxBackups
+	// MaxBackups is the number of rotated log files retained.  If unset, all rotated files are retained, subject to MaxAge.
 	MaxBackups int `json:"maxbackups"`
 
+	// Compress indicates whether rotated log files are compressed with gzip
+	Compress bool `json:"compress"`
+
+	// LocalTime indicates whether the timestamps in rotated file names use local time.  The default is UTC.
+	LocalTime bool `json:"localtime"`
+
 	// JSON is a flag indicating whether JSON logging output is used.  The default is false,
 	// meaning that logfmt output is used.
 	JSON bool `json:"json"`
@@ -34,19 +44,57 @@ type Options struct {
 	// Level is the error level to output: ERROR, INFO, WARN, or DEBUG.  Any unrecognized string,
 	// including the empty string, is equivalent to passing ERROR.
 	Level string `json:"level"`
+
+	// Modules maps module names onto levels, using the same level strings as Level.  Log entries with a ModuleKey()
+	// field use the level of their most specific module, e.g. "service.consul" applies to "service.consul.watch" and
+	// overrides "service".  Entries from modules not present in this map use Level.  Module names are case-insensitive.
+	Modules map[string]string `json:"modules" mapstructure:"-"`
+
+	// Export optionally ships log records to Loki or an OTLP endpoint, in addition to the output configured by File.
+	// Records are exported after level filtering.  Call Close before exiting so that queued records are sent.
+	Export *ExportOptions `json:"export"`
+
+	// Redact optionally masks sensitive values, such as credentials, before records are written or exported.
+	// If unset, no redaction is performed.
+	Redact *RedactOptions `json:"redact"`
+
+	// Async optionally writes log output from a background goroutine, so that logging never blocks on a slow disk.
+	// As with Export, call Close before exiting so that buffered records are written.  If unset, output is synchronous.
+	Async *AsyncOptions `json:"async"`
+}
+
+// Validate checks the rotation settings of these options.  Negative values for MaxSize, MaxAge, or MaxBackups
+// are rejected, as lumberjack would otherwise rotate on every write or never clean up.  A nil Options is valid.
+func (o *Options) Validate() error {
+	if o != nil && (o.MaxSize < 0 || o.MaxAge < 0 || o.MaxBackups < 0) {
+		return errNegativeRotation
+	}
+
+	return nil
 }
 
 func (o *Options) output() io.Writer {
+	var output io.Writer
 	if o != nil && len(o.File) > 0 && o.File != StdoutFile {
-		return &lumberjack.Logger{
+		output = &lumberjack.Logger{
 			Filename:   o.File,
 			MaxSize:    o.MaxSize,
 			MaxAge:     o.MaxAge,
 			MaxBackups: o.MaxBackups,
+			LocalTime:  o.LocalTime,
+			Compress:   o.Compress,
 		}
+	} else {
+		output = log.NewSyncWriter(os.Stdout)
 	}
 
-	return log.NewSyncWriter(os.Stdout)
+	if o != nil && o.Async != nil {
+		async := NewAsyncWriter(output, o.Async)
+		registerCloser(async)
+		return async
+	}
+
+	return output
 }
 
 func (o *Options) loggerFactory() func(io.Writer) log.Logger {
@@ -64,3 +112,11 @@ func (o *Options) level() string {
 
 	return ""
 }
+
+func (o *Options) modules() map[string]string {
+	if o != nil {
+		return o.Modules
+	}
+
+	return nil
+}
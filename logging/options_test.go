@@ -33,6 +33,8 @@ func testOptionsOutput(t *testing.T) {
 			MaxSize:    689328,
 			MaxAge:     9,
 			MaxBackups: 454,
+			Compress:   true,
+			LocalTime:  true,
 		}
 
 		output               = rolling.output()
@@ -44,6 +46,13 @@ func testOptionsOutput(t *testing.T) {
 	assert.Equal(689328, lumberjackLogger.MaxSize)
 	assert.Equal(9, lumberjackLogger.MaxAge)
 	assert.Equal(454, lumberjackLogger.MaxBackups)
+	assert.True(lumberjackLogger.Compress)
+	assert.True(lumberjackLogger.LocalTime)
+
+	async := (&Options{Async: new(AsyncOptions)}).output()
+	_, ok = async.(*asyncWriter)
+	assert.True(ok)
+	assert.NoError(async.(*asyncWriter).Close())
 }
 
 func testOptionsLevel(t *testing.T) {
@@ -56,8 +65,21 @@ func testOptionsLevel(t *testing.T) {
 	assert.Equal("info", (&Options{Level: "info"}).level())
 }
 
+func testOptionsValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, o := range []*Options{nil, new(Options), {MaxSize: 100, MaxAge: 7, MaxBackups: 3}} {
+		assert.NoError(o.Validate())
+	}
+
+	for _, o := range []*Options{{MaxSize: -1}, {MaxAge: -1}, {MaxBackups: -1}} {
+		assert.Equal(errNegativeRotation, o.Validate())
+	}
+}
+
 func TestOptions(t *testing.T) {
 	t.Run("LoggerFactory", testOptionsLoggerFactory)
 	t.Run("Output", testOptionsOutput)
 	t.Run("Level", testOptionsLevel)
+	t.Run("Validate", testOptionsValidate)
 }
@@ -0,0 +1,172 @@
+package logging
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+const (
+	// RedactedValue replaces sensitive values in log entries
+	RedactedValue = "<redacted>"
+
+	// CredentialsPattern matches the credentials of Basic and Bearer authorization headers
+	CredentialsPattern = `(?i)\b(?:basic|bearer)\s+[A-Za-z0-9._~+/=-]+`
+
+	// JWTPattern matches JSON Web Tokens, such as SAT tokens
+	JWTPattern = `\beyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`
+
+	// MACPattern matches MAC addresses, with or without separators and the "mac:" device ID prefix.
+	// This pattern is not applied by default, since device IDs are usually needed to troubleshoot.
+	MACPattern = `(?i)\b(?:mac:)?(?:[0-9a-f]{12}|(?:[0-9a-f]{2}[:-]){5}[0-9a-f]{2})\b`
+)
+
+var (
+	defaultRedactFields   = []string{"authorization", "password", "secret", "token"}
+	defaultRedactPatterns = []*regexp.Regexp{
+		regexp.MustCompile(CredentialsPattern),
+		regexp.MustCompile(JWTPattern),
+	}
+)
+
+// RedactOptions configures the masking of sensitive values in log entries.  Authorization credentials and
+// JWTs are always masked, as are the values of fields named authorization, password, secret, or token.
+// These options add to those defaults.
+type RedactOptions struct {
+	// Fields are additional logging keys whose values are always masked.  Keys are compared case-insensitively.
+	Fields []string `json:"fields"`
+
+	// Patterns are additional regular expressions.  Any match within a string, error, or fmt.Stringer value is masked.
+	// MACPattern may be used here to mask device MAC addresses.
+	Patterns []string `json:"patterns"`
+}
+
+// redactor is a go-kit Logger that masks sensitive values before passing entries to the next logger
+type redactor struct {
+	next     log.Logger
+	fields   map[string]bool
+	patterns []*regexp.Regexp
+}
+
+// redactString applies each pattern to a string, returning the masked string and whether anything was masked
+func (r *redactor) redactString(v string) (string, bool) {
+	masked := v
+	for _, p := range r.patterns {
+		masked = p.ReplaceAllLiteralString(masked, RedactedValue)
+	}
+
+	return masked, masked != v
+}
+
+// redactValue masks a single logged value.  Values whose text contains no sensitive data are
+// left untouched, so that they are rendered exactly as they would be without redaction.
+func (r *redactor) redactValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case string:
+		masked, _ := r.redactString(value)
+		return masked
+
+	case []string:
+		var masked []string
+		for i, s := range value {
+			if m, ok := r.redactString(s); ok {
+				if masked == nil {
+					masked = append([]string(nil), value...)
+				}
+
+				masked[i] = m
+			}
+		}
+
+		if masked != nil {
+			return masked
+		}
+
+	case error:
+		if masked, ok := r.redactString(value.Error()); ok {
+			return masked
+		}
+
+	case fmt.Stringer:
+		if masked, ok := r.redactString(value.String()); ok {
+			return masked
+		}
+	}
+
+	return v
+}
+
+func (r *redactor) Log(keyvals ...interface{}) error {
+	redacted := make([]interface{}, len(keyvals))
+	copy(redacted, keyvals)
+
+	for i := 1; i < len(redacted); i += 2 {
+		if r.fields[strings.ToLower(fmt.Sprint(redacted[i-1]))] {
+			redacted[i] = RedactedValue
+		} else {
+			redacted[i] = r.redactValue(redacted[i])
+		}
+	}
+
+	return r.next.Log(redacted...)
+}
+
+// NewRedactor decorates a logger so that sensitive values are masked before they are written.  The default
+// fields and patterns are always applied, and the options, which may be nil, add to them.  An error is returned
+// if any pattern is not a valid regular expression.
+//
+// The returned logger should be decorated with log.With rather than the other way around, so that
+// contextual key/value pairs are also redacted.
+func NewRedactor(next log.Logger, o *RedactOptions) (log.Logger, error) {
+	r := &redactor{
+		next:     next,
+		fields:   make(map[string]bool),
+		patterns: append([]*regexp.Regexp(nil), defaultRedactPatterns...),
+	}
+
+	for _, f := range defaultRedactFields {
+		r.fields[f] = true
+	}
+
+	if o != nil {
+		for _, f := range o.Fields {
+			r.fields[strings.ToLower(f)] = true
+		}
+
+		for _, p := range o.Patterns {
+			compiled, err := regexp.Compile(p)
+			if err != nil {
+				return nil, err
+			}
+
+			r.patterns = append(r.patterns, compiled)
+		}
+	}
+
+	return r, nil
+}
+
+// Redact decorates a logger with the default redaction rules
+func Redact(next log.Logger) log.Logger {
+	r, _ := NewRedactor(next, nil)
+	return r
+}
+
+// withRedact applies any configured redaction rules.  Invalid rules are reported to the given logger,
+// and the default rules are used instead.
+func (o *Options) withRedact(next log.Logger) log.Logger {
+	if o == nil || o.Redact == nil {
+		return next
+	}
+
+	r, err := NewRedactor(next, o.Redact)
+	if err != nil {
+		next.Log(level.Key(), level.ErrorValue(), MessageKey(), "invalid redaction options", ErrorKey(), err)
+		return Redact(next)
+	}
+
+	return r
+}
@@ -0,0 +1,146 @@
+package logging
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/metrics"
+)
+
+const (
+	DefaultSamplingInitial    = 100
+	DefaultSamplingThereafter = 100
+	DefaultSamplingInterval   = time.Second
+)
+
+// SamplingOptions configures the sampling of high-frequency log entries.  Within each interval, the first Initial
+// entries with a given key are logged, and thereafter only every Thereafter-th entry with that key is logged.
+type SamplingOptions struct {
+	// Initial is the number of entries per key logged in each interval before sampling begins.
+	// If unset or nonpositive, DefaultSamplingInitial is used.
+	Initial int `json:"initial"`
+
+	// Thereafter is the sampling rate once Initial entries have been logged, e.g. 100 means that 1 in 100 entries are logged.
+	// If unset or nonpositive, DefaultSamplingThereafter is used.
+	Thereafter int `json:"thereafter"`
+
+	// Interval is the period over which entries are counted.  If unset or nonpositive, DefaultSamplingInterval is used.
+	Interval time.Duration `json:"interval"`
+
+	// Key computes the sampling key of a log entry from its key/value pairs.  If unset, the key is the entry's level and message,
+	// so that each distinct message is sampled separately.
+	Key func([]interface{}) string `json:"-"`
+
+	// Suppressed is an optional counter incremented for each entry that is not logged
+	Suppressed metrics.Counter `json:"-"`
+}
+
+func (so SamplingOptions) initial() uint64 {
+	if so.Initial > 0 {
+		return uint64(so.Initial)
+	}
+
+	return DefaultSamplingInitial
+}
+
+func (so SamplingOptions) thereafter() uint64 {
+	if so.Thereafter > 0 {
+		return uint64(so.Thereafter)
+	}
+
+	return DefaultSamplingThereafter
+}
+
+func (so SamplingOptions) interval() time.Duration {
+	if so.Interval > 0 {
+		return so.Interval
+	}
+
+	return DefaultSamplingInterval
+}
+
+// DefaultSamplingKey returns the level and message of a log entry, separated by a colon
+func DefaultSamplingKey(keyvals []interface{}) string {
+	var lvl, message interface{}
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		switch keyvals[i] {
+		case level.Key():
+			lvl = keyvals[i+1]
+
+		case MessageKey():
+			message = keyvals[i+1]
+		}
+	}
+
+	return fmt.Sprintf("%v:%v", lvl, message)
+}
+
+// sampler is a go-kit Logger that drops entries in excess of its sampling limits
+type sampler struct {
+	next       log.Logger
+	initial    uint64
+	thereafter uint64
+	interval   time.Duration
+	key        func([]interface{}) string
+	suppressed metrics.Counter
+	now        func() time.Time
+
+	lock   sync.Mutex
+	reset  time.Time
+	counts map[string]uint64
+}
+
+// sample increments the count for the given key and tests if the entry should be logged
+func (s *sampler) sample(key string) bool {
+	defer s.lock.Unlock()
+	s.lock.Lock()
+
+	if now := s.now(); !now.Before(s.reset) {
+		// discard all the counts at the start of each interval, which also bounds the memory used by infrequent keys
+		s.counts = make(map[string]uint64, len(s.counts))
+		s.reset = now.Add(s.interval)
+	}
+
+	n := s.counts[key] + 1
+	s.counts[key] = n
+	return n <= s.initial || (n-s.initial)%s.thereafter == 0
+}
+
+func (s *sampler) Log(keyvals ...interface{}) error {
+	if s.sample(s.key(keyvals)) {
+		return s.next.Log(keyvals...)
+	}
+
+	if s.suppressed != nil {
+		s.suppressed.Add(1.0)
+	}
+
+	return nil
+}
+
+// NewSampler decorates a logger so that high-frequency entries, such as device connect and disconnect messages
+// during a reconnect storm, are sampled rather than overwhelming the log pipeline.  Entries are keyed by level and
+// message by default, so infrequent messages are unaffected.
+//
+// The returned logger should be decorated with log.With and the level functions in this package rather than the other
+// way around, so that the level and message are visible to the sampling key.
+func NewSampler(next log.Logger, o SamplingOptions) log.Logger {
+	key := o.Key
+	if key == nil {
+		key = DefaultSamplingKey
+	}
+
+	return &sampler{
+		next:       next,
+		initial:    o.initial(),
+		thereafter: o.thereafter(),
+		interval:   o.interval(),
+		key:        key,
+		suppressed: o.Suppressed,
+		now:        time.Now,
+		counts:     make(map[string]uint64),
+	}
+}
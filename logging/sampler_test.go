@@ -0,0 +1,125 @@
+package logging
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingLogger counts the entries it receives by message
+type countingLogger map[interface{}]int
+
+func (cl countingLogger) Log(keyvals ...interface{}) error {
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if keyvals[i] == MessageKey() {
+			cl[keyvals[i+1]]++
+		}
+	}
+
+	return nil
+}
+
+func TestDefaultSamplingKey(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("info:connected", DefaultSamplingKey([]interface{}{level.Key(), level.InfoValue(), MessageKey(), "connected", "id", "mac:112233445566"}))
+	assert.Equal("info:connected", DefaultSamplingKey([]interface{}{MessageKey(), "connected", level.Key(), level.InfoValue()}))
+	assert.Equal("<nil>:connected", DefaultSamplingKey([]interface{}{MessageKey(), "connected"}))
+	assert.NotEqual(
+		DefaultSamplingKey([]interface{}{level.Key(), level.InfoValue(), MessageKey(), "connected"}),
+		DefaultSamplingKey([]interface{}{level.Key(), level.ErrorValue(), MessageKey(), "connected"}),
+	)
+}
+
+func testNewSamplerDefaults(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		next   = make(countingLogger)
+		logger = NewSampler(next, SamplingOptions{})
+	)
+
+	require.NotNil(logger)
+	s := logger.(*sampler)
+	assert.Equal(uint64(DefaultSamplingInitial), s.initial)
+	assert.Equal(uint64(DefaultSamplingThereafter), s.thereafter)
+	assert.Equal(DefaultSamplingInterval, s.interval)
+	assert.NotNil(s.key)
+	assert.Nil(s.suppressed)
+
+	for i := 0; i < DefaultSamplingInitial; i++ {
+		logger.Log(MessageKey(), "message")
+	}
+
+	assert.Equal(DefaultSamplingInitial, next["message"])
+}
+
+func testNewSamplerSampling(t *testing.T) {
+	var (
+		assert     = assert.New(t)
+		next       = make(countingLogger)
+		suppressed = generic.NewCounter("suppressed")
+		now        = time.Now()
+
+		logger = NewSampler(next, SamplingOptions{
+			Initial:    3,
+			Thereafter: 5,
+			Interval:   time.Minute,
+			Suppressed: suppressed,
+		})
+	)
+
+	logger.(*sampler).now = func() time.Time { return now }
+
+	connected := Info(log.With(logger, "id", "mac:112233445566"), MessageKey(), "connected")
+	for i := 0; i < 20; i++ {
+		connected.Log()
+	}
+
+	// 3 initial entries, then the 5th, 10th, and 15th after those
+	assert.Equal(6, next["connected"])
+	assert.Equal(14.0, suppressed.Value())
+
+	// other messages are sampled independently
+	Error(logger).Log(MessageKey(), "failed")
+	assert.Equal(1, next["failed"])
+
+	// counts start over in the next interval
+	now = now.Add(time.Minute)
+	for i := 0; i < 3; i++ {
+		connected.Log()
+	}
+
+	assert.Equal(9, next["connected"])
+	assert.Equal(14.0, suppressed.Value())
+}
+
+func testNewSamplerCustomKey(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		next   = make(countingLogger)
+
+		logger = NewSampler(next, SamplingOptions{
+			Initial:    1,
+			Thereafter: 1000,
+			Key:        func([]interface{}) string { return "everything" },
+		})
+	)
+
+	logger.Log(MessageKey(), "first")
+	logger.Log(MessageKey(), "second")
+	assert.Equal(1, next["first"])
+	assert.Zero(next["second"])
+}
+
+func TestNewSampler(t *testing.T) {
+	t.Run("Defaults", testNewSamplerDefaults)
+	t.Run("Sampling", testNewSamplerSampling)
+	t.Run("CustomKey", testNewSamplerCustomKey)
+}
@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/log"
+)
+
+const traceContextKey contextKey = 2
+
+var (
+	traceIDKey interface{} = "traceID"
+	spanIDKey  interface{} = "spanID"
+)
+
+// TraceIDKey returns the logging key for the distributed trace ID
+func TraceIDKey() interface{} {
+	return traceIDKey
+}
+
+// SpanIDKey returns the logging key for the span ID within a distributed trace
+func SpanIDKey() interface{} {
+	return spanIDKey
+}
+
+// TraceContext holds the identifiers which correlate log entries with a distributed trace
+type TraceContext struct {
+	TraceID string
+	SpanID  string
+}
+
+// WithTraceContext adds the given trace identifiers to the context so that they can be retrieved with GetTraceContext
+func WithTraceContext(parent context.Context, tc TraceContext) context.Context {
+	return context.WithValue(parent, traceContextKey, tc)
+}
+
+// GetTraceContext retrieves the trace identifiers associated with the context, if any
+func GetTraceContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(traceContextKey).(TraceContext)
+	return tc, ok
+}
+
+// WithTrace uses log.With to add the trace and span IDs from the context to a logger.  If the context has no
+// trace identifiers, the logger is returned as is.  An empty span ID is omitted.
+func WithTrace(logger log.Logger, ctx context.Context) log.Logger {
+	tc, ok := GetTraceContext(ctx)
+	if !ok || len(tc.TraceID) == 0 {
+		return logger
+	}
+
+	if len(tc.SpanID) == 0 {
+		return log.With(logger, TraceIDKey(), tc.TraceID)
+	}
+
+	return log.With(logger, TraceIDKey(), tc.TraceID, SpanIDKey(), tc.SpanID)
+}
@@ -0,0 +1,71 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceIDKey(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(traceIDKey, TraceIDKey())
+}
+
+func TestSpanIDKey(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(spanIDKey, SpanIDKey())
+}
+
+func TestTraceContext(t *testing.T) {
+	assert := assert.New(t)
+
+	tc, ok := GetTraceContext(context.Background())
+	assert.False(ok)
+	assert.Zero(tc)
+
+	expected := TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"}
+	tc, ok = GetTraceContext(WithTraceContext(context.Background(), expected))
+	assert.True(ok)
+	assert.Equal(expected, tc)
+}
+
+func testWithTraceMissing(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		next   = make(countingLogger)
+	)
+
+	assert.Equal(next, WithTrace(next, context.Background()))
+	assert.Equal(next, WithTrace(next, WithTraceContext(context.Background(), TraceContext{})))
+}
+
+func testWithTrace(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		entries [][]interface{}
+		next    = log.LoggerFunc(func(keyvals ...interface{}) error {
+			entries = append(entries, keyvals)
+			return nil
+		})
+
+		ctx = WithTraceContext(context.Background(), TraceContext{TraceID: "80f198ee56343ba8", SpanID: "e457b5a2e4d86bd1"})
+	)
+
+	WithTrace(next, ctx).Log(MessageKey(), "traced")
+	WithTrace(next, WithTraceContext(context.Background(), TraceContext{TraceID: "80f198ee56343ba8"})).Log(MessageKey(), "no span")
+
+	assert.Equal(
+		[][]interface{}{
+			{TraceIDKey(), "80f198ee56343ba8", SpanIDKey(), "e457b5a2e4d86bd1", MessageKey(), "traced"},
+			{TraceIDKey(), "80f198ee56343ba8", MessageKey(), "no span"},
+		},
+		entries,
+	)
+}
+
+func TestWithTrace(t *testing.T) {
+	t.Run("Missing", testWithTraceMissing)
+	t.Run("Present", testWithTrace)
+}
@@ -1,6 +1,7 @@
 package logging
 
 import (
+	"github.com/spf13/cast"
 	"github.com/spf13/viper"
 )
 
@@ -21,14 +22,45 @@ func Sub(v *viper.Viper) *viper.Viper {
 }
 
 // FromViper produces an Options from a (possibly nil) Viper instance.
-// Callers should use FromViper(Sub(v)) if the standard subkey is desired.
+// Callers should use FromViper(Sub(v)) if the standard subkey is desired.  An error is returned if
+// the rotation settings are invalid.
 func FromViper(v *viper.Viper) (*Options, error) {
 	o := new(Options)
 	if v != nil {
 		if err := v.Unmarshal(o); err != nil {
 			return nil, err
 		}
+
+		if modules := v.Get("modules"); modules != nil {
+			o.Modules = make(map[string]string)
+			flattenStringMap("", modules, o.Modules)
+		}
+
+		if labels := v.Get("export.labels"); o.Export != nil && labels != nil {
+			o.Export.Labels = make(map[string]string)
+			flattenStringMap("", labels, o.Export.Labels)
+		}
+
+		if err := o.Validate(); err != nil {
+			return nil, err
+		}
 	}
 
 	return o, nil
 }
+
+// flattenStringMap converts a possibly nested map produced by Viper into a map with dotted keys.  Viper treats a dot
+// in a key as a path separator, so "service.consul" is unmarshalled as {"service": {"consul": ...}}.
+func flattenStringMap(prefix string, v interface{}, flattened map[string]string) {
+	if m, err := cast.ToStringMapE(v); err == nil {
+		for k, child := range m {
+			if len(prefix) > 0 {
+				k = prefix + "." + k
+			}
+
+			flattenStringMap(k, child, flattened)
+		}
+	} else if len(prefix) > 0 {
+		flattened[prefix] = cast.ToString(v)
+	}
+}
@@ -3,6 +3,7 @@ package logging
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
@@ -72,6 +73,25 @@ func testFromViperError(t *testing.T) {
 	assert.Error(err)
 }
 
+func testFromViperInvalidRotation(t *testing.T) {
+	for _, configuration := range []string{`{"maxsize": -1}`, `{"maxage": -1}`, `{"maxbackups": -1}`} {
+		t.Run(configuration, func(t *testing.T) {
+			var (
+				assert  = assert.New(t)
+				require = require.New(t)
+				v       = viper.New()
+			)
+
+			v.SetConfigType("json")
+			require.NoError(v.ReadConfig(strings.NewReader(configuration)))
+
+			o, err := FromViper(v)
+			assert.Nil(o)
+			assert.Equal(errNegativeRotation, err)
+		})
+	}
+}
+
 func testFromViperUnmarshal(t *testing.T) {
 	var (
 		assert        = assert.New(t)
@@ -82,6 +102,8 @@ func testFromViperUnmarshal(t *testing.T) {
 				"maxsize": 459234098,
 				"maxage": 52,
 				"maxbackups": 452,
+				"compress": true,
+				"localtime": true,
 				"json": true,
 				"level": "info"
 			}
@@ -101,13 +123,64 @@ func testFromViperUnmarshal(t *testing.T) {
 	assert.Equal(459234098, o.MaxSize)
 	assert.Equal(52, o.MaxAge)
 	assert.Equal(452, o.MaxBackups)
+	assert.True(o.Compress)
+	assert.True(o.LocalTime)
 	assert.True(o.JSON)
 	assert.Equal("info", o.Level)
 }
 
+func testFromViperDottedKeys(t *testing.T) {
+	var (
+		assert        = assert.New(t)
+		require       = require.New(t)
+		configuration = `
+			{
+				"level": "error",
+				"export": {
+					"type": "otlp",
+					"url": "http://localhost:4318/v1/logs",
+					"flushInterval": "5s",
+					"labels": {"service.name": "talaria"}
+				},
+				"modules": {
+					"device": "debug",
+					"service.consul": "warn",
+					"xhttp": {"fanout": "info"}
+				}
+			}
+		`
+
+		v = viper.New()
+	)
+
+	v.SetConfigType("json")
+	require.NoError(v.ReadConfig(strings.NewReader(configuration)))
+
+	o, err := FromViper(v)
+	require.NotNil(o)
+	require.Nil(err)
+
+	assert.Equal(
+		map[string]string{
+			"device":         "debug",
+			"service.consul": "warn",
+			"xhttp.fanout":   "info",
+		},
+		o.Modules,
+	)
+
+	require.NotNil(o.Export)
+	assert.Equal(ExportOTLP, o.Export.Type)
+	assert.Equal("http://localhost:4318/v1/logs", o.Export.URL)
+	assert.Equal(5*time.Second, o.Export.FlushInterval)
+	assert.Equal(map[string]string{"service.name": "talaria"}, o.Export.Labels)
+}
+
 func TestFromViper(t *testing.T) {
 	t.Run("Nil", testFromViperNil)
 	t.Run("Missing", testFromViperMissing)
 	t.Run("Error", testFromViperError)
+	t.Run("InvalidRotation", testFromViperInvalidRotation)
 	t.Run("Unmarshal", testFromViperUnmarshal)
+	t.Run("DottedKeys", testFromViperDottedKeys)
 }
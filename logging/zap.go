@@ -0,0 +1,110 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// zapLogger adapts a zap.Logger to the go-kit Logger interface.  The go-kit level and message
+// keys are translated into zap's level and message, and all other key/value pairs become fields.
+type zapLogger struct {
+	logger *zap.Logger
+}
+
+// zapLevel translates a go-kit level value into the corresponding zap level
+func zapLevel(v interface{}) zapcore.Level {
+	switch fmt.Sprint(v) {
+	case level.DebugValue().String():
+		return zapcore.DebugLevel
+
+	case level.WarnValue().String():
+		return zapcore.WarnLevel
+
+	case level.ErrorValue().String():
+		return zapcore.ErrorLevel
+
+	default:
+		return zapcore.InfoLevel
+	}
+}
+
+func (zl zapLogger) Log(keyvals ...interface{}) error {
+	if len(keyvals)%2 != 0 {
+		keyvals = append(keyvals, log.ErrMissingValue)
+	}
+
+	var (
+		lvl     = zapcore.InfoLevel
+		message string
+		fields  = make([]zap.Field, 0, len(keyvals)/2)
+	)
+
+	for i := 0; i < len(keyvals); i += 2 {
+		key, value := fmt.Sprint(keyvals[i]), keyvals[i+1]
+		switch key {
+		case fmt.Sprint(level.Key()):
+			lvl = zapLevel(value)
+
+		case fmt.Sprint(MessageKey()):
+			message = fmt.Sprint(value)
+
+		default:
+			fields = append(fields, zap.Any(key, value))
+		}
+	}
+
+	if entry := zl.logger.Check(lvl, message); entry != nil {
+		entry.Write(fields...)
+	}
+
+	return nil
+}
+
+// encodeTimeUTC renders timestamps in the same format as the go-kit loggers created by New
+func encodeTimeUTC(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendString(t.UTC().Format(time.RFC3339Nano))
+}
+
+// NewZap is like New, except that the returned go-kit Logger is backed by zap.  The same options, and therefore
+// the same configuration keys, apply.  Existing code can switch to zap simply by calling this function instead of New.
+//
+// As with New, the returned logger includes a UTC timestamp and filters according to the Level field, but does not
+// insert caller information.  Log entries without a level are written at zap's info level.
+func NewZap(o *Options) log.Logger {
+	return newZap(o, o.output())
+}
+
+func newZap(o *Options, output io.Writer) log.Logger {
+	var (
+		config = zapcore.EncoderConfig{
+			TimeKey:        fmt.Sprint(TimestampKey()),
+			LevelKey:       fmt.Sprint(level.Key()),
+			MessageKey:     fmt.Sprint(MessageKey()),
+			LineEnding:     zapcore.DefaultLineEnding,
+			EncodeLevel:    zapcore.LowercaseLevelEncoder,
+			EncodeTime:     encodeTimeUTC,
+			EncodeDuration: zapcore.StringDurationEncoder,
+		}
+
+		encoder zapcore.Encoder
+	)
+
+	if o != nil && o.JSON {
+		encoder = zapcore.NewJSONEncoder(config)
+	} else {
+		encoder = zapcore.NewConsoleEncoder(config)
+	}
+
+	return NewFilter(
+		o.withRedact(o.withExport(zapLogger{
+			logger: zap.New(zapcore.NewCore(encoder, zapcore.AddSync(output), zapcore.DebugLevel)),
+		})),
+		o,
+	)
+}
@@ -0,0 +1,86 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewZap(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.NotNil(NewZap(nil))
+	assert.NotNil(NewZap(new(Options)))
+}
+
+func testNewZapJSON(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		output bytes.Buffer
+		logger = newZap(&Options{JSON: true, Level: "INFO"}, &output)
+	)
+
+	require.NotNil(logger)
+	logger.Log(level.Key(), level.DebugValue(), MessageKey(), "filtered")
+	assert.Zero(output.Len())
+
+	Error(logger).Log(MessageKey(), "failed", ErrorKey(), errors.New("expected"), "count", 3)
+
+	var entry map[string]interface{}
+	require.NoError(json.Unmarshal(output.Bytes(), &entry))
+	assert.Equal("error", entry["level"])
+	assert.Equal("failed", entry["msg"])
+	assert.Equal("expected", entry["error"])
+	assert.Equal(3.0, entry["count"])
+
+	ts, err := time.Parse(time.RFC3339Nano, entry["ts"].(string))
+	require.NoError(err)
+	assert.Equal(time.UTC, ts.Location())
+
+	output.Reset()
+	Info(log.With(logger, "component", "test")).Log(MessageKey(), "started", "odd")
+
+	entry = nil
+	require.NoError(json.Unmarshal(output.Bytes(), &entry))
+	assert.Equal("info", entry["level"])
+	assert.Equal("started", entry["msg"])
+	assert.Equal("test", entry["component"])
+	assert.Equal(log.ErrMissingValue.Error(), entry["odd"])
+
+	output.Reset()
+	Warn(logger).Log(MessageKey(), "warning")
+	entry = nil
+	require.NoError(json.Unmarshal(output.Bytes(), &entry))
+	assert.Equal("warn", entry["level"])
+}
+
+func testNewZapConsole(t *testing.T) {
+	var (
+		assert = assert.New(t)
+
+		output bytes.Buffer
+		logger = newZap(&Options{Level: "DEBUG"}, &output)
+	)
+
+	Debug(logger).Log(MessageKey(), "console message", "key", "value")
+	line := output.String()
+	assert.True(strings.HasSuffix(line, "\n"))
+	assert.Contains(line, "debug")
+	assert.Contains(line, "console message")
+	assert.Contains(line, `"key": "value"`)
+}
+
+func TestZapLogger(t *testing.T) {
+	t.Run("JSON", testNewZapJSON)
+	t.Run("Console", testNewZapConsole)
+}
@@ -76,7 +76,7 @@ func (a AuthorizationHandler) Decorate(delegate http.Handler) http.Handler {
 	var (
 		headerName          = a.headerName()
 		forbiddenStatusCode = a.forbiddenStatusCode()
-		logger              = a.logger()
+		logger              = logging.Redact(a.logger())
 		errorLog            = logging.Error(logger)
 	)
 
@@ -25,6 +25,7 @@ import (
 	"github.com/xmidt-org/webpa-common/xhttp"
 	"github.com/xmidt-org/webpa-common/xlistener"
 	"github.com/xmidt-org/webpa-common/xmetrics"
+	"github.com/xmidt-org/webpa-common/xmetrics/xmetricshttp"
 )
 
 const (
@@ -329,6 +330,10 @@ type Metric struct {
 	LogConnectionState bool
 	HandlerOptions     promhttp.HandlerOpts
 	MetricsOptions     xmetrics.Options
+
+	// Auth optionally requires credentials to scrape metrics, which is necessary when the metrics
+	// server shares a publicly reachable port
+	Auth xmetricshttp.AuthOptions
 }
 
 func (m *Metric) NewRegistry(modules ...xmetrics.Module) (xmetrics.Registry, error) {
@@ -342,9 +347,15 @@ func (m *Metric) New(logger log.Logger, chain alice.Chain, gatherer stdprometheu
 		return nil
 	}
 
+	options := m.HandlerOptions
+	if r, ok := gatherer.(stdprometheus.Registerer); ok && options.Registry == nil {
+		// record the handler's own metrics, such as scrape durations, in the gatherer when possible
+		options.Registry = r
+	}
+
 	var (
 		mux     = http.NewServeMux()
-		handler = chain.Then(promhttp.HandlerFor(gatherer, m.HandlerOptions))
+		handler = chain.Append(xmetricshttp.NewAuthConstructor(m.Auth)).Then(xmetricshttp.NewHandler(gatherer, options))
 	)
 
 	mux.Handle("/metrics", handler)
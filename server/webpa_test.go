@@ -4,6 +4,7 @@ import (
 	"crypto/tls"
 	"errors"
 	"net/http"
+	"net/http/httptest"
 	"sync"
 	"testing"
 	"time"
@@ -13,6 +14,7 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"github.com/xmidt-org/webpa-common/xmetrics"
+	"github.com/xmidt-org/webpa-common/xmetrics/xmetricshttp"
 )
 
 func TestListenAndServeNonSecure(t *testing.T) {
@@ -355,6 +357,43 @@ func TestHealthNew(t *testing.T) {
 	}
 }
 
+func TestMetricNew(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		_, logger = newTestLogger()
+		metric    = Metric{
+			Name:    "TestMetricNew",
+			Address: ":8080",
+			Auth:    xmetricshttp.AuthOptions{Tokens: []string{"token"}},
+		}
+
+		r, err = metric.NewRegistry()
+	)
+
+	require.NoError(err)
+	server := metric.New(logger, alice.New(), r)
+	require.NotNil(server)
+	assert.Equal(":8080", server.Addr)
+
+	{
+		response := httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, httptest.NewRequest("GET", "/metrics", nil))
+		assert.Equal(http.StatusUnauthorized, response.Code)
+	}
+
+	{
+		request := httptest.NewRequest("GET", "/metrics", nil)
+		request.Header.Set("Authorization", "Bearer token")
+		response := httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+		assert.Equal(http.StatusOK, response.Code)
+	}
+
+	assert.Nil(new(Metric).New(logger, alice.New(), r))
+}
+
 func TestWebPANoPrimaryAddress(t *testing.T) {
 	var (
 		assert  = assert.New(t)
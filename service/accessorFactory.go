@@ -8,14 +8,33 @@ import (
 const DefaultVnodeCount = 211
 
 // AccessorFactory defines the behavior of functions which can take a set
-// of nodes and turn them into an Accessor.
+// of nodes and turn them into an Accessor.  An instance that appears more than once
+// is weighted by the number of times it appears.
 type AccessorFactory func([]string) Accessor
 
+// hasDuplicates tests if any instance appears more than once
+func hasDuplicates(instances []string) bool {
+	seen := make(map[string]bool, len(instances))
+	for _, i := range instances {
+		if seen[i] {
+			return true
+		}
+
+		seen[i] = true
+	}
+
+	return false
+}
+
 func newConsistentAccessor(vnodeCount int, instances []string) Accessor {
 	if len(instances) == 0 {
 		return emptyAccessor{}
 	}
 
+	if hasDuplicates(instances) {
+		return newWeightedRing(vnodeCount, instances)
+	}
+
 	hasher := consistentHash.New()
 	hasher.SetVnodeCount(vnodeCount)
 	for _, i := range instances {
@@ -40,9 +40,26 @@ func testNewConsistentAccessorNonEmpty(t *testing.T) {
 	}
 }
 
+func testNewConsistentAccessorWeighted(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		a = newConsistentAccessor(123, []string{"instance1", "instance2", "instance2"})
+	)
+
+	require.IsType((*weightedRing)(nil), a)
+	for _, k := range []string{"a", "alsdkjfa;lksehjuro8iwurjhf", "asdf8974", "875kjh4", "928375hjdfgkyu9832745kjshdfgoi873465"} {
+		i, err := a.Get([]byte(k))
+		assert.Contains([]string{"instance1", "instance2"}, i)
+		assert.NoError(err)
+	}
+}
+
 func TestNewConsistentAccessor(t *testing.T) {
 	t.Run("Empty", testNewConsistentAccessorEmpty)
 	t.Run("Nonempty", testNewConsistentAccessorNonEmpty)
+	t.Run("Weighted", testNewConsistentAccessorWeighted)
 }
 
 func testNewConsistentAccessorFactory(t *testing.T, vnodeCount int) {
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"math"
+	"sync"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/provider"
+)
+
+// DefaultLoadBoundFactor is the load bound factor used when a factor not greater than 1.0 is supplied.  With this factor,
+// no instance is assigned more than 25% over the average number of keys.
+const DefaultLoadBoundFactor = 1.25
+
+type boundedMeasures struct {
+	spillover    metrics.Counter
+	rebalance    metrics.Counter
+	assignedKeys metrics.Gauge
+}
+
+func newBoundedMeasures(p provider.Provider) boundedMeasures {
+	if p == nil {
+		p = provider.NewDiscardProvider()
+	}
+
+	return boundedMeasures{
+		spillover:    p.NewCounter(BoundedLoadSpilloverCount),
+		rebalance:    p.NewCounter(BoundedLoadRebalanceCount),
+		assignedKeys: p.NewGauge(BoundedLoadAssignedKeys),
+	}
+}
+
+// BoundedAccessor is an Accessor that uses consistent hashing with bounded loads.  Each key is
+// assigned to the first instance on the hash ring, starting from the key's position, whose load is
+// under the bound.  The bound for any instance is the load bound factor times the average load, rounded up.
+// This keeps hot ranges of keys from overloading a single instance, while most keys still map to the same
+// instance as they would with plain consistent hashing.
+//
+// Instances that appear more than once are weighted by the number of times they appear, in both their share of
+// the hash ring and their load bound.
+//
+// Assignments are sticky:  once a key is assigned to an instance, Get returns that instance until the key
+// is released.  Callers should invoke Release when a key no longer represents load, e.g. when a device disconnects.
+type BoundedAccessor struct {
+	ring        *weightedRing
+	instances   int
+	loadFactor  float64
+	measures    boundedMeasures
+	lock        sync.Mutex
+	assignments map[string]string
+	loads       map[string]int
+}
+
+func newBoundedAccessor(vnodeCount int, loadFactor float64, instances []string, m boundedMeasures) *BoundedAccessor {
+	if loadFactor <= 1.0 {
+		loadFactor = DefaultLoadBoundFactor
+	}
+
+	ring := newWeightedRing(vnodeCount, instances)
+	loads := make(map[string]int, len(ring.weights))
+	for i := range ring.weights {
+		loads[i] = 0
+	}
+
+	return &BoundedAccessor{
+		ring:        ring,
+		instances:   len(loads),
+		loadFactor:  loadFactor,
+		measures:    m,
+		assignments: make(map[string]string),
+		loads:       loads,
+	}
+}
+
+// bound returns the maximum load of an instance when the given number of keys are assigned
+func (ba *BoundedAccessor) bound(instance string, keys int) int {
+	return int(math.Ceil(ba.loadFactor * float64(keys) * float64(ba.ring.weights[instance]) / float64(ba.ring.total)))
+}
+
+// full tests if an instance is at its bound, assuming one more key is assigned.  The lock must be held.
+func (ba *BoundedAccessor) full(instance string) bool {
+	return ba.loads[instance] >= ba.bound(instance, len(ba.assignments)+1)
+}
+
+// assign selects an instance for a key that has no assignment.  The lock must be held.
+func (ba *BoundedAccessor) assign(key string) (string, error) {
+	primary, err := ba.ring.Get([]byte(key))
+	if err != nil {
+		return "", err
+	}
+
+	instance := primary
+	if ba.full(primary) {
+		candidates, err := ba.ring.candidates([]byte(key))
+		if err != nil {
+			return "", err
+		}
+
+		// since the bounds round up above each instance's share of the load, at least one instance is always under its bound
+		for _, candidate := range candidates {
+			if !ba.full(candidate) {
+				instance = candidate
+				break
+			}
+		}
+
+		ba.measures.spillover.Add(1.0)
+	}
+
+	ba.assignments[key] = instance
+	ba.loads[instance]++
+	ba.measures.assignedKeys.Set(float64(len(ba.assignments)))
+	return instance, nil
+}
+
+// Get returns the instance assigned to the key, assigning one if necessary.
+func (ba *BoundedAccessor) Get(key []byte) (string, error) {
+	if ba.instances == 0 {
+		return "", errNoInstances
+	}
+
+	defer ba.lock.Unlock()
+	ba.lock.Lock()
+
+	if instance, ok := ba.assignments[string(key)]; ok {
+		return instance, nil
+	}
+
+	return ba.assign(string(key))
+}
+
+// Release removes the key's assignment, freeing capacity on its instance.  Releasing a key that
+// is not assigned does nothing.
+func (ba *BoundedAccessor) Release(key []byte) {
+	defer ba.lock.Unlock()
+	ba.lock.Lock()
+
+	if instance, ok := ba.assignments[string(key)]; ok {
+		delete(ba.assignments, string(key))
+		ba.loads[instance]--
+		ba.measures.assignedKeys.Set(float64(len(ba.assignments)))
+	}
+}
+
+// Loads returns a copy of the current number of keys assigned to each instance
+func (ba *BoundedAccessor) Loads() map[string]int {
+	defer ba.lock.Unlock()
+	ba.lock.Lock()
+
+	loads := make(map[string]int, len(ba.loads))
+	for instance, load := range ba.loads {
+		loads[instance] = load
+	}
+
+	return loads
+}
+
+// rebalance assigns the keys of a previous accessor to this accessor, counting each key which moved
+// to a different instance.  This accessor must not be in use yet.
+func (ba *BoundedAccessor) rebalance(previous *BoundedAccessor) {
+	if ba.instances == 0 {
+		return
+	}
+
+	previous.lock.Lock()
+	assignments := make(map[string]string, len(previous.assignments))
+	for key, instance := range previous.assignments {
+		assignments[key] = instance
+	}
+
+	previous.lock.Unlock()
+
+	defer ba.lock.Unlock()
+	ba.lock.Lock()
+
+	// keys stay on their current instance where possible, so that the only keys which move are those on
+	// removed instances and those needed to keep loads under the bound
+	var moved []string
+	for key, instance := range assignments {
+		if load, ok := ba.loads[instance]; ok && load < ba.bound(instance, len(assignments)) {
+			ba.assignments[key] = instance
+			ba.loads[instance]++
+		} else {
+			moved = append(moved, key)
+		}
+	}
+
+	rebalanced := 0
+	for _, key := range moved {
+		if instance, _ := ba.assign(key); instance != assignments[key] {
+			rebalanced++
+		}
+	}
+
+	ba.measures.rebalance.Add(float64(rebalanced))
+	ba.measures.assignedKeys.Set(float64(len(ba.assignments)))
+}
+
+// NewBoundedAccessorFactory produces a factory which uses consistent hashing with bounded loads.  The loadFactor
+// bounds the load of each instance relative to the average load, and must be greater than 1.0.  If loadFactor is not
+// greater than 1.0, DefaultLoadBoundFactor is used.  If vnodeCount is nonpositive, DefaultVnodeCount is used.
+//
+// Each Accessor produced by the returned factory carries over the key assignments from the previous one, so that
+// loads stay bounded across service discovery updates.  Keys that move to a different instance as a result are
+// counted in the BoundedLoadRebalanceCount metric.  The provider may be nil, in which case no metrics are recorded.
+func NewBoundedAccessorFactory(vnodeCount int, loadFactor float64, p provider.Provider) AccessorFactory {
+	if vnodeCount < 1 {
+		vnodeCount = DefaultVnodeCount
+	}
+
+	var (
+		m        = newBoundedMeasures(p)
+		lock     sync.Mutex
+		previous *BoundedAccessor
+	)
+
+	return func(instances []string) Accessor {
+		if len(instances) == 0 {
+			return emptyAccessor{}
+		}
+
+		current := newBoundedAccessor(vnodeCount, loadFactor, instances, m)
+
+		lock.Lock()
+		if previous != nil {
+			current.rebalance(previous)
+		}
+
+		previous = current
+		lock.Unlock()
+
+		return current
+	}
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/xmetrics/xmetricstest"
+)
+
+func boundedKeys(count int) [][]byte {
+	keys := make([][]byte, count)
+	for i := 0; i < count; i++ {
+		keys[i] = []byte(fmt.Sprintf("mac:%012x", i))
+	}
+
+	return keys
+}
+
+func testBoundedAccessorEmpty(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		af      = NewBoundedAccessorFactory(0, 0.0, nil)
+	)
+
+	for _, i := range [][]string{nil, []string{}} {
+		a := af(i)
+		require.NotNil(a)
+		i, err := a.Get([]byte("test"))
+		assert.Empty(i)
+		assert.Error(err)
+	}
+}
+
+func testBoundedAccessorSticky(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		a = newBoundedAccessor(DefaultVnodeCount, 0.0, []string{"instance1", "instance2", "instance3"}, newBoundedMeasures(nil))
+	)
+
+	assert.Equal(DefaultLoadBoundFactor, a.loadFactor)
+
+	first, err := a.Get([]byte("test"))
+	require.NoError(err)
+	for repeat := 0; repeat < 10; repeat++ {
+		instance, err := a.Get([]byte("test"))
+		assert.NoError(err)
+		assert.Equal(first, instance)
+	}
+
+	assert.Equal(1, a.Loads()[first])
+
+	a.Release([]byte("test"))
+	assert.Equal(0, a.Loads()[first])
+
+	// releasing an unassigned key does nothing
+	a.Release([]byte("test"))
+	assert.Equal(0, a.Loads()[first])
+}
+
+func testBoundedAccessorBounded(t *testing.T, loadFactor float64) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		instances  = []string{"instance1", "instance2", "instance3", "instance4", "instance5"}
+		keys       = boundedKeys(1000)
+		consistent = newConsistentAccessor(DefaultVnodeCount, instances)
+
+		p = xmetricstest.NewProvider(nil, Metrics).
+			Expect(BoundedLoadAssignedKeys)(xmetricstest.Value(float64(len(keys))))
+
+		a = NewBoundedAccessorFactory(DefaultVnodeCount, loadFactor, p)(instances)
+	)
+
+	require.IsType((*BoundedAccessor)(nil), a)
+
+	unmoved := 0
+	for _, k := range keys {
+		instance, err := a.Get(k)
+		require.NoError(err)
+
+		expected, err := consistent.Get(k)
+		require.NoError(err)
+		if instance == expected {
+			unmoved++
+		}
+	}
+
+	bound := int(math.Ceil(loadFactor * float64(len(keys)) / float64(len(instances))))
+	for instance, load := range a.(*BoundedAccessor).Loads() {
+		assert.True(load <= bound, "instance %s has load %d, which exceeds the bound %d", instance, load, bound)
+	}
+
+	// keys that stayed under the bound should map to the same instance as plain consistent hashing
+	assert.True(unmoved > len(keys)/2)
+	p.AssertExpectations(t)
+	p.Assert(t, BoundedLoadSpilloverCount)(xmetricstest.Value(float64(len(keys) - unmoved)))
+}
+
+func testBoundedAccessorHotRange(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		a        = newBoundedAccessor(DefaultVnodeCount, 1.5, []string{"instance1", "instance2"}, newBoundedMeasures(nil))
+		hot, err = a.ring.Get([]byte("seed"))
+	)
+
+	require.NoError(err)
+
+	// choose only keys which hash to the same instance, as with a hot range of device ids
+	var keys [][]byte
+	for _, k := range boundedKeys(1000) {
+		if instance, _ := a.ring.Get(k); instance == hot {
+			keys = append(keys, k)
+		}
+	}
+
+	for _, k := range keys {
+		_, err := a.Get(k)
+		require.NoError(err)
+	}
+
+	loads := a.Loads()
+	assert.Len(loads, 2)
+	assert.InDelta(1.5*float64(len(keys))/2.0, loads[hot], 1.0)
+	assert.Equal(len(keys), loads["instance1"]+loads["instance2"])
+}
+
+func testBoundedAccessorRebalance(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		keys = boundedKeys(1000)
+		p    = xmetricstest.NewProvider(nil, Metrics)
+		af   = NewBoundedAccessorFactory(DefaultVnodeCount, 1.25, p)
+
+		before = af([]string{"instance1", "instance2", "instance3", "instance4"})
+	)
+
+	assignments := make(map[string]string, len(keys))
+	for _, k := range keys {
+		instance, err := before.Get(k)
+		require.NoError(err)
+		assignments[string(k)] = instance
+	}
+
+	p.Assert(t, BoundedLoadRebalanceCount)(xmetricstest.Value(0.0))
+
+	// removing an instance moves only its keys, plus any needed to keep loads under the bound
+	after := af([]string{"instance1", "instance2", "instance3"})
+	moved := 0
+	for _, k := range keys {
+		instance, err := after.Get(k)
+		require.NoError(err)
+		assert.NotEqual("instance4", instance)
+		if instance != assignments[string(k)] {
+			moved++
+		}
+	}
+
+	assert.True(moved > 0)
+	assert.True(moved < len(keys)/2)
+	p.Assert(t, BoundedLoadRebalanceCount)(xmetricstest.Value(float64(moved)))
+	p.Assert(t, BoundedLoadAssignedKeys)(xmetricstest.Value(float64(len(keys))))
+
+	bound := int(math.Ceil(1.25 * float64(len(keys)) / 3.0))
+	for instance, load := range after.(*BoundedAccessor).Loads() {
+		assert.True(load <= bound, "instance %s has load %d, which exceeds the bound %d", instance, load, bound)
+	}
+
+	// an empty update doesn't discard the assignments
+	af(nil)
+	again := af([]string{"instance1", "instance2", "instance3"})
+	assert.Equal(after.(*BoundedAccessor).Loads(), again.(*BoundedAccessor).Loads())
+}
+
+func testBoundedAccessorWeighted(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		keys = boundedKeys(1000)
+		a    = NewBoundedAccessorFactory(DefaultVnodeCount, 1.25, nil)([]string{"instance1", "instance2", "instance2"})
+	)
+
+	for _, k := range keys {
+		_, err := a.Get(k)
+		require.NoError(err)
+	}
+
+	// each instance's bound is proportional to its weight
+	loads := a.(*BoundedAccessor).Loads()
+	assert.True(loads["instance1"] <= int(math.Ceil(1.25*float64(len(keys))/3.0)))
+	assert.True(loads["instance2"] <= int(math.Ceil(1.25*float64(len(keys))*2.0/3.0)))
+	assert.True(loads["instance2"] > loads["instance1"])
+}
+
+func TestBoundedAccessor(t *testing.T) {
+	t.Run("Empty", testBoundedAccessorEmpty)
+	t.Run("Sticky", testBoundedAccessorSticky)
+	t.Run("Bounded", func(t *testing.T) {
+		for _, loadFactor := range []float64{1.1, 1.25, 2.0} {
+			t.Run(fmt.Sprintf("LoadFactor=%v", loadFactor), func(t *testing.T) {
+				testBoundedAccessorBounded(t, loadFactor)
+			})
+		}
+	})
+
+	t.Run("HotRange", testBoundedAccessorHotRange)
+	t.Run("Rebalance", testBoundedAccessorRebalance)
+	t.Run("Weighted", testBoundedAccessorWeighted)
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/go-kit/kit/sd"
+)
+
+// MergeStrategy combines the most recent events from several sd.Instancer objects into a single event.
+// The events are given in the same order as the instancers were given to NewCompositeInstancer.
+type MergeStrategy func([]sd.Event) sd.Event
+
+// UnionMerge is a MergeStrategy that combines the instances from every event.  Events with errors are
+// ignored, unless every event has an error, in which case the first error is used.
+func UnionMerge(events []sd.Event) sd.Event {
+	var (
+		merged   sd.Event
+		firstErr error
+		healthy  bool
+	)
+
+	for _, e := range events {
+		if e.Err != nil {
+			if firstErr == nil {
+				firstErr = e.Err
+			}
+
+			continue
+		}
+
+		healthy = true
+		merged.Instances = append(merged.Instances, e.Instances...)
+	}
+
+	if !healthy {
+		merged.Err = firstErr
+	}
+
+	return merged
+}
+
+// PriorityMerge is a MergeStrategy that uses the instances from the first event with no error and
+// at least one instance.  This allows lower priority instancers to act as fallbacks.  If no event has
+// instances, the first error is used.
+func PriorityMerge(events []sd.Event) sd.Event {
+	var firstErr error
+	for _, e := range events {
+		switch {
+		case e.Err == nil && len(e.Instances) > 0:
+			return sd.Event{Instances: append([]string{}, e.Instances...)}
+
+		case e.Err != nil && firstErr == nil:
+			firstErr = e.Err
+		}
+	}
+
+	return sd.Event{Err: firstErr}
+}
+
+// compositeInstancer is an sd.Instancer that merges the events from other instancers
+type compositeInstancer struct {
+	merge      MergeStrategy
+	instancers []sd.Instancer
+	channels   []chan sd.Event
+	stop       chan struct{}
+	stopOnce   sync.Once
+
+	lock     sync.Mutex
+	latest   []sd.Event
+	state    sd.Event
+	registry map[chan<- sd.Event]bool
+}
+
+// NewCompositeInstancer returns an sd.Instancer whose events merge the most recent events from each of
+// the given instancers, using the supplied MergeStrategy.  If the MergeStrategy is nil, UnionMerge is used.
+// Until an instancer sends its first event, its event is treated as having no instances.
+//
+// Stopping the returned instancer does not stop the given instancers, as they are typically owned by
+// another service discovery environment.
+func NewCompositeInstancer(m MergeStrategy, instancers ...sd.Instancer) sd.Instancer {
+	if m == nil {
+		m = UnionMerge
+	}
+
+	ci := &compositeInstancer{
+		merge:      m,
+		instancers: instancers,
+		channels:   make([]chan sd.Event, len(instancers)),
+		stop:       make(chan struct{}),
+		latest:     make([]sd.Event, len(instancers)),
+		registry:   make(map[chan<- sd.Event]bool),
+	}
+
+	ci.state = ci.merge(ci.latest)
+	for position, i := range instancers {
+		ci.channels[position] = make(chan sd.Event, 10)
+		go ci.receive(position)
+		i.Register(ci.channels[position])
+	}
+
+	return ci
+}
+
+// receive is a goroutine that handles the events from the instancer at the given position
+func (ci *compositeInstancer) receive(position int) {
+	events := ci.channels[position]
+	for {
+		select {
+		case e := <-events:
+			ci.update(position, e)
+
+		case <-ci.stop:
+			return
+		}
+	}
+}
+
+func (ci *compositeInstancer) update(position int, e sd.Event) {
+	defer ci.lock.Unlock()
+	ci.lock.Lock()
+
+	ci.latest[position] = e
+	merged := ci.merge(ci.latest)
+	sort.Strings(merged.Instances)
+	if reflect.DeepEqual(ci.state, merged) {
+		return
+	}
+
+	ci.state = merged
+	for c := range ci.registry {
+		c <- ci.state
+	}
+}
+
+func (ci *compositeInstancer) Register(ch chan<- sd.Event) {
+	defer ci.lock.Unlock()
+	ci.lock.Lock()
+	ci.registry[ch] = true
+
+	// push the current state to the new channel
+	ch <- ci.state
+}
+
+func (ci *compositeInstancer) Deregister(ch chan<- sd.Event) {
+	defer ci.lock.Unlock()
+	ci.lock.Lock()
+	delete(ci.registry, ch)
+}
+
+func (ci *compositeInstancer) Stop() {
+	ci.stopOnce.Do(func() {
+		for position, i := range ci.instancers {
+			i.Deregister(ci.channels[position])
+		}
+
+		close(ci.stop)
+	})
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/sd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnionMerge(t *testing.T) {
+	var (
+		firstErr  = errors.New("first")
+		secondErr = errors.New("second")
+	)
+
+	testData := []struct {
+		events   []sd.Event
+		expected sd.Event
+	}{
+		{nil, sd.Event{}},
+		{[]sd.Event{{}, {}}, sd.Event{}},
+		{
+			[]sd.Event{{Instances: []string{"a", "b"}}, {Instances: []string{"c"}}},
+			sd.Event{Instances: []string{"a", "b", "c"}},
+		},
+		{
+			[]sd.Event{{Err: firstErr}, {Instances: []string{"c"}}},
+			sd.Event{Instances: []string{"c"}},
+		},
+		{
+			[]sd.Event{{Err: firstErr}, {Err: secondErr}},
+			sd.Event{Err: firstErr},
+		},
+	}
+
+	for i, record := range testData {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert.Equal(t, record.expected, UnionMerge(record.events))
+		})
+	}
+}
+
+func TestPriorityMerge(t *testing.T) {
+	var (
+		firstErr  = errors.New("first")
+		secondErr = errors.New("second")
+	)
+
+	testData := []struct {
+		events   []sd.Event
+		expected sd.Event
+	}{
+		{nil, sd.Event{}},
+		{[]sd.Event{{}, {}}, sd.Event{}},
+		{
+			[]sd.Event{{Instances: []string{"a", "b"}}, {Instances: []string{"c"}}},
+			sd.Event{Instances: []string{"a", "b"}},
+		},
+		{
+			[]sd.Event{{}, {Instances: []string{"c"}}},
+			sd.Event{Instances: []string{"c"}},
+		},
+		{
+			[]sd.Event{{Err: firstErr}, {Instances: []string{"c"}}},
+			sd.Event{Instances: []string{"c"}},
+		},
+		{
+			[]sd.Event{{Err: firstErr}, {Err: secondErr}},
+			sd.Event{Err: firstErr},
+		},
+	}
+
+	for i, record := range testData {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert.Equal(t, record.expected, PriorityMerge(record.events))
+		})
+	}
+}
+
+// testInstancer is a simple sd.Instancer whose events are sent explicitly
+type testInstancer struct {
+	lock     sync.Mutex
+	state    sd.Event
+	registry map[chan<- sd.Event]bool
+}
+
+func newTestInstancer(initial sd.Event) *testInstancer {
+	return &testInstancer{state: initial, registry: make(map[chan<- sd.Event]bool)}
+}
+
+func (ti *testInstancer) send(e sd.Event) {
+	defer ti.lock.Unlock()
+	ti.lock.Lock()
+	ti.state = e
+	for c := range ti.registry {
+		c <- e
+	}
+}
+
+func (ti *testInstancer) registered() int {
+	defer ti.lock.Unlock()
+	ti.lock.Lock()
+	return len(ti.registry)
+}
+
+func (ti *testInstancer) Register(ch chan<- sd.Event) {
+	defer ti.lock.Unlock()
+	ti.lock.Lock()
+	ti.registry[ch] = true
+	ch <- ti.state
+}
+
+func (ti *testInstancer) Deregister(ch chan<- sd.Event) {
+	defer ti.lock.Unlock()
+	ti.lock.Lock()
+	delete(ti.registry, ch)
+}
+
+func (ti *testInstancer) Stop() {}
+
+func expectCompositeEvent(t *testing.T, events <-chan sd.Event, expected sd.Event) {
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e := <-events:
+			if assert.ObjectsAreEqual(expected, e) {
+				return
+			}
+
+		case <-timeout:
+			assert.Fail(t, "No matching event", "expected: %v", expected)
+			return
+		}
+	}
+}
+
+func testNewCompositeInstancerUnion(t *testing.T) {
+	var (
+		require = require.New(t)
+		assert  = assert.New(t)
+
+		first  = newTestInstancer(sd.Event{Instances: []string{"b", "a"}})
+		second = newTestInstancer(sd.Event{Instances: []string{"c"}})
+		ci     = NewCompositeInstancer(nil, first, second)
+		events = make(chan sd.Event, 10)
+	)
+
+	require.NotNil(ci)
+	ci.Register(events)
+	expectCompositeEvent(t, events, sd.Event{Instances: []string{"a", "b", "c"}})
+
+	second.send(sd.Event{Err: errors.New("expected")})
+	expectCompositeEvent(t, events, sd.Event{Instances: []string{"a", "b"}})
+
+	first.send(sd.Event{Instances: []string{"d"}})
+	expectCompositeEvent(t, events, sd.Event{Instances: []string{"d"}})
+
+	ci.Deregister(events)
+	ci.Stop()
+	ci.Stop() // idempotent
+	assert.Zero(first.registered())
+	assert.Zero(second.registered())
+}
+
+func testNewCompositeInstancerPriority(t *testing.T) {
+	var (
+		require = require.New(t)
+
+		primary  = newTestInstancer(sd.Event{Instances: []string{"primary"}})
+		fallback = newTestInstancer(sd.Event{Instances: []string{"fallback"}})
+		ci       = NewCompositeInstancer(PriorityMerge, primary, fallback)
+		events   = make(chan sd.Event, 10)
+	)
+
+	require.NotNil(ci)
+	defer ci.Stop()
+
+	ci.Register(events)
+	defer ci.Deregister(events)
+	expectCompositeEvent(t, events, sd.Event{Instances: []string{"primary"}})
+
+	primary.send(sd.Event{})
+	expectCompositeEvent(t, events, sd.Event{Instances: []string{"fallback"}})
+
+	primary.send(sd.Event{Instances: []string{"primary"}})
+	expectCompositeEvent(t, events, sd.Event{Instances: []string{"primary"}})
+}
+
+func TestNewCompositeInstancer(t *testing.T) {
+	t.Run("Union", testNewCompositeInstancerUnion)
+	t.Run("Priority", testNewCompositeInstancerPriority)
+}
@@ -0,0 +1,164 @@
+package consul
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+	"github.com/xmidt-org/webpa-common/service"
+)
+
+const (
+	// LocalAffinity is the datacenter affinity policy that prefers the local datacenter, then the remaining
+	// datacenters in name order.
+	LocalAffinity = "local"
+
+	// RTTAffinity is the datacenter affinity policy that prefers datacenters in order of their estimated round trip
+	// time from the local datacenter, as reported by the consul catalog.  This is the default policy.
+	RTTAffinity = "rtt"
+
+	// PriorityAffinity is the datacenter affinity policy that prefers datacenters in a static, configured order.
+	// Datacenters that are not in the configured list are preferred last, in round trip time order.
+	PriorityAffinity = "priority"
+)
+
+// Affinity describes how a cross datacenter watch prefers some datacenters over others.  When a cross datacenter
+// Watch has an Affinity, its per-datacenter instancers are merged into a single instancer whose instances come
+// from the most preferred datacenter that has any instances.  The other datacenters act as failovers.
+type Affinity struct {
+	// Policy is the ordering policy, one of local, rtt, or priority.  If not supplied, RTTAffinity is used.
+	Policy string `json:"policy,omitempty"`
+
+	// LocalDatacenter is the name of the local datacenter.  If not supplied, the datacenter from the consul client
+	// configuration is used, and if that is not supplied the nearest datacenter reported by the catalog is used.
+	LocalDatacenter string `json:"localDatacenter,omitempty"`
+
+	// Priority is the list of datacenters, most preferred first, used by the priority policy
+	Priority []string `json:"priority,omitempty"`
+}
+
+func (a *Affinity) policy() string {
+	if a != nil && len(a.Policy) > 0 {
+		return strings.ToLower(a.Policy)
+	}
+
+	return RTTAffinity
+}
+
+func (a *Affinity) validate() error {
+	switch a.policy() {
+	case LocalAffinity, RTTAffinity, PriorityAffinity:
+		return nil
+
+	default:
+		return fmt.Errorf("invalid datacenter affinity policy: %s", a.Policy)
+	}
+}
+
+// localDatacenter determines the local datacenter, given the datacenters in catalog order
+func (a *Affinity) localDatacenter(co Options, datacenters []string) string {
+	switch {
+	case a != nil && len(a.LocalDatacenter) > 0:
+		return a.LocalDatacenter
+
+	case len(co.config().Datacenter) > 0:
+		return co.config().Datacenter
+
+	case len(datacenters) > 0:
+		// the catalog sorts datacenters by round trip time, so the local datacenter is first
+		return datacenters[0]
+
+	default:
+		return ""
+	}
+}
+
+// order returns the given datacenters, which must be in catalog order, in order of preference
+func (a *Affinity) order(local string, datacenters []string) []string {
+	ordered := make([]string, 0, len(datacenters))
+	switch a.policy() {
+	case LocalAffinity:
+		var remote []string
+		for _, datacenter := range datacenters {
+			if datacenter == local {
+				ordered = append(ordered, datacenter)
+			} else {
+				remote = append(remote, datacenter)
+			}
+		}
+
+		sort.Strings(remote)
+		ordered = append(ordered, remote...)
+
+	case PriorityAffinity:
+		present := make(map[string]bool, len(datacenters))
+		for _, datacenter := range datacenters {
+			present[datacenter] = true
+		}
+
+		for _, datacenter := range a.Priority {
+			if present[datacenter] {
+				ordered = append(ordered, datacenter)
+				delete(present, datacenter)
+			}
+		}
+
+		for _, datacenter := range datacenters {
+			if present[datacenter] {
+				ordered = append(ordered, datacenter)
+			}
+		}
+
+	default:
+		ordered = append(ordered, datacenters...)
+	}
+
+	return ordered
+}
+
+// affinityInstancer merges the instancers for several datacenters.  Unlike the composite instancer it wraps,
+// this type owns the per-datacenter instancers and stops them when it is stopped.
+type affinityInstancer struct {
+	sd.Instancer
+	datacenters []sd.Instancer
+}
+
+func (ai affinityInstancer) Stop() {
+	ai.Instancer.Stop()
+	for _, i := range ai.datacenters {
+		i.Stop()
+	}
+}
+
+// newAffinityInstancerKey produces the instancer key for a watch with an affinity.  The key includes the
+// ordered datacenters, so that a change in the datacenters or their order produces a new instancer.
+func newAffinityInstancerKey(w Watch, ordered []string) string {
+	w.QueryOptions.Datacenter = ""
+	return fmt.Sprintf("%s{affinity=%s}{datacenters=%s}", newInstancerKey(w), w.Affinity.policy(), ordered)
+}
+
+// newAffinityInstancer creates an instancer for the given datacenters, in order of preference, which
+// uses the instances from the most preferred datacenter that has any.
+func newAffinityInstancer(l log.Logger, c Client, m *service.MetadataStore, w Watch, ordered []string) sd.Instancer {
+	datacenters := make([]sd.Instancer, len(ordered))
+	for position, datacenter := range ordered {
+		w.QueryOptions.Datacenter = datacenter
+		datacenters[position] = newInstancer(l, c, m, w)
+	}
+
+	return service.NewContextualInstancer(
+		affinityInstancer{
+			Instancer:   service.NewCompositeInstancer(service.PriorityMerge, datacenters...),
+			datacenters: datacenters,
+		},
+		map[string]interface{}{
+			"service":     w.Service,
+			"tags":        w.Tags,
+			"passingOnly": w.PassingOnly,
+			"affinity":    w.Affinity.policy(),
+			"datacenters": ordered,
+		},
+	)
+}
@@ -0,0 +1,287 @@
+package consul
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/sd"
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/service"
+)
+
+func testAffinityPolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(RTTAffinity, (*Affinity)(nil).policy())
+	assert.Equal(RTTAffinity, new(Affinity).policy())
+	assert.Equal(LocalAffinity, (&Affinity{Policy: "Local"}).policy())
+	assert.Equal(PriorityAffinity, (&Affinity{Policy: PriorityAffinity}).policy())
+
+	assert.NoError((*Affinity)(nil).validate())
+	assert.NoError(new(Affinity).validate())
+	assert.NoError((&Affinity{Policy: "LOCAL"}).validate())
+	assert.NoError((&Affinity{Policy: RTTAffinity}).validate())
+	assert.NoError((&Affinity{Policy: PriorityAffinity}).validate())
+	assert.Error((&Affinity{Policy: "nosuch"}).validate())
+}
+
+func testAffinityLocalDatacenter(t *testing.T) {
+	var (
+		assert      = assert.New(t)
+		datacenters = []string{"dc2", "dc1", "dc3"}
+	)
+
+	assert.Equal("dc3", (&Affinity{LocalDatacenter: "dc3"}).localDatacenter(Options{Client: &api.Config{Datacenter: "dc1"}}, datacenters))
+	assert.Equal("dc1", new(Affinity).localDatacenter(Options{Client: &api.Config{Datacenter: "dc1"}}, datacenters))
+	assert.Equal("dc2", new(Affinity).localDatacenter(Options{Client: new(api.Config)}, datacenters))
+	assert.Empty(new(Affinity).localDatacenter(Options{Client: new(api.Config)}, nil))
+}
+
+func testAffinityOrder(t *testing.T) {
+	// datacenters are given in catalog, i.e. round trip time, order
+	datacenters := []string{"dc-east", "dc-west", "dc-central", "dc-south"}
+
+	testData := []struct {
+		affinity Affinity
+		local    string
+		expected []string
+	}{
+		{
+			Affinity{},
+			"dc-east",
+			[]string{"dc-east", "dc-west", "dc-central", "dc-south"},
+		},
+		{
+			Affinity{Policy: RTTAffinity},
+			"dc-west",
+			[]string{"dc-east", "dc-west", "dc-central", "dc-south"},
+		},
+		{
+			Affinity{Policy: LocalAffinity},
+			"dc-south",
+			[]string{"dc-south", "dc-central", "dc-east", "dc-west"},
+		},
+		{
+			Affinity{Policy: LocalAffinity},
+			"dc-nosuch",
+			[]string{"dc-central", "dc-east", "dc-south", "dc-west"},
+		},
+		{
+			Affinity{Policy: PriorityAffinity, Priority: []string{"dc-south", "dc-nosuch", "dc-west"}},
+			"dc-east",
+			[]string{"dc-south", "dc-west", "dc-east", "dc-central"},
+		},
+		{
+			Affinity{Policy: PriorityAffinity},
+			"dc-east",
+			[]string{"dc-east", "dc-west", "dc-central", "dc-south"},
+		},
+	}
+
+	for i, record := range testData {
+		t.Logf("%d: %#v", i, record)
+		assert.Equal(t, record.expected, record.affinity.order(record.local, datacenters))
+	}
+}
+
+func TestAffinity(t *testing.T) {
+	t.Run("Policy", testAffinityPolicy)
+	t.Run("LocalDatacenter", testAffinityLocalDatacenter)
+	t.Run("Order", testAffinityOrder)
+}
+
+// inDatacenter matches the query options for a given datacenter, waiting on the given index
+func inDatacenter(datacenter string, index uint64) interface{} {
+	return mock.MatchedBy(func(qo *api.QueryOptions) bool {
+		return qo.Datacenter == datacenter && qo.WaitIndex == index
+	})
+}
+
+func TestNewAffinityInstancer(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		logger = logging.NewTestLogger(nil, t)
+		client = new(mockClient)
+		block  = make(chan time.Time)
+		events = make(chan sd.Event, 10)
+
+		w = Watch{
+			Service:         "talaria",
+			CrossDatacenter: true,
+			Affinity:        &Affinity{Policy: PriorityAffinity, Priority: []string{"dc1", "dc2", "dc3"}},
+		}
+	)
+
+	defer close(block)
+
+	// the most preferred datacenter has no instances, so the next one is used
+	client.On("Service", "talaria", "", false, inDatacenter("dc1", 0)).
+		Return([]*api.ServiceEntry{}, &api.QueryMeta{LastIndex: 1}, error(nil)).Once()
+	client.On("Service", "talaria", "", false, inDatacenter("dc2", 0)).
+		Return([]*api.ServiceEntry{newServiceEntry("talaria-dc2.com", 8080)}, &api.QueryMeta{LastIndex: 1}, error(nil)).Once()
+	client.On("Service", "talaria", "", false, inDatacenter("dc3", 0)).
+		Return([]*api.ServiceEntry{newServiceEntry("talaria-dc3.com", 8080)}, &api.QueryMeta{LastIndex: 1}, error(nil)).Once()
+	client.On("Service", "talaria", "", false, mock.MatchedBy(func(qo *api.QueryOptions) bool { return qo.WaitIndex > 0 })).
+		WaitUntil(block).
+		Return([]*api.ServiceEntry{}, &api.QueryMeta{LastIndex: 1}, error(nil))
+
+	i := newAffinityInstancer(logger, client, nil, w, w.Affinity.order("dc3", []string{"dc3", "dc2", "dc1"}))
+	require.NotNil(i)
+
+	ci, ok := i.(service.ContextualInstancer)
+	require.True(ok)
+	assert.Equal(PriorityAffinity, ci.Metadata()["affinity"])
+	assert.Equal([]string{"dc1", "dc2", "dc3"}, ci.Metadata()["datacenters"])
+
+	i.Register(events)
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e := <-events:
+			if len(e.Instances) == 1 && e.Instances[0] == "talaria-dc2.com:8080" {
+				i.Stop()
+				return
+			}
+
+		case <-timeout:
+			require.Fail("The preferred datacenter with instances was not used")
+			return
+		}
+	}
+}
+
+func TestNewAffinityInstancerKey(t *testing.T) {
+	var (
+		assert = assert.New(t)
+
+		w = Watch{
+			Service:         "talaria",
+			CrossDatacenter: true,
+			Affinity:        &Affinity{Policy: LocalAffinity},
+		}
+
+		key = newAffinityInstancerKey(w, []string{"dc1", "dc2"})
+	)
+
+	assert.Contains(key, "{datacenter=}")
+	assert.Contains(key, "{affinity=local}")
+	assert.Contains(key, "{datacenters=[dc1 dc2]}")
+	assert.NotEqual(key, newAffinityInstancerKey(w, []string{"dc2", "dc1"}))
+	assert.NotEqual(key, newAffinityInstancerKey(w, []string{"dc1"}))
+}
+
+func testNewInstancersAffinity(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		logger = logging.NewTestLogger(nil, t)
+		client = new(mockClient)
+		block  = make(chan time.Time)
+
+		w = Watch{
+			Service:         "talaria",
+			CrossDatacenter: true,
+			Affinity:        &Affinity{Policy: LocalAffinity},
+		}
+	)
+
+	defer close(block)
+	client.On("Datacenters").Return([]string{"dc2", "dc1"}, error(nil)).Once()
+	client.On("Service", "talaria", "", false, waitIndex(0)).
+		Return([]*api.ServiceEntry{}, &api.QueryMeta{LastIndex: 1}, error(nil))
+	client.On("Service", "talaria", "", false, waitIndex(1)).
+		WaitUntil(block).
+		Return([]*api.ServiceEntry{}, &api.QueryMeta{LastIndex: 1}, error(nil))
+
+	// duplicate watches are ignored
+	i, err := newInstancers(logger, client, nil, Options{Watches: []Watch{w, w}})
+	require.NoError(err)
+	require.Len(i, 1)
+	assert.True(i.Has(newAffinityInstancerKey(w, []string{"dc2", "dc1"})))
+
+	i.Stop()
+	client.AssertNumberOfCalls(t, "Datacenters", 1)
+}
+
+func testNewInstancersInvalidAffinity(t *testing.T) {
+	var (
+		assert = assert.New(t)
+
+		logger = logging.NewTestLogger(nil, t)
+		client = new(mockClient)
+
+		w = Watch{
+			Service:         "talaria",
+			CrossDatacenter: true,
+			Affinity:        &Affinity{Policy: "nosuch"},
+		}
+	)
+
+	client.On("Datacenters").Return([]string{"dc1"}, error(nil)).Once()
+
+	i, err := newInstancers(logger, client, nil, Options{Watches: []Watch{w}})
+	assert.Empty(i)
+	assert.Error(err)
+
+	client.AssertExpectations(t)
+}
+
+func TestNewInstancersAffinity(t *testing.T) {
+	t.Run("Valid", testNewInstancersAffinity)
+	t.Run("Invalid", testNewInstancersInvalidAffinity)
+}
+
+func TestCreateAffinityInstancer(t *testing.T) {
+	var (
+		assert = assert.New(t)
+
+		logger = logging.NewTestLogger(nil, t)
+		client = new(mockClient)
+		block  = make(chan time.Time)
+
+		w = Watch{
+			Service:         "talaria",
+			CrossDatacenter: true,
+			Affinity:        &Affinity{Policy: RTTAffinity},
+		}
+
+		dw = &datacenterWatcher{
+			logger:              logger,
+			environment:         environment{new(service.MockEnvironment), client, nil},
+			options:             Options{Watches: []Watch{w}},
+			inactiveDatacenters: map[string]bool{"dc2": true},
+		}
+
+		keys            = make(map[string]bool)
+		instancersToAdd = make(service.Instancers)
+		expectedKey     = newAffinityInstancerKey(w, []string{"dc1", "dc3"})
+	)
+
+	defer close(block)
+	client.On("Service", "talaria", "", false, waitIndex(0)).
+		Return([]*api.ServiceEntry{}, &api.QueryMeta{LastIndex: 1}, error(nil))
+	client.On("Service", "talaria", "", false, waitIndex(1)).
+		WaitUntil(block).
+		Return([]*api.ServiceEntry{}, &api.QueryMeta{LastIndex: 1}, error(nil))
+
+	// inactive datacenters are excluded
+	createAffinityInstancer(keys, instancersToAdd, service.Instancers{}, dw, []string{"dc1", "dc2", "dc3"}, w)
+	assert.Equal(map[string]bool{expectedKey: true}, keys)
+	assert.Len(instancersToAdd, 1)
+	assert.True(instancersToAdd.Has(expectedKey))
+
+	// an existing instancer for the same datacenters is kept
+	keys = make(map[string]bool)
+	createAffinityInstancer(keys, make(service.Instancers), instancersToAdd, dw, []string{"dc1", "dc2", "dc3"}, w)
+	assert.Equal(map[string]bool{expectedKey: true}, keys)
+
+	instancersToAdd.Stop()
+}
@@ -19,15 +19,41 @@ func NewClient(c *api.Client) Client {
 	return client{
 		gokitconsul.NewClient(c),
 		c,
+		newQueryTracker(),
 	}
 }
 
 // client implements go-kit's consul Client interface and extends it to the local Client interface
 type client struct {
 	gokitconsul.Client
-	c *api.Client
+	c       *api.Client
+	queries *queryTracker
 }
 
 func (c client) Datacenters() ([]string, error) {
 	return c.c.Catalog().Datacenters()
 }
+
+// Service queries consul for a service, recording the query metadata of each successful query
+func (c client) Service(service, tag string, passingOnly bool, queryOpts *api.QueryOptions) ([]*api.ServiceEntry, *api.QueryMeta, error) {
+	entries, meta, err := c.Client.Service(service, tag, passingOnly, queryOpts)
+	if err == nil && meta != nil {
+		c.queries.record(service, queryOpts, meta)
+	}
+
+	return entries, meta, err
+}
+
+// APIClient returns the hashicorp consul client wrapped by this Client
+func (c client) APIClient() *api.Client {
+	return c.c
+}
+
+// QueryMeta returns the metadata of the most recent successful query for each service and datacenter
+func (c client) QueryMeta() []QueryMeta {
+	return c.queries.get()
+}
+
+func (c client) queryTracker() *queryTracker {
+	return c.queries
+}
@@ -0,0 +1,67 @@
+package consul
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClient(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		server = httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			if request.URL.Path != "/v1/health/service/talaria" {
+				response.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			response.Header().Set("X-Consul-Index", "42")
+			response.Header().Set("X-Consul-LastContact", "1500")
+			response.Header().Set("X-Consul-KnownLeader", "true")
+			response.Header().Set("Content-Type", "application/json")
+			response.Write([]byte(`[{"Node": {"Node": "node1"}, "Service": {"Service": "talaria", "Address": "talaria.com", "Port": 8080}}]`))
+		}))
+	)
+
+	defer server.Close()
+
+	apiClient, err := api.NewClient(&api.Config{Address: server.Listener.Addr().String()})
+	require.NoError(err)
+
+	c := NewClient(apiClient)
+	require.NotNil(c)
+
+	ac, ok := c.(interface{ APIClient() *api.Client })
+	require.True(ok)
+	assert.Equal(apiClient, ac.APIClient())
+
+	qm, ok := c.(interface{ QueryMeta() []QueryMeta })
+	require.True(ok)
+	assert.Empty(qm.QueryMeta())
+
+	entries, meta, err := c.Service("talaria", "", false, &api.QueryOptions{Datacenter: "dc1"})
+	require.NoError(err)
+	assert.Len(entries, 1)
+	require.NotNil(meta)
+	assert.Equal(uint64(42), meta.LastIndex)
+
+	queries := qm.QueryMeta()
+	require.Len(queries, 1)
+	assert.Equal("talaria", queries[0].Service)
+	assert.Equal("dc1", queries[0].Datacenter)
+	assert.Equal(uint64(42), queries[0].LastIndex)
+	assert.Equal(1500*time.Millisecond, queries[0].LastContact)
+	assert.True(queries[0].KnownLeader)
+
+	// failed queries are not recorded
+	_, _, err = c.Service("nosuch", "", false, nil)
+	assert.Error(err)
+	assert.Len(qm.QueryMeta(), 1)
+}
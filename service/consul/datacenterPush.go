@@ -0,0 +1,139 @@
+package consul
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/xmidt-org/argus/model"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+// DefaultDatacenterPushTimeout is the default time the datacenter watcher waits for a push before it falls back
+// to polling the chrysom bucket
+const DefaultDatacenterPushTimeout = 5 * time.Minute
+
+// DatacenterPush receives the items of the chrysom datacenter bucket as they are pushed, rather than polling for them.
+// Items can be pushed through ServeHTTP, which accepts the same JSON array of items that argus returns for a bucket and
+// so can be registered as an argus webhook, or through Update, which allows a DatacenterPush to be used as a chrysom.Listener.
+//
+// ServeHTTP performs no authorization.  Callers should decorate this handler as appropriate before exposing it.
+type DatacenterPush struct {
+	lock    sync.Mutex
+	pending []model.Item
+	pushed  bool
+	signal  chan struct{}
+}
+
+// NewDatacenterPush creates a DatacenterPush, suitable for Options.DatacenterPush
+func NewDatacenterPush() *DatacenterPush {
+	return &DatacenterPush{
+		signal: make(chan struct{}, 1),
+	}
+}
+
+// Update pushes the current items in the datacenter bucket.  Each push replaces the previous one, so if pushes arrive
+// faster than they are applied only the most recent is used.  This method never blocks.
+func (dp *DatacenterPush) Update(items []model.Item) {
+	dp.lock.Lock()
+	dp.pending = items
+	dp.pushed = true
+	dp.lock.Unlock()
+
+	select {
+	case dp.signal <- struct{}{}:
+	default:
+	}
+}
+
+// next returns the most recent push, if any
+func (dp *DatacenterPush) next() ([]model.Item, bool) {
+	defer dp.lock.Unlock()
+	dp.lock.Lock()
+
+	items, pushed := dp.pending, dp.pushed
+	dp.pending, dp.pushed = nil, false
+	return items, pushed
+}
+
+// ServeHTTP decodes a JSON array of items from the request body and pushes them
+func (dp *DatacenterPush) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	var items []model.Item
+	if err := json.NewDecoder(request.Body).Decode(&items); err != nil {
+		http.Error(response, "Unable to decode datacenter items", http.StatusBadRequest)
+		return
+	}
+
+	dp.Update(items)
+	response.WriteHeader(http.StatusOK)
+}
+
+// watchPushes applies pushed datacenter items as they arrive.  If no push arrives within the timeout, the chrysom
+// bucket is polled on the given interval until a push arrives.
+func (d *datacenterWatcher) watchPushes(push *DatacenterPush, timeout, pullInterval time.Duration) {
+	var (
+		fallback = time.NewTimer(timeout)
+		poll     <-chan time.Time
+		stopPoll = func() {}
+	)
+
+	defer func() {
+		fallback.Stop()
+		stopPoll()
+	}()
+
+	for {
+		select {
+		case <-d.environment.Closed():
+			return
+
+		case <-push.signal:
+			items, ok := push.next()
+			if !ok {
+				continue
+			}
+
+			if poll != nil {
+				d.logger.Log(level.Key(), level.InfoValue(), logging.MessageKey(), "datacenter push received, no longer polling")
+				stopPoll()
+				poll, stopPoll = nil, func() {}
+			}
+
+			if !fallback.Stop() {
+				select {
+				case <-fallback.C:
+				default:
+				}
+			}
+
+			fallback.Reset(timeout)
+			d.applyPush(items)
+
+		case <-fallback.C:
+			d.logger.Log(level.Key(), level.WarnValue(), logging.MessageKey(), "no datacenter push received, falling back to polling", "timeout", timeout, "pullInterval", pullInterval)
+			poll, stopPoll = tickerFactory(pullInterval)
+
+		case <-poll:
+			items, err := d.chrysomClient.GetItems("", true)
+			if err != nil {
+				d.logger.Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "failed to poll datacenters", logging.ErrorKey(), err)
+				d.measures.updates.With(SourceLabel, ChrysomSource, OutcomeLabel, FailureOutcome).Add(1.0)
+				continue
+			}
+
+			d.applyPush(items)
+		}
+	}
+}
+
+// applyPush updates the inactive datacenters, then requests that the instancers be updated right away
+// rather than on the next consul watch interval
+func (d *datacenterWatcher) applyPush(items []model.Item) {
+	d.updateInactive(items)
+	select {
+	case d.refresh <- struct{}{}:
+	default:
+	}
+}
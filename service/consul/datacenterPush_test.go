@@ -0,0 +1,248 @@
+package consul
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/argus/chrysom"
+	"github.com/xmidt-org/argus/model"
+	"github.com/xmidt-org/webpa-common/service"
+	"github.com/xmidt-org/webpa-common/xmetrics/xmetricstest"
+)
+
+func inactiveItem(name string) model.Item {
+	return model.Item{
+		UUID: name,
+		Data: map[string]interface{}{"name": name, "inactive": true},
+	}
+}
+
+func testDatacenterPushUpdate(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		dp     = NewDatacenterPush()
+	)
+
+	items, ok := dp.next()
+	assert.Empty(items)
+	assert.False(ok)
+
+	// only the most recent push is kept
+	dp.Update([]model.Item{inactiveItem("dc1")})
+	dp.Update([]model.Item{inactiveItem("dc2")})
+	assert.Len(dp.signal, 1)
+
+	items, ok = dp.next()
+	assert.Equal([]model.Item{inactiveItem("dc2")}, items)
+	assert.True(ok)
+
+	// an empty push is still a push, since it means no datacenters are inactive
+	dp.Update(nil)
+	items, ok = dp.next()
+	assert.Empty(items)
+	assert.True(ok)
+}
+
+func testDatacenterPushServeHTTP(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		dp      = NewDatacenterPush()
+
+		body, err = json.Marshal([]model.Item{inactiveItem("dc1")})
+	)
+
+	require.NoError(err)
+
+	response := httptest.NewRecorder()
+	dp.ServeHTTP(response, httptest.NewRequest("POST", "/", strings.NewReader("this is not JSON")))
+	assert.Equal(http.StatusBadRequest, response.Code)
+	_, ok := dp.next()
+	assert.False(ok)
+
+	response = httptest.NewRecorder()
+	dp.ServeHTTP(response, httptest.NewRequest("POST", "/", strings.NewReader(string(body))))
+	assert.Equal(http.StatusOK, response.Code)
+
+	items, ok := dp.next()
+	assert.True(ok)
+	require.Len(items, 1)
+	assert.Equal("dc1", items[0].Data["name"])
+}
+
+func TestDatacenterPush(t *testing.T) {
+	t.Run("Update", testDatacenterPushUpdate)
+	t.Run("ServeHTTP", testDatacenterPushServeHTTP)
+}
+
+// newPushWatcher creates a datacenterWatcher whose chrysom client polls the given argus server
+func newPushWatcher(t *testing.T, argus *httptest.Server, closed <-chan struct{}) *datacenterWatcher {
+	env := new(service.MockEnvironment)
+	env.On("Closed").Return(closed)
+
+	chrysomClient, err := chrysom.CreateClient(chrysom.ClientConfig{
+		Bucket:          "datacenters",
+		PullInterval:    time.Minute,
+		Address:         argus.URL,
+		AdminToken:      "admin-token",
+		MetricsProvider: xmetricstest.NewProvider(nil, chrysom.Metrics),
+	})
+
+	require.NoError(t, err)
+	return &datacenterWatcher{
+		logger:              log.NewNopLogger(),
+		environment:         environment{env, new(mockClient), nil},
+		inactiveDatacenters: make(map[string]bool),
+		chrysomClient:       chrysomClient,
+		measures:            newMeasures(nil),
+		refresh:             make(chan struct{}, 1),
+	}
+}
+
+func (d *datacenterWatcher) isInactive(datacenter string) bool {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	return d.inactiveDatacenters[datacenter]
+}
+
+func testWatchPushesPush(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		closed = make(chan struct{})
+		argus  = httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, _ *http.Request) {
+			assert.Fail("The chrysom bucket should not be polled")
+		}))
+
+		push = NewDatacenterPush()
+		w    = newPushWatcher(t, argus, closed)
+		done = make(chan struct{})
+	)
+
+	defer argus.Close()
+	go func() {
+		defer close(done)
+		w.watchPushes(push, time.Hour, time.Minute)
+	}()
+
+	push.Update([]model.Item{inactiveItem("dc1")})
+	select {
+	case <-w.refresh:
+		assert.True(w.isInactive("dc1"))
+	case <-time.After(5 * time.Second):
+		assert.Fail("The push did not refresh the instancers")
+	}
+
+	close(closed)
+	<-done
+}
+
+func testWatchPushesFallback(t *testing.T) {
+	defer resetTickerFactory()
+
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		closed  = make(chan struct{})
+
+		argus = httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			assert.Equal("/api/v1/store/datacenters", request.URL.Path)
+			json.NewEncoder(response).Encode([]model.Item{inactiveItem("dc1")})
+		}))
+
+		tickerFactory = prepareMockTickerFactory()
+		ticker        = make(chan time.Time)
+		tickerStopped = make(chan struct{})
+
+		push = NewDatacenterPush()
+		w    = newPushWatcher(t, argus, closed)
+		done = make(chan struct{})
+	)
+
+	defer argus.Close()
+	tickerFactory.On("NewTicker", 30*time.Second).
+		Return((<-chan time.Time)(ticker), func() { close(tickerStopped) }).Once()
+
+	go func() {
+		defer close(done)
+		w.watchPushes(push, time.Millisecond, 30*time.Second)
+	}()
+
+	// no push arrives, so the bucket is polled
+	select {
+	case ticker <- time.Now():
+	case <-time.After(5 * time.Second):
+		require.Fail("The watcher did not fall back to polling")
+	}
+
+	select {
+	case <-w.refresh:
+		assert.True(w.isInactive("dc1"))
+	case <-time.After(5 * time.Second):
+		require.Fail("Polling did not refresh the instancers")
+	}
+
+	// a push stops the polling
+	push.Update([]model.Item{inactiveItem("dc2")})
+	select {
+	case <-tickerStopped:
+	case <-time.After(5 * time.Second):
+		require.Fail("Polling was not stopped by a push")
+	}
+
+	select {
+	case <-w.refresh:
+		assert.False(w.isInactive("dc1"))
+		assert.True(w.isInactive("dc2"))
+	case <-time.After(5 * time.Second):
+		require.Fail("The push did not refresh the instancers")
+	}
+
+	close(closed)
+	<-done
+	tickerFactory.AssertExpectations(t)
+}
+
+func TestWatchPushes(t *testing.T) {
+	t.Run("Push", testWatchPushesPush)
+	t.Run("Fallback", testWatchPushesFallback)
+}
+
+func TestNewDatacenterWatcherPush(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		p       = xmetricstest.NewProvider(nil, chrysom.Metrics)
+		closed  = make(chan struct{})
+
+		env = new(service.MockEnvironment)
+	)
+
+	defer close(closed)
+	env.On("Provider").Return(p, true)
+	env.On("Closed").Return((<-chan struct{})(closed))
+
+	w, err := newDatacenterWatcher(nil, environment{env, new(mockClient), nil}, Options{DatacenterPush: NewDatacenterPush()})
+	assert.Nil(w)
+	assert.Error(err)
+
+	w, err = newDatacenterWatcher(nil, environment{env, new(mockClient), nil}, Options{
+		DatacenterPush: NewDatacenterPush(),
+		ChrysomConfig: chrysom.ClientConfig{
+			Bucket:       "datacenters",
+			PullInterval: time.Minute,
+			Address:      "http://argus:6600",
+		},
+	})
+
+	require.NoError(err)
+	require.NotNil(w)
+	assert.NotNil(w.chrysomClient)
+	assert.NotNil(w.refresh)
+}
@@ -24,6 +24,10 @@ type datacenterWatcher struct {
 	chrysomClient       *chrysom.Client
 	consulWatchInterval time.Duration
 	lock                sync.RWMutex
+	measures            *measures
+
+	// refresh requests an immediate update of the instancers.  This is only used with datacenter pushes.
+	refresh chan struct{}
 }
 
 type datacenterFilter struct {
@@ -53,6 +57,11 @@ func newDatacenterWatcher(logger log.Logger, environment Environment, options Op
 		options:             options,
 		environment:         environment,
 		inactiveDatacenters: make(map[string]bool),
+		measures:            newMeasures(environment.Provider()),
+	}
+
+	if options.DatacenterPush != nil && len(options.ChrysomConfig.Bucket) == 0 {
+		return nil, errors.New("a chrysom bucket is required for datacenter pushes")
 	}
 
 	if len(options.ChrysomConfig.Bucket) > 0 {
@@ -67,11 +76,14 @@ func newDatacenterWatcher(logger log.Logger, environment Environment, options Op
 
 		options.ChrysomConfig.MetricsProvider = environment.Provider()
 
-		var datacenterListenerFunc chrysom.ListenerFunc = func(items []model.Item) {
-			updateInactiveDatacenters(items, datacenterWatcher.inactiveDatacenters, &datacenterWatcher.lock, logger)
-		}
+		// with pushes, the chrysom client is only used to poll when pushes stop arriving
+		if options.DatacenterPush == nil {
+			var datacenterListenerFunc chrysom.ListenerFunc = func(items []model.Item) {
+				datacenterWatcher.updateInactive(items)
+			}
 
-		options.ChrysomConfig.Listener = datacenterListenerFunc
+			options.ChrysomConfig.Listener = datacenterListenerFunc
+		}
 
 		options.ChrysomConfig.Logger = logger
 		chrysomClient, err := chrysom.CreateClient(options.ChrysomConfig)
@@ -82,7 +94,12 @@ func newDatacenterWatcher(logger log.Logger, environment Environment, options Op
 
 		//create chrysom client and start it
 		datacenterWatcher.chrysomClient = chrysomClient
-		datacenterWatcher.chrysomClient.Start(context.Background())
+		if options.DatacenterPush == nil {
+			datacenterWatcher.chrysomClient.Start(context.Background())
+		} else {
+			datacenterWatcher.refresh = make(chan struct{}, 1)
+			go datacenterWatcher.watchPushes(options.DatacenterPush, options.datacenterPushTimeout(), options.ChrysomConfig.PullInterval)
+		}
 	}
 
 	//start consul watch
@@ -107,18 +124,27 @@ func (d *datacenterWatcher) watchDatacenters(ticker *time.Ticker) {
 			d.stop()
 			return
 		case <-ticker.C:
-			datacenters, err := getDatacenters(d.logger, d.environment.Client(), d.options)
-
-			if err != nil {
-				// getDatacenters function logs the error, but a metric should be added
-				continue
-			}
+			d.refreshDatacenters()
+		case <-d.refresh:
+			d.refreshDatacenters()
+		}
 
-			d.updateInstancers(datacenters)
+	}
+}
 
-		}
+// refreshDatacenters queries consul for the current datacenters and updates the instancers accordingly
+func (d *datacenterWatcher) refreshDatacenters() {
+	datacenters, err := getDatacenters(d.logger, d.environment.Client(), d.options)
 
+	if err != nil {
+		// getDatacenters function logs the error
+		d.measures.updates.With(SourceLabel, ConsulSource, OutcomeLabel, FailureOutcome).Add(1.0)
+		return
 	}
+
+	d.measures.updates.With(SourceLabel, ConsulSource, OutcomeLabel, SuccessOutcome).Add(1.0)
+	d.measures.lastRefresh.Set(float64(time.Now().Unix()))
+	d.updateInstancers(datacenters)
 }
 
 func (d *datacenterWatcher) updateInstancers(datacenters []string) {
@@ -129,6 +155,11 @@ func (d *datacenterWatcher) updateInstancers(datacenters []string) {
 
 	for _, w := range d.options.watches() {
 		if w.CrossDatacenter {
+			if w.Affinity != nil {
+				createAffinityInstancer(keys, instancersToAdd, currentInstancers, d, datacenters, w)
+				continue
+			}
+
 			for _, datacenter := range datacenters {
 
 				createNewInstancer(keys, instancersToAdd, currentInstancers, d, datacenter, w)
@@ -140,7 +171,25 @@ func (d *datacenterWatcher) updateInstancers(datacenters []string) {
 
 }
 
-func updateInactiveDatacenters(items []model.Item, inactiveDatacenters map[string]bool, lock *sync.RWMutex, logger log.Logger) {
+// updateInactive applies an update from chrysom to the set of inactive datacenters.  An update containing
+// any item that cannot be decoded is counted as a failure, though the remaining items are still applied.
+func (d *datacenterWatcher) updateInactive(items []model.Item) {
+	outcome := SuccessOutcome
+	if updateInactiveDatacenters(items, d.inactiveDatacenters, &d.lock, d.logger) > 0 {
+		outcome = FailureOutcome
+	}
+
+	d.lock.RLock()
+	inactive := len(d.inactiveDatacenters)
+	d.lock.RUnlock()
+
+	d.measures.updates.With(SourceLabel, ChrysomSource, OutcomeLabel, outcome).Add(1.0)
+	d.measures.inactive.Set(float64(inactive))
+}
+
+// updateInactiveDatacenters replaces the inactive datacenters with those marked inactive in the given items.
+// The number of items that could not be decoded is returned.
+func updateInactiveDatacenters(items []model.Item, inactiveDatacenters map[string]bool, lock *sync.RWMutex, logger log.Logger) (decodeErrors int) {
 	chrysomMap := make(map[string]bool)
 	for _, item := range items {
 
@@ -151,6 +200,7 @@ func updateInactiveDatacenters(items []model.Item, inactiveDatacenters map[strin
 
 		if err != nil {
 			logger.Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "failed to decode database results into datacenter filter struct")
+			decodeErrors++
 			continue
 		}
 
@@ -173,6 +223,7 @@ func updateInactiveDatacenters(items []model.Item, inactiveDatacenters map[strin
 	}
 
 	lock.Unlock()
+	return
 }
 
 func createNewInstancer(keys map[string]bool, instancersToAdd service.Instancers, currentInstancers service.Instancers, dw *datacenterWatcher, datacenter string, w Watch) {
@@ -204,5 +255,29 @@ func createNewInstancer(keys map[string]bool, instancersToAdd service.Instancers
 	}
 
 	// create new instancer and add it to the map of instancers to add
-	instancersToAdd.Set(key, newInstancer(dw.logger, dw.environment.Client(), w))
+	instancersToAdd.Set(key, newInstancer(dw.logger, dw.environment.Client(), dw.environment.Metadata(), w))
+}
+
+// createAffinityInstancer creates the single, merged instancer for a cross datacenter watch with an affinity.
+// Inactive datacenters are excluded.
+func createAffinityInstancer(keys map[string]bool, instancersToAdd service.Instancers, currentInstancers service.Instancers, dw *datacenterWatcher, datacenters []string, w Watch) {
+	local := w.Affinity.localDatacenter(dw.options, datacenters)
+	ordered := make([]string, 0, len(datacenters))
+
+	dw.lock.RLock()
+	for _, datacenter := range w.Affinity.order(local, datacenters) {
+		if !dw.inactiveDatacenters[datacenter] {
+			ordered = append(ordered, datacenter)
+		}
+	}
+
+	dw.lock.RUnlock()
+
+	key := newAffinityInstancerKey(w, ordered)
+	keys[key] = true
+	if currentInstancers.Has(key) || instancersToAdd.Has(key) {
+		return
+	}
+
+	instancersToAdd.Set(key, newAffinityInstancer(dw.logger, dw.environment.Client(), dw.environment.Metadata(), w, ordered))
 }
@@ -9,6 +9,7 @@ import (
 
 	"github.com/go-kit/kit/log"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/xmidt-org/argus/chrysom"
 	"github.com/xmidt-org/argus/model"
 	"github.com/xmidt-org/webpa-common/service"
@@ -52,7 +53,7 @@ func TestNewDatacenterWatcher(t *testing.T) {
 			description: "Successful Consul Datacenter Watcher",
 			logger:      logger,
 			environment: environment{
-				mockServiceEnvironment, new(mockClient),
+				mockServiceEnvironment, new(mockClient), nil,
 			},
 			options: Options{
 				DatacenterWatchInterval: 10 * time.Second,
@@ -60,12 +61,13 @@ func TestNewDatacenterWatcher(t *testing.T) {
 			expectedWatcher: &datacenterWatcher{
 				logger: logger,
 				environment: environment{
-					mockServiceEnvironment, new(mockClient),
+					mockServiceEnvironment, new(mockClient), nil,
 				},
 				options: Options{
 					DatacenterWatchInterval: 10 * time.Second,
 				},
 				inactiveDatacenters: make(map[string]bool),
+				measures:            newMeasures(p),
 				consulWatchInterval: 10 * time.Second,
 			},
 		},
@@ -73,7 +75,7 @@ func TestNewDatacenterWatcher(t *testing.T) {
 			description: "Empty Chrysom Client Bucket",
 			logger:      logger,
 			environment: environment{
-				mockServiceEnvironment, new(mockClient),
+				mockServiceEnvironment, new(mockClient), nil,
 			},
 			options: Options{
 				ChrysomConfig: chrysom.ClientConfig{
@@ -83,12 +85,13 @@ func TestNewDatacenterWatcher(t *testing.T) {
 			expectedWatcher: &datacenterWatcher{
 				logger: logger,
 				environment: environment{
-					mockServiceEnvironment, new(mockClient),
+					mockServiceEnvironment, new(mockClient), nil,
 				},
 				options: Options{
 					DatacenterWatchInterval: defaultWatchInterval,
 				},
 				inactiveDatacenters: make(map[string]bool),
+				measures:            newMeasures(p),
 				consulWatchInterval: defaultWatchInterval,
 			},
 		},
@@ -96,7 +99,7 @@ func TestNewDatacenterWatcher(t *testing.T) {
 			description: "Successful Chrysom Client",
 			logger:      logger,
 			environment: environment{
-				mockServiceEnvironment, new(mockClient),
+				mockServiceEnvironment, new(mockClient), nil,
 			},
 			options: Options{
 				ChrysomConfig: validChrysomConfig,
@@ -104,7 +107,7 @@ func TestNewDatacenterWatcher(t *testing.T) {
 			expectedWatcher: &datacenterWatcher{
 				logger: logger,
 				environment: environment{
-					mockServiceEnvironment, new(mockClient),
+					mockServiceEnvironment, new(mockClient), nil,
 				},
 				options: Options{
 					DatacenterWatchInterval: defaultWatchInterval,
@@ -112,6 +115,7 @@ func TestNewDatacenterWatcher(t *testing.T) {
 				},
 				consulWatchInterval: defaultWatchInterval,
 				inactiveDatacenters: make(map[string]bool),
+				measures:            newMeasures(p),
 				chrysomClient:       &chrysom.Client{},
 			},
 		},
@@ -119,7 +123,7 @@ func TestNewDatacenterWatcher(t *testing.T) {
 			description: "Successful Consul and Chrysom Datacenter Watcher",
 			logger:      logger,
 			environment: environment{
-				mockServiceEnvironment, new(mockClient),
+				mockServiceEnvironment, new(mockClient), nil,
 			},
 			options: Options{
 				DatacenterWatchInterval: 10 * time.Second,
@@ -128,13 +132,14 @@ func TestNewDatacenterWatcher(t *testing.T) {
 			expectedWatcher: &datacenterWatcher{
 				logger: logger,
 				environment: environment{
-					mockServiceEnvironment, new(mockClient),
+					mockServiceEnvironment, new(mockClient), nil,
 				},
 				options: Options{
 					DatacenterWatchInterval: 10 * time.Second,
 					ChrysomConfig:           validChrysomConfig,
 				},
 				inactiveDatacenters: make(map[string]bool),
+				measures:            newMeasures(p),
 				consulWatchInterval: 10 * time.Second,
 				chrysomClient:       &chrysom.Client{},
 			},
@@ -142,7 +147,7 @@ func TestNewDatacenterWatcher(t *testing.T) {
 		{
 			description: "Success with Default Logger",
 			environment: environment{
-				mockServiceEnvironment, new(mockClient),
+				mockServiceEnvironment, new(mockClient), nil,
 			},
 			options: Options{
 				DatacenterWatchInterval: 10 * time.Second,
@@ -150,20 +155,21 @@ func TestNewDatacenterWatcher(t *testing.T) {
 			expectedWatcher: &datacenterWatcher{
 				logger: defaultLogger,
 				environment: environment{
-					mockServiceEnvironment, new(mockClient),
+					mockServiceEnvironment, new(mockClient), nil,
 				},
 				options: Options{
 					DatacenterWatchInterval: 10 * time.Second,
 				},
 				consulWatchInterval: 10 * time.Second,
 				inactiveDatacenters: make(map[string]bool),
+				measures:            newMeasures(p),
 			},
 		},
 		{
 			description: "Default Consul Watch Interval",
 			logger:      logger,
 			environment: environment{
-				mockServiceEnvironment, new(mockClient),
+				mockServiceEnvironment, new(mockClient), nil,
 			},
 			options: Options{
 				DatacenterWatchInterval: 0,
@@ -171,20 +177,21 @@ func TestNewDatacenterWatcher(t *testing.T) {
 			expectedWatcher: &datacenterWatcher{
 				logger: logger,
 				environment: environment{
-					mockServiceEnvironment, new(mockClient),
+					mockServiceEnvironment, new(mockClient), nil,
 				},
 				options: Options{
 					DatacenterWatchInterval: defaultWatchInterval,
 				},
 				consulWatchInterval: defaultWatchInterval,
 				inactiveDatacenters: make(map[string]bool),
+				measures:            newMeasures(p),
 			},
 		},
 		{
 			description: "No Provider",
 			logger:      logger,
 			environment: environment{
-				noProviderEnv, new(mockClient),
+				noProviderEnv, new(mockClient), nil,
 			},
 			options: Options{
 				ChrysomConfig: validChrysomConfig,
@@ -195,7 +202,7 @@ func TestNewDatacenterWatcher(t *testing.T) {
 			description: "Invalid chrysom watcher interval",
 			logger:      logger,
 			environment: environment{
-				mockServiceEnvironment, new(mockClient),
+				mockServiceEnvironment, new(mockClient), nil,
 			},
 			options: Options{
 				ChrysomConfig: chrysom.ClientConfig{
@@ -307,3 +314,96 @@ func TestUpdateInactiveDatacenters(t *testing.T) {
 		})
 	}
 }
+
+func TestDatacenterWatcherUpdateInactive(t *testing.T) {
+	var (
+		p = xmetricstest.NewProvider(nil, Metrics)
+		w = &datacenterWatcher{
+			logger:              log.NewNopLogger(),
+			inactiveDatacenters: make(map[string]bool),
+			measures:            newMeasures(p),
+		}
+	)
+
+	w.updateInactive([]model.Item{
+		{
+			UUID: "random-id",
+			Data: map[string]interface{}{"name": "testDC1", "inactive": true},
+		},
+		{
+			UUID: "random-id2",
+			Data: map[string]interface{}{"name": "testDC2", "inactive": true},
+		},
+	})
+
+	p.Assert(t, InactiveDatacenterCount)(xmetricstest.Value(2.0))
+	p.Assert(t, DatacenterUpdateCount, SourceLabel, ChrysomSource, OutcomeLabel, SuccessOutcome)(xmetricstest.Value(1.0))
+	p.Assert(t, DatacenterUpdateCount, SourceLabel, ChrysomSource, OutcomeLabel, FailureOutcome)(xmetricstest.Value(0.0))
+
+	checkpoint := p.Checkpoint()
+	w.updateInactive([]model.Item{
+		{
+			UUID: "random-id",
+			Data: map[string]interface{}{"name": "testDC1", "inactive": true},
+		},
+		{
+			UUID: "random-id2",
+			Data: map[string]interface{}{"name": 123, "inactive": "not a bool"},
+		},
+	})
+
+	p.Assert(t, InactiveDatacenterCount)(xmetricstest.Value(1.0))
+	p.Assert(t, DatacenterUpdateCount, SourceLabel, ChrysomSource, OutcomeLabel, SuccessOutcome)(checkpoint.Delta(0.0))
+	p.Assert(t, DatacenterUpdateCount, SourceLabel, ChrysomSource, OutcomeLabel, FailureOutcome)(checkpoint.Delta(1.0))
+	p.AssertMatching(t, DatacenterUpdateCount, SourceLabel, ChrysomSource)(xmetricstest.Value(2.0))
+}
+
+func TestDatacenterWatcherConsulMetrics(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		p      = xmetricstest.NewProvider(nil, Metrics)
+		closed = make(chan struct{})
+		client = new(mockClient)
+		env    = new(service.MockEnvironment)
+		before = time.Now().Unix()
+
+		updated = make(chan struct{}, 1)
+	)
+
+	env.On("Closed").Return((<-chan struct{})(closed))
+	env.On("Instancers").Return(service.Instancers{})
+	env.On("UpdateInstancers", mock.Anything, mock.Anything).Run(func(mock.Arguments) {
+		select {
+		case updated <- struct{}{}:
+		default:
+		}
+	})
+	client.On("Datacenters").Return(nil, errors.New("expected")).Twice()
+	client.On("Datacenters").Return([]string{"dc1"}, error(nil))
+
+	w := &datacenterWatcher{
+		logger:              log.NewNopLogger(),
+		environment:         environment{env, client, nil},
+		options:             Options{DatacenterRetries: 1},
+		inactiveDatacenters: make(map[string]bool),
+		measures:            newMeasures(p),
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.watchDatacenters(time.NewTicker(time.Millisecond))
+	}()
+
+	select {
+	case <-updated:
+	case <-time.After(5 * time.Second):
+		assert.Fail("The instancers were not updated")
+	}
+
+	close(closed)
+	<-done
+
+	p.Assert(t, DatacenterUpdateCount, SourceLabel, ConsulSource, OutcomeLabel, FailureOutcome)(xmetricstest.Value(1.0))
+	p.Assert(t, LastDatacenterRefreshTimestamp)(xmetricstest.Minimum(float64(before)))
+}
@@ -29,17 +29,51 @@ type Environment interface {
 
 	// Client returns the custom consul Client interface exposed by this package
 	Client() Client
+
+	// APIClient returns the underlying hashicorp consul client, for API calls not covered by Client.
+	// This method returns nil if the Client was not created by NewClient.
+	APIClient() *api.Client
+
+	// QueryMeta returns the consul metadata of the most recent successful query for each watched service and datacenter.
+	// This method returns nil if the Client was not created by NewClient.
+	QueryMeta() []QueryMeta
+
+	// Metadata returns the store of metadata for the instances discovered by this environment's watches.
+	// Use service.NewMetadataAccessor or service.NewMetadataAccessorFactory to obtain metadata along with
+	// accessor results.
+	Metadata() *service.MetadataStore
 }
 
 type environment struct {
 	service.Environment
-	client Client
+	client   Client
+	metadata *service.MetadataStore
 }
 
 func (e environment) Client() Client {
 	return e.client
 }
 
+func (e environment) APIClient() *api.Client {
+	if ac, ok := e.client.(interface{ APIClient() *api.Client }); ok {
+		return ac.APIClient()
+	}
+
+	return nil
+}
+
+func (e environment) QueryMeta() []QueryMeta {
+	if qm, ok := e.client.(interface{ QueryMeta() []QueryMeta }); ok {
+		return qm.QueryMeta()
+	}
+
+	return nil
+}
+
+func (e environment) Metadata() *service.MetadataStore {
+	return e.metadata
+}
+
 func generateID() string {
 	b := make([]byte, 16)
 	_, err := rand.Read(b)
@@ -69,10 +103,15 @@ func ensureIDs(r *api.AgentServiceRegistration) {
 
 func newInstancerKey(w Watch) string {
 	return fmt.Sprintf(
-		"%s%s{passingOnly=%t}{datacenter=%s}",
+		"%s%s{tagSets=%s}{filter=%s}{passingOnly=%t}{includeWarning=%t}{checkIDs=%s}{useWeights=%t}{datacenter=%s}",
 		w.Service,
 		w.Tags,
+		w.TagSets,
+		w.queryOptions().Filter,
 		w.PassingOnly,
+		w.IncludeWarning,
+		w.CheckIDs,
+		w.UseWeights,
 		w.QueryOptions.Datacenter,
 	)
 }
@@ -107,15 +146,21 @@ func getDatacenters(l log.Logger, c Client, co Options) ([]string, error) {
 	return nil, errNoDatacenters
 }
 
-func newInstancer(l log.Logger, c Client, w Watch) sd.Instancer {
+func newInstancer(l log.Logger, c Client, m *service.MetadataStore, w Watch) sd.Instancer {
 	return service.NewContextualInstancer(
 		NewInstancer(InstancerOptions{
-			Client:       c,
-			Logger:       l,
-			Service:      w.Service,
-			Tags:         w.Tags,
-			PassingOnly:  w.PassingOnly,
-			QueryOptions: w.QueryOptions,
+			Client:         c,
+			Logger:         l,
+			Service:        w.Service,
+			Tags:           w.Tags,
+			PassingOnly:    w.PassingOnly,
+			IncludeWarning: w.IncludeWarning,
+			CheckIDs:       w.CheckIDs,
+			TagSets:        w.TagSets,
+			UseWeights:     w.UseWeights,
+			QueryOptions:   w.queryOptions(),
+			PollInterval:   w.PollInterval,
+			Metadata:       m,
 		}),
 		map[string]interface{}{
 			"service":     w.Service,
@@ -126,7 +171,7 @@ func newInstancer(l log.Logger, c Client, w Watch) sd.Instancer {
 	)
 }
 
-func newInstancers(l log.Logger, c Client, co Options) (i service.Instancers, err error) {
+func newInstancers(l log.Logger, c Client, m *service.MetadataStore, co Options) (i service.Instancers, err error) {
 	var datacenters []string
 	for _, w := range co.watches() {
 		if w.CrossDatacenter {
@@ -137,6 +182,23 @@ func newInstancers(l log.Logger, c Client, co Options) (i service.Instancers, er
 				}
 			}
 
+			if w.Affinity != nil {
+				if err = w.Affinity.validate(); err != nil {
+					i.Stop()
+					return
+				}
+
+				ordered := w.Affinity.order(w.Affinity.localDatacenter(co, datacenters), datacenters)
+				key := newAffinityInstancerKey(w, ordered)
+				if i.Has(key) {
+					l.Log(level.Key(), level.WarnValue(), logging.MessageKey(), "skipping duplicate watch", "service", w.Service, "tags", w.Tags, "passingOnly", w.PassingOnly, "affinity", w.Affinity.policy())
+					continue
+				}
+
+				i.Set(key, newAffinityInstancer(l, c, m, w, ordered))
+				continue
+			}
+
 			for _, datacenter := range datacenters {
 				w.QueryOptions.Datacenter = datacenter
 				key := newInstancerKey(w)
@@ -144,7 +206,7 @@ func newInstancers(l log.Logger, c Client, co Options) (i service.Instancers, er
 					l.Log(level.Key(), level.WarnValue(), logging.MessageKey(), "skipping duplicate watch", "service", w.Service, "tags", w.Tags, "passingOnly", w.PassingOnly, "datacenter", w.QueryOptions.Datacenter)
 					continue
 				}
-				i.Set(key, newInstancer(l, c, w))
+				i.Set(key, newInstancer(l, c, m, w))
 			}
 		} else {
 			key := newInstancerKey(w)
@@ -152,16 +214,18 @@ func newInstancers(l log.Logger, c Client, co Options) (i service.Instancers, er
 				l.Log(level.Key(), level.WarnValue(), logging.MessageKey(), "skipping duplicate watch", "service", w.Service, "tags", w.Tags, "passingOnly", w.PassingOnly, "datacenter", w.QueryOptions.Datacenter)
 				continue
 			}
-			i.Set(key, newInstancer(l, c, w))
+			i.Set(key, newInstancer(l, c, m, w))
 		}
 	}
 
 	return
 }
 
-func newRegistrars(l log.Logger, registrationScheme string, c gokitconsul.Client, u ttlUpdater, co Options) (r service.Registrars, closer func() error, err error) {
+func newRegistrars(l log.Logger, registrationScheme string, c gokitconsul.Client, u ttlUpdater, rm *registrationMeasures, co Options) (r service.Registrars, closer func() error, err error) {
 	var consulRegistrar sd.Registrar
 	for _, registration := range co.registrations() {
+		// each registrar retains a pointer to its registration, so each needs its own copy
+		registration := registration
 		instance := service.FormatInstance(registrationScheme, registration.Address, registration.Port)
 		if r.Has(instance) {
 			l.Log(level.Key(), level.WarnValue(), logging.MessageKey(), "skipping duplicate registration", "instance", instance)
@@ -172,11 +236,31 @@ func newRegistrars(l log.Logger, registrationScheme string, c gokitconsul.Client
 			ensureIDs(&registration)
 		}
 
-		consulRegistrar, err = NewRegistrar(c, u, &registration, log.With(l, "id", registration.ID, "instance", instance))
+		var interval time.Duration
+		if h := co.heartbeat(); h != nil {
+			if err = h.apply(&registration); err != nil {
+				return
+			}
+
+			interval = h.Interval
+		}
+
+		registrarLogger := log.With(l, "id", registration.ID, "instance", instance)
+		consulRegistrar, err = newRegistrar(c, u, &registration, interval, registrarLogger)
 		if err != nil {
 			return
 		}
 
+		if watchInterval := co.registrationWatchInterval(); watchInterval > 0 {
+			agent, ok := u.(agentServices)
+			if !ok {
+				err = errors.New("The consul agent does not support verifying registrations")
+				return
+			}
+
+			consulRegistrar = newWatchdogRegistrar(consulRegistrar, c, agent, &registration, watchInterval, rm, registrarLogger)
+		}
+
 		r.Add(instance, consulRegistrar)
 	}
 
@@ -192,22 +276,56 @@ func NewEnvironment(l log.Logger, registrationScheme string, co Options, eo ...s
 		return nil, service.ErrIncomplete
 	}
 
-	consulClient, err := api.NewClient(co.config())
+	var (
+		config    = co.config()
+		refresher *tokenRefresher
+	)
+
+	if ts := co.tokenSource(); ts != nil {
+		refresher = newTokenRefresher(l, ts)
+		if err := refresher.refresh(); err != nil {
+			return nil, err
+		}
+
+		decorated, err := refresher.decorate(config)
+		if err != nil {
+			return nil, err
+		}
+
+		config = decorated
+	}
+
+	consulClient, err := api.NewClient(config)
 	if err != nil {
 		return nil, err
 	}
 
 	client, updater := clientFactory(consulClient)
-	r, closer, err := newRegistrars(l, registrationScheme, client, updater, co)
+	rm := newRegistrationMeasures()
+	r, closer, err := newRegistrars(l, registrationScheme, client, updater, rm, co)
 	if err != nil {
 		return nil, err
 	}
 
-	i, err := newInstancers(l, client, co)
+	metadata := service.NewMetadataStore()
+	i, err := newInstancers(l, client, metadata, co)
 	if err != nil {
 		return nil, err
 	}
 
+	if refresher != nil {
+		go refresher.refreshPeriodically(co.tokenRefreshInterval())
+		registrarsCloser := closer
+		closer = func() error {
+			refresher.close()
+			if registrarsCloser != nil {
+				return registrarsCloser()
+			}
+
+			return nil
+		}
+	}
+
 	newServiceEnvironment := environment{
 		service.NewEnvironment(
 			append(
@@ -215,9 +333,9 @@ func NewEnvironment(l log.Logger, registrationScheme string, co Options, eo ...s
 				service.WithRegistrars(r),
 				service.WithInstancers(i),
 				service.WithCloser(closer),
-			)...), NewClient(consulClient)}
+			)...), NewClient(consulClient), metadata}
 
-	if co.DatacenterWatchInterval > 0 || (len(co.ChrysomConfig.Bucket) > 0 && co.ChrysomConfig.PullInterval > 0) {
+	if co.DatacenterWatchInterval > 0 || co.DatacenterPush != nil || (len(co.ChrysomConfig.Bucket) > 0 && co.ChrysomConfig.PullInterval > 0) {
 		_, err := newDatacenterWatcher(l, newServiceEnvironment, co)
 		if err != nil {
 			l.Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "Could not create datacenter watcher", logging.ErrorKey(), err)
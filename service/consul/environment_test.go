@@ -2,6 +2,7 @@ package consul
 
 import (
 	"testing"
+	"time"
 
 	"github.com/hashicorp/consul/api"
 	"github.com/stretchr/testify/assert"
@@ -123,8 +124,9 @@ func testNewEnvironmentFull(t *testing.T) {
 	require.NoError(err)
 	require.NotNil(e)
 
-	_, ok := e.(Environment)
-	assert.True(ok)
+	ce, ok := e.(Environment)
+	require.True(ok)
+	assert.NotNil(ce.Metadata())
 
 	e.Register()
 	e.Deregister()
@@ -141,3 +143,113 @@ func TestNewEnvironment(t *testing.T) {
 	t.Run("ClientError", testNewEnvironmentClientError)
 	t.Run("Full", testNewEnvironmentFull)
 }
+
+func testNewRegistrarsHeartbeat(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		logger = logging.NewTestLogger(nil, t)
+		client = new(mockClient)
+
+		co = Options{
+			DisableGenerateID: true,
+			Registrations: []api.AgentServiceRegistration{
+				{ID: "service1", Address: "grubly.com", Port: 1111},
+			},
+			Heartbeat: &Heartbeat{TTL: 30 * time.Second, Interval: 10 * time.Second, DeregisterCriticalServiceAfter: time.Minute},
+		}
+	)
+
+	r, closer, err := newRegistrars(logger, "http", client, new(mockTTLUpdater), newRegistrationMeasures(), co)
+	require.NoError(err)
+	assert.Nil(closer)
+	require.Len(r, 1)
+
+	tr, ok := r["http://grubly.com:1111"].(*ttlRegistrar)
+	require.True(ok)
+	require.Len(tr.checks, 1)
+	assert.Equal("service:service1:heartbeat", tr.checks[0].checkID)
+	assert.Equal(10*time.Second, tr.checks[0].interval)
+
+	// the configured registrations are not modified
+	assert.Empty(co.Registrations[0].Checks)
+}
+
+func testNewRegistrarsInvalidHeartbeat(t *testing.T) {
+	var (
+		assert = assert.New(t)
+
+		co = Options{
+			Registrations: []api.AgentServiceRegistration{
+				{ID: "service1", Address: "grubly.com", Port: 1111},
+			},
+			Heartbeat: &Heartbeat{TTL: 30 * time.Second, Interval: time.Minute},
+		}
+	)
+
+	r, _, err := newRegistrars(logging.NewTestLogger(nil, t), "http", new(mockClient), new(mockTTLUpdater), newRegistrationMeasures(), co)
+	assert.Empty(r)
+	assert.Error(err)
+}
+
+func testNewRegistrarsWatchdog(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		logger = logging.NewTestLogger(nil, t)
+
+		co = Options{
+			Registrations: []api.AgentServiceRegistration{
+				{ID: "service1", Address: "grubly.com", Port: 1111},
+				{ID: "service2", Address: "grubly.com", Port: 2222},
+			},
+			RegistrationWatchInterval: time.Minute,
+		}
+	)
+
+	r, _, err := newRegistrars(logger, "http", new(mockClient), new(mockTTLUpdater), newRegistrationMeasures(), co)
+	require.NoError(err)
+	require.Len(r, 2)
+
+	first, ok := r["http://grubly.com:1111"].(*watchdogRegistrar)
+	require.True(ok)
+	assert.Equal("service1", first.registration.ID)
+	assert.Equal(time.Minute, first.interval)
+
+	second, ok := r["http://grubly.com:2222"].(*watchdogRegistrar)
+	require.True(ok)
+	assert.Equal("service2", second.registration.ID)
+
+	// the updater must be able to list the agent's services
+	r, _, err = newRegistrars(logger, "http", new(mockClient), struct{ ttlUpdater }{new(mockTTLUpdater)}, newRegistrationMeasures(), co)
+	assert.Empty(r)
+	assert.Error(err)
+}
+
+func TestNewRegistrars(t *testing.T) {
+	t.Run("Heartbeat", testNewRegistrarsHeartbeat)
+	t.Run("InvalidHeartbeat", testNewRegistrarsInvalidHeartbeat)
+	t.Run("Watchdog", testNewRegistrarsWatchdog)
+}
+
+func TestEnvironmentAPIClient(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+	)
+
+	// clients which are not created by NewClient have no hashicorp client or query metadata
+	e := environment{new(service.MockEnvironment), new(mockClient), nil}
+	assert.Nil(e.APIClient())
+	assert.Nil(e.QueryMeta())
+
+	apiClient, err := api.NewClient(api.DefaultConfig())
+	require.NoError(err)
+
+	e = environment{new(service.MockEnvironment), NewClient(apiClient), nil}
+	assert.Equal(apiClient, e.APIClient())
+	assert.NotNil(e.QueryMeta())
+	assert.Empty(e.QueryMeta())
+}
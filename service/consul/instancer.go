@@ -14,12 +14,19 @@ import (
 	"github.com/go-kit/kit/util/conn"
 	"github.com/hashicorp/consul/api"
 	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/service"
 )
 
 var (
 	errStopped = errors.New("Instancer stopped")
 )
 
+// MaxWeight is the largest weight honored when InstancerOptions.UseWeights is set
+const MaxWeight = 100
+
+// InstancerOptions configures a consul Instancer.  By default, the Instancer watches the service with
+// consul blocking queries, so that changes are observed as soon as consul reports them.  The maximum time
+// each blocking query waits is given by QueryOptions.WaitTime, and defaults to consul's own limit.
 type InstancerOptions struct {
 	Client       Client
 	Logger       log.Logger
@@ -27,6 +34,36 @@ type InstancerOptions struct {
 	Tags         []string
 	PassingOnly  bool
 	QueryOptions api.QueryOptions
+
+	// TagSets further restricts the instances to those having all the tags in at least one set, i.e. each set is
+	// an AND of tags, and the sets are ORed together.  This is applied in addition to Tags.  Server side filtering
+	// is also available through QueryOptions.Filter, which accepts a consul filter expression.
+	TagSets [][]string
+
+	// IncludeWarning, when PassingOnly is set, also selects instances whose health checks are in the warning state
+	IncludeWarning bool
+
+	// CheckIDs, when PassingOnly is set, restricts the health checks that determine whether an instance is selected.
+	// Other checks, including the node's serfHealth check, are ignored.  An instance lacking any of these checks
+	// is not selected.
+	CheckIDs []string
+
+	// UseWeights replicates each instance in the events according to its consul service weight, i.e. Weights.Passing,
+	// or Weights.Warning for an instance whose checks are in the warning state.  Weights are reduced by their greatest
+	// common divisor and limited to MaxWeight.  Accessors give replicated instances a proportionally larger share of the
+	// hash ring, so this is useful for fleets whose instances have different capacities.  Since the events then contain
+	// duplicate instances, this option should only be used for watches that feed accessors.
+	UseWeights bool
+
+	// PollInterval, if positive, disables blocking queries.  Instead, the service is queried
+	// once per interval.  This is a fallback for environments where long-lived blocking queries
+	// are not viable, e.g. due to proxies that time out idle requests.
+	PollInterval time.Duration
+
+	// Metadata, if set, receives the tags, service and node metadata, weight, and datacenter of each
+	// discovered instance.  The metadata is updated before each event is sent, and is removed when the
+	// Instancer is stopped.
+	Metadata *service.MetadataStore
 }
 
 func NewInstancer(o InstancerOptions) sd.Instancer {
@@ -35,13 +72,19 @@ func NewInstancer(o InstancerOptions) sd.Instancer {
 	}
 
 	i := &instancer{
-		client:       o.Client,
-		logger:       log.With(o.Logger, "service", o.Service, "tags", fmt.Sprint(o.Tags), "passingOnly", o.PassingOnly, "datacenter", o.QueryOptions.Datacenter),
-		service:      o.Service,
-		passingOnly:  o.PassingOnly,
-		queryOptions: o.QueryOptions,
-		stop:         make(chan struct{}),
-		registry:     make(map[chan<- sd.Event]bool),
+		client:         o.Client,
+		logger:         log.With(o.Logger, "service", o.Service, "tags", fmt.Sprint(o.Tags), "passingOnly", o.PassingOnly, "datacenter", o.QueryOptions.Datacenter),
+		service:        o.Service,
+		tagSets:        o.TagSets,
+		passingOnly:    o.PassingOnly,
+		includeWarning: o.IncludeWarning,
+		checkIDs:       o.CheckIDs,
+		useWeights:     o.UseWeights,
+		queryOptions:   o.QueryOptions,
+		pollInterval:   o.PollInterval,
+		metadata:       o.Metadata,
+		stop:           make(chan struct{}),
+		registry:       make(map[chan<- sd.Event]bool),
 	}
 
 	if len(o.Tags) > 0 {
@@ -60,7 +103,11 @@ func NewInstancer(o InstancerOptions) sd.Instancer {
 	}
 
 	i.update(sd.Event{Instances: instances, Err: err})
-	go i.loop(index)
+	if i.pollInterval > 0 {
+		go i.poll()
+	} else {
+		go i.loop(index)
+	}
 
 	return i
 }
@@ -72,9 +119,15 @@ type instancer struct {
 
 	tag        string
 	filterTags []string
+	tagSets    [][]string
 
-	passingOnly  bool
-	queryOptions api.QueryOptions
+	passingOnly    bool
+	includeWarning bool
+	checkIDs       []string
+	useWeights     bool
+	queryOptions   api.QueryOptions
+	pollInterval   time.Duration
+	metadata       *service.MetadataStore
 
 	stop chan struct{}
 
@@ -124,6 +177,33 @@ func (i *instancer) loop(lastIndex uint64) {
 	}
 }
 
+// poll queries consul for the service once per pollInterval, without blocking queries
+func (i *instancer) poll() {
+	ticker, stop := tickerFactory(i.pollInterval)
+	defer stop()
+
+	for {
+		select {
+		case <-i.stop:
+			return
+
+		case <-ticker:
+			instances, _, err := i.getInstances(0, i.stop)
+			switch {
+			case err == errStopped:
+				return
+
+			case err != nil:
+				i.logger.Log(logging.ErrorKey(), err)
+				i.update(sd.Event{Err: err})
+
+			default:
+				i.update(sd.Event{Instances: instances})
+			}
+		}
+	}
+}
+
 // getInstances is implemented similarly to go-kits sd/consul version, albeit with support for
 // arbitrary query options
 func (i *instancer) getInstances(lastIndex uint64, stop <-chan struct{}) ([]string, uint64, error) {
@@ -138,7 +218,9 @@ func (i *instancer) getInstances(lastIndex uint64, stop <-chan struct{}) ([]stri
 	go func() {
 		var queryOptions api.QueryOptions = i.queryOptions
 		queryOptions.WaitIndex = lastIndex
-		entries, meta, err := i.client.Service(i.service, i.tag, i.passingOnly, &queryOptions)
+		// when health is filtered client side, consul must return instances in every state
+		filterHealth := i.passingOnly && (i.includeWarning || len(i.checkIDs) > 0)
+		entries, meta, err := i.client.Service(i.service, i.tag, i.passingOnly && !filterHealth, &queryOptions)
 		if err != nil {
 			result <- response{err: err}
 			return
@@ -148,6 +230,14 @@ func (i *instancer) getInstances(lastIndex uint64, stop <-chan struct{}) ([]stri
 			entries = filterEntries(entries, i.filterTags)
 		}
 
+		if len(i.tagSets) > 0 {
+			entries = filterEntriesAnyOf(entries, i.tagSets)
+		}
+
+		if filterHealth {
+			entries = filterHealthyEntries(entries, i.includeWarning, i.checkIDs)
+		}
+
 		// see: https://www.consul.io/api-docs/features/blocking#implementation-details
 		if meta == nil || meta.LastIndex < lastIndex {
 			lastIndex = 0
@@ -155,8 +245,15 @@ func (i *instancer) getInstances(lastIndex uint64, stop <-chan struct{}) ([]stri
 			lastIndex = meta.LastIndex
 		}
 
+		instances := makeInstances(entries)
+		if i.useWeights {
+			instances = makeWeightedInstances(entries)
+		}
+
+		i.updateMetadata(entries)
+
 		result <- response{
-			instances: makeInstances(entries),
+			instances: instances,
 			index:     lastIndex,
 		}
 	}()
@@ -169,6 +266,22 @@ func (i *instancer) getInstances(lastIndex uint64, stop <-chan struct{}) ([]stri
 	}
 }
 
+// updateMetadata replaces this instancer's metadata with that of the given entries, unless this instancer is stopped
+func (i *instancer) updateMetadata(entries []*api.ServiceEntry) {
+	if i.metadata == nil {
+		return
+	}
+
+	defer i.registerLock.Unlock()
+	i.registerLock.Lock()
+
+	select {
+	case <-i.stop:
+	default:
+		i.metadata.Set(i, makeMetadata(entries))
+	}
+}
+
 func filterEntry(candidate *api.ServiceEntry, requiredTags []string) bool {
 	serviceTags := make(map[string]bool, len(candidate.Service.Tags))
 	for _, tag := range candidate.Service.Tags {
@@ -197,6 +310,66 @@ func filterEntries(entries []*api.ServiceEntry, requiredTags []string) []*api.Se
 	return filtered
 }
 
+// filterEntriesAnyOf selects the entries having all the tags in at least one of the given tag sets
+func filterEntriesAnyOf(entries []*api.ServiceEntry, tagSets [][]string) []*api.ServiceEntry {
+	var filtered []*api.ServiceEntry
+	for _, entry := range entries {
+		for _, tagSet := range tagSets {
+			if filterEntry(entry, tagSet) {
+				filtered = append(filtered, entry)
+				break
+			}
+		}
+	}
+
+	return filtered
+}
+
+// healthyEntry tests if the candidate's checks, optionally restricted to the given check IDs,
+// are passing or, if includeWarning is set, in the warning state
+func healthyEntry(candidate *api.ServiceEntry, includeWarning bool, checkIDs []string) bool {
+	checks := candidate.Checks
+	if len(checkIDs) > 0 {
+		checks = make(api.HealthChecks, 0, len(checkIDs))
+		for _, checkID := range checkIDs {
+			found := false
+			for _, check := range candidate.Checks {
+				if check.CheckID == checkID {
+					checks = append(checks, check)
+					found = true
+				}
+			}
+
+			if !found {
+				return false
+			}
+		}
+	}
+
+	switch checks.AggregatedStatus() {
+	case api.HealthPassing:
+		return true
+
+	case api.HealthWarning:
+		return includeWarning
+
+	default:
+		return false
+	}
+}
+
+// filterHealthyEntries selects the entries whose health is acceptable, as determined by healthyEntry
+func filterHealthyEntries(entries []*api.ServiceEntry, includeWarning bool, checkIDs []string) []*api.ServiceEntry {
+	var filtered []*api.ServiceEntry
+	for _, entry := range entries {
+		if healthyEntry(entry, includeWarning, checkIDs) {
+			filtered = append(filtered, entry)
+		}
+	}
+
+	return filtered
+}
+
 // makeInstances is identical to go-kit's version
 func makeInstances(entries []*api.ServiceEntry) []string {
 	instances := make([]string, len(entries))
@@ -212,6 +385,83 @@ func makeInstances(entries []*api.ServiceEntry) []string {
 	return instances
 }
 
+// makeMetadata produces the metadata for each instance in a set of entries
+func makeMetadata(entries []*api.ServiceEntry) map[string]service.Metadata {
+	var (
+		instances = makeInstances(entries)
+		metadata  = make(map[string]service.Metadata, len(entries))
+	)
+
+	for i, entry := range entries {
+		metadata[instances[i]] = service.Metadata{
+			Tags:       entry.Service.Tags,
+			Meta:       entry.Service.Meta,
+			NodeMeta:   entry.Node.Meta,
+			Weight:     entryWeight(entry),
+			Datacenter: entry.Node.Datacenter,
+		}
+	}
+
+	return metadata
+}
+
+// entryWeight returns the consul service weight of an entry, based on the health of its checks
+func entryWeight(entry *api.ServiceEntry) int {
+	weight := entry.Service.Weights.Passing
+	if entry.Checks.AggregatedStatus() == api.HealthWarning {
+		weight = entry.Service.Weights.Warning
+	}
+
+	switch {
+	case weight < 1:
+		return 1
+
+	case weight > MaxWeight:
+		return MaxWeight
+
+	default:
+		return weight
+	}
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+
+	return a
+}
+
+// makeWeightedInstances is like makeInstances, except that each instance is repeated according to its weight.
+// Weights are reduced by their greatest common divisor, so that uniformly weighted instances appear once each.
+func makeWeightedInstances(entries []*api.ServiceEntry) []string {
+	var (
+		instances = makeInstances(entries)
+		weights   = make([]int, len(entries))
+		divisor   = 0
+		total     = 0
+	)
+
+	for i, entry := range entries {
+		weights[i] = entryWeight(entry)
+		divisor = gcd(divisor, weights[i])
+	}
+
+	for i := range weights {
+		weights[i] /= divisor
+		total += weights[i]
+	}
+
+	weighted := make([]string, 0, total)
+	for i, instance := range instances {
+		for copy := 0; copy < weights[i]; copy++ {
+			weighted = append(weighted, instance)
+		}
+	}
+
+	return weighted
+}
+
 func (i *instancer) Register(ch chan<- sd.Event) {
 	defer i.registerLock.Unlock()
 	i.registerLock.Lock()
@@ -230,4 +480,10 @@ func (i *instancer) Deregister(ch chan<- sd.Event) {
 func (i *instancer) Stop() {
 	// this isn't idempotent, but mimics go-kit's behavior
 	close(i.stop)
+
+	if i.metadata != nil {
+		i.registerLock.Lock()
+		i.metadata.Set(i, nil)
+		i.registerLock.Unlock()
+	}
 }
@@ -1,11 +1,17 @@
 package consul
 
 import (
+	"errors"
 	"strconv"
 	"testing"
+	"time"
 
+	"github.com/go-kit/kit/sd"
 	"github.com/hashicorp/consul/api"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/service"
 )
 
 // newServiceEntry creates a consul ServiceEntry with a service address
@@ -97,6 +103,73 @@ func TestFilterEntries(t *testing.T) {
 	}
 }
 
+func TestFilterEntriesAnyOf(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		entries = []*api.ServiceEntry{
+			newServiceEntry("canary-arm.com", 8080, "stage=canary", "hw=arm"),
+			newServiceEntry("canary-x86.com", 8080, "stage=canary", "hw=x86"),
+			newServiceEntry("beta-x86.com", 8080, "stage=beta", "hw=x86"),
+			newServiceEntry("prod-arm.com", 8080, "stage=prod", "hw=arm"),
+		}
+	)
+
+	assert.Empty(filterEntriesAnyOf(entries, [][]string{{"stage=canary", "hw=sparc"}}))
+	assert.Equal(
+		[]*api.ServiceEntry{entries[0]},
+		filterEntriesAnyOf(entries, [][]string{{"stage=canary", "hw=arm"}}),
+	)
+
+	assert.Equal(
+		[]*api.ServiceEntry{entries[0], entries[2]},
+		filterEntriesAnyOf(entries, [][]string{{"stage=canary", "hw=arm"}, {"stage=beta"}}),
+	)
+
+	assert.Equal(
+		[]*api.ServiceEntry{entries[0], entries[3]},
+		filterEntriesAnyOf(entries, [][]string{{"hw=arm"}, {"stage=canary", "hw=arm"}}),
+		"an entry matching several tag sets should only be selected once",
+	)
+}
+
+// newServiceEntryChecks creates a consul service entry with the given health checks, as alternating check IDs and statuses
+func newServiceEntryChecks(serviceAddress string, port int, idsAndStatuses ...string) *api.ServiceEntry {
+	entry := newServiceEntry(serviceAddress, port)
+	for i := 0; i < len(idsAndStatuses); i += 2 {
+		entry.Checks = append(entry.Checks, &api.HealthCheck{CheckID: idsAndStatuses[i], Status: idsAndStatuses[i+1]})
+	}
+
+	return entry
+}
+
+func TestHealthyEntry(t *testing.T) {
+	testData := []struct {
+		entry          *api.ServiceEntry
+		includeWarning bool
+		checkIDs       []string
+		expected       bool
+	}{
+		{newServiceEntryChecks("service.com", 80), false, nil, true},
+		{newServiceEntryChecks("service.com", 80, "serfHealth", api.HealthPassing, "service", api.HealthPassing), false, nil, true},
+		{newServiceEntryChecks("service.com", 80, "serfHealth", api.HealthPassing, "service", api.HealthWarning), false, nil, false},
+		{newServiceEntryChecks("service.com", 80, "serfHealth", api.HealthPassing, "service", api.HealthWarning), true, nil, true},
+		{newServiceEntryChecks("service.com", 80, "serfHealth", api.HealthPassing, "service", api.HealthCritical), true, nil, false},
+		{newServiceEntryChecks("service.com", 80, "serfHealth", api.HealthCritical, "service", api.HealthPassing), false, []string{"service"}, true},
+		{newServiceEntryChecks("service.com", 80, "serfHealth", api.HealthPassing, "service", api.HealthCritical), false, []string{"serfHealth"}, true},
+		{newServiceEntryChecks("service.com", 80, "serfHealth", api.HealthPassing, "service", api.HealthWarning), false, []string{"service"}, false},
+		{newServiceEntryChecks("service.com", 80, "serfHealth", api.HealthPassing, "service", api.HealthWarning), true, []string{"service"}, true},
+		{newServiceEntryChecks("service.com", 80, "serfHealth", api.HealthPassing), true, []string{"service"}, false},
+		{newServiceEntryChecks("service.com", 80, "_service_maintenance:service", api.HealthCritical, "service", api.HealthPassing), true, nil, false},
+	}
+
+	for i, record := range testData {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert := assert.New(t)
+			assert.Equal(record.expected, healthyEntry(record.entry, record.includeWarning, record.checkIDs))
+		})
+	}
+}
+
 func TestMakeInstances(t *testing.T) {
 	testData := []struct {
 		entries  []*api.ServiceEntry
@@ -130,3 +203,368 @@ func TestMakeInstances(t *testing.T) {
 		})
 	}
 }
+
+func newWeightedServiceEntry(address string, port, passing, warning int, status string) *api.ServiceEntry {
+	entry := newServiceEntryChecks(address, port, "service", status)
+	entry.Service.Weights = api.AgentWeights{Passing: passing, Warning: warning}
+	return entry
+}
+
+func TestMakeWeightedInstances(t *testing.T) {
+	testData := []struct {
+		entries  []*api.ServiceEntry
+		expected []string
+	}{
+		{
+			[]*api.ServiceEntry{
+				newServiceEntry("service1.com", 8080),
+				newServiceEntry("service2.com", 8080),
+			},
+			[]string{"service1.com:8080", "service2.com:8080"},
+		},
+		{
+			[]*api.ServiceEntry{
+				newWeightedServiceEntry("service1.com", 8080, 10, 1, api.HealthPassing),
+				newWeightedServiceEntry("service2.com", 8080, 10, 1, api.HealthPassing),
+			},
+			[]string{"service1.com:8080", "service2.com:8080"},
+		},
+		{
+			[]*api.ServiceEntry{
+				newWeightedServiceEntry("service1.com", 8080, 2, 1, api.HealthPassing),
+				newWeightedServiceEntry("service2.com", 8080, 6, 1, api.HealthPassing),
+				newWeightedServiceEntry("service3.com", 8080, 6, 2, api.HealthWarning),
+			},
+			[]string{"service1.com:8080", "service2.com:8080", "service2.com:8080", "service2.com:8080", "service3.com:8080"},
+		},
+		{
+			[]*api.ServiceEntry{
+				newWeightedServiceEntry("service1.com", 8080, 1, 0, api.HealthWarning),
+				newWeightedServiceEntry("service2.com", 8080, 2, 1, api.HealthPassing),
+			},
+			[]string{"service1.com:8080", "service2.com:8080", "service2.com:8080"},
+		},
+	}
+
+	for i, record := range testData {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert := assert.New(t)
+			assert.Equal(record.expected, makeWeightedInstances(record.entries))
+		})
+	}
+
+	t.Run("MaxWeight", func(t *testing.T) {
+		assert := assert.New(t)
+		instances := makeWeightedInstances([]*api.ServiceEntry{
+			newWeightedServiceEntry("service1.com", 8080, 1, 1, api.HealthPassing),
+			newWeightedServiceEntry("service2.com", 8080, 1000, 1, api.HealthPassing),
+		})
+
+		assert.Len(instances, 1+MaxWeight)
+	})
+}
+
+func TestMakeMetadata(t *testing.T) {
+	var (
+		assert = assert.New(t)
+
+		tagged   = newServiceEntry("service1.com", 8080, "stage=canary")
+		weighted = newWeightedServiceEntry("service2.com", 8080, 3, 1, api.HealthPassing)
+	)
+
+	tagged.Service.Meta = map[string]string{"protocol": "h2"}
+	tagged.Node.Meta = map[string]string{"rack": "1"}
+	tagged.Node.Datacenter = "dc1"
+	weighted.Node.Datacenter = "dc2"
+
+	assert.Empty(makeMetadata(nil))
+	assert.Equal(
+		map[string]service.Metadata{
+			"service1.com:8080": {
+				Tags:       []string{"stage=canary"},
+				Meta:       map[string]string{"protocol": "h2"},
+				NodeMeta:   map[string]string{"rack": "1"},
+				Weight:     1,
+				Datacenter: "dc1",
+			},
+			"service2.com:8080": {
+				Weight:     3,
+				Datacenter: "dc2",
+			},
+		},
+		makeMetadata([]*api.ServiceEntry{tagged, weighted}),
+	)
+}
+
+func waitIndex(index uint64) interface{} {
+	return mock.MatchedBy(func(qo *api.QueryOptions) bool {
+		return qo.WaitIndex == index
+	})
+}
+
+func testInstancerBlockingQueries(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		client  = new(mockClient)
+		block   = make(chan time.Time)
+		events  = make(chan sd.Event, 10)
+	)
+
+	defer close(block)
+	client.On("Service", "test", "", false, waitIndex(0)).
+		Return([]*api.ServiceEntry{newServiceEntry("service1.com", 8080)}, &api.QueryMeta{LastIndex: 5}, error(nil)).Once()
+	client.On("Service", "test", "", false, waitIndex(5)).
+		Return([]*api.ServiceEntry{newServiceEntry("service1.com", 8080), newServiceEntry("service2.com", 8080)}, &api.QueryMeta{LastIndex: 6}, error(nil)).Once()
+	client.On("Service", "test", "", false, waitIndex(6)).
+		WaitUntil(block).
+		Return([]*api.ServiceEntry{}, &api.QueryMeta{LastIndex: 7}, error(nil))
+
+	i := NewInstancer(InstancerOptions{
+		Client:  client,
+		Service: "test",
+	})
+
+	require.NotNil(i)
+	defer i.Stop()
+	i.Register(events)
+
+	select {
+	case e := <-events:
+		if len(e.Instances) < 2 {
+			e = <-events
+		}
+
+		assert.Equal([]string{"service1.com:8080", "service2.com:8080"}, e.Instances)
+		assert.NoError(e.Err)
+	case <-time.After(5 * time.Second):
+		require.Fail("No update from the blocking query")
+	}
+}
+
+func testInstancerPolling(t *testing.T) {
+	var (
+		assert        = assert.New(t)
+		require       = require.New(t)
+		client        = new(mockClient)
+		tickerFactory = prepareMockTickerFactory()
+		ticker        = make(chan time.Time)
+		tickerStopped = make(chan struct{})
+		events        = make(chan sd.Event, 10)
+	)
+
+	defer resetTickerFactory()
+	tickerFactory.On("NewTicker", time.Minute).
+		Return((<-chan time.Time)(ticker), func() { close(tickerStopped) }).Once()
+
+	client.On("Service", "test", "", false, waitIndex(0)).
+		Return([]*api.ServiceEntry{newServiceEntry("service1.com", 8080)}, &api.QueryMeta{LastIndex: 5}, error(nil)).Once()
+	client.On("Service", "test", "", false, waitIndex(0)).
+		Return([]*api.ServiceEntry{newServiceEntry("service2.com", 8080)}, &api.QueryMeta{LastIndex: 6}, error(nil)).Once()
+
+	i := NewInstancer(InstancerOptions{
+		Client:       client,
+		Service:      "test",
+		PollInterval: time.Minute,
+	})
+
+	require.NotNil(i)
+	i.Register(events)
+	assert.Equal([]string{"service1.com:8080"}, (<-events).Instances)
+
+	ticker <- time.Now()
+	select {
+	case e := <-events:
+		assert.Equal([]string{"service2.com:8080"}, e.Instances)
+	case <-time.After(5 * time.Second):
+		require.Fail("No update from polling")
+	}
+
+	i.Stop()
+	select {
+	case <-tickerStopped:
+	case <-time.After(5 * time.Second):
+		require.Fail("The ticker was not stopped")
+	}
+
+	client.AssertExpectations(t)
+	tickerFactory.AssertExpectations(t)
+}
+
+func testInstancerHealthFilter(t *testing.T, o InstancerOptions, expectPassingOnly bool, expected []string) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		client  = new(mockClient)
+		block   = make(chan time.Time)
+		events  = make(chan sd.Event, 1)
+	)
+
+	defer close(block)
+	client.On("Service", "test", "", expectPassingOnly, waitIndex(0)).
+		Return(
+			[]*api.ServiceEntry{
+				newServiceEntryChecks("passing.com", 8080, "serfHealth", api.HealthPassing, "service", api.HealthPassing),
+				newServiceEntryChecks("warning.com", 8080, "serfHealth", api.HealthPassing, "service", api.HealthWarning),
+				newServiceEntryChecks("critical.com", 8080, "serfHealth", api.HealthCritical, "service", api.HealthPassing),
+			},
+			&api.QueryMeta{LastIndex: 1},
+			error(nil),
+		).Once()
+
+	client.On("Service", "test", "", expectPassingOnly, waitIndex(1)).
+		WaitUntil(block).
+		Return(nil, nil, errors.New("expected"))
+
+	o.Client = client
+	o.Service = "test"
+	i := NewInstancer(o)
+	require.NotNil(i)
+	defer i.Stop()
+
+	i.Register(events)
+	assert.Equal(expected, (<-events).Instances)
+}
+
+func testInstancerTagSets(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		client  = new(mockClient)
+		block   = make(chan time.Time)
+		events  = make(chan sd.Event, 1)
+	)
+
+	defer close(block)
+	client.On("Service", "test", "stage=canary", false, waitIndex(0)).
+		Return(
+			[]*api.ServiceEntry{
+				newServiceEntry("canary-arm.com", 8080, "stage=canary", "hw=arm"),
+				newServiceEntry("canary-x86.com", 8080, "stage=canary", "hw=x86"),
+				newServiceEntry("canary-sparc.com", 8080, "stage=canary", "hw=sparc"),
+			},
+			&api.QueryMeta{LastIndex: 1},
+			error(nil),
+		).Once()
+
+	client.On("Service", "test", "stage=canary", false, waitIndex(1)).
+		WaitUntil(block).
+		Return(nil, nil, errors.New("expected"))
+
+	i := NewInstancer(InstancerOptions{
+		Client:  client,
+		Service: "test",
+		Tags:    []string{"stage=canary"},
+		TagSets: [][]string{{"hw=arm"}, {"hw=x86"}},
+	})
+
+	require.NotNil(i)
+	defer i.Stop()
+
+	i.Register(events)
+	assert.Equal([]string{"canary-arm.com:8080", "canary-x86.com:8080"}, (<-events).Instances)
+}
+
+func testInstancerUseWeights(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		client  = new(mockClient)
+		block   = make(chan time.Time)
+		events  = make(chan sd.Event, 1)
+	)
+
+	defer close(block)
+	client.On("Service", "test", "", false, waitIndex(0)).
+		Return(
+			[]*api.ServiceEntry{
+				newWeightedServiceEntry("small.com", 8080, 1, 1, api.HealthPassing),
+				newWeightedServiceEntry("large.com", 8080, 3, 1, api.HealthPassing),
+			},
+			&api.QueryMeta{LastIndex: 1},
+			error(nil),
+		).Once()
+
+	client.On("Service", "test", "", false, waitIndex(1)).
+		WaitUntil(block).
+		Return(nil, nil, errors.New("expected"))
+
+	i := NewInstancer(InstancerOptions{
+		Client:     client,
+		Service:    "test",
+		UseWeights: true,
+	})
+
+	require.NotNil(i)
+	defer i.Stop()
+
+	i.Register(events)
+	assert.Equal([]string{"large.com:8080", "large.com:8080", "large.com:8080", "small.com:8080"}, (<-events).Instances)
+}
+
+func testInstancerMetadata(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		require  = require.New(t)
+		client   = new(mockClient)
+		block    = make(chan time.Time)
+		events   = make(chan sd.Event, 1)
+		metadata = service.NewMetadataStore()
+		entry    = newServiceEntry("service1.com", 8080, "stage=canary")
+	)
+
+	defer close(block)
+	entry.Node.Datacenter = "dc1"
+	client.On("Service", "test", "", false, waitIndex(0)).
+		Return([]*api.ServiceEntry{entry}, &api.QueryMeta{LastIndex: 1}, error(nil)).Once()
+
+	client.On("Service", "test", "", false, waitIndex(1)).
+		WaitUntil(block).
+		Return(nil, nil, errors.New("expected"))
+
+	i := NewInstancer(InstancerOptions{
+		Client:   client,
+		Service:  "test",
+		Metadata: metadata,
+	})
+
+	require.NotNil(i)
+	i.Register(events)
+	assert.Equal([]string{"service1.com:8080"}, (<-events).Instances)
+
+	m, ok := metadata.Get("https://service1.com:8080")
+	assert.True(ok)
+	assert.Equal(service.Metadata{Tags: []string{"stage=canary"}, Weight: 1, Datacenter: "dc1"}, m)
+
+	i.Stop()
+	_, ok = metadata.Get("service1.com:8080")
+	assert.False(ok)
+	assert.Zero(metadata.Len())
+}
+
+func TestInstancer(t *testing.T) {
+	t.Run("TagSets", testInstancerTagSets)
+	t.Run("Metadata", testInstancerMetadata)
+	t.Run("UseWeights", testInstancerUseWeights)
+	t.Run("BlockingQueries", testInstancerBlockingQueries)
+	t.Run("Polling", testInstancerPolling)
+
+	t.Run("HealthFilter", func(t *testing.T) {
+		t.Run("PassingOnly", func(t *testing.T) {
+			// consul filters the instances, so the mock's results are not filtered again
+			testInstancerHealthFilter(t, InstancerOptions{PassingOnly: true}, true, []string{"critical.com:8080", "passing.com:8080", "warning.com:8080"})
+		})
+
+		t.Run("IncludeWarning", func(t *testing.T) {
+			testInstancerHealthFilter(t, InstancerOptions{PassingOnly: true, IncludeWarning: true}, false, []string{"passing.com:8080", "warning.com:8080"})
+		})
+
+		t.Run("CheckIDs", func(t *testing.T) {
+			testInstancerHealthFilter(t, InstancerOptions{PassingOnly: true, CheckIDs: []string{"service"}}, false, []string{"critical.com:8080", "passing.com:8080"})
+		})
+
+		t.Run("IgnoredWithoutPassingOnly", func(t *testing.T) {
+			testInstancerHealthFilter(t, InstancerOptions{IncludeWarning: true, CheckIDs: []string{"service"}}, false, []string{"critical.com:8080", "passing.com:8080", "warning.com:8080"})
+		})
+	})
+}
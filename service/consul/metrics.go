@@ -0,0 +1,96 @@
+package consul
+
+import (
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/provider"
+	"github.com/xmidt-org/webpa-common/service"
+	"github.com/xmidt-org/webpa-common/xmetrics"
+)
+
+const (
+	DatacenterUpdateCount          = "sd_datacenter_update_count"
+	InactiveDatacenterCount        = "sd_inactive_datacenter_count"
+	LastDatacenterRefreshTimestamp = "sd_last_datacenter_refresh_timestamp"
+
+	QueryLastIndex   = "sd_consul_query_last_index"
+	QueryLastContact = "sd_consul_query_last_contact_seconds"
+	QueryKnownLeader = "sd_consul_query_known_leader"
+
+	ReregistrationCount = "sd_consul_reregistration_count"
+
+	SourceLabel  = "source"
+	OutcomeLabel = "outcome"
+
+	DatacenterLabel = "datacenter"
+
+	ConsulSource  = "consul"
+	ChrysomSource = "chrysom"
+
+	SuccessOutcome = "success"
+	FailureOutcome = "failure"
+)
+
+// Metrics is the module function for the metrics of the consul datacenter watcher
+func Metrics() []xmetrics.Metric {
+	return []xmetrics.Metric{
+		{
+			Name:       DatacenterUpdateCount,
+			Type:       xmetrics.CounterType,
+			Help:       "The total count of datacenter updates, by source and outcome",
+			LabelNames: []string{SourceLabel, OutcomeLabel},
+		},
+		{
+			Name: InactiveDatacenterCount,
+			Type: xmetrics.GaugeType,
+			Help: "The current number of datacenters marked as inactive",
+		},
+		{
+			Name: LastDatacenterRefreshTimestamp,
+			Type: xmetrics.GaugeType,
+			Help: "The last time the datacenters were successfully refreshed from consul",
+		},
+		{
+			Name:       QueryLastIndex,
+			Type:       xmetrics.GaugeType,
+			Help:       "The consul index of the most recent service query",
+			LabelNames: []string{service.ServiceLabel, DatacenterLabel},
+		},
+		{
+			Name:       QueryLastContact,
+			Type:       xmetrics.GaugeType,
+			Help:       "The time since the consul servers last contacted the leader, as of the most recent service query",
+			LabelNames: []string{service.ServiceLabel, DatacenterLabel},
+		},
+		{
+			Name:       QueryKnownLeader,
+			Type:       xmetrics.GaugeType,
+			Help:       "Whether the consul servers had a known leader as of the most recent service query, 1 for true and 0 for false",
+			LabelNames: []string{service.ServiceLabel, DatacenterLabel},
+		},
+		{
+			Name:       ReregistrationCount,
+			Type:       xmetrics.CounterType,
+			Help:       "The total count of registrations restored after the local consul agent lost them",
+			LabelNames: []string{service.ServiceLabel},
+		},
+	}
+}
+
+// measures holds the metrics for a datacenterWatcher
+type measures struct {
+	updates     metrics.Counter
+	inactive    metrics.Gauge
+	lastRefresh metrics.Gauge
+}
+
+func newMeasures(p provider.Provider) *measures {
+	if p == nil {
+		p = provider.NewDiscardProvider()
+	}
+
+	return &measures{
+		updates:     p.NewCounter(DatacenterUpdateCount),
+		inactive:    p.NewGauge(InactiveDatacenterCount),
+		lastRefresh: p.NewGauge(LastDatacenterRefreshTimestamp),
+	}
+}
@@ -0,0 +1,33 @@
+package consul
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/xmetrics"
+)
+
+func TestMetrics(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		r, err = xmetrics.NewRegistry(nil, Metrics)
+	)
+
+	require.NoError(err)
+	require.NotNil(r)
+
+	assert.NotNil(r.NewCounter(DatacenterUpdateCount))
+	assert.NotNil(r.NewGauge(InactiveDatacenterCount))
+	assert.NotNil(r.NewGauge(LastDatacenterRefreshTimestamp))
+}
+
+func TestNewMeasures(t *testing.T) {
+	assert := assert.New(t)
+	m := newMeasures(nil)
+	assert.NotNil(m.updates)
+	assert.NotNil(m.inactive)
+	assert.NotNil(m.lastRefresh)
+}
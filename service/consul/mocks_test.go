@@ -87,3 +87,9 @@ type mockTTLUpdater struct {
 func (m *mockTTLUpdater) UpdateTTL(checkID, output, status string) error {
 	return m.Called(checkID, output, status).Error(0)
 }
+
+func (m *mockTTLUpdater) Services() (map[string]*api.AgentService, error) {
+	arguments := m.Called()
+	first, _ := arguments.Get(0).(map[string]*api.AgentService)
+	return first, arguments.Error(1)
+}
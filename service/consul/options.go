@@ -15,6 +15,40 @@ type Watch struct {
 	PassingOnly     bool             `json:"passingOnly"`
 	CrossDatacenter bool             `json:"crossDatacenter"`
 	QueryOptions    api.QueryOptions `json:"queryOptions"`
+
+	// Affinity, when CrossDatacenter is set, merges the datacenters into a single instancer which prefers
+	// nearby datacenters and uses the others as failovers.  If not set, each datacenter has its own instancer.
+	Affinity *Affinity `json:"affinity,omitempty"`
+
+	// Filter is a consul filter expression, e.g. "Service.Meta.stage == canary and \"arm\" in Service.Tags",
+	// applied by the consul servers.  If set, this overrides QueryOptions.Filter.
+	Filter string `json:"filter,omitempty"`
+
+	// TagSets selects instances having all the tags in at least one set, e.g. [["stage=canary", "hw=arm"], ["stage=beta"]]
+	TagSets [][]string `json:"tagSets,omitempty"`
+
+	// IncludeWarning, when PassingOnly is set, also watches instances whose health checks are in the warning state
+	IncludeWarning bool `json:"includeWarning,omitempty"`
+
+	// CheckIDs, when PassingOnly is set, restricts the health checks used to determine if an instance is passing
+	CheckIDs []string `json:"checkIDs,omitempty"`
+
+	// UseWeights repeats each instance according to its consul service weight, so that accessors give heavier
+	// instances a proportionally larger share of the hash ring
+	UseWeights bool `json:"useWeights,omitempty"`
+
+	// PollInterval, if positive, queries consul on this interval rather than using blocking queries
+	PollInterval time.Duration `json:"pollInterval,omitempty"`
+}
+
+// queryOptions returns the consul query options for this watch, including any Filter
+func (w Watch) queryOptions() api.QueryOptions {
+	qo := w.QueryOptions
+	if len(w.Filter) > 0 {
+		qo.Filter = w.Filter
+	}
+
+	return qo
 }
 
 type Options struct {
@@ -25,6 +59,34 @@ type Options struct {
 	DatacenterWatchInterval time.Duration                  `json:"datacenterWatchInterval"`
 	Registrations           []api.AgentServiceRegistration `json:"registrations,omitempty"`
 	Watches                 []Watch                        `json:"watches,omitempty"`
+
+	// DatacenterPush, if set, receives the items of the chrysom datacenter bucket as they change, so that inactive
+	// datacenters take effect right away.  The bucket is polled only when no push arrives within DatacenterPushTimeout.
+	// ChrysomConfig must have a Bucket when this field is set.
+	DatacenterPush *DatacenterPush `json:"-"`
+
+	// DatacenterPushTimeout is how long to wait for a datacenter push before falling back to polling the chrysom
+	// bucket.  If unset, DefaultDatacenterPushTimeout is used.
+	DatacenterPushTimeout time.Duration `json:"datacenterPushTimeout,omitempty"`
+
+	// Heartbeat, if set, adds a TTL check to each registration that has none, which this package refreshes
+	// while the instance is registered.  This allows consul to remove instances that crash without deregistering.
+	Heartbeat *Heartbeat `json:"heartbeat,omitempty"`
+
+	// RegistrationWatchInterval, if positive, is how often each registration is verified with the local consul agent.
+	// A registration that the agent has lost, e.g. due to an agent restart, is registered again.
+	RegistrationWatchInterval time.Duration `json:"registrationWatchInterval,omitempty"`
+
+	// TokenFile is a file containing the consul ACL token, which is reread every TokenRefreshInterval.
+	// This allows tokens with short TTLs to be rotated without recreating the Environment.
+	TokenFile string `json:"tokenFile,omitempty"`
+
+	// TokenRefreshInterval is how often the ACL token is refreshed.  If unset, DefaultTokenRefreshInterval is used.
+	TokenRefreshInterval time.Duration `json:"tokenRefreshInterval,omitempty"`
+
+	// TokenSource is an optional, programmatic source for the ACL token, such as a Vault lookup.
+	// If set, this takes precedence over TokenFile.
+	TokenSource TokenSource `json:"-"`
 }
 
 func (o *Options) config() *api.Config {
@@ -51,6 +113,30 @@ func (o *Options) datacenterRetries() int {
 	return DefaultDatacenterRetries
 }
 
+func (o *Options) tokenSource() TokenSource {
+	switch {
+	case o == nil:
+		return nil
+
+	case o.TokenSource != nil:
+		return o.TokenSource
+
+	case len(o.TokenFile) > 0:
+		return FileTokenSource(o.TokenFile)
+
+	default:
+		return nil
+	}
+}
+
+func (o *Options) tokenRefreshInterval() time.Duration {
+	if o != nil && o.TokenRefreshInterval > 0 {
+		return o.TokenRefreshInterval
+	}
+
+	return DefaultTokenRefreshInterval
+}
+
 func (o *Options) registrations() []api.AgentServiceRegistration {
 	if o != nil && len(o.Registrations) > 0 {
 		return o.Registrations
@@ -59,6 +145,30 @@ func (o *Options) registrations() []api.AgentServiceRegistration {
 	return nil
 }
 
+func (o *Options) datacenterPushTimeout() time.Duration {
+	if o != nil && o.DatacenterPushTimeout > 0 {
+		return o.DatacenterPushTimeout
+	}
+
+	return DefaultDatacenterPushTimeout
+}
+
+func (o *Options) heartbeat() *Heartbeat {
+	if o != nil {
+		return o.Heartbeat
+	}
+
+	return nil
+}
+
+func (o *Options) registrationWatchInterval() time.Duration {
+	if o != nil && o.RegistrationWatchInterval > 0 {
+		return o.RegistrationWatchInterval
+	}
+
+	return 0
+}
+
 func (o *Options) watches() []Watch {
 	if o != nil && len(o.Watches) > 0 {
 		return o.Watches
@@ -2,6 +2,7 @@ package consul
 
 import (
 	"testing"
+	"time"
 
 	"github.com/hashicorp/consul/api"
 	"github.com/stretchr/testify/assert"
@@ -15,6 +16,11 @@ func testOptionsDefault(t *testing.T, o *Options) {
 	assert.False(o.disableGenerateID())
 	assert.Len(o.registrations(), 0)
 	assert.Len(o.watches(), 0)
+	assert.Nil(o.heartbeat())
+	assert.Equal(DefaultDatacenterPushTimeout, o.datacenterPushTimeout())
+	assert.Zero(o.registrationWatchInterval())
+	assert.Nil(o.tokenSource())
+	assert.Equal(DefaultTokenRefreshInterval, o.tokenRefreshInterval())
 }
 
 func testOptionsCustom(t *testing.T) {
@@ -44,6 +50,14 @@ func testOptionsCustom(t *testing.T) {
 					PassingOnly: true,
 				},
 			},
+
+			Heartbeat: &Heartbeat{TTL: 30 * time.Second},
+
+			DatacenterPushTimeout:     time.Minute,
+			RegistrationWatchInterval: 30 * time.Second,
+
+			TokenFile:            "/etc/consul/token",
+			TokenRefreshInterval: 15 * time.Second,
 		}
 	)
 
@@ -74,6 +88,17 @@ func testOptionsCustom(t *testing.T) {
 		},
 		o.watches(),
 	)
+
+	assert.Equal(&Heartbeat{TTL: 30 * time.Second}, o.heartbeat())
+	assert.Equal(time.Minute, o.datacenterPushTimeout())
+	assert.Equal(30*time.Second, o.registrationWatchInterval())
+	assert.NotNil(o.tokenSource())
+	assert.Equal(15*time.Second, o.tokenRefreshInterval())
+
+	o.TokenSource = TokenSourceFunc(func() (string, error) { return "programmatic", nil })
+	token, err := o.tokenSource().Token()
+	assert.Equal("programmatic", token)
+	assert.NoError(err)
 }
 
 func TestOptions(t *testing.T) {
@@ -84,3 +109,14 @@ func TestOptions(t *testing.T) {
 
 	t.Run("Custom", testOptionsCustom)
 }
+
+func TestWatchQueryOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	w := Watch{QueryOptions: api.QueryOptions{Datacenter: "dc1", Filter: "\"a\" in Service.Tags"}}
+	assert.Equal(api.QueryOptions{Datacenter: "dc1", Filter: "\"a\" in Service.Tags"}, w.queryOptions())
+
+	w.Filter = "Service.Meta.stage == canary"
+	assert.Equal(api.QueryOptions{Datacenter: "dc1", Filter: "Service.Meta.stage == canary"}, w.queryOptions())
+	assert.Equal("\"a\" in Service.Tags", w.QueryOptions.Filter, "the watch's query options should not be modified")
+}
@@ -0,0 +1,137 @@
+package consul
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/provider"
+	"github.com/hashicorp/consul/api"
+	"github.com/xmidt-org/webpa-common/service"
+)
+
+// QueryMeta is the consul metadata from the most recent successful query for a service in a datacenter.
+// Applications can use this information to build health checks for their consul connectivity.
+type QueryMeta struct {
+	// Service is the name of the queried service
+	Service string
+
+	// Datacenter is the datacenter that was queried.  An empty datacenter is the local agent's datacenter.
+	Datacenter string
+
+	// LastIndex is the consul index of the query's result
+	LastIndex uint64
+
+	// LastContact is the time since the servers last had contact with the leader
+	LastContact time.Duration
+
+	// KnownLeader indicates whether the servers had a known leader when the query was answered
+	KnownLeader bool
+
+	// Timestamp is the time the query completed
+	Timestamp time.Time
+}
+
+type queryKey struct {
+	service    string
+	datacenter string
+}
+
+// queryMeasures holds the metrics for consul query metadata
+type queryMeasures struct {
+	lastIndex   metrics.Gauge
+	lastContact metrics.Gauge
+	knownLeader metrics.Gauge
+}
+
+func newQueryMeasures(p provider.Provider) *queryMeasures {
+	return &queryMeasures{
+		lastIndex:   p.NewGauge(QueryLastIndex),
+		lastContact: p.NewGauge(QueryLastContact),
+		knownLeader: p.NewGauge(QueryKnownLeader),
+	}
+}
+
+func (qm *queryMeasures) set(m QueryMeta) {
+	knownLeader := 0.0
+	if m.KnownLeader {
+		knownLeader = 1.0
+	}
+
+	qm.lastIndex.With(service.ServiceLabel, m.Service, DatacenterLabel, m.Datacenter).Set(float64(m.LastIndex))
+	qm.lastContact.With(service.ServiceLabel, m.Service, DatacenterLabel, m.Datacenter).Set(m.LastContact.Seconds())
+	qm.knownLeader.With(service.ServiceLabel, m.Service, DatacenterLabel, m.Datacenter).Set(knownLeader)
+}
+
+// queryTracker records the metadata of consul queries, and optionally reports that metadata as metrics
+type queryTracker struct {
+	lock     sync.RWMutex
+	queries  map[queryKey]QueryMeta
+	measures *queryMeasures
+}
+
+func newQueryTracker() *queryTracker {
+	return &queryTracker{
+		queries: make(map[queryKey]QueryMeta),
+	}
+}
+
+// record stores the metadata from a service query
+func (qt *queryTracker) record(serviceName string, qo *api.QueryOptions, meta *api.QueryMeta) {
+	m := QueryMeta{
+		Service:     serviceName,
+		LastIndex:   meta.LastIndex,
+		LastContact: meta.LastContact,
+		KnownLeader: meta.KnownLeader,
+		Timestamp:   time.Now(),
+	}
+
+	if qo != nil {
+		m.Datacenter = qo.Datacenter
+	}
+
+	defer qt.lock.Unlock()
+	qt.lock.Lock()
+
+	qt.queries[queryKey{service: m.Service, datacenter: m.Datacenter}] = m
+	if qt.measures != nil {
+		qt.measures.set(m)
+	}
+}
+
+// setProvider starts reporting query metadata as metrics, including any metadata already recorded
+func (qt *queryTracker) setProvider(p provider.Provider) {
+	if p == nil {
+		return
+	}
+
+	defer qt.lock.Unlock()
+	qt.lock.Lock()
+
+	qt.measures = newQueryMeasures(p)
+	for _, m := range qt.queries {
+		qt.measures.set(m)
+	}
+}
+
+// get returns the recorded query metadata, sorted by service and then datacenter
+func (qt *queryTracker) get() []QueryMeta {
+	qt.lock.RLock()
+	result := make([]QueryMeta, 0, len(qt.queries))
+	for _, m := range qt.queries {
+		result = append(result, m)
+	}
+
+	qt.lock.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Service == result[j].Service {
+			return result[i].Datacenter < result[j].Datacenter
+		}
+
+		return result[i].Service < result[j].Service
+	})
+
+	return result
+}
@@ -0,0 +1,57 @@
+package consul
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/xmidt-org/webpa-common/service"
+	"github.com/xmidt-org/webpa-common/xmetrics/xmetricstest"
+)
+
+func TestQueryTracker(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		p      = xmetricstest.NewProvider(nil, Metrics)
+		qt     = newQueryTracker()
+		before = time.Now()
+	)
+
+	assert.Empty(qt.get())
+
+	qt.record("talaria", &api.QueryOptions{Datacenter: "dc2"}, &api.QueryMeta{LastIndex: 10, LastContact: 2 * time.Second, KnownLeader: true})
+	qt.record("talaria", nil, &api.QueryMeta{LastIndex: 5})
+	qt.record("scytale", &api.QueryOptions{Datacenter: "dc1"}, &api.QueryMeta{LastIndex: 7, KnownLeader: true})
+
+	// metrics are reported for queries recorded before the provider was set
+	qt.setProvider(nil)
+	qt.setProvider(p)
+	p.Assert(t, QueryLastIndex, service.ServiceLabel, "talaria", DatacenterLabel, "dc2")(xmetricstest.Value(10.0))
+	p.Assert(t, QueryLastContact, service.ServiceLabel, "talaria", DatacenterLabel, "dc2")(xmetricstest.Value(2.0))
+	p.Assert(t, QueryKnownLeader, service.ServiceLabel, "talaria", DatacenterLabel, "dc2")(xmetricstest.Value(1.0))
+	p.Assert(t, QueryKnownLeader, service.ServiceLabel, "talaria", DatacenterLabel, "")(xmetricstest.Value(0.0))
+
+	// the most recent query for a service and datacenter replaces any previous query
+	qt.record("talaria", &api.QueryOptions{Datacenter: "dc2"}, &api.QueryMeta{LastIndex: 11, KnownLeader: false})
+	p.Assert(t, QueryLastIndex, service.ServiceLabel, "talaria", DatacenterLabel, "dc2")(xmetricstest.Value(11.0))
+	p.Assert(t, QueryKnownLeader, service.ServiceLabel, "talaria", DatacenterLabel, "dc2")(xmetricstest.Value(0.0))
+
+	queries := qt.get()
+	if assert.Len(queries, 3) {
+		assert.Equal("scytale", queries[0].Service)
+		assert.Equal("dc1", queries[0].Datacenter)
+		assert.Equal(uint64(7), queries[0].LastIndex)
+		assert.True(queries[0].KnownLeader)
+
+		assert.Equal("talaria", queries[1].Service)
+		assert.Empty(queries[1].Datacenter)
+		assert.Equal(uint64(5), queries[1].LastIndex)
+
+		assert.Equal("talaria", queries[2].Service)
+		assert.Equal("dc2", queries[2].Datacenter)
+		assert.Equal(uint64(11), queries[2].LastIndex)
+		assert.False(queries[2].KnownLeader)
+		assert.False(queries[2].Timestamp.Before(before))
+	}
+}
@@ -1,16 +1,21 @@
 package consul
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/go-kit/kit/metrics/provider"
 	"github.com/go-kit/kit/sd"
 	gokitconsul "github.com/go-kit/kit/sd/consul"
 	"github.com/hashicorp/consul/api"
 	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/service"
 )
 
 // passFormat returns a closure that produces the output for a passing TTL, given the current system time
@@ -84,9 +89,76 @@ func (tc ttlCheck) updatePeriodically(updater ttlUpdater, shutdown <-chan struct
 	}
 }
 
+// Heartbeat describes TTL checks that this package refreshes on an interval.  When a registered instance crashes,
+// its TTL checks stop being refreshed and become critical, and consul can then remove the instance from the catalog.
+type Heartbeat struct {
+	// TTL is the time to live of the heartbeat check added to each registration that doesn't already have a TTL check.
+	// This field is required.
+	TTL time.Duration `json:"ttl"`
+
+	// Interval is how often each TTL check is refreshed.  If unset, half of each check's TTL is used.
+	// This interval must be less than the TTL of every TTL check.
+	Interval time.Duration `json:"interval,omitempty"`
+
+	// DeregisterCriticalServiceAfter, if set, is how long a TTL check can be critical before consul deregisters the
+	// instance.  It applies to each TTL check that doesn't set its own.  Consul enforces a minimum of one minute.
+	DeregisterCriticalServiceAfter time.Duration `json:"deregisterCriticalServiceAfter,omitempty"`
+}
+
+// heartbeatCheckID produces the check ID for the heartbeat check of a registration
+func heartbeatCheckID(r *api.AgentServiceRegistration) string {
+	serviceID := r.ID
+	if len(serviceID) == 0 {
+		serviceID = r.Name
+	}
+
+	return fmt.Sprintf("service:%s:heartbeat", serviceID)
+}
+
+// apply adds a heartbeat TTL check to the given registration if it has no TTL checks, and sets DeregisterCriticalServiceAfter
+// on each TTL check as configured.  This method should be called after any IDs are generated for the registration.
+func (h Heartbeat) apply(r *api.AgentServiceRegistration) error {
+	if h.TTL <= 0 {
+		return errors.New("A heartbeat TTL is required")
+	}
+
+	if h.Interval >= h.TTL {
+		return fmt.Errorf("The heartbeat interval %s must be less than the TTL %s", h.Interval, h.TTL)
+	}
+
+	var deregisterCriticalServiceAfter string
+	if h.DeregisterCriticalServiceAfter > 0 {
+		deregisterCriticalServiceAfter = h.DeregisterCriticalServiceAfter.String()
+	}
+
+	hasTTL := false
+	for _, agentCheck := range append([]*api.AgentServiceCheck{r.Check}, r.Checks...) {
+		if agentCheck == nil || len(agentCheck.TTL) == 0 {
+			continue
+		}
+
+		hasTTL = true
+		if len(agentCheck.DeregisterCriticalServiceAfter) == 0 {
+			agentCheck.DeregisterCriticalServiceAfter = deregisterCriticalServiceAfter
+		}
+	}
+
+	if !hasTTL {
+		r.Checks = append(r.Checks, &api.AgentServiceCheck{
+			CheckID:                        heartbeatCheckID(r),
+			Name:                           "heartbeat",
+			TTL:                            h.TTL.String(),
+			DeregisterCriticalServiceAfter: deregisterCriticalServiceAfter,
+		})
+	}
+
+	return nil
+}
+
 // appendTTLCheck conditionally creates a ttlCheck for the given agent check if and only if the agent check is configured with a TTL.
-// If the agent check is nil or has no TTL, this function returns ttlChecks unmodified with no error.
-func appendTTLCheck(logger log.Logger, serviceID string, agentCheck *api.AgentServiceCheck, ttlChecks []ttlCheck) ([]ttlCheck, error) {
+// If the agent check is nil or has no TTL, this function returns ttlChecks unmodified with no error.  If interval is not positive,
+// the TTL check is updated every TTL/2.
+func appendTTLCheck(logger log.Logger, serviceID string, agentCheck *api.AgentServiceCheck, interval time.Duration, ttlChecks []ttlCheck) ([]ttlCheck, error) {
 	if agentCheck == nil || len(agentCheck.TTL) == 0 {
 		return ttlChecks, nil
 	}
@@ -96,7 +168,12 @@ func appendTTLCheck(logger log.Logger, serviceID string, agentCheck *api.AgentSe
 		return nil, err
 	}
 
-	interval := ttl / 2
+	if interval <= 0 {
+		interval = ttl / 2
+	} else if interval >= ttl {
+		return nil, fmt.Errorf("TTL %s must be greater than the update interval %s", agentCheck.TTL, interval)
+	}
+
 	if interval < 1 {
 		return nil, fmt.Errorf("TTL %s is too small", agentCheck.TTL)
 	}
@@ -137,18 +214,24 @@ type ttlRegistrar struct {
 
 // NewRegistrar creates an sd.Registrar, binding any TTL checks to the Register/Deregister lifecycle as needed.
 func NewRegistrar(c gokitconsul.Client, u ttlUpdater, r *api.AgentServiceRegistration, logger log.Logger) (sd.Registrar, error) {
+	return newRegistrar(c, u, r, 0, logger)
+}
+
+// newRegistrar is like NewRegistrar, but allows the TTL update interval to be specified.  A nonpositive interval
+// updates each TTL check every TTL/2.
+func newRegistrar(c gokitconsul.Client, u ttlUpdater, r *api.AgentServiceRegistration, interval time.Duration, logger log.Logger) (sd.Registrar, error) {
 	var (
 		ttlChecks []ttlCheck
 		err       error
 	)
 
-	ttlChecks, err = appendTTLCheck(logger, r.ID, r.Check, ttlChecks)
+	ttlChecks, err = appendTTLCheck(logger, r.ID, r.Check, interval, ttlChecks)
 	if err != nil {
 		return nil, err
 	}
 
 	for _, agentCheck := range r.Checks {
-		ttlChecks, err = appendTTLCheck(logger, r.ID, agentCheck, ttlChecks)
+		ttlChecks, err = appendTTLCheck(logger, r.ID, agentCheck, interval, ttlChecks)
 		if err != nil {
 			return nil, err
 		}
@@ -197,3 +280,138 @@ func (tr *ttlRegistrar) Deregister() {
 	tr.shutdown = nil
 	tr.registrar.Deregister()
 }
+
+// agentServices is the subset of the consul agent API used to verify that a service is still registered.
+// The consul api Agent implements this interface.
+type agentServices interface {
+	Services() (map[string]*api.AgentService, error)
+}
+
+// registrationMeasures holds the metrics for registrations.  The fields must be set before any registrar is registered.
+type registrationMeasures struct {
+	reregistrations metrics.Counter
+}
+
+func newRegistrationMeasures() *registrationMeasures {
+	return &registrationMeasures{
+		reregistrations: discard.NewCounter(),
+	}
+}
+
+// setProvider creates the registration metrics from the given provider, if it is not nil
+func (rm *registrationMeasures) setProvider(p provider.Provider) {
+	if p != nil {
+		rm.reregistrations = p.NewCounter(ReregistrationCount)
+	}
+}
+
+// watchdogRegistrar is an sd.Registrar that, while registered, verifies on an interval that its service is still
+// registered with the local consul agent.  If the agent has lost the registration, e.g. because it was restarted
+// without persistent state, the service is registered again.
+type watchdogRegistrar struct {
+	logger       log.Logger
+	registrar    sd.Registrar
+	client       gokitconsul.Client
+	agent        agentServices
+	registration *api.AgentServiceRegistration
+	serviceID    string
+	interval     time.Duration
+	measures     *registrationMeasures
+
+	lifecycleLock sync.Mutex
+	shutdown      chan struct{}
+}
+
+func newWatchdogRegistrar(registrar sd.Registrar, c gokitconsul.Client, a agentServices, r *api.AgentServiceRegistration, interval time.Duration, measures *registrationMeasures, logger log.Logger) *watchdogRegistrar {
+	// the consul agent uses the service name as the ID when no ID is supplied
+	serviceID := r.ID
+	if len(serviceID) == 0 {
+		serviceID = r.Name
+	}
+
+	return &watchdogRegistrar{
+		logger:       logger,
+		registrar:    registrar,
+		client:       c,
+		agent:        a,
+		registration: r,
+		serviceID:    serviceID,
+		interval:     interval,
+		measures:     measures,
+	}
+}
+
+func (wr *watchdogRegistrar) Register() {
+	defer wr.lifecycleLock.Unlock()
+	wr.lifecycleLock.Lock()
+
+	if wr.shutdown != nil {
+		return
+	}
+
+	wr.registrar.Register()
+	wr.shutdown = make(chan struct{})
+	go wr.watch(wr.shutdown)
+}
+
+func (wr *watchdogRegistrar) Deregister() {
+	defer wr.lifecycleLock.Unlock()
+	wr.lifecycleLock.Lock()
+
+	if wr.shutdown == nil {
+		return
+	}
+
+	close(wr.shutdown)
+	wr.shutdown = nil
+	wr.registrar.Deregister()
+}
+
+func (wr *watchdogRegistrar) watch(shutdown <-chan struct{}) {
+	ticker, stop := tickerFactory(wr.interval)
+	defer stop()
+
+	wr.logger.Log(level.Key(), level.InfoValue(), logging.MessageKey(), "starting registration watchdog")
+	for {
+		select {
+		case <-ticker:
+			wr.verify(shutdown)
+
+		case <-shutdown:
+			wr.logger.Log(level.Key(), level.InfoValue(), logging.MessageKey(), "registration watchdog shutdown")
+			return
+		}
+	}
+}
+
+// verify checks that the service is registered with the agent, and registers it again if not
+func (wr *watchdogRegistrar) verify(shutdown <-chan struct{}) {
+	services, err := wr.agent.Services()
+	if err != nil {
+		// the agent is most likely restarting, so the registration is checked again on the next interval
+		wr.logger.Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "unable to verify registration", logging.ErrorKey(), err)
+		return
+	}
+
+	if _, ok := services[wr.serviceID]; ok {
+		return
+	}
+
+	defer wr.lifecycleLock.Unlock()
+	wr.lifecycleLock.Lock()
+
+	// don't register again if the registrar was deregistered while the agent was queried
+	select {
+	case <-shutdown:
+		return
+	default:
+	}
+
+	wr.logger.Log(level.Key(), level.WarnValue(), logging.MessageKey(), "service is not registered with the consul agent, registering again")
+	if err := wr.client.Register(wr.registration); err != nil {
+		wr.logger.Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "unable to register again", logging.ErrorKey(), err)
+		return
+	}
+
+	wr.measures.reregistrations.With(service.ServiceLabel, wr.registration.Name).Add(1.0)
+}
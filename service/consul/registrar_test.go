@@ -10,6 +10,8 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/service"
+	"github.com/xmidt-org/webpa-common/xmetrics/xmetricstest"
 )
 
 func TestDefaultTickerFactory(t *testing.T) {
@@ -371,6 +373,74 @@ func testNewRegistrarTTL(t *testing.T) {
 	tickerFactory.AssertExpectations(t)
 }
 
+func testNewRegistrarInterval(t *testing.T) {
+	defer resetTickerFactory()
+
+	var (
+		require = require.New(t)
+
+		logger        = logging.NewTestLogger(nil, t)
+		client        = new(mockClient)
+		ttlUpdater    = new(mockTTLUpdater)
+		tickerFactory = prepareMockTickerFactory()
+
+		timer      = make(chan time.Time)
+		updateDone = make(chan struct{})
+
+		registration = &api.AgentServiceRegistration{
+			ID:      "service1",
+			Address: "somehost.com",
+			Port:    1111,
+			Check: &api.AgentServiceCheck{
+				CheckID: "check1",
+				TTL:     "15s",
+			},
+		}
+	)
+
+	ttlUpdater.On("UpdateTTL", "check1", mock.MatchedBy(func(v string) bool { return len(v) > 0 }), "fail").Return(error(nil)).Once()
+	tickerFactory.On("NewTicker", 5*time.Second).Return((<-chan time.Time)(timer), func() { close(updateDone) }).Once()
+
+	client.On("Register", mock.MatchedBy(func(r *api.AgentServiceRegistration) bool { return r.ID == "service1" })).Return(error(nil)).Once()
+	client.On("Deregister", mock.MatchedBy(func(r *api.AgentServiceRegistration) bool { return r.ID == "service1" })).Return(error(nil)).Once()
+
+	r, err := newRegistrar(client, ttlUpdater, registration, 5*time.Second, logger)
+	require.NoError(err)
+	require.NotNil(r)
+
+	r.Register()
+	r.Deregister()
+
+	select {
+	case <-updateDone:
+		// passing
+	case <-time.After(2 * time.Second):
+		require.Fail("TTL update goroutine did not fail the TTL")
+	}
+
+	client.AssertExpectations(t)
+	ttlUpdater.AssertExpectations(t)
+	tickerFactory.AssertExpectations(t)
+}
+
+func testNewRegistrarIntervalTooLarge(t *testing.T) {
+	var (
+		assert = assert.New(t)
+
+		registration = &api.AgentServiceRegistration{
+			ID: "service1",
+			Check: &api.AgentServiceCheck{
+				CheckID: "check1",
+				TTL:     "15s",
+			},
+		}
+	)
+
+	r, err := newRegistrar(new(mockClient), new(mockTTLUpdater), registration, 15*time.Second, logging.NewTestLogger(nil, t))
+	assert.Error(err)
+	assert.Nil(r)
+}
+
 func TestNewRegistrar(t *testing.T) {
 	t.Run("NoChecks", testNewRegistrarNoChecks)
 	t.Run("NoTTL", testNewRegistrarNoTTL)
@@ -386,4 +456,169 @@ func TestNewRegistrar(t *testing.T) {
 	})
 
 	t.Run("TTL", testNewRegistrarTTL)
+	t.Run("Interval", testNewRegistrarInterval)
+	t.Run("IntervalTooLarge", testNewRegistrarIntervalTooLarge)
+}
+
+func testHeartbeatInvalid(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Error(Heartbeat{}.apply(new(api.AgentServiceRegistration)))
+	assert.Error(Heartbeat{TTL: -time.Second}.apply(new(api.AgentServiceRegistration)))
+	assert.Error(Heartbeat{TTL: 10 * time.Second, Interval: 10 * time.Second}.apply(new(api.AgentServiceRegistration)))
+}
+
+func testHeartbeatAddCheck(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		httpCheck    = &api.AgentServiceCheck{CheckID: "check1", HTTP: "https://foobar.com/health"}
+		registration = api.AgentServiceRegistration{ID: "service1", Name: "petasos", Check: httpCheck}
+	)
+
+	require.NoError(Heartbeat{TTL: 30 * time.Second, DeregisterCriticalServiceAfter: 10 * time.Minute}.apply(&registration))
+	assert.Equal(httpCheck, registration.Check)
+	assert.Empty(httpCheck.DeregisterCriticalServiceAfter)
+	assert.Equal(
+		api.AgentServiceChecks{
+			{
+				CheckID:                        "service:service1:heartbeat",
+				Name:                           "heartbeat",
+				TTL:                            "30s",
+				DeregisterCriticalServiceAfter: "10m0s",
+			},
+		},
+		registration.Checks,
+	)
+
+	// without an ID, the service name is used for the check ID
+	registration = api.AgentServiceRegistration{Name: "petasos"}
+	require.NoError(Heartbeat{TTL: 30 * time.Second}.apply(&registration))
+	require.Len(registration.Checks, 1)
+	assert.Equal("service:petasos:heartbeat", registration.Checks[0].CheckID)
+	assert.Empty(registration.Checks[0].DeregisterCriticalServiceAfter)
+}
+
+func testHeartbeatExistingTTL(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		ttlCheck     = &api.AgentServiceCheck{CheckID: "check1", TTL: "15s"}
+		customCheck  = &api.AgentServiceCheck{CheckID: "check2", TTL: "20s", DeregisterCriticalServiceAfter: "5m"}
+		registration = api.AgentServiceRegistration{ID: "service1", Check: ttlCheck, Checks: []*api.AgentServiceCheck{customCheck}}
+	)
+
+	require.NoError(Heartbeat{TTL: 30 * time.Second, DeregisterCriticalServiceAfter: 10 * time.Minute}.apply(&registration))
+	assert.Equal(api.AgentServiceChecks{customCheck}, registration.Checks)
+	assert.Equal("10m0s", ttlCheck.DeregisterCriticalServiceAfter)
+	assert.Equal("5m", customCheck.DeregisterCriticalServiceAfter)
+}
+
+func TestHeartbeat(t *testing.T) {
+	t.Run("Invalid", testHeartbeatInvalid)
+	t.Run("AddCheck", testHeartbeatAddCheck)
+	t.Run("ExistingTTL", testHeartbeatExistingTTL)
+}
+
+func testWatchdogRegistrarReregister(t *testing.T) {
+	defer resetTickerFactory()
+
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		logger        = logging.NewTestLogger(nil, t)
+		p             = xmetricstest.NewProvider(nil, Metrics)
+		measures      = newRegistrationMeasures()
+		client        = new(mockClient)
+		agent         = new(mockTTLUpdater)
+		registrar     = new(service.MockRegistrar)
+		tickerFactory = prepareMockTickerFactory()
+
+		ticker        = make(chan time.Time)
+		tickerStopped = make(chan struct{})
+		verified      = make(chan struct{}, 1)
+		verifiedRun   = func(mock.Arguments) { verified <- struct{}{} }
+
+		registration = &api.AgentServiceRegistration{
+			ID:   "service1",
+			Name: "talaria",
+		}
+	)
+
+	measures.setProvider(nil)
+	measures.setProvider(p)
+	tickerFactory.On("NewTicker", time.Minute).Return((<-chan time.Time)(ticker), func() { close(tickerStopped) }).Once()
+	registrar.On("Register").Once()
+	registrar.On("Deregister").Once()
+
+	agent.On("Services").Return(map[string]*api.AgentService{"service1": {ID: "service1"}}, error(nil)).Once().Run(verifiedRun)
+	agent.On("Services").Return(nil, errors.New("expected")).Once().Run(verifiedRun)
+	agent.On("Services").Return(map[string]*api.AgentService{"service2": {ID: "service2"}}, error(nil)).Once()
+	agent.On("Services").Return(map[string]*api.AgentService{}, error(nil)).Once()
+	client.On("Register", registration).Return(errors.New("expected")).Once().Run(verifiedRun)
+	client.On("Register", registration).Return(error(nil)).Once().Run(verifiedRun)
+
+	wr := newWatchdogRegistrar(registrar, client, agent, registration, time.Minute, measures, logger)
+	require.NotNil(wr)
+
+	wr.Register()
+	wr.Register() // idempotent
+
+	for repeat := 0; repeat < 4; repeat++ {
+		ticker <- time.Now()
+		select {
+		case <-verified:
+		case <-time.After(2 * time.Second):
+			require.Fail("The registration was not verified")
+		}
+	}
+
+	wr.Deregister()
+	wr.Deregister() // idempotent
+
+	select {
+	case <-tickerStopped:
+	case <-time.After(2 * time.Second):
+		assert.Fail("The watchdog did not stop")
+	}
+
+	p.Assert(t, ReregistrationCount, service.ServiceLabel, "talaria")(xmetricstest.Value(1.0))
+	client.AssertExpectations(t)
+	agent.AssertExpectations(t)
+	registrar.AssertExpectations(t)
+	tickerFactory.AssertExpectations(t)
+}
+
+func testWatchdogRegistrarServiceID(t *testing.T) {
+	assert := assert.New(t)
+
+	wr := newWatchdogRegistrar(new(service.MockRegistrar), new(mockClient), new(mockTTLUpdater), &api.AgentServiceRegistration{Name: "talaria"}, time.Minute, newRegistrationMeasures(), logging.NewTestLogger(nil, t))
+	assert.Equal("talaria", wr.serviceID)
+}
+
+func testWatchdogRegistrarShutdown(t *testing.T) {
+	var (
+		client = new(mockClient)
+		agent  = new(mockTTLUpdater)
+
+		shutdown = make(chan struct{})
+		wr       = newWatchdogRegistrar(new(service.MockRegistrar), client, agent, &api.AgentServiceRegistration{ID: "service1"}, time.Minute, newRegistrationMeasures(), logging.NewTestLogger(nil, t))
+	)
+
+	// a missing registration is not restored once the watchdog is shutdown
+	agent.On("Services").Return(map[string]*api.AgentService{}, error(nil)).Once()
+	close(shutdown)
+	wr.verify(shutdown)
+
+	client.AssertExpectations(t)
+	agent.AssertExpectations(t)
+}
+
+func TestWatchdogRegistrar(t *testing.T) {
+	t.Run("Reregister", testWatchdogRegistrarReregister)
+	t.Run("ServiceID", testWatchdogRegistrarServiceID)
+	t.Run("Shutdown", testWatchdogRegistrarShutdown)
 }
@@ -0,0 +1,166 @@
+package consul
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/hashicorp/consul/api"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+const DefaultTokenRefreshInterval = time.Minute
+
+var errEmptyToken = errors.New("The ACL token source returned an empty token")
+
+// TokenSource supplies the current consul ACL token.  Implementations are invoked periodically,
+// and may fetch the token from any location, e.g. a file or a secrets store such as Vault.
+type TokenSource interface {
+	Token() (string, error)
+}
+
+// TokenSourceFunc is a function type that implements TokenSource
+type TokenSourceFunc func() (string, error)
+
+func (tsf TokenSourceFunc) Token() (string, error) {
+	return tsf()
+}
+
+// FileTokenSource returns a TokenSource that reads the ACL token from a file each time it is invoked.
+// Leading and trailing whitespace is ignored.
+func FileTokenSource(path string) TokenSource {
+	return TokenSourceFunc(func() (string, error) {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+
+		return strings.TrimSpace(string(data)), nil
+	})
+}
+
+// tokenRefresher holds the current ACL token obtained from a TokenSource, and decorates
+// an http.RoundTripper so that every consul request carries that token.  Since the token is
+// applied to each outgoing request, any request retried after a refresh uses the new token.
+type tokenRefresher struct {
+	source TokenSource
+	logger log.Logger
+	next   http.RoundTripper
+
+	current   atomic.Value
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+func newTokenRefresher(l log.Logger, source TokenSource) *tokenRefresher {
+	tr := &tokenRefresher{
+		source: source,
+		logger: l,
+		stop:   make(chan struct{}),
+	}
+
+	tr.current.Store("")
+	return tr
+}
+
+// token returns the current ACL token
+func (tr *tokenRefresher) token() string {
+	return tr.current.Load().(string)
+}
+
+// refresh obtains a new token from the source.  If the source fails, the current token is retained.
+func (tr *tokenRefresher) refresh() error {
+	token, err := tr.source.Token()
+	if err == nil && len(token) == 0 {
+		err = errEmptyToken
+	}
+
+	if err != nil {
+		tr.logger.Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "unable to refresh ACL token", logging.ErrorKey(), err)
+		return err
+	}
+
+	tr.current.Store(token)
+	return nil
+}
+
+// refreshPeriodically refreshes the token on the given interval until close is called
+func (tr *tokenRefresher) refreshPeriodically(interval time.Duration) {
+	ticker, stop := tickerFactory(interval)
+	defer stop()
+
+	for {
+		select {
+		case <-tr.stop:
+			return
+
+		case <-ticker:
+			tr.refresh()
+		}
+	}
+}
+
+// close stops any periodic refresh.  This method is idempotent, and always returns nil.
+func (tr *tokenRefresher) close() error {
+	tr.closeOnce.Do(func() {
+		close(tr.stop)
+	})
+
+	return nil
+}
+
+// RoundTrip applies the current token to requests that do not already carry one.  Requests that
+// consul rejects as forbidden trigger an immediate refresh, so that a retry can succeed without
+// waiting for the next refresh interval.
+func (tr *tokenRefresher) RoundTrip(request *http.Request) (*http.Response, error) {
+	if token := tr.token(); len(token) > 0 && len(request.Header.Get("X-Consul-Token")) == 0 {
+		request = request.Clone(request.Context())
+		request.Header.Set("X-Consul-Token", token)
+	}
+
+	response, err := tr.next.RoundTrip(request)
+	if err == nil && response.StatusCode == http.StatusForbidden {
+		tr.refresh()
+	}
+
+	return response, err
+}
+
+// decorate returns a copy of the given consul configuration whose HTTP client applies this
+// refresher's token.  Any static token in the configuration is removed.
+func (tr *tokenRefresher) decorate(config *api.Config) (*api.Config, error) {
+	decorated := *config
+	decorated.Token = ""
+	decorated.TokenFile = ""
+
+	if decorated.HttpClient == nil {
+		transport := decorated.Transport
+		if transport == nil {
+			transport = api.DefaultConfig().Transport
+		}
+
+		httpClient, err := api.NewHttpClient(transport, decorated.TLSConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		decorated.HttpClient = httpClient
+	} else {
+		httpClient := *decorated.HttpClient
+		decorated.HttpClient = &httpClient
+	}
+
+	tr.next = decorated.HttpClient.Transport
+	if tr.next == nil {
+		tr.next = http.DefaultTransport
+	}
+
+	decorated.HttpClient.Transport = tr
+	return &decorated, nil
+}
@@ -0,0 +1,198 @@
+package consul
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+// sequenceTokenSource returns a TokenSource that returns each of the given tokens in turn,
+// then repeats the last one
+func sequenceTokenSource(tokens ...string) TokenSource {
+	return TokenSourceFunc(func() (string, error) {
+		token := tokens[0]
+		if len(tokens) > 1 {
+			tokens = tokens[1:]
+		}
+
+		return token, nil
+	})
+}
+
+func TestFileTokenSource(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+	)
+
+	dir, err := ioutil.TempDir("", "token")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "token")
+	require.NoError(ioutil.WriteFile(path, []byte("  first-token\n"), 0600))
+
+	ts := FileTokenSource(path)
+	token, err := ts.Token()
+	assert.Equal("first-token", token)
+	assert.NoError(err)
+
+	require.NoError(ioutil.WriteFile(path, []byte("second-token"), 0600))
+	token, err = ts.Token()
+	assert.Equal("second-token", token)
+	assert.NoError(err)
+
+	token, err = FileTokenSource(filepath.Join(dir, "missing")).Token()
+	assert.Empty(token)
+	assert.Error(err)
+}
+
+func testTokenRefresherRefresh(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		results = []struct {
+			token string
+			err   error
+		}{
+			{"first", nil},
+			{"", errors.New("expected")},
+			{"", nil},
+			{"second", nil},
+		}
+
+		tr = newTokenRefresher(logging.NewTestLogger(nil, t), TokenSourceFunc(func() (string, error) {
+			r := results[0]
+			results = results[1:]
+			return r.token, r.err
+		}))
+	)
+
+	assert.Empty(tr.token())
+	assert.NoError(tr.refresh())
+	assert.Equal("first", tr.token())
+
+	assert.Error(tr.refresh())
+	assert.Equal("first", tr.token(), "a failed refresh should retain the current token")
+
+	assert.Equal(errEmptyToken, tr.refresh())
+	assert.Equal("first", tr.token(), "an empty token should not replace the current token")
+
+	assert.NoError(tr.refresh())
+	assert.Equal("second", tr.token())
+}
+
+func testTokenRefresherPeriodically(t *testing.T) {
+	var (
+		assert        = assert.New(t)
+		tickerFactory = prepareMockTickerFactory()
+		ticker        = make(chan time.Time)
+		tickerStopped = make(chan struct{})
+
+		tr = newTokenRefresher(logging.NewTestLogger(nil, t), sequenceTokenSource("first", "second"))
+	)
+
+	defer resetTickerFactory()
+	tickerFactory.On("NewTicker", time.Minute).
+		Return((<-chan time.Time)(ticker), func() { close(tickerStopped) }).Once()
+
+	assert.NoError(tr.refresh())
+	go tr.refreshPeriodically(time.Minute)
+
+	ticker <- time.Now()
+	ticker <- time.Now() // the second tick cannot be received until the first refresh completes
+	assert.Equal("second", tr.token())
+
+	assert.NoError(tr.close())
+	assert.NoError(tr.close())
+
+	select {
+	case <-tickerStopped:
+	case <-time.After(5 * time.Second):
+		assert.Fail("The ticker was not stopped")
+	}
+
+	tickerFactory.AssertExpectations(t)
+}
+
+func testTokenRefresherRoundTrip(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		received []string
+		server   = httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			token := request.Header.Get("X-Consul-Token")
+			received = append(received, token)
+			if token == "expired" {
+				response.WriteHeader(http.StatusForbidden)
+			}
+		}))
+
+		tr = newTokenRefresher(logging.NewTestLogger(nil, t), sequenceTokenSource("expired", "rotated"))
+	)
+
+	defer server.Close()
+	require.NoError(tr.refresh())
+
+	config, err := tr.decorate(&api.Config{Address: server.URL, Token: "static"})
+	require.NoError(err)
+	require.NotNil(config.HttpClient)
+	assert.Empty(config.Token)
+
+	response, err := config.HttpClient.Get(server.URL)
+	require.NoError(err)
+	response.Body.Close()
+	assert.Equal(http.StatusForbidden, response.StatusCode)
+	assert.Equal("rotated", tr.token(), "a forbidden response should trigger a refresh")
+
+	// a retry uses the new token
+	response, err = config.HttpClient.Get(server.URL)
+	require.NoError(err)
+	response.Body.Close()
+	assert.Equal(http.StatusOK, response.StatusCode)
+
+	// an explicit, per-request token is not overwritten
+	request, err := http.NewRequest("GET", server.URL, nil)
+	require.NoError(err)
+	request.Header.Set("X-Consul-Token", "explicit")
+	response, err = config.HttpClient.Do(request)
+	require.NoError(err)
+	response.Body.Close()
+
+	assert.Equal([]string{"expired", "rotated", "explicit"}, received)
+}
+
+func testTokenRefresherDecorateHttpClient(t *testing.T) {
+	var (
+		assert     = assert.New(t)
+		require    = require.New(t)
+		httpClient = &http.Client{Timeout: time.Minute}
+
+		tr = newTokenRefresher(logging.NewTestLogger(nil, t), sequenceTokenSource("token"))
+	)
+
+	config, err := tr.decorate(&api.Config{HttpClient: httpClient, TokenFile: "/etc/consul/token"})
+	require.NoError(err)
+	assert.Empty(config.TokenFile)
+	assert.Nil(httpClient.Transport, "the original client should not be modified")
+	assert.Equal(time.Minute, config.HttpClient.Timeout)
+	assert.Equal(tr, config.HttpClient.Transport)
+	assert.Equal(http.DefaultTransport, tr.next)
+}
+
+func TestTokenRefresher(t *testing.T) {
+	t.Run("Refresh", testTokenRefresherRefresh)
+	t.Run("Periodically", testTokenRefresherPeriodically)
+	t.Run("RoundTrip", testTokenRefresherRoundTrip)
+	t.Run("DecorateHttpClient", testTokenRefresherDecorateHttpClient)
+}
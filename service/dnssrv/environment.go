@@ -0,0 +1,56 @@
+package dnssrv
+
+import (
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/service"
+)
+
+// resolverFactory is the factory function used to create the resolver.  Tests can change this for mocked behavior.
+var resolverFactory = func(o Options) (resolver, error) {
+	return newResolver(o)
+}
+
+func newInstancers(l log.Logger, r resolver, o Options) (i service.Instancers) {
+	for _, w := range o.watches() {
+		if i.Has(w.Name) {
+			l.Log(level.Key(), level.WarnValue(), logging.MessageKey(), "skipping duplicate watch", "name", w.Name)
+			continue
+		}
+
+		i.Set(
+			w.Name,
+			service.NewContextualInstancer(
+				newInstancer(l, r, w, o.minInterval(), o.maxInterval()),
+				map[string]interface{}{"name": w.Name},
+			),
+		)
+	}
+
+	return
+}
+
+// NewEnvironment constructs a service.Environment that discovers instances from DNS SRV records.  There is no
+// registration with DNS, so the returned Environment has no Registrars.
+func NewEnvironment(l log.Logger, o Options, eo ...service.Option) (service.Environment, error) {
+	if l == nil {
+		l = logging.DefaultLogger()
+	}
+
+	if len(o.Watches) == 0 {
+		return nil, service.ErrIncomplete
+	}
+
+	r, err := resolverFactory(o)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.NewEnvironment(
+		append(
+			eo,
+			service.WithInstancers(newInstancers(l, r, o)),
+		)...,
+	), nil
+}
@@ -0,0 +1,76 @@
+package dnssrv
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/service"
+)
+
+func testNewEnvironmentEmpty(t *testing.T) {
+	assert := assert.New(t)
+	e, err := NewEnvironment(nil, Options{})
+	assert.Nil(e)
+	assert.Equal(service.ErrIncomplete, err)
+}
+
+func testNewEnvironmentResolverError(t *testing.T) {
+	defer func() {
+		resolverFactory = func(o Options) (resolver, error) {
+			return newResolver(o)
+		}
+	}()
+
+	var (
+		assert      = assert.New(t)
+		expectedErr = errors.New("expected")
+	)
+
+	resolverFactory = func(Options) (resolver, error) {
+		return nil, expectedErr
+	}
+
+	e, err := NewEnvironment(nil, Options{Watches: []Watch{{Name: "_talaria._tcp.example.com"}}})
+	assert.Nil(e)
+	assert.Equal(expectedErr, err)
+}
+
+func testNewEnvironmentFull(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+	)
+
+	address, shutdown := startDNSServer(t)
+	defer shutdown()
+
+	e, err := NewEnvironment(
+		logging.NewTestLogger(nil, t),
+		Options{
+			Servers: []string{address},
+			Timeout: time.Second,
+			Watches: []Watch{
+				{Name: "_talaria._tcp.example.com"},
+				{Name: "_talaria._tcp.example.com"}, // duplicates should be ignored
+				{Name: "_petasos._tcp.example.com"},
+			},
+		},
+	)
+
+	require.NoError(err)
+	require.NotNil(e)
+	assert.Len(e.Instancers(), 2)
+	assert.True(e.Instancers().Has("_talaria._tcp.example.com"))
+	assert.True(e.Instancers().Has("_petasos._tcp.example.com"))
+	assert.NoError(e.Close())
+}
+
+func TestNewEnvironment(t *testing.T) {
+	t.Run("Empty", testNewEnvironmentEmpty)
+	t.Run("ResolverError", testNewEnvironmentResolverError)
+	t.Run("Full", testNewEnvironmentFull)
+}
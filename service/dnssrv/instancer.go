@@ -0,0 +1,147 @@
+package dnssrv
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/sd"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/service"
+)
+
+// instancer is a go-kit sd.Instancer that resolves a DNS SRV name each time the TTL of its records expires
+type instancer struct {
+	resolver    resolver
+	logger      log.Logger
+	watch       Watch
+	minInterval time.Duration
+	maxInterval time.Duration
+
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	registerLock sync.Mutex
+	state        sd.Event
+	registry     map[chan<- sd.Event]bool
+}
+
+func newInstancer(l log.Logger, r resolver, w Watch, minInterval, maxInterval time.Duration) *instancer {
+	if l == nil {
+		l = logging.DefaultLogger()
+	}
+
+	i := &instancer{
+		resolver:    r,
+		logger:      log.With(l, "name", w.Name),
+		watch:       w,
+		minInterval: minInterval,
+		maxInterval: maxInterval,
+		stop:        make(chan struct{}),
+		registry:    make(map[chan<- sd.Event]bool),
+	}
+
+	// grab the initial set of instances
+	next := i.resolve()
+	if i.state.Err == nil {
+		i.logger.Log(level.Key(), level.InfoValue(), "instances", len(i.state.Instances))
+	}
+
+	go i.loop(next)
+	return i
+}
+
+func (i *instancer) update(e sd.Event) {
+	sort.Strings(e.Instances)
+	defer i.registerLock.Unlock()
+	i.registerLock.Lock()
+
+	if reflect.DeepEqual(i.state, e) {
+		return
+	}
+
+	i.state = e
+	for c := range i.registry {
+		c <- i.state
+	}
+}
+
+// resolve looks up the watched name and dispatches the result, returning the time to wait until the next lookup
+func (i *instancer) resolve() time.Duration {
+	targets, ttl, err := i.resolver.lookupSRV(i.watch.Name)
+	if err != nil {
+		i.logger.Log(level.Key(), level.ErrorValue(), logging.ErrorKey(), err)
+		i.update(sd.Event{Err: err})
+		return i.minInterval
+	}
+
+	i.update(sd.Event{Instances: i.instances(targets)})
+
+	switch {
+	case ttl < i.minInterval:
+		return i.minInterval
+	case ttl > i.maxInterval:
+		return i.maxInterval
+	default:
+		return ttl
+	}
+}
+
+func (i *instancer) loop(next time.Duration) {
+	timer := time.NewTimer(next)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-i.stop:
+			return
+
+		case <-timer.C:
+			timer.Reset(i.resolve())
+		}
+	}
+}
+
+// instances formats the targets as instances, keeping only those with the lowest priority
+// value unless the watch includes all priorities
+func (i *instancer) instances(targets []target) []string {
+	var lowest uint16
+	for ix, t := range targets {
+		if ix == 0 || t.priority < lowest {
+			lowest = t.priority
+		}
+	}
+
+	instances := []string{}
+	for _, t := range targets {
+		if i.watch.AllPriorities || t.priority == lowest {
+			instances = append(instances, service.FormatInstance(i.watch.scheme(), t.host, t.port))
+		}
+	}
+
+	return instances
+}
+
+func (i *instancer) Register(ch chan<- sd.Event) {
+	defer i.registerLock.Unlock()
+	i.registerLock.Lock()
+	i.registry[ch] = true
+
+	// push the current state to the new channel
+	ch <- i.state
+}
+
+func (i *instancer) Deregister(ch chan<- sd.Event) {
+	defer i.registerLock.Unlock()
+	i.registerLock.Lock()
+	delete(i.registry, ch)
+}
+
+func (i *instancer) Stop() {
+	i.stopOnce.Do(func() {
+		close(i.stop)
+	})
+}
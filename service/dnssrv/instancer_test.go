@@ -0,0 +1,105 @@
+package dnssrv
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/sd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+var testTargets = []target{
+	{host: "talaria-1.example.com", port: 8080, priority: 10},
+	{host: "talaria-2.example.com", port: 443, priority: 10},
+	{host: "talaria-3.example.com", port: 8080, priority: 20},
+}
+
+func testInstancerInstances(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		i      = &instancer{watch: Watch{Name: "_talaria._tcp.example.com"}}
+	)
+
+	assert.Equal([]string{"http://talaria-1.example.com:8080", "http://talaria-2.example.com:443"}, i.instances(testTargets))
+	assert.Equal([]string{}, i.instances(nil))
+
+	i.watch.Scheme = "https"
+	i.watch.AllPriorities = true
+	assert.Equal(
+		[]string{"https://talaria-1.example.com:8080", "https://talaria-2.example.com", "https://talaria-3.example.com:8080"},
+		i.instances(testTargets),
+	)
+}
+
+func testInstancerResolve(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		resolver = new(mockResolver)
+		i        = &instancer{
+			resolver:    resolver,
+			logger:      logging.NewTestLogger(nil, t),
+			watch:       Watch{Name: "_talaria._tcp.example.com"},
+			minInterval: time.Second,
+			maxInterval: time.Minute,
+			registry:    make(map[chan<- sd.Event]bool),
+		}
+	)
+
+	resolver.On("lookupSRV", "_talaria._tcp.example.com").Return(testTargets, 30*time.Second, error(nil)).Once()
+	assert.Equal(30*time.Second, i.resolve(), "the TTL should determine the next lookup")
+	assert.Len(i.state.Instances, 2)
+
+	resolver.On("lookupSRV", "_talaria._tcp.example.com").Return(testTargets, time.Duration(0), error(nil)).Once()
+	assert.Equal(time.Second, i.resolve(), "short TTLs should be raised to the minimum interval")
+
+	resolver.On("lookupSRV", "_talaria._tcp.example.com").Return(testTargets, time.Hour, error(nil)).Once()
+	assert.Equal(time.Minute, i.resolve(), "long TTLs should be lowered to the maximum interval")
+
+	expectedErr := errors.New("expected")
+	resolver.On("lookupSRV", "_talaria._tcp.example.com").Return(nil, time.Duration(0), expectedErr).Once()
+	assert.Equal(time.Second, i.resolve(), "failed lookups should be retried after the minimum interval")
+	assert.Equal(expectedErr, i.state.Err)
+	assert.Empty(i.state.Instances)
+
+	resolver.AssertExpectations(t)
+}
+
+func testInstancerRefresh(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		require  = require.New(t)
+		resolver = new(mockResolver)
+		events   = make(chan sd.Event, 10)
+	)
+
+	resolver.On("lookupSRV", "_talaria._tcp.example.com").Return(testTargets[:1], time.Millisecond, error(nil)).Once()
+	resolver.On("lookupSRV", "_talaria._tcp.example.com").Return(testTargets, time.Millisecond, error(nil))
+
+	i := newInstancer(logging.NewTestLogger(nil, t), resolver, Watch{Name: "_talaria._tcp.example.com"}, 10*time.Millisecond, time.Minute)
+	require.NotNil(i)
+	defer i.Stop()
+
+	i.Register(events)
+	assert.Equal([]string{"http://talaria-1.example.com:8080"}, (<-events).Instances)
+
+	select {
+	case e := <-events:
+		assert.Equal([]string{"http://talaria-1.example.com:8080", "http://talaria-2.example.com:443"}, e.Instances)
+	case <-time.After(5 * time.Second):
+		require.Fail("The SRV name was not resolved again")
+	}
+
+	i.Stop()
+	i.Stop()
+	resolver.AssertCalled(t, "lookupSRV", mock.Anything)
+}
+
+func TestInstancer(t *testing.T) {
+	t.Run("Instances", testInstancerInstances)
+	t.Run("Resolve", testInstancerResolve)
+	t.Run("Refresh", testInstancerRefresh)
+}
@@ -0,0 +1,17 @@
+package dnssrv
+
+import (
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type mockResolver struct {
+	mock.Mock
+}
+
+func (m *mockResolver) lookupSRV(name string) ([]target, time.Duration, error) {
+	arguments := m.Called(name)
+	first, _ := arguments.Get(0).([]target)
+	return first, arguments.Get(1).(time.Duration), arguments.Error(2)
+}
@@ -0,0 +1,94 @@
+package dnssrv
+
+import (
+	"time"
+)
+
+const (
+	DefaultResolvConf  = "/etc/resolv.conf"
+	DefaultScheme      = "http"
+	DefaultMinInterval = 5 * time.Second
+	DefaultMaxInterval = 5 * time.Minute
+	DefaultTimeout     = 5 * time.Second
+)
+
+// Watch describes a DNS SRV name whose targets are to be discovered
+type Watch struct {
+	// Name is the SRV name to resolve, e.g. _talaria._tcp.xmidt.example.com.  This field is required.
+	Name string `json:"name"`
+
+	// Scheme is the scheme used to format instances.  If not supplied, DefaultScheme is used.
+	Scheme string `json:"scheme,omitempty"`
+
+	// AllPriorities includes every target.  By default, only the targets with the lowest priority value
+	// are included, as RFC 2782 requires clients to prefer those.
+	AllPriorities bool `json:"allPriorities"`
+}
+
+func (w Watch) scheme() string {
+	if len(w.Scheme) > 0 {
+		return w.Scheme
+	}
+
+	return DefaultScheme
+}
+
+// Options describes the configuration for DNS SRV service discovery.  Each watch is resolved again
+// when the shortest TTL among its records expires, bounded by MinInterval and MaxInterval.
+type Options struct {
+	// Servers are the nameservers to query, as host:port.  If not supplied, the nameservers in
+	// DefaultResolvConf are used.
+	Servers []string `json:"servers,omitempty"`
+
+	// MinInterval is the shortest time between queries for a watch, which also applies after
+	// a failed query.  If not supplied, DefaultMinInterval is used.
+	MinInterval time.Duration `json:"minInterval,omitempty"`
+
+	// MaxInterval is the longest time between queries for a watch.  If not supplied, DefaultMaxInterval is used.
+	MaxInterval time.Duration `json:"maxInterval,omitempty"`
+
+	// Timeout is the timeout for each DNS query.  If not supplied, DefaultTimeout is used.
+	Timeout time.Duration `json:"timeout,omitempty"`
+
+	Watches []Watch `json:"watches,omitempty"`
+}
+
+func (o *Options) servers() []string {
+	if o != nil && len(o.Servers) > 0 {
+		return o.Servers
+	}
+
+	return nil
+}
+
+func (o *Options) minInterval() time.Duration {
+	if o != nil && o.MinInterval > 0 {
+		return o.MinInterval
+	}
+
+	return DefaultMinInterval
+}
+
+func (o *Options) maxInterval() time.Duration {
+	if o != nil && o.MaxInterval > 0 {
+		return o.MaxInterval
+	}
+
+	return DefaultMaxInterval
+}
+
+func (o *Options) timeout() time.Duration {
+	if o != nil && o.Timeout > 0 {
+		return o.Timeout
+	}
+
+	return DefaultTimeout
+}
+
+func (o *Options) watches() []Watch {
+	if o != nil && len(o.Watches) > 0 {
+		return o.Watches
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package dnssrv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testOptionsDefault(t *testing.T, o *Options) {
+	assert := assert.New(t)
+
+	assert.Len(o.servers(), 0)
+	assert.Equal(DefaultMinInterval, o.minInterval())
+	assert.Equal(DefaultMaxInterval, o.maxInterval())
+	assert.Equal(DefaultTimeout, o.timeout())
+	assert.Len(o.watches(), 0)
+}
+
+func testOptionsCustom(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		o      = Options{
+			Servers:     []string{"127.0.0.1:53"},
+			MinInterval: time.Second,
+			MaxInterval: time.Hour,
+			Timeout:     2 * time.Second,
+			Watches: []Watch{
+				{Name: "_talaria._tcp.example.com"},
+			},
+		}
+	)
+
+	assert.Equal([]string{"127.0.0.1:53"}, o.servers())
+	assert.Equal(time.Second, o.minInterval())
+	assert.Equal(time.Hour, o.maxInterval())
+	assert.Equal(2*time.Second, o.timeout())
+	assert.Equal([]Watch{{Name: "_talaria._tcp.example.com"}}, o.watches())
+}
+
+func TestOptions(t *testing.T) {
+	t.Run("Default", func(t *testing.T) {
+		testOptionsDefault(t, nil)
+		testOptionsDefault(t, new(Options))
+	})
+
+	t.Run("Custom", testOptionsCustom)
+}
+
+func TestWatchScheme(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(DefaultScheme, Watch{}.scheme())
+	assert.Equal("https", Watch{Scheme: "https"}.scheme())
+}
@@ -0,0 +1,109 @@
+package dnssrv
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+var errNoServers = errors.New("No DNS servers configured")
+
+// target is a single SRV record
+type target struct {
+	host     string
+	port     int
+	priority uint16
+}
+
+// resolver performs SRV lookups.  Unlike net.Resolver, the TTL of the records is returned.
+type resolver interface {
+	// lookupSRV returns the targets for a name, along with the shortest TTL of the answer records
+	lookupSRV(name string) ([]target, time.Duration, error)
+}
+
+// dnsResolver is the resolver implementation that queries nameservers directly
+type dnsResolver struct {
+	servers []string
+	udp     *dns.Client
+	tcp     *dns.Client
+}
+
+func newResolver(o Options) (*dnsResolver, error) {
+	servers := o.servers()
+	if len(servers) == 0 {
+		cc, err := dns.ClientConfigFromFile(DefaultResolvConf)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range cc.Servers {
+			servers = append(servers, net.JoinHostPort(s, cc.Port))
+		}
+	}
+
+	if len(servers) == 0 {
+		return nil, errNoServers
+	}
+
+	return &dnsResolver{
+		servers: servers,
+		udp:     &dns.Client{Net: "udp", Timeout: o.timeout()},
+		tcp:     &dns.Client{Net: "tcp", Timeout: o.timeout()},
+	}, nil
+}
+
+// exchange sends a query to each server in turn, returning the first response.  Truncated UDP responses
+// are retried over TCP.
+func (r *dnsResolver) exchange(query *dns.Msg) (response *dns.Msg, err error) {
+	for _, server := range r.servers {
+		response, _, err = r.udp.Exchange(query, server)
+		if err == nil && response.Truncated {
+			response, _, err = r.tcp.Exchange(query, server)
+		}
+
+		if err == nil {
+			return
+		}
+	}
+
+	return
+}
+
+func (r *dnsResolver) lookupSRV(name string) ([]target, time.Duration, error) {
+	query := new(dns.Msg)
+	query.SetQuestion(dns.Fqdn(name), dns.TypeSRV)
+
+	response, err := r.exchange(query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if response.Rcode != dns.RcodeSuccess {
+		return nil, 0, fmt.Errorf("SRV lookup for %s failed: %s", name, dns.RcodeToString[response.Rcode])
+	}
+
+	var (
+		targets []target
+		ttl     time.Duration
+	)
+
+	for _, rr := range response.Answer {
+		if srv, ok := rr.(*dns.SRV); ok {
+			targets = append(targets, target{
+				host:     strings.TrimSuffix(srv.Target, "."),
+				port:     int(srv.Port),
+				priority: srv.Priority,
+			})
+
+			if recordTTL := time.Duration(srv.Hdr.Ttl) * time.Second; len(targets) == 1 || recordTTL < ttl {
+				ttl = recordTTL
+			}
+		}
+	}
+
+	return targets, ttl, nil
+}
@@ -0,0 +1,79 @@
+package dnssrv
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startDNSServer starts a UDP nameserver on the loopback interface that answers SRV
+// queries for _talaria._tcp.example.com.  Other names are answered with NXDOMAIN.
+func startDNSServer(t *testing.T) (string, func()) {
+	mux := dns.NewServeMux()
+	mux.HandleFunc(".", func(w dns.ResponseWriter, request *dns.Msg) {
+		response := new(dns.Msg)
+		response.SetReply(request)
+
+		if request.Question[0].Name == "_talaria._tcp.example.com." {
+			for _, record := range []string{
+				"_talaria._tcp.example.com. 60 IN SRV 10 5 8080 talaria-1.example.com.",
+				"_talaria._tcp.example.com. 30 IN SRV 10 5 8080 talaria-2.example.com.",
+				"_talaria._tcp.example.com. 90 IN SRV 20 5 8080 talaria-3.example.com.",
+			} {
+				rr, err := dns.NewRR(record)
+				if err != nil {
+					panic(err)
+				}
+
+				response.Answer = append(response.Answer, rr)
+			}
+		} else {
+			response.Rcode = dns.RcodeNameError
+		}
+
+		w.WriteMsg(response)
+	})
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	server := &dns.Server{PacketConn: conn, Handler: mux, NotifyStartedFunc: func() { close(started) }}
+	go server.ActivateAndServe()
+	<-started
+
+	return conn.LocalAddr().String(), func() { server.Shutdown() }
+}
+
+func TestResolver(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+	)
+
+	address, shutdown := startDNSServer(t)
+	defer shutdown()
+
+	r, err := newResolver(Options{Servers: []string{address}, Timeout: time.Second})
+	require.NoError(err)
+
+	targets, ttl, err := r.lookupSRV("_talaria._tcp.example.com")
+	require.NoError(err)
+	assert.Equal(30*time.Second, ttl)
+	assert.Equal(
+		[]target{
+			{host: "talaria-1.example.com", port: 8080, priority: 10},
+			{host: "talaria-2.example.com", port: 8080, priority: 10},
+			{host: "talaria-3.example.com", port: 8080, priority: 20},
+		},
+		targets,
+	)
+
+	targets, _, err = r.lookupSRV("_missing._tcp.example.com")
+	assert.Empty(targets)
+	assert.Error(err)
+}
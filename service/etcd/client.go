@@ -0,0 +1,254 @@
+package etcd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errLeaseExpired = errors.New("The etcd lease has expired")
+	errNoEndpoints  = errors.New("No etcd endpoint could be reached")
+)
+
+// int64String is an int64 which the etcd JSON gateway encodes as a string
+type int64String int64
+
+func (i int64String) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(strconv.FormatInt(int64(i), 10))), nil
+}
+
+func (i *int64String) UnmarshalJSON(data []byte) error {
+	v, err := strconv.ParseInt(strings.Trim(string(data), `"`), 10, 64)
+	if err != nil {
+		return err
+	}
+
+	*i = int64String(v)
+	return nil
+}
+
+// bytesString is a key or value, which the etcd JSON gateway encodes as base64
+type bytesString string
+
+func (b bytesString) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.StdEncoding.EncodeToString([]byte(b)))
+}
+
+func (b *bytesString) UnmarshalJSON(data []byte) error {
+	var encoded string
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return err
+	}
+
+	*b = bytesString(decoded)
+	return nil
+}
+
+type responseHeader struct {
+	Revision int64String `json:"revision"`
+}
+
+type keyValue struct {
+	Key   bytesString `json:"key"`
+	Value bytesString `json:"value"`
+}
+
+type watchEvent struct {
+	Type string   `json:"type"`
+	KV   keyValue `json:"kv"`
+}
+
+type watchResponse struct {
+	Result struct {
+		Header          responseHeader `json:"header"`
+		Created         bool           `json:"created"`
+		Canceled        bool           `json:"canceled"`
+		CompactRevision int64String    `json:"compact_revision"`
+		Events          []watchEvent   `json:"events"`
+	} `json:"result"`
+
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// prefixEnd returns the end of the key range for a prefix, in the same way as the etcd clientv3 package
+func prefixEnd(prefix string) string {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1])
+		}
+	}
+
+	// the prefix is all 0xff bytes, so the range extends to the end of the keyspace
+	return "\x00"
+}
+
+// client is a minimal etcd v3 client that uses the JSON gRPC gateway exposed by each etcd member
+type client struct {
+	endpoints  []string
+	timeout    time.Duration
+	httpClient *http.Client
+}
+
+func newClient(c *Client) *client {
+	return &client{
+		endpoints:  c.endpoints(),
+		timeout:    c.requestTimeout(),
+		httpClient: new(http.Client),
+	}
+}
+
+// post sends a request to the first reachable endpoint.  The caller must close the returned body.
+func (c *client) post(ctx context.Context, path string, request interface{}) (io.ReadCloser, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	err = errNoEndpoints
+	for _, endpoint := range c.endpoints {
+		var httpRequest *http.Request
+		httpRequest, err = http.NewRequest("POST", endpoint+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+
+		httpRequest = httpRequest.WithContext(ctx)
+		httpRequest.Header.Set("Content-Type", "application/json")
+
+		var response *http.Response
+		response, err = c.httpClient.Do(httpRequest)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+
+			continue
+		}
+
+		if response.StatusCode != http.StatusOK {
+			message, _ := ioutil.ReadAll(response.Body)
+			response.Body.Close()
+			return nil, fmt.Errorf("etcd request %s failed with status %d: %s", path, response.StatusCode, bytes.TrimSpace(message))
+		}
+
+		return response.Body, nil
+	}
+
+	return nil, err
+}
+
+// call sends a request and decodes the response
+func (c *client) call(path string, request, response interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	body, err := c.post(ctx, path, request)
+	if err != nil {
+		return err
+	}
+
+	defer body.Close()
+	return json.NewDecoder(body).Decode(response)
+}
+
+// grant creates a lease with the given TTL
+func (c *client) grant(ttl time.Duration) (int64String, error) {
+	var response struct {
+		ID    int64String `json:"ID"`
+		Error string      `json:"error"`
+	}
+
+	if err := c.call("/v3/lease/grant", map[string]interface{}{"TTL": int64String(ttl / time.Second)}, &response); err != nil {
+		return 0, err
+	}
+
+	if len(response.Error) > 0 {
+		return 0, errors.New(response.Error)
+	}
+
+	return response.ID, nil
+}
+
+// keepAlive refreshes a lease, returning errLeaseExpired if the lease no longer exists
+func (c *client) keepAlive(lease int64String) error {
+	var response struct {
+		Result struct {
+			TTL int64String `json:"TTL"`
+		} `json:"result"`
+	}
+
+	if err := c.call("/v3/lease/keepalive", map[string]interface{}{"ID": lease}, &response); err != nil {
+		return err
+	}
+
+	if response.Result.TTL <= 0 {
+		return errLeaseExpired
+	}
+
+	return nil
+}
+
+// revoke revokes a lease, which deletes all keys attached to it
+func (c *client) revoke(lease int64String) error {
+	return c.call("/v3/lease/revoke", map[string]interface{}{"ID": lease}, new(json.RawMessage))
+}
+
+// put sets a key's value, attaching the key to a lease
+func (c *client) put(key, value string, lease int64String) error {
+	return c.call(
+		"/v3/kv/put",
+		map[string]interface{}{"key": bytesString(key), "value": bytesString(value), "lease": lease},
+		new(json.RawMessage),
+	)
+}
+
+// rangePrefix returns all the key/value pairs under a prefix, along with the store revision
+func (c *client) rangePrefix(prefix string) ([]keyValue, int64String, error) {
+	var response struct {
+		Header responseHeader `json:"header"`
+		KVs    []keyValue     `json:"kvs"`
+	}
+
+	err := c.call(
+		"/v3/kv/range",
+		map[string]interface{}{"key": bytesString(prefix), "range_end": bytesString(prefixEnd(prefix))},
+		&response,
+	)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return response.KVs, response.Header.Revision, nil
+}
+
+// watch streams changes to the keys under a prefix, starting at the given revision.  The caller must
+// close the returned stream, and may cancel the context to end the watch.
+func (c *client) watch(ctx context.Context, prefix string, startRevision int64String) (io.ReadCloser, error) {
+	return c.post(ctx, "/v3/watch", map[string]interface{}{
+		"create_request": map[string]interface{}{
+			"key":            bytesString(prefix),
+			"range_end":      bytesString(prefixEnd(prefix)),
+			"start_revision": startRevision,
+		},
+	})
+}
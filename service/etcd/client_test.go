@@ -0,0 +1,158 @@
+package etcd
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrefixEnd(t *testing.T) {
+	testData := []struct {
+		prefix   string
+		expected string
+	}{
+		{"/xmidt/", "/xmidt0"},
+		{"a", "b"},
+		{"a\xff", "b"},
+		{"\xff\xff", "\x00"},
+	}
+
+	for _, record := range testData {
+		t.Run(record.prefix, func(t *testing.T) {
+			assert.Equal(t, record.expected, prefixEnd(record.prefix))
+		})
+	}
+}
+
+func TestInt64String(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		actual  int64String
+	)
+
+	data, err := json.Marshal(int64String(7587892347))
+	require.NoError(err)
+	assert.Equal(`"7587892347"`, string(data))
+
+	require.NoError(json.Unmarshal([]byte(`"123"`), &actual))
+	assert.Equal(int64String(123), actual)
+
+	require.NoError(json.Unmarshal([]byte(`456`), &actual))
+	assert.Equal(int64String(456), actual)
+
+	assert.Error(json.Unmarshal([]byte(`"abc"`), &actual))
+}
+
+func TestBytesString(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		actual  bytesString
+	)
+
+	data, err := json.Marshal(bytesString("/xmidt/"))
+	require.NoError(err)
+	assert.Equal(`"L3htaWR0Lw=="`, string(data))
+
+	require.NoError(json.Unmarshal(data, &actual))
+	assert.Equal(bytesString("/xmidt/"), actual)
+
+	assert.Error(json.Unmarshal([]byte(`"not base64!"`), &actual))
+	assert.Error(json.Unmarshal([]byte(`123`), &actual))
+}
+
+func testClientFailover(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		server = newFakeEtcd()
+		c      = newClient(&Client{Endpoints: []string{"http://127.0.0.1:1", server.URL}, RequestTimeout: time.Second})
+	)
+
+	defer server.Close()
+
+	lease, err := c.grant(10 * time.Second)
+	require.NoError(err)
+	assert.Equal(int64String(1), lease)
+	assert.Equal(1, server.leaseCount())
+}
+
+func testClientNoEndpoints(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		c      = newClient(&Client{Endpoints: []string{"http://127.0.0.1:1"}, RequestTimeout: time.Second})
+	)
+
+	_, err := c.grant(10 * time.Second)
+	assert.Error(err)
+}
+
+func testClientErrorStatus(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		server = newFakeEtcd()
+		c      = newClient(&Client{Endpoints: []string{server.URL}})
+	)
+
+	defer server.Close()
+	assert.Error(c.revoke(12345))
+	assert.Error(c.put("/xmidt/test/key", "value", 12345))
+}
+
+func testClientKeepAlive(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		server = newFakeEtcd()
+		c      = newClient(&Client{Endpoints: []string{server.URL}})
+	)
+
+	defer server.Close()
+
+	lease, err := c.grant(10 * time.Second)
+	require.NoError(err)
+	assert.NoError(c.keepAlive(lease))
+
+	server.expire(int64(lease))
+	assert.Equal(errLeaseExpired, c.keepAlive(lease))
+}
+
+func testClientRange(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		server = newFakeEtcd()
+		c      = newClient(&Client{Endpoints: []string{server.URL}})
+	)
+
+	defer server.Close()
+	server.set("/xmidt/talaria/a", "http://talaria-1:8080")
+	server.set("/xmidt/talaria/b", "http://talaria-2:8080")
+	server.set("/xmidt/scytale/a", "http://scytale-1:8080")
+
+	kvs, revision, err := c.rangePrefix("/xmidt/talaria/")
+	require.NoError(err)
+	assert.Equal(int64String(4), revision)
+	assert.ElementsMatch(
+		[]keyValue{
+			{Key: "/xmidt/talaria/a", Value: "http://talaria-1:8080"},
+			{Key: "/xmidt/talaria/b", Value: "http://talaria-2:8080"},
+		},
+		kvs,
+	)
+}
+
+func TestClientCalls(t *testing.T) {
+	t.Run("Failover", testClientFailover)
+	t.Run("NoEndpoints", testClientNoEndpoints)
+	t.Run("ErrorStatus", testClientErrorStatus)
+	t.Run("KeepAlive", testClientKeepAlive)
+	t.Run("Range", testClientRange)
+}
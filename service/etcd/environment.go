@@ -0,0 +1,57 @@
+package etcd
+
+import (
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/service"
+)
+
+func newInstancers(l log.Logger, c *client, eo Options) (i service.Instancers) {
+	for _, prefix := range eo.watches() {
+		if i.Has(prefix) {
+			l.Log(level.Key(), level.WarnValue(), logging.MessageKey(), "skipping duplicate watch", "prefix", prefix)
+			continue
+		}
+
+		i.Set(prefix, service.NewContextualInstancer(newInstancer(l, c, prefix), map[string]interface{}{"prefix": prefix}))
+	}
+
+	return
+}
+
+func newRegistrars(base log.Logger, c *client, eo Options) (r service.Registrars) {
+	for _, registration := range eo.registrations() {
+		instance := service.FormatInstance(registration.scheme(), registration.address(), registration.port())
+		if r.Has(instance) {
+			base.Log(level.Key(), level.WarnValue(), logging.MessageKey(), "skipping duplicate registration", "instance", instance)
+			continue
+		}
+
+		key := registration.prefix() + instance
+		r.Add(instance, newRegistrar(c, log.With(base, "instance", instance, "key", key), key, instance, registration.ttl()))
+	}
+
+	return
+}
+
+// NewEnvironment constructs an etcd v3 based service.Environment using both an etcd Options (typically unmarshaled
+// from configuration) and an optional extra set of environment options.
+func NewEnvironment(l log.Logger, eo Options, so ...service.Option) (service.Environment, error) {
+	if l == nil {
+		l = logging.DefaultLogger()
+	}
+
+	if len(eo.Watches) == 0 && len(eo.Registrations) == 0 {
+		return nil, service.ErrIncomplete
+	}
+
+	c := newClient(eo.client())
+	return service.NewEnvironment(
+		append(
+			so,
+			service.WithRegistrars(newRegistrars(l, c, eo)),
+			service.WithInstancers(newInstancers(l, c, eo)),
+		)...,
+	), nil
+}
@@ -0,0 +1,65 @@
+package etcd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/service"
+)
+
+func testNewEnvironmentEmpty(t *testing.T) {
+	assert := assert.New(t)
+	e, err := NewEnvironment(nil, Options{})
+	assert.Nil(e)
+	assert.Equal(service.ErrIncomplete, err)
+}
+
+func testNewEnvironmentFull(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		server  = newFakeEtcd()
+	)
+
+	defer server.Close()
+
+	e, err := NewEnvironment(
+		logging.NewTestLogger(nil, t),
+		Options{
+			Client: Client{Endpoints: []string{server.URL}},
+			Registrations: []Registration{
+				{Prefix: "/xmidt/talaria/", Address: "talaria-1", Port: 8080},
+				{Prefix: "/xmidt/talaria/", Address: "talaria-1", Port: 8080}, // duplicates should be ignored
+			},
+			Watches: []string{
+				"/xmidt/talaria/",
+				"/xmidt/talaria/", // duplicates should be ignored
+				"/xmidt/scytale/",
+			},
+		},
+	)
+
+	require.NoError(err)
+	require.NotNil(e)
+
+	assert.Len(e.Instancers(), 2)
+	assert.True(e.Instancers().Has("/xmidt/talaria/"))
+	assert.True(e.Instancers().Has("/xmidt/scytale/"))
+
+	e.Register()
+	value, ok := server.value("/xmidt/talaria/http://talaria-1:8080")
+	assert.True(ok)
+	assert.Equal("http://talaria-1:8080", value)
+	assert.Equal(1, server.leaseCount())
+
+	assert.NoError(e.Close())
+	_, ok = server.value("/xmidt/talaria/http://talaria-1:8080")
+	assert.False(ok)
+}
+
+func TestNewEnvironment(t *testing.T) {
+	t.Run("Empty", testNewEnvironmentEmpty)
+	t.Run("Full", testNewEnvironmentFull)
+}
@@ -0,0 +1,210 @@
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/util/conn"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+var errWatchCanceled = errors.New("The etcd watch was canceled")
+
+// instancer is a go-kit sd.Instancer that lists and then watches the keys under a prefix.  The value of each
+// key is an instance.  If the watch is canceled, e.g. because its revision was compacted, the keys are listed again.
+type instancer struct {
+	client *client
+	logger log.Logger
+	prefix string
+
+	ctx    context.Context
+	cancel func()
+
+	// kvs is only accessed by the goroutine running loop, once that goroutine starts
+	kvs map[string]string
+
+	registerLock sync.Mutex
+	state        sd.Event
+	registry     map[chan<- sd.Event]bool
+}
+
+func newInstancer(l log.Logger, c *client, prefix string) *instancer {
+	if l == nil {
+		l = logging.DefaultLogger()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	i := &instancer{
+		client:   c,
+		logger:   log.With(l, "prefix", prefix),
+		prefix:   prefix,
+		ctx:      ctx,
+		cancel:   cancel,
+		registry: make(map[chan<- sd.Event]bool),
+	}
+
+	// grab the initial set of instances
+	revision, err := i.relist()
+	if err == nil {
+		i.logger.Log(level.Key(), level.InfoValue(), "instances", len(i.state.Instances))
+	} else {
+		i.logger.Log(level.Key(), level.ErrorValue(), logging.ErrorKey(), err)
+		i.update(sd.Event{Err: err})
+	}
+
+	go i.loop(revision)
+	return i
+}
+
+func (i *instancer) update(e sd.Event) {
+	sort.Strings(e.Instances)
+	defer i.registerLock.Unlock()
+	i.registerLock.Lock()
+
+	if reflect.DeepEqual(i.state, e) {
+		return
+	}
+
+	i.state = e
+	for c := range i.registry {
+		c <- i.state
+	}
+}
+
+func (i *instancer) instances() []string {
+	instances := make([]string, 0, len(i.kvs))
+	for _, v := range i.kvs {
+		instances = append(instances, v)
+	}
+
+	return instances
+}
+
+// relist replaces all the keys with the current set, returning the revision to watch from
+func (i *instancer) relist() (int64String, error) {
+	kvs, revision, err := i.client.rangePrefix(i.prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	i.kvs = make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		i.kvs[string(kv.Key)] = string(kv.Value)
+	}
+
+	i.update(sd.Event{Instances: i.instances()})
+	return revision + 1, nil
+}
+
+func (i *instancer) loop(revision int64String) {
+	var (
+		err error
+		d   time.Duration = 10 * time.Millisecond
+	)
+
+	for {
+		if revision == 0 {
+			revision, err = i.relist()
+		}
+
+		if err == nil {
+			revision, err = i.watchChanges(revision)
+		}
+
+		if i.ctx.Err() != nil {
+			return
+		}
+
+		switch {
+		case err == errWatchCanceled:
+			i.logger.Log(level.Key(), level.DebugValue(), logging.MessageKey(), "watch canceled, relisting")
+			d = 10 * time.Millisecond
+
+		case err != nil:
+			i.logger.Log(logging.ErrorKey(), err)
+			i.update(sd.Event{Err: err})
+
+			select {
+			case <-i.ctx.Done():
+				return
+			case <-time.After(d):
+			}
+
+			d = conn.Exponential(d)
+
+		default:
+			d = 10 * time.Millisecond
+		}
+	}
+}
+
+// watchChanges applies changes from a single watch stream until that stream ends.  The next revision
+// to watch from is returned.
+func (i *instancer) watchChanges(revision int64String) (int64String, error) {
+	stream, err := i.client.watch(i.ctx, i.prefix, revision)
+	if err != nil {
+		return 0, err
+	}
+
+	defer stream.Close()
+	decoder := json.NewDecoder(stream)
+	for {
+		var response watchResponse
+		if err := decoder.Decode(&response); err == io.EOF {
+			return revision, nil
+		} else if err != nil {
+			return 0, err
+		}
+
+		switch {
+		case response.Error != nil:
+			return 0, errors.New(response.Error.Message)
+
+		case response.Result.Canceled || response.Result.CompactRevision > 0:
+			return 0, errWatchCanceled
+		}
+
+		if len(response.Result.Events) == 0 {
+			continue
+		}
+
+		for _, event := range response.Result.Events {
+			if event.Type == "DELETE" {
+				delete(i.kvs, string(event.KV.Key))
+			} else {
+				i.kvs[string(event.KV.Key)] = string(event.KV.Value)
+			}
+		}
+
+		revision = response.Result.Header.Revision + 1
+		i.update(sd.Event{Instances: i.instances()})
+	}
+}
+
+func (i *instancer) Register(ch chan<- sd.Event) {
+	defer i.registerLock.Unlock()
+	i.registerLock.Lock()
+	i.registry[ch] = true
+
+	// push the current state to the new channel
+	ch <- i.state
+}
+
+func (i *instancer) Deregister(ch chan<- sd.Event) {
+	defer i.registerLock.Unlock()
+	i.registerLock.Lock()
+	delete(i.registry, ch)
+}
+
+func (i *instancer) Stop() {
+	i.cancel()
+}
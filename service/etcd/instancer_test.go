@@ -0,0 +1,122 @@
+package etcd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/sd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+func expectEvent(t *testing.T, events <-chan sd.Event, expected sd.Event) {
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e := <-events:
+			if assert.ObjectsAreEqual(expected, e) {
+				return
+			}
+
+		case <-timeout:
+			assert.Fail(t, "No matching event", "expected: %v", expected)
+			return
+		}
+	}
+}
+
+func testInstancerWatch(t *testing.T) {
+	var (
+		require = require.New(t)
+		server  = newFakeEtcd()
+	)
+
+	defer server.Close()
+	server.set("/xmidt/talaria/b", "http://talaria-2:8080")
+	server.set("/xmidt/talaria/a", "http://talaria-1:8080")
+	server.set("/xmidt/scytale/a", "http://scytale-1:8080")
+
+	i := newInstancer(logging.NewTestLogger(nil, t), newClient(&Client{Endpoints: []string{server.URL}}), "/xmidt/talaria/")
+	require.NotNil(i)
+	defer i.Stop()
+
+	events := make(chan sd.Event, 10)
+	i.Register(events)
+	defer i.Deregister(events)
+
+	expectEvent(t, events, sd.Event{Instances: []string{"http://talaria-1:8080", "http://talaria-2:8080"}})
+
+	server.set("/xmidt/talaria/c", "http://talaria-3:8080")
+	expectEvent(t, events, sd.Event{Instances: []string{"http://talaria-1:8080", "http://talaria-2:8080", "http://talaria-3:8080"}})
+
+	// changes to other prefixes are ignored
+	server.set("/xmidt/scytale/b", "http://scytale-2:8080")
+
+	c := newClient(&Client{Endpoints: []string{server.URL}})
+	lease, err := c.grant(time.Minute)
+	require.NoError(err)
+	require.NoError(c.put("/xmidt/talaria/d", "http://talaria-4:8080", lease))
+	expectEvent(t, events, sd.Event{Instances: []string{"http://talaria-1:8080", "http://talaria-2:8080", "http://talaria-3:8080", "http://talaria-4:8080"}})
+
+	require.NoError(c.revoke(lease))
+	expectEvent(t, events, sd.Event{Instances: []string{"http://talaria-1:8080", "http://talaria-2:8080", "http://talaria-3:8080"}})
+}
+
+func testInstancerRelist(t *testing.T) {
+	var (
+		require = require.New(t)
+		server  = newFakeEtcd()
+	)
+
+	defer server.Close()
+	server.set("/xmidt/talaria/a", "http://talaria-1:8080")
+
+	// a compacted watch forces the instancer to list the keys again
+	server.setCompact(true)
+	i := newInstancer(logging.NewTestLogger(nil, t), newClient(&Client{Endpoints: []string{server.URL}}), "/xmidt/talaria/")
+	require.NotNil(i)
+	defer i.Stop()
+
+	events := make(chan sd.Event, 10)
+	i.Register(events)
+	defer i.Deregister(events)
+	expectEvent(t, events, sd.Event{Instances: []string{"http://talaria-1:8080"}})
+
+	server.set("/xmidt/talaria/b", "http://talaria-2:8080")
+	expectEvent(t, events, sd.Event{Instances: []string{"http://talaria-1:8080", "http://talaria-2:8080"}})
+
+	server.setCompact(false)
+	server.set("/xmidt/talaria/c", "http://talaria-3:8080")
+	expectEvent(t, events, sd.Event{Instances: []string{"http://talaria-1:8080", "http://talaria-2:8080", "http://talaria-3:8080"}})
+}
+
+func testInstancerError(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+	)
+
+	i := newInstancer(
+		logging.NewTestLogger(nil, t),
+		newClient(&Client{Endpoints: []string{"http://127.0.0.1:1"}, RequestTimeout: time.Second}),
+		"/xmidt/talaria/",
+	)
+
+	require.NotNil(i)
+	defer i.Stop()
+
+	events := make(chan sd.Event, 10)
+	i.Register(events)
+	defer i.Deregister(events)
+
+	e := <-events
+	assert.Error(e.Err)
+	assert.Empty(e.Instances)
+}
+
+func TestInstancer(t *testing.T) {
+	t.Run("Watch", testInstancerWatch)
+	t.Run("Relist", testInstancerRelist)
+	t.Run("Error", testInstancerError)
+}
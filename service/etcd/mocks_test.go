@@ -0,0 +1,282 @@
+package etcd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// fakeEtcd is an in-memory etcd that serves the subset of the v3 JSON gateway used by this package
+type fakeEtcd struct {
+	*httptest.Server
+
+	lock     sync.Mutex
+	revision int64
+	nextID   int64
+	leases   map[int64]bool
+	kvs      map[string]fakeValue
+	history  []fakeEvent
+	watchers map[chan fakeEvent]string
+	compact  bool
+}
+
+// fakeEvent is a watch event along with the revision at which it happened
+type fakeEvent struct {
+	watchEvent
+	revision int64
+}
+
+type fakeValue struct {
+	value string
+	lease int64
+}
+
+func newFakeEtcd() *fakeEtcd {
+	fe := &fakeEtcd{
+		revision: 1,
+		leases:   make(map[int64]bool),
+		kvs:      make(map[string]fakeValue),
+		watchers: make(map[chan fakeEvent]string),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v3/lease/grant", fe.grant)
+	mux.HandleFunc("/v3/lease/keepalive", fe.keepAlive)
+	mux.HandleFunc("/v3/lease/revoke", fe.revoke)
+	mux.HandleFunc("/v3/kv/put", fe.put)
+	mux.HandleFunc("/v3/kv/range", fe.rangeKeys)
+	mux.HandleFunc("/v3/watch", fe.watch)
+	fe.Server = httptest.NewServer(mux)
+	return fe
+}
+
+func decodeRequest(request *http.Request, v interface{}) {
+	if err := json.NewDecoder(request.Body).Decode(v); err != nil {
+		panic(err)
+	}
+}
+
+func writeResponse(response http.ResponseWriter, v interface{}) {
+	response.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(response).Encode(v); err != nil {
+		panic(err)
+	}
+}
+
+// notify records an event at the current revision and sends it to each watcher of the key.  The lock must be held.
+func (fe *fakeEtcd) notify(event watchEvent) {
+	fe.history = append(fe.history, fakeEvent{watchEvent: event, revision: fe.revision})
+	for events, prefix := range fe.watchers {
+		if strings.HasPrefix(string(event.KV.Key), prefix) {
+			events <- fe.history[len(fe.history)-1]
+		}
+	}
+}
+
+// expire removes a lease and its keys, as etcd does when a lease's TTL elapses
+func (fe *fakeEtcd) expire(lease int64) {
+	fe.lock.Lock()
+	defer fe.lock.Unlock()
+	fe.deleteLease(lease)
+}
+
+// deleteLease removes a lease and its keys.  The lock must be held.
+func (fe *fakeEtcd) deleteLease(lease int64) {
+	delete(fe.leases, lease)
+	for k, v := range fe.kvs {
+		if v.lease == lease {
+			fe.revision++
+			delete(fe.kvs, k)
+			fe.notify(watchEvent{Type: "DELETE", KV: keyValue{Key: bytesString(k)}})
+		}
+	}
+}
+
+// setCompact causes subsequent watches to be canceled as compacted
+func (fe *fakeEtcd) setCompact(compact bool) {
+	fe.lock.Lock()
+	fe.compact = compact
+	fe.lock.Unlock()
+}
+
+// leaseCount returns the number of active leases
+func (fe *fakeEtcd) leaseCount() int {
+	fe.lock.Lock()
+	defer fe.lock.Unlock()
+	return len(fe.leases)
+}
+
+// value returns the value of a key
+func (fe *fakeEtcd) value(key string) (string, bool) {
+	fe.lock.Lock()
+	defer fe.lock.Unlock()
+	v, ok := fe.kvs[key]
+	return v.value, ok
+}
+
+// set writes a key without a lease
+func (fe *fakeEtcd) set(key, value string) {
+	fe.lock.Lock()
+	defer fe.lock.Unlock()
+	fe.revision++
+	fe.kvs[key] = fakeValue{value: value}
+	fe.notify(watchEvent{KV: keyValue{Key: bytesString(key), Value: bytesString(value)}})
+}
+
+func (fe *fakeEtcd) grant(response http.ResponseWriter, request *http.Request) {
+	var body struct {
+		TTL int64String `json:"TTL"`
+	}
+
+	decodeRequest(request, &body)
+	fe.lock.Lock()
+	fe.nextID++
+	id := fe.nextID
+	fe.leases[id] = true
+	fe.lock.Unlock()
+
+	writeResponse(response, map[string]interface{}{"ID": int64String(id), "TTL": body.TTL})
+}
+
+func (fe *fakeEtcd) keepAlive(response http.ResponseWriter, request *http.Request) {
+	var body struct {
+		ID int64String `json:"ID"`
+	}
+
+	decodeRequest(request, &body)
+	fe.lock.Lock()
+	active := fe.leases[int64(body.ID)]
+	fe.lock.Unlock()
+
+	result := map[string]interface{}{"ID": body.ID}
+	if active {
+		result["TTL"] = int64String(10)
+	}
+
+	writeResponse(response, map[string]interface{}{"result": result})
+}
+
+func (fe *fakeEtcd) revoke(response http.ResponseWriter, request *http.Request) {
+	var body struct {
+		ID int64String `json:"ID"`
+	}
+
+	decodeRequest(request, &body)
+	fe.lock.Lock()
+	defer fe.lock.Unlock()
+	if !fe.leases[int64(body.ID)] {
+		response.WriteHeader(http.StatusNotFound)
+		writeResponse(response, map[string]interface{}{"error": "etcdserver: requested lease not found"})
+		return
+	}
+
+	fe.deleteLease(int64(body.ID))
+	writeResponse(response, map[string]interface{}{})
+}
+
+func (fe *fakeEtcd) put(response http.ResponseWriter, request *http.Request) {
+	var body struct {
+		Key   bytesString `json:"key"`
+		Value bytesString `json:"value"`
+		Lease int64String `json:"lease"`
+	}
+
+	decodeRequest(request, &body)
+	fe.lock.Lock()
+	defer fe.lock.Unlock()
+	if body.Lease != 0 && !fe.leases[int64(body.Lease)] {
+		response.WriteHeader(http.StatusNotFound)
+		writeResponse(response, map[string]interface{}{"error": "etcdserver: requested lease not found"})
+		return
+	}
+
+	fe.revision++
+	fe.kvs[string(body.Key)] = fakeValue{value: string(body.Value), lease: int64(body.Lease)}
+	fe.notify(watchEvent{KV: keyValue{Key: body.Key, Value: body.Value}})
+	writeResponse(response, map[string]interface{}{})
+}
+
+func (fe *fakeEtcd) rangeKeys(response http.ResponseWriter, request *http.Request) {
+	var body struct {
+		Key      bytesString `json:"key"`
+		RangeEnd bytesString `json:"range_end"`
+	}
+
+	decodeRequest(request, &body)
+	fe.lock.Lock()
+	defer fe.lock.Unlock()
+
+	kvs := []keyValue{}
+	for k, v := range fe.kvs {
+		if k >= string(body.Key) && k < string(body.RangeEnd) {
+			kvs = append(kvs, keyValue{Key: bytesString(k), Value: bytesString(v.value)})
+		}
+	}
+
+	writeResponse(response, map[string]interface{}{
+		"header": responseHeader{Revision: int64String(fe.revision)},
+		"kvs":    kvs,
+	})
+}
+
+func (fe *fakeEtcd) watch(response http.ResponseWriter, request *http.Request) {
+	var body struct {
+		CreateRequest struct {
+			Key           bytesString `json:"key"`
+			StartRevision int64String `json:"start_revision"`
+		} `json:"create_request"`
+	}
+
+	decodeRequest(request, &body)
+	events := make(chan fakeEvent, 100)
+	prefix := string(body.CreateRequest.Key)
+
+	fe.lock.Lock()
+	compact := fe.compact
+	if !compact {
+		// replay any events since the start revision, as etcd does
+		for _, event := range fe.history {
+			if event.revision >= int64(body.CreateRequest.StartRevision) && strings.HasPrefix(string(event.KV.Key), prefix) {
+				events <- event
+			}
+		}
+
+		fe.watchers[events] = prefix
+	}
+
+	fe.lock.Unlock()
+
+	defer func() {
+		fe.lock.Lock()
+		delete(fe.watchers, events)
+		fe.lock.Unlock()
+	}()
+
+	response.Header().Set("Content-Type", "application/json")
+	if compact {
+		writeResponse(response, map[string]interface{}{"result": map[string]interface{}{"canceled": true, "compact_revision": "1"}})
+		return
+	}
+
+	writeResponse(response, map[string]interface{}{"result": map[string]interface{}{"created": true}})
+	response.(http.Flusher).Flush()
+
+	for {
+		select {
+		case event := <-events:
+			writeResponse(response, map[string]interface{}{
+				"result": map[string]interface{}{
+					"header": responseHeader{Revision: int64String(event.revision)},
+					"events": []watchEvent{event.watchEvent},
+				},
+			})
+
+			response.(http.Flusher).Flush()
+
+		case <-request.Context().Done():
+			return
+		}
+	}
+}
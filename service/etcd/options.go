@@ -0,0 +1,147 @@
+package etcd
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	DefaultEndpoint       = "http://localhost:2379"
+	DefaultPrefix         = "/xmidt/test/"
+	DefaultAddress        = "localhost"
+	DefaultPort           = 8080
+	DefaultScheme         = "http"
+	DefaultTTL            = 10 * time.Second
+	DefaultRequestTimeout = 5 * time.Second
+)
+
+type Registration struct {
+	// Prefix is the key prefix under which to register.  The instance is appended to this prefix to form
+	// the key.  If not supplied, DefaultPrefix is used.
+	Prefix string `json:"prefix,omitempty"`
+
+	// Address is the FQDN or hostname of the server which hosts the service.  If not supplied, DefaultAddress is used.
+	Address string `json:"address,omitempty"`
+
+	// Port is the TCP port on which the service listens.  If not supplied, DefaultPort is used.
+	Port int `json:"port,omitempty"`
+
+	// Scheme specifies the protocol used for the service.  If not supplied, DefaultScheme is used.
+	Scheme string `json:"scheme,omitempty"`
+
+	// TTL is the time to live of the lease attached to the registration's key.  The lease is kept alive
+	// while the registration is active, so the key disappears within this time if the process dies.
+	// If not supplied, DefaultTTL is used.
+	TTL time.Duration `json:"ttl,omitempty"`
+}
+
+func (r Registration) prefix() string {
+	if len(r.Prefix) > 0 {
+		return r.Prefix
+	}
+
+	return DefaultPrefix
+}
+
+func (r Registration) address() string {
+	if len(r.Address) > 0 {
+		return r.Address
+	}
+
+	return DefaultAddress
+}
+
+func (r Registration) port() int {
+	if r.Port > 0 {
+		return r.Port
+	}
+
+	return DefaultPort
+}
+
+func (r Registration) scheme() string {
+	if len(r.Scheme) > 0 {
+		return r.Scheme
+	}
+
+	return DefaultScheme
+}
+
+func (r Registration) ttl() time.Duration {
+	if r.TTL >= time.Second {
+		return r.TTL
+	}
+
+	return DefaultTTL
+}
+
+// Client is the client portion of the options struct
+type Client struct {
+	// Endpoints are the URLs of the etcd cluster members, e.g. http://etcd-1:2379.  Requests fail
+	// over to the next endpoint when one is unreachable.  If not supplied, DefaultEndpoint is used.
+	Endpoints []string `json:"endpoints,omitempty"`
+
+	// RequestTimeout is the timeout for each etcd request, other than watches.
+	RequestTimeout time.Duration `json:"requestTimeout"`
+}
+
+func (c *Client) endpoints() []string {
+	var endpoints []string
+	if c != nil {
+		for _, e := range c.Endpoints {
+			endpoints = append(endpoints, strings.TrimSuffix(e, "/"))
+		}
+	}
+
+	if len(endpoints) == 0 {
+		endpoints = append(endpoints, DefaultEndpoint)
+	}
+
+	return endpoints
+}
+
+func (c *Client) requestTimeout() time.Duration {
+	if c != nil && c.RequestTimeout > 0 {
+		return c.RequestTimeout
+	}
+
+	return DefaultRequestTimeout
+}
+
+// Options represents the set of configurable attributes for etcd
+type Options struct {
+	// Client holds the etcd client options
+	Client Client `json:"client"`
+
+	// Registrations are the ways in which the host process should be registered with etcd.
+	// There is no default for this field.
+	Registrations []Registration `json:"registrations,omitempty"`
+
+	// Watches are the etcd key prefixes to watch for updates.  The value of each key under a prefix
+	// is an instance.  There is no default for this field.
+	Watches []string `json:"watches,omitempty"`
+}
+
+func (o *Options) client() *Client {
+	if o != nil {
+		return &o.Client
+	}
+
+	return nil
+}
+
+func (o *Options) registrations() []Registration {
+	if o != nil && len(o.Registrations) > 0 {
+		return o.Registrations
+	}
+
+	return nil
+}
+
+func (o *Options) watches() []string {
+	if o != nil && len(o.Watches) > 0 {
+		return o.Watches
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package etcd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testRegistrationDefault(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		r      Registration
+	)
+
+	assert.Equal(DefaultPrefix, r.prefix())
+	assert.Equal(DefaultAddress, r.address())
+	assert.Equal(DefaultPort, r.port())
+	assert.Equal(DefaultScheme, r.scheme())
+	assert.Equal(DefaultTTL, r.ttl())
+
+	// a TTL less than the granularity of etcd leases isn't usable
+	r.TTL = 500 * time.Millisecond
+	assert.Equal(DefaultTTL, r.ttl())
+}
+
+func testRegistrationCustom(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		r      = Registration{
+			Prefix:  "/xmidt/talaria/",
+			Address: "talaria-1.xmidt.net",
+			Port:    1234,
+			Scheme:  "https",
+			TTL:     30 * time.Second,
+		}
+	)
+
+	assert.Equal("/xmidt/talaria/", r.prefix())
+	assert.Equal("talaria-1.xmidt.net", r.address())
+	assert.Equal(1234, r.port())
+	assert.Equal("https", r.scheme())
+	assert.Equal(30*time.Second, r.ttl())
+}
+
+func TestRegistration(t *testing.T) {
+	t.Run("Default", testRegistrationDefault)
+	t.Run("Custom", testRegistrationCustom)
+}
+
+func testClientDefault(t *testing.T, c *Client) {
+	assert := assert.New(t)
+	assert.Equal([]string{DefaultEndpoint}, c.endpoints())
+	assert.Equal(DefaultRequestTimeout, c.requestTimeout())
+}
+
+func testClientCustom(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		c      = Client{
+			Endpoints:      []string{"http://etcd-1:2379/", "http://etcd-2:2379"},
+			RequestTimeout: 2 * time.Second,
+		}
+	)
+
+	assert.Equal([]string{"http://etcd-1:2379", "http://etcd-2:2379"}, c.endpoints())
+	assert.Equal(2*time.Second, c.requestTimeout())
+}
+
+func TestClient(t *testing.T) {
+	t.Run("Default", func(t *testing.T) {
+		testClientDefault(t, nil)
+		testClientDefault(t, new(Client))
+	})
+
+	t.Run("Custom", testClientCustom)
+}
+
+func testOptionsDefault(t *testing.T, o *Options) {
+	assert := assert.New(t)
+	assert.Equal([]string{DefaultEndpoint}, o.client().endpoints())
+	assert.Len(o.registrations(), 0)
+	assert.Len(o.watches(), 0)
+}
+
+func testOptionsCustom(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		o      = Options{
+			Client:        Client{Endpoints: []string{"http://etcd:2379"}},
+			Registrations: []Registration{{Address: "talaria-1"}},
+			Watches:       []string{"/xmidt/talaria/"},
+		}
+	)
+
+	assert.Equal([]string{"http://etcd:2379"}, o.client().endpoints())
+	assert.Equal([]Registration{{Address: "talaria-1"}}, o.registrations())
+	assert.Equal([]string{"/xmidt/talaria/"}, o.watches())
+}
+
+func TestOptions(t *testing.T) {
+	t.Run("Default", func(t *testing.T) {
+		testOptionsDefault(t, nil)
+		testOptionsDefault(t, new(Options))
+	})
+
+	t.Run("Custom", testOptionsCustom)
+}
@@ -0,0 +1,121 @@
+package etcd
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+// registrar is a go-kit sd.Registrar that registers an instance under a key attached to an etcd lease.
+// The lease is kept alive while the instance is registered, and revoked on deregistration.
+type registrar struct {
+	client *client
+	logger log.Logger
+	key    string
+	value  string
+	ttl    time.Duration
+
+	lock  sync.Mutex
+	lease int64String
+	stop  chan struct{}
+}
+
+func newRegistrar(c *client, l log.Logger, key, value string, ttl time.Duration) *registrar {
+	return &registrar{
+		client: c,
+		logger: l,
+		key:    key,
+		value:  value,
+		ttl:    ttl,
+	}
+}
+
+// register grants a new lease and attaches the key to it
+func (r *registrar) register() (int64String, error) {
+	lease, err := r.client.grant(r.ttl)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := r.client.put(r.key, r.value, lease); err != nil {
+		r.client.revoke(lease)
+		return 0, err
+	}
+
+	return lease, nil
+}
+
+func (r *registrar) Register() {
+	defer r.lock.Unlock()
+	r.lock.Lock()
+
+	if r.stop != nil {
+		return
+	}
+
+	lease, err := r.register()
+	if err != nil {
+		r.logger.Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "unable to register", logging.ErrorKey(), err)
+		return
+	}
+
+	r.logger.Log(level.Key(), level.InfoValue(), logging.MessageKey(), "registered", "lease", int64(lease))
+	r.lease = lease
+	r.stop = make(chan struct{})
+	go r.keepAlive(r.stop)
+}
+
+// keepAlive refreshes the lease at a third of its TTL.  If the lease expires anyway, e.g. due to a
+// network partition, the key is registered again with a new lease.
+func (r *registrar) keepAlive(stop <-chan struct{}) {
+	ticker := time.NewTicker(r.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+
+		case <-ticker.C:
+			r.lock.Lock()
+			if r.stop != stop {
+				r.lock.Unlock()
+				return
+			}
+
+			err := r.client.keepAlive(r.lease)
+			if err == errLeaseExpired {
+				r.logger.Log(level.Key(), level.WarnValue(), logging.MessageKey(), "lease expired, registering again")
+				var lease int64String
+				if lease, err = r.register(); err == nil {
+					r.lease = lease
+				}
+			}
+
+			r.lock.Unlock()
+			if err != nil {
+				r.logger.Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "unable to keep lease alive", logging.ErrorKey(), err)
+			}
+		}
+	}
+}
+
+func (r *registrar) Deregister() {
+	defer r.lock.Unlock()
+	r.lock.Lock()
+
+	if r.stop == nil {
+		return
+	}
+
+	close(r.stop)
+	r.stop = nil
+
+	// revoking the lease deletes the key
+	if err := r.client.revoke(r.lease); err != nil {
+		r.logger.Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "unable to deregister", logging.ErrorKey(), err)
+	}
+}
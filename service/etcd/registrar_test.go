@@ -0,0 +1,109 @@
+package etcd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+func testRegistrarRegisterDeregister(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		server = newFakeEtcd()
+		r      = newRegistrar(
+			newClient(&Client{Endpoints: []string{server.URL}}),
+			logging.NewTestLogger(nil, t),
+			"/xmidt/talaria/http://talaria-1:8080",
+			"http://talaria-1:8080",
+			time.Minute,
+		)
+	)
+
+	defer server.Close()
+
+	r.Register()
+	value, ok := server.value("/xmidt/talaria/http://talaria-1:8080")
+	assert.True(ok)
+	assert.Equal("http://talaria-1:8080", value)
+	assert.Equal(1, server.leaseCount())
+
+	// registering again is idempotent
+	r.Register()
+	assert.Equal(1, server.leaseCount())
+
+	r.Deregister()
+	_, ok = server.value("/xmidt/talaria/http://talaria-1:8080")
+	assert.False(ok)
+	assert.Zero(server.leaseCount())
+
+	// deregistering again is idempotent
+	r.Deregister()
+}
+
+func testRegistrarRegisterError(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		r      = newRegistrar(
+			newClient(&Client{Endpoints: []string{"http://127.0.0.1:1"}, RequestTimeout: time.Second}),
+			logging.NewTestLogger(nil, t),
+			"/xmidt/talaria/http://talaria-1:8080",
+			"http://talaria-1:8080",
+			time.Minute,
+		)
+	)
+
+	r.Register()
+	assert.Nil(r.stop)
+
+	// deregistering when not registered does nothing
+	r.Deregister()
+}
+
+func testRegistrarLeaseExpired(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		server = newFakeEtcd()
+		r      = newRegistrar(
+			newClient(&Client{Endpoints: []string{server.URL}}),
+			logging.NewTestLogger(nil, t),
+			"/xmidt/talaria/http://talaria-1:8080",
+			"http://talaria-1:8080",
+			30*time.Millisecond,
+		)
+	)
+
+	defer server.Close()
+
+	r.Register()
+	defer r.Deregister()
+
+	r.lock.Lock()
+	lease := r.lease
+	r.lock.Unlock()
+
+	server.expire(int64(lease))
+	_, ok := server.value("/xmidt/talaria/http://talaria-1:8080")
+	assert.False(ok)
+
+	// the keepalive goroutine should notice the expired lease and register again
+	assert.Eventually(
+		func() bool {
+			_, ok := server.value("/xmidt/talaria/http://talaria-1:8080")
+			return ok
+		},
+		5*time.Second,
+		10*time.Millisecond,
+	)
+
+	r.lock.Lock()
+	assert.NotEqual(lease, r.lease)
+	r.lock.Unlock()
+}
+
+func TestRegistrar(t *testing.T) {
+	t.Run("RegisterDeregister", testRegistrarRegisterDeregister)
+	t.Run("RegisterError", testRegistrarRegisterError)
+	t.Run("LeaseExpired", testRegistrarLeaseExpired)
+}
@@ -0,0 +1,169 @@
+package kubernetes
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const serviceNameLabel = "kubernetes.io/service-name"
+
+var (
+	errNoCertificates  = errors.New("No certificates could be loaded from the CA file")
+	errResourceExpired = errors.New("The watched resource version has expired")
+)
+
+// endpointSlice is the subset of a discovery.k8s.io/v1 EndpointSlice used for service discovery
+type endpointSlice struct {
+	Metadata struct {
+		Name            string `json:"name"`
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+
+	Ports []struct {
+		Name *string `json:"name"`
+		Port *int    `json:"port"`
+	} `json:"ports"`
+
+	Endpoints []struct {
+		Addresses  []string `json:"addresses"`
+		Conditions struct {
+			Ready *bool `json:"ready"`
+		} `json:"conditions"`
+	} `json:"endpoints"`
+}
+
+type endpointSliceList struct {
+	Metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+
+	Items []endpointSlice `json:"items"`
+}
+
+// watchEvent is a single event from a kubernetes watch stream
+type watchEvent struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+// status is a kubernetes Status object, returned in error events
+type status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// client is a minimal kubernetes API client for EndpointSlices
+type client struct {
+	server     string
+	tokenFile  string
+	httpClient *http.Client
+}
+
+func newClient(o Options) (*client, error) {
+	c := &client{
+		server:     strings.TrimSuffix(o.server(), "/"),
+		tokenFile:  o.tokenFile(),
+		httpClient: new(http.Client),
+	}
+
+	if caFile := o.caFile(); len(caFile) > 0 {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, errNoCertificates
+		}
+
+		c.httpClient.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{RootCAs: roots},
+		}
+	}
+
+	return c, nil
+}
+
+func (c *client) get(ctx context.Context, namespace, service string, query url.Values) (*http.Response, error) {
+	query.Set("labelSelector", serviceNameLabel+"="+service)
+	request, err := http.NewRequest(
+		"GET",
+		fmt.Sprintf("%s/apis/discovery.k8s.io/v1/namespaces/%s/endpointslices?%s", c.server, url.PathEscape(namespace), query.Encode()),
+		nil,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	request = request.WithContext(ctx)
+	request.Header.Set("Accept", "application/json")
+	if len(c.tokenFile) > 0 {
+		token, err := ioutil.ReadFile(c.tokenFile)
+		if err != nil {
+			return nil, err
+		}
+
+		request.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		io.Copy(ioutil.Discard, response.Body)
+		response.Body.Close()
+		if response.StatusCode == http.StatusGone {
+			return nil, errResourceExpired
+		}
+
+		return nil, fmt.Errorf("Unexpected status from the kubernetes API server: %d", response.StatusCode)
+	}
+
+	return response, nil
+}
+
+// list returns the current EndpointSlices for a service
+func (c *client) list(ctx context.Context, namespace, service string) (*endpointSliceList, error) {
+	response, err := c.get(ctx, namespace, service, url.Values{})
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+	list := new(endpointSliceList)
+	if err := json.NewDecoder(response.Body).Decode(list); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// watch streams changes to a service's EndpointSlices, starting after the given resource version.
+// The caller must close the returned stream.
+func (c *client) watch(ctx context.Context, namespace, service, resourceVersion string) (io.ReadCloser, error) {
+	response, err := c.get(ctx, namespace, service, url.Values{
+		"watch":               {"true"},
+		"resourceVersion":     {resourceVersion},
+		"allowWatchBookmarks": {"false"},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Body, nil
+}
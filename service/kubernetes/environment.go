@@ -0,0 +1,75 @@
+package kubernetes
+
+import (
+	"fmt"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/service"
+)
+
+func newInstancerKey(namespace string, w Watch) string {
+	return fmt.Sprintf(
+		"%s/%s{portName=%s}{includeNotReady=%t}",
+		namespace,
+		w.Service,
+		w.PortName,
+		w.IncludeNotReady,
+	)
+}
+
+func newInstancers(l log.Logger, c *client, ko Options) (i service.Instancers) {
+	defaultNamespace := ko.namespace()
+	for _, w := range ko.watches() {
+		namespace := w.Namespace
+		if len(namespace) == 0 {
+			namespace = defaultNamespace
+		}
+
+		key := newInstancerKey(namespace, w)
+		if i.Has(key) {
+			l.Log(level.Key(), level.WarnValue(), logging.MessageKey(), "skipping duplicate watch", "service", w.Service, "namespace", namespace, "portName", w.PortName)
+			continue
+		}
+
+		i.Set(
+			key,
+			service.NewContextualInstancer(
+				newInstancer(l, c, namespace, w),
+				map[string]interface{}{
+					"service":   w.Service,
+					"namespace": namespace,
+					"portName":  w.PortName,
+				},
+			),
+		)
+	}
+
+	return
+}
+
+// NewEnvironment constructs a service.Environment that discovers instances from kubernetes EndpointSlices.
+// Kubernetes itself registers pods with Services, so the returned Environment has no Registrars.  The service
+// account used requires only list and watch permissions on endpointslices in the discovery.k8s.io API group.
+func NewEnvironment(l log.Logger, ko Options, eo ...service.Option) (service.Environment, error) {
+	if l == nil {
+		l = logging.DefaultLogger()
+	}
+
+	if len(ko.Watches) == 0 {
+		return nil, service.ErrIncomplete
+	}
+
+	c, err := newClient(ko)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.NewEnvironment(
+		append(
+			eo,
+			service.WithInstancers(newInstancers(l, c, ko)),
+		)...,
+	), nil
+}
@@ -0,0 +1,83 @@
+package kubernetes
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/service"
+)
+
+func testNewEnvironmentEmpty(t *testing.T) {
+	assert := assert.New(t)
+	e, err := NewEnvironment(nil, Options{})
+	assert.Nil(e)
+	assert.Equal(service.ErrIncomplete, err)
+}
+
+func testNewEnvironmentBadCAFile(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+	)
+
+	dir, err := ioutil.TempDir("", "kubernetes")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	caFile := filepath.Join(dir, "ca.crt")
+	require.NoError(ioutil.WriteFile(caFile, []byte("this is not a certificate"), 0600))
+
+	e, err := NewEnvironment(nil, Options{Server: "https://localhost:6443", CAFile: caFile, Watches: []Watch{{Service: "talaria"}}})
+	assert.Nil(e)
+	assert.Equal(errNoCertificates, err)
+
+	e, err = NewEnvironment(nil, Options{Server: "https://localhost:6443", CAFile: filepath.Join(dir, "missing"), Watches: []Watch{{Service: "talaria"}}})
+	assert.Nil(e)
+	assert.Error(err)
+}
+
+func testNewEnvironmentFull(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		server = newFakeAPIServer(listJSON("1",
+			sliceJSON("talaria-1", "1", "http", 8080, []string{"10.0.0.1"}, nil),
+		))
+	)
+
+	defer server.Close()
+
+	e, err := NewEnvironment(
+		logging.NewTestLogger(nil, t),
+		Options{
+			Server:    server.URL,
+			Namespace: "xmidt",
+			Watches: []Watch{
+				{Service: "talaria"},
+				{Service: "talaria"}, // duplicates should be ignored
+				{Service: "talaria", Namespace: "edge"},
+			},
+		},
+	)
+
+	require.NoError(err)
+	require.NotNil(e)
+
+	assert.Len(e.Instancers(), 2)
+	assert.True(e.Instancers().Has(newInstancerKey("xmidt", Watch{Service: "talaria"})))
+	assert.True(e.Instancers().Has(newInstancerKey("edge", Watch{Service: "talaria", Namespace: "edge"})))
+
+	assert.NoError(e.Close())
+}
+
+func TestNewEnvironment(t *testing.T) {
+	t.Run("Empty", testNewEnvironmentEmpty)
+	t.Run("BadCAFile", testNewEnvironmentBadCAFile)
+	t.Run("Full", testNewEnvironmentFull)
+}
@@ -0,0 +1,249 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/util/conn"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/service"
+)
+
+// instancer is a go-kit sd.Instancer that lists and then watches the EndpointSlices of a kubernetes Service.
+// Whenever the watch ends, it is resumed from the last observed resource version.  If that version has
+// expired, the EndpointSlices are listed again.
+type instancer struct {
+	client    *client
+	logger    log.Logger
+	namespace string
+	watch     Watch
+
+	ctx    context.Context
+	cancel func()
+
+	// slices is only accessed by the goroutine running loop, once that goroutine starts
+	slices map[string]endpointSlice
+
+	registerLock sync.Mutex
+	state        sd.Event
+	registry     map[chan<- sd.Event]bool
+}
+
+func newInstancer(l log.Logger, c *client, namespace string, w Watch) *instancer {
+	if l == nil {
+		l = logging.DefaultLogger()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	i := &instancer{
+		client:    c,
+		logger:    log.With(l, "service", w.Service, "namespace", namespace, "portName", w.PortName),
+		namespace: namespace,
+		watch:     w,
+		ctx:       ctx,
+		cancel:    cancel,
+		registry:  make(map[chan<- sd.Event]bool),
+	}
+
+	// grab the initial set of instances
+	resourceVersion, err := i.relist()
+	if err == nil {
+		i.logger.Log(level.Key(), level.InfoValue(), "instances", len(i.state.Instances))
+	} else {
+		i.logger.Log(level.Key(), level.ErrorValue(), logging.ErrorKey(), err)
+		i.update(sd.Event{Err: err})
+	}
+
+	go i.loop(resourceVersion)
+	return i
+}
+
+func (i *instancer) update(e sd.Event) {
+	sort.Strings(e.Instances)
+	defer i.registerLock.Unlock()
+	i.registerLock.Lock()
+
+	if reflect.DeepEqual(i.state, e) {
+		return
+	}
+
+	i.state = e
+	for c := range i.registry {
+		c <- i.state
+	}
+}
+
+// relist replaces all the EndpointSlices with the current set, returning the resource version to watch from
+func (i *instancer) relist() (string, error) {
+	list, err := i.client.list(i.ctx, i.namespace, i.watch.Service)
+	if err != nil {
+		return "", err
+	}
+
+	i.slices = make(map[string]endpointSlice, len(list.Items))
+	for _, slice := range list.Items {
+		i.slices[slice.Metadata.Name] = slice
+	}
+
+	i.update(sd.Event{Instances: i.instances()})
+	return list.Metadata.ResourceVersion, nil
+}
+
+func (i *instancer) loop(resourceVersion string) {
+	var (
+		err error
+		d   time.Duration = 10 * time.Millisecond
+	)
+
+	for {
+		if len(resourceVersion) == 0 {
+			resourceVersion, err = i.relist()
+		}
+
+		if err == nil {
+			resourceVersion, err = i.watchChanges(resourceVersion)
+		}
+
+		if i.ctx.Err() != nil {
+			return
+		}
+
+		switch {
+		case err == errResourceExpired:
+			i.logger.Log(level.Key(), level.DebugValue(), logging.MessageKey(), "resource version expired, relisting")
+			d = 10 * time.Millisecond
+
+		case err != nil:
+			i.logger.Log(logging.ErrorKey(), err)
+			i.update(sd.Event{Err: err})
+
+			select {
+			case <-i.ctx.Done():
+				return
+			case <-time.After(d):
+			}
+
+			d = conn.Exponential(d)
+
+		default:
+			d = 10 * time.Millisecond
+		}
+	}
+}
+
+// watchChanges applies changes from a single watch stream until that stream ends.  The last observed
+// resource version is returned, from which the next watch can resume.
+func (i *instancer) watchChanges(resourceVersion string) (string, error) {
+	stream, err := i.client.watch(i.ctx, i.namespace, i.watch.Service, resourceVersion)
+	if err != nil {
+		return "", err
+	}
+
+	defer stream.Close()
+	decoder := json.NewDecoder(stream)
+	for {
+		var event watchEvent
+		if err := decoder.Decode(&event); err == io.EOF {
+			return resourceVersion, nil
+		} else if err != nil {
+			return "", err
+		}
+
+		switch event.Type {
+		case "ADDED", "MODIFIED", "DELETED":
+			var slice endpointSlice
+			if err := json.Unmarshal(event.Object, &slice); err != nil {
+				return "", err
+			}
+
+			if event.Type == "DELETED" {
+				delete(i.slices, slice.Metadata.Name)
+			} else {
+				i.slices[slice.Metadata.Name] = slice
+			}
+
+			resourceVersion = slice.Metadata.ResourceVersion
+			i.update(sd.Event{Instances: i.instances()})
+
+		case "ERROR":
+			var s status
+			if err := json.Unmarshal(event.Object, &s); err != nil {
+				return "", err
+			}
+
+			if s.Code == http.StatusGone {
+				return "", errResourceExpired
+			}
+
+			return "", fmt.Errorf("Error from the kubernetes watch: %d %s", s.Code, s.Message)
+		}
+	}
+}
+
+// instances formats the addresses of all endpoints with the watched port
+func (i *instancer) instances() []string {
+	instances := []string{}
+	for _, slice := range i.slices {
+		port, ok := slicePort(slice, i.watch.PortName)
+		if !ok {
+			continue
+		}
+
+		for _, endpoint := range slice.Endpoints {
+			// per the kubernetes API, a nil ready condition is interpreted as ready
+			if !i.watch.IncludeNotReady && endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+				continue
+			}
+
+			for _, address := range endpoint.Addresses {
+				instances = append(instances, service.FormatInstance(i.watch.scheme(), address, port))
+			}
+		}
+	}
+
+	return instances
+}
+
+// slicePort returns the port with the given name, or the first port if the name is empty
+func slicePort(slice endpointSlice, name string) (int, bool) {
+	for _, p := range slice.Ports {
+		if p.Port == nil {
+			continue
+		}
+
+		if len(name) == 0 || (p.Name != nil && *p.Name == name) {
+			return *p.Port, true
+		}
+	}
+
+	return 0, false
+}
+
+func (i *instancer) Register(ch chan<- sd.Event) {
+	defer i.registerLock.Unlock()
+	i.registerLock.Lock()
+	i.registry[ch] = true
+
+	// push the current state to the new channel
+	ch <- i.state
+}
+
+func (i *instancer) Deregister(ch chan<- sd.Event) {
+	defer i.registerLock.Unlock()
+	i.registerLock.Lock()
+	delete(i.registry, ch)
+}
+
+func (i *instancer) Stop() {
+	i.cancel()
+}
@@ -0,0 +1,209 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/sd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+// nextEvent waits for the next event from an instancer
+func nextEvent(t *testing.T, events <-chan sd.Event) sd.Event {
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "No event from the instancer")
+		return sd.Event{}
+	}
+}
+
+func testInstancerWatch(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		events  = make(chan sd.Event, 10)
+
+		server = newFakeAPIServer(listJSON("1",
+			sliceJSON("talaria-1", "1", "http", 8080, []string{"10.0.0.1", "10.0.0.2"}, []string{"10.0.0.3"}),
+		))
+	)
+
+	defer server.Close()
+	watch := server.newWatch()
+
+	c, err := newClient(Options{Server: server.URL})
+	require.NoError(err)
+
+	i := newInstancer(logging.NewTestLogger(nil, t), c, "xmidt", Watch{Service: "talaria"})
+	defer i.Stop()
+
+	i.Register(events)
+	assert.Equal([]string{"http://10.0.0.1:8080", "http://10.0.0.2:8080"}, nextEvent(t, events).Instances)
+
+	watch <- eventJSON("MODIFIED", sliceJSON("talaria-1", "2", "http", 8080, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}, nil))
+	assert.Equal([]string{"http://10.0.0.1:8080", "http://10.0.0.2:8080", "http://10.0.0.3:8080"}, nextEvent(t, events).Instances)
+
+	watch <- eventJSON("ADDED", sliceJSON("talaria-2", "3", "http", 8080, []string{"10.0.1.1"}, nil))
+	assert.Equal([]string{"http://10.0.0.1:8080", "http://10.0.0.2:8080", "http://10.0.0.3:8080", "http://10.0.1.1:8080"}, nextEvent(t, events).Instances)
+
+	watch <- eventJSON("DELETED", sliceJSON("talaria-1", "4", "http", 8080, nil, nil))
+	assert.Equal([]string{"http://10.0.1.1:8080"}, nextEvent(t, events).Instances)
+
+	// when a watch ends, the next watch resumes from the last resource version
+	next := server.newWatch()
+	close(watch)
+	next <- eventJSON("DELETED", sliceJSON("talaria-2", "5", "http", 8080, nil, nil))
+	assert.Equal([]string{}, nextEvent(t, events).Instances)
+	assert.Equal(1, server.listCount())
+
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	require.Len(server.queries, 3)
+
+	list, err := url.ParseQuery(server.queries[0])
+	require.NoError(err)
+	assert.Equal("kubernetes.io/service-name=talaria", list.Get("labelSelector"))
+
+	first, err := url.ParseQuery(server.queries[1])
+	require.NoError(err)
+	assert.Equal("true", first.Get("watch"))
+	assert.Equal("1", first.Get("resourceVersion"))
+
+	second, err := url.ParseQuery(server.queries[2])
+	require.NoError(err)
+	assert.Equal("4", second.Get("resourceVersion"))
+}
+
+func testInstancerExpired(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		events  = make(chan sd.Event, 10)
+
+		server = newFakeAPIServer(listJSON("1",
+			sliceJSON("talaria-1", "1", "http", 8080, []string{"10.0.0.1"}, nil),
+		))
+	)
+
+	defer server.Close()
+	watch := server.newWatch()
+
+	c, err := newClient(Options{Server: server.URL})
+	require.NoError(err)
+
+	i := newInstancer(logging.NewTestLogger(nil, t), c, "xmidt", Watch{Service: "talaria"})
+	defer i.Stop()
+
+	i.Register(events)
+	assert.Equal([]string{"http://10.0.0.1:8080"}, nextEvent(t, events).Instances)
+
+	server.setList(listJSON("10",
+		sliceJSON("talaria-1", "10", "http", 8080, []string{"10.0.0.2"}, nil),
+	))
+
+	server.newWatch()
+	watch <- eventJSON("ERROR", `{"kind": "Status", "code": 410, "message": "too old resource version"}`)
+
+	e := nextEvent(t, events)
+	assert.NoError(e.Err)
+	assert.Equal([]string{"http://10.0.0.2:8080"}, e.Instances)
+	assert.Equal(2, server.listCount())
+}
+
+func testInstancerListError(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		server  = newFakeAPIServer(`this is not JSON`)
+	)
+
+	defer server.Close()
+
+	c, err := newClient(Options{Server: server.URL})
+	require.NoError(err)
+
+	i := newInstancer(logging.NewTestLogger(nil, t), c, "xmidt", Watch{Service: "talaria"})
+	i.Stop()
+
+	events := make(chan sd.Event, 1)
+	i.Register(events)
+	e := <-events
+	assert.Error(e.Err)
+	assert.Empty(e.Instances)
+}
+
+func testInstancerToken(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		server  = newFakeAPIServer(listJSON("1"))
+	)
+
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "kubernetes")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	tokenFile := filepath.Join(dir, "token")
+	require.NoError(ioutil.WriteFile(tokenFile, []byte("test-token\n"), 0600))
+
+	c, err := newClient(Options{Server: server.URL, TokenFile: tokenFile})
+	require.NoError(err)
+
+	list, err := c.list(context.Background(), "xmidt", "talaria")
+	require.NoError(err)
+	assert.Equal("1", list.Metadata.ResourceVersion)
+	assert.Empty(list.Items)
+
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	assert.Equal([]string{"Bearer test-token"}, server.auth)
+}
+
+func TestInstancer(t *testing.T) {
+	t.Run("Watch", testInstancerWatch)
+	t.Run("Expired", testInstancerExpired)
+	t.Run("ListError", testInstancerListError)
+	t.Run("Token", testInstancerToken)
+}
+
+func TestInstancerInstances(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		i      = &instancer{
+			slices: map[string]endpointSlice{},
+		}
+	)
+
+	for _, data := range []string{
+		sliceJSON("talaria-1", "1", "http", 8080, []string{"10.0.0.1"}, []string{"10.0.0.2"}),
+		sliceJSON("talaria-2", "1", "metrics", 9090, []string{"10.0.0.3"}, nil),
+	} {
+		var slice endpointSlice
+		assert.NoError(json.Unmarshal([]byte(data), &slice))
+		i.slices[slice.Metadata.Name] = slice
+	}
+
+	i.watch = Watch{Service: "talaria", PortName: "http"}
+	assert.Equal([]string{"http://10.0.0.1:8080"}, i.instances())
+
+	i.watch = Watch{Service: "talaria", PortName: "http", IncludeNotReady: true, Scheme: "https"}
+	assert.ElementsMatch([]string{"https://10.0.0.1:8080", "https://10.0.0.2:8080"}, i.instances())
+
+	i.watch = Watch{Service: "talaria", PortName: "missing"}
+	assert.Empty(i.instances())
+
+	i.watch = Watch{Service: "talaria"}
+	assert.ElementsMatch([]string{"http://10.0.0.1:8080", "http://10.0.0.3:9090"}, i.instances())
+}
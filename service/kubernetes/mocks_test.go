@@ -0,0 +1,128 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// fakeAPIServer is a fake kubernetes API server that serves EndpointSlice lists and watches
+type fakeAPIServer struct {
+	*httptest.Server
+
+	lock    sync.Mutex
+	list    string
+	lists   int
+	watches chan chan string
+	queries []string
+	auth    []string
+}
+
+func newFakeAPIServer(list string) *fakeAPIServer {
+	fas := &fakeAPIServer{
+		list:    list,
+		watches: make(chan chan string, 10),
+	}
+
+	fas.Server = httptest.NewServer(http.HandlerFunc(fas.serveHTTP))
+	return fas
+}
+
+func (fas *fakeAPIServer) setList(list string) {
+	fas.lock.Lock()
+	fas.list = list
+	fas.lock.Unlock()
+}
+
+func (fas *fakeAPIServer) listCount() int {
+	fas.lock.Lock()
+	defer fas.lock.Unlock()
+	return fas.lists
+}
+
+// newWatch returns a channel of events for the next watch request.  Closing the channel ends that watch.
+func (fas *fakeAPIServer) newWatch() chan<- string {
+	events := make(chan string, 10)
+	fas.watches <- events
+	return events
+}
+
+func (fas *fakeAPIServer) serveHTTP(response http.ResponseWriter, request *http.Request) {
+	fas.lock.Lock()
+	fas.queries = append(fas.queries, request.URL.RawQuery)
+	fas.auth = append(fas.auth, request.Header.Get("Authorization"))
+	fas.lock.Unlock()
+
+	if request.URL.Query().Get("watch") != "true" {
+		fas.lock.Lock()
+		fas.lists++
+		list := fas.list
+		fas.lock.Unlock()
+
+		response.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(response, list)
+		return
+	}
+
+	var events chan string
+	select {
+	case events = <-fas.watches:
+	case <-request.Context().Done():
+		return
+	}
+
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(http.StatusOK)
+	response.(http.Flusher).Flush()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+
+			fmt.Fprintln(response, event)
+			response.(http.Flusher).Flush()
+
+		case <-request.Context().Done():
+			return
+		}
+	}
+}
+
+// sliceJSON produces the JSON for an EndpointSlice with a single port and the given ready and not ready addresses
+func sliceJSON(name, resourceVersion, portName string, port int, ready []string, notReady []string) string {
+	type endpoint map[string]interface{}
+	var endpoints []endpoint
+	for _, a := range ready {
+		endpoints = append(endpoints, endpoint{"addresses": []string{a}, "conditions": map[string]bool{"ready": true}})
+	}
+
+	for _, a := range notReady {
+		endpoints = append(endpoints, endpoint{"addresses": []string{a}, "conditions": map[string]bool{"ready": false}})
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"metadata":  map[string]string{"name": name, "resourceVersion": resourceVersion},
+		"ports":     []map[string]interface{}{{"name": portName, "port": port}},
+		"endpoints": endpoints,
+	})
+
+	if err != nil {
+		panic(err)
+	}
+
+	return string(data)
+}
+
+func listJSON(resourceVersion string, slices ...string) string {
+	return fmt.Sprintf(`{"metadata": {"resourceVersion": "%s"}, "items": [%s]}`, resourceVersion, strings.Join(slices, ","))
+}
+
+func eventJSON(eventType, object string) string {
+	return fmt.Sprintf(`{"type": "%s", "object": %s}`, eventType, object)
+}
@@ -0,0 +1,124 @@
+package kubernetes
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+)
+
+const (
+	DefaultTokenFile     = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	DefaultCAFile        = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+	DefaultNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+	DefaultNamespace     = "default"
+	DefaultScheme        = "http"
+)
+
+// Watch describes a kubernetes Service whose endpoints are to be discovered
+type Watch struct {
+	// Service is the name of the kubernetes Service.  This field is required.
+	Service string `json:"service"`
+
+	// Namespace is the namespace of the Service.  If not supplied, Options.Namespace is used.
+	Namespace string `json:"namespace,omitempty"`
+
+	// PortName selects the named port of the Service's endpoints.  If not supplied, the first port is used.
+	PortName string `json:"portName,omitempty"`
+
+	// Scheme is the scheme used to format instances.  If not supplied, DefaultScheme is used.
+	Scheme string `json:"scheme,omitempty"`
+
+	// IncludeNotReady includes endpoints that are not ready, e.g. pods failing their readiness probes
+	IncludeNotReady bool `json:"includeNotReady"`
+}
+
+func (w Watch) scheme() string {
+	if len(w.Scheme) > 0 {
+		return w.Scheme
+	}
+
+	return DefaultScheme
+}
+
+// Options describes the configuration for kubernetes service discovery.  The zero value configures
+// in-cluster discovery, using the pod's service account.
+type Options struct {
+	// Server is the URL of the kubernetes API server.  If not supplied, the in-cluster server given by the
+	// KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT environment variables is used.
+	Server string `json:"server,omitempty"`
+
+	// Namespace is the default namespace for watches.  If not supplied, the pod's namespace is used, or
+	// DefaultNamespace if that is not available.
+	Namespace string `json:"namespace,omitempty"`
+
+	// TokenFile is the bearer token used to authenticate with the API server.  The file is reread for each
+	// request, so that rotated tokens are used.  If not supplied and Server is unset, DefaultTokenFile is used.
+	TokenFile string `json:"tokenFile,omitempty"`
+
+	// CAFile is the PEM certificate bundle used to verify the API server.  If not supplied and Server is unset,
+	// DefaultCAFile is used.
+	CAFile string `json:"caFile,omitempty"`
+
+	Watches []Watch `json:"watches,omitempty"`
+}
+
+func (o *Options) server() string {
+	if o != nil && len(o.Server) > 0 {
+		return o.Server
+	}
+
+	return "https://" + net.JoinHostPort(os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT"))
+}
+
+func (o *Options) inCluster() bool {
+	return o == nil || len(o.Server) == 0
+}
+
+func (o *Options) namespace() string {
+	if o != nil && len(o.Namespace) > 0 {
+		return o.Namespace
+	}
+
+	if o.inCluster() {
+		if data, err := ioutil.ReadFile(DefaultNamespaceFile); err == nil {
+			if namespace := strings.TrimSpace(string(data)); len(namespace) > 0 {
+				return namespace
+			}
+		}
+	}
+
+	return DefaultNamespace
+}
+
+func (o *Options) tokenFile() string {
+	if o != nil && len(o.TokenFile) > 0 {
+		return o.TokenFile
+	}
+
+	if o.inCluster() {
+		return DefaultTokenFile
+	}
+
+	return ""
+}
+
+func (o *Options) caFile() string {
+	if o != nil && len(o.CAFile) > 0 {
+		return o.CAFile
+	}
+
+	if o.inCluster() {
+		return DefaultCAFile
+	}
+
+	return ""
+}
+
+func (o *Options) watches() []Watch {
+	if o != nil && len(o.Watches) > 0 {
+		return o.Watches
+	}
+
+	return nil
+}
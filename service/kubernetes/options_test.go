@@ -0,0 +1,66 @@
+package kubernetes
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testOptionsDefault(t *testing.T, o *Options) {
+	assert := assert.New(t)
+
+	os.Setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")
+	os.Setenv("KUBERNETES_SERVICE_PORT", "443")
+	defer os.Unsetenv("KUBERNETES_SERVICE_HOST")
+	defer os.Unsetenv("KUBERNETES_SERVICE_PORT")
+
+	assert.Equal("https://10.0.0.1:443", o.server())
+	assert.True(o.inCluster())
+	assert.NotEmpty(o.namespace())
+	assert.Equal(DefaultTokenFile, o.tokenFile())
+	assert.Equal(DefaultCAFile, o.caFile())
+	assert.Len(o.watches(), 0)
+}
+
+func testOptionsCustom(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		o      = Options{
+			Server:    "http://localhost:8001",
+			Namespace: "xmidt",
+			Watches: []Watch{
+				{Service: "talaria"},
+			},
+		}
+	)
+
+	assert.Equal("http://localhost:8001", o.server())
+	assert.False(o.inCluster())
+	assert.Equal("xmidt", o.namespace())
+	assert.Empty(o.tokenFile())
+	assert.Empty(o.caFile())
+	assert.Equal([]Watch{{Service: "talaria"}}, o.watches())
+
+	o.Namespace = ""
+	o.TokenFile = "/etc/token"
+	o.CAFile = "/etc/ca.crt"
+	assert.Equal(DefaultNamespace, o.namespace())
+	assert.Equal("/etc/token", o.tokenFile())
+	assert.Equal("/etc/ca.crt", o.caFile())
+}
+
+func TestOptions(t *testing.T) {
+	t.Run("Default", func(t *testing.T) {
+		testOptionsDefault(t, nil)
+		testOptionsDefault(t, new(Options))
+	})
+
+	t.Run("Custom", testOptionsCustom)
+}
+
+func TestWatchScheme(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(DefaultScheme, Watch{}.scheme())
+	assert.Equal("https", Watch{Scheme: "https"}.scheme())
+}
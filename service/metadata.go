@@ -0,0 +1,153 @@
+package service
+
+import (
+	"strings"
+	"sync"
+)
+
+// Metadata is the information a service discovery backend holds about a discovered instance, beyond its address.
+// Backends fill in what they support, and leave the remaining fields at their zero values.
+type Metadata struct {
+	// Tags are the tags the instance was registered with
+	Tags []string
+
+	// Meta is the key/value metadata the instance was registered with
+	Meta map[string]string
+
+	// NodeMeta is the key/value metadata of the node hosting the instance
+	NodeMeta map[string]string
+
+	// Weight is the instance's relative weight, based on its current health.  Zero means the backend has no weights.
+	Weight int
+
+	// Datacenter is the datacenter in which the instance was discovered
+	Datacenter string
+}
+
+// metadataKey produces the key used to store an instance's metadata.  Any scheme and trailing slash are removed,
+// so that an instance can be looked up both before and after it has been normalized.
+func metadataKey(instance string) string {
+	if p := strings.Index(instance, "://"); p >= 0 {
+		instance = instance[p+3:]
+	}
+
+	return strings.TrimSuffix(instance, "/")
+}
+
+// MetadataStore holds the Metadata of discovered instances, as reported by one or more sources, e.g. instancers.
+// It is safe for concurrent use.  The zero value is not usable; use NewMetadataStore.
+type MetadataStore struct {
+	lock    sync.RWMutex
+	sources map[interface{}]map[string]Metadata
+}
+
+// NewMetadataStore creates an empty MetadataStore
+func NewMetadataStore() *MetadataStore {
+	return &MetadataStore{
+		sources: make(map[interface{}]map[string]Metadata),
+	}
+}
+
+// Set replaces the metadata reported by a source, which may be any comparable value.  The given map is keyed
+// by instance, and must not be modified afterward.  Setting an empty map removes the source.
+func (ms *MetadataStore) Set(source interface{}, metadata map[string]Metadata) {
+	defer ms.lock.Unlock()
+	ms.lock.Lock()
+
+	if len(metadata) == 0 {
+		delete(ms.sources, source)
+		return
+	}
+
+	keyed := make(map[string]Metadata, len(metadata))
+	for instance, m := range metadata {
+		keyed[metadataKey(instance)] = m
+	}
+
+	ms.sources[source] = keyed
+}
+
+// Get returns the metadata for an instance.  The instance may be given with or without a scheme.  If more than one
+// source reports the same instance, the metadata from any one of them is returned.
+func (ms *MetadataStore) Get(instance string) (Metadata, bool) {
+	key := metadataKey(instance)
+
+	defer ms.lock.RUnlock()
+	ms.lock.RLock()
+
+	for _, metadata := range ms.sources {
+		if m, ok := metadata[key]; ok {
+			return m, true
+		}
+	}
+
+	return Metadata{}, false
+}
+
+// Len returns the number of distinct instances with metadata
+func (ms *MetadataStore) Len() int {
+	defer ms.lock.RUnlock()
+	ms.lock.RLock()
+
+	instances := make(map[string]bool)
+	for _, metadata := range ms.sources {
+		for key := range metadata {
+			instances[key] = true
+		}
+	}
+
+	return len(instances)
+}
+
+// MetadataAccessor is an Accessor that can also return the Metadata of the instance it selects, so that
+// callers can make per-instance decisions, e.g. which protocol to use, without querying service discovery again.
+type MetadataAccessor interface {
+	Accessor
+
+	// GetWithMetadata returns the same instance as Get, along with its Metadata.  If there is no known Metadata
+	// for the instance, the zero Metadata is returned and ok is false.
+	GetWithMetadata(key []byte) (instance string, m Metadata, ok bool, err error)
+}
+
+type metadataAccessor struct {
+	Accessor
+	store *MetadataStore
+}
+
+func (ma metadataAccessor) GetWithMetadata(key []byte) (string, Metadata, bool, error) {
+	instance, err := ma.Get(key)
+	if err != nil {
+		return instance, Metadata{}, false, err
+	}
+
+	m, ok := ma.store.Get(instance)
+	return instance, m, ok, nil
+}
+
+// Release releases the key from the decorated Accessor, if it supports releasing keys as BoundedAccessor does
+func (ma metadataAccessor) Release(key []byte) {
+	if r, ok := ma.Accessor.(interface{ Release([]byte) }); ok {
+		r.Release(key)
+	}
+}
+
+// NewMetadataAccessor decorates an Accessor so that it also returns instance Metadata from the given store.
+func NewMetadataAccessor(a Accessor, store *MetadataStore) MetadataAccessor {
+	if store == nil {
+		store = NewMetadataStore()
+	}
+
+	return metadataAccessor{Accessor: a, store: store}
+}
+
+// NewMetadataAccessorFactory decorates an AccessorFactory so that each Accessor it creates is a MetadataAccessor
+// backed by the given store.  If af is nil, DefaultAccessorFactory is used.
+func NewMetadataAccessorFactory(af AccessorFactory, store *MetadataStore) AccessorFactory {
+	if af == nil {
+		af = DefaultAccessorFactory
+	}
+
+	return func(instances []string) Accessor {
+		return NewMetadataAccessor(af(instances), store)
+	}
+}
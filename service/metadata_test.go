@@ -0,0 +1,173 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadataKey(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("host.com:8080", metadataKey("host.com:8080"))
+	assert.Equal("host.com:8080", metadataKey("http://host.com:8080"))
+	assert.Equal("host.com:8080", metadataKey("https://host.com:8080/"))
+}
+
+func testMetadataStoreEmpty(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		ms     = NewMetadataStore()
+	)
+
+	m, ok := ms.Get("host.com:8080")
+	assert.Equal(Metadata{}, m)
+	assert.False(ok)
+	assert.Zero(ms.Len())
+}
+
+func testMetadataStoreSet(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		ms     = NewMetadataStore()
+
+		first  = Metadata{Tags: []string{"first"}, Meta: map[string]string{"protocol": "h2"}, Weight: 1, Datacenter: "dc1"}
+		second = Metadata{Tags: []string{"second"}, NodeMeta: map[string]string{"rack": "1"}, Weight: 2, Datacenter: "dc2"}
+	)
+
+	ms.Set("source1", map[string]Metadata{"host1.com:8080": first})
+	ms.Set("source2", map[string]Metadata{"https://host2.com:8080": second})
+	assert.Equal(2, ms.Len())
+
+	m, ok := ms.Get("http://host1.com:8080")
+	assert.Equal(first, m)
+	assert.True(ok)
+
+	m, ok = ms.Get("host2.com:8080")
+	assert.Equal(second, m)
+	assert.True(ok)
+
+	// replacing a source's metadata removes instances it no longer reports
+	ms.Set("source1", map[string]Metadata{"host3.com:8080": first})
+	assert.Equal(2, ms.Len())
+
+	_, ok = ms.Get("host1.com:8080")
+	assert.False(ok)
+
+	m, ok = ms.Get("host3.com:8080")
+	assert.Equal(first, m)
+	assert.True(ok)
+
+	// an instance reported by more than one source is counted once
+	ms.Set("source2", map[string]Metadata{"host2.com:8080": second, "host3.com:8080": first})
+	assert.Equal(2, ms.Len())
+
+	ms.Set("source1", nil)
+	ms.Set("source2", map[string]Metadata{})
+	assert.Zero(ms.Len())
+}
+
+func TestMetadataStore(t *testing.T) {
+	t.Run("Empty", testMetadataStoreEmpty)
+	t.Run("Set", testMetadataStoreSet)
+}
+
+func testMetadataAccessorGet(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		expected = Metadata{Tags: []string{"tls"}, Weight: 3}
+		store    = NewMetadataStore()
+		a        = NewMetadataAccessor(MapAccessor{"key1": "https://host1.com:8080", "key2": "https://host2.com:8080"}, store)
+	)
+
+	store.Set("test", map[string]Metadata{"host1.com:8080": expected})
+
+	instance, err := a.Get([]byte("key1"))
+	require.NoError(err)
+	assert.Equal("https://host1.com:8080", instance)
+
+	instance, m, ok, err := a.GetWithMetadata([]byte("key1"))
+	require.NoError(err)
+	assert.Equal("https://host1.com:8080", instance)
+	assert.Equal(expected, m)
+	assert.True(ok)
+
+	instance, m, ok, err = a.GetWithMetadata([]byte("key2"))
+	require.NoError(err)
+	assert.Equal("https://host2.com:8080", instance)
+	assert.Equal(Metadata{}, m)
+	assert.False(ok)
+
+	instance, m, ok, err = a.GetWithMetadata([]byte("nosuch"))
+	assert.Error(err)
+	assert.Empty(instance)
+	assert.Equal(Metadata{}, m)
+	assert.False(ok)
+}
+
+func testMetadataAccessorNilStore(t *testing.T) {
+	var (
+		assert        = assert.New(t)
+		expectedError = errors.New("expected")
+		a             = NewMetadataAccessor(AccessorFunc(func([]byte) (string, error) { return "", expectedError }), nil)
+	)
+
+	_, _, ok, err := a.GetWithMetadata([]byte("key"))
+	assert.False(ok)
+	assert.Equal(expectedError, err)
+}
+
+func testMetadataAccessorRelease(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		bounded = NewBoundedAccessorFactory(0, 0.0, nil)([]string{"instance1", "instance2"}).(*BoundedAccessor)
+		a       = NewMetadataAccessor(bounded, NewMetadataStore())
+	)
+
+	instance, err := a.Get([]byte("key"))
+	require.NoError(err)
+	assert.Equal(1, bounded.Loads()[instance])
+
+	r, ok := a.(interface{ Release([]byte) })
+	require.True(ok)
+	r.Release([]byte("key"))
+	assert.Equal(0, bounded.Loads()[instance])
+
+	// releasing through an accessor which doesn't support it does nothing
+	NewMetadataAccessor(MapAccessor{}, nil).(interface{ Release([]byte) }).Release([]byte("key"))
+}
+
+func TestMetadataAccessor(t *testing.T) {
+	t.Run("Get", testMetadataAccessorGet)
+	t.Run("NilStore", testMetadataAccessorNilStore)
+	t.Run("Release", testMetadataAccessorRelease)
+}
+
+func TestNewMetadataAccessorFactory(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		store = NewMetadataStore()
+	)
+
+	store.Set("test", map[string]Metadata{"host.com:8080": {Weight: 5}})
+	for _, af := range []AccessorFactory{nil, DefaultAccessorFactory} {
+		a := NewMetadataAccessorFactory(af, store)([]string{"http://host.com:8080"})
+		require.NotNil(a)
+
+		ma, ok := a.(MetadataAccessor)
+		require.True(ok)
+
+		instance, m, ok, err := ma.GetWithMetadata([]byte("key"))
+		require.NoError(err)
+		assert.Equal("http://host.com:8080", instance)
+		assert.Equal(Metadata{Weight: 5}, m)
+		assert.True(ok)
+	}
+}
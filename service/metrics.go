@@ -11,8 +11,19 @@ const (
 	LastErrorTimestamp  = "sd_last_error_timestamp"
 	LastUpdateTimestamp = "sd_last_update_timestamp"
 
+	InstancesAddedCount   = "sd_instances_added_count"
+	InstancesRemovedCount = "sd_instances_removed_count"
+	MovedKeysRatio        = "sd_moved_keys_ratio"
+
+	BoundedLoadSpilloverCount = "sd_bounded_load_spillover_count"
+	BoundedLoadRebalanceCount = "sd_bounded_load_rebalance_count"
+	BoundedLoadAssignedKeys   = "sd_bounded_load_assigned_keys"
+
+	ListenerErrorCount = "sd_listener_error_count"
+
 	ServiceLabel  = "service"
 	EventKeyLabel = "eventKey"
+	ListenerLabel = "listener"
 )
 
 // Metrics is the service discovery module function for metrics
@@ -48,5 +59,44 @@ func Metrics() []xmetrics.Metric {
 			Help:       "The last time the service discovery backend sent updated instances for a given service",
 			LabelNames: []string{ServiceLabel, EventKeyLabel},
 		},
+		{
+			Name:       InstancesAddedCount,
+			Type:       "counter",
+			Help:       "The total count of instances added by service discovery updates for a particular service",
+			LabelNames: []string{ServiceLabel, EventKeyLabel},
+		},
+		{
+			Name:       InstancesRemovedCount,
+			Type:       "counter",
+			Help:       "The total count of instances removed by service discovery updates for a particular service",
+			LabelNames: []string{ServiceLabel, EventKeyLabel},
+		},
+		{
+			Name:       MovedKeysRatio,
+			Type:       "gauge",
+			Help:       "The estimated fraction of hash keys moved to a different instance by the last service discovery update",
+			LabelNames: []string{ServiceLabel, EventKeyLabel},
+		},
+		{
+			Name: BoundedLoadSpilloverCount,
+			Type: "counter",
+			Help: "The total count of keys assigned away from their consistent hash instance because that instance was at its load bound",
+		},
+		{
+			Name: BoundedLoadRebalanceCount,
+			Type: "counter",
+			Help: "The total count of keys moved to a different instance when the set of instances changed",
+		},
+		{
+			Name: BoundedLoadAssignedKeys,
+			Type: "gauge",
+			Help: "The current number of keys assigned to instances by the bounded load accessor",
+		},
+		{
+			Name:       ListenerErrorCount,
+			Type:       "counter",
+			Help:       "The total count of panics from service discovery monitor listeners",
+			LabelNames: []string{ServiceLabel, ListenerLabel},
+		},
 	}
 }
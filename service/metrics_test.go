@@ -24,4 +24,8 @@ func TestMetrics(t *testing.T) {
 	assert.NotNil(r.NewGauge(InstanceCount))
 	assert.NotNil(r.NewGauge(LastErrorTimestamp))
 	assert.NotNil(r.NewGauge(LastUpdateTimestamp))
+	assert.NotNil(r.NewCounter(BoundedLoadSpilloverCount))
+	assert.NotNil(r.NewCounter(BoundedLoadRebalanceCount))
+	assert.NotNil(r.NewGauge(BoundedLoadAssignedKeys))
+	assert.NotNil(r.NewCounter(ListenerErrorCount))
 }
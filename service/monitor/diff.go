@@ -0,0 +1,109 @@
+package monitor
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/xmidt-org/webpa-common/service"
+)
+
+// diffSampleCount is the number of sample keys hashed to estimate the fraction of keys moved by an update
+const diffSampleCount = 1000
+
+// diffSampleKeys are the keys hashed to estimate the fraction of keys moved by an update
+var diffSampleKeys = func() [][]byte {
+	keys := make([][]byte, diffSampleCount)
+	for i := range keys {
+		keys[i] = []byte("diff-sample-" + strconv.Itoa(i))
+	}
+
+	return keys
+}()
+
+// Diff describes how the instances for a key changed since the previous update
+type Diff struct {
+	// Added are the instances that were not present in the previous update, in sorted order
+	Added []string
+
+	// Removed are the instances from the previous update that are no longer present, in sorted order
+	Removed []string
+
+	// MovedKeys is the estimated fraction, from 0 to 1, of hash keys that map to a different instance after this update.
+	// The estimate assumes the default consistent hash, regardless of the accessors in use.  Keys can move even when
+	// no instances were added or removed, e.g. when instances are repeated according to their weights.
+	MovedKeys float64
+}
+
+// Changed tests if this diff represents any change in instances
+func (d Diff) Changed() bool {
+	return len(d.Added) > 0 || len(d.Removed) > 0
+}
+
+// differ tracks the instances for a single key across updates.  A differ is not safe for concurrent use,
+// since each key is monitored by its own goroutine.
+type differ struct {
+	instances map[string]bool
+	accessor  service.Accessor
+}
+
+func newDiffer() *differ {
+	return &differ{
+		accessor: service.EmptyAccessor(),
+	}
+}
+
+// diff computes the Diff between the previous instances and the given instances, which then become the previous instances
+func (d *differ) diff(instances []string) Diff {
+	var (
+		result  Diff
+		current = make(map[string]bool, len(instances))
+	)
+
+	// duplicate instances, e.g. from weights, are reported once
+	for _, i := range instances {
+		if current[i] {
+			continue
+		}
+
+		current[i] = true
+		if !d.instances[i] {
+			result.Added = append(result.Added, i)
+		}
+	}
+
+	for i := range d.instances {
+		if !current[i] {
+			result.Removed = append(result.Removed, i)
+		}
+	}
+
+	sort.Strings(result.Added)
+	sort.Strings(result.Removed)
+
+	accessor := service.EmptyAccessor()
+	if len(instances) > 0 {
+		accessor = service.DefaultAccessorFactory(instances)
+	}
+
+	if len(instances) > 0 || len(d.instances) > 0 {
+		result.MovedKeys = movedKeys(d.accessor, accessor)
+	}
+
+	d.instances = current
+	d.accessor = accessor
+	return result
+}
+
+// movedKeys estimates the fraction of keys that map to a different instance in the after Accessor
+func movedKeys(before, after service.Accessor) float64 {
+	moved := 0
+	for _, key := range diffSampleKeys {
+		b, beforeErr := before.Get(key)
+		a, afterErr := after.Get(key)
+		if beforeErr != nil || afterErr != nil || a != b {
+			moved++
+		}
+	}
+
+	return float64(moved) / float64(len(diffSampleKeys))
+}
@@ -0,0 +1,52 @@
+package monitor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffChanged(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.False(Diff{}.Changed())
+	assert.False(Diff{MovedKeys: 0.5}.Changed())
+	assert.True(Diff{Added: []string{"instance1"}}.Changed())
+	assert.True(Diff{Removed: []string{"instance1"}}.Changed())
+}
+
+func TestDiffer(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		d      = newDiffer()
+	)
+
+	assert.Equal(Diff{}, d.diff(nil))
+
+	// the first instances move every key
+	first := d.diff([]string{"instance2", "instance1", "instance3", "instance1"})
+	assert.Equal([]string{"instance1", "instance2", "instance3"}, first.Added)
+	assert.Empty(first.Removed)
+	assert.Equal(1.0, first.MovedKeys)
+
+	unchanged := d.diff([]string{"instance1", "instance3", "instance2", "instance1"})
+	assert.False(unchanged.Changed())
+	assert.Zero(unchanged.MovedKeys)
+
+	// a consistent hash moves only the keys of the removed instance, plus those taken by the added instance
+	churn := d.diff([]string{"instance1", "instance2", "instance4"})
+	assert.Equal([]string{"instance4"}, churn.Added)
+	assert.Equal([]string{"instance3"}, churn.Removed)
+	assert.True(churn.MovedKeys > 0.0)
+	assert.True(churn.MovedKeys < 0.8)
+
+	// weights can move keys without adding or removing instances
+	weighted := d.diff([]string{"instance1", "instance1", "instance1", "instance2", "instance4"})
+	assert.False(weighted.Changed())
+	assert.True(weighted.MovedKeys > 0.0)
+
+	last := d.diff(nil)
+	assert.Empty(last.Added)
+	assert.Equal([]string{"instance1", "instance2", "instance4"}, last.Removed)
+	assert.Equal(1.0, last.MovedKeys)
+}
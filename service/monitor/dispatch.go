@@ -0,0 +1,88 @@
+package monitor
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/metrics"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/service"
+)
+
+// namedListener is a Listener with a name used for metrics and logging
+type namedListener struct {
+	Listener
+	name string
+}
+
+func (nl namedListener) Name() string {
+	return nl.name
+}
+
+// NamedListener gives a Listener a name.  The monitor uses this name to label the metrics and
+// logging for that Listener.  Listeners that are not named are identified by their position in
+// the monitor's Listeners, e.g. "0" for the first.
+func NamedListener(name string, l Listener) Listener {
+	return namedListener{Listener: l, name: name}
+}
+
+// listenerName returns the name of the Listener at the given position
+func listenerName(l Listener, position int) string {
+	if n, ok := l.(interface{ Name() string }); ok {
+		return n.Name()
+	}
+
+	return strconv.Itoa(position)
+}
+
+// dispatcher delivers events to a single Listener on behalf of a monitor, isolating the monitor from
+// panics in that Listener.  Since each instancer has its own goroutine, a dispatcher is shared across goroutines.
+type dispatcher struct {
+	name     string
+	listener Listener
+
+	// failures is the number of consecutive panics
+	failures int32
+
+	// removed is nonzero once the listener has exceeded the maximum number of failures
+	removed int32
+}
+
+// monitorEvent delivers the event, returning an error if the listener panicked
+func (d *dispatcher) monitorEvent(e Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("listener panic: %v", r)
+		}
+	}()
+
+	d.listener.MonitorEvent(e)
+	return
+}
+
+// dispatch sends an event to each dispatcher that has not been removed.  A listener that panics has its error counted,
+// and is removed once it panics maxFailures times in a row, if maxFailures is positive.
+func dispatch(l log.Logger, dispatchers []*dispatcher, errors metrics.Counter, maxFailures int, e Event) {
+	for _, d := range dispatchers {
+		if atomic.LoadInt32(&d.removed) != 0 {
+			continue
+		}
+
+		err := d.monitorEvent(e)
+		if err == nil {
+			atomic.StoreInt32(&d.failures, 0)
+			continue
+		}
+
+		errors.With(service.ServiceLabel, e.Service, service.ListenerLabel, d.name).Add(1.0)
+		l.Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "listener failed", "listener", d.name, logging.ErrorKey(), err)
+
+		failures := atomic.AddInt32(&d.failures, 1)
+		if maxFailures > 0 && int(failures) >= maxFailures && atomic.CompareAndSwapInt32(&d.removed, 0, 1) {
+			l.Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "removing listener after repeated failures", "listener", d.name, "failures", failures)
+		}
+	}
+}
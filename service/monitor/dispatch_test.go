@@ -0,0 +1,149 @@
+package monitor
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/sd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/service"
+	"github.com/xmidt-org/webpa-common/xmetrics/xmetricstest"
+)
+
+func TestNamedListener(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		called = false
+		l      = NamedListener("accessor", ListenerFunc(func(Event) { called = true }))
+	)
+
+	assert.Equal("accessor", listenerName(l, 3))
+	l.MonitorEvent(Event{})
+	assert.True(called)
+
+	assert.Equal("3", listenerName(ListenerFunc(func(Event) {}), 3))
+}
+
+func testDispatchPanic(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		logger = logging.NewTestLogger(nil, t)
+		p      = xmetricstest.NewProvider(nil, service.Metrics)
+
+		healthyCount = 0
+		healthy      = &dispatcher{name: "healthy", listener: ListenerFunc(func(Event) { healthyCount++ })}
+
+		fail  = true
+		flaky = &dispatcher{name: "flaky", listener: ListenerFunc(func(Event) {
+			if fail {
+				panic("expected")
+			}
+		})}
+	)
+
+	dispatchers := []*dispatcher{flaky, healthy}
+	assert.NotPanics(func() {
+		dispatch(logger, dispatchers, p.NewCounter(service.ListenerErrorCount), 0, Event{Service: "talaria"})
+		dispatch(logger, dispatchers, p.NewCounter(service.ListenerErrorCount), 0, Event{Service: "talaria"})
+	})
+
+	assert.Equal(2, healthyCount)
+	assert.Equal(int32(2), flaky.failures)
+	p.Assert(t, service.ListenerErrorCount, service.ServiceLabel, "talaria", service.ListenerLabel, "flaky")(xmetricstest.Value(2.0))
+	p.Assert(t, service.ListenerErrorCount, service.ServiceLabel, "talaria", service.ListenerLabel, "healthy")(xmetricstest.Value(0.0))
+
+	// a success resets the consecutive failures
+	fail = false
+	dispatch(logger, dispatchers, p.NewCounter(service.ListenerErrorCount), 0, Event{Service: "talaria"})
+	assert.Zero(flaky.failures)
+	assert.Zero(flaky.removed)
+}
+
+func testDispatchRemoval(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		logger = logging.NewTestLogger(nil, t)
+		p      = xmetricstest.NewProvider(nil, service.Metrics)
+
+		panicCount = 0
+		bad        = &dispatcher{name: "bad", listener: ListenerFunc(func(Event) {
+			panicCount++
+			panic(errors.New("expected"))
+		})}
+	)
+
+	for repeat := 0; repeat < 5; repeat++ {
+		dispatch(logger, []*dispatcher{bad}, p.NewCounter(service.ListenerErrorCount), 3, Event{Service: "talaria"})
+	}
+
+	assert.Equal(3, panicCount)
+	assert.Equal(int32(1), bad.removed)
+	p.Assert(t, service.ListenerErrorCount, service.ServiceLabel, "talaria", service.ListenerLabel, "bad")(xmetricstest.Value(3.0))
+}
+
+func TestDispatch(t *testing.T) {
+	t.Run("Panic", testDispatchPanic)
+	t.Run("Removal", testDispatchRemoval)
+}
+
+func TestMonitorListenerPanic(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		logger  = logging.NewTestLogger(nil, t)
+		p       = xmetricstest.NewProvider(nil, service.Metrics)
+
+		instancer     = new(service.MockInstancer)
+		registerQueue = make(chan chan<- sd.Event, 1)
+		sdEvents      chan<- sd.Event
+		monitorEvents = make(chan Event, 5)
+	)
+
+	instancer.On("Register", mock.AnythingOfType("chan<- sd.Event")).
+		Run(func(arguments mock.Arguments) {
+			registerQueue <- arguments.Get(0).(chan<- sd.Event)
+		}).Once()
+
+	instancer.On("Deregister", mock.AnythingOfType("chan<- sd.Event")).Once()
+
+	m, err := New(
+		WithLogger(logger),
+		WithFilter(nil),
+		WithProvider(p),
+		WithMaxListenerFailures(2),
+		WithListeners(
+			NamedListener("panicky", ListenerFunc(func(Event) { panic("expected") })),
+			ListenerFunc(func(e Event) { monitorEvents <- e }),
+		),
+		WithInstancers(service.Instancers{"test": instancer}),
+	)
+
+	require.NoError(err)
+	require.NotNil(m)
+	defer m.Stop()
+
+	select {
+	case sdEvents = <-registerQueue:
+	case <-time.After(5 * time.Second):
+		require.Fail("Failed to receive registered event channel")
+		return
+	}
+
+	// the dispatch goroutine survives the panics, and other listeners still receive every event
+	for repeat := 1; repeat <= 3; repeat++ {
+		sdEvents <- sd.Event{Instances: []string{"instance1"}}
+		select {
+		case event := <-monitorEvents:
+			assert.Equal(repeat, event.EventCount)
+		case <-time.After(5 * time.Second):
+			assert.Fail("Failed to receive monitor event")
+		}
+	}
+
+	// the panicky listener was removed after 2 failures
+	p.Assert(t, service.ListenerErrorCount, service.ServiceLabel, "test", service.ListenerLabel, "panicky")(xmetricstest.Value(2.0))
+}
@@ -39,6 +39,10 @@ type Event struct {
 	// Err is any service discovery error that occurred.  If this is set, Instances will be empty.
 	Err error
 
+	// Diff describes how Instances changed from the previous update for the same Key.  This field is
+	// only set for updates, i.e. it is nil when Err is set or when Stopped is true.
+	Diff *Diff
+
 	// Stopped is set to true if and only if this event is being sent to indicate the monitoring goroutine
 	// has exited, either because of being explicitly stopped or because the environment was closed.
 	Stopped bool
@@ -70,6 +74,9 @@ func NewMetricsListener(p provider.Provider) Listener {
 		updateCount   = p.NewCounter(service.UpdateCount)
 		lastUpdate    = p.NewGauge(service.LastUpdateTimestamp)
 		instanceCount = p.NewGauge(service.InstanceCount)
+		addedCount    = p.NewCounter(service.InstancesAddedCount)
+		removedCount  = p.NewCounter(service.InstancesRemovedCount)
+		movedKeys     = p.NewGauge(service.MovedKeysRatio)
 	)
 
 	return ListenerFunc(func(e Event) {
@@ -84,6 +91,12 @@ func NewMetricsListener(p provider.Provider) Listener {
 		}
 
 		instanceCount.With(service.ServiceLabel, e.Service, service.EventKeyLabel, e.Key).Set(float64(len(e.Instances)))
+
+		if e.Diff != nil {
+			addedCount.With(service.ServiceLabel, e.Service, service.EventKeyLabel, e.Key).Add(float64(len(e.Diff.Added)))
+			removedCount.With(service.ServiceLabel, e.Service, service.EventKeyLabel, e.Key).Add(float64(len(e.Diff.Removed)))
+			movedKeys.With(service.ServiceLabel, e.Service, service.EventKeyLabel, e.Key).Set(e.Diff.MovedKeys)
+		}
 	})
 }
 
@@ -105,8 +105,24 @@ func testNewMetricsListenerError(t *testing.T) {
 	p.AssertExpectations(t)
 }
 
+func testNewMetricsListenerDiff(t *testing.T) {
+	var (
+		p = xmetricstest.NewProvider(nil, service.Metrics).
+			Expect(service.InstancesAddedCount, service.ServiceLabel, "talaria", service.EventKeyLabel, "test")(xmetricstest.Value(3.0)).
+			Expect(service.InstancesRemovedCount, service.ServiceLabel, "talaria", service.EventKeyLabel, "test")(xmetricstest.Value(1.0)).
+			Expect(service.MovedKeysRatio, service.ServiceLabel, "talaria", service.EventKeyLabel, "test")(xmetricstest.Value(0.25))
+		l = NewMetricsListener(p)
+	)
+
+	l.MonitorEvent(Event{Key: "test", Service: "talaria", Instances: []string{"instance1", "instance2"}, Diff: &Diff{Added: []string{"instance1", "instance2"}, MovedKeys: 1.0}})
+	l.MonitorEvent(Event{Key: "test", Service: "talaria", Instances: []string{"instance1", "instance3"}, Diff: &Diff{Added: []string{"instance3"}, Removed: []string{"instance2"}, MovedKeys: 0.25}})
+	l.MonitorEvent(Event{Key: "test", Service: "talaria", Err: errors.New("expected")})
+	p.AssertExpectations(t)
+}
+
 func TestNewMetricsListener(t *testing.T) {
 	t.Run("Update", testNewMetricsListenerUpdate)
+	t.Run("Diff", testNewMetricsListenerDiff)
 	t.Run("Error", testNewMetricsListenerError)
 }
 
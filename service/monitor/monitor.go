@@ -3,9 +3,12 @@ package monitor
 import (
 	"errors"
 	"sync"
+	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/provider"
 	"github.com/go-kit/kit/sd"
 	"github.com/xmidt-org/webpa-common/logging"
 	"github.com/xmidt-org/webpa-common/service"
@@ -74,6 +77,24 @@ func WithListeners(l ...Listener) Option {
 	}
 }
 
+// WithProvider sets the metrics provider used for the monitor's own metrics, such as service.ListenerErrorCount.
+// If nil, which is the default, the monitor's metrics are discarded.
+func WithProvider(p provider.Provider) Option {
+	return func(m *monitor) {
+		m.provider = p
+	}
+}
+
+// WithMaxListenerFailures removes a Listener from the monitor once it panics this many times in a row.
+// A removed Listener receives no further events, including the final stopped event.  If nonpositive,
+// which is the default, Listeners are never removed.  Regardless of this setting, a panicking Listener
+// does not affect other Listeners or the monitor.
+func WithMaxListenerFailures(n int) Option {
+	return func(m *monitor) {
+		m.maxListenerFailures = n
+	}
+}
+
 // WithInstancers establishes the set of sd.Instancer objects to be monitored.  The given Instancers
 // is copied to maintain the monitor's immutability.
 func WithInstancers(i service.Instancers) Option {
@@ -82,10 +103,27 @@ func WithInstancers(i service.Instancers) Option {
 	}
 }
 
+// WithQuietPeriod coalesces bursts of service discovery events, such as those that occur during deployments.
+// Each event from an instancer restarts the quiet period, and only the most recent event is dispatched to
+// Listeners once the quiet period elapses with no further events.  This avoids rehashing on every instance change.
+// A nonpositive quiet period, which is the default, dispatches every event immediately.
+//
+// Any event still waiting for its quiet period to elapse is discarded when the monitor stops.
+func WithQuietPeriod(d time.Duration) Option {
+	return func(m *monitor) {
+		m.quietPeriod = d
+	}
+}
+
+// WithEnvironment monitors the environment's Instancers until the environment is closed.  If the environment
+// has a metrics provider and no provider has been set with WithProvider, the environment's provider is used.
 func WithEnvironment(e service.Environment) Option {
 	return func(m *monitor) {
 		m.instancers = e.Instancers()
 		m.closed = e.Closed()
+		if m.provider == nil {
+			m.provider = e.Provider()
+		}
 	}
 }
 
@@ -119,11 +157,38 @@ type monitor struct {
 	filter     Filter
 	listeners  Listeners
 
+	quietPeriod time.Duration
+
+	provider            provider.Provider
+	maxListenerFailures int
+	dispatchers         []*dispatcher
+	listenerErrors      metrics.Counter
+
 	closed   <-chan struct{}
 	stopped  chan struct{}
 	stopOnce sync.Once
 }
 
+// dispatch sends an event to each listener, isolating the monitor from listener panics
+func (m *monitor) dispatch(l log.Logger, e Event) {
+	dispatch(l, m.dispatchers, m.listenerErrors, m.maxListenerFailures, e)
+}
+
+// dispatchUpdate computes the Diff for an event that carries instances, then dispatches the event
+func (m *monitor) dispatchUpdate(l log.Logger, d *differ, e Event) {
+	if e.Err == nil {
+		diff := d.diff(e.Instances)
+		if diff.Changed() {
+			l.Log(level.Key(), level.InfoValue(), logging.MessageKey(), "service discovery instances changed",
+				"added", diff.Added, "removed", diff.Removed, "movedKeys", diff.MovedKeys)
+		}
+
+		e.Diff = &diff
+	}
+
+	m.dispatch(l, e)
+}
+
 func (m *monitor) Stopped() <-chan struct{} {
 	return m.stopped
 }
@@ -139,6 +204,15 @@ func (m *monitor) start() error {
 		return errNoInstances
 	}
 
+	if m.provider == nil {
+		m.provider = provider.NewDiscardProvider()
+	}
+
+	m.listenerErrors = m.provider.NewCounter(service.ListenerErrorCount)
+	for position, l := range m.listeners {
+		m.dispatchers = append(m.dispatchers, &dispatcher{name: listenerName(l, position), listener: l})
+	}
+
 	for k, v := range m.instancers {
 		var svc = k
 		if ci, ok := v.(service.ContextualInstancer); ok {
@@ -164,8 +238,21 @@ func (m *monitor) dispatchEvents(key, service string, l log.Logger, i sd.Instanc
 
 		logger = log.With(l, EventCountKey(), eventCounter)
 		events = make(chan sd.Event, 10)
+		differ = newDiffer()
+
+		// when a quiet period is configured, pending holds the most recent event until the quiet period elapses
+		pending   Event
+		coalesced int
+		timer     *time.Timer
+		quiet     <-chan time.Time
 	)
 
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
 	logger.Log(level.Key(), level.InfoValue(), logging.MessageKey(), "subscription monitor starting")
 
 	defer i.Deregister(events)
@@ -192,17 +279,37 @@ func (m *monitor) dispatchEvents(key, service string, l log.Logger, i sd.Instanc
 				}
 			}
 
-			m.listeners.MonitorEvent(event)
+			if m.quietPeriod <= 0 {
+				m.dispatchUpdate(logger, differ, event)
+				continue
+			}
+
+			if timer != nil {
+				timer.Stop()
+				coalesced++
+			}
+
+			pending = event
+			timer = time.NewTimer(m.quietPeriod)
+			quiet = timer.C
+
+		case <-quiet:
+			logger.Log(level.Key(), level.DebugValue(), logging.MessageKey(), "dispatching event after quiet period", "coalesced", coalesced)
+			m.dispatchUpdate(logger, differ, pending)
+			pending = Event{}
+			coalesced = 0
+			timer = nil
+			quiet = nil
 
 		case <-m.stopped:
 			logger.Log(level.Key(), level.InfoValue(), logging.MessageKey(), "subscription monitor was stopped")
-			m.listeners.MonitorEvent(Event{Key: key, Service: service, Instancer: i, EventCount: eventCount, Stopped: true})
+			m.dispatch(logger, Event{Key: key, Service: service, Instancer: i, EventCount: eventCount, Stopped: true})
 			return
 
 		case <-m.closed:
 			logger.Log(level.Key(), level.InfoValue(), logging.MessageKey(), "subscription monitor exiting due to external closure")
 			m.Stop() // ensure that the Stopped state is correct
-			m.listeners.MonitorEvent(Event{Key: key, Service: service, Instancer: i, EventCount: eventCount, Stopped: true})
+			m.dispatch(logger, Event{Key: key, Service: service, Instancer: i, EventCount: eventCount, Stopped: true})
 			return
 		}
 	}
@@ -84,6 +84,7 @@ func testNewStop(t *testing.T) {
 		assert.Equal(instancer, event.Instancer)
 		assert.Equal(expectedError, event.Err)
 		assert.Len(event.Instances, 0)
+		assert.Nil(event.Diff)
 		assert.False(event.Stopped)
 		assert.Equal(1, event.EventCount)
 
@@ -98,6 +99,12 @@ func testNewStop(t *testing.T) {
 		assert.Equal(instancer, event.Instancer)
 		assert.NoError(event.Err)
 		assert.Equal(expectedInstances, event.Instances)
+		if assert.NotNil(event.Diff) {
+			assert.Equal(expectedInstances, event.Diff.Added)
+			assert.Empty(event.Diff.Removed)
+			assert.Equal(1.0, event.Diff.MovedKeys)
+		}
+
 		assert.False(event.Stopped)
 		assert.Equal(2, event.EventCount)
 
@@ -119,6 +126,7 @@ func testNewStop(t *testing.T) {
 		assert.Equal(instancer, finalEvent.Instancer)
 		assert.NoError(finalEvent.Err)
 		assert.Len(finalEvent.Instances, 0)
+		assert.Nil(finalEvent.Diff)
 		assert.True(finalEvent.Stopped)
 		assert.Equal(2, finalEvent.EventCount)
 
@@ -271,8 +279,109 @@ func testNewWithEnvironment(t *testing.T) {
 	listener.AssertExpectations(t)
 }
 
+func testNewQuietPeriod(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		logger  = logging.NewTestLogger(nil, t)
+
+		instancer     = new(service.MockInstancer)
+		listener      = new(mockListener)
+		registerQueue = make(chan chan<- sd.Event, 1)
+		sdEvents      chan<- sd.Event
+
+		monitorEvents = make(chan Event, 5)
+	)
+
+	instancer.On("Register", mock.AnythingOfType("chan<- sd.Event")).
+		Run(func(arguments mock.Arguments) {
+			registerQueue <- arguments.Get(0).(chan<- sd.Event)
+		}).Once()
+
+	instancer.On("Deregister", mock.AnythingOfType("chan<- sd.Event")).Once()
+
+	listener.On("MonitorEvent", mock.MatchedBy(func(Event) bool { return true })).Run(func(arguments mock.Arguments) {
+		monitorEvents <- arguments.Get(0).(Event)
+	})
+
+	m, err := New(
+		WithLogger(logger),
+		WithFilter(nil),
+		WithListeners(listener),
+		WithInstancers(service.Instancers{"test": instancer}),
+		WithQuietPeriod(100*time.Millisecond),
+	)
+
+	require.NoError(err)
+	require.NotNil(m)
+
+	select {
+	case sdEvents = <-registerQueue:
+	case <-time.After(5 * time.Second):
+		m.Stop()
+		require.Fail("Failed to receive registered event channel")
+		return
+	}
+
+	// a burst of events is coalesced into the last one
+	sdEvents <- sd.Event{Instances: []string{"instance1"}}
+	sdEvents <- sd.Event{Err: errors.New("expected")}
+	sdEvents <- sd.Event{Instances: []string{"instance1", "instance2"}}
+	select {
+	case event := <-monitorEvents:
+		assert.Equal("test", event.Key)
+		assert.NoError(event.Err)
+		assert.Equal([]string{"instance1", "instance2"}, event.Instances)
+		assert.False(event.Stopped)
+		assert.Equal(3, event.EventCount)
+
+	case <-time.After(5 * time.Second):
+		assert.Fail("Failed to receive monitor event")
+	}
+
+	select {
+	case event := <-monitorEvents:
+		assert.Fail("Unexpected monitor event", "%v", event)
+	case <-time.After(250 * time.Millisecond):
+		// passing
+	}
+
+	// events after the quiet period are dispatched separately
+	sdEvents <- sd.Event{Instances: []string{"instance3"}}
+	select {
+	case event := <-monitorEvents:
+		assert.Equal([]string{"instance3"}, event.Instances)
+		assert.Equal(4, event.EventCount)
+
+	case <-time.After(5 * time.Second):
+		assert.Fail("Failed to receive monitor event")
+	}
+
+	// a pending event is discarded when the monitor stops
+	sdEvents <- sd.Event{Instances: []string{"instance4"}}
+	m.Stop()
+	select {
+	case finalEvent := <-monitorEvents:
+		assert.True(finalEvent.Stopped)
+		assert.Empty(finalEvent.Instances)
+
+	case <-time.After(5 * time.Second):
+		assert.Fail("No stopped event received")
+	}
+
+	select {
+	case event := <-monitorEvents:
+		assert.Fail("Unexpected monitor event", "%v", event)
+	case <-time.After(250 * time.Millisecond):
+		// passing
+	}
+
+	listener.AssertExpectations(t)
+}
+
 func TestNew(t *testing.T) {
 	t.Run("NoInstances", testNewNoInstances)
 	t.Run("Stop", testNewStop)
 	t.Run("WithEnvironment", testNewWithEnvironment)
+	t.Run("QuietPeriod", testNewQuietPeriod)
 }
@@ -0,0 +1,141 @@
+package servicecfg
+
+import (
+	"fmt"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/sd"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/service"
+)
+
+// compositeEnvironment is a service.Environment whose instances are merged from several backend environments.
+// Registration and shutdown are delegated to the backends.
+type compositeEnvironment struct {
+	service.Environment
+	backends []service.Environment
+}
+
+func (ce compositeEnvironment) IsRegistered(instance string) bool {
+	for _, b := range ce.backends {
+		if b.IsRegistered(instance) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (ce compositeEnvironment) Register() {
+	for _, b := range ce.backends {
+		b.Register()
+	}
+}
+
+func (ce compositeEnvironment) Deregister() {
+	for _, b := range ce.backends {
+		b.Deregister()
+	}
+}
+
+func (ce compositeEnvironment) Close() error {
+	err := ce.Environment.Close()
+	for _, b := range ce.backends {
+		if closeErr := b.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+func closeAll(backends []service.Environment) {
+	for _, b := range backends {
+		b.Close()
+	}
+}
+
+// newMergeStrategy returns the service.MergeStrategy for a configured strategy name
+func newMergeStrategy(strategy string) (service.MergeStrategy, error) {
+	switch strategy {
+	case UnionStrategy:
+		return service.UnionMerge, nil
+
+	case PriorityStrategy:
+		return service.PriorityMerge, nil
+
+	default:
+		return nil, fmt.Errorf("Unsupported composite strategy: %s", strategy)
+	}
+}
+
+// backendInstancer merges all the instancers of a backend environment
+func backendInstancer(e service.Environment) sd.Instancer {
+	var instancers []sd.Instancer
+	for _, i := range e.Instancers() {
+		instancers = append(instancers, i)
+	}
+
+	if len(instancers) == 1 {
+		return instancers[0]
+	}
+
+	return service.NewCompositeInstancer(service.UnionMerge, instancers...)
+}
+
+// newCompositeEnvironment creates each backend environment, then an environment with a single instancer that merges
+// the instances from each backend.  The backends are created with the caller's options, while the composite environment
+// uses the full set of environment options.
+func newCompositeEnvironment(l log.Logger, o *Options, eo, options []service.Option) (service.Environment, error) {
+	merge, err := newMergeStrategy(o.Composite.strategy())
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		backends   []service.Environment
+		instancers []sd.Instancer
+	)
+
+	for position, bo := range o.Composite.Backends {
+		if bo.Composite != nil {
+			closeAll(backends)
+			return nil, errNestedComposite
+		}
+
+		if len(bo.DefaultScheme) == 0 {
+			bo.DefaultScheme = o.DefaultScheme
+		}
+
+		b, err := newBackendEnvironment(log.With(l, "backend", position), &bo, options)
+		if err != nil {
+			closeAll(backends)
+			return nil, err
+		}
+
+		backends = append(backends, b)
+		instancers = append(instancers, backendInstancer(b))
+	}
+
+	if len(backends) == 0 {
+		return nil, errNoServiceDiscovery
+	}
+
+	l.Log(level.Key(), level.InfoValue(), logging.MessageKey(), "using a composite of service discovery backends", "strategy", o.Composite.strategy(), "backends", len(backends))
+	return compositeEnvironment{
+		Environment: service.NewEnvironment(
+			append(eo,
+				service.WithInstancers(
+					service.Instancers{
+						"composite": service.NewContextualInstancer(
+							service.NewCompositeInstancer(merge, instancers...),
+							map[string]interface{}{"strategy": o.Composite.strategy(), "backends": len(backends)},
+						),
+					},
+				),
+			)...,
+		),
+		backends: backends,
+	}, nil
+}
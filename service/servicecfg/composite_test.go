@@ -0,0 +1,150 @@
+package servicecfg
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/service"
+	"github.com/xmidt-org/webpa-common/service/consul"
+)
+
+func newTestViper(t *testing.T, configuration string) *viper.Viper {
+	v := viper.New()
+	v.SetConfigType("json")
+	require.NoError(t, v.ReadConfig(strings.NewReader(configuration)))
+	return v
+}
+
+func expectInstances(t *testing.T, e service.Environment, expected []string) {
+	i, ok := e.Instancers().Get("composite")
+	require.True(t, ok)
+
+	events := make(chan sd.Event, 10)
+	i.Register(events)
+	defer i.Deregister(events)
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event := <-events:
+			if assert.ObjectsAreEqual(expected, event.Instances) {
+				return
+			}
+
+		case <-timeout:
+			assert.Fail(t, "No matching event", "expected: %v", expected)
+			return
+		}
+	}
+}
+
+func testNewEnvironmentCompositeUnion(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		v = newTestViper(t, `
+			{
+				"composite": {
+					"backends": [
+						{"fixed": ["http://instance1.com:8080", "http://instance2.com:8080"]},
+						{"fixed": ["http://instance3.net:8080"]}
+					]
+				}
+			}
+		`)
+	)
+
+	e, err := NewEnvironment(logging.NewTestLogger(nil, t), v)
+	require.NoError(err)
+	require.NotNil(e)
+
+	assert.Len(e.Instancers(), 1)
+	expectInstances(t, e, []string{"http://instance1.com:8080", "http://instance2.com:8080", "http://instance3.net:8080"})
+	assert.False(e.IsRegistered("http://instance1.com:8080"))
+	assert.NoError(e.Close())
+}
+
+func testNewEnvironmentCompositePriority(t *testing.T) {
+	defer resetEnvironmentFactories()
+
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		v = newTestViper(t, `
+			{
+				"defaultScheme": "https",
+				"composite": {
+					"strategy": "priority",
+					"backends": [
+						{"consul": {"watches": [{"service": "talaria"}]}},
+						{"fixed": ["http://fallback.com:8080"]}
+					]
+				}
+			}
+		`)
+
+		backend = new(service.MockEnvironment)
+	)
+
+	backend.On("Instancers").Return(service.Instancers{"talaria": sd.FixedInstancer{"https://talaria.com:8080"}})
+	backend.On("Register").Once()
+	backend.On("Deregister").Once()
+	backend.On("IsRegistered", "https://talaria.com:8080").Return(true)
+	backend.On("Close").Return(error(nil)).Once()
+
+	consulEnvironmentFactory = func(l log.Logger, defaultScheme string, o consul.Options, eo ...service.Option) (service.Environment, error) {
+		// backends inherit the default scheme
+		assert.Equal("https", defaultScheme)
+		assert.Equal([]consul.Watch{{Service: "talaria"}}, o.Watches)
+		return backend, nil
+	}
+
+	e, err := NewEnvironment(logging.NewTestLogger(nil, t), v)
+	require.NoError(err)
+	require.NotNil(e)
+
+	expectInstances(t, e, []string{"https://talaria.com:8080"})
+	e.Register()
+	assert.True(e.IsRegistered("https://talaria.com:8080"))
+	e.Deregister()
+	assert.NoError(e.Close())
+
+	backend.AssertExpectations(t)
+}
+
+func testNewEnvironmentCompositeError(t *testing.T, configuration string) {
+	assert := assert.New(t)
+	e, err := NewEnvironment(logging.NewTestLogger(nil, t), newTestViper(t, configuration))
+	assert.Nil(e)
+	assert.Error(err)
+}
+
+func TestNewEnvironmentComposite(t *testing.T) {
+	t.Run("Union", testNewEnvironmentCompositeUnion)
+	t.Run("Priority", testNewEnvironmentCompositePriority)
+
+	t.Run("UnsupportedStrategy", func(t *testing.T) {
+		testNewEnvironmentCompositeError(t, `{"composite": {"strategy": "random", "backends": [{"fixed": ["http://instance1.com:8080"]}]}}`)
+	})
+
+	t.Run("NoBackends", func(t *testing.T) {
+		testNewEnvironmentCompositeError(t, `{"composite": {"strategy": "union"}}`)
+	})
+
+	t.Run("EmptyBackend", func(t *testing.T) {
+		testNewEnvironmentCompositeError(t, `{"composite": {"backends": [{"fixed": ["http://instance1.com:8080"]}, {}]}}`)
+	})
+
+	t.Run("Nested", func(t *testing.T) {
+		testNewEnvironmentCompositeError(t, `{"composite": {"backends": [{"composite": {"backends": [{"fixed": ["http://instance1.com:8080"]}]}}]}}`)
+	})
+}
@@ -9,15 +9,22 @@ import (
 	"github.com/xmidt-org/webpa-common/logging"
 	"github.com/xmidt-org/webpa-common/service"
 	"github.com/xmidt-org/webpa-common/service/consul"
+	"github.com/xmidt-org/webpa-common/service/dnssrv"
+	"github.com/xmidt-org/webpa-common/service/etcd"
+	"github.com/xmidt-org/webpa-common/service/kubernetes"
 	"github.com/xmidt-org/webpa-common/service/zk"
 	"github.com/xmidt-org/webpa-common/xviper"
 )
 
 var (
-	zookeeperEnvironmentFactory = zk.NewEnvironment
-	consulEnvironmentFactory    = consul.NewEnvironment
+	zookeeperEnvironmentFactory  = zk.NewEnvironment
+	consulEnvironmentFactory     = consul.NewEnvironment
+	kubernetesEnvironmentFactory = kubernetes.NewEnvironment
+	dnssrvEnvironmentFactory     = dnssrv.NewEnvironment
+	etcdEnvironmentFactory       = etcd.NewEnvironment
 
 	errNoServiceDiscovery = errors.New("No service discovery configured")
+	errNestedComposite    = errors.New("A composite backend cannot itself be a composite")
 )
 
 func NewEnvironment(l log.Logger, u xviper.Unmarshaler, options ...service.Option) (service.Environment, error) {
@@ -30,15 +37,27 @@ func NewEnvironment(l log.Logger, u xviper.Unmarshaler, options ...service.Optio
 		return nil, err
 	}
 
+	af := service.NewConsistentAccessorFactory(o.vnodeCount())
+	if o.loadBoundFactor() > 0.0 {
+		// the bounded load metrics use whatever provider the caller configured for the environment
+		af = service.NewBoundedAccessorFactory(o.vnodeCount(), o.loadBoundFactor(), service.NewEnvironment(options...).Provider())
+	}
+
 	eo := []service.Option{
-		service.WithAccessorFactory(
-			service.NewConsistentAccessorFactory(o.vnodeCount()),
-		),
+		service.WithAccessorFactory(af),
 		service.WithDefaultScheme(o.defaultScheme()),
 	}
 
 	eo = append(eo, options...)
+	if o.Composite != nil {
+		return newCompositeEnvironment(l, o, eo, options)
+	}
 
+	return newBackendEnvironment(l, o, eo)
+}
+
+// newBackendEnvironment creates the environment for the single backend configured in the options
+func newBackendEnvironment(l log.Logger, o *Options, eo []service.Option) (service.Environment, error) {
 	if len(o.Fixed) > 0 {
 		l.Log(level.Key(), level.InfoValue(), logging.MessageKey(), "using a fixed set of instances for service discovery", "instances", o.Fixed)
 		return service.NewEnvironment(
@@ -65,5 +84,20 @@ func NewEnvironment(l log.Logger, u xviper.Unmarshaler, options ...service.Optio
 		return consulEnvironmentFactory(l, o.DefaultScheme, *o.Consul, eo...)
 	}
 
+	if o.Kubernetes != nil {
+		l.Log(level.Key(), level.InfoValue(), logging.MessageKey(), "using kubernetes for service discovery")
+		return kubernetesEnvironmentFactory(l, *o.Kubernetes, eo...)
+	}
+
+	if o.DNSSRV != nil {
+		l.Log(level.Key(), level.InfoValue(), logging.MessageKey(), "using DNS SRV records for service discovery")
+		return dnssrvEnvironmentFactory(l, *o.DNSSRV, eo...)
+	}
+
+	if o.Etcd != nil {
+		l.Log(level.Key(), level.InfoValue(), logging.MessageKey(), "using etcd for service discovery")
+		return etcdEnvironmentFactory(l, *o.Etcd, eo...)
+	}
+
 	return nil, errNoServiceDiscovery
 }
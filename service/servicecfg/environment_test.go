@@ -14,6 +14,9 @@ import (
 	"github.com/xmidt-org/webpa-common/logging"
 	"github.com/xmidt-org/webpa-common/service"
 	"github.com/xmidt-org/webpa-common/service/consul"
+	"github.com/xmidt-org/webpa-common/service/dnssrv"
+	"github.com/xmidt-org/webpa-common/service/etcd"
+	"github.com/xmidt-org/webpa-common/service/kubernetes"
 	"github.com/xmidt-org/webpa-common/service/zk"
 	"github.com/xmidt-org/webpa-common/xviper"
 )
@@ -70,6 +73,35 @@ func testNewEnvironmentFixed(t *testing.T) {
 	assert.NoError(e.Close())
 }
 
+func testNewEnvironmentBoundedLoad(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		logger = logging.NewTestLogger(nil, t)
+		v      = viper.New()
+
+		configuration = strings.NewReader(`
+			{
+				"loadBoundFactor": 1.5,
+				"fixed": ["instance1.com:1234", "instance2.net:8888"]
+			}
+		`)
+	)
+
+	v.SetConfigType("json")
+	require.NoError(v.ReadConfig(configuration))
+
+	e, err := NewEnvironment(logger, v)
+	require.NoError(err)
+	require.NotNil(e)
+
+	a := e.AccessorFactory()([]string{"instance1.com:1234", "instance2.net:8888"})
+	assert.IsType((*service.BoundedAccessor)(nil), a)
+
+	assert.NoError(e.Close())
+}
+
 func testNewEnvironmentZookeeper(t *testing.T) {
 	defer resetEnvironmentFactories()
 
@@ -124,6 +156,80 @@ func testNewEnvironmentZookeeper(t *testing.T) {
 	assert.NoError(actualEnvironment.Close())
 }
 
+func testNewEnvironmentZookeeperSecure(t *testing.T) {
+	defer resetEnvironmentFactories()
+
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		logger = logging.NewTestLogger(nil, t)
+		v      = viper.New()
+
+		expectedEnvironment = service.NewEnvironment()
+
+		configuration = strings.NewReader(`
+			{
+				"zookeeper": {
+					"client": {
+						"connection": "host1.com:1111",
+						"username": "scytale",
+						"password": "secret",
+						"acl": [
+							{"scheme": "digest", "id": "scytale:secret", "permissions": ["all"]},
+							{"scheme": "world", "permissions": ["read"]}
+						],
+						"tls": {
+							"certificateFile": "/etc/zk/client.pem",
+							"keyFile": "/etc/zk/client.key",
+							"caFile": "/etc/zk/ca.pem",
+							"serverName": "zookeeper.example.com"
+						}
+					},
+					"watches": ["/some/where"]
+				}
+			}
+		`)
+	)
+
+	v.SetConfigType("json")
+	require.NoError(v.ReadConfig(configuration))
+
+	zookeeperEnvironmentFactory = func(l log.Logger, zo zk.Options, eo ...service.Option) (service.Environment, error) {
+		assert.Equal(logger, l)
+		assert.Equal(
+			zk.Options{
+				Client: zk.Client{
+					Connection: "host1.com:1111",
+					Username:   "scytale",
+					Password:   "secret",
+					ACL: []zk.ACL{
+						{Scheme: zk.DigestScheme, ID: "scytale:secret", Permissions: []string{"all"}},
+						{Scheme: zk.WorldScheme, Permissions: []string{"read"}},
+					},
+					TLS: &zk.TLS{
+						CertificateFile: "/etc/zk/client.pem",
+						KeyFile:         "/etc/zk/client.key",
+						CAFile:          "/etc/zk/ca.pem",
+						ServerName:      "zookeeper.example.com",
+					},
+				},
+				Watches: []string{"/some/where"},
+			},
+			zo,
+		)
+
+		return expectedEnvironment, nil
+	}
+
+	actualEnvironment, err := NewEnvironment(logger, v)
+	require.NoError(err)
+	require.NotNil(actualEnvironment)
+	assert.Equal(expectedEnvironment, actualEnvironment)
+
+	assert.NoError(actualEnvironment.Close())
+}
+
 func testNewEnvironmentConsul(t *testing.T) {
 	defer resetEnvironmentFactories()
 
@@ -222,10 +328,203 @@ func testNewEnvironmentConsul(t *testing.T) {
 	assert.NoError(actualEnvironment.Close())
 }
 
+func testNewEnvironmentKubernetes(t *testing.T) {
+	defer resetEnvironmentFactories()
+
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		logger = logging.NewTestLogger(nil, t)
+		v      = viper.New()
+
+		expectedEnvironment = service.NewEnvironment()
+
+		configuration = strings.NewReader(`
+			{
+				"kubernetes": {
+					"namespace": "xmidt",
+					"watches": [
+						{
+							"service": "talaria",
+							"portName": "http"
+						},
+						{
+							"service": "petasos",
+							"namespace": "edge",
+							"scheme": "https",
+							"includeNotReady": true
+						}
+					]
+				}
+			}
+		`)
+	)
+
+	v.SetConfigType("json")
+	require.NoError(v.ReadConfig(configuration))
+
+	kubernetesEnvironmentFactory = func(l log.Logger, ko kubernetes.Options, eo ...service.Option) (service.Environment, error) {
+		assert.Equal(logger, l)
+		assert.Equal(
+			kubernetes.Options{
+				Namespace: "xmidt",
+				Watches: []kubernetes.Watch{
+					{
+						Service:  "talaria",
+						PortName: "http",
+					},
+					{
+						Service:         "petasos",
+						Namespace:       "edge",
+						Scheme:          "https",
+						IncludeNotReady: true,
+					},
+				},
+			},
+			ko,
+		)
+
+		return expectedEnvironment, nil
+	}
+
+	actualEnvironment, err := NewEnvironment(logger, v)
+	require.NoError(err)
+	require.NotNil(actualEnvironment)
+	assert.Equal(expectedEnvironment, actualEnvironment)
+
+	assert.NoError(actualEnvironment.Close())
+}
+
+func testNewEnvironmentDNSSRV(t *testing.T) {
+	defer resetEnvironmentFactories()
+
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		logger = logging.NewTestLogger(nil, t)
+		v      = viper.New()
+
+		expectedEnvironment = service.NewEnvironment()
+
+		configuration = strings.NewReader(`
+			{
+				"dnssrv": {
+					"servers": ["10.0.0.2:53"],
+					"watches": [
+						{
+							"name": "_talaria._tcp.example.com",
+							"scheme": "https"
+						}
+					]
+				}
+			}
+		`)
+	)
+
+	v.SetConfigType("json")
+	require.NoError(v.ReadConfig(configuration))
+
+	dnssrvEnvironmentFactory = func(l log.Logger, o dnssrv.Options, eo ...service.Option) (service.Environment, error) {
+		assert.Equal(logger, l)
+		assert.Equal(
+			dnssrv.Options{
+				Servers: []string{"10.0.0.2:53"},
+				Watches: []dnssrv.Watch{
+					{
+						Name:   "_talaria._tcp.example.com",
+						Scheme: "https",
+					},
+				},
+			},
+			o,
+		)
+
+		return expectedEnvironment, nil
+	}
+
+	actualEnvironment, err := NewEnvironment(logger, v)
+	require.NoError(err)
+	require.NotNil(actualEnvironment)
+	assert.Equal(expectedEnvironment, actualEnvironment)
+
+	assert.NoError(actualEnvironment.Close())
+}
+
+func testNewEnvironmentEtcd(t *testing.T) {
+	defer resetEnvironmentFactories()
+
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		logger = logging.NewTestLogger(nil, t)
+		v      = viper.New()
+
+		expectedEnvironment = service.NewEnvironment()
+
+		configuration = strings.NewReader(`
+			{
+				"etcd": {
+					"client": {
+						"endpoints": ["http://etcd-1:2379", "http://etcd-2:2379"]
+					},
+					"registrations": [
+						{
+							"prefix": "/xmidt/talaria/",
+							"address": "talaria-1.xmidt.net",
+							"port": 8080
+						}
+					],
+					"watches": ["/xmidt/scytale/"]
+				}
+			}
+		`)
+	)
+
+	v.SetConfigType("json")
+	require.NoError(v.ReadConfig(configuration))
+
+	etcdEnvironmentFactory = func(l log.Logger, o etcd.Options, eo ...service.Option) (service.Environment, error) {
+		assert.Equal(logger, l)
+		assert.Equal(
+			etcd.Options{
+				Client: etcd.Client{
+					Endpoints: []string{"http://etcd-1:2379", "http://etcd-2:2379"},
+				},
+				Registrations: []etcd.Registration{
+					{
+						Prefix:  "/xmidt/talaria/",
+						Address: "talaria-1.xmidt.net",
+						Port:    8080,
+					},
+				},
+				Watches: []string{"/xmidt/scytale/"},
+			},
+			o,
+		)
+
+		return expectedEnvironment, nil
+	}
+
+	actualEnvironment, err := NewEnvironment(logger, v)
+	require.NoError(err)
+	require.NotNil(actualEnvironment)
+	assert.Equal(expectedEnvironment, actualEnvironment)
+
+	assert.NoError(actualEnvironment.Close())
+}
+
 func TestNewEnvironment(t *testing.T) {
 	t.Run("Empty", testNewEnvironmentEmpty)
 	t.Run("UnmarshalError", testNewEnvironmentUnmarshalError)
 	t.Run("Fixed", testNewEnvironmentFixed)
+	t.Run("BoundedLoad", testNewEnvironmentBoundedLoad)
 	t.Run("Zookeeper", testNewEnvironmentZookeeper)
+	t.Run("ZookeeperSecure", testNewEnvironmentZookeeperSecure)
 	t.Run("Consul", testNewEnvironmentConsul)
+	t.Run("Kubernetes", testNewEnvironmentKubernetes)
+	t.Run("DNSSRV", testNewEnvironmentDNSSRV)
+	t.Run("Etcd", testNewEnvironmentEtcd)
 }
@@ -2,6 +2,9 @@ package servicecfg
 
 import (
 	"github.com/xmidt-org/webpa-common/service/consul"
+	"github.com/xmidt-org/webpa-common/service/dnssrv"
+	"github.com/xmidt-org/webpa-common/service/etcd"
+	"github.com/xmidt-org/webpa-common/service/kubernetes"
 	"github.com/xmidt-org/webpa-common/service/zk"
 )
 
@@ -9,4 +12,7 @@ import (
 func resetEnvironmentFactories() {
 	zookeeperEnvironmentFactory = zk.NewEnvironment
 	consulEnvironmentFactory = consul.NewEnvironment
+	kubernetesEnvironmentFactory = kubernetes.NewEnvironment
+	dnssrvEnvironmentFactory = dnssrv.NewEnvironment
+	etcdEnvironmentFactory = etcd.NewEnvironment
 }
@@ -3,6 +3,9 @@ package servicecfg
 import (
 	"github.com/xmidt-org/webpa-common/service"
 	"github.com/xmidt-org/webpa-common/service/consul"
+	"github.com/xmidt-org/webpa-common/service/dnssrv"
+	"github.com/xmidt-org/webpa-common/service/etcd"
+	"github.com/xmidt-org/webpa-common/service/kubernetes"
 	"github.com/xmidt-org/webpa-common/service/zk"
 )
 
@@ -12,9 +15,50 @@ type Options struct {
 	DisableFilter bool   `json:"disableFilter"`
 	DefaultScheme string `json:"defaultScheme"`
 
-	Fixed     []string        `json:"fixed,omitempty"`
-	Zookeeper *zk.Options     `json:"zookeeper,omitempty"`
-	Consul    *consul.Options `json:"consul,omitempty"`
+	// LoadBoundFactor enables consistent hashing with bounded loads when set.  No instance is assigned more
+	// than this factor times the average number of keys.  Values not greater than 1.0 use service.DefaultLoadBoundFactor.
+	LoadBoundFactor float64 `json:"loadBoundFactor,omitempty"`
+
+	Fixed      []string            `json:"fixed,omitempty"`
+	Zookeeper  *zk.Options         `json:"zookeeper,omitempty"`
+	Consul     *consul.Options     `json:"consul,omitempty"`
+	Kubernetes *kubernetes.Options `json:"kubernetes,omitempty"`
+	DNSSRV     *dnssrv.Options     `json:"dnssrv,omitempty"`
+	Etcd       *etcd.Options       `json:"etcd,omitempty"`
+
+	// Composite merges the instances from several backends.  If set, the backends configured directly
+	// in these options are ignored.
+	Composite *Composite `json:"composite,omitempty"`
+}
+
+const (
+	// UnionStrategy merges the instances from all backends
+	UnionStrategy = "union"
+
+	// PriorityStrategy uses the instances from the first backend, in configuration order, that has any
+	PriorityStrategy = "priority"
+)
+
+// Composite describes an environment that merges the instances from several service discovery backends,
+// e.g. consul in one datacenter together with a fixed set of instances for another.
+type Composite struct {
+	// Strategy determines how instances from the backends are merged, and is either UnionStrategy or
+	// PriorityStrategy.  If unset, UnionStrategy is used.
+	Strategy string `json:"strategy,omitempty"`
+
+	// Backends are the service discovery backends to merge, in priority order.  Each backend is configured
+	// with one of the usual backend sections, e.g. "fixed" or "consul".  All the watches in a single backend
+	// are merged together, so each backend should watch the same service.  Registrations in each backend
+	// are honored.
+	Backends []Options `json:"backends,omitempty"`
+}
+
+func (c *Composite) strategy() string {
+	if c != nil && len(c.Strategy) > 0 {
+		return c.Strategy
+	}
+
+	return UnionStrategy
 }
 
 func (o *Options) vnodeCount() int {
@@ -25,6 +69,14 @@ func (o *Options) vnodeCount() int {
 	return service.DefaultVnodeCount
 }
 
+func (o *Options) loadBoundFactor() float64 {
+	if o != nil {
+		return o.LoadBoundFactor
+	}
+
+	return 0.0
+}
+
 func (o *Options) disableFilter() bool {
 	if o != nil {
 		return o.DisableFilter
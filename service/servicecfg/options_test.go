@@ -12,6 +12,7 @@ func testOptionsDefault(t *testing.T, o *Options) {
 	assert.Equal(service.DefaultVnodeCount, o.vnodeCount())
 	assert.False(o.disableFilter())
 	assert.Equal(service.DefaultScheme, o.defaultScheme())
+	assert.Zero(o.loadBoundFactor())
 }
 
 func testOptionsCustom(t *testing.T) {
@@ -22,12 +23,15 @@ func testOptionsCustom(t *testing.T) {
 			VnodeCount:    345234,
 			DisableFilter: true,
 			DefaultScheme: "ftp",
+
+			LoadBoundFactor: 1.5,
 		}
 	)
 
 	assert.Equal(345234, o.vnodeCount())
 	assert.True(o.disableFilter())
 	assert.Equal("ftp", o.defaultScheme())
+	assert.Equal(1.5, o.loadBoundFactor())
 }
 
 func TestOptions(t *testing.T) {
@@ -38,3 +42,10 @@ func TestOptions(t *testing.T) {
 
 	t.Run("Custom", testOptionsCustom)
 }
+
+func TestComposite(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(UnionStrategy, (*Composite)(nil).strategy())
+	assert.Equal(UnionStrategy, new(Composite).strategy())
+	assert.Equal(PriorityStrategy, (&Composite{Strategy: PriorityStrategy}).strategy())
+}
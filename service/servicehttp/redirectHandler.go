@@ -1,7 +1,9 @@
 package servicehttp
 
 import (
+	"encoding/json"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/go-kit/kit/log/level"
@@ -15,6 +17,26 @@ import (
 // The device.IDHashParser function is a valid KeyFunc, and is the typical one used by WebPA.
 type KeyFunc func(*http.Request) ([]byte, error)
 
+// PathPolicy determines how much of the original request URI is appended to the instance when redirecting
+type PathPolicy int
+
+const (
+	// PreserveRequestURI appends the original request URI, including any query, without trailing slashes.
+	// This is the default.
+	PreserveRequestURI PathPolicy = iota
+
+	// PreservePath appends the original request path, without trailing slashes, and drops any query
+	PreservePath
+
+	// DiscardPath redirects to the instance itself, ignoring the original request URI
+	DiscardPath
+)
+
+// redirectBody is the JSON body written when a RedirectHandler is configured to respond with JSON
+type redirectBody struct {
+	Location string `json:"location"`
+}
+
 // RedirectHandler is an http.Handler that redirects all incoming requests using a key obtained
 // from a request.  The Accessor is passed the key to return the appropriate instance to redirect to.
 type RedirectHandler struct {
@@ -26,6 +48,79 @@ type RedirectHandler struct {
 
 	// RedirectCode is the HTTP status code sent as part of the redirect.  If not set, http.StatusTemporaryRedirect is used.
 	RedirectCode int
+
+	// Scheme, if set, replaces the scheme of the instance, e.g. "https" to send clients to a secure endpoint.
+	// Instances without a scheme have this scheme added.
+	Scheme string
+
+	// StripPort removes any port from the instance, so that clients use the default port for the scheme
+	StripPort bool
+
+	// PathPolicy determines how much of the original request URI is appended to the instance
+	PathPolicy PathPolicy
+
+	// JSONResponse, if set, sends the redirect target in a JSON body of the form {"location": "..."} with a 200 status,
+	// rather than sending a redirect.  This is useful for clients that cannot follow redirects.  The Location header is
+	// still set.
+	JSONResponse bool
+}
+
+// rewrite applies any scheme and port changes to an instance
+func (rh *RedirectHandler) rewrite(instance string) (string, error) {
+	if len(rh.Scheme) == 0 && !rh.StripPort {
+		return instance, nil
+	}
+
+	hasScheme := strings.Contains(instance, "://")
+	if !hasScheme {
+		// parse the instance as a host, rather than a path
+		instance = "//" + instance
+	}
+
+	u, err := url.Parse(instance)
+	if err != nil {
+		return "", err
+	}
+
+	if len(rh.Scheme) > 0 {
+		u.Scheme = rh.Scheme
+	}
+
+	if rh.StripPort {
+		u.Host = u.Hostname()
+		if strings.Contains(u.Host, ":") {
+			// IPv6 addresses must be enclosed in brackets
+			u.Host = "[" + u.Host + "]"
+		}
+	}
+
+	rewritten := u.String()
+	if len(u.Scheme) == 0 {
+		rewritten = strings.TrimPrefix(rewritten, "//")
+	}
+
+	return rewritten, nil
+}
+
+// target produces the redirect target for an instance, given the original request
+func (rh *RedirectHandler) target(instance string, request *http.Request) (string, error) {
+	target, err := rh.rewrite(instance)
+	if err != nil {
+		return "", err
+	}
+
+	switch rh.PathPolicy {
+	case PreservePath:
+		target += strings.TrimRight(request.URL.EscapedPath(), "/")
+
+	case DiscardPath:
+		// the instance is used as is
+
+	default:
+		target += strings.TrimRight(request.RequestURI, "/")
+	}
+
+	return target, nil
 }
 
 func (rh *RedirectHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
@@ -44,13 +139,25 @@ func (rh *RedirectHandler) ServeHTTP(response http.ResponseWriter, request *http
 		return
 	}
 
-	instance += strings.TrimRight(request.RequestURI, "/")
-	ctxLogger.Log(level.Key(), level.DebugValue(), logging.MessageKey(), "redirecting", "instance", instance)
+	target, err := rh.target(instance, request)
+	if err != nil {
+		ctxLogger.Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "unable to produce redirect target", "instance", instance, logging.ErrorKey(), err)
+		http.Error(response, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	ctxLogger.Log(level.Key(), level.DebugValue(), logging.MessageKey(), "redirecting", "instance", target)
+	if rh.JSONResponse {
+		response.Header().Set("Content-Type", "application/json")
+		response.Header().Set("Location", target)
+		json.NewEncoder(response).Encode(redirectBody{Location: target})
+		return
+	}
 
 	code := rh.RedirectCode
 	if code < 300 {
 		code = http.StatusTemporaryRedirect
 	}
 
-	http.Redirect(response, request, instance, code)
+	http.Redirect(response, request, target, code)
 }
@@ -1,9 +1,11 @@
 package servicehttp
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -119,9 +121,129 @@ func testRedirectHandlerSuccessWithPath(t *testing.T) {
 	accessor.AssertExpectations(t)
 }
 
+func testRedirectHandlerTarget(t *testing.T) {
+	testData := []struct {
+		handler    RedirectHandler
+		instance   string
+		requestURI string
+		expected   string
+	}{
+		{RedirectHandler{}, "http://host.com:8080", "/api/v2/device?format=json", "http://host.com:8080/api/v2/device?format=json"},
+		{RedirectHandler{}, "http://host.com:8080", "/api/v2/device/", "http://host.com:8080/api/v2/device"},
+		{RedirectHandler{PathPolicy: PreservePath}, "http://host.com:8080", "/api/v2/device/?format=json", "http://host.com:8080/api/v2/device"},
+		{RedirectHandler{PathPolicy: DiscardPath}, "http://host.com:8080", "/api/v2/device?format=json", "http://host.com:8080"},
+		{RedirectHandler{Scheme: "https"}, "http://host.com:8080", "/api", "https://host.com:8080/api"},
+		{RedirectHandler{Scheme: "https"}, "host.com:8080", "/api", "https://host.com:8080/api"},
+		{RedirectHandler{StripPort: true}, "http://host.com:8080", "/api", "http://host.com/api"},
+		{RedirectHandler{StripPort: true}, "host.com:8080", "/api", "host.com/api"},
+		{RedirectHandler{Scheme: "https", StripPort: true}, "http://[::1]:8080", "/api", "https://[::1]/api"},
+		{RedirectHandler{Scheme: "https", StripPort: true, PathPolicy: DiscardPath}, "http://host.com:8080/base", "/api", "https://host.com/base"},
+	}
+
+	for i, record := range testData {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var (
+				assert  = assert.New(t)
+				request = httptest.NewRequest("GET", record.requestURI, nil)
+			)
+
+			actual, err := record.handler.target(record.instance, request)
+			assert.NoError(err)
+			assert.Equal(record.expected, actual)
+		})
+	}
+}
+
+func testRedirectHandlerInvalidInstance(t *testing.T) {
+	var (
+		assert = assert.New(t)
+
+		expectedKey = []byte("asdfqwer")
+		keyFunc     = func(*http.Request) ([]byte, error) { return expectedKey, nil }
+		accessor    = new(service.MockAccessor)
+
+		response = httptest.NewRecorder()
+		request  = httptest.NewRequest("GET", "/", nil)
+
+		handler = RedirectHandler{
+			KeyFunc:   keyFunc,
+			Accessor:  accessor,
+			StripPort: true,
+		}
+	)
+
+	accessor.On("Get", expectedKey).Return("http://host.com:8080/%zz", error(nil)).Once()
+	handler.ServeHTTP(response, request)
+
+	assert.Equal(http.StatusInternalServerError, response.Code)
+	accessor.AssertExpectations(t)
+}
+
+func testRedirectHandlerFound(t *testing.T) {
+	var (
+		assert = assert.New(t)
+
+		expectedKey = []byte("asdfqwer")
+		keyFunc     = func(*http.Request) ([]byte, error) { return expectedKey, nil }
+		accessor    = new(service.MockAccessor)
+
+		response = httptest.NewRecorder()
+		request  = httptest.NewRequest("GET", "/api/v2/device", nil)
+
+		handler = RedirectHandler{
+			KeyFunc:      keyFunc,
+			Accessor:     accessor,
+			RedirectCode: http.StatusFound,
+			Scheme:       "https",
+		}
+	)
+
+	accessor.On("Get", expectedKey).Return("http://ahost123.com:324", error(nil)).Once()
+	handler.ServeHTTP(response, request)
+
+	assert.Equal(http.StatusFound, response.Code)
+	assert.Equal("https://ahost123.com:324/api/v2/device", response.HeaderMap.Get("Location"))
+	accessor.AssertExpectations(t)
+}
+
+func testRedirectHandlerJSONResponse(t *testing.T) {
+	var (
+		assert = assert.New(t)
+
+		expectedKey = []byte("asdfqwer")
+		keyFunc     = func(*http.Request) ([]byte, error) { return expectedKey, nil }
+		accessor    = new(service.MockAccessor)
+
+		response = httptest.NewRecorder()
+		request  = httptest.NewRequest("GET", "/api/v2/device", nil)
+
+		handler = RedirectHandler{
+			KeyFunc:      keyFunc,
+			Accessor:     accessor,
+			JSONResponse: true,
+		}
+
+		body redirectBody
+	)
+
+	accessor.On("Get", expectedKey).Return("https://ahost123.com:324", error(nil)).Once()
+	handler.ServeHTTP(response, request)
+
+	assert.Equal(http.StatusOK, response.Code)
+	assert.Equal("application/json", response.HeaderMap.Get("Content-Type"))
+	assert.Equal("https://ahost123.com:324/api/v2/device", response.HeaderMap.Get("Location"))
+	assert.NoError(json.Unmarshal(response.Body.Bytes(), &body))
+	assert.Equal("https://ahost123.com:324/api/v2/device", body.Location)
+	accessor.AssertExpectations(t)
+}
+
 func TestRedirectHandler(t *testing.T) {
 	t.Run("KeyFuncError", testRedirectHandlerKeyFuncError)
 	t.Run("AccessorError", testRedirectHandlerAccessorError)
 	t.Run("Success", testRedirectHandlerSuccess)
 	t.Run("SuccessPath", testRedirectHandlerSuccessWithPath)
+	t.Run("Target", testRedirectHandlerTarget)
+	t.Run("InvalidInstance", testRedirectHandlerInvalidInstance)
+	t.Run("Found", testRedirectHandlerFound)
+	t.Run("JSONResponse", testRedirectHandlerJSONResponse)
 }
@@ -0,0 +1,61 @@
+package servicehttp
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/xmidt-org/webpa-common/device"
+	"github.com/xmidt-org/wrp-go/v3"
+)
+
+var (
+	// ErrMissingWRPBody is returned by WRPDestinationKey when the request has no body
+	ErrMissingWRPBody = errors.New("missing WRP message body")
+
+	// ErrMissingWRPDestination is returned by WRPDestinationKey when the WRP message has no destination
+	ErrMissingWRPDestination = errors.New("missing WRP destination")
+)
+
+// WRPDestinationKey is a KeyFunc that decodes the WRP message in the request body and uses the
+// device id in its destination as the hash key.  The Content-Type header selects the WRP format,
+// with msgpack as the default.  Any service name or path after the device id in the destination is ignored,
+// so that "mac:112233445566/config" and "mac:112233445566" produce the same key.
+//
+// The request body is buffered and restored, so that it can still be read after this function returns.
+func WRPDestinationKey(request *http.Request) ([]byte, error) {
+	if request.Body == nil {
+		return nil, ErrMissingWRPBody
+	}
+
+	contents, err := ioutil.ReadAll(request.Body)
+	request.Body.Close()
+	request.Body = ioutil.NopCloser(bytes.NewReader(contents))
+	if err != nil {
+		return nil, err
+	} else if len(contents) == 0 {
+		return nil, ErrMissingWRPBody
+	}
+
+	format, err := wrp.FormatFromContentType(request.Header.Get("Content-Type"), wrp.Msgpack)
+	if err != nil {
+		return nil, err
+	}
+
+	var message wrp.Message
+	if err := wrp.NewDecoderBytes(contents, format).Decode(&message); err != nil {
+		return nil, err
+	}
+
+	if len(message.Destination) == 0 {
+		return nil, ErrMissingWRPDestination
+	}
+
+	id, err := device.ParseID(message.Destination)
+	if err != nil {
+		return nil, err
+	}
+
+	return id.Bytes(), nil
+}
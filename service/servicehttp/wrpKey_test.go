@@ -0,0 +1,144 @@
+package servicehttp
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/device"
+	"github.com/xmidt-org/wrp-go/v3"
+)
+
+func testWRPDestinationKeyNoBody(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		request = httptest.NewRequest("POST", "/", nil)
+	)
+
+	request.Body = nil
+	key, err := WRPDestinationKey(request)
+	assert.Nil(key)
+	assert.Equal(ErrMissingWRPBody, err)
+
+	key, err = WRPDestinationKey(httptest.NewRequest("POST", "/", nil))
+	assert.Nil(key)
+	assert.Equal(ErrMissingWRPBody, err)
+}
+
+func testWRPDestinationKeySuccess(t *testing.T, format wrp.Format, destination string) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		expected, _ = device.ParseID("mac:112233445566")
+		contents    = wrp.MustEncode(
+			&wrp.Message{
+				Type:        wrp.SimpleRequestResponseMessageType,
+				Source:      "dns:scytale.example.com",
+				Destination: destination,
+			},
+			format,
+		)
+
+		request = httptest.NewRequest("POST", "/", bytes.NewReader(contents))
+	)
+
+	request.Header.Set("Content-Type", format.ContentType())
+	key, err := WRPDestinationKey(request)
+	require.NoError(err)
+	assert.Equal(expected.Bytes(), key)
+
+	// the body must still be readable
+	body, err := ioutil.ReadAll(request.Body)
+	require.NoError(err)
+	assert.Equal(contents, body)
+}
+
+func testWRPDestinationKeyDefaultFormat(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		expected, _ = device.ParseID("mac:112233445566")
+		request     = httptest.NewRequest(
+			"POST",
+			"/",
+			bytes.NewReader(wrp.MustEncode(&wrp.Message{Type: wrp.SimpleEventMessageType, Destination: "mac:112233445566/event"}, wrp.Msgpack)),
+		)
+	)
+
+	key, err := WRPDestinationKey(request)
+	require.NoError(err)
+	assert.Equal(expected.Bytes(), key)
+}
+
+func testWRPDestinationKeyMissingDestination(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		request = httptest.NewRequest("POST", "/", strings.NewReader(`{"msg_type": 3, "source": "dns:scytale.example.com"}`))
+	)
+
+	request.Header.Set("Content-Type", wrp.JSON.ContentType())
+	key, err := WRPDestinationKey(request)
+	assert.Nil(key)
+	assert.Equal(ErrMissingWRPDestination, err)
+}
+
+func testWRPDestinationKeyInvalidDestination(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		request = httptest.NewRequest("POST", "/", strings.NewReader(`{"msg_type": 3, "dest": "this is not valid"}`))
+	)
+
+	request.Header.Set("Content-Type", wrp.JSON.ContentType())
+	key, err := WRPDestinationKey(request)
+	assert.Nil(key)
+	assert.Equal(device.ErrorInvalidDeviceName, err)
+}
+
+func testWRPDestinationKeyInvalidMessage(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		request = httptest.NewRequest("POST", "/", strings.NewReader(`this is not JSON`))
+	)
+
+	request.Header.Set("Content-Type", wrp.JSON.ContentType())
+	key, err := WRPDestinationKey(request)
+	assert.Nil(key)
+	assert.Error(err)
+}
+
+func testWRPDestinationKeyInvalidContentType(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		request = httptest.NewRequest("POST", "/", strings.NewReader(`{"msg_type": 3, "dest": "mac:112233445566"}`))
+	)
+
+	request.Header.Set("Content-Type", "text/plain")
+	key, err := WRPDestinationKey(request)
+	assert.Nil(key)
+	assert.Error(err)
+}
+
+func TestWRPDestinationKey(t *testing.T) {
+	t.Run("NoBody", testWRPDestinationKeyNoBody)
+	t.Run("Success", func(t *testing.T) {
+		for _, format := range wrp.AllFormats() {
+			for _, destination := range []string{"mac:112233445566", "mac:11-22-33-44-55-66/config", "MAC:112233445566/service/path"} {
+				t.Run(format.String()+"/"+destination, func(t *testing.T) {
+					testWRPDestinationKeySuccess(t, format, destination)
+				})
+			}
+		}
+	})
+
+	t.Run("DefaultFormat", testWRPDestinationKeyDefaultFormat)
+	t.Run("MissingDestination", testWRPDestinationKeyMissingDestination)
+	t.Run("InvalidDestination", testWRPDestinationKeyInvalidDestination)
+	t.Run("InvalidMessage", testWRPDestinationKeyInvalidMessage)
+	t.Run("InvalidContentType", testWRPDestinationKeyInvalidContentType)
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"strconv"
+
+	"github.com/billhathaway/consistentHash"
+)
+
+// weightedRing is a consistent hash ring in which an instance that appears more than once in the
+// set of instances is given proportionally more vnodes.  This is how service discovery backends
+// express weights, e.g. consul's service weights.
+//
+// The first copy of each instance is added to the ring under its own name, so a ring with no
+// duplicate instances is identical to an unweighted ring.  Each further copy is added under an alias.
+type weightedRing struct {
+	hasher *consistentHash.ConsistentHash
+
+	// aliases maps each alias to its instance
+	aliases map[string]string
+
+	// weights is the number of copies of each instance
+	weights map[string]int
+
+	// total is the sum of the weights
+	total int
+}
+
+// instanceAlias returns the name of the given copy of an instance.  Since instances are URIs without fragments,
+// this cannot collide with another instance.
+func instanceAlias(instance string, copy int) string {
+	return instance + "#" + strconv.Itoa(copy)
+}
+
+func newWeightedRing(vnodeCount int, instances []string) *weightedRing {
+	r := &weightedRing{
+		hasher:  consistentHash.New(),
+		aliases: make(map[string]string),
+		weights: make(map[string]int, len(instances)),
+		total:   len(instances),
+	}
+
+	r.hasher.SetVnodeCount(vnodeCount)
+	for _, i := range instances {
+		copy := r.weights[i]
+		r.weights[i]++
+		if copy == 0 {
+			r.hasher.Add(i)
+		} else {
+			alias := instanceAlias(i, copy)
+			r.aliases[alias] = i
+			r.hasher.Add(alias)
+		}
+	}
+
+	return r
+}
+
+// instance returns the instance for a ring node, which may be an alias
+func (r *weightedRing) instance(node string) string {
+	if i, ok := r.aliases[node]; ok {
+		return i
+	}
+
+	return node
+}
+
+// Get returns the instance that owns the given key
+func (r *weightedRing) Get(key []byte) (string, error) {
+	node, err := r.hasher.Get(key)
+	if err != nil {
+		return "", err
+	}
+
+	return r.instance(node), nil
+}
+
+// candidates returns each distinct instance in ring order, starting at the owner of the given key
+func (r *weightedRing) candidates(key []byte) ([]string, error) {
+	nodes, err := r.hasher.GetN(key, r.total)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		candidates = make([]string, 0, len(r.weights))
+		seen       = make(map[string]bool, len(r.weights))
+	)
+
+	for _, node := range nodes {
+		if i := r.instance(node); !seen[i] {
+			seen[i] = true
+			candidates = append(candidates, i)
+		}
+	}
+
+	return candidates, nil
+}
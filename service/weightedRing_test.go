@@ -0,0 +1,74 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testWeightedRingUnweighted(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		instances  = []string{"http://instance1:8080", "http://instance2:8080", "http://instance3:8080"}
+		ring       = newWeightedRing(DefaultVnodeCount, instances)
+		consistent = newConsistentAccessor(DefaultVnodeCount, instances)
+	)
+
+	assert.Empty(ring.aliases)
+	assert.Equal(3, ring.total)
+
+	// with no duplicates, the ring is the same as an unweighted ring
+	for _, k := range boundedKeys(100) {
+		expected, err := consistent.Get(k)
+		require.NoError(err)
+
+		actual, err := ring.Get(k)
+		require.NoError(err)
+		assert.Equal(expected, actual)
+	}
+}
+
+func testWeightedRingWeighted(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		ring = newWeightedRing(DefaultVnodeCount, []string{
+			"http://instance1:8080",
+			"http://instance2:8080", "http://instance2:8080", "http://instance2:8080",
+		})
+
+		counts = make(map[string]int)
+		keys   = boundedKeys(4000)
+	)
+
+	assert.Equal(map[string]int{"http://instance1:8080": 1, "http://instance2:8080": 3}, ring.weights)
+	assert.Equal(4, ring.total)
+	assert.Len(ring.aliases, 2)
+
+	for _, k := range keys {
+		instance, err := ring.Get(k)
+		require.NoError(err)
+		counts[instance]++
+	}
+
+	// aliases are never returned, and the heavier instance receives about 3 times the keys
+	assert.Len(counts, 2)
+	assert.InDelta(3.0, float64(counts["http://instance2:8080"])/float64(counts["http://instance1:8080"]), 0.6)
+
+	candidates, err := ring.candidates([]byte("test"))
+	require.NoError(err)
+	assert.ElementsMatch([]string{"http://instance1:8080", "http://instance2:8080"}, candidates)
+
+	owner, err := ring.Get([]byte("test"))
+	require.NoError(err)
+	assert.Equal(owner, candidates[0])
+}
+
+func TestWeightedRing(t *testing.T) {
+	t.Run("Unweighted", testWeightedRingUnweighted)
+	t.Run("Weighted", testWeightedRingWeighted)
+}
@@ -0,0 +1,187 @@
+package zk
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	gokitzk "github.com/go-kit/kit/sd/zk"
+	"github.com/samuel/go-zookeeper/zk"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+// tlsClientConfig holds the settings for a client that connects to Zookeeper over TLS
+type tlsClientConfig struct {
+	tls            *tls.Config
+	connectTimeout time.Duration
+	sessionTimeout time.Duration
+	username       string
+	password       string
+	acl            []zk.ACL
+}
+
+// tlsClientFactory is the factory function used to create a Client which connects over TLS.
+// The go-kit client always dials in plaintext, so TLS connections use this package's own client.
+// Tests can change this for mocked behavior.
+var tlsClientFactory = newTLSClient
+
+// zkLogger adapts a go-kit logger to the zookeeper library's logging interface
+type zkLogger struct {
+	log.Logger
+}
+
+func (zl zkLogger) Printf(format string, args ...interface{}) {
+	zl.Log(level.Key(), level.InfoValue(), logging.MessageKey(), fmt.Sprintf(format, args...))
+}
+
+// newTLSDialer produces a zookeeper dialer which establishes TLS connections
+func newTLSDialer(config *tls.Config, connectTimeout time.Duration) zk.Dialer {
+	return func(network, address string, _ time.Duration) (net.Conn, error) {
+		return tls.DialWithDialer(&net.Dialer{Timeout: connectTimeout}, network, address, config)
+	}
+}
+
+// tlsClient is a go-kit zookeeper Client which connects over TLS.  Its behavior is the same as
+// the go-kit client, except that Deregister removes the node created by Register.
+type tlsClient struct {
+	*zk.Conn
+	logger log.Logger
+	acl    []zk.ACL
+
+	quit     chan struct{}
+	stopOnce sync.Once
+
+	lock  sync.Mutex
+	nodes map[*gokitzk.Service]string
+}
+
+func newTLSClient(servers []string, l log.Logger, cfg tlsClientConfig) (gokitzk.Client, error) {
+	conn, events, err := zk.Connect(
+		servers,
+		cfg.sessionTimeout,
+		zk.WithLogger(zkLogger{l}),
+		zk.WithDialer(newTLSDialer(cfg.tls, cfg.connectTimeout)),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cfg.username) > 0 {
+		if err := conn.AddAuth("digest", []byte(cfg.username+":"+cfg.password)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	acl := cfg.acl
+	if len(acl) == 0 {
+		acl = gokitzk.DefaultACL
+	}
+
+	c := &tlsClient{
+		Conn:   conn,
+		logger: l,
+		acl:    acl,
+		quit:   make(chan struct{}),
+		nodes:  make(map[*gokitzk.Service]string),
+	}
+
+	go c.handleEvents(events)
+	return c, nil
+}
+
+func (c *tlsClient) handleEvents(events <-chan zk.Event) {
+	for {
+		select {
+		case e := <-events:
+			c.logger.Log(level.Key(), level.DebugValue(), "eventType", e.Type.String(), "server", e.Server, "state", e.State.String(), logging.ErrorKey(), e.Err)
+
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+func (c *tlsClient) GetEntries(path string) ([]string, <-chan zk.Event, error) {
+	znodes, _, events, err := c.ChildrenW(path)
+	if err != nil {
+		return nil, events, err
+	}
+
+	var entries []string
+	for _, znode := range znodes {
+		if data, _, err := c.Get(path + "/" + znode); err == nil {
+			entries = append(entries, string(data))
+		}
+	}
+
+	return entries, events, nil
+}
+
+func (c *tlsClient) CreateParentNodes(path string) error {
+	if !strings.HasPrefix(path, "/") {
+		return zk.ErrInvalidPath
+	}
+
+	var parent string
+	for _, node := range strings.Split(path[1:], "/") {
+		parent += "/" + node
+
+		// it's fine for the node to already exist, or for this client to only have read access
+		if _, err := c.Create(parent, []byte{}, 0, c.acl); err != nil && err != zk.ErrNodeExists && err != zk.ErrNoAuth {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *tlsClient) Register(s *gokitzk.Service) error {
+	path := strings.TrimSuffix(s.Path, "/") + "/" + s.Name
+	if err := c.CreateParentNodes(path); err != nil {
+		return err
+	}
+
+	node, err := c.CreateProtectedEphemeralSequential(path+"/", s.Data, c.acl)
+	if err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	c.nodes[s] = node
+	c.lock.Unlock()
+	return nil
+}
+
+func (c *tlsClient) Deregister(s *gokitzk.Service) error {
+	c.lock.Lock()
+	node, ok := c.nodes[s]
+	delete(c.nodes, s)
+	c.lock.Unlock()
+
+	if !ok {
+		return gokitzk.ErrNotRegistered
+	}
+
+	found, stat, err := c.Exists(node)
+	if err != nil {
+		return err
+	} else if !found {
+		return gokitzk.ErrNodeNotFound
+	}
+
+	return c.Delete(node, stat.Version)
+}
+
+func (c *tlsClient) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.quit)
+		c.Close()
+	})
+}
@@ -0,0 +1,169 @@
+package zk
+
+import (
+	"crypto/tls"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	gokitzk "github.com/go-kit/kit/sd/zk"
+	"github.com/samuel/go-zookeeper/zk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+// startTLSListener starts a TLS listener on localhost that performs the server side of the TLS handshake
+// for each accepted connection, then closes it.  The result of each handshake is sent on the returned channel.
+func startTLSListener(t *testing.T) (net.Listener, *tls.Config, <-chan error) {
+	require := require.New(t)
+
+	dir, certificateFile, keyFile := writeTestCertificate(t)
+	defer os.RemoveAll(dir)
+
+	serverConfig, err := (&TLS{CertificateFile: certificateFile, KeyFile: keyFile}).newConfig()
+	require.NoError(err)
+
+	clientConfig, err := (&TLS{CAFile: certificateFile}).newConfig()
+	require.NoError(err)
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	require.NoError(err)
+
+	handshakes := make(chan error, 10)
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			c.SetDeadline(time.Now().Add(5 * time.Second))
+			select {
+			case handshakes <- c.(*tls.Conn).Handshake():
+			default:
+			}
+
+			c.Close()
+		}
+	}()
+
+	return l, clientConfig, handshakes
+}
+
+func testNewTLSDialer(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		l, config, handshakes = startTLSListener(t)
+	)
+
+	defer l.Close()
+
+	c, err := newTLSDialer(config, 5*time.Second)("tcp", l.Addr().String(), time.Second)
+	require.NoError(err)
+	require.NotNil(c)
+	defer c.Close()
+
+	select {
+	case err := <-handshakes:
+		assert.NoError(err)
+	case <-time.After(5 * time.Second):
+		assert.Fail("No TLS handshake occurred")
+	}
+
+	state := c.(*tls.Conn).ConnectionState()
+	assert.True(state.HandshakeComplete)
+}
+
+func testNewTLSDialerUntrusted(t *testing.T) {
+	var (
+		assert = assert.New(t)
+
+		l, _, _ = startTLSListener(t)
+	)
+
+	defer l.Close()
+
+	// the system roots do not include the test certificate
+	c, err := newTLSDialer(new(tls.Config), 5*time.Second)("tcp", l.Addr().String(), time.Second)
+	assert.Nil(c)
+	assert.Error(err)
+}
+
+func TestNewTLSDialer(t *testing.T) {
+	t.Run("Trusted", testNewTLSDialer)
+	t.Run("Untrusted", testNewTLSDialerUntrusted)
+}
+
+func testNewTLSClientConnect(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		l, config, handshakes = startTLSListener(t)
+	)
+
+	defer l.Close()
+
+	c, err := newTLSClient(
+		[]string{l.Addr().String()},
+		logging.NewTestLogger(nil, t),
+		tlsClientConfig{
+			tls:            config,
+			connectTimeout: 5 * time.Second,
+			sessionTimeout: 10 * time.Second,
+		},
+	)
+
+	require.NoError(err)
+	require.NotNil(c)
+
+	// the zookeeper connection is established in the background
+	select {
+	case err := <-handshakes:
+		assert.NoError(err)
+	case <-time.After(5 * time.Second):
+		assert.Fail("No TLS handshake occurred")
+	}
+
+	assert.Equal(gokitzk.DefaultACL, c.(*tlsClient).acl)
+
+	c.Stop()
+	c.Stop() // idempotent
+}
+
+func testNewTLSClientACL(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		acl = zk.DigestACL(zk.PermAll, "user", "password")
+	)
+
+	c, err := newTLSClient(
+		[]string{"127.0.0.1:1"},
+		logging.NewTestLogger(nil, t),
+		tlsClientConfig{
+			tls:            new(tls.Config),
+			connectTimeout: time.Second,
+			sessionTimeout: time.Second,
+			acl:            acl,
+		},
+	)
+
+	require.NoError(err)
+	require.NotNil(c)
+	defer c.Stop()
+
+	assert.Equal(acl, c.(*tlsClient).acl)
+	assert.Equal(zk.ErrInvalidPath, c.CreateParentNodes("relative/path"))
+	assert.Equal(gokitzk.ErrNotRegistered, c.Deregister(&gokitzk.Service{Path: "/test", Name: "test"}))
+}
+
+func TestNewTLSClient(t *testing.T) {
+	t.Run("Connect", testNewTLSClientConnect)
+	t.Run("ACL", testNewTLSClientACL)
+}
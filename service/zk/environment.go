@@ -29,12 +29,46 @@ var clientFactory = gokitzk.NewClient
 
 func newClient(l log.Logger, zo Options) (gokitzk.Client, error) {
 	client := zo.client()
-	return clientFactory(
-		client.servers(),
-		l,
+	acl, err := client.acl()
+	if err != nil {
+		return nil, err
+	}
+
+	username, password, authenticated := client.credentials()
+	if t := client.tls(); t != nil {
+		config, err := t.newConfig()
+		if err != nil {
+			return nil, err
+		}
+
+		return tlsClientFactory(
+			client.servers(),
+			l,
+			tlsClientConfig{
+				tls:            config,
+				connectTimeout: client.connectTimeout(),
+				sessionTimeout: client.sessionTimeout(),
+				username:       username,
+				password:       password,
+				acl:            acl,
+			},
+		)
+	}
+
+	options := []gokitzk.Option{
 		gokitzk.ConnectTimeout(client.connectTimeout()),
 		gokitzk.SessionTimeout(client.sessionTimeout()),
-	)
+	}
+
+	if authenticated {
+		options = append(options, gokitzk.Credentials(username, password))
+	}
+
+	if len(acl) > 0 {
+		options = append(options, gokitzk.ACL(acl))
+	}
+
+	return clientFactory(client.servers(), l, options...)
 }
 
 func newInstancer(l log.Logger, c gokitzk.Client, path string) (i sd.Instancer, err error) {
@@ -2,6 +2,7 @@ package zk
 
 import (
 	"errors"
+	"reflect"
 	"testing"
 
 	"github.com/go-kit/kit/log"
@@ -172,9 +173,150 @@ func testNewEnvironmentFull(t *testing.T) {
 	client.AssertExpectations(t)
 }
 
+func testNewEnvironmentCredentials(t *testing.T) {
+	defer resetClientFactory()
+
+	var (
+		assert = assert.New(t)
+
+		clientFactory       = prepareMockClientFactory()
+		expectedClientError = errors.New("expected client error")
+
+		zo = Options{
+			Client: Client{
+				Connection: "zookeeper.example.com:2181",
+				Username:   "user",
+				Password:   "password",
+				ACL:        []ACL{{Scheme: AuthScheme}},
+			},
+			Watches: []string{"/some/where"},
+		}
+	)
+
+	// the go-kit client is used, with the credentials and ACL options added
+	clientFactory.On("NewClient",
+		[]string{"zookeeper.example.com:2181"},
+		mock.MatchedBy(func(l log.Logger) bool { return l != nil }),
+		mock.MatchedBy(func(o []gokitzk.Option) bool { return len(o) == 4 }),
+	).Return(nil, expectedClientError).Once()
+
+	e, actualClientError := NewEnvironment(nil, zo)
+	assert.Nil(e)
+	assert.Equal(expectedClientError, actualClientError)
+
+	clientFactory.AssertExpectations(t)
+}
+
+func testNewEnvironmentInvalidACL(t *testing.T) {
+	defer resetClientFactory()
+
+	var (
+		assert = assert.New(t)
+
+		clientFactory    = prepareMockClientFactory()
+		tlsClientFactory = prepareMockTLSClientFactory()
+
+		zo = Options{
+			Client: Client{
+				ACL: []ACL{{Scheme: "nosuch"}},
+			},
+			Watches: []string{"/some/where"},
+		}
+	)
+
+	e, err := NewEnvironment(nil, zo)
+	assert.Nil(e)
+	assert.Error(err)
+
+	clientFactory.AssertExpectations(t)
+	tlsClientFactory.AssertExpectations(t)
+}
+
+func testNewEnvironmentInvalidTLS(t *testing.T) {
+	defer resetClientFactory()
+
+	var (
+		assert = assert.New(t)
+
+		clientFactory    = prepareMockClientFactory()
+		tlsClientFactory = prepareMockTLSClientFactory()
+
+		zo = Options{
+			Client: Client{
+				TLS: &TLS{CAFile: "/no/such/file.pem"},
+			},
+			Watches: []string{"/some/where"},
+		}
+	)
+
+	e, err := NewEnvironment(nil, zo)
+	assert.Nil(e)
+	assert.Error(err)
+
+	clientFactory.AssertExpectations(t)
+	tlsClientFactory.AssertExpectations(t)
+}
+
+func testNewEnvironmentTLS(t *testing.T) {
+	defer resetClientFactory()
+
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		logger           = logging.NewTestLogger(nil, t)
+		clientFactory    = prepareMockClientFactory()
+		tlsClientFactory = prepareMockTLSClientFactory()
+		client           = new(mockClient)
+		zkEvents         = make(chan zk.Event, 5)
+
+		zo = Options{
+			Client: Client{
+				Connection: "zookeeper.example.com:2281",
+				Username:   "user",
+				Password:   "password",
+				ACL:        []ACL{{Scheme: AuthScheme, Permissions: []string{"all"}}},
+				TLS:        &TLS{ServerName: "zookeeper.example.com"},
+			},
+			Watches: []string{"/test"},
+		}
+	)
+
+	tlsClientFactory.On("NewClient",
+		[]string{"zookeeper.example.com:2281"},
+		logger,
+		mock.MatchedBy(func(cfg tlsClientConfig) bool {
+			return cfg.tls != nil &&
+				cfg.tls.ServerName == "zookeeper.example.com" &&
+				cfg.connectTimeout == DefaultConnectTimeout &&
+				cfg.sessionTimeout == DefaultSessionTimeout &&
+				cfg.username == "user" &&
+				cfg.password == "password" &&
+				reflect.DeepEqual(zk.AuthACL(zk.PermAll), cfg.acl)
+		}),
+	).Return(client, error(nil)).Once()
+
+	client.On("CreateParentNodes", "/test").Return(error(nil)).Once()
+	client.On("GetEntries", "/test").Return([]string{"instance1"}, (<-chan zk.Event)(zkEvents), error(nil)).Once()
+	client.On("Stop").Once()
+
+	e, err := NewEnvironment(logger, zo)
+	require.NoError(err)
+	require.NotNil(e)
+	assert.NoError(e.Close())
+
+	clientFactory.AssertExpectations(t)
+	tlsClientFactory.AssertExpectations(t)
+	client.AssertExpectations(t)
+}
+
 func TestNewEnvironment(t *testing.T) {
 	t.Run("Empty", testNewEnvironmentEmpty)
 	t.Run("ClientError", testNewEnvironmentClientError)
 	t.Run("InstancerError", testNewEnvironmentInstancerError)
 	t.Run("Full", testNewEnvironmentFull)
+	t.Run("Credentials", testNewEnvironmentCredentials)
+	t.Run("InvalidACL", testNewEnvironmentInvalidACL)
+	t.Run("InvalidTLS", testNewEnvironmentInvalidTLS)
+	t.Run("TLS", testNewEnvironmentTLS)
 }
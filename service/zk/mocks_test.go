@@ -11,6 +11,7 @@ import (
 // to its original value.  This function is handy as a defer for tests.
 func resetClientFactory() {
 	clientFactory = gokitzk.NewClient
+	tlsClientFactory = newTLSClient
 }
 
 // prepareMockClientFactory creates a new mockClientFactory and sets up this package
@@ -32,6 +33,25 @@ func (m *mockClientFactory) NewClient(servers []string, logger log.Logger, optio
 	return first, arguments.Error(1)
 }
 
+// prepareMockTLSClientFactory creates a new mockTLSClientFactory and sets up this package
+// to use it.
+func prepareMockTLSClientFactory() *mockTLSClientFactory {
+	m := new(mockTLSClientFactory)
+	tlsClientFactory = m.NewClient
+	return m
+}
+
+type mockTLSClientFactory struct {
+	mock.Mock
+}
+
+func (m *mockTLSClientFactory) NewClient(servers []string, logger log.Logger, cfg tlsClientConfig) (gokitzk.Client, error) {
+	arguments := m.Called(servers, logger, cfg)
+
+	first, _ := arguments.Get(0).(gokitzk.Client)
+	return first, arguments.Error(1)
+}
+
 type mockClient struct {
 	mock.Mock
 }
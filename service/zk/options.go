@@ -3,6 +3,8 @@ package zk
 import (
 	"strings"
 	"time"
+
+	"github.com/samuel/go-zookeeper/zk"
 )
 
 const (
@@ -89,6 +91,20 @@ type Client struct {
 
 	// SessionTimeout is the Zookeeper session timeout.
 	SessionTimeout time.Duration `json:"sessionTimeout"`
+
+	// Username is the user for digest authentication.  If both this and Password are set,
+	// the client authenticates with the digest scheme.  Otherwise, the client is anonymous.
+	Username string `json:"username,omitempty"`
+
+	// Password is the password for digest authentication.
+	Password string `json:"password,omitempty"`
+
+	// ACL is the access control list applied to each znode this client creates.  If not supplied,
+	// created znodes are open to the world.
+	ACL []ACL `json:"acl,omitempty"`
+
+	// TLS holds the TLS connection options.  If not supplied, connections to Zookeeper are in plaintext.
+	TLS *TLS `json:"tls,omitempty"`
 }
 
 func (c *Client) servers() []string {
@@ -129,6 +145,30 @@ func (c *Client) sessionTimeout() time.Duration {
 	return DefaultSessionTimeout
 }
 
+func (c *Client) credentials() (string, string, bool) {
+	if c != nil && len(c.Username) > 0 && len(c.Password) > 0 {
+		return c.Username, c.Password, true
+	}
+
+	return "", "", false
+}
+
+func (c *Client) acl() ([]zk.ACL, error) {
+	if c != nil && len(c.ACL) > 0 {
+		return newACL(c.ACL)
+	}
+
+	return nil, nil
+}
+
+func (c *Client) tls() *TLS {
+	if c != nil {
+		return c.TLS
+	}
+
+	return nil
+}
+
 // Options represents the set of configurable attributes for Zookeeper
 type Options struct {
 	// Client holds the zookeeper client options
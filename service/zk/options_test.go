@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/samuel/go-zookeeper/zk"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -50,6 +51,17 @@ func testClientDefault(t *testing.T, c *Client) {
 	assert.Equal([]string{DefaultServer}, c.servers())
 	assert.Equal(DefaultConnectTimeout, c.connectTimeout())
 	assert.Equal(DefaultSessionTimeout, c.sessionTimeout())
+
+	username, password, ok := c.credentials()
+	assert.Empty(username)
+	assert.Empty(password)
+	assert.False(ok)
+
+	acl, err := c.acl()
+	assert.Empty(acl)
+	assert.NoError(err)
+
+	assert.Nil(c.tls())
 }
 
 func testClientCustom(t *testing.T) {
@@ -60,12 +72,35 @@ func testClientCustom(t *testing.T) {
 			Servers:        []string{"somewhere.com:8888"},
 			ConnectTimeout: 13 * time.Hour,
 			SessionTimeout: 1239 * time.Minute,
+			Username:       "user",
+			Password:       "password",
+			ACL:            []ACL{{Scheme: AuthScheme, Permissions: []string{"read", "write"}}},
+			TLS:            &TLS{ServerName: "zookeeper.example.com"},
 		}
 	)
 
 	assert.Equal([]string{"localhost:1234", "somewhere.com:8888"}, c.servers())
 	assert.Equal(13*time.Hour, c.connectTimeout())
 	assert.Equal(1239*time.Minute, c.sessionTimeout())
+
+	username, password, ok := c.credentials()
+	assert.Equal("user", username)
+	assert.Equal("password", password)
+	assert.True(ok)
+
+	acl, err := c.acl()
+	assert.Equal(zk.AuthACL(zk.PermRead|zk.PermWrite), acl)
+	assert.NoError(err)
+
+	assert.Equal(&TLS{ServerName: "zookeeper.example.com"}, c.tls())
+}
+
+func testClientPartialCredentials(t *testing.T) {
+	assert := assert.New(t)
+	for _, c := range []Client{{Username: "user"}, {Password: "password"}} {
+		_, _, ok := c.credentials()
+		assert.False(ok)
+	}
 }
 
 func TestClient(t *testing.T) {
@@ -75,6 +110,7 @@ func TestClient(t *testing.T) {
 	})
 
 	t.Run("Custom", testClientCustom)
+	t.Run("PartialCredentials", testClientPartialCredentials)
 }
 
 func testOptionsDefault(t *testing.T, o *Options) {
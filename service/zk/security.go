@@ -0,0 +1,172 @@
+package zk
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+const (
+	// WorldScheme is the ACL scheme that grants permissions to everyone.  Its only ID is "anyone".
+	WorldScheme = "world"
+
+	// AuthScheme is the ACL scheme that grants permissions to any user this client has authenticated as.
+	AuthScheme = "auth"
+
+	// DigestScheme is the ACL scheme that grants permissions to a user and password.  Its ID is of the form
+	// user:password, with the password in the clear.  The password is hashed before being sent to Zookeeper.
+	DigestScheme = "digest"
+
+	// IPScheme is the ACL scheme that grants permissions to an IP address or CIDR block.
+	IPScheme = "ip"
+)
+
+var (
+	errNoCACertificates = errors.New("no CA certificates found")
+
+	permissions = map[string]int32{
+		"read":   zk.PermRead,
+		"write":  zk.PermWrite,
+		"create": zk.PermCreate,
+		"delete": zk.PermDelete,
+		"admin":  zk.PermAdmin,
+		"all":    zk.PermAll,
+	}
+)
+
+// ACL is a single entry in the access control list applied to created znodes
+type ACL struct {
+	// Scheme is the ACL scheme, one of world, auth, digest, or ip.  If not supplied, WorldScheme is used.
+	Scheme string `json:"scheme,omitempty"`
+
+	// ID identifies who is granted the permissions, and its format depends on the scheme.  For the world scheme,
+	// this field is ignored.
+	ID string `json:"id,omitempty"`
+
+	// Permissions are the names of the granted permissions:  read, write, create, delete, admin, or all.
+	// If not supplied, all permissions are granted.
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+func (a ACL) perms() (int32, error) {
+	if len(a.Permissions) == 0 {
+		return zk.PermAll, nil
+	}
+
+	var perms int32
+	for _, name := range a.Permissions {
+		p, ok := permissions[strings.ToLower(name)]
+		if !ok {
+			return 0, fmt.Errorf("invalid zookeeper ACL permission: %s", name)
+		}
+
+		perms |= p
+	}
+
+	return perms, nil
+}
+
+func (a ACL) toZK() ([]zk.ACL, error) {
+	perms, err := a.perms()
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(a.Scheme) {
+	case WorldScheme, "":
+		return zk.WorldACL(perms), nil
+
+	case AuthScheme:
+		return zk.AuthACL(perms), nil
+
+	case DigestScheme:
+		separator := strings.IndexByte(a.ID, ':')
+		if separator < 1 {
+			return nil, fmt.Errorf("invalid zookeeper digest ACL id: %s", a.ID)
+		}
+
+		return zk.DigestACL(perms, a.ID[:separator], a.ID[separator+1:]), nil
+
+	case IPScheme:
+		if len(a.ID) == 0 {
+			return nil, errors.New("an ip zookeeper ACL requires an id")
+		}
+
+		return []zk.ACL{{Perms: perms, Scheme: IPScheme, ID: a.ID}}, nil
+
+	default:
+		return nil, fmt.Errorf("invalid zookeeper ACL scheme: %s", a.Scheme)
+	}
+}
+
+// newACL converts configured ACL entries into the zookeeper client's form
+func newACL(entries []ACL) ([]zk.ACL, error) {
+	acl := make([]zk.ACL, 0, len(entries))
+	for _, entry := range entries {
+		converted, err := entry.toZK()
+		if err != nil {
+			return nil, err
+		}
+
+		acl = append(acl, converted...)
+	}
+
+	return acl, nil
+}
+
+// TLS holds the options for connecting to Zookeeper over TLS
+type TLS struct {
+	// CertificateFile is the PEM-encoded client certificate presented to Zookeeper.  This field and KeyFile must
+	// either both be set or both be unset.
+	CertificateFile string `json:"certificateFile,omitempty"`
+
+	// KeyFile is the PEM-encoded private key for CertificateFile.
+	KeyFile string `json:"keyFile,omitempty"`
+
+	// CAFile is the PEM-encoded set of CA certificates used to verify Zookeeper servers.  If not supplied,
+	// the system roots are used.
+	CAFile string `json:"caFile,omitempty"`
+
+	// ServerName overrides the name used to verify server certificates.  If not supplied, the host
+	// of each server is used.
+	ServerName string `json:"serverName,omitempty"`
+
+	// InsecureSkipVerify disables verification of server certificates.  This should only be used for testing.
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
+// newConfig produces the crypto/tls configuration described by these options
+func (t *TLS) newConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+
+	if len(t.CertificateFile) > 0 || len(t.KeyFile) > 0 {
+		certificate, err := tls.LoadX509KeyPair(t.CertificateFile, t.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	if len(t.CAFile) > 0 {
+		pem, err := ioutil.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errNoCACertificates
+		}
+	}
+
+	return config, nil
+}
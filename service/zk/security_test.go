@@ -0,0 +1,177 @@
+package zk
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/samuel/go-zookeeper/zk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestCertificate generates a self-signed certificate for localhost, writing the certificate
+// and key as PEM files in a new temporary directory.  The returned directory should be removed by the caller.
+func writeTestCertificate(t *testing.T) (dir, certificateFile, keyFile string) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "zk")
+	require.NoError(err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{"Test"}},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(err)
+
+	certificateFile = filepath.Join(dir, "cert.pem")
+	require.NoError(ioutil.WriteFile(certificateFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+
+	keyFile = filepath.Join(dir, "key.pem")
+	require.NoError(ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+
+	return
+}
+
+func testNewACLValid(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+	)
+
+	acl, err := newACL([]ACL{
+		{},
+		{Scheme: "WORLD", Permissions: []string{"read"}},
+		{Scheme: AuthScheme, Permissions: []string{"read", "Write", "create", "delete"}},
+		{Scheme: DigestScheme, ID: "user:pass:word", Permissions: []string{"admin"}},
+		{Scheme: IPScheme, ID: "10.0.0.0/8", Permissions: []string{"all"}},
+	})
+
+	require.NoError(err)
+
+	expected := append(zk.WorldACL(zk.PermAll), zk.WorldACL(zk.PermRead)...)
+	expected = append(expected, zk.AuthACL(zk.PermRead|zk.PermWrite|zk.PermCreate|zk.PermDelete)...)
+	expected = append(expected, zk.DigestACL(zk.PermAdmin, "user", "pass:word")...)
+	expected = append(expected, zk.ACL{Perms: zk.PermAll, Scheme: "ip", ID: "10.0.0.0/8"})
+	assert.Equal(expected, acl)
+}
+
+func testNewACLInvalid(t *testing.T) {
+	assert := assert.New(t)
+	for _, entry := range []ACL{
+		{Scheme: "nosuch"},
+		{Scheme: WorldScheme, Permissions: []string{"read", "nosuch"}},
+		{Scheme: DigestScheme, ID: "nopassword"},
+		{Scheme: DigestScheme, ID: ":password"},
+		{Scheme: IPScheme},
+	} {
+		acl, err := newACL([]ACL{{}, entry})
+		assert.Nil(acl)
+		assert.Error(err)
+	}
+}
+
+func TestNewACL(t *testing.T) {
+	t.Run("Valid", testNewACLValid)
+	t.Run("Invalid", testNewACLInvalid)
+}
+
+func testTLSNewConfigDefault(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+	)
+
+	config, err := new(TLS).newConfig()
+	require.NoError(err)
+	require.NotNil(config)
+	assert.Empty(config.Certificates)
+	assert.Nil(config.RootCAs)
+	assert.Empty(config.ServerName)
+	assert.False(config.InsecureSkipVerify)
+}
+
+func testTLSNewConfigFull(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		dir, certificateFile, keyFile = writeTestCertificate(t)
+	)
+
+	defer os.RemoveAll(dir)
+
+	config, err := (&TLS{
+		CertificateFile:    certificateFile,
+		KeyFile:            keyFile,
+		CAFile:             certificateFile,
+		ServerName:         "zookeeper.example.com",
+		InsecureSkipVerify: true,
+	}).newConfig()
+
+	require.NoError(err)
+	require.NotNil(config)
+	assert.Len(config.Certificates, 1)
+	assert.NotNil(config.RootCAs)
+	assert.Equal("zookeeper.example.com", config.ServerName)
+	assert.True(config.InsecureSkipVerify)
+}
+
+func testTLSNewConfigError(t *testing.T) {
+	var (
+		assert = assert.New(t)
+
+		dir, certificateFile, keyFile = writeTestCertificate(t)
+		notPEM                        = filepath.Join(dir, "notpem.txt")
+	)
+
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, ioutil.WriteFile(notPEM, []byte("this is not PEM"), 0600))
+
+	for _, o := range []TLS{
+		{CertificateFile: certificateFile},
+		{KeyFile: keyFile},
+		{CertificateFile: filepath.Join(dir, "nosuch.pem"), KeyFile: keyFile},
+		{CAFile: filepath.Join(dir, "nosuch.pem")},
+		{CAFile: notPEM},
+	} {
+		config, err := o.newConfig()
+		assert.Nil(config)
+		assert.Error(err)
+	}
+}
+
+func TestTLS(t *testing.T) {
+	t.Run("NewConfig", func(t *testing.T) {
+		t.Run("Default", testTLSNewConfigDefault)
+		t.Run("Full", testTLSNewConfigFull)
+		t.Run("Error", testTLSNewConfigError)
+	})
+}
@@ -0,0 +1,247 @@
+package xhttp
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+)
+
+const (
+	DefaultBreakerFailureThreshold = 5
+	DefaultBreakerCooldown         = 30 * time.Second
+	DefaultBreakerHalfOpenProbes   = 1
+
+	// BreakerHostLabel is the metric label whose value is the host a circuit breaker applies to
+	BreakerHostLabel = "host"
+
+	// BreakerStateLabel is the metric label whose value is the BreakerState a circuit breaker transitioned into
+	BreakerStateLabel = "state"
+)
+
+// BreakerState is the state of the circuit breaker for a single host
+type BreakerState string
+
+const (
+	// BreakerClosed is the normal state, in which requests are sent to the host
+	BreakerClosed BreakerState = "closed"
+
+	// BreakerOpen indicates the host has failed too many consecutive requests.  Requests fail
+	// with a BreakerOpenError, without being sent, until the cooldown elapses.
+	BreakerOpen BreakerState = "open"
+
+	// BreakerHalfOpen indicates the cooldown has elapsed.  A limited number of probe requests are sent to
+	// the host.  A successful probe closes the breaker, while a failed probe opens it again.
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// BreakerOpenError is returned for requests that were not sent because the circuit breaker for their host is open.
+// This error is not temporary, so a RetryTransactor decorating a BreakerTransactor with the default ShouldRetry
+// predicate does not spend its retries on a host that is known to be failing.
+type BreakerOpenError struct {
+	Host string
+}
+
+func (boe BreakerOpenError) Error() string {
+	return fmt.Sprintf("The circuit breaker for host %s is open", boe.Host)
+}
+
+// StatusCode allows this error to be rendered as a 503 by go-kit error encoders
+func (boe BreakerOpenError) StatusCode() int {
+	return http.StatusServiceUnavailable
+}
+
+// DefaultIsFailure is the default predicate for circuit breakers.  It treats errors and 5xx responses as failures.
+func DefaultIsFailure(response *http.Response, err error) bool {
+	return err != nil || (response != nil && response.StatusCode >= 500)
+}
+
+// BreakerOptions are the configuration options for a circuit breaker transactor
+type BreakerOptions struct {
+	// FailureThreshold is the number of consecutive failures which opens the breaker for a host.
+	// If not positive, DefaultBreakerFailureThreshold is used.
+	FailureThreshold int
+
+	// Cooldown is how long a host's breaker stays open before probe requests are allowed.
+	// If not positive, DefaultBreakerCooldown is used.
+	Cooldown time.Duration
+
+	// HalfOpenProbes is the maximum number of concurrent probe requests sent to a host whose breaker is half-open.
+	// If not positive, DefaultBreakerHalfOpenProbes is used.
+	HalfOpenProbes int
+
+	// IsFailure is the predicate that determines whether a transaction failed.  Defaults to DefaultIsFailure if unset.
+	IsFailure func(*http.Response, error) bool
+
+	// Transitions is the counter for breaker state changes, labeled with BreakerHostLabel and BreakerStateLabel.
+	// If unset, no metrics are collected on state changes.
+	Transitions metrics.Counter
+
+	// Rejected is the counter for requests not sent because a breaker was open, labeled with BreakerHostLabel.
+	// If unset, no metrics are collected on rejections.
+	Rejected metrics.Counter
+
+	// Now is the function used to obtain the current time.  If unset, time.Now is used.
+	Now func() time.Time
+}
+
+// hostBreaker is the circuit breaker state for a single host
+type hostBreaker struct {
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probes   int
+}
+
+// breakers tracks the circuit breakers for all hosts
+type breakers struct {
+	lock  sync.Mutex
+	hosts map[string]*hostBreaker
+
+	o BreakerOptions
+}
+
+// transition moves a host's breaker into the given state.  The lock must be held.
+func (b *breakers) transition(host string, hb *hostBreaker, state BreakerState) {
+	hb.state = state
+	b.o.Transitions.With(BreakerHostLabel, host, BreakerStateLabel, string(state)).Add(1.0)
+}
+
+// allow tests if a request may be sent to the given host.  The first return is true if the request
+// is a half-open probe, and the second is true if the request may be sent.
+func (b *breakers) allow(host string) (bool, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	hb := b.hosts[host]
+	if hb == nil {
+		hb = &hostBreaker{state: BreakerClosed}
+		b.hosts[host] = hb
+	}
+
+	if hb.state == BreakerOpen {
+		if b.o.Now().Sub(hb.openedAt) < b.o.Cooldown {
+			return false, false
+		}
+
+		hb.probes = 0
+		b.transition(host, hb, BreakerHalfOpen)
+	}
+
+	if hb.state == BreakerHalfOpen {
+		if hb.probes >= b.o.HalfOpenProbes {
+			return false, false
+		}
+
+		hb.probes++
+		return true, true
+	}
+
+	return false, true
+}
+
+// record updates a host's breaker with the outcome of a request.  Only probes affect a half-open breaker,
+// and requests that complete after a breaker has opened have no effect.
+func (b *breakers) record(host string, probe, failed bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	hb := b.hosts[host]
+	if probe {
+		hb.probes--
+	}
+
+	switch {
+	case hb.state == BreakerOpen, hb.state == BreakerHalfOpen && !probe:
+		return
+
+	case !failed:
+		hb.failures = 0
+		if hb.state == BreakerHalfOpen {
+			b.transition(host, hb, BreakerClosed)
+		}
+
+	case hb.state == BreakerHalfOpen:
+		hb.openedAt = b.o.Now()
+		b.transition(host, hb, BreakerOpen)
+
+	default:
+		hb.failures++
+		if hb.failures >= b.o.FailureThreshold {
+			hb.failures = 0
+			hb.openedAt = b.o.Now()
+			b.transition(host, hb, BreakerOpen)
+		}
+	}
+}
+
+// BreakerTransactor returns an HTTP transactor function, of the same signature as http.Client.Do, that maintains
+// a circuit breaker for each host that requests are sent to, as given by the request URL.  This keeps a single failing
+// host from slowing down every request.  When combined with RetryTransactor, the breaker should be the inner decorator.
+func BreakerTransactor(o BreakerOptions, next func(*http.Request) (*http.Response, error)) func(*http.Request) (*http.Response, error) {
+	if o.FailureThreshold < 1 {
+		o.FailureThreshold = DefaultBreakerFailureThreshold
+	}
+
+	if o.Cooldown <= 0 {
+		o.Cooldown = DefaultBreakerCooldown
+	}
+
+	if o.HalfOpenProbes < 1 {
+		o.HalfOpenProbes = DefaultBreakerHalfOpenProbes
+	}
+
+	if o.IsFailure == nil {
+		o.IsFailure = DefaultIsFailure
+	}
+
+	if o.Transitions == nil {
+		o.Transitions = discard.NewCounter()
+	}
+
+	if o.Rejected == nil {
+		o.Rejected = discard.NewCounter()
+	}
+
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+
+	b := &breakers{
+		hosts: make(map[string]*hostBreaker),
+		o:     o,
+	}
+
+	return func(request *http.Request) (*http.Response, error) {
+		host := request.URL.Host
+		probe, ok := b.allow(host)
+		if !ok {
+			o.Rejected.With(BreakerHostLabel, host).Add(1.0)
+			return nil, BreakerOpenError{Host: host}
+		}
+
+		response, err := next(request)
+		b.record(host, probe, o.IsFailure(response, err))
+		return response, err
+	}
+}
+
+// breakerRoundTripper adapts a BreakerTransactor to http.RoundTripper
+type breakerRoundTripper func(*http.Request) (*http.Response, error)
+
+func (brt breakerRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	return brt(request)
+}
+
+// NewBreakerRoundTripper decorates an http.RoundTripper with per-host circuit breakers, as described by BreakerTransactor.
+// If next is nil, http.DefaultTransport is used.
+func NewBreakerRoundTripper(o BreakerOptions, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return breakerRoundTripper(BreakerTransactor(o, next.RoundTrip))
+}
@@ -0,0 +1,228 @@
+package xhttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/xmetrics"
+	"github.com/xmidt-org/webpa-common/xmetrics/xmetricstest"
+)
+
+func TestDefaultIsFailure(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.False(DefaultIsFailure(&http.Response{StatusCode: http.StatusOK}, nil))
+	assert.False(DefaultIsFailure(&http.Response{StatusCode: http.StatusNotFound}, nil))
+	assert.True(DefaultIsFailure(&http.Response{StatusCode: http.StatusServiceUnavailable}, nil))
+	assert.True(DefaultIsFailure(nil, errors.New("expected")))
+}
+
+func TestBreakerOpenError(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		err    = BreakerOpenError{Host: "argus-1:8080"}
+	)
+
+	assert.Contains(err.Error(), "argus-1:8080")
+	assert.Equal(http.StatusServiceUnavailable, err.StatusCode())
+	assert.False(DefaultShouldRetry(err))
+}
+
+func testBreakerTransactorOpenAndClose(t *testing.T) {
+	var (
+		assert      = assert.New(t)
+		require     = require.New(t)
+		now         = time.Now()
+		transitions = xmetricstest.NewCounter("transitions")
+		rejected    = xmetricstest.NewCounter("rejected")
+
+		failing    = map[string]bool{"failing": true}
+		sent       = map[string]int{}
+		transactor = func(request *http.Request) (*http.Response, error) {
+			sent[request.URL.Host]++
+			if failing[request.URL.Host] {
+				return &http.Response{StatusCode: http.StatusServiceUnavailable}, nil
+			}
+
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		}
+
+		breaker = BreakerTransactor(
+			BreakerOptions{
+				FailureThreshold: 3,
+				Cooldown:         time.Minute,
+				Transitions:      transitions,
+				Rejected:         rejected,
+				Now:              func() time.Time { return now },
+			},
+			transactor,
+		)
+	)
+
+	require.NotNil(breaker)
+
+	for i := 0; i < 3; i++ {
+		response, err := breaker(httptest.NewRequest("GET", "http://failing/", nil))
+		require.NotNil(response)
+		assert.Equal(http.StatusServiceUnavailable, response.StatusCode)
+		assert.NoError(err)
+	}
+
+	response, err := breaker(httptest.NewRequest("GET", "http://failing/", nil))
+	assert.Nil(response)
+	assert.Equal(BreakerOpenError{Host: "failing"}, err)
+	assert.Equal(3, sent["failing"])
+
+	// other hosts are unaffected
+	response, err = breaker(httptest.NewRequest("GET", "http://healthy/", nil))
+	require.NotNil(response)
+	assert.Equal(http.StatusOK, response.StatusCode)
+	assert.NoError(err)
+
+	// a failed probe reopens the breaker
+	now = now.Add(time.Minute)
+	response, err = breaker(httptest.NewRequest("GET", "http://failing/", nil))
+	require.NotNil(response)
+	assert.NoError(err)
+	assert.Equal(4, sent["failing"])
+
+	_, err = breaker(httptest.NewRequest("GET", "http://failing/", nil))
+	assert.Equal(BreakerOpenError{Host: "failing"}, err)
+
+	// a successful probe closes the breaker
+	now = now.Add(time.Minute)
+	failing["failing"] = false
+	response, err = breaker(httptest.NewRequest("GET", "http://failing/", nil))
+	require.NotNil(response)
+	assert.Equal(http.StatusOK, response.StatusCode)
+	assert.NoError(err)
+
+	response, err = breaker(httptest.NewRequest("GET", "http://failing/", nil))
+	require.NotNil(response)
+	assert.NoError(err)
+	assert.Equal(6, sent["failing"])
+
+	assert.Equal(2.0, transitions.With(BreakerHostLabel, "failing", BreakerStateLabel, string(BreakerOpen)).(xmetrics.Valuer).Value())
+	assert.Equal(2.0, transitions.With(BreakerHostLabel, "failing", BreakerStateLabel, string(BreakerHalfOpen)).(xmetrics.Valuer).Value())
+	assert.Equal(1.0, transitions.With(BreakerHostLabel, "failing", BreakerStateLabel, string(BreakerClosed)).(xmetrics.Valuer).Value())
+	assert.Equal(2.0, rejected.With(BreakerHostLabel, "failing").(xmetrics.Valuer).Value())
+	assert.Equal(0.0, rejected.With(BreakerHostLabel, "healthy").(xmetrics.Valuer).Value())
+}
+
+func testBreakerTransactorSuccessResets(t *testing.T) {
+	var (
+		assert = assert.New(t)
+
+		results    = []error{errors.New("1"), errors.New("2"), nil, errors.New("3"), errors.New("4"), nil}
+		sent       = 0
+		transactor = func(*http.Request) (*http.Response, error) {
+			err := results[sent]
+			sent++
+			return nil, err
+		}
+
+		breaker = BreakerTransactor(BreakerOptions{FailureThreshold: 3}, transactor)
+	)
+
+	for range results {
+		_, err := breaker(httptest.NewRequest("GET", "http://host/", nil))
+		_, open := err.(BreakerOpenError)
+		assert.False(open)
+	}
+
+	assert.Equal(len(results), sent)
+}
+
+func testBreakerTransactorHalfOpenProbes(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		now     = time.Now()
+
+		probing    = make(chan struct{})
+		release    = make(chan struct{})
+		fail       = true
+		transactor = func(*http.Request) (*http.Response, error) {
+			if fail {
+				return nil, errors.New("expected")
+			}
+
+			probing <- struct{}{}
+			<-release
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		}
+
+		breaker = BreakerTransactor(
+			BreakerOptions{
+				FailureThreshold: 1,
+				Cooldown:         time.Second,
+				Now:              func() time.Time { return now },
+			},
+			transactor,
+		)
+	)
+
+	_, err := breaker(httptest.NewRequest("GET", "http://host/", nil))
+	require.Error(err)
+
+	now = now.Add(time.Second)
+	fail = false
+
+	probeDone := make(chan error)
+	go func() {
+		_, err := breaker(httptest.NewRequest("GET", "http://host/", nil))
+		probeDone <- err
+	}()
+
+	<-probing
+
+	// only one probe is allowed at a time
+	_, err = breaker(httptest.NewRequest("GET", "http://host/", nil))
+	assert.Equal(BreakerOpenError{Host: "host"}, err)
+
+	close(release)
+	assert.NoError(<-probeDone)
+
+	go func() { <-probing }()
+	response, err := breaker(httptest.NewRequest("GET", "http://host/", nil))
+	require.NotNil(response)
+	assert.NoError(err)
+}
+
+func TestBreakerTransactor(t *testing.T) {
+	t.Run("OpenAndClose", testBreakerTransactorOpenAndClose)
+	t.Run("SuccessResets", testBreakerTransactorSuccessResets)
+	t.Run("HalfOpenProbes", testBreakerTransactorHalfOpenProbes)
+}
+
+func TestNewBreakerRoundTripper(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		server = httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, _ *http.Request) {
+			response.WriteHeader(http.StatusInternalServerError)
+		}))
+	)
+
+	defer server.Close()
+
+	client := &http.Client{
+		Transport: NewBreakerRoundTripper(BreakerOptions{FailureThreshold: 2}, nil),
+	}
+
+	for i := 0; i < 2; i++ {
+		response, err := client.Get(server.URL)
+		require.NoError(err)
+		response.Body.Close()
+		assert.Equal(http.StatusInternalServerError, response.StatusCode)
+	}
+
+	_, err := client.Get(server.URL)
+	assert.Error(err)
+}
@@ -1,6 +1,7 @@
 package fanout
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -126,6 +127,15 @@ func WithClientFailure(failure ...gokithttp.ClientResponseFunc) Option {
 	}
 }
 
+// WithHedging enables hedged fanouts, as described by HedgeOptions.  Rather than sending a request to every endpoint
+// at once, a hedged fanout sends requests one at a time, only as each outstanding request becomes slower than usual.
+// This option is appropriate when any endpoint can handle a request, e.g. for redundant servers.
+func WithHedging(o HedgeOptions) Option {
+	return func(h *Handler) {
+		h.hedging = newHedger(o)
+	}
+}
+
 // WithConfiguration uses a set of (typically injected) fanout configuration options to configure a Handler.
 // Use of this option will not override the configured Endpoints instance.
 func WithConfiguration(c Configuration) Option {
@@ -148,6 +158,7 @@ type Handler struct {
 	failure         []FanoutResponseFunc
 	shouldTerminate ShouldTerminateFunc
 	transactor      func(*http.Request) (*http.Response, error)
+	hedging         *hedger
 }
 
 // New creates a fanout Handler.  The Endpoints strategy is required, and this constructor function will
@@ -183,6 +194,8 @@ func (h *Handler) newFanoutRequests(fanoutCtx context.Context, original *http.Re
 		return nil, err
 	}
 
+	// restore the original body, as the Endpoints strategy may need it, e.g. to hash on a WRP destination
+	original.Body = ioutil.NopCloser(bytes.NewReader(body))
 	urls, err := h.endpoints.FanoutURLs(original)
 	if err != nil {
 		return nil, err
@@ -299,6 +312,16 @@ func (h *Handler) finish(logger log.Logger, response http.ResponseWriter, result
 	}
 }
 
+// complete handles the common bookkeeping for a single fanout result as it is received
+func (h *Handler) complete(logger log.Logger, response http.ResponseWriter, r Result) {
+	tracinghttp.HeadersForSpans("", response.Header(), r.Span)
+	if r.Err != nil {
+		logger.Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "fanout request complete", "statusCode", r.StatusCode, "url", r.Request.URL, logging.ErrorKey(), r.Err)
+	} else {
+		logger.Log(level.Key(), level.DebugValue(), logging.MessageKey(), "fanout request complete", "statusCode", r.StatusCode, "url", r.Request.URL)
+	}
+}
+
 func (h *Handler) ServeHTTP(response http.ResponseWriter, original *http.Request) {
 	var (
 		fanoutCtx     = original.Context()
@@ -312,6 +335,11 @@ func (h *Handler) ServeHTTP(response http.ResponseWriter, original *http.Request
 		return
 	}
 
+	if h.hedging != nil {
+		h.serveHedged(logger, response, original, requests)
+		return
+	}
+
 	var (
 		spanner = tracing.NewSpanner()
 		results = make(chan Result, len(requests))
@@ -331,13 +359,7 @@ func (h *Handler) ServeHTTP(response http.ResponseWriter, original *http.Request
 			return
 
 		case r := <-results:
-			tracinghttp.HeadersForSpans("", response.Header(), r.Span)
-			if r.Err != nil {
-				logger.Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "fanout request complete", "statusCode", r.StatusCode, "url", r.Request.URL, logging.ErrorKey(), r.Err)
-			} else {
-				logger.Log(level.Key(), level.DebugValue(), logging.MessageKey(), "fanout request complete", "statusCode", r.StatusCode, "url", r.Request.URL)
-			}
-
+			h.complete(logger, response, r)
 			if h.shouldTerminate(r) {
 				// this was a "success", so no reason to wait any longer
 				h.finish(logger, response, r, h.after)
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -98,6 +99,38 @@ func testHandlerEndpointsError(t *testing.T) {
 	body.AssertExpectations(t)
 }
 
+func testHandlerEndpointsReadBody(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		expectedError = errors.New("endpoints error")
+		endpoints     = new(mockEndpoints)
+
+		logger   = logging.NewTestLogger(nil, t)
+		ctx      = logging.WithLogger(context.Background(), logger)
+		original = httptest.NewRequest("POST", "/something", strings.NewReader("original body")).WithContext(ctx)
+		response = httptest.NewRecorder()
+
+		handler = New(endpoints, WithErrorEncoder(func(_ context.Context, err error, response http.ResponseWriter) {
+			response.WriteHeader(599)
+		}))
+	)
+
+	require.NotNil(handler)
+	endpoints.On("FanoutURLs", original).Once().Return(nil, expectedError).Run(func(arguments mock.Arguments) {
+		// the Endpoints strategy must be able to read the original body
+		body, err := ioutil.ReadAll(arguments.Get(0).(*http.Request).Body)
+		assert.NoError(err)
+		assert.Equal("original body", string(body))
+	})
+
+	handler.ServeHTTP(response, original)
+	assert.Equal(599, response.Code)
+
+	endpoints.AssertExpectations(t)
+}
+
 func testHandlerBadTransactor(t *testing.T) {
 	var (
 		assert  = assert.New(t)
@@ -358,6 +391,7 @@ func TestHandler(t *testing.T) {
 	t.Run("BodyError", testHandlerBodyError)
 	t.Run("NoEndpoints", testHandlerNoEndpoints)
 	t.Run("EndpointsError", testHandlerEndpointsError)
+	t.Run("EndpointsReadBody", testHandlerEndpointsReadBody)
 	t.Run("BadTransactor", testHandlerBadTransactor)
 
 	t.Run("Fanout", func(t *testing.T) {
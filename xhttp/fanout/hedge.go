@@ -0,0 +1,286 @@
+package fanout
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/tracing"
+)
+
+const (
+	DefaultHedgePercentile   = 95.0
+	DefaultHedgeWindow       = 100
+	DefaultHedgeMinSamples   = 10
+	DefaultHedgeInitialDelay = 500 * time.Millisecond
+	DefaultMaxHedges         = 1
+)
+
+// HedgeOptions are the configuration options for hedged fanouts.  When hedging, a Handler sends its request to
+// the first, or primary, endpoint only.  If no successful response arrives within a delay computed from recent
+// latencies, the request is also sent to the next endpoint, and so on up to MaxHedges times.  The first successful
+// response wins, and the requests still outstanding are canceled.
+type HedgeOptions struct {
+	// Percentile is the percentile of recent successful latencies, in the range (0, 100], that a request may take
+	// before it is hedged.  If unset or out of range, DefaultHedgePercentile is used.
+	Percentile float64
+
+	// Window is the number of recent successful latencies the percentile is computed over.
+	// If not positive, DefaultHedgeWindow is used.
+	Window int
+
+	// MinSamples is the number of latencies required before the percentile is used.  Until then, InitialDelay is the
+	// hedge delay.  If not positive, DefaultHedgeMinSamples is used.  Values larger than Window are reduced to Window.
+	MinSamples int
+
+	// InitialDelay is the hedge delay used until enough latencies have been observed.
+	// If not positive, DefaultHedgeInitialDelay is used.
+	InitialDelay time.Duration
+
+	// MinDelay is the lower bound on the hedge delay.  There is no lower bound if this is not positive.
+	MinDelay time.Duration
+
+	// MaxDelay is the upper bound on the hedge delay.  There is no upper bound if this is not positive.
+	MaxDelay time.Duration
+
+	// MaxHedges is the maximum number of hedge requests sent in addition to the primary request.  Hedges are sent to
+	// endpoints in the order returned by the Endpoints strategy, so a fanout with a single endpoint is never hedged.
+	// If not positive, DefaultMaxHedges is used.
+	MaxHedges int
+
+	// Requests is the counter for hedged fanout operations.  If unset, no metrics are collected on fanouts.
+	Requests metrics.Counter
+
+	// Hedges is the counter for hedge requests sent.  Dividing this by Requests yields the hedge rate.
+	// If unset, no metrics are collected on hedges.
+	Hedges metrics.Counter
+
+	// HedgeWins is the counter for fanouts won by a hedge request rather than the primary request.
+	// If unset, no metrics are collected on wins.
+	HedgeWins metrics.Counter
+}
+
+// hedger holds the normalized hedging configuration along with the window of recent latencies
+type hedger struct {
+	o HedgeOptions
+
+	lock      sync.Mutex
+	latencies []time.Duration
+	next      int
+}
+
+func newHedger(o HedgeOptions) *hedger {
+	if o.Percentile <= 0.0 || o.Percentile > 100.0 {
+		o.Percentile = DefaultHedgePercentile
+	}
+
+	if o.Window < 1 {
+		o.Window = DefaultHedgeWindow
+	}
+
+	if o.MinSamples < 1 {
+		o.MinSamples = DefaultHedgeMinSamples
+	}
+
+	if o.MinSamples > o.Window {
+		o.MinSamples = o.Window
+	}
+
+	if o.InitialDelay <= 0 {
+		o.InitialDelay = DefaultHedgeInitialDelay
+	}
+
+	if o.MaxHedges < 1 {
+		o.MaxHedges = DefaultMaxHedges
+	}
+
+	if o.Requests == nil {
+		o.Requests = discard.NewCounter()
+	}
+
+	if o.Hedges == nil {
+		o.Hedges = discard.NewCounter()
+	}
+
+	if o.HedgeWins == nil {
+		o.HedgeWins = discard.NewCounter()
+	}
+
+	return &hedger{
+		o:         o,
+		latencies: make([]time.Duration, 0, o.Window),
+	}
+}
+
+// observe records the latency of a successful fanout request, replacing the oldest latency once the window is full
+func (hg *hedger) observe(latency time.Duration) {
+	hg.lock.Lock()
+	if len(hg.latencies) < hg.o.Window {
+		hg.latencies = append(hg.latencies, latency)
+	} else {
+		hg.latencies[hg.next] = latency
+	}
+
+	hg.next = (hg.next + 1) % hg.o.Window
+	hg.lock.Unlock()
+}
+
+// delay computes how long to wait on outstanding requests before sending a hedge request
+func (hg *hedger) delay() time.Duration {
+	hg.lock.Lock()
+	if len(hg.latencies) < hg.o.MinSamples {
+		hg.lock.Unlock()
+		return hg.clamp(hg.o.InitialDelay)
+	}
+
+	sorted := make([]time.Duration, len(hg.latencies))
+	copy(sorted, hg.latencies)
+	hg.lock.Unlock()
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	// nearest-rank percentile
+	rank := int(math.Ceil(hg.o.Percentile / 100.0 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+
+	return hg.clamp(sorted[rank-1])
+}
+
+func (hg *hedger) clamp(d time.Duration) time.Duration {
+	if hg.o.MinDelay > 0 && d < hg.o.MinDelay {
+		return hg.o.MinDelay
+	}
+
+	if hg.o.MaxDelay > 0 && d > hg.o.MaxDelay {
+		return hg.o.MaxDelay
+	}
+
+	return d
+}
+
+// attempts returns the total number of requests, primary and hedges, that may be sent for a fanout
+func (hg *hedger) attempts(endpointCount int) int {
+	if endpointCount > hg.o.MaxHedges+1 {
+		return hg.o.MaxHedges + 1
+	}
+
+	return endpointCount
+}
+
+// hedgeAttempt tracks a single request sent as part of a hedged fanout
+type hedgeAttempt struct {
+	index int
+	start time.Time
+}
+
+// serveHedged carries out a hedged fanout.  Each request is sent with its own cancelable context, so that
+// requests which lose the race are canceled as soon as a result terminates the fanout.
+func (h *Handler) serveHedged(logger log.Logger, response http.ResponseWriter, original *http.Request, requests []*http.Request) {
+	var (
+		fanoutCtx = original.Context()
+		spanner   = tracing.NewSpanner()
+		count     = h.hedging.attempts(len(requests))
+		results   = make(chan Result, count)
+		attempts  = make(map[*http.Request]hedgeAttempt, count)
+		cancels   = make([]context.CancelFunc, 0, count)
+		pending   = 0
+		timer     = time.NewTimer(h.hedging.delay())
+	)
+
+	defer timer.Stop()
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+
+	send := func() {
+		i := len(cancels)
+		ctx, cancel := context.WithCancel(requests[i].Context())
+		r := requests[i].WithContext(ctx)
+		cancels = append(cancels, cancel)
+		attempts[r] = hedgeAttempt{index: i, start: time.Now()}
+		pending++
+
+		if i > 0 {
+			logger.Log(level.Key(), level.DebugValue(), logging.MessageKey(), "sending hedge request", "url", r.URL)
+			h.hedging.o.Hedges.Add(1.0)
+		}
+
+		go h.execute(logger, spanner, results, r)
+	}
+
+	// hedge sends the next request, if any remain, and restarts the hedge delay
+	hedge := func() {
+		if len(cancels) >= count {
+			return
+		}
+
+		send()
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+
+		timer.Reset(h.hedging.delay())
+	}
+
+	h.hedging.o.Requests.Add(1.0)
+	send()
+
+	statusCode := 0
+	var latestResponse Result
+	for pending > 0 {
+		select {
+		case <-fanoutCtx.Done():
+			logger.Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "fanout operation canceled or timed out", "statusCode", http.StatusGatewayTimeout, "url", original.URL, logging.ErrorKey(), fanoutCtx.Err())
+			response.WriteHeader(http.StatusGatewayTimeout)
+			return
+
+		case <-timer.C:
+			// the outstanding requests are slower than usual
+			hedge()
+
+		case r := <-results:
+			pending--
+			attempt := attempts[r.Request]
+
+			// report the request as it was built, rather than with the internal cancelable context
+			r.Request = requests[attempt.index]
+			h.complete(logger, response, r)
+
+			if h.shouldTerminate(r) {
+				h.hedging.observe(time.Since(attempt.start))
+				if attempt.index > 0 {
+					h.hedging.o.HedgeWins.Add(1.0)
+				}
+
+				h.finish(logger, response, r, h.after)
+				return
+			}
+
+			if statusCode < r.StatusCode {
+				statusCode = r.StatusCode
+				latestResponse = r
+			}
+
+			// a failure need not wait for the hedge delay
+			hedge()
+		}
+	}
+
+	logger.Log(level.Key(), level.ErrorValue(), logging.MessageKey(), "all fanout requests failed", "statusCode", statusCode, "url", original.URL)
+	h.finish(logger, response, latestResponse, h.failure)
+}
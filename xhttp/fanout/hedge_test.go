@@ -0,0 +1,296 @@
+package fanout
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+// hedgeEndpoint describes how a fake endpoint responds during a hedging test.  A negative status code
+// causes the endpoint to block until its request is canceled.
+type hedgeEndpoint struct {
+	statusCode int
+	body       string
+}
+
+// newHedgeTransactor produces a transactor that responds as the given endpoints do.  Each request's host
+// is sent on called, and the host of each canceled request is sent on canceled.
+func newHedgeTransactor(endpoints FixedEndpoints, behaviors []hedgeEndpoint, called, canceled chan<- string) func(*http.Request) (*http.Response, error) {
+	return func(request *http.Request) (*http.Response, error) {
+		called <- request.URL.Host
+		for i, e := range endpoints {
+			if e.Host != request.URL.Host {
+				continue
+			}
+
+			if behaviors[i].statusCode < 0 {
+				<-request.Context().Done()
+				canceled <- request.URL.Host
+				return nil, request.Context().Err()
+			}
+
+			return &http.Response{
+				StatusCode: behaviors[i].statusCode,
+				Header:     http.Header{"Content-Type": []string{"text/plain"}},
+				Body:       ioutil.NopCloser(bytes.NewBufferString(behaviors[i].body)),
+			}, nil
+		}
+
+		return nil, errNoFanoutURLs
+	}
+}
+
+func testHedgerDefaults(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		hg     = newHedger(HedgeOptions{Percentile: 150.0, MinSamples: 500})
+	)
+
+	assert.Equal(DefaultHedgePercentile, hg.o.Percentile)
+	assert.Equal(DefaultHedgeWindow, hg.o.Window)
+	assert.Equal(DefaultHedgeWindow, hg.o.MinSamples)
+	assert.Equal(DefaultHedgeInitialDelay, hg.o.InitialDelay)
+	assert.Equal(DefaultMaxHedges, hg.o.MaxHedges)
+	assert.NotNil(hg.o.Requests)
+	assert.NotNil(hg.o.Hedges)
+	assert.NotNil(hg.o.HedgeWins)
+	assert.Equal(DefaultHedgeInitialDelay, hg.delay())
+
+	assert.Equal(1, hg.attempts(1))
+	assert.Equal(2, hg.attempts(2))
+	assert.Equal(2, hg.attempts(5))
+}
+
+func testHedgerDelay(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		hg     = newHedger(HedgeOptions{
+			Percentile:   90.0,
+			Window:       10,
+			MinSamples:   5,
+			InitialDelay: time.Second,
+		})
+	)
+
+	for i := 1; i < 5; i++ {
+		hg.observe(time.Duration(i) * time.Millisecond)
+		assert.Equal(time.Second, hg.delay())
+	}
+
+	for i := 5; i <= 10; i++ {
+		hg.observe(time.Duration(i) * time.Millisecond)
+	}
+
+	assert.Equal(9*time.Millisecond, hg.delay())
+
+	// the oldest latencies are replaced once the window is full
+	for i := 0; i < 9; i++ {
+		hg.observe(100 * time.Millisecond)
+	}
+
+	assert.Len(hg.latencies, 10)
+	assert.Equal(100*time.Millisecond, hg.delay())
+
+	hg.o.MaxDelay = 50 * time.Millisecond
+	assert.Equal(50*time.Millisecond, hg.delay())
+
+	hg.o.MaxDelay = 0
+	hg.o.MinDelay = 200 * time.Millisecond
+	assert.Equal(200*time.Millisecond, hg.delay())
+}
+
+func testHandlerHedging(t *testing.T, o HedgeOptions, behaviors []hedgeEndpoint, expectedStatusCode int, expectedBody string, expectedCalls, expectedCanceled []int, expectedHedges, expectedWins float64) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		logger   = logging.NewTestLogger(nil, t)
+		ctx      = logging.WithLogger(context.Background(), logger)
+		original = httptest.NewRequest("GET", "/api/v2/something", nil).WithContext(ctx)
+		response = httptest.NewRecorder()
+
+		endpoints = generateEndpoints(len(behaviors))
+		called    = make(chan string, len(behaviors))
+		canceled  = make(chan string, len(behaviors))
+
+		requests  = generic.NewCounter("requests")
+		hedges    = generic.NewCounter("hedges")
+		hedgeWins = generic.NewCounter("hedgeWins")
+
+		afterCalled   = false
+		failureCalled = false
+	)
+
+	o.Requests = requests
+	o.Hedges = hedges
+	o.HedgeWins = hedgeWins
+
+	handler := New(endpoints,
+		WithTransactor(newHedgeTransactor(endpoints, behaviors, called, canceled)),
+		WithHedging(o),
+		WithFanoutAfter(func(actualCtx context.Context, _ http.ResponseWriter, _ Result) context.Context {
+			assert.Equal(ctx, actualCtx)
+			afterCalled = true
+			return actualCtx
+		}),
+		WithFanoutFailure(func(actualCtx context.Context, _ http.ResponseWriter, _ Result) context.Context {
+			failureCalled = true
+			return actualCtx
+		}),
+	)
+
+	require.NotNil(handler)
+	handler.ServeHTTP(response, original)
+	assert.Equal(expectedStatusCode, response.Code)
+	assert.Equal(expectedBody, response.Body.String())
+	assert.Equal(expectedStatusCode < 400, afterCalled)
+	assert.Equal(expectedStatusCode >= 400, failureCalled)
+
+	timeout := time.After(5 * time.Second)
+	for _, i := range expectedCalls {
+		select {
+		case host := <-called:
+			assert.Equal(endpoints[i].Host, host)
+		case <-timeout:
+			assert.Fail("Not all endpoints were called")
+		}
+	}
+
+	for _, i := range expectedCanceled {
+		select {
+		case host := <-canceled:
+			assert.Equal(endpoints[i].Host, host)
+		case <-timeout:
+			assert.Fail("Not all losing requests were canceled")
+		}
+	}
+
+	select {
+	case host := <-called:
+		assert.Fail("Unexpected fanout request", host)
+	default:
+	}
+
+	assert.Equal(1.0, requests.Value())
+	assert.Equal(expectedHedges, hedges.Value())
+	assert.Equal(expectedWins, hedgeWins.Value())
+}
+
+func testHandlerHedgingTimeout(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		logger      = logging.NewTestLogger(nil, t)
+		ctx, cancel = context.WithCancel(logging.WithLogger(context.Background(), logger))
+		original    = httptest.NewRequest("GET", "/api/v2/something", nil).WithContext(ctx)
+		response    = httptest.NewRecorder()
+
+		behaviors = []hedgeEndpoint{{statusCode: -1}, {statusCode: -1}}
+		endpoints = generateEndpoints(len(behaviors))
+		called    = make(chan string, len(behaviors))
+		canceled  = make(chan string, len(behaviors))
+
+		handler = New(endpoints,
+			WithTransactor(newHedgeTransactor(endpoints, behaviors, called, canceled)),
+			WithHedging(HedgeOptions{InitialDelay: time.Hour}),
+		)
+
+		handlerWait = make(chan struct{})
+	)
+
+	require.NotNil(handler)
+	go func() {
+		defer close(handlerWait)
+		handler.ServeHTTP(response, original)
+	}()
+
+	select {
+	case host := <-called:
+		assert.Equal(endpoints[0].Host, host)
+	case <-time.After(2 * time.Second):
+		assert.Fail("The primary endpoint was not called")
+	}
+
+	cancel()
+	select {
+	case <-handlerWait:
+		assert.Equal(http.StatusGatewayTimeout, response.Code)
+	case <-time.After(2 * time.Second):
+		assert.Fail("ServeHTTP did not return")
+	}
+
+	select {
+	case host := <-canceled:
+		assert.Equal(endpoints[0].Host, host)
+	case <-time.After(2 * time.Second):
+		assert.Fail("The primary request was not canceled")
+	}
+}
+
+func TestHedger(t *testing.T) {
+	t.Run("Defaults", testHedgerDefaults)
+	t.Run("Delay", testHedgerDelay)
+}
+
+func TestHandlerHedging(t *testing.T) {
+	t.Run("PrimaryWins", func(t *testing.T) {
+		testHandlerHedging(t,
+			HedgeOptions{InitialDelay: time.Hour},
+			[]hedgeEndpoint{{statusCode: 200, body: "primary"}, {statusCode: 200, body: "hedge"}},
+			200, "primary", []int{0}, nil, 0.0, 0.0,
+		)
+	})
+
+	t.Run("SingleEndpoint", func(t *testing.T) {
+		testHandlerHedging(t,
+			HedgeOptions{InitialDelay: time.Millisecond},
+			[]hedgeEndpoint{{statusCode: 500, body: "primary"}},
+			500, "primary", []int{0}, nil, 0.0, 0.0,
+		)
+	})
+
+	t.Run("HedgeWins", func(t *testing.T) {
+		testHandlerHedging(t,
+			HedgeOptions{InitialDelay: 10 * time.Millisecond},
+			[]hedgeEndpoint{{statusCode: -1}, {statusCode: 200, body: "hedge"}},
+			200, "hedge", []int{0, 1}, []int{0}, 1.0, 1.0,
+		)
+	})
+
+	t.Run("PrimaryFails", func(t *testing.T) {
+		// a failed primary is hedged immediately, without waiting for the delay
+		testHandlerHedging(t,
+			HedgeOptions{InitialDelay: time.Hour},
+			[]hedgeEndpoint{{statusCode: 500, body: "primary"}, {statusCode: 202, body: "hedge"}},
+			202, "hedge", []int{0, 1}, nil, 1.0, 1.0,
+		)
+	})
+
+	t.Run("AllFail", func(t *testing.T) {
+		testHandlerHedging(t,
+			HedgeOptions{InitialDelay: time.Hour, MaxHedges: 2},
+			[]hedgeEndpoint{{statusCode: 500, body: "primary"}, {statusCode: 503, body: "first"}, {statusCode: 404, body: "second"}},
+			503, "first", []int{0, 1, 2}, nil, 2.0, 0.0,
+		)
+	})
+
+	t.Run("MaxHedges", func(t *testing.T) {
+		testHandlerHedging(t,
+			HedgeOptions{InitialDelay: time.Millisecond},
+			[]hedgeEndpoint{{statusCode: 500, body: "primary"}, {statusCode: 500, body: "hedge"}, {statusCode: 200, body: "unused"}},
+			500, "primary", []int{0, 1}, nil, 1.0, 0.0,
+		)
+	})
+
+	t.Run("Timeout", testHandlerHedgingTimeout)
+}
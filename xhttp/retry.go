@@ -1,7 +1,11 @@
 package xhttp
 
 import (
+	"context"
+	"math"
+	"math/rand"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/go-kit/kit/log"
@@ -53,10 +57,35 @@ type RetryOptions struct {
 	// Retries is the count of retries.  If not positive, then no transactor decoration is performed.
 	Retries int
 
-	// Interval is the time between retries.  If not set, DefaultRetryInterval is used.
+	// Interval is the time between retries.  If not set, DefaultRetryInterval is used.  When Multiplier is set,
+	// this is the interval before the first retry.
 	Interval time.Duration
 
-	// Sleep is function used to wait out a duration.  If unset, time.Sleep is used.
+	// Multiplier, if greater than 1, produces exponential backoff:  each interval is the previous one multiplied
+	// by this value.  By default, every retry waits the same Interval.
+	Multiplier float64
+
+	// MaxInterval caps the interval between retries, including an interval supplied by a Retry-After header.
+	// If unset, intervals are not capped.
+	MaxInterval time.Duration
+
+	// Jitter enables full jitter, where each retry waits a random duration between zero and the computed interval.
+	// This spreads out retries from many clients that failed at the same time.
+	Jitter bool
+
+	// MaxElapsedTime is the total time, starting with the initial attempt, after which no more retries are attempted.
+	// A retry is not attempted if its wait would end after this time.  If unset, only Retries limits retrying.
+	MaxElapsedTime time.Duration
+
+	// HonorRetryAfter causes the Retry-After header of a 429 or 503 response, when present, to be used in place of
+	// the computed interval.  Both delay-seconds and HTTP-date values are supported.
+	HonorRetryAfter bool
+
+	// Now is the function used to obtain the current time.  If unset, time.Now is used.
+	Now func() time.Time
+
+	// Sleep is function used to wait out a duration.  If unset, the wait ends early with the request context's
+	// error if that context is canceled or times out.
 	Sleep func(time.Duration)
 
 	// ShouldRetry is the retry predicate.  Defaults to DefaultShouldRetry if unset.
@@ -107,15 +136,18 @@ func RetryTransactor(o RetryOptions, next func(*http.Request) (*http.Response, e
 		o.Interval = DefaultRetryInterval
 	}
 
-	if o.Sleep == nil {
-		o.Sleep = time.Sleep
+	if o.Now == nil {
+		o.Now = time.Now
 	}
 
 	return func(request *http.Request) (*http.Response, error) {
 		if err := EnsureRewindable(request); err != nil {
 			return nil, err
 		}
-		var statusCode int
+		var (
+			statusCode int
+			start      = o.Now()
+		)
 
 		// initial attempt:
 		response, err := next(request)
@@ -124,8 +156,22 @@ func RetryTransactor(o RetryOptions, next func(*http.Request) (*http.Response, e
 		}
 
 		for r := 0; r < o.Retries && ((err != nil && o.ShouldRetry(err)) || o.ShouldRetryStatus(statusCode)); r++ {
+			wait := o.wait(r, response)
+			if o.MaxElapsedTime > 0 && wait > o.MaxElapsedTime-o.Now().Sub(start) {
+				o.Logger.Log(level.Key(), level.DebugValue(), logging.MessageKey(), "HTTP transaction retries exceeded the maximum elapsed time", "url", request.URL.String(), "retries", r, "maxElapsedTime", o.MaxElapsedTime)
+				break
+			}
+
 			o.Counter.Add(1.0)
-			o.Sleep(o.Interval)
+			if err := o.sleep(request.Context(), wait); err != nil {
+				if response != nil && response.Body != nil {
+					response.Body.Close()
+				}
+
+				o.Logger.Log(level.Key(), level.DebugValue(), logging.MessageKey(), "HTTP transaction retries canceled", "url", request.URL.String(), logging.ErrorKey(), err, "retries", r)
+				return nil, err
+			}
+
 			o.Logger.Log(level.Key(), level.DebugValue(), logging.MessageKey(), "retrying HTTP transaction", "url", request.URL.String(), logging.ErrorKey(), err, "retry", r+1, "statusCode", statusCode)
 
 			if err := Rewind(request); err != nil {
@@ -146,3 +192,99 @@ func RetryTransactor(o RetryOptions, next func(*http.Request) (*http.Response, e
 		return response, err
 	}
 }
+
+// sleep waits out the given duration, returning the context's error if the context ends first
+func (o RetryOptions) sleep(ctx context.Context, d time.Duration) error {
+	if o.Sleep != nil {
+		o.Sleep(d)
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// backoff computes the interval before the given retry, numbered from zero, without jitter
+func (o RetryOptions) backoff(retry int) time.Duration {
+	if o.Multiplier <= 1 {
+		return o.Interval
+	}
+
+	interval := float64(o.Interval) * math.Pow(o.Multiplier, float64(retry))
+	if o.MaxInterval > 0 && interval > float64(o.MaxInterval) {
+		return o.MaxInterval
+	}
+
+	if interval > math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+
+	return time.Duration(interval)
+}
+
+// wait computes how long to wait before the given retry, numbered from zero, in response to the
+// previous attempt's response, which may be nil
+func (o RetryOptions) wait(retry int, previous *http.Response) time.Duration {
+	if o.HonorRetryAfter && previous != nil &&
+		(previous.StatusCode == http.StatusTooManyRequests || previous.StatusCode == http.StatusServiceUnavailable) {
+		if retryAfter, ok := ParseRetryAfter(previous.Header.Get("Retry-After"), o.Now()); ok {
+			if o.MaxInterval > 0 && retryAfter > o.MaxInterval {
+				return o.MaxInterval
+			}
+
+			return retryAfter
+		}
+	}
+
+	interval := o.backoff(retry)
+	if o.Jitter && interval > 0 {
+		interval = time.Duration(rand.Int63n(int64(interval) + 1))
+	}
+
+	return interval
+}
+
+// ParseRetryAfter parses the value of a Retry-After header, which is either a number of seconds or an HTTP-date,
+// into the duration to wait from now.  Dates in the past produce a zero duration, and a number of seconds too large
+// to be represented as a time.Duration produces the largest possible duration.  This function returns false
+// if the value is blank or cannot be parsed.
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if len(value) == 0 {
+		return 0, false
+	}
+
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+		// ParseInt returns the nearest representable value, so the sign is preserved
+		err = nil
+	}
+
+	if err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+
+		if seconds > math.MaxInt64/int64(time.Second) {
+			return time.Duration(math.MaxInt64), true
+		}
+
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := date.Sub(now); wait > 0 {
+			return wait, true
+		}
+
+		return 0, true
+	}
+
+	return 0, false
+}
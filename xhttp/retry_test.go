@@ -1,10 +1,12 @@
 package xhttp
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
 	"net"
 	"net/http"
 	"net/http/httptest"
@@ -250,6 +252,344 @@ func testRetryTransactorRewindError(t *testing.T) {
 	assert.Equal(expectedError, actualError)
 }
 
+func testRetryTransactorBackoff(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		transactor = func(*http.Request) (*http.Response, error) {
+			return nil, &net.DNSError{IsTemporary: true}
+		}
+
+		slept []time.Duration
+		retry = RetryTransactor(
+			RetryOptions{
+				Logger:      logging.NewTestLogger(nil, t),
+				Retries:     5,
+				Interval:    time.Second,
+				Multiplier:  2.0,
+				MaxInterval: 5 * time.Second,
+				Sleep: func(d time.Duration) {
+					slept = append(slept, d)
+				},
+			},
+			transactor,
+		)
+	)
+
+	require.NotNil(retry)
+	retry(httptest.NewRequest("GET", "/", nil))
+	assert.Equal(
+		[]time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second},
+		slept,
+	)
+}
+
+func testRetryTransactorJitter(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		transactor = func(*http.Request) (*http.Response, error) {
+			return nil, &net.DNSError{IsTemporary: true}
+		}
+
+		slept []time.Duration
+		retry = RetryTransactor(
+			RetryOptions{
+				Logger:     logging.NewTestLogger(nil, t),
+				Retries:    10,
+				Interval:   time.Second,
+				Multiplier: 2.0,
+				Jitter:     true,
+				Sleep: func(d time.Duration) {
+					slept = append(slept, d)
+				},
+			},
+			transactor,
+		)
+	)
+
+	require.NotNil(retry)
+	retry(httptest.NewRequest("GET", "/", nil))
+	require.Len(slept, 10)
+	for i, d := range slept {
+		assert.True(d >= 0)
+		assert.True(d <= time.Second<<uint(i))
+	}
+}
+
+func testRetryTransactorMaxElapsedTime(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		counter = generic.NewCounter("test")
+		now     = time.Now()
+
+		transactorCount = 0
+		transactor      = func(*http.Request) (*http.Response, error) {
+			transactorCount++
+			return nil, &net.DNSError{IsTemporary: true}
+		}
+
+		retry = RetryTransactor(
+			RetryOptions{
+				Logger:         logging.NewTestLogger(nil, t),
+				Retries:        10,
+				Counter:        counter,
+				Interval:       time.Second,
+				Multiplier:     2.0,
+				MaxElapsedTime: 10 * time.Second,
+				Now:            func() time.Time { return now },
+				Sleep: func(d time.Duration) {
+					now = now.Add(d)
+				},
+			},
+			transactor,
+		)
+	)
+
+	require.NotNil(retry)
+	retry(httptest.NewRequest("GET", "/", nil))
+
+	// waits of 1s, 2s, and 4s fit within 10s, but the next wait of 8s does not
+	assert.Equal(4, transactorCount)
+	assert.Equal(3.0, counter.Value())
+}
+
+func testRetryTransactorRetryAfter(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		now     = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+		responses = []*http.Response{
+			{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"7"}}},
+			{StatusCode: http.StatusServiceUnavailable, Header: http.Header{"Retry-After": {now.Add(time.Minute).Format(http.TimeFormat)}}},
+			{StatusCode: http.StatusServiceUnavailable, Header: http.Header{"Retry-After": {"garbage"}}},
+			{StatusCode: http.StatusInternalServerError, Header: http.Header{"Retry-After": {"30"}}},
+			{StatusCode: http.StatusOK},
+		}
+
+		transactorCount = 0
+		transactor      = func(*http.Request) (*http.Response, error) {
+			response := responses[transactorCount]
+			transactorCount++
+			return response, nil
+		}
+
+		slept []time.Duration
+		retry = RetryTransactor(
+			RetryOptions{
+				Logger:            logging.NewTestLogger(nil, t),
+				Retries:           5,
+				Interval:          time.Second,
+				HonorRetryAfter:   true,
+				ShouldRetryStatus: func(status int) bool { return status != http.StatusOK },
+				Now:               func() time.Time { return now },
+				Sleep: func(d time.Duration) {
+					slept = append(slept, d)
+				},
+			},
+			transactor,
+		)
+	)
+
+	require.NotNil(retry)
+	response, err := retry(httptest.NewRequest("GET", "/", nil))
+	assert.NoError(err)
+	require.NotNil(response)
+	assert.Equal(http.StatusOK, response.StatusCode)
+	assert.Equal([]time.Duration{7 * time.Second, time.Minute, time.Second, time.Second}, slept)
+}
+
+func testRetryTransactorRetryAfterMaxInterval(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		responses = []*http.Response{
+			{StatusCode: http.StatusServiceUnavailable, Header: http.Header{"Retry-After": {"3600"}}},
+			{StatusCode: http.StatusServiceUnavailable, Header: http.Header{"Retry-After": {"99999999999999999999"}}},
+			{StatusCode: http.StatusServiceUnavailable, Header: http.Header{"Retry-After": {"2"}}},
+			{StatusCode: http.StatusOK},
+		}
+
+		transactorCount = 0
+		transactor      = func(*http.Request) (*http.Response, error) {
+			response := responses[transactorCount]
+			transactorCount++
+			return response, nil
+		}
+
+		slept []time.Duration
+		retry = RetryTransactor(
+			RetryOptions{
+				Logger:            logging.NewTestLogger(nil, t),
+				Retries:           5,
+				MaxInterval:       10 * time.Second,
+				HonorRetryAfter:   true,
+				ShouldRetryStatus: func(status int) bool { return status != http.StatusOK },
+				Sleep: func(d time.Duration) {
+					slept = append(slept, d)
+				},
+			},
+			transactor,
+		)
+	)
+
+	require.NotNil(retry)
+	response, err := retry(httptest.NewRequest("GET", "/", nil))
+	assert.NoError(err)
+	require.NotNil(response)
+	assert.Equal(http.StatusOK, response.StatusCode)
+	assert.Equal([]time.Duration{10 * time.Second, 10 * time.Second, 2 * time.Second}, slept)
+}
+
+func testRetryTransactorRetryAfterMaxElapsedTime(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		transactorCount = 0
+		transactor      = func(*http.Request) (*http.Response, error) {
+			transactorCount++
+			return &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"99999999999999999999"}}}, nil
+		}
+
+		retry = RetryTransactor(
+			RetryOptions{
+				Logger:            logging.NewTestLogger(nil, t),
+				Retries:           5,
+				MaxElapsedTime:    time.Minute,
+				HonorRetryAfter:   true,
+				ShouldRetryStatus: func(status int) bool { return status != http.StatusOK },
+				Sleep: func(time.Duration) {
+					assert.Fail("No retry should have been attempted")
+				},
+			},
+			transactor,
+		)
+	)
+
+	require.NotNil(retry)
+	response, err := retry(httptest.NewRequest("GET", "/", nil))
+	assert.NoError(err)
+	require.NotNil(response)
+	assert.Equal(http.StatusTooManyRequests, response.StatusCode)
+	assert.Equal(1, transactorCount)
+}
+
+func testRetryTransactorContextCanceled(t *testing.T) {
+	var (
+		assert      = assert.New(t)
+		require     = require.New(t)
+		ctx, cancel = context.WithCancel(context.Background())
+		counter     = generic.NewCounter("test")
+
+		transactorCount = 0
+		transactor      = func(*http.Request) (*http.Response, error) {
+			transactorCount++
+			cancel()
+			return nil, &net.DNSError{IsTemporary: true}
+		}
+
+		retry = RetryTransactor(
+			RetryOptions{
+				Logger:   logging.NewTestLogger(nil, t),
+				Retries:  5,
+				Interval: time.Hour,
+				Counter:  counter,
+			},
+			transactor,
+		)
+
+		result = make(chan error, 1)
+	)
+
+	defer cancel()
+	require.NotNil(retry)
+	go func() {
+		response, err := retry(httptest.NewRequest("GET", "/", nil).WithContext(ctx))
+		assert.Nil(response)
+		result <- err
+	}()
+
+	select {
+	case err := <-result:
+		assert.Equal(context.Canceled, err)
+	case <-time.After(5 * time.Second):
+		require.Fail("The retry wait did not end when the context was canceled")
+	}
+
+	assert.Equal(1, transactorCount)
+	assert.Equal(1.0, counter.Value())
+}
+
+func testRetryTransactorContextCanceledSleep(t *testing.T) {
+	var (
+		assert      = assert.New(t)
+		require     = require.New(t)
+		ctx, cancel = context.WithCancel(context.Background())
+
+		transactorCount = 0
+		transactor      = func(*http.Request) (*http.Response, error) {
+			transactorCount++
+			return nil, &net.DNSError{IsTemporary: true}
+		}
+
+		retry = RetryTransactor(
+			RetryOptions{
+				Logger:  logging.NewTestLogger(nil, t),
+				Retries: 5,
+				Sleep: func(time.Duration) {
+					cancel()
+				},
+			},
+			transactor,
+		)
+	)
+
+	defer cancel()
+	require.NotNil(retry)
+	response, err := retry(httptest.NewRequest("GET", "/", nil).WithContext(ctx))
+	assert.Nil(response)
+	assert.Equal(context.Canceled, err)
+	assert.Equal(1, transactorCount)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	var (
+		now      = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+		testData = []struct {
+			value    string
+			expected time.Duration
+			ok       bool
+		}{
+			{"", 0, false},
+			{"120", 2 * time.Minute, true},
+			{"0", 0, true},
+			{"-1", 0, false},
+			{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second, true},
+			{now.Add(-time.Hour).Format(http.TimeFormat), 0, true},
+			{"not a valid value", 0, false},
+			{"9223372036", 9223372036 * time.Second, true},
+			{"9223372037", time.Duration(math.MaxInt64), true},
+			{"99999999999999999999", time.Duration(math.MaxInt64), true},
+			{"-99999999999999999999", 0, false},
+		}
+	)
+
+	for _, record := range testData {
+		t.Run(record.value, func(t *testing.T) {
+			assert := assert.New(t)
+			actual, ok := ParseRetryAfter(record.value, now)
+			assert.Equal(record.expected, actual)
+			assert.Equal(record.ok, ok)
+		})
+	}
+}
+
 func TestRetryTransactor(t *testing.T) {
 	t.Run("DefaultLogger", testRetryTransactorDefaultLogger)
 	t.Run("NoRetries", testRetryTransactorNoRetries)
@@ -272,4 +612,12 @@ func TestRetryTransactor(t *testing.T) {
 	t.Run("NotRewindable", testRetryTransactorNotRewindable)
 	t.Run("RewindError", testRetryTransactorRewindError)
 	t.Run("StatusRetry", testRetryTransactorStatus)
+	t.Run("Backoff", testRetryTransactorBackoff)
+	t.Run("Jitter", testRetryTransactorJitter)
+	t.Run("MaxElapsedTime", testRetryTransactorMaxElapsedTime)
+	t.Run("RetryAfter", testRetryTransactorRetryAfter)
+	t.Run("RetryAfterMaxInterval", testRetryTransactorRetryAfterMaxInterval)
+	t.Run("RetryAfterMaxElapsedTime", testRetryTransactorRetryAfterMaxElapsedTime)
+	t.Run("ContextCanceled", testRetryTransactorContextCanceled)
+	t.Run("ContextCanceledSleep", testRetryTransactorContextCanceledSleep)
 }
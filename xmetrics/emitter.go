@@ -0,0 +1,281 @@
+package xmetrics
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+const (
+	StatsDProtocol   = "statsd"
+	GraphiteProtocol = "graphite"
+
+	DefaultEmitInterval = 10 * time.Second
+	DefaultEmitTimeout  = 5 * time.Second
+
+	// maxStatsDPacket is the largest UDP payload sent to StatsD, chosen to avoid fragmentation on common networks
+	maxStatsDPacket = 1432
+)
+
+// EmitterOptions configures the periodic emission of a Registry's metrics to StatsD or Graphite, for sites
+// which do not scrape metrics with Prometheus.
+//
+// Metrics are emitted as dotted paths, with each label name and value appended to the metric name in label order.
+// For example, a counter named "requests" with a "code" label of "200" is emitted as "requests.code.200".
+// Histograms and summaries are emitted as two metrics, with ".count" and ".sum" appended to the name.
+//
+// StatsD counters are emitted as the change since the previous emission, and StatsD gauges as their current values.
+// Graphite metrics are all emitted as their current values, since Graphite functions such as derivative can compute rates.
+type EmitterOptions struct {
+	// Protocol is either StatsDProtocol or GraphiteProtocol.  This field is required.
+	Protocol string
+
+	// Address is the host:port of the StatsD or Graphite server.  This field is required.
+	Address string
+
+	// Network is the network used to connect to the Address.  If unset, "udp" is used for StatsD and "tcp" for Graphite.
+	Network string
+
+	// Interval is how often metrics are emitted.  If unset, DefaultEmitInterval is used.
+	Interval time.Duration
+
+	// Timeout is the time allowed to connect and write each emission.  If unset, DefaultEmitTimeout is used.
+	Timeout time.Duration
+
+	// Prefix is an optional path prepended to every metric, e.g. "xmidt.talaria".
+	Prefix string
+}
+
+func (eo *EmitterOptions) network() string {
+	if len(eo.Network) > 0 {
+		return eo.Network
+	}
+
+	if eo.Protocol == StatsDProtocol {
+		return "udp"
+	}
+
+	return "tcp"
+}
+
+func (eo *EmitterOptions) interval() time.Duration {
+	if eo.Interval > 0 {
+		return eo.Interval
+	}
+
+	return DefaultEmitInterval
+}
+
+func (eo *EmitterOptions) timeout() time.Duration {
+	if eo.Timeout > 0 {
+		return eo.Timeout
+	}
+
+	return DefaultEmitTimeout
+}
+
+// sanitizePath replaces any characters that are not safe in StatsD and Graphite paths
+func sanitizePath(v string) string {
+	return strings.Map(
+		func(r rune) rune {
+			switch {
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+				return r
+			default:
+				return '_'
+			}
+		},
+		v,
+	)
+}
+
+// emitter is a Mirror that formats metrics as lines of StatsD or Graphite protocol
+type emitter struct {
+	options EmitterOptions
+	statsD  bool
+
+	// previous holds the last emitted value of each StatsD counter, so that deltas can be emitted
+	previous  map[string]float64
+	timestamp string
+	lines     []string
+}
+
+func newEmitter(o EmitterOptions) (*emitter, error) {
+	switch o.Protocol {
+	case StatsDProtocol, GraphiteProtocol:
+	default:
+		return nil, fmt.Errorf("Unsupported emitter protocol: %s", o.Protocol)
+	}
+
+	if len(o.Address) == 0 {
+		return nil, fmt.Errorf("An address is required for the %s emitter", o.Protocol)
+	}
+
+	return &emitter{
+		options:  o,
+		statsD:   o.Protocol == StatsDProtocol,
+		previous: make(map[string]float64),
+	}, nil
+}
+
+// path produces the dotted path for a metric
+func (e *emitter) path(name string, labels map[string]string, suffix string) string {
+	var (
+		output bytes.Buffer
+		keys   = make([]string, 0, len(labels))
+	)
+
+	if len(e.options.Prefix) > 0 {
+		output.WriteString(e.options.Prefix)
+		output.WriteRune('.')
+	}
+
+	output.WriteString(sanitizePath(name))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	for _, k := range keys {
+		output.WriteRune('.')
+		output.WriteString(sanitizePath(k))
+		output.WriteRune('.')
+		output.WriteString(sanitizePath(labels[k]))
+	}
+
+	output.WriteString(suffix)
+	return output.String()
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func (e *emitter) counter(path string, value float64) {
+	if !e.statsD {
+		e.lines = append(e.lines, path+" "+formatValue(value)+" "+e.timestamp)
+		return
+	}
+
+	delta := value - e.previous[path]
+	if delta < 0 {
+		// the counter was reset, so all of its current value is new
+		delta = value
+	}
+
+	e.previous[path] = value
+	if delta > 0 {
+		e.lines = append(e.lines, path+":"+formatValue(delta)+"|c")
+	}
+}
+
+func (e *emitter) gauge(path string, value float64) {
+	if e.statsD {
+		e.lines = append(e.lines, path+":"+formatValue(value)+"|g")
+	} else {
+		e.lines = append(e.lines, path+" "+formatValue(value)+" "+e.timestamp)
+	}
+}
+
+func (e *emitter) MirrorCounter(name string, labels map[string]string, value float64) {
+	e.counter(e.path(name, labels, ""), value)
+}
+
+func (e *emitter) MirrorGauge(name string, labels map[string]string, value float64) {
+	e.gauge(e.path(name, labels, ""), value)
+}
+
+func (e *emitter) MirrorHistogram(name string, labels map[string]string, snapshot HistogramSnapshot) {
+	e.counter(e.path(name, labels, ".count"), float64(snapshot.Count))
+	e.counter(e.path(name, labels, ".sum"), snapshot.Sum)
+}
+
+// format gathers the current metrics and returns the payloads to send.  StatsD over UDP is split into
+// packets of limited size, while everything else is sent as a single payload.
+func (e *emitter) format(g prometheus.Gatherer, now time.Time) ([][]byte, error) {
+	e.timestamp = strconv.FormatInt(now.Unix(), 10)
+	e.lines = e.lines[:0]
+	err := mirrorTo(g, e)
+
+	var (
+		payloads [][]byte
+		current  bytes.Buffer
+		limit    = 0
+	)
+
+	if e.statsD && strings.HasPrefix(e.options.network(), "udp") {
+		limit = maxStatsDPacket
+	}
+
+	for _, line := range e.lines {
+		if limit > 0 && current.Len() > 0 && current.Len()+len(line)+1 > limit {
+			payloads = append(payloads, append([]byte(nil), current.Bytes()...))
+			current.Reset()
+		}
+
+		current.WriteString(line)
+		current.WriteRune('\n')
+	}
+
+	if current.Len() > 0 {
+		payloads = append(payloads, current.Bytes())
+	}
+
+	return payloads, err
+}
+
+// emit sends the current metrics to the configured server
+func (e *emitter) emit(g prometheus.Gatherer) error {
+	payloads, gatherErr := e.format(g, time.Now())
+	if len(payloads) == 0 {
+		return gatherErr
+	}
+
+	conn, err := net.DialTimeout(e.options.network(), e.options.Address, e.options.timeout())
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+	conn.SetWriteDeadline(time.Now().Add(e.options.timeout()))
+	for _, p := range payloads {
+		if _, err := conn.Write(p); err != nil {
+			return err
+		}
+	}
+
+	return gatherErr
+}
+
+// emitLoop emits the gatherer's metrics on the configured interval until the done channel is closed
+func emitLoop(logger log.Logger, g prometheus.Gatherer, e *emitter, done <-chan struct{}) {
+	ticker := time.NewTicker(e.options.interval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+
+		case <-ticker.C:
+			if err := e.emit(g); err != nil {
+				logger.Log(
+					level.Key(), level.ErrorValue(),
+					logging.MessageKey(), "unable to emit metrics",
+					"protocol", e.options.Protocol,
+					"address", e.options.Address,
+					logging.ErrorKey(), err,
+				)
+			}
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package xmetrics
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newEmitterRegistry(t *testing.T, o *Options) Registry {
+	if o == nil {
+		o = new(Options)
+	}
+
+	o.DisableGoCollector = true
+	o.DisableProcessCollector = true
+	o.Metrics = []Metric{
+		Metric{Name: "requests", Type: CounterType, LabelNames: []string{"code", "method"}},
+		Metric{Name: "devices", Type: GaugeType},
+		Metric{Name: "latency", Type: HistogramType, Buckets: []float64{1.0}},
+	}
+
+	r, err := NewRegistry(o)
+	require.NoError(t, err)
+
+	r.NewCounter("requests").With("code", "200", "method", "GET").Add(3.0)
+	r.NewGauge("devices").Set(12.0)
+	r.NewHistogram("latency", 0).Observe(0.5)
+	return r
+}
+
+func TestEmitterOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	statsD := EmitterOptions{Protocol: StatsDProtocol}
+	assert.Equal("udp", statsD.network())
+	assert.Equal(DefaultEmitInterval, statsD.interval())
+	assert.Equal(DefaultEmitTimeout, statsD.timeout())
+
+	graphite := EmitterOptions{Protocol: GraphiteProtocol, Network: "tcp4", Interval: time.Minute, Timeout: time.Second}
+	assert.Equal("tcp4", graphite.network())
+	assert.Equal(time.Minute, graphite.interval())
+	assert.Equal(time.Second, graphite.timeout())
+	assert.Equal("tcp", (&EmitterOptions{Protocol: GraphiteProtocol}).network())
+}
+
+func TestNewEmitter(t *testing.T) {
+	assert := assert.New(t)
+
+	e, err := newEmitter(EmitterOptions{Protocol: "carrier pigeon", Address: "localhost:8125"})
+	assert.Nil(e)
+	assert.Error(err)
+
+	e, err = newEmitter(EmitterOptions{Protocol: StatsDProtocol})
+	assert.Nil(e)
+	assert.Error(err)
+
+	e, err = newEmitter(EmitterOptions{Protocol: StatsDProtocol, Address: "localhost:8125"})
+	assert.NotNil(e)
+	assert.NoError(err)
+}
+
+func TestSanitizePath(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("mac_112233445566", sanitizePath("mac:112233445566"))
+	assert.Equal("a_b_c-d_e", sanitizePath("a.b|c-d e"))
+}
+
+func TestEmitterFormatStatsD(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		r       = newEmitterRegistry(t, nil)
+
+		e, err = newEmitter(EmitterOptions{Protocol: StatsDProtocol, Address: "localhost:8125", Prefix: "xmidt"})
+	)
+
+	require.NoError(err)
+	payloads, err := e.format(r, time.Now())
+	require.NoError(err)
+	require.Len(payloads, 1)
+	assert.Equal(
+		"xmidt.test_test_devices:12|g\n"+
+			"xmidt.test_test_latency.count:1|c\n"+
+			"xmidt.test_test_latency.sum:0.5|c\n"+
+			"xmidt.test_test_requests.code.200.method.GET:3|c\n",
+		string(payloads[0]),
+	)
+
+	// counters are emitted as deltas, and unchanged counters are not emitted
+	r.NewCounter("requests").With("code", "200", "method", "GET").Add(2.0)
+	payloads, err = e.format(r, time.Now())
+	require.NoError(err)
+	require.Len(payloads, 1)
+	assert.Equal(
+		"xmidt.test_test_devices:12|g\n"+
+			"xmidt.test_test_requests.code.200.method.GET:2|c\n",
+		string(payloads[0]),
+	)
+}
+
+func TestEmitterFormatStatsDPackets(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		r       = newEmitterRegistry(t, nil)
+
+		e, err = newEmitter(EmitterOptions{Protocol: StatsDProtocol, Address: "localhost:8125"})
+	)
+
+	require.NoError(err)
+	c := r.NewCounter("requests")
+	for i := 0; i < 100; i++ {
+		c.With("code", strings.Repeat("x", i), "method", "GET").Add(1.0)
+	}
+
+	payloads, err := e.format(r, time.Now())
+	require.NoError(err)
+	assert.True(len(payloads) > 1)
+	for _, p := range payloads {
+		assert.True(len(p) <= maxStatsDPacket)
+		assert.True(strings.HasSuffix(string(p), "\n"))
+	}
+}
+
+func TestEmitterFormatGraphite(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		r       = newEmitterRegistry(t, nil)
+		now     = time.Unix(1600000000, 0)
+
+		e, err = newEmitter(EmitterOptions{Protocol: GraphiteProtocol, Address: "localhost:2003"})
+	)
+
+	require.NoError(err)
+	payloads, err := e.format(r, now)
+	require.NoError(err)
+	require.Len(payloads, 1)
+	assert.Equal(
+		"test_test_devices 12 1600000000\n"+
+			"test_test_latency.count 1 1600000000\n"+
+			"test_test_latency.sum 0.5 1600000000\n"+
+			"test_test_requests.code.200.method.GET 3 1600000000\n",
+		string(payloads[0]),
+	)
+}
+
+func TestEmitterEmitGraphite(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		require  = require.New(t)
+		r        = newEmitterRegistry(t, nil)
+		received = make(chan []string, 1)
+	)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(err)
+	defer l.Close()
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+		var lines []string
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+
+		received <- lines
+	}()
+
+	e, err := newEmitter(EmitterOptions{Protocol: GraphiteProtocol, Address: l.Addr().String()})
+	require.NoError(err)
+	require.NoError(e.emit(r))
+
+	select {
+	case lines := <-received:
+		assert.Len(lines, 4)
+	case <-time.After(5 * time.Second):
+		assert.Fail("No metrics were received")
+	}
+}
+
+func TestEmitterEmitDialError(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		r       = newEmitterRegistry(t, nil)
+	)
+
+	// grab a free port, then close it so that nothing is listening
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(err)
+	address := l.Addr().String()
+	l.Close()
+
+	e, err := newEmitter(EmitterOptions{Protocol: GraphiteProtocol, Address: address})
+	require.NoError(err)
+	assert.Error(e.emit(r))
+}
+
+func testRegistryEmitterInvalid(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		r, err = NewRegistry(&Options{Emitter: &EmitterOptions{Protocol: "nosuch"}})
+	)
+
+	assert.Nil(r)
+	assert.Error(err)
+}
+
+func testRegistryEmitterStatsD(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+	)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(err)
+	defer conn.Close()
+
+	r := newEmitterRegistry(t, &Options{
+		Emitter: &EmitterOptions{
+			Protocol: StatsDProtocol,
+			Address:  conn.LocalAddr().String(),
+			Interval: 10 * time.Millisecond,
+		},
+	})
+
+	defer r.Stop()
+
+	buffer := make([]byte, maxStatsDPacket)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buffer)
+	require.NoError(err)
+	assert.Contains(string(buffer[:n]), "test_test_devices:12|g\n")
+}
+
+func TestRegistryEmitter(t *testing.T) {
+	t.Run("Invalid", testRegistryEmitterInvalid)
+	t.Run("StatsD", testRegistryEmitterStatsD)
+}
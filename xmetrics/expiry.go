@@ -0,0 +1,221 @@
+package xmetrics
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+// deleter is the behavior of Prometheus metric vectors that allows children to be removed
+type deleter interface {
+	Delete(prometheus.Labels) bool
+}
+
+// trackedSeries is a single child of a metric vector along with the last time it was updated
+type trackedSeries struct {
+	labels  prometheus.Labels
+	updated time.Time
+}
+
+// seriesTracker records when each labelled series of a single metric was last updated, so that
+// stale series can be removed from the underlying vector
+type seriesTracker struct {
+	ttl time.Duration
+	vec deleter
+	now func() time.Time
+
+	lock   sync.Mutex
+	series map[string]trackedSeries
+}
+
+// seriesLabels converts label/value pairs into Prometheus labels and a key that is independent of the order of the pairs
+func seriesLabels(labelsAndValues []string) (prometheus.Labels, string) {
+	labels := make(prometheus.Labels, len(labelsAndValues)/2)
+	for i := 0; i+1 < len(labelsAndValues); i += 2 {
+		labels[labelsAndValues[i]] = labelsAndValues[i+1]
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	var key strings.Builder
+	for _, name := range names {
+		key.WriteString(name)
+		key.WriteByte(0)
+		key.WriteString(labels[name])
+		key.WriteByte(0)
+	}
+
+	return labels, key.String()
+}
+
+// update invokes f, which updates the series with the given label/value pairs, and records the time of the update.
+// The update happens under this tracker's lock, so that a series cannot be removed between the update and its recording.
+func (st *seriesTracker) update(labelsAndValues []string, f func()) {
+	if len(labelsAndValues) == 0 {
+		// series without labels never expire
+		f()
+		return
+	}
+
+	labels, key := seriesLabels(labelsAndValues)
+
+	defer st.lock.Unlock()
+	st.lock.Lock()
+
+	f()
+	st.series[key] = trackedSeries{labels: labels, updated: st.now()}
+}
+
+// sweep removes the series which have not been updated within the ttl, returning the number removed
+func (st *seriesTracker) sweep() int {
+	defer st.lock.Unlock()
+	st.lock.Lock()
+
+	var (
+		removed = 0
+		cutoff  = st.now().Add(-st.ttl)
+	)
+
+	for key, s := range st.series {
+		if s.updated.Before(cutoff) {
+			st.vec.Delete(s.labels)
+			delete(st.series, key)
+			removed++
+		}
+	}
+
+	return removed
+}
+
+// seriesExpiry holds the trackers for all metrics whose series expire
+type seriesExpiry struct {
+	ttl func(fqn, name string) time.Duration
+	now func() time.Time
+
+	lock     sync.Mutex
+	trackers map[string]*seriesTracker
+}
+
+func newSeriesExpiry(ttl func(string, string) time.Duration) *seriesExpiry {
+	return &seriesExpiry{
+		ttl:      ttl,
+		now:      time.Now,
+		trackers: make(map[string]*seriesTracker),
+	}
+}
+
+// tracker returns the tracker for the given metric, or nil if the metric's series do not expire
+func (se *seriesExpiry) tracker(fqn, name string, vec deleter) *seriesTracker {
+	ttl := se.ttl(fqn, name)
+	if ttl <= 0 {
+		return nil
+	}
+
+	defer se.lock.Unlock()
+	se.lock.Lock()
+
+	st, ok := se.trackers[fqn]
+	if !ok {
+		st = &seriesTracker{
+			ttl:    ttl,
+			vec:    vec,
+			now:    se.now,
+			series: make(map[string]trackedSeries),
+		}
+
+		se.trackers[fqn] = st
+	}
+
+	return st
+}
+
+// sweep removes the stale series of every tracked metric, returning the number removed
+func (se *seriesExpiry) sweep() int {
+	se.lock.Lock()
+	trackers := make([]*seriesTracker, 0, len(se.trackers))
+	for _, st := range se.trackers {
+		trackers = append(trackers, st)
+	}
+
+	se.lock.Unlock()
+
+	removed := 0
+	for _, st := range trackers {
+		removed += st.sweep()
+	}
+
+	return removed
+}
+
+// expiryLoop sweeps stale series on the given interval until the done channel is closed
+func expiryLoop(logger log.Logger, se *seriesExpiry, interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+
+		case <-ticker.C:
+			if removed := se.sweep(); removed > 0 {
+				logger.Log(
+					level.Key(), level.DebugValue(),
+					logging.MessageKey(), "removed stale series",
+					"count", removed,
+				)
+			}
+		}
+	}
+}
+
+type expiringCounter struct {
+	metrics.Counter
+	tracker         *seriesTracker
+	labelsAndValues []string
+}
+
+func (ec expiringCounter) With(labelsAndValues ...string) metrics.Counter {
+	return expiringCounter{
+		ec.Counter.With(labelsAndValues...),
+		ec.tracker,
+		append(ec.labelsAndValues[:len(ec.labelsAndValues):len(ec.labelsAndValues)], labelsAndValues...),
+	}
+}
+
+func (ec expiringCounter) Add(delta float64) {
+	ec.tracker.update(ec.labelsAndValues, func() { ec.Counter.Add(delta) })
+}
+
+type expiringGauge struct {
+	metrics.Gauge
+	tracker         *seriesTracker
+	labelsAndValues []string
+}
+
+func (eg expiringGauge) With(labelsAndValues ...string) metrics.Gauge {
+	return expiringGauge{
+		eg.Gauge.With(labelsAndValues...),
+		eg.tracker,
+		append(eg.labelsAndValues[:len(eg.labelsAndValues):len(eg.labelsAndValues)], labelsAndValues...),
+	}
+}
+
+func (eg expiringGauge) Set(value float64) {
+	eg.tracker.update(eg.labelsAndValues, func() { eg.Gauge.Set(value) })
+}
+
+func (eg expiringGauge) Add(delta float64) {
+	eg.tracker.update(eg.labelsAndValues, func() { eg.Gauge.Add(delta) })
+}
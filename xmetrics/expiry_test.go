@@ -0,0 +1,194 @@
+package xmetrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+// gatherSeries returns the label values of the given label for each series of the named metric
+func gatherSeries(t *testing.T, g prometheus.Gatherer, name, label string) []string {
+	families, err := g.Gather()
+	require.NoError(t, err)
+
+	var values []string
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+
+		for _, m := range family.GetMetric() {
+			values = append(values, labelValue(m, label))
+		}
+	}
+
+	return values
+}
+
+func labelValue(m *dto.Metric, label string) string {
+	for _, pair := range m.GetLabel() {
+		if pair.GetName() == label {
+			return pair.GetValue()
+		}
+	}
+
+	return ""
+}
+
+func TestSeriesLabels(t *testing.T) {
+	var (
+		assert = assert.New(t)
+
+		labels1, key1 = seriesLabels([]string{"a", "1", "b", "2"})
+		labels2, key2 = seriesLabels([]string{"b", "2", "a", "1"})
+		_, key3       = seriesLabels([]string{"a", "1", "b", "3"})
+	)
+
+	assert.Equal(prometheus.Labels{"a": "1", "b": "2"}, labels1)
+	assert.Equal(labels1, labels2)
+	assert.Equal(key1, key2)
+	assert.NotEqual(key1, key3)
+}
+
+func TestSeriesTracker(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		r, err = NewRegistry(&Options{DisableGoCollector: true, DisableProcessCollector: true})
+
+		now   = time.Now()
+		clock = func() time.Time { return now }
+	)
+
+	require.NoError(err)
+	vec := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "instances"}, []string{"instance"})
+	require.NoError(r.(*registry).Register(vec))
+
+	st := &seriesTracker{
+		ttl:    time.Minute,
+		vec:    vec,
+		now:    clock,
+		series: make(map[string]trackedSeries),
+	}
+
+	for _, instance := range []string{"a", "b"} {
+		instance := instance
+		st.update([]string{"instance", instance}, func() { vec.WithLabelValues(instance).Set(1.0) })
+	}
+
+	assert.Zero(st.sweep())
+	assert.ElementsMatch([]string{"a", "b"}, gatherSeries(t, r, "instances", "instance"))
+
+	now = now.Add(45 * time.Second)
+	st.update([]string{"instance", "b"}, func() { vec.WithLabelValues("b").Set(2.0) })
+
+	now = now.Add(30 * time.Second)
+	assert.Equal(1, st.sweep())
+	assert.Equal([]string{"b"}, gatherSeries(t, r, "instances", "instance"))
+
+	// an expired series reappears when updated again
+	st.update([]string{"instance", "a"}, func() { vec.WithLabelValues("a").Set(3.0) })
+	assert.ElementsMatch([]string{"a", "b"}, gatherSeries(t, r, "instances", "instance"))
+
+	now = now.Add(2 * time.Minute)
+	assert.Equal(2, st.sweep())
+	assert.Empty(gatherSeries(t, r, "instances", "instance"))
+
+	// series without labels are never tracked
+	called := false
+	st.update(nil, func() { called = true })
+	assert.True(called)
+	assert.Empty(st.series)
+}
+
+func TestRegistrySeriesTTL(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		r, err = NewRegistry(&Options{
+			DisableGoCollector:      true,
+			DisableProcessCollector: true,
+			SeriesTTL:               time.Hour,
+			SeriesTTLs:              map[string]time.Duration{"devices": 0},
+			MaxLabelValues:          2,
+			Metrics: []Metric{
+				Metric{Name: "instances", Type: GaugeType, LabelNames: []string{"instance"}},
+				Metric{Name: "requests", Type: CounterType, LabelNames: []string{"instance"}},
+				Metric{Name: "devices", Type: GaugeType, LabelNames: []string{"instance"}},
+			},
+		})
+
+		now = time.Now()
+	)
+
+	require.NoError(err)
+	defer r.Stop()
+
+	expiry := r.(*registry).expiry
+	require.NotNil(expiry)
+	expiry.now = func() time.Time { return now }
+
+	var (
+		instances = r.NewGauge("instances")
+		requests  = r.NewCounter("requests")
+		devices   = r.NewGauge("devices")
+	)
+
+	instances.With("instance", "a").Set(1.0)
+	instances.With("instance", "b").Add(1.0)
+	requests.With("instance", "a").Add(1.0)
+	devices.With("instance", "a").Set(1.0)
+
+	now = now.Add(45 * time.Minute)
+	instances.With("instance", "b").Set(2.0)
+
+	now = now.Add(30 * time.Minute)
+	assert.Equal(2, expiry.sweep())
+	assert.Equal([]string{"b"}, gatherSeries(t, r, "test_test_instances", "instance"))
+	assert.Empty(gatherSeries(t, r, "test_test_requests", "instance"))
+	assert.Equal([]string{"a"}, gatherSeries(t, r, "test_test_devices", "instance"))
+
+	// the label guard replaces values before expiry tracking, so overflowed values are tracked as OverflowLabelValue
+	instances.With("instance", "c").Set(1.0)
+	instances.With("instance", "d").Set(1.0)
+	assert.ElementsMatch([]string{"b", OverflowLabelValue}, gatherSeries(t, r, "test_test_instances", "instance"))
+
+	now = now.Add(2 * time.Hour)
+	assert.Equal(2, expiry.sweep())
+	assert.Empty(gatherSeries(t, r, "test_test_instances", "instance"))
+}
+
+func TestExpiryLoop(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		r, err = NewRegistry(&Options{
+			Logger:                  logging.NewTestLogger(nil, t),
+			DisableGoCollector:      true,
+			DisableProcessCollector: true,
+			SeriesTTL:               10 * time.Millisecond,
+			Metrics: []Metric{
+				Metric{Name: "instances", Type: GaugeType, LabelNames: []string{"instance"}},
+			},
+		})
+	)
+
+	require.NoError(err)
+	defer r.Stop()
+
+	r.NewGauge("instances").With("instance", "a").Set(1.0)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(gatherSeries(t, r, "test_test_instances", "instance")) > 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	assert.Empty(gatherSeries(t, r, "test_test_instances", "instance"))
+}
@@ -0,0 +1,81 @@
+package xmetrics
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// FederatedSource is a gatherer, such as a Registry embedded in a library, whose metrics are merged
+// into a federation
+type FederatedSource struct {
+	// Gatherer is the source of metrics.  This field is required.
+	Gatherer prometheus.Gatherer
+
+	// Prefix is prepended to the name of every metric from this source, e.g. "chrysom_".  This field is optional.
+	Prefix string
+
+	// Exclude holds name prefixes of metrics to drop from this source.  Exclusions are matched against the original
+	// names, before Prefix is applied.  This is typically used to drop the Go and process metrics, i.e. "go_" and "process_",
+	// which every Registry reports by default and which would otherwise conflict across sources.
+	Exclude []string
+}
+
+// excluded tests if a metric family with the given name should be dropped from this source
+func (fs FederatedSource) excluded(name string) bool {
+	for _, e := range fs.Exclude {
+		if strings.HasPrefix(name, e) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (fs FederatedSource) Gather() ([]*dto.MetricFamily, error) {
+	families, err := fs.Gatherer.Gather()
+	if len(fs.Prefix) == 0 && len(fs.Exclude) == 0 {
+		return families, err
+	}
+
+	result := make([]*dto.MetricFamily, 0, len(families))
+	for _, family := range families {
+		if fs.excluded(family.GetName()) {
+			continue
+		}
+
+		if len(fs.Prefix) > 0 {
+			name := fs.Prefix + family.GetName()
+			prefixed := *family
+			prefixed.Name = &name
+			family = &prefixed
+		}
+
+		result = append(result, family)
+	}
+
+	return result, err
+}
+
+// NewFederation creates a gatherer that merges several sources of metrics, so that they can be exposed
+// by a single handler rather than forcing all metrics into one global Registry.  Metric families with the
+// same name from different sources are merged, and any inconsistency such as a duplicate series is reported
+// as an error by Gather, in the same way as prometheus.Gatherers.
+func NewFederation(sources ...FederatedSource) (prometheus.Gatherer, error) {
+	gatherers := make(prometheus.Gatherers, 0, len(sources))
+	for i, s := range sources {
+		if s.Gatherer == nil {
+			return nil, fmt.Errorf("Federated source %d has no gatherer", i)
+		}
+
+		if len(s.Prefix) > 0 && !validMetricName.MatchString(s.Prefix) {
+			return nil, fmt.Errorf("Federated source %d has an invalid prefix: %s", i, s.Prefix)
+		}
+
+		gatherers = append(gatherers, s)
+	}
+
+	return gatherers, nil
+}
@@ -0,0 +1,95 @@
+package xmetrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFederationRegistry(t *testing.T, namespace string) Registry {
+	r, err := NewRegistry(&Options{
+		Namespace: namespace,
+		Subsystem: "test",
+		Metrics: []Metric{
+			Metric{Name: "requests", Type: CounterType},
+		},
+	})
+
+	require.NoError(t, err)
+	r.NewCounter("requests").Add(1.0)
+	return r
+}
+
+func TestNewFederation(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		primary = newFederationRegistry(t, "primary")
+		library = newFederationRegistry(t, "library")
+	)
+
+	f, err := NewFederation(
+		FederatedSource{Gatherer: primary},
+		FederatedSource{Gatherer: library, Prefix: "chrysom_", Exclude: []string{"go_", "process_"}},
+	)
+
+	require.NoError(err)
+	require.NotNil(f)
+
+	families, err := f.Gather()
+	require.NoError(err)
+
+	var (
+		names     = make(map[string]bool)
+		goMetrics int
+	)
+
+	for _, family := range families {
+		names[family.GetName()] = true
+		if family.GetName() == "go_goroutines" {
+			goMetrics++
+		}
+	}
+
+	assert.True(names["primary_test_requests"])
+	assert.True(names["chrysom_library_test_requests"])
+	assert.False(names["library_test_requests"])
+	assert.False(names["chrysom_go_goroutines"])
+	assert.Equal(1, goMetrics)
+}
+
+func TestNewFederationConflict(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+	)
+
+	// without exclusions, both registries report the same go and process series
+	f, err := NewFederation(
+		FederatedSource{Gatherer: newFederationRegistry(t, "primary")},
+		FederatedSource{Gatherer: newFederationRegistry(t, "library")},
+	)
+
+	require.NoError(err)
+	_, err = f.Gather()
+	assert.Error(err)
+}
+
+func TestNewFederationInvalid(t *testing.T) {
+	assert := assert.New(t)
+
+	f, err := NewFederation(FederatedSource{})
+	assert.Nil(f)
+	assert.Error(err)
+
+	f, err = NewFederation(FederatedSource{Gatherer: prometheus.NewRegistry(), Prefix: "not-valid"})
+	assert.Nil(f)
+	assert.Error(err)
+
+	f, err = NewFederation()
+	assert.NotNil(f)
+	assert.NoError(err)
+}
@@ -0,0 +1,140 @@
+package xmetrics
+
+import (
+	"sync"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/metrics"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+const (
+	// OverflowLabelValue is the label value used in place of any value beyond a label's cardinality limit
+	OverflowLabelValue = "other"
+
+	// LabelOverflowCount is the counter of label values collapsed into OverflowLabelValue
+	LabelOverflowCount = "label_overflow_count"
+
+	MetricLabel = "metric"
+	LabelLabel  = "label"
+)
+
+// GuardModule defines the metrics used by the label cardinality guard.  A Registry automatically includes
+// this module when Options.MaxLabelValues or Options.LabelLimits is set.
+func GuardModule() []Metric {
+	return []Metric{
+		Metric{
+			Name:       LabelOverflowCount,
+			Type:       CounterType,
+			Help:       "The number of times a label value was replaced because the label exceeded its cardinality limit",
+			LabelNames: []string{MetricLabel, LabelLabel},
+		},
+	}
+}
+
+// labelGuard caps the number of distinct values of each label of a single metric.  Values seen before the limit
+// was reached remain allowed, and any other values are collapsed into OverflowLabelValue.
+type labelGuard struct {
+	logger   log.Logger
+	metric   string
+	limit    func(string) int
+	overflow metrics.Counter
+
+	lock   sync.Mutex
+	seen   map[string]map[string]bool
+	warned map[string]bool
+}
+
+func newLabelGuard(logger log.Logger, metric string, limit func(string) int, overflow metrics.Counter) *labelGuard {
+	return &labelGuard{
+		logger:   logger,
+		metric:   metric,
+		limit:    limit,
+		overflow: overflow,
+		seen:     make(map[string]map[string]bool),
+		warned:   make(map[string]bool),
+	}
+}
+
+// check returns the label/value pairs with any values beyond their label's limit replaced.  The given slice is not modified.
+func (lg *labelGuard) check(labelsAndValues []string) []string {
+	var result []string
+
+	defer lg.lock.Unlock()
+	lg.lock.Lock()
+
+	for i := 0; i+1 < len(labelsAndValues); i += 2 {
+		label, value := labelsAndValues[i], labelsAndValues[i+1]
+		limit := lg.limit(label)
+		if limit <= 0 {
+			continue
+		}
+
+		values, ok := lg.seen[label]
+		if !ok {
+			values = make(map[string]bool)
+			lg.seen[label] = values
+		}
+
+		if values[value] {
+			continue
+		}
+
+		if len(values) < limit {
+			values[value] = true
+			continue
+		}
+
+		if result == nil {
+			result = append([]string(nil), labelsAndValues...)
+		}
+
+		result[i+1] = OverflowLabelValue
+		lg.overflow.With(MetricLabel, lg.metric, LabelLabel, label).Add(1.0)
+		if !lg.warned[label] {
+			// log only the first overflow for each label
+			lg.warned[label] = true
+			lg.logger.Log(
+				level.Key(), level.WarnValue(),
+				logging.MessageKey(), "label exceeded its cardinality limit",
+				MetricLabel, lg.metric,
+				LabelLabel, label,
+				"limit", limit,
+			)
+		}
+	}
+
+	if result == nil {
+		return labelsAndValues
+	}
+
+	return result
+}
+
+type guardedCounter struct {
+	metrics.Counter
+	guard *labelGuard
+}
+
+func (gc guardedCounter) With(labelsAndValues ...string) metrics.Counter {
+	return guardedCounter{gc.Counter.With(gc.guard.check(labelsAndValues)...), gc.guard}
+}
+
+type guardedGauge struct {
+	metrics.Gauge
+	guard *labelGuard
+}
+
+func (gg guardedGauge) With(labelsAndValues ...string) metrics.Gauge {
+	return guardedGauge{gg.Gauge.With(gg.guard.check(labelsAndValues)...), gg.guard}
+}
+
+type guardedHistogram struct {
+	metrics.Histogram
+	guard *labelGuard
+}
+
+func (gh guardedHistogram) With(labelsAndValues ...string) metrics.Histogram {
+	return guardedHistogram{gh.Histogram.With(gh.guard.check(labelsAndValues)...), gh.guard}
+}
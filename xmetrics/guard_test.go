@@ -0,0 +1,133 @@
+package xmetrics
+
+import (
+	"testing"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+// overflowCounter is a metrics.Counter that records the labels and total of everything added through it
+type overflowCounter struct {
+	labelsAndValues []string
+	value           float64
+}
+
+func (oc *overflowCounter) With(labelsAndValues ...string) metrics.Counter {
+	oc.labelsAndValues = labelsAndValues
+	return oc
+}
+
+func (oc *overflowCounter) Add(delta float64) {
+	oc.value += delta
+}
+
+func TestLabelGuard(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		overflow = new(overflowCounter)
+		limits   = map[string]int{"device": 2, "code": 0}
+		lg       = newLabelGuard(logging.NewTestLogger(nil, t), "requests", func(label string) int { return limits[label] }, overflow)
+	)
+
+	input := []string{"device", "a", "code", "200"}
+	assert.Equal([]string{"device", "a", "code", "200"}, lg.check(input))
+	assert.Equal([]string{"device", "b", "code", "500"}, lg.check([]string{"device", "b", "code", "500"}))
+	assert.Equal([]string{"device", "a", "code", "404"}, lg.check([]string{"device", "a", "code", "404"}))
+
+	input = []string{"device", "c", "code", "200"}
+	assert.Equal([]string{"device", OverflowLabelValue, "code", "200"}, lg.check(input))
+	assert.Equal([]string{"device", "c", "code", "200"}, input, "the input should not be modified")
+	assert.Equal([]string{"device", OverflowLabelValue}, lg.check([]string{"device", "d"}))
+	assert.Equal([]string{"device", "b"}, lg.check([]string{"device", "b"}))
+
+	assert.Equal(2.0, overflow.value)
+	assert.Equal([]string{"metric", "requests", "label", "device"}, overflow.labelsAndValues)
+	assert.Equal([]string{"device", "b"}, lg.check([]string{"device", "b"}))
+	assert.Empty(lg.check(nil))
+}
+
+func testRegistryLabelGuardDisabled(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		r, err = NewRegistry(&Options{DisableGoCollector: true, DisableProcessCollector: true})
+	)
+
+	require.NoError(err)
+	_, guarded := r.NewCounter("counter").(guardedCounter)
+	assert.False(guarded)
+}
+
+func testRegistryLabelGuardEnabled(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		r, err = NewRegistry(&Options{
+			DisableGoCollector:      true,
+			DisableProcessCollector: true,
+			MaxLabelValues:          1,
+			LabelLimits:             map[string]int{"code": 0},
+			Metrics: []Metric{
+				Metric{Name: "counter", Type: CounterType, LabelNames: []string{"device", "code"}},
+				Metric{Name: "gauge", Type: GaugeType, LabelNames: []string{"partner"}},
+				Metric{Name: "histogram", Type: HistogramType, LabelNames: []string{"partner"}},
+				Metric{Name: "summary", Type: SummaryType, LabelNames: []string{"partner"}},
+			},
+		})
+	)
+
+	require.NoError(err)
+
+	c := r.NewCounter("counter")
+	c.With("device", "a", "code", "200").Add(1.0)
+	c.With("device", "b", "code", "500").Add(1.0)
+	c.With("device", "a").With("code", "404").Add(1.0)
+
+	g := r.NewGauge("gauge")
+	g.With("partner", "comcast").Set(1.0)
+	g.With("partner", "other-partner").Set(2.0)
+
+	r.NewHistogram("histogram", 0).With("partner", "x").Observe(1.0)
+	r.NewHistogram("histogram", 0).With("partner", "y").Observe(1.0)
+	r.NewHistogram("summary", 0).With("partner", "x").Observe(1.0)
+	r.NewHistogram("summary", 0).With("partner", "y").Observe(1.0)
+
+	families, err := r.Gather()
+	require.NoError(err)
+
+	series := make(map[string][]string)
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			var lvs string
+			for _, pair := range m.GetLabel() {
+				lvs += pair.GetName() + "=" + pair.GetValue() + ","
+			}
+
+			series[family.GetName()] = append(series[family.GetName()], lvs)
+		}
+	}
+
+	assert.ElementsMatch([]string{"code=200,device=a,", "code=500,device=other,", "code=404,device=a,"}, series["test_test_counter"])
+	assert.ElementsMatch([]string{"partner=comcast,", "partner=other,"}, series["test_test_gauge"])
+	assert.ElementsMatch([]string{"partner=x,", "partner=other,"}, series["test_test_histogram"])
+	assert.ElementsMatch([]string{"partner=x,", "partner=other,"}, series["test_test_summary"])
+	assert.ElementsMatch(
+		[]string{
+			"label=device,metric=test_test_counter,",
+			"label=partner,metric=test_test_gauge,",
+			"label=partner,metric=test_test_histogram,",
+			"label=partner,metric=test_test_summary,",
+		},
+		series["test_test_label_overflow_count"],
+	)
+}
+
+func TestRegistryLabelGuard(t *testing.T) {
+	t.Run("Disabled", testRegistryLabelGuardDisabled)
+	t.Run("Enabled", testRegistryLabelGuardEnabled)
+}
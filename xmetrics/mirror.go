@@ -0,0 +1,112 @@
+package xmetrics
+
+import (
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/xmidt-org/webpa-common/logging"
+)
+
+// DefaultMirrorInterval is the default interval on which a Registry's metrics are copied into its Mirror
+const DefaultMirrorInterval = 15 * time.Second
+
+// Bucket is a single cumulative histogram bucket
+type Bucket struct {
+	// UpperBound is the inclusive upper bound of this bucket
+	UpperBound float64
+
+	// Count is the number of observations less than or equal to UpperBound
+	Count uint64
+}
+
+// HistogramSnapshot is the cumulative state of a histogram or summary at the time it was mirrored
+type HistogramSnapshot struct {
+	Count   uint64
+	Sum     float64
+	Buckets []Bucket
+}
+
+// Mirror receives copies of the metrics in a Registry.  The intent is to allow a Registry to feed another
+// metrics system, such as an OpenTelemetry MeterProvider, so that applications can export OTLP without changing
+// any code that creates or updates metrics.
+//
+// Each method is invoked once per time series, i.e. per distinct set of label values, on every mirror interval.
+// Values are cumulative, matching Prometheus semantics.  Summaries are mirrored as histograms with no buckets.
+//
+// This package does not depend on OpenTelemetry.  The xmetricsotel package provides a Mirror over an OpenTelemetry MeterProvider.
+type Mirror interface {
+	MirrorCounter(name string, labels map[string]string, value float64)
+	MirrorGauge(name string, labels map[string]string, value float64)
+	MirrorHistogram(name string, labels map[string]string, snapshot HistogramSnapshot)
+}
+
+// mirrorTo gathers all the metrics from the given gatherer and copies them into the given Mirror
+func mirrorTo(g prometheus.Gatherer, m Mirror) error {
+	families, err := g.Gather()
+	for _, family := range families {
+		name := family.GetName()
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string, len(metric.GetLabel()))
+			for _, pair := range metric.GetLabel() {
+				labels[pair.GetName()] = pair.GetValue()
+			}
+
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				m.MirrorCounter(name, labels, metric.GetCounter().GetValue())
+
+			case dto.MetricType_GAUGE:
+				m.MirrorGauge(name, labels, metric.GetGauge().GetValue())
+
+			case dto.MetricType_UNTYPED:
+				m.MirrorGauge(name, labels, metric.GetUntyped().GetValue())
+
+			case dto.MetricType_HISTOGRAM:
+				h := metric.GetHistogram()
+				snapshot := HistogramSnapshot{
+					Count:   h.GetSampleCount(),
+					Sum:     h.GetSampleSum(),
+					Buckets: make([]Bucket, 0, len(h.GetBucket())),
+				}
+
+				for _, b := range h.GetBucket() {
+					snapshot.Buckets = append(snapshot.Buckets, Bucket{UpperBound: b.GetUpperBound(), Count: b.GetCumulativeCount()})
+				}
+
+				m.MirrorHistogram(name, labels, snapshot)
+
+			case dto.MetricType_SUMMARY:
+				s := metric.GetSummary()
+				m.MirrorHistogram(name, labels, HistogramSnapshot{Count: s.GetSampleCount(), Sum: s.GetSampleSum()})
+			}
+		}
+	}
+
+	// Gather can return partial results along with an error, so whatever was gathered is still mirrored
+	return err
+}
+
+// mirrorLoop copies the gatherer's metrics into the Mirror on the given interval until the done channel is closed
+func mirrorLoop(logger log.Logger, g prometheus.Gatherer, m Mirror, interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+
+		case <-ticker.C:
+			if err := mirrorTo(g, m); err != nil {
+				logger.Log(
+					level.Key(), level.ErrorValue(),
+					logging.MessageKey(), "unable to gather all metrics for the mirror",
+					logging.ErrorKey(), err,
+				)
+			}
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package xmetrics
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mirrored struct {
+	labels   map[string]string
+	value    float64
+	snapshot HistogramSnapshot
+}
+
+// recordingMirror is a Mirror that keeps the most recent value of each series, keyed by metric name
+type recordingMirror struct {
+	lock       sync.Mutex
+	counters   map[string]mirrored
+	gauges     map[string]mirrored
+	histograms map[string]mirrored
+	updated    chan struct{}
+}
+
+func newRecordingMirror() *recordingMirror {
+	return &recordingMirror{
+		counters:   make(map[string]mirrored),
+		gauges:     make(map[string]mirrored),
+		histograms: make(map[string]mirrored),
+		updated:    make(chan struct{}, 1),
+	}
+}
+
+func (rm *recordingMirror) record(series map[string]mirrored, name string, m mirrored) {
+	rm.lock.Lock()
+	series[name] = m
+	rm.lock.Unlock()
+
+	select {
+	case rm.updated <- struct{}{}:
+	default:
+	}
+}
+
+func (rm *recordingMirror) MirrorCounter(name string, labels map[string]string, value float64) {
+	rm.record(rm.counters, name, mirrored{labels: labels, value: value})
+}
+
+func (rm *recordingMirror) MirrorGauge(name string, labels map[string]string, value float64) {
+	rm.record(rm.gauges, name, mirrored{labels: labels, value: value})
+}
+
+func (rm *recordingMirror) MirrorHistogram(name string, labels map[string]string, snapshot HistogramSnapshot) {
+	rm.record(rm.histograms, name, mirrored{labels: labels, snapshot: snapshot})
+}
+
+func (rm *recordingMirror) counter(name string) (mirrored, bool) {
+	rm.lock.Lock()
+	defer rm.lock.Unlock()
+	m, ok := rm.counters[name]
+	return m, ok
+}
+
+func TestMirrorTo(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		mirror  = newRecordingMirror()
+
+		r, err = NewRegistry(&Options{
+			DisableGoCollector:      true,
+			DisableProcessCollector: true,
+			Metrics: []Metric{
+				Metric{Name: "counter", Type: "counter", LabelNames: []string{"code"}},
+				Metric{Name: "gauge", Type: "gauge"},
+				Metric{Name: "histogram", Type: "histogram", Buckets: []float64{1.0, 2.0}},
+				Metric{Name: "summary", Type: "summary"},
+			},
+		})
+	)
+
+	require.NoError(err)
+	r.NewCounter("counter").With("code", "200").Add(3.0)
+	r.NewGauge("gauge").Set(12.5)
+	r.NewHistogram("histogram", 0).Observe(1.5)
+	r.NewHistogram("summary", 0).Observe(4.0)
+
+	require.NoError(mirrorTo(r, mirror))
+
+	assert.Equal(
+		mirrored{labels: map[string]string{"code": "200"}, value: 3.0},
+		mirror.counters["test_test_counter"],
+	)
+
+	assert.Equal(
+		mirrored{labels: map[string]string{}, value: 12.5},
+		mirror.gauges["test_test_gauge"],
+	)
+
+	assert.Equal(
+		mirrored{
+			labels:   map[string]string{},
+			snapshot: HistogramSnapshot{Count: 1, Sum: 1.5, Buckets: []Bucket{{UpperBound: 1.0, Count: 0}, {UpperBound: 2.0, Count: 1}}},
+		},
+		mirror.histograms["test_test_histogram"],
+	)
+
+	assert.Equal(
+		mirrored{
+			labels:   map[string]string{},
+			snapshot: HistogramSnapshot{Count: 1, Sum: 4.0},
+		},
+		mirror.histograms["test_test_summary"],
+	)
+}
+
+func TestMirrorToGatherError(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		mirror   = newRecordingMirror()
+		expected = errors.New("expected")
+		name     = "partial"
+		value    = 1.0
+		counter  = dto.MetricType_COUNTER
+		gatherer = prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+			return []*dto.MetricFamily{
+				&dto.MetricFamily{
+					Name:   &name,
+					Type:   &counter,
+					Metric: []*dto.Metric{&dto.Metric{Counter: &dto.Counter{Value: &value}}},
+				},
+			}, expected
+		})
+	)
+
+	assert.Equal(expected, mirrorTo(gatherer, mirror))
+	assert.Equal(mirrored{labels: map[string]string{}, value: 1.0}, mirror.counters["partial"])
+}
+
+func TestRegistryMirror(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		mirror  = newRecordingMirror()
+
+		r, err = NewRegistry(&Options{
+			DisableGoCollector:      true,
+			DisableProcessCollector: true,
+			Mirror:                  mirror,
+			MirrorInterval:          10 * time.Millisecond,
+		})
+	)
+
+	require.NoError(err)
+	defer r.Stop()
+
+	r.NewCounter("requests").Add(1.0)
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case <-mirror.updated:
+			if m, ok := mirror.counter("test_test_requests"); ok {
+				assert.Equal(1.0, m.value)
+				r.Stop()
+				r.Stop() // idempotent
+				return
+			}
+
+		case <-deadline:
+			assert.Fail("The registry did not mirror its metrics")
+			return
+		}
+	}
+}
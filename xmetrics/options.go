@@ -1,6 +1,8 @@
 package xmetrics
 
 import (
+	"time"
+
 	"github.com/go-kit/kit/log"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/xmidt-org/webpa-common/logging"
@@ -45,6 +47,65 @@ type Options struct {
 	// Any duplicate metrics will cause an error.  Duplicate metrics are defined as those having the same namespace,
 	// subsystem, and name.
 	Metrics []Metric
+
+	// Overrides changes the buckets of histograms and the objectives of summaries, keyed by either the metric's
+	// name or its fully-qualified name.  The fully-qualified name takes precedence.  Overrides apply to metrics
+	// from modules, metrics from configuration, and ad hoc metrics.  Unlike overriding an entire metric through
+	// the Metrics field, an override leaves the rest of the metric's definition, e.g. its labels, intact.
+	Overrides map[string]Override
+
+	// Renames maps the fully-qualified names of metrics onto new fully-qualified names.  Renaming happens when
+	// metrics are gathered, so code continues to create and update metrics using their original names.
+	Renames map[string]string
+
+	// RenamePrefixes maps prefixes of fully-qualified names onto new prefixes, e.g. "oldnamespace_" onto "newnamespace_"
+	// or "namespace_oldsubsystem_" onto "namespace_newsubsystem_".  This allows an entire namespace or subsystem to be renamed.
+	// An exact rename in Renames takes precedence over a prefix, and longer prefixes take precedence over shorter ones.
+	RenamePrefixes map[string]string
+
+	// KeepOriginalNames controls whether renamed metrics are also gathered under their original names.  This allows
+	// dashboards and alerts to be migrated during a deprecation window.  By default, this is false.
+	KeepOriginalNames bool
+
+	// MaxLabelValues caps the number of distinct values of each label of a metric.  Once a label reaches its limit,
+	// any new values are replaced with OverflowLabelValue and counted by the LabelOverflowCount metric.  This protects
+	// Prometheus from unbounded labels, such as device identifiers.  If unset or nonpositive, labels are not limited.
+	//
+	// The limit only applies to metrics obtained through the go-kit provider methods, e.g. NewCounter, and not to
+	// the Prometheus vectors returned by methods like NewCounterVec.
+	MaxLabelValues int
+
+	// LabelLimits overrides MaxLabelValues for specific labels, keyed by label name.  A nonpositive limit means
+	// that the label is not limited.
+	LabelLimits map[string]int
+
+	// SeriesTTL removes the series of counters and gauges which have not been updated within this duration, so that
+	// series for things that have gone away, e.g. per-instance gauges for instances no longer in service discovery,
+	// do not accumulate forever.  A removed series reappears, starting over from zero, the next time it is updated.
+	// If unset or nonpositive, series never expire.
+	//
+	// As with MaxLabelValues, expiry only applies to metrics obtained through the go-kit provider methods, e.g. NewGauge.
+	// Series without labels never expire.
+	SeriesTTL time.Duration
+
+	// SeriesTTLs overrides SeriesTTL for specific metrics, keyed by either the metric's name or its fully-qualified name.
+	// The fully-qualified name takes precedence.  A nonpositive duration means that the metric's series never expire.
+	SeriesTTLs map[string]time.Duration
+
+	// RuntimeMetrics controls whether the metrics in RuntimeModule are registered and kept current.  By default
+	// this is false.
+	RuntimeMetrics bool
+
+	// Mirror is an optional destination, such as an xmetricsotel.Mirror over an OpenTelemetry MeterProvider, into which
+	// all metrics in the Registry are periodically copied.  If unset, no mirroring takes place.
+	Mirror Mirror
+
+	// Emitter optionally configures the periodic emission of all metrics to StatsD or Graphite.  If unset,
+	// no metrics are emitted.
+	Emitter *EmitterOptions
+
+	// MirrorInterval is how often metrics are copied into the Mirror.  If unset, DefaultMirrorInterval is used.
+	MirrorInterval time.Duration
 }
 
 func (o *Options) logger() log.Logger {
@@ -128,6 +189,134 @@ func (o *Options) reportProcessCollectorErrors() bool {
 	return false
 }
 
+func (o *Options) overrides() map[string]Override {
+	if o != nil {
+		return o.Overrides
+	}
+
+	return nil
+}
+
+func (o *Options) renames() map[string]string {
+	if o != nil {
+		return o.Renames
+	}
+
+	return nil
+}
+
+func (o *Options) renamePrefixes() map[string]string {
+	if o != nil {
+		return o.RenamePrefixes
+	}
+
+	return nil
+}
+
+func (o *Options) keepOriginalNames() bool {
+	if o != nil {
+		return o.KeepOriginalNames
+	}
+
+	return false
+}
+
+// labelLimit returns the function that computes the cardinality limit of each label, or nil if labels are not limited
+func (o *Options) labelLimit() func(string) int {
+	if o == nil || (o.MaxLabelValues <= 0 && len(o.LabelLimits) == 0) {
+		return nil
+	}
+
+	var (
+		max    = o.MaxLabelValues
+		limits = make(map[string]int, len(o.LabelLimits))
+	)
+
+	for label, limit := range o.LabelLimits {
+		limits[label] = limit
+	}
+
+	return func(label string) int {
+		if limit, ok := limits[label]; ok {
+			return limit
+		}
+
+		return max
+	}
+}
+
+// seriesTTL returns the function that computes the time-to-live of each metric's series, along with how often
+// stale series should be removed.  If no series expire, this method returns nil and zero.
+func (o *Options) seriesTTL() (func(fqn, name string) time.Duration, time.Duration) {
+	if o == nil {
+		return nil, 0
+	}
+
+	var (
+		global   = o.SeriesTTL
+		ttls     = make(map[string]time.Duration, len(o.SeriesTTLs))
+		smallest = global
+	)
+
+	for key, ttl := range o.SeriesTTLs {
+		ttls[key] = ttl
+		if ttl > 0 && (smallest <= 0 || ttl < smallest) {
+			smallest = ttl
+		}
+	}
+
+	if smallest <= 0 {
+		return nil, 0
+	}
+
+	ttl := func(fqn, name string) time.Duration {
+		if ttl, ok := ttls[fqn]; ok {
+			return ttl
+		}
+
+		if ttl, ok := ttls[name]; ok {
+			return ttl
+		}
+
+		return global
+	}
+
+	// sweeping at half the smallest ttl bounds how long a stale series can outlive its ttl
+	return ttl, smallest / 2
+}
+
+func (o *Options) runtimeMetrics() bool {
+	if o != nil {
+		return o.RuntimeMetrics
+	}
+
+	return false
+}
+
+func (o *Options) mirror() Mirror {
+	if o != nil {
+		return o.Mirror
+	}
+
+	return nil
+}
+
+func (o *Options) emitter() *EmitterOptions {
+	if o != nil {
+		return o.Emitter
+	}
+
+	return nil
+}
+
+func (o *Options) mirrorInterval() time.Duration {
+	if o != nil && o.MirrorInterval > 0 {
+		return o.MirrorInterval
+	}
+
+	return DefaultMirrorInterval
+}
+
 // Module acts as a metrics module function using the (normally) injected metrics.
 func (o *Options) Module() []Metric {
 	if o != nil {
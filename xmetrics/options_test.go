@@ -1,9 +1,13 @@
 package xmetrics
 
 import (
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/xmidt-org/webpa-common/logging"
 )
 
@@ -20,12 +24,27 @@ func testOptionsDefault(o *Options, t *testing.T) {
 	assert.False(o.disableProcessCollector())
 	assert.NotNil(o.registry())
 	assert.Empty(o.Module())
+	assert.Empty(o.overrides())
+	assert.Nil(o.labelLimit())
+
+	ttl, interval := o.seriesTTL()
+	assert.Nil(ttl)
+	assert.Zero(interval)
+
+	assert.Empty(o.renames())
+	assert.Empty(o.renamePrefixes())
+	assert.False(o.keepOriginalNames())
+	assert.False(o.runtimeMetrics())
+	assert.Nil(o.mirror())
+	assert.Nil(o.emitter())
+	assert.Equal(DefaultMirrorInterval, o.mirrorInterval())
 }
 
 func testOptionsCustom(t *testing.T) {
 	var (
 		assert = assert.New(t)
 		logger = logging.NewTestLogger(nil, t)
+		mirror = newRecordingMirror()
 		o      = Options{
 			Logger:                  logger,
 			Namespace:               "custom namespace",
@@ -39,6 +58,20 @@ func testOptionsCustom(t *testing.T) {
 					Type: "counter",
 				},
 			},
+			Overrides: map[string]Override{
+				"latency": Override{Buckets: []float64{0.5, 1.0}},
+			},
+			MaxLabelValues:    100,
+			LabelLimits:       map[string]int{"partner": 10},
+			SeriesTTL:         time.Hour,
+			SeriesTTLs:        map[string]time.Duration{"instances": time.Minute, "test_test_devices": 0},
+			Renames:           map[string]string{"old_name": "new_name"},
+			RenamePrefixes:    map[string]string{"old_": "new_"},
+			KeepOriginalNames: true,
+			RuntimeMetrics:    true,
+			Mirror:            mirror,
+			MirrorInterval:    time.Minute,
+			Emitter:           &EmitterOptions{Protocol: StatsDProtocol, Address: "localhost:8125"},
 		}
 	)
 
@@ -58,6 +91,60 @@ func testOptionsCustom(t *testing.T) {
 		},
 		o.Module(),
 	)
+
+	assert.Equal(map[string]Override{"latency": Override{Buckets: []float64{0.5, 1.0}}}, o.overrides())
+	labelLimit := o.labelLimit()
+	if assert.NotNil(labelLimit) {
+		assert.Equal(10, labelLimit("partner"))
+		assert.Equal(100, labelLimit("device"))
+	}
+
+	ttl, interval := o.seriesTTL()
+	if assert.NotNil(ttl) {
+		assert.Equal(time.Minute, ttl("custom_instances", "instances"))
+		assert.Equal(time.Duration(0), ttl("test_test_devices", "devices"))
+		assert.Equal(time.Hour, ttl("custom_other", "other"))
+		assert.Equal(30*time.Second, interval)
+	}
+
+	assert.Equal(map[string]string{"old_name": "new_name"}, o.renames())
+	assert.Equal(map[string]string{"old_": "new_"}, o.renamePrefixes())
+	assert.True(o.keepOriginalNames())
+	assert.True(o.runtimeMetrics())
+	assert.Equal(mirror, o.mirror())
+	assert.Equal(time.Minute, o.mirrorInterval())
+	assert.Equal(&EmitterOptions{Protocol: StatsDProtocol, Address: "localhost:8125"}, o.emitter())
+}
+
+func testOptionsViperOverrides(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		v       = viper.New()
+		o       Options
+	)
+
+	v.SetConfigType("yaml")
+	require.NoError(v.ReadConfig(strings.NewReader(`
+overrides:
+  request_duration_seconds:
+    buckets: [0.01, 0.1, 1.0]
+  test_test_gc_pause_seconds:
+    objectives:
+      - quantile: 0.5
+        error: 0.05
+      - quantile: 0.99
+        error: 0.001
+`)))
+
+	require.NoError(v.Unmarshal(&o))
+	assert.Equal(
+		map[string]Override{
+			"request_duration_seconds":   Override{Buckets: []float64{0.01, 0.1, 1.0}},
+			"test_test_gc_pause_seconds": Override{Objectives: []Objective{{Quantile: 0.5, Error: 0.05}, {Quantile: 0.99, Error: 0.001}}},
+		},
+		o.overrides(),
+	)
 }
 
 func TestOptions(t *testing.T) {
@@ -70,4 +157,5 @@ func TestOptions(t *testing.T) {
 	})
 
 	t.Run("Custom", testOptionsCustom)
+	t.Run("ViperOverrides", testOptionsViperOverrides)
 }
@@ -0,0 +1,63 @@
+package xmetrics
+
+import "fmt"
+
+// Objective is a single summary objective.  Objectives are configured as a list, rather than as a map like
+// Metric.Objectives, because configuration keys such as "0.99" are split on the dot by Viper.
+type Objective struct {
+	// Quantile is the quantile to track, e.g. 0.99
+	Quantile float64
+
+	// Error is the allowed absolute error of the quantile, e.g. 0.001
+	Error float64
+}
+
+// Override holds the configurable changes to a single metric.  Overrides allow operators to tune metrics,
+// e.g. latency bucket boundaries, for an environment without any code changes.
+type Override struct {
+	// Buckets replaces the buckets of a histogram.  The buckets must be in increasing order.
+	Buckets []float64
+
+	// Objectives replaces the objectives of a summary
+	Objectives []Objective
+}
+
+// apply returns a copy of the given metric with this override applied
+func (o Override) apply(m Metric) (Metric, error) {
+	if len(o.Buckets) > 0 {
+		if m.Type != HistogramType {
+			return m, fmt.Errorf("Buckets can only be overridden for histograms, but metric %s is of type %s", m.Name, m.Type)
+		}
+
+		for i := 1; i < len(o.Buckets); i++ {
+			if o.Buckets[i-1] >= o.Buckets[i] {
+				return m, fmt.Errorf("The bucket overrides for metric %s are not in increasing order", m.Name)
+			}
+		}
+
+		m.Buckets = o.Buckets
+	}
+
+	if len(o.Objectives) > 0 {
+		if m.Type != SummaryType {
+			return m, fmt.Errorf("Objectives can only be overridden for summaries, but metric %s is of type %s", m.Name, m.Type)
+		}
+
+		m.Objectives = make(map[float64]float64, len(o.Objectives))
+		for _, objective := range o.Objectives {
+			m.Objectives[objective.Quantile] = objective.Error
+		}
+	}
+
+	return m, nil
+}
+
+// findOverride looks up the override for a metric, first by fully-qualified name and then by name
+func findOverride(overrides map[string]Override, fqn, name string) (Override, bool) {
+	if o, ok := overrides[fqn]; ok {
+		return o, true
+	}
+
+	o, ok := overrides[name]
+	return o, ok
+}
@@ -0,0 +1,156 @@
+package xmetrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverrideApply(t *testing.T) {
+	var (
+		assert    = assert.New(t)
+		histogram = Metric{Name: "histogram", Type: HistogramType, Buckets: []float64{1.0}, LabelNames: []string{"code"}}
+		summary   = Metric{Name: "summary", Type: SummaryType, LabelNames: []string{"code"}}
+	)
+
+	m, err := Override{}.apply(histogram)
+	assert.NoError(err)
+	assert.Equal(histogram, m)
+
+	m, err = Override{Buckets: []float64{0.1, 0.5, 2.0}}.apply(histogram)
+	assert.NoError(err)
+	assert.Equal([]float64{0.1, 0.5, 2.0}, m.Buckets)
+	assert.Equal([]string{"code"}, m.LabelNames)
+	assert.Equal([]float64{1.0}, histogram.Buckets)
+
+	_, err = Override{Buckets: []float64{0.5, 0.5}}.apply(histogram)
+	assert.Error(err)
+
+	_, err = Override{Objectives: []Objective{{Quantile: 0.5, Error: 0.05}}}.apply(histogram)
+	assert.Error(err)
+
+	m, err = Override{Objectives: []Objective{{Quantile: 0.5, Error: 0.05}}}.apply(summary)
+	assert.NoError(err)
+	assert.Equal(map[float64]float64{0.5: 0.05}, m.Objectives)
+	assert.Equal([]string{"code"}, m.LabelNames)
+
+	_, err = Override{Buckets: []float64{1.0}}.apply(summary)
+	assert.Error(err)
+}
+
+func TestFindOverride(t *testing.T) {
+	var (
+		assert    = assert.New(t)
+		overrides = map[string]Override{
+			"latency":          Override{Buckets: []float64{1.0}},
+			"test_foo_latency": Override{Buckets: []float64{2.0}},
+		}
+	)
+
+	o, ok := findOverride(overrides, "test_foo_latency", "latency")
+	assert.True(ok)
+	assert.Equal([]float64{2.0}, o.Buckets)
+
+	o, ok = findOverride(overrides, "test_bar_latency", "latency")
+	assert.True(ok)
+	assert.Equal([]float64{1.0}, o.Buckets)
+
+	_, ok = findOverride(overrides, "test_bar_size", "size")
+	assert.False(ok)
+
+	_, ok = findOverride(nil, "test_bar_size", "size")
+	assert.False(ok)
+}
+
+func testRegistryOverridesPreregistered(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		r, err = NewRegistry(
+			&Options{
+				DisableGoCollector:      true,
+				DisableProcessCollector: true,
+				Overrides: map[string]Override{
+					"latency":        Override{Buckets: []float64{0.25, 0.5}},
+					"test_test_size": Override{Objectives: []Objective{{Quantile: 0.9, Error: 0.01}}},
+				},
+			},
+			func() []Metric {
+				return []Metric{
+					Metric{Name: "latency", Type: HistogramType, Buckets: []float64{1.0, 5.0, 10.0}, LabelNames: []string{"code"}},
+					Metric{Name: "size", Type: SummaryType},
+				}
+			},
+		)
+	)
+
+	require.NoError(err)
+	r.NewHistogramVec("latency").WithLabelValues("200").Observe(0.3)
+	r.NewSummaryVec("size").WithLabelValues().Observe(100.0)
+
+	families, err := r.Gather()
+	require.NoError(err)
+	require.Len(families, 2)
+
+	var upperBounds []float64
+	for _, b := range families[0].GetMetric()[0].GetHistogram().GetBucket() {
+		upperBounds = append(upperBounds, b.GetUpperBound())
+	}
+
+	assert.Equal([]float64{0.25, 0.5}, upperBounds)
+	require.Len(families[1].GetMetric()[0].GetSummary().GetQuantile(), 1)
+	assert.Equal(0.9, families[1].GetMetric()[0].GetSummary().GetQuantile()[0].GetQuantile())
+}
+
+func testRegistryOverridesAdHoc(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		r, err = NewRegistry(&Options{
+			DisableGoCollector:      true,
+			DisableProcessCollector: true,
+			Overrides: map[string]Override{
+				"latency": Override{Buckets: []float64{0.25, 0.5}},
+				"size":    Override{Objectives: []Objective{{Quantile: 0.9, Error: 0.01}}},
+				"bad":     Override{Objectives: []Objective{{Quantile: 0.9, Error: 0.01}}},
+			},
+		})
+	)
+
+	require.NoError(err)
+	r.NewHistogramVec("latency").WithLabelValues().Observe(0.3)
+	r.NewSummaryVec("size").WithLabelValues().Observe(100.0)
+	assert.Panics(func() { r.NewHistogramVec("bad") })
+
+	families, err := r.Gather()
+	require.NoError(err)
+	require.Len(families, 2)
+	assert.Len(families[0].GetMetric()[0].GetHistogram().GetBucket(), 2)
+	assert.Len(families[1].GetMetric()[0].GetSummary().GetQuantile(), 1)
+}
+
+func testRegistryOverridesInvalid(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		r, err = NewRegistry(&Options{
+			Metrics: []Metric{
+				Metric{Name: "counter", Type: CounterType},
+			},
+			Overrides: map[string]Override{
+				"counter": Override{Buckets: []float64{1.0}},
+			},
+		})
+	)
+
+	assert.Nil(r)
+	assert.Error(err)
+}
+
+func TestRegistryOverrides(t *testing.T) {
+	t.Run("Preregistered", testRegistryOverridesPreregistered)
+	t.Run("AdHoc", testRegistryOverridesAdHoc)
+	t.Run("Invalid", testRegistryOverridesInvalid)
+}
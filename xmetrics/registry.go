@@ -1,7 +1,9 @@
 package xmetrics
 
 import (
+	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
@@ -28,6 +30,26 @@ type PrometheusProvider interface {
 	NewSummaryVecEx(namespace, subsystem, name string) *prometheus.SummaryVec
 }
 
+// GaugeFuncProvider is implemented by providers that support gauges whose values are computed each time
+// metrics are gathered, e.g. the length of an internal map or the depth of a queue.  Code that is handed
+// a go-kit provider.Provider can type assert to this interface.
+type GaugeFuncProvider interface {
+	// NewGaugeFunc registers a gauge whose value is the result of f.  The function must be safe for concurrent use.
+	NewGaugeFunc(name string, f func() float64)
+}
+
+// Registrar is implemented by registries that allow complete metric definitions to be registered after the registry
+// is built, e.g. by plugins or subsystems that are initialized late.
+type Registrar interface {
+	// GetOrRegister returns the collector for the given metric, registering it if necessary.  The metric's namespace and
+	// subsystem default to those of the registry.  If a metric with the same fully-qualified name already exists, it must
+	// have the same type and label names, and the existing collector is returned.  Otherwise, an error is returned.
+	// Other differences, such as buckets or help, do not affect compatibility.
+	//
+	// Once registered, the metric is returned by the other provider methods in the same way as a preregistered metric.
+	GetOrRegister(Metric) (prometheus.Collector, error)
+}
+
 // Registry is the core abstraction for this package.  It is a Prometheus gatherer and a go-kit metrics.Provider all in one.
 //
 // The Provider implementation works slightly differently than the go-kit implementation.  For any metric that is already defined
@@ -35,6 +57,9 @@ type PrometheusProvider interface {
 // and returned by subsequent calles to the Provider methods.
 type Registry interface {
 	PrometheusProvider
+	GaugeFuncProvider
+	TimerProvider
+	Registrar
 	provider.Provider
 	prometheus.Gatherer
 }
@@ -44,9 +69,135 @@ type registry struct {
 	prometheus.Gatherer
 	prometheus.Registerer
 
-	namespace     string
-	subsystem     string
+	namespace string
+	subsystem string
+	overrides map[string]Override
+
+	// preregistered holds the collectors for the configured metrics and any metrics registered with GetOrRegister,
+	// while definitions holds the metrics themselves.  Both are keyed by fully-qualified name.
+	registerLock  sync.RWMutex
 	preregistered map[string]prometheus.Collector
+	definitions   map[string]Metric
+
+	logger     log.Logger
+	labelLimit func(string) int
+	guardLock  sync.Mutex
+	guards     map[string]*labelGuard
+	expiry     *seriesExpiry
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// lookup returns the collector for a preregistered metric or a metric registered with GetOrRegister
+func (r *registry) lookup(key string) (prometheus.Collector, bool) {
+	r.registerLock.RLock()
+	c, ok := r.preregistered[key]
+	r.registerLock.RUnlock()
+	return c, ok
+}
+
+// collectorType returns the metric type of a collector created by this package, or the empty string if unknown
+func collectorType(c prometheus.Collector) string {
+	switch c.(type) {
+	case *prometheus.CounterVec:
+		return CounterType
+	case *prometheus.GaugeVec:
+		return GaugeType
+	case *prometheus.HistogramVec:
+		return HistogramType
+	case *prometheus.SummaryVec:
+		return SummaryType
+	default:
+		return ""
+	}
+}
+
+// compatible checks that a metric can be satisfied by an existing metric with the same name
+func compatible(key string, existing, m Metric) error {
+	if existing.Type != m.Type {
+		return fmt.Errorf("The metric %s is already registered as a %s, not a %s", key, existing.Type, m.Type)
+	}
+
+	if len(existing.LabelNames) != len(m.LabelNames) {
+		return fmt.Errorf("The metric %s is already registered with labels %v, not %v", key, existing.LabelNames, m.LabelNames)
+	}
+
+	for i := range existing.LabelNames {
+		if existing.LabelNames[i] != m.LabelNames[i] {
+			return fmt.Errorf("The metric %s is already registered with labels %v, not %v", key, existing.LabelNames, m.LabelNames)
+		}
+	}
+
+	return nil
+}
+
+func (r *registry) GetOrRegister(m Metric) (prometheus.Collector, error) {
+	if len(m.Name) == 0 {
+		return nil, errors.New("A name is required for a metric")
+	}
+
+	if len(m.Namespace) == 0 {
+		m.Namespace = r.namespace
+	}
+
+	if len(m.Subsystem) == 0 {
+		m.Subsystem = r.subsystem
+	}
+
+	key := prometheus.BuildFQName(m.Namespace, m.Subsystem, m.Name)
+
+	defer r.registerLock.Unlock()
+	r.registerLock.Lock()
+
+	if existing, ok := r.definitions[key]; ok {
+		if err := compatible(key, existing, m); err != nil {
+			return nil, err
+		}
+
+		return r.preregistered[key], nil
+	}
+
+	if override, ok := findOverride(r.overrides, key, m.Name); ok {
+		var err error
+		if m, err = override.apply(m); err != nil {
+			return nil, err
+		}
+	}
+
+	c, err := NewCollector(m)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.Register(c); err != nil {
+		already, ok := err.(prometheus.AlreadyRegisteredError)
+		if !ok {
+			return nil, err
+		}
+
+		// an identical descriptor was registered outside of GetOrRegister, e.g. as an ad hoc metric,
+		// so only the type remains to be checked
+		if collectorType(already.ExistingCollector) != m.Type {
+			return nil, fmt.Errorf("The metric %s is already registered and is not a %s", key, m.Type)
+		}
+
+		c = already.ExistingCollector
+	}
+
+	r.logger.Log(
+		level.Key(), level.DebugValue(),
+		logging.MessageKey(), "registered metric",
+		"name", m.Name,
+		"namespace", m.Namespace,
+		"subsystem", m.Subsystem,
+		"type", m.Type,
+		"fqn", key,
+	)
+
+	r.preregistered[key] = c
+	r.definitions[key] = m
+	return c, nil
 }
 
 func (r *registry) NewCounterVec(name string) *prometheus.CounterVec {
@@ -55,7 +206,7 @@ func (r *registry) NewCounterVec(name string) *prometheus.CounterVec {
 
 func (r *registry) NewCounterVecEx(namespace, subsystem, name string) *prometheus.CounterVec {
 	key := prometheus.BuildFQName(namespace, subsystem, name)
-	if existing, ok := r.preregistered[key]; ok {
+	if existing, ok := r.lookup(key); ok {
 		if counterVec, ok := existing.(*prometheus.CounterVec); ok {
 			return counterVec
 		}
@@ -75,17 +226,69 @@ func (r *registry) NewCounterVecEx(namespace, subsystem, name string) *prometheu
 
 	if err := r.Register(counterVec); err != nil {
 		if already, ok := err.(prometheus.AlreadyRegisteredError); ok {
-			return already.ExistingCollector.(*prometheus.CounterVec)
-		} else {
-			panic(err)
+			if existing, ok := already.ExistingCollector.(*prometheus.CounterVec); ok {
+				return existing
+			}
+
+			panic(fmt.Errorf("The metric %s is already registered and is not a counter", key))
 		}
+
+		panic(err)
 	}
 
 	return counterVec
 }
 
+// guard returns the label cardinality guard for the given metric, or nil if labels are not limited
+func (r *registry) guard(name string) *labelGuard {
+	if r.labelLimit == nil {
+		return nil
+	}
+
+	key := prometheus.BuildFQName(r.namespace, r.subsystem, name)
+
+	defer r.guardLock.Unlock()
+	r.guardLock.Lock()
+
+	g, ok := r.guards[key]
+	if !ok {
+		g = newLabelGuard(
+			r.logger,
+			key,
+			r.labelLimit,
+			gokitprometheus.NewCounter(r.NewCounterVecEx(r.namespace, r.subsystem, LabelOverflowCount)),
+		)
+
+		r.guards[key] = g
+	}
+
+	return g
+}
+
+// tracker returns the series tracker for the given metric, or nil if the metric's series do not expire
+func (r *registry) tracker(name string, vec deleter) *seriesTracker {
+	if r.expiry == nil {
+		return nil
+	}
+
+	return r.expiry.tracker(prometheus.BuildFQName(r.namespace, r.subsystem, name), name, vec)
+}
+
 func (r *registry) NewCounter(name string) metrics.Counter {
-	return gokitprometheus.NewCounter(r.NewCounterVec(name))
+	var (
+		vec                 = r.NewCounterVec(name)
+		c   metrics.Counter = gokitprometheus.NewCounter(vec)
+	)
+
+	if st := r.tracker(name, vec); st != nil {
+		c = expiringCounter{Counter: c, tracker: st}
+	}
+
+	if g := r.guard(name); g != nil {
+		return guardedCounter{c, g}
+	}
+
+	return c
 }
 
 func (r *registry) NewGaugeVec(name string) *prometheus.GaugeVec {
@@ -94,7 +297,7 @@ func (r *registry) NewGaugeVec(name string) *prometheus.GaugeVec {
 
 func (r *registry) NewGaugeVecEx(namespace, subsystem, name string) *prometheus.GaugeVec {
 	key := prometheus.BuildFQName(namespace, subsystem, name)
-	if existing, ok := r.preregistered[key]; ok {
+	if existing, ok := r.lookup(key); ok {
 		if gaugeVec, ok := existing.(*prometheus.GaugeVec); ok {
 			return gaugeVec
 		}
@@ -114,17 +317,53 @@ func (r *registry) NewGaugeVecEx(namespace, subsystem, name string) *prometheus.
 
 	if err := r.Register(gaugeVec); err != nil {
 		if already, ok := err.(prometheus.AlreadyRegisteredError); ok {
-			return already.ExistingCollector.(*prometheus.GaugeVec)
-		} else {
-			panic(err)
+			if existing, ok := already.ExistingCollector.(*prometheus.GaugeVec); ok {
+				return existing
+			}
+
+			panic(fmt.Errorf("The metric %s is already registered and is not a gauge", key))
 		}
+
+		panic(err)
 	}
 
 	return gaugeVec
 }
 
 func (r *registry) NewGauge(name string) metrics.Gauge {
-	return gokitprometheus.NewGauge(r.NewGaugeVec(name))
+	var (
+		vec               = r.NewGaugeVec(name)
+		g   metrics.Gauge = gokitprometheus.NewGauge(vec)
+	)
+
+	if st := r.tracker(name, vec); st != nil {
+		g = expiringGauge{Gauge: g, tracker: st}
+	}
+
+	if lg := r.guard(name); lg != nil {
+		return guardedGauge{g, lg}
+	}
+
+	return g
+}
+
+// NewGaugeFunc registers a gauge function under this registry's namespace and subsystem.  Since a function
+// cannot be changed once registered, this method panics if a metric with the same name already exists.
+func (r *registry) NewGaugeFunc(name string, f func() float64) {
+	key := prometheus.BuildFQName(r.namespace, r.subsystem, name)
+	if _, ok := r.lookup(key); ok {
+		panic(fmt.Errorf("The preregistered metric %s cannot be a gauge function", key))
+	}
+
+	r.MustRegister(prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace: r.namespace,
+			Subsystem: r.subsystem,
+			Name:      name,
+			Help:      name,
+		},
+		f,
+	))
 }
 
 func (r *registry) NewHistogramVec(name string) *prometheus.HistogramVec {
@@ -133,7 +372,7 @@ func (r *registry) NewHistogramVec(name string) *prometheus.HistogramVec {
 
 func (r *registry) NewHistogramVecEx(namespace, subsystem, name string) *prometheus.HistogramVec {
 	key := prometheus.BuildFQName(namespace, subsystem, name)
-	if existing, ok := r.preregistered[key]; ok {
+	if existing, ok := r.lookup(key); ok {
 		if histogramVec, ok := existing.(*prometheus.HistogramVec); ok {
 			return histogramVec
 		}
@@ -141,22 +380,37 @@ func (r *registry) NewHistogramVecEx(namespace, subsystem, name string) *prometh
 		panic(fmt.Errorf("The preregistered metric %s is not a histogram", key))
 	}
 
+	var buckets []float64
+	if o, ok := findOverride(r.overrides, key, name); ok {
+		m, err := o.apply(Metric{Name: name, Type: HistogramType})
+		if err != nil {
+			panic(err)
+		}
+
+		buckets = m.Buckets
+	}
+
 	histogramVec := prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Namespace: namespace,
 			Subsystem: subsystem,
 			Name:      name,
 			Help:      name,
+			Buckets:   buckets,
 		},
 		[]string{},
 	)
 
 	if err := r.Register(histogramVec); err != nil {
 		if already, ok := err.(prometheus.AlreadyRegisteredError); ok {
-			return already.ExistingCollector.(*prometheus.HistogramVec)
-		} else {
-			panic(err)
+			if existing, ok := already.ExistingCollector.(*prometheus.HistogramVec); ok {
+				return existing
+			}
+
+			panic(fmt.Errorf("The metric %s is already registered and is not a histogram", key))
 		}
+
+		panic(err)
 	}
 
 	return histogramVec
@@ -165,8 +419,21 @@ func (r *registry) NewHistogramVecEx(namespace, subsystem, name string) *prometh
 // NewHistogram has some special logic over and above the go-kit implementations.  This method allows a summary or
 // a histogram as the underlying metric for the go-kit metrics.Histogram.
 func (r *registry) NewHistogram(name string, _ int) metrics.Histogram {
+	h := r.newHistogram(name)
+	if g := r.guard(name); g != nil {
+		return guardedHistogram{h, g}
+	}
+
+	return h
+}
+
+func (r *registry) NewTimer(name string, labelsAndValues ...string) *Timer {
+	return NewTimer(r.NewHistogram(name, 0).With(labelsAndValues...))
+}
+
+func (r *registry) newHistogram(name string) metrics.Histogram {
 	key := prometheus.BuildFQName(r.namespace, r.subsystem, name)
-	if existing, ok := r.preregistered[key]; ok {
+	if existing, ok := r.lookup(key); ok {
 		switch e := existing.(type) {
 		case *prometheus.HistogramVec:
 			return gokitprometheus.NewHistogram(e)
@@ -186,7 +453,7 @@ func (r *registry) NewSummaryVec(name string) *prometheus.SummaryVec {
 
 func (r *registry) NewSummaryVecEx(namespace, subsystem, name string) *prometheus.SummaryVec {
 	key := prometheus.BuildFQName(namespace, subsystem, name)
-	if existing, ok := r.preregistered[key]; ok {
+	if existing, ok := r.lookup(key); ok {
 		if summaryVec, ok := existing.(*prometheus.SummaryVec); ok {
 			return summaryVec
 		}
@@ -194,29 +461,48 @@ func (r *registry) NewSummaryVecEx(namespace, subsystem, name string) *prometheu
 		panic(fmt.Errorf("The preregistered metric %s is not a histogram", key))
 	}
 
+	var objectives map[float64]float64
+	if o, ok := findOverride(r.overrides, key, name); ok {
+		m, err := o.apply(Metric{Name: name, Type: SummaryType})
+		if err != nil {
+			panic(err)
+		}
+
+		objectives = m.Objectives
+	}
+
 	summaryVec := prometheus.NewSummaryVec(
 		prometheus.SummaryOpts{
-			Namespace: namespace,
-			Subsystem: subsystem,
-			Name:      name,
-			Help:      name,
+			Namespace:  namespace,
+			Subsystem:  subsystem,
+			Name:       name,
+			Help:       name,
+			Objectives: objectives,
 		},
 		[]string{},
 	)
 
 	if err := r.Register(summaryVec); err != nil {
 		if already, ok := err.(prometheus.AlreadyRegisteredError); ok {
-			return already.ExistingCollector.(*prometheus.SummaryVec)
-		} else {
-			panic(err)
+			if existing, ok := already.ExistingCollector.(*prometheus.SummaryVec); ok {
+				return existing
+			}
+
+			panic(fmt.Errorf("The metric %s is already registered and is not a summary", key))
 		}
+
+		panic(err)
 	}
 
 	return summaryVec
 }
 
-// Stop is just here to implement metrics.Provider.  This method is a noop.
+// Stop implements metrics.Provider.  If this registry has a Mirror, an emitter, or expiring series, this method
+// stops their background tasks.  Otherwise, this method is a noop.
 func (r *registry) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
 }
 
 // NewRegistry creates an xmetrics.Registry from an externally supplied set of Options and a set
@@ -225,6 +511,14 @@ func (r *registry) Stop() {
 // present in the options will override any corresponding metric from modules.
 func NewRegistry(o *Options, modules ...Module) (Registry, error) {
 	logger := o.logger()
+	if o.runtimeMetrics() {
+		modules = append(modules[:len(modules):len(modules)], RuntimeModule)
+	}
+
+	labelLimit := o.labelLimit()
+	if labelLimit != nil {
+		modules = append(modules[:len(modules):len(modules)], GuardModule)
+	}
 
 	// merge all the metrics, allowing options to override modules
 	merger := NewMerger().
@@ -238,6 +532,20 @@ func NewRegistry(o *Options, modules ...Module) (Registry, error) {
 		return nil, merger.Err()
 	}
 
+	var e *emitter
+	if eo := o.emitter(); eo != nil {
+		var err error
+		if e, err = newEmitter(*eo); err != nil {
+			logger.Log(
+				level.Key(), level.ErrorValue(),
+				logging.MessageKey(), "invalid metrics emitter",
+				logging.ErrorKey(), err,
+			)
+
+			return nil, err
+		}
+	}
+
 	var (
 		pr = o.registry()
 		r  = &registry{
@@ -246,6 +554,12 @@ func NewRegistry(o *Options, modules ...Module) (Registry, error) {
 			namespace:     o.namespace(),
 			subsystem:     o.subsystem(),
 			preregistered: make(map[string]prometheus.Collector),
+			definitions:   make(map[string]Metric),
+			overrides:     o.overrides(),
+			logger:        logger,
+			labelLimit:    labelLimit,
+			guards:        make(map[string]*labelGuard),
+			stop:          make(chan struct{}),
 		}
 	)
 
@@ -265,6 +579,19 @@ func NewRegistry(o *Options, modules ...Module) (Registry, error) {
 			logging.MessageKey(), "registering merged metric",
 		)
 
+		if override, ok := findOverride(r.overrides, name, metric.Name); ok {
+			var err error
+			if metric, err = override.apply(metric); err != nil {
+				metricLogger.Log(
+					level.Key(), level.ErrorValue(),
+					logging.MessageKey(), "unable to override metric",
+					logging.ErrorKey(), err,
+				)
+
+				return nil, err
+			}
+		}
+
 		c, err := NewCollector(metric)
 		if err != nil {
 			metricLogger.Log(
@@ -287,6 +614,43 @@ func NewRegistry(o *Options, modules ...Module) (Registry, error) {
 		}
 
 		r.preregistered[name] = c
+		r.definitions[name] = metric
+	}
+
+	if o.runtimeMetrics() {
+		r.Gatherer = newRuntimeGatherer(r.Gatherer, r, o.namespace())
+	}
+
+	if len(o.renames()) > 0 || len(o.renamePrefixes()) > 0 {
+		rn, err := newRenamer(o.renames(), o.renamePrefixes())
+		if err != nil {
+			logger.Log(
+				level.Key(), level.ErrorValue(),
+				logging.MessageKey(), "invalid metric renames",
+				logging.ErrorKey(), err,
+			)
+
+			return nil, err
+		}
+
+		r.Gatherer = &renameGatherer{
+			Gatherer:     r.Gatherer,
+			renamer:      rn,
+			keepOriginal: o.keepOriginalNames(),
+		}
+	}
+
+	if ttl, interval := o.seriesTTL(); ttl != nil {
+		r.expiry = newSeriesExpiry(ttl)
+		go expiryLoop(logger, r.expiry, interval, r.stop)
+	}
+
+	if m := o.mirror(); m != nil {
+		go mirrorLoop(logger, r, m, o.mirrorInterval(), r.stop)
+	}
+
+	if e != nil {
+		go emitLoop(logger, r, e, r.stop)
 	}
 
 	return r, nil
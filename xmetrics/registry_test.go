@@ -377,6 +377,179 @@ func testRegistryCounterLabel(t *testing.T) {
 	c.With("label", "value").Add(1.0)
 }
 
+func testRegistryGaugeFunc(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		depth  = 3.0
+		r, err = NewRegistry(&Options{
+			DisableGoCollector:      true,
+			DisableProcessCollector: true,
+			Metrics: []Metric{
+				Metric{
+					Name: "preregistered",
+					Type: "gauge",
+				},
+			},
+		})
+	)
+
+	require.NoError(err)
+	r.NewGaugeFunc("queue_depth", func() float64 { return depth })
+
+	gather := func() float64 {
+		families, err := r.Gather()
+		require.NoError(err)
+		for _, family := range families {
+			if family.GetName() == "test_test_queue_depth" {
+				return family.GetMetric()[0].GetGauge().GetValue()
+			}
+		}
+
+		require.Fail("The gauge function was not gathered")
+		return 0.0
+	}
+
+	assert.Equal(3.0, gather())
+	depth = 7.0
+	assert.Equal(7.0, gather())
+
+	assert.Panics(func() { r.NewGaugeFunc("preregistered", func() float64 { return 0.0 }) })
+	assert.Panics(func() { r.NewGaugeFunc("queue_depth", func() float64 { return 0.0 }) })
+}
+
+func testRegistryTimer(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		r, err = NewRegistry(&Options{
+			DisableGoCollector:      true,
+			DisableProcessCollector: true,
+			Metrics: []Metric{
+				Metric{
+					Name:       "duration_seconds",
+					Type:       "histogram",
+					LabelNames: []string{"method"},
+				},
+			},
+		})
+	)
+
+	require.NoError(err)
+	r.NewTimer("duration_seconds", "method", "GET").Stop()
+
+	families, err := r.Gather()
+	require.NoError(err)
+	require.Len(families, 1)
+	require.Len(families[0].GetMetric(), 1)
+
+	m := families[0].GetMetric()[0]
+	assert.Equal("method", m.GetLabel()[0].GetName())
+	assert.Equal("GET", m.GetLabel()[0].GetValue())
+	assert.Equal(uint64(1), m.GetHistogram().GetSampleCount())
+}
+
+func testRegistryGetOrRegister(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		r, err = NewRegistry(&Options{
+			DisableGoCollector:      true,
+			DisableProcessCollector: true,
+			Metrics: []Metric{
+				Metric{
+					Name:       "preregistered",
+					Type:       CounterType,
+					LabelNames: []string{"code"},
+				},
+			},
+		})
+	)
+
+	require.NoError(err)
+
+	{
+		c, err := r.GetOrRegister(Metric{Name: "preregistered", Type: CounterType, LabelNames: []string{"code"}})
+		assert.NoError(err)
+		assert.True(c == r.NewCounterVec("preregistered"))
+
+		c, err = r.GetOrRegister(Metric{Name: "preregistered", Type: GaugeType, LabelNames: []string{"code"}})
+		assert.Error(err)
+		assert.Nil(c)
+
+		c, err = r.GetOrRegister(Metric{Name: "preregistered", Type: CounterType, LabelNames: []string{"method"}})
+		assert.Error(err)
+		assert.Nil(c)
+	}
+
+	{
+		c, err := r.GetOrRegister(Metric{Name: "plugin", Type: HistogramType, Help: "a plugin metric", LabelNames: []string{"method", "code"}})
+		require.NoError(err)
+		require.IsType((*prometheus.HistogramVec)(nil), c)
+
+		again, err := r.GetOrRegister(Metric{Name: "plugin", Type: HistogramType, LabelNames: []string{"method", "code"}})
+		assert.NoError(err)
+		assert.True(c == again)
+
+		// the provider methods use the dynamically registered metric, with its labels
+		assert.True(c == r.NewHistogramVec("plugin"))
+		r.NewHistogram("plugin", 0).With("method", "GET", "code", "200").Observe(1.0)
+
+		again, err = r.GetOrRegister(Metric{Name: "plugin", Type: HistogramType, LabelNames: []string{"code", "method"}})
+		assert.Error(err)
+		assert.Nil(again)
+
+		assert.Panics(func() { r.NewCounterVec("plugin") })
+	}
+
+	{
+		adhoc := r.NewGaugeVec("adhoc")
+		c, err := r.GetOrRegister(Metric{Name: "adhoc", Type: GaugeType})
+		assert.NoError(err)
+		assert.True(adhoc == c)
+
+		c, err = r.GetOrRegister(Metric{Name: "adhoc_counter", Type: CounterType})
+		require.NoError(err)
+		assert.True(c == r.NewCounterVec("adhoc_counter"))
+
+		r.NewSummaryVec("adhoc_summary")
+		c, err = r.GetOrRegister(Metric{Name: "adhoc_summary", Type: HistogramType})
+		assert.Error(err)
+		assert.Nil(c)
+	}
+
+	{
+		c, err := r.GetOrRegister(Metric{Type: CounterType})
+		assert.Error(err)
+		assert.Nil(c)
+
+		c, err = r.GetOrRegister(Metric{Name: "unsupported", Type: "nosuch"})
+		assert.Error(err)
+		assert.Nil(c)
+	}
+}
+
+func testRegistryAdHocTypeMismatch(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		r, err = NewRegistry(&Options{DisableGoCollector: true, DisableProcessCollector: true})
+	)
+
+	require.NoError(err)
+	r.NewCounterVec("adhoc")
+	assert.Panics(func() { r.NewGaugeVec("adhoc") })
+	assert.Panics(func() { r.NewHistogramVec("adhoc") })
+	assert.Panics(func() { r.NewSummaryVec("adhoc") })
+
+	r.NewGaugeVec("adhoc_gauge")
+	assert.Panics(func() { r.NewCounterVec("adhoc_gauge") })
+}
+
 func TestRegistry(t *testing.T) {
 	t.Run("AsPrometheusProvider", testRegistryAsPrometheusProvider)
 	t.Run("AsGoKitProvider", testRegistryAsGoKitProvider)
@@ -385,4 +558,8 @@ func TestRegistry(t *testing.T) {
 	t.Run("Duplicate", testRegistryDuplicate)
 	t.Run("UnsupportedType", testRegistryUnsupportedType)
 	t.Run("CounterLabel", testRegistryCounterLabel)
+	t.Run("GaugeFunc", testRegistryGaugeFunc)
+	t.Run("Timer", testRegistryTimer)
+	t.Run("GetOrRegister", testRegistryGetOrRegister)
+	t.Run("AdHocTypeMismatch", testRegistryAdHocTypeMismatch)
 }
@@ -0,0 +1,130 @@
+package xmetrics
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// validMetricName matches the legal Prometheus metric names
+var validMetricName = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// renamer computes the new fully-qualified names of metrics
+type renamer struct {
+	names    map[string]string
+	prefixes map[string]string
+
+	// sortedPrefixes holds the keys of prefixes, longest first, so that the most specific prefix wins
+	sortedPrefixes []string
+}
+
+func newRenamer(names, prefixes map[string]string) (*renamer, error) {
+	for from, to := range names {
+		if !validMetricName.MatchString(to) {
+			return nil, fmt.Errorf("Cannot rename metric %s: %s is not a valid metric name", from, to)
+		}
+	}
+
+	r := &renamer{
+		names:          names,
+		prefixes:       prefixes,
+		sortedPrefixes: make([]string, 0, len(prefixes)),
+	}
+
+	for from, to := range prefixes {
+		if len(from) == 0 {
+			return nil, fmt.Errorf("Cannot rename the empty prefix to %s", to)
+		}
+
+		if len(to) > 0 && !validMetricName.MatchString(to) {
+			return nil, fmt.Errorf("Cannot rename prefix %s: %s is not a valid metric name prefix", from, to)
+		}
+
+		r.sortedPrefixes = append(r.sortedPrefixes, from)
+	}
+
+	sort.Slice(r.sortedPrefixes, func(i, j int) bool {
+		return len(r.sortedPrefixes[i]) > len(r.sortedPrefixes[j])
+	})
+
+	return r, nil
+}
+
+// rename returns the new name for the given fully-qualified name.  An exact rename takes precedence
+// over any prefix, and a longer prefix takes precedence over a shorter one.
+func (r *renamer) rename(fqn string) (string, bool) {
+	if to, ok := r.names[fqn]; ok {
+		return to, true
+	}
+
+	for _, from := range r.sortedPrefixes {
+		// the remainder of the name must be nonempty, so that a prefix can never rename a metric to an empty name
+		if strings.HasPrefix(fqn, from) && len(fqn) > len(from) {
+			return r.prefixes[from] + fqn[len(from):], true
+		}
+	}
+
+	return fqn, false
+}
+
+// renameGatherer renames metric families as they are gathered.  Since the renaming happens at gather time,
+// the code that creates and updates metrics continues to use the original names.
+type renameGatherer struct {
+	prometheus.Gatherer
+
+	renamer      *renamer
+	keepOriginal bool
+}
+
+func (rg *renameGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := rg.Gatherer.Gather()
+
+	var (
+		errs   prometheus.MultiError
+		result = make([]*dto.MetricFamily, 0, len(families))
+		names  = make(map[string]bool, len(families))
+	)
+
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	add := func(family *dto.MetricFamily) {
+		if names[family.GetName()] {
+			errs = append(errs, fmt.Errorf("The metric %s was gathered more than once due to renaming", family.GetName()))
+			return
+		}
+
+		names[family.GetName()] = true
+		result = append(result, family)
+	}
+
+	for _, family := range families {
+		to, ok := rg.renamer.rename(family.GetName())
+		if !ok {
+			add(family)
+			continue
+		}
+
+		renamed := *family
+		renamed.Name = &to
+		add(&renamed)
+
+		if rg.keepOriginal {
+			original := *family
+			help := fmt.Sprintf("Deprecated: use %s instead.  %s", to, family.GetHelp())
+			original.Help = &help
+			add(&original)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].GetName() < result[j].GetName()
+	})
+
+	return result, errs.MaybeUnwrap()
+}
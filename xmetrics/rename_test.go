@@ -0,0 +1,181 @@
+package xmetrics
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRenamer(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := newRenamer(nil, nil)
+	assert.NotNil(r)
+	assert.NoError(err)
+
+	r, err = newRenamer(map[string]string{"old": "not valid"}, nil)
+	assert.Nil(r)
+	assert.Error(err)
+
+	r, err = newRenamer(nil, map[string]string{"": "new_"})
+	assert.Nil(r)
+	assert.Error(err)
+
+	r, err = newRenamer(nil, map[string]string{"old_": "0new_"})
+	assert.Nil(r)
+	assert.Error(err)
+
+	r, err = newRenamer(nil, map[string]string{"old_": ""})
+	assert.NotNil(r)
+	assert.NoError(err)
+}
+
+func TestRenamerRename(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		r, err = newRenamer(
+			map[string]string{"xmidt_talaria_requests": "talaria_http_requests"},
+			map[string]string{
+				"xmidt_":         "xmidt2_",
+				"xmidt_talaria_": "talaria_",
+			},
+		)
+	)
+
+	require.NoError(err)
+	testData := []struct {
+		fqn      string
+		expected string
+		renamed  bool
+	}{
+		{"xmidt_talaria_requests", "talaria_http_requests", true},
+		{"xmidt_talaria_devices", "talaria_devices", true},
+		{"xmidt_scytale_devices", "xmidt2_scytale_devices", true},
+		{"xmidt_", "xmidt_", false},
+		{"go_goroutines", "go_goroutines", false},
+	}
+
+	for _, record := range testData {
+		actual, renamed := r.rename(record.fqn)
+		assert.Equal(record.expected, actual, record.fqn)
+		assert.Equal(record.renamed, renamed, record.fqn)
+	}
+}
+
+func gatheredNames(t *testing.T, g prometheus.Gatherer) ([]string, []string) {
+	families, err := g.Gather()
+	require.NoError(t, err)
+
+	var names, help []string
+	for _, family := range families {
+		names = append(names, family.GetName())
+		help = append(help, family.GetHelp())
+	}
+
+	return names, help
+}
+
+func testRegistryRenames(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		r, err = NewRegistry(&Options{
+			Namespace:               "xmidt",
+			Subsystem:               "talaria",
+			DisableGoCollector:      true,
+			DisableProcessCollector: true,
+			Metrics: []Metric{
+				Metric{Name: "requests", Type: CounterType, Help: "The requests"},
+				Metric{Name: "devices", Type: GaugeType, Help: "The devices"},
+				Metric{Name: "events", Type: CounterType, Help: "The events"},
+			},
+			Renames:        map[string]string{"xmidt_talaria_requests": "talaria_http_requests"},
+			RenamePrefixes: map[string]string{"xmidt_talaria_dev": "talaria_dev"},
+		})
+	)
+
+	require.NoError(err)
+	r.NewCounter("requests").Add(1.0)
+	r.NewGauge("devices").Set(5.0)
+	r.NewCounter("events").Add(2.0)
+
+	names, _ := gatheredNames(t, r)
+	assert.Equal([]string{"talaria_devices", "talaria_http_requests", "xmidt_talaria_events"}, names)
+}
+
+func testRegistryRenamesKeepOriginal(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		r, err = NewRegistry(&Options{
+			DisableGoCollector:      true,
+			DisableProcessCollector: true,
+			Metrics: []Metric{
+				Metric{Name: "requests", Type: CounterType, Help: "The requests"},
+			},
+			Renames:           map[string]string{"test_test_requests": "requests_total"},
+			KeepOriginalNames: true,
+		})
+	)
+
+	require.NoError(err)
+	r.NewCounter("requests").Add(1.0)
+
+	names, help := gatheredNames(t, r)
+	assert.Equal([]string{"requests_total", "test_test_requests"}, names)
+	assert.Equal([]string{"The requests", "Deprecated: use requests_total instead.  The requests"}, help)
+}
+
+func testRegistryRenamesInvalid(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		r, err = NewRegistry(&Options{
+			Renames: map[string]string{"test_test_requests": "requests-total"},
+		})
+	)
+
+	assert.Nil(r)
+	assert.Error(err)
+}
+
+func TestRegistryRenames(t *testing.T) {
+	t.Run("Renames", testRegistryRenames)
+	t.Run("KeepOriginal", testRegistryRenamesKeepOriginal)
+	t.Run("Invalid", testRegistryRenamesInvalid)
+}
+
+func TestRenameGathererCollision(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		require  = require.New(t)
+		expected = errors.New("expected")
+
+		first    = "first"
+		second   = "second"
+		gatherer = prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+			return []*dto.MetricFamily{
+				&dto.MetricFamily{Name: &first},
+				&dto.MetricFamily{Name: &second},
+			}, expected
+		})
+
+		rn, err = newRenamer(map[string]string{"first": "second"}, nil)
+	)
+
+	require.NoError(err)
+	families, err := (&renameGatherer{Gatherer: gatherer, renamer: rn}).Gather()
+	require.Len(families, 1)
+	assert.Equal("second", families[0].GetName())
+
+	require.IsType(prometheus.MultiError{}, err)
+	assert.Len(err.(prometheus.MultiError), 2)
+	assert.Equal(expected, err.(prometheus.MultiError)[0])
+}
@@ -0,0 +1,154 @@
+package xmetrics
+
+import (
+	"runtime"
+	"runtime/pprof"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/procfs"
+)
+
+const (
+	// RuntimeSubsystem is the subsystem for all the metrics in RuntimeModule
+	RuntimeSubsystem = "runtime"
+
+	GCPauseSeconds = "gc_pause_seconds"
+	HeapAllocBytes = "heap_alloc_bytes"
+	HeapInuseBytes = "heap_inuse_bytes"
+	GoroutineCount = "goroutines"
+	ThreadCount    = "threads"
+	OpenFDs        = "open_fds"
+	MaxFDs         = "max_fds"
+)
+
+// RuntimeModule is the metrics module for the Go runtime.  These metrics use the RuntimeSubsystem and the
+// default namespace, so they are named consistently across services.  Set Options.RuntimeMetrics to register
+// this module and keep its metrics updated.  As with any module, the configured Options.Metrics can override these metrics,
+// e.g. to change the GC pause objectives.
+//
+// The file descriptor metrics are only reported on platforms with a proc filesystem.
+func RuntimeModule() []Metric {
+	return []Metric{
+		Metric{
+			Name:       GCPauseSeconds,
+			Type:       SummaryType,
+			Subsystem:  RuntimeSubsystem,
+			Help:       "The stop-the-world pause durations of garbage collection cycles",
+			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+		},
+		Metric{
+			Name:      HeapAllocBytes,
+			Type:      GaugeType,
+			Subsystem: RuntimeSubsystem,
+			Help:      "The bytes of allocated heap objects",
+		},
+		Metric{
+			Name:      HeapInuseBytes,
+			Type:      GaugeType,
+			Subsystem: RuntimeSubsystem,
+			Help:      "The bytes in in-use heap spans",
+		},
+		Metric{
+			Name:      GoroutineCount,
+			Type:      GaugeType,
+			Subsystem: RuntimeSubsystem,
+			Help:      "The number of goroutines that currently exist",
+		},
+		Metric{
+			Name:      ThreadCount,
+			Type:      GaugeType,
+			Subsystem: RuntimeSubsystem,
+			Help:      "The number of OS threads created",
+		},
+		Metric{
+			Name:      OpenFDs,
+			Type:      GaugeType,
+			Subsystem: RuntimeSubsystem,
+			Help:      "The number of open file descriptors",
+		},
+		Metric{
+			Name:      MaxFDs,
+			Type:      GaugeType,
+			Subsystem: RuntimeSubsystem,
+			Help:      "The maximum number of open file descriptors",
+		},
+	}
+}
+
+// runtimeGatherer updates the runtime metrics immediately before each gather, so that the values
+// are current for both scrapes and any Mirror
+type runtimeGatherer struct {
+	prometheus.Gatherer
+
+	lock      sync.Mutex
+	lastNumGC uint32
+
+	gcPause   prometheus.Observer
+	heapAlloc prometheus.Gauge
+	heapInuse prometheus.Gauge
+	goroutine prometheus.Gauge
+	threads   prometheus.Gauge
+	openFDs   prometheus.Gauge
+	maxFDs    prometheus.Gauge
+}
+
+func newRuntimeGatherer(g prometheus.Gatherer, r PrometheusProvider, namespace string) *runtimeGatherer {
+	return &runtimeGatherer{
+		Gatherer:  g,
+		gcPause:   r.NewSummaryVecEx(namespace, RuntimeSubsystem, GCPauseSeconds).WithLabelValues(),
+		heapAlloc: r.NewGaugeVecEx(namespace, RuntimeSubsystem, HeapAllocBytes).WithLabelValues(),
+		heapInuse: r.NewGaugeVecEx(namespace, RuntimeSubsystem, HeapInuseBytes).WithLabelValues(),
+		goroutine: r.NewGaugeVecEx(namespace, RuntimeSubsystem, GoroutineCount).WithLabelValues(),
+		threads:   r.NewGaugeVecEx(namespace, RuntimeSubsystem, ThreadCount).WithLabelValues(),
+		openFDs:   r.NewGaugeVecEx(namespace, RuntimeSubsystem, OpenFDs).WithLabelValues(),
+		maxFDs:    r.NewGaugeVecEx(namespace, RuntimeSubsystem, MaxFDs).WithLabelValues(),
+	}
+}
+
+func (rg *runtimeGatherer) Gather() ([]*dto.MetricFamily, error) {
+	rg.update()
+	return rg.Gatherer.Gather()
+}
+
+func (rg *runtimeGatherer) update() {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	rg.lock.Lock()
+	rg.observePauses(&ms)
+	rg.lock.Unlock()
+
+	rg.heapAlloc.Set(float64(ms.HeapAlloc))
+	rg.heapInuse.Set(float64(ms.HeapInuse))
+	rg.goroutine.Set(float64(runtime.NumGoroutine()))
+	rg.threads.Set(float64(pprof.Lookup("threadcreate").Count()))
+
+	if p, err := procfs.Self(); err == nil {
+		if n, err := p.FileDescriptorsLen(); err == nil {
+			rg.openFDs.Set(float64(n))
+		}
+
+		if limits, err := p.Limits(); err == nil {
+			rg.maxFDs.Set(float64(limits.OpenFiles))
+		}
+	}
+}
+
+// observePauses records the pauses of any garbage collections since the last update.  The runtime only retains
+// the most recent pauses, so some pauses are not observed if there were many collections between updates.
+func (rg *runtimeGatherer) observePauses(ms *runtime.MemStats) {
+	cycles := ms.NumGC - rg.lastNumGC
+	if cycles > uint32(len(ms.PauseNs)) {
+		cycles = uint32(len(ms.PauseNs))
+	}
+
+	for i := uint32(0); i < cycles; i++ {
+		pause := ms.PauseNs[(ms.NumGC-i+uint32(len(ms.PauseNs))-1)%uint32(len(ms.PauseNs))]
+		rg.gcPause.Observe(time.Duration(pause).Seconds())
+	}
+
+	rg.lastNumGC = ms.NumGC
+}
@@ -0,0 +1,124 @@
+package xmetrics
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuntimeModule(t *testing.T) {
+	assert := assert.New(t)
+
+	metrics := RuntimeModule()
+	assert.Len(metrics, 7)
+	for _, m := range metrics {
+		assert.Equal(RuntimeSubsystem, m.Subsystem)
+		assert.NotEmpty(m.Help)
+		_, err := NewCollector(m)
+		assert.NoError(err)
+	}
+}
+
+func TestRuntimeGathererObservePauses(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		summary = prometheus.NewSummary(prometheus.SummaryOpts{Name: "test"})
+		rg      = &runtimeGatherer{gcPause: summary}
+		ms      runtime.MemStats
+		m       dto.Metric
+	)
+
+	ms.NumGC = 2
+	ms.PauseNs[0] = uint64(time.Second)
+	ms.PauseNs[1] = uint64(2 * time.Second)
+	rg.observePauses(&ms)
+
+	// no new collections
+	rg.observePauses(&ms)
+
+	// more collections than the runtime retains
+	ms.NumGC = 2 + uint32(len(ms.PauseNs)) + 10
+	rg.observePauses(&ms)
+
+	assert.NoError(summary.Write(&m))
+	assert.Equal(uint64(2+len(ms.PauseNs)), m.GetSummary().GetSampleCount())
+	assert.Equal(6.0, m.GetSummary().GetSampleSum())
+}
+
+func testRegistryRuntimeMetricsDisabled(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		r, err = NewRegistry(&Options{DisableGoCollector: true, DisableProcessCollector: true})
+	)
+
+	require.NoError(err)
+	families, err := r.Gather()
+	assert.NoError(err)
+	assert.Empty(families)
+}
+
+func testRegistryRuntimeMetricsEnabled(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		mirror  = newRecordingMirror()
+
+		r, err = NewRegistry(&Options{
+			Namespace:               "xmidt",
+			DisableGoCollector:      true,
+			DisableProcessCollector: true,
+			RuntimeMetrics:          true,
+		})
+	)
+
+	require.NoError(err)
+	runtime.GC()
+	require.NoError(mirrorTo(r, mirror))
+
+	assert.True(mirror.gauges["xmidt_runtime_goroutines"].value >= 1.0)
+	assert.True(mirror.gauges["xmidt_runtime_threads"].value >= 1.0)
+	assert.True(mirror.gauges["xmidt_runtime_heap_alloc_bytes"].value > 0.0)
+	assert.True(mirror.gauges["xmidt_runtime_heap_inuse_bytes"].value > 0.0)
+	assert.True(mirror.histograms["xmidt_runtime_gc_pause_seconds"].snapshot.Count >= 1)
+
+}
+
+func testRegistryRuntimeMetricsOverride(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		mirror  = newRecordingMirror()
+
+		r, err = NewRegistry(&Options{
+			DisableGoCollector:      true,
+			DisableProcessCollector: true,
+			RuntimeMetrics:          true,
+			Metrics: []Metric{
+				Metric{
+					Name:      GCPauseSeconds,
+					Type:      SummaryType,
+					Subsystem: RuntimeSubsystem,
+				},
+			},
+		})
+	)
+
+	require.NoError(err)
+	runtime.GC()
+	require.NoError(mirrorTo(r, mirror))
+	assert.True(mirror.histograms["test_runtime_gc_pause_seconds"].snapshot.Count >= 1)
+}
+
+func TestRegistryRuntimeMetrics(t *testing.T) {
+	t.Run("Disabled", testRegistryRuntimeMetricsDisabled)
+	t.Run("Enabled", testRegistryRuntimeMetricsEnabled)
+	t.Run("Override", testRegistryRuntimeMetricsOverride)
+}
@@ -0,0 +1,61 @@
+package xmetrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/xmidt-org/webpa-common/clock"
+)
+
+// TimerProvider is implemented by providers that can create timers for their histograms.  Code that is handed
+// a go-kit provider.Provider can type assert to this interface.
+type TimerProvider interface {
+	// NewTimer starts a Timer that observes into the histogram with the given name and label/value pairs
+	NewTimer(name string, labelsAndValues ...string) *Timer
+}
+
+// Timer measures the duration of an operation and observes it, in seconds, into a histogram.  This replaces
+// the usual time.Now and time.Since boilerplate:
+//
+//	timer := registry.NewTimer("request_duration_seconds", "method", "GET")
+//	defer timer.Stop()
+type Timer struct {
+	histogram metrics.Histogram
+	clock     clock.Interface
+	start     time.Time
+
+	stopOnce sync.Once
+	elapsed  time.Duration
+}
+
+// NewTimer starts a Timer for the given histogram, which should already have any labels applied
+func NewTimer(h metrics.Histogram) *Timer {
+	return NewTimerWithClock(h, clock.System())
+}
+
+// NewTimerWithClock starts a Timer that uses the given clock to measure time, which allows tests to
+// control the observed durations
+func NewTimerWithClock(h metrics.Histogram, c clock.Interface) *Timer {
+	return &Timer{
+		histogram: h,
+		clock:     c,
+		start:     c.Now(),
+	}
+}
+
+// Elapsed returns the time since this Timer was started, without observing it
+func (t *Timer) Elapsed() time.Duration {
+	return t.clock.Now().Sub(t.start)
+}
+
+// Stop observes the time since this Timer was started and returns it.  Only the first call to Stop
+// observes a value, and subsequent calls return the same duration.
+func (t *Timer) Stop() time.Duration {
+	t.stopOnce.Do(func() {
+		t.elapsed = t.Elapsed()
+		t.histogram.Observe(t.elapsed.Seconds())
+	})
+
+	return t.elapsed
+}
@@ -0,0 +1,60 @@
+package xmetrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/xmidt-org/webpa-common/clock/clocktest"
+)
+
+// recordingHistogram is a go-kit histogram that records each observation
+type recordingHistogram struct {
+	observations []float64
+}
+
+func (rh *recordingHistogram) With(...string) metrics.Histogram {
+	return rh
+}
+
+func (rh *recordingHistogram) Observe(v float64) {
+	rh.observations = append(rh.observations, v)
+}
+
+func TestNewTimer(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		h      = new(recordingHistogram)
+		timer  = NewTimer(h)
+	)
+
+	assert.True(timer.Elapsed() >= 0)
+	assert.True(timer.Stop() >= 0)
+	assert.Len(h.observations, 1)
+}
+
+func TestNewTimerWithClock(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		h      = new(recordingHistogram)
+		c      = new(clocktest.Mock)
+		start  = time.Now()
+	)
+
+	c.OnNow(start).Once()
+	timer := NewTimerWithClock(h, c)
+
+	c.OnNow(start.Add(500 * time.Millisecond)).Once()
+	assert.Equal(500*time.Millisecond, timer.Elapsed())
+	assert.Empty(h.observations)
+
+	c.OnNow(start.Add(2 * time.Second)).Once()
+	assert.Equal(2*time.Second, timer.Stop())
+	assert.Equal([]float64{2.0}, h.observations)
+
+	// subsequent stops neither consult the clock nor observe again
+	assert.Equal(2*time.Second, timer.Stop())
+	assert.Equal([]float64{2.0}, h.observations)
+	c.AssertExpectations(t)
+}
@@ -0,0 +1,133 @@
+package xmetricshttp
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// DefaultRealm is the realm reported to unauthenticated clients when AuthOptions.Realm is unset
+const DefaultRealm = "metrics"
+
+// AuthOptions configures the credentials required to scrape metrics, for deployments that must expose
+// the metrics endpoint on a public port.  Basic authentication and bearer tokens may be used together,
+// in which case a request may supply either.  If no credentials are configured, requests are not authenticated.
+type AuthOptions struct {
+	// Username is the basic authentication user.  Basic authentication is required if either Username or Password is set.
+	Username string
+
+	// Password is the basic authentication password
+	Password string
+
+	// Tokens are the accepted bearer tokens.  More than one token allows tokens to be rotated without downtime.
+	Tokens []string
+
+	// Realm is the realm reported in the WWW-Authenticate header.  If unset, DefaultRealm is used.
+	Realm string
+}
+
+func (ao AuthOptions) realm() string {
+	if len(ao.Realm) > 0 {
+		return ao.Realm
+	}
+
+	return DefaultRealm
+}
+
+// digest hashes a credential so that comparisons take the same time regardless of the credential's length
+func digest(v string) []byte {
+	d := sha256.Sum256([]byte(v))
+	return d[:]
+}
+
+// authHandler rejects requests that do not carry one of the configured credentials
+type authHandler struct {
+	next      http.Handler
+	basic     bool
+	username  []byte
+	password  []byte
+	tokens    [][]byte
+	challenge []string
+}
+
+func (ah *authHandler) authenticated(request *http.Request) bool {
+	if ah.basic {
+		if username, password, ok := request.BasicAuth(); ok {
+			// evaluate both comparisons, so that the time taken does not reveal which one failed
+			u := subtle.ConstantTimeCompare(ah.username, digest(username))
+			p := subtle.ConstantTimeCompare(ah.password, digest(password))
+			return u&p == 1
+		}
+	}
+
+	if len(ah.tokens) > 0 {
+		authorization := request.Header.Get("Authorization")
+		if len(authorization) > 7 && strings.EqualFold(authorization[:7], "Bearer ") {
+			token := digest(authorization[7:])
+			valid := 0
+			for _, t := range ah.tokens {
+				valid |= subtle.ConstantTimeCompare(t, token)
+			}
+
+			return valid == 1
+		}
+	}
+
+	return false
+}
+
+func (ah *authHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if !ah.authenticated(request) {
+		for _, c := range ah.challenge {
+			response.Header().Add("WWW-Authenticate", c)
+		}
+
+		response.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	ah.next.ServeHTTP(response, request)
+}
+
+// NewAuthConstructor creates an Alice-style constructor that requires the credentials described by the given
+// options.  Unauthenticated requests receive a 401 response with a WWW-Authenticate challenge for each configured
+// scheme.  If the options do not configure any credentials, the returned constructor does not decorate handlers.
+func NewAuthConstructor(o AuthOptions) func(http.Handler) http.Handler {
+	var (
+		basic     = len(o.Username) > 0 || len(o.Password) > 0
+		tokens    = make([][]byte, 0, len(o.Tokens))
+		challenge []string
+	)
+
+	for _, t := range o.Tokens {
+		if len(t) > 0 {
+			tokens = append(tokens, digest(t))
+		}
+	}
+
+	if !basic && len(tokens) == 0 {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+
+	if basic {
+		challenge = append(challenge, `Basic realm="`+o.realm()+`"`)
+	}
+
+	if len(tokens) > 0 {
+		challenge = append(challenge, `Bearer realm="`+o.realm()+`"`)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return &authHandler{
+			next:      next,
+			basic:     basic,
+			username:  digest(o.Username),
+			password:  digest(o.Password),
+			tokens:    tokens,
+			challenge: challenge,
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package xmetricshttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var authOK = http.HandlerFunc(func(response http.ResponseWriter, _ *http.Request) {
+	response.WriteHeader(http.StatusOK)
+})
+
+func testNewAuthConstructorNone(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		handler  = NewAuthConstructor(AuthOptions{Tokens: []string{""}})(authOK)
+		response = httptest.NewRecorder()
+	)
+
+	handler.ServeHTTP(response, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(http.StatusOK, response.Code)
+}
+
+func testNewAuthConstructorBasic(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		handler = NewAuthConstructor(AuthOptions{Username: "prometheus", Password: "secret"})(authOK)
+	)
+
+	testData := []struct {
+		username string
+		password string
+		bearer   string
+		expected int
+	}{
+		{"prometheus", "secret", "", http.StatusOK},
+		{"prometheus", "wrong", "", http.StatusUnauthorized},
+		{"other", "secret", "", http.StatusUnauthorized},
+		{"", "", "secret", http.StatusUnauthorized},
+		{"", "", "", http.StatusUnauthorized},
+	}
+
+	for i, record := range testData {
+		t.Logf("%d: %#v", i, record)
+
+		var (
+			request  = httptest.NewRequest("GET", "/metrics", nil)
+			response = httptest.NewRecorder()
+		)
+
+		if len(record.username) > 0 {
+			request.SetBasicAuth(record.username, record.password)
+		}
+
+		if len(record.bearer) > 0 {
+			request.Header.Set("Authorization", "Bearer "+record.bearer)
+		}
+
+		handler.ServeHTTP(response, request)
+		assert.Equal(record.expected, response.Code)
+		if record.expected == http.StatusUnauthorized {
+			assert.Equal([]string{`Basic realm="metrics"`}, response.Header()["Www-Authenticate"])
+		}
+	}
+}
+
+func testNewAuthConstructorBearer(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		handler = NewAuthConstructor(AuthOptions{Tokens: []string{"current", "previous"}, Realm: "talaria"})(authOK)
+	)
+
+	testData := []struct {
+		authorization string
+		expected      int
+	}{
+		{"Bearer current", http.StatusOK},
+		{"bearer previous", http.StatusOK},
+		{"Bearer other", http.StatusUnauthorized},
+		{"Bearer ", http.StatusUnauthorized},
+		{"Basic Y3VycmVudDo=", http.StatusUnauthorized},
+		{"", http.StatusUnauthorized},
+	}
+
+	for i, record := range testData {
+		t.Logf("%d: %#v", i, record)
+
+		var (
+			request  = httptest.NewRequest("GET", "/metrics", nil)
+			response = httptest.NewRecorder()
+		)
+
+		if len(record.authorization) > 0 {
+			request.Header.Set("Authorization", record.authorization)
+		}
+
+		handler.ServeHTTP(response, request)
+		assert.Equal(record.expected, response.Code)
+		if record.expected == http.StatusUnauthorized {
+			assert.Equal([]string{`Bearer realm="talaria"`}, response.Header()["Www-Authenticate"])
+		}
+	}
+}
+
+func testNewAuthConstructorBoth(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		handler = NewAuthConstructor(AuthOptions{Username: "prometheus", Password: "secret", Tokens: []string{"token"}})(authOK)
+	)
+
+	{
+		request := httptest.NewRequest("GET", "/metrics", nil)
+		request.SetBasicAuth("prometheus", "secret")
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, request)
+		assert.Equal(http.StatusOK, response.Code)
+	}
+
+	{
+		request := httptest.NewRequest("GET", "/metrics", nil)
+		request.Header.Set("Authorization", "Bearer token")
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, request)
+		assert.Equal(http.StatusOK, response.Code)
+	}
+
+	{
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, httptest.NewRequest("GET", "/metrics", nil))
+		assert.Equal(http.StatusUnauthorized, response.Code)
+		assert.Equal([]string{`Basic realm="metrics"`, `Bearer realm="metrics"`}, response.Header()["Www-Authenticate"])
+	}
+}
+
+func TestNewAuthConstructor(t *testing.T) {
+	t.Run("None", testNewAuthConstructorNone)
+	t.Run("Basic", testNewAuthConstructorBasic)
+	t.Run("Bearer", testNewAuthConstructorBearer)
+	t.Run("Both", testNewAuthConstructorBoth)
+}
@@ -0,0 +1,101 @@
+package xmetricshttp
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/xmidt-org/webpa-common/xmetrics"
+)
+
+const (
+	// UnknownTarget is the target label used when no target is configured
+	UnknownTarget = "unknown"
+
+	// ErrorCode is the code label used for requests that failed without a response
+	ErrorCode = "error"
+)
+
+// RoundTripperFunc is a function type that implements http.RoundTripper.  Any function with the same signature as
+// http.Client.Do, such as the transactors used by fanout, can be decorated by converting it to this type.
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (rtf RoundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return rtf(request)
+}
+
+// ClientOptions holds the configurable options for the client decorator
+type ClientOptions struct {
+	// Target is the logical name of the service being called, e.g. "talaria".  If unset, UnknownTarget is used.
+	Target string
+}
+
+func (o ClientOptions) target() string {
+	if len(o.Target) > 0 {
+		return o.Target
+	}
+
+	return UnknownTarget
+}
+
+// clientRoundTripper is the decorator that instruments a RoundTripper
+type clientRoundTripper struct {
+	next     http.RoundTripper
+	target   string
+	count    *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight *prometheus.GaugeVec
+}
+
+func (crt *clientRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	var (
+		start  = time.Now()
+		host   = request.URL.Host
+		method = request.Method
+	)
+
+	inFlight := crt.inFlight.WithLabelValues(crt.target, host)
+	inFlight.Inc()
+	defer inFlight.Dec()
+
+	response, err := crt.next.RoundTrip(request)
+
+	code := ErrorCode
+	if err == nil && response != nil {
+		code = strconv.Itoa(response.StatusCode)
+	}
+
+	crt.count.WithLabelValues(crt.target, host, method, code).Inc()
+	crt.duration.WithLabelValues(crt.target, host, method, code).Observe(time.Since(start).Seconds())
+	return response, err
+}
+
+// NewClientConstructor returns a decorator that records the request count, duration, and in-flight requests of
+// the RoundTripper it decorates, labeled by target, host, method, and code.  The duration is the time taken to
+// receive the response headers, since the response body is read after RoundTrip returns.  Requests that fail
+// without a response are recorded with the ErrorCode.  If the decorated RoundTripper is nil, http.DefaultTransport is used.
+//
+// The given provider must have the metrics from this package's Metrics module.
+func NewClientConstructor(p xmetrics.PrometheusProvider, o ClientOptions) func(http.RoundTripper) http.RoundTripper {
+	var (
+		target   = o.target()
+		count    = p.NewCounterVec(ClientRequestCount)
+		duration = p.NewHistogramVec(ClientRequestDurationSeconds)
+		inFlight = p.NewGaugeVec(ClientInFlightRequests)
+	)
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		if next == nil {
+			next = http.DefaultTransport
+		}
+
+		return &clientRoundTripper{
+			next:     next,
+			target:   target,
+			count:    count,
+			duration: duration,
+			inFlight: inFlight,
+		}
+	}
+}
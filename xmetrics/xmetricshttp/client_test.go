@@ -0,0 +1,98 @@
+package xmetricshttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundTripperFunc(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		expected = new(http.Response)
+		request  = httptest.NewRequest("GET", "/", nil)
+
+		rtf = RoundTripperFunc(func(actual *http.Request) (*http.Response, error) {
+			assert.Equal(request, actual)
+			return expected, nil
+		})
+	)
+
+	actual, err := rtf.RoundTrip(request)
+	assert.Equal(expected, actual)
+	assert.NoError(err)
+}
+
+func testNewClientConstructorSuccess(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		r       = newTestRegistry(t)
+
+		server = httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			m := findMetric(t, r, "test_test_client_in_flight_requests", map[string]string{TargetLabel: "talaria", HostLabel: request.Host})
+			if assert.NotNil(m) {
+				assert.Equal(1.0, m.GetGauge().GetValue())
+			}
+
+			response.WriteHeader(http.StatusAccepted)
+		}))
+
+		client = http.Client{
+			Transport: NewClientConstructor(r, ClientOptions{Target: "talaria"})(nil),
+		}
+	)
+
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(err)
+
+	response, err := client.Post(server.URL, "text/plain", nil)
+	require.NoError(err)
+	response.Body.Close()
+	assert.Equal(http.StatusAccepted, response.StatusCode)
+
+	labels := map[string]string{TargetLabel: "talaria", HostLabel: serverURL.Host, MethodLabel: "POST", CodeLabel: "202"}
+	count := findMetric(t, r, "test_test_client_request_count", labels)
+	require.NotNil(count)
+	assert.Equal(1.0, count.GetCounter().GetValue())
+
+	duration := findMetric(t, r, "test_test_client_request_duration_seconds", labels)
+	require.NotNil(duration)
+	assert.Equal(uint64(1), duration.GetHistogram().GetSampleCount())
+
+	inFlight := findMetric(t, r, "test_test_client_in_flight_requests", map[string]string{TargetLabel: "talaria", HostLabel: serverURL.Host})
+	require.NotNil(inFlight)
+	assert.Zero(inFlight.GetGauge().GetValue())
+}
+
+func testNewClientConstructorError(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		require  = require.New(t)
+		r        = newTestRegistry(t)
+		expected = errors.New("expected")
+
+		transactor = NewClientConstructor(r, ClientOptions{})(RoundTripperFunc(func(*http.Request) (*http.Response, error) {
+			return nil, expected
+		})).RoundTrip
+	)
+
+	response, err := transactor(httptest.NewRequest("GET", "http://device.example.com/api", nil))
+	assert.Nil(response)
+	assert.Equal(expected, err)
+
+	count := findMetric(t, r, "test_test_client_request_count", map[string]string{TargetLabel: UnknownTarget, HostLabel: "device.example.com", MethodLabel: "GET", CodeLabel: ErrorCode})
+	require.NotNil(count)
+	assert.Equal(1.0, count.GetCounter().GetValue())
+}
+
+func TestNewClientConstructor(t *testing.T) {
+	t.Run("Success", testNewClientConstructorSuccess)
+	t.Run("Error", testNewClientConstructorError)
+}
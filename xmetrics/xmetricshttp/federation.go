@@ -0,0 +1,19 @@
+package xmetricshttp
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/xmidt-org/webpa-common/xmetrics"
+)
+
+// NewFederationHandler creates a single metrics handler that exposes the merged metrics of several sources,
+// e.g. an application's primary Registry along with the registries embedded in its libraries.
+func NewFederationHandler(o promhttp.HandlerOpts, sources ...xmetrics.FederatedSource) (http.Handler, error) {
+	federation, err := xmetrics.NewFederation(sources...)
+	if err != nil {
+		return nil, err
+	}
+
+	return promhttp.HandlerFor(federation, o), nil
+}
@@ -0,0 +1,48 @@
+package xmetricshttp
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/xmetrics"
+)
+
+func TestNewFederationHandler(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		primary = newTestRegistry(t)
+		library = newTestRegistry(t)
+	)
+
+	primary.NewCounter("primary_requests").Add(1.0)
+	library.NewCounter("library_requests").Add(2.0)
+
+	h, err := NewFederationHandler(
+		promhttp.HandlerOpts{},
+		xmetrics.FederatedSource{Gatherer: primary},
+		xmetrics.FederatedSource{Gatherer: library, Prefix: "chrysom_"},
+	)
+
+	require.NoError(err)
+	require.NotNil(h)
+
+	response := httptest.NewRecorder()
+	h.ServeHTTP(response, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(http.StatusOK, response.Code)
+
+	body, err := ioutil.ReadAll(response.Body)
+	require.NoError(err)
+	assert.Contains(string(body), "test_test_primary_requests 1")
+	assert.Contains(string(body), "chrysom_test_test_library_requests 2")
+
+	h, err = NewFederationHandler(promhttp.HandlerOpts{}, xmetrics.FederatedSource{})
+	assert.Nil(h)
+	assert.Error(err)
+}
@@ -0,0 +1,138 @@
+package xmetricshttp
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// ScrapeDurationSeconds is the histogram of the time taken to serve each scrape.  It is registered with
+// HandlerOpts.Registry, alongside the error counter that promhttp registers.
+const ScrapeDurationSeconds = "metrics_scrape_duration_seconds"
+
+// scrapeLimiter responds with a 503 to any scrape beyond a maximum number of concurrent scrapes
+type scrapeLimiter struct {
+	next     http.Handler
+	inFlight chan struct{}
+}
+
+func (sl *scrapeLimiter) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	select {
+	case sl.inFlight <- struct{}{}:
+		defer func() { <-sl.inFlight }()
+		sl.next.ServeHTTP(response, request)
+
+	default:
+		http.Error(
+			response,
+			fmt.Sprintf("Limit of concurrent requests reached (%d), try again later.", cap(sl.inFlight)),
+			http.StatusServiceUnavailable,
+		)
+	}
+}
+
+// scrapeObserver records the duration of each scrape, including scrapes rejected by the limit or the timeout
+type scrapeObserver struct {
+	next     http.Handler
+	duration *prometheus.HistogramVec
+	now      func() time.Time
+}
+
+func (so *scrapeObserver) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	var (
+		start  = so.now()
+		writer = &recordingWriter{ResponseWriter: response, code: http.StatusOK}
+	)
+
+	so.next.ServeHTTP(writer, request)
+	so.duration.WithLabelValues(strconv.Itoa(writer.code)).Observe(so.now().Sub(start).Seconds())
+}
+
+// newScrapeDuration registers the scrape duration histogram, or returns the existing one if another handler
+// has already registered it
+func newScrapeDuration(r prometheus.Registerer) *prometheus.HistogramVec {
+	duration := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    ScrapeDurationSeconds,
+			Help:    "The time taken to serve scrapes of the metrics handler",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{CodeLabel},
+	)
+
+	if err := r.Register(duration); err != nil {
+		if already, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return already.ExistingCollector.(*prometheus.HistogramVec)
+		}
+
+		panic(err)
+	}
+
+	return duration
+}
+
+// NewHandler creates the metrics handler for a gatherer, such as an xmetrics.Registry.  This handler honors all
+// the promhttp options, with the following additions:
+//
+// If o.EnableOpenMetrics is true, clients which negotiate the OpenMetrics content type, such as newer versions
+// of Prometheus and Mimir, are served OpenMetrics that includes a _created series for each counter, histogram, and summary.
+// Since metrics do not record when they were created, the creation time is the time this handler was created for series
+// present on the first scrape, and the time of the first scrape that included the series otherwise.  Note that counters
+// are only exposed with the counter type, and thus a _created series, if their names end in "_total".
+// All other clients receive the usual Prometheus text or protobuf format.
+//
+// o.MaxRequestsInFlight and o.Timeout apply to all scrapes, regardless of format.  Scrapes beyond the limit or
+// the timeout receive a 503 response.
+//
+// If o.Registry is set, the ScrapeDurationSeconds histogram is registered with it and records the duration of every scrape,
+// labelled by response code.
+func NewHandler(g prometheus.Gatherer, o promhttp.HandlerOpts) http.Handler {
+	return newHandler(g, o, time.Now)
+}
+
+func newHandler(g prometheus.Gatherer, o promhttp.HandlerOpts, now func() time.Time) http.Handler {
+	var (
+		maxInFlight = o.MaxRequestsInFlight
+		timeout     = o.Timeout
+	)
+
+	// the limit and timeout are applied below, so that they cover the OpenMetrics handler as well
+	o.MaxRequestsInFlight = 0
+	o.Timeout = 0
+
+	var handler http.Handler = promhttp.HandlerFor(g, o)
+	if o.EnableOpenMetrics {
+		handler = &openMetricsHandler{
+			gatherer: g,
+			options:  o,
+			standard: handler,
+			now:      now,
+			start:    now(),
+		}
+	}
+
+	if maxInFlight > 0 {
+		handler = &scrapeLimiter{
+			next:     handler,
+			inFlight: make(chan struct{}, maxInFlight),
+		}
+	}
+
+	if timeout > 0 {
+		handler = http.TimeoutHandler(handler, timeout, fmt.Sprintf("Exceeded configured timeout of %v.\n", timeout))
+	}
+
+	if o.Registry != nil {
+		handler = &scrapeObserver{
+			next:     handler,
+			duration: newScrapeDuration(o.Registry),
+			now:      now,
+		}
+	}
+
+	return handler
+}
@@ -0,0 +1,128 @@
+package xmetricshttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingGatherer blocks each Gather until released, so that scrapes can be held in flight
+type blockingGatherer struct {
+	gathering chan struct{}
+	release   chan struct{}
+}
+
+func newBlockingGatherer() *blockingGatherer {
+	return &blockingGatherer{
+		gathering: make(chan struct{}, 10),
+		release:   make(chan struct{}),
+	}
+}
+
+func (bg *blockingGatherer) Gather() ([]*dto.MetricFamily, error) {
+	bg.gathering <- struct{}{}
+	<-bg.release
+	return nil, nil
+}
+
+func testNewHandlerMaxRequestsInFlight(t *testing.T, openMetrics bool) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		gatherer = newBlockingGatherer()
+		handler  = NewHandler(gatherer, promhttp.HandlerOpts{MaxRequestsInFlight: 1, EnableOpenMetrics: openMetrics})
+
+		first = make(chan int, 1)
+	)
+
+	go func() {
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, newOpenMetricsRequest())
+		first <- response.Code
+	}()
+
+	select {
+	case <-gatherer.gathering:
+	case <-time.After(5 * time.Second):
+		require.Fail("The first scrape did not start")
+	}
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, newOpenMetricsRequest())
+	assert.Equal(http.StatusServiceUnavailable, response.Code)
+
+	close(gatherer.release)
+	select {
+	case code := <-first:
+		assert.Equal(http.StatusOK, code)
+	case <-time.After(5 * time.Second):
+		assert.Fail("The first scrape did not complete")
+	}
+
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, newOpenMetricsRequest())
+	assert.Equal(http.StatusOK, response.Code)
+}
+
+func testNewHandlerTimeout(t *testing.T, openMetrics bool) {
+	var (
+		assert = assert.New(t)
+
+		gatherer = newBlockingGatherer()
+		handler  = NewHandler(gatherer, promhttp.HandlerOpts{Timeout: 10 * time.Millisecond, EnableOpenMetrics: openMetrics})
+		response = httptest.NewRecorder()
+	)
+
+	defer close(gatherer.release)
+	handler.ServeHTTP(response, newOpenMetricsRequest())
+	assert.Equal(http.StatusServiceUnavailable, response.Code)
+}
+
+func testNewHandlerScrapeDuration(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		r       = prometheus.NewPedanticRegistry()
+		now     = time.Unix(1000, 0)
+		handler = newHandler(r, promhttp.HandlerOpts{Registry: r}, func() time.Time {
+			current := now
+			now = now.Add(250 * time.Millisecond)
+			return current
+		})
+	)
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(http.StatusOK, response.Code)
+
+	// a second handler shares the histogram rather than failing to register it
+	require.NotPanics(func() { NewHandler(r, promhttp.HandlerOpts{Registry: r}) })
+
+	duration := findMetric(t, r, ScrapeDurationSeconds, map[string]string{CodeLabel: "200"})
+	require.NotNil(duration)
+	assert.Equal(uint64(1), duration.GetHistogram().GetSampleCount())
+	assert.Equal(0.25, duration.GetHistogram().GetSampleSum())
+}
+
+func TestNewHandlerLimits(t *testing.T) {
+	t.Run("MaxRequestsInFlight", func(t *testing.T) {
+		t.Run("Prometheus", func(t *testing.T) { testNewHandlerMaxRequestsInFlight(t, false) })
+		t.Run("OpenMetrics", func(t *testing.T) { testNewHandlerMaxRequestsInFlight(t, true) })
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		t.Run("Prometheus", func(t *testing.T) { testNewHandlerTimeout(t, false) })
+		t.Run("OpenMetrics", func(t *testing.T) { testNewHandlerTimeout(t, true) })
+	})
+
+	t.Run("ScrapeDuration", testNewHandlerScrapeDuration)
+}
@@ -0,0 +1,79 @@
+// Package xmetricshttp provides standard HTTP instrumentation built on xmetrics.
+package xmetricshttp
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/xmidt-org/webpa-common/xmetrics"
+)
+
+const (
+	ServerRequestCount           = "server_request_count"
+	ServerRequestDurationSeconds = "server_request_duration_seconds"
+	ServerInFlightRequests       = "server_in_flight_requests"
+	ServerResponseSizeBytes      = "server_response_size_bytes"
+
+	ClientRequestCount           = "client_request_count"
+	ClientRequestDurationSeconds = "client_request_duration_seconds"
+	ClientInFlightRequests       = "client_in_flight_requests"
+
+	RouteLabel  = "route"
+	MethodLabel = "method"
+	CodeLabel   = "code"
+	TargetLabel = "target"
+	HostLabel   = "host"
+
+	// TraceIDLabel is the exemplar label that holds a request's trace ID
+	TraceIDLabel = "trace_id"
+)
+
+// Metrics is the module function for this package.  The server middleware created by NewServerConstructor
+// and the client decorator created by NewClientConstructor require that these metrics be registered.
+func Metrics() []xmetrics.Metric {
+	return []xmetrics.Metric{
+		xmetrics.Metric{
+			Name:       ServerRequestCount,
+			Type:       xmetrics.CounterType,
+			Help:       "The total number of HTTP requests served",
+			LabelNames: []string{RouteLabel, MethodLabel, CodeLabel},
+		},
+		xmetrics.Metric{
+			Name:       ServerRequestDurationSeconds,
+			Type:       xmetrics.HistogramType,
+			Help:       "The time taken to serve HTTP requests",
+			Buckets:    prometheus.DefBuckets,
+			LabelNames: []string{RouteLabel, MethodLabel, CodeLabel},
+		},
+		xmetrics.Metric{
+			Name:       ServerInFlightRequests,
+			Type:       xmetrics.GaugeType,
+			Help:       "The number of HTTP requests currently being served",
+			LabelNames: []string{RouteLabel, MethodLabel},
+		},
+		xmetrics.Metric{
+			Name:       ServerResponseSizeBytes,
+			Type:       xmetrics.HistogramType,
+			Help:       "The size of HTTP response bodies",
+			Buckets:    prometheus.ExponentialBuckets(100, 10, 6),
+			LabelNames: []string{RouteLabel, MethodLabel, CodeLabel},
+		},
+		xmetrics.Metric{
+			Name:       ClientRequestCount,
+			Type:       xmetrics.CounterType,
+			Help:       "The total number of outbound HTTP requests",
+			LabelNames: []string{TargetLabel, HostLabel, MethodLabel, CodeLabel},
+		},
+		xmetrics.Metric{
+			Name:       ClientRequestDurationSeconds,
+			Type:       xmetrics.HistogramType,
+			Help:       "The time taken for outbound HTTP requests to receive a response",
+			Buckets:    prometheus.DefBuckets,
+			LabelNames: []string{TargetLabel, HostLabel, MethodLabel, CodeLabel},
+		},
+		xmetrics.Metric{
+			Name:       ClientInFlightRequests,
+			Type:       xmetrics.GaugeType,
+			Help:       "The number of outbound HTTP requests currently awaiting a response",
+			LabelNames: []string{TargetLabel, HostLabel},
+		},
+	}
+}
@@ -0,0 +1,198 @@
+package xmetricshttp
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// labelValueEscaper escapes label values as required by the OpenMetrics text format
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+// seriesKey produces a unique key for a single time series within a gather
+func seriesKey(name string, m *dto.Metric) string {
+	var key strings.Builder
+	key.WriteString(name)
+	for _, pair := range m.GetLabel() {
+		key.WriteByte(0)
+		key.WriteString(pair.GetName())
+		key.WriteByte(0)
+		key.WriteString(pair.GetValue())
+	}
+
+	return key.String()
+}
+
+// createdName returns the name of the _created series for a metric family, or the empty string
+// if the family's type has no _created series
+func createdName(family *dto.MetricFamily) string {
+	switch family.GetType() {
+	case dto.MetricType_COUNTER:
+		// the OpenMetrics encoder only treats counters with the _total suffix as counters
+		if strings.HasSuffix(family.GetName(), "_total") {
+			return strings.TrimSuffix(family.GetName(), "_total") + "_created"
+		}
+
+	case dto.MetricType_HISTOGRAM, dto.MetricType_SUMMARY:
+		return family.GetName() + "_created"
+	}
+
+	return ""
+}
+
+// openMetricsHandler serves the OpenMetrics format, including _created series, to clients that negotiate it.
+// All other clients are served by the standard promhttp handler.
+type openMetricsHandler struct {
+	gatherer prometheus.Gatherer
+	options  promhttp.HandlerOpts
+	standard http.Handler
+	now      func() time.Time
+
+	lock    sync.Mutex
+	start   time.Time
+	created map[string]time.Time
+}
+
+// createdTimes returns the creation time of each series in the gathered families.  Series present on
+// the first gather are considered to have been created when this handler was, while series that appear
+// later are considered to have been created when first gathered.  Series that are no longer gathered are
+// forgotten, so that they start over if they reappear.
+func (omh *openMetricsHandler) createdTimes(families []*dto.MetricFamily) map[string]time.Time {
+	defer omh.lock.Unlock()
+	omh.lock.Lock()
+
+	var (
+		first   = omh.created == nil
+		now     = omh.now()
+		created = make(map[string]time.Time, len(omh.created))
+	)
+
+	for _, family := range families {
+		if len(createdName(family)) == 0 {
+			continue
+		}
+
+		for _, m := range family.GetMetric() {
+			key := seriesKey(family.GetName(), m)
+			if t, ok := omh.created[key]; ok {
+				created[key] = t
+			} else if first {
+				created[key] = omh.start
+			} else {
+				created[key] = now
+			}
+		}
+	}
+
+	omh.created = created
+	return created
+}
+
+// writeFamily writes a single metric family, with a _created sample following the samples of each series
+func writeFamily(w *bufio.Writer, family *dto.MetricFamily, created map[string]time.Time) error {
+	name := createdName(family)
+	if len(name) == 0 {
+		_, err := expfmt.MetricFamilyToOpenMetrics(w, family)
+		return err
+	}
+
+	// write the HELP and TYPE lines once, from a copy of the family without any metrics
+	header := *family
+	header.Metric = nil
+	if _, err := expfmt.MetricFamilyToOpenMetrics(w, &header); err != nil {
+		return err
+	}
+
+	var (
+		single  = *family
+		samples bytes.Buffer
+	)
+
+	for _, m := range family.GetMetric() {
+		single.Metric = []*dto.Metric{m}
+		samples.Reset()
+		if _, err := expfmt.MetricFamilyToOpenMetrics(&samples, &single); err != nil {
+			return err
+		}
+
+		// discard the HELP and TYPE lines, since they were already written
+		for _, line := range strings.SplitAfter(samples.String(), "\n") {
+			if len(line) > 0 && !strings.HasPrefix(line, "#") {
+				w.WriteString(line)
+			}
+		}
+
+		w.WriteString(name)
+		if len(m.GetLabel()) > 0 {
+			w.WriteByte('{')
+			for i, pair := range m.GetLabel() {
+				if i > 0 {
+					w.WriteByte(',')
+				}
+
+				fmt.Fprintf(w, `%s="%s"`, pair.GetName(), labelValueEscaper.Replace(pair.GetValue()))
+			}
+
+			w.WriteByte('}')
+		}
+
+		t := created[seriesKey(family.GetName(), m)]
+		w.WriteByte(' ')
+		w.WriteString(strconv.FormatFloat(float64(t.UnixNano())/1e9, 'f', 3, 64))
+		w.WriteByte('\n')
+	}
+
+	return nil
+}
+
+func (omh *openMetricsHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if expfmt.NegotiateIncludingOpenMetrics(request.Header) != expfmt.FmtOpenMetrics {
+		omh.standard.ServeHTTP(response, request)
+		return
+	}
+
+	families, err := omh.gatherer.Gather()
+	if err != nil {
+		if omh.options.ErrorLog != nil {
+			omh.options.ErrorLog.Println("error gathering metrics:", err)
+		}
+
+		if omh.options.ErrorHandling == promhttp.PanicOnError {
+			panic(err)
+		}
+
+		if omh.options.ErrorHandling != promhttp.ContinueOnError || len(families) == 0 {
+			http.Error(response, "An error has occurred while gathering metrics:\n\n"+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	var (
+		created = omh.createdTimes(families)
+		output  bytes.Buffer
+		w       = bufio.NewWriter(&output)
+	)
+
+	for _, family := range families {
+		if err := writeFamily(w, family, created); err != nil {
+			http.Error(response, "An error has occurred while encoding metrics:\n\n"+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	expfmt.FinalizeOpenMetrics(w)
+	w.Flush()
+
+	response.Header().Set("Content-Type", string(expfmt.FmtOpenMetrics))
+	response.Write(output.Bytes())
+}
@@ -0,0 +1,144 @@
+package xmetricshttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/xmetrics"
+)
+
+func newOpenMetricsRequest() *http.Request {
+	request := httptest.NewRequest("GET", "/metrics", nil)
+	request.Header.Set("Accept", expfmt.OpenMetricsType+"; version="+expfmt.OpenMetricsVersion)
+	return request
+}
+
+func testNewHandlerDisabled(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		r, err = xmetrics.NewRegistry(&xmetrics.Options{DisableGoCollector: true, DisableProcessCollector: true})
+	)
+
+	require.NoError(err)
+	r.NewCounter("requests_total").Add(1.0)
+
+	response := httptest.NewRecorder()
+	NewHandler(r, promhttp.HandlerOpts{}).ServeHTTP(response, newOpenMetricsRequest())
+	assert.Equal(http.StatusOK, response.Code)
+	assert.Equal(string(expfmt.FmtText), response.Header().Get("Content-Type"))
+	assert.NotContains(response.Body.String(), "_created")
+}
+
+func testNewHandlerPrometheusClient(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		r, err = xmetrics.NewRegistry(&xmetrics.Options{DisableGoCollector: true, DisableProcessCollector: true})
+	)
+
+	require.NoError(err)
+	r.NewCounter("requests_total").Add(1.0)
+
+	response := httptest.NewRecorder()
+	NewHandler(r, promhttp.HandlerOpts{EnableOpenMetrics: true}).ServeHTTP(response, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(http.StatusOK, response.Code)
+	assert.Equal(string(expfmt.FmtText), response.Header().Get("Content-Type"))
+	assert.Contains(response.Body.String(), "requests_total 1")
+	assert.NotContains(response.Body.String(), "_created")
+}
+
+func testNewHandlerOpenMetrics(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		r, err = xmetrics.NewRegistry(&xmetrics.Options{
+			DisableGoCollector:      true,
+			DisableProcessCollector: true,
+			Metrics: []xmetrics.Metric{
+				xmetrics.Metric{Name: "requests_total", Type: xmetrics.CounterType, Help: "The requests", LabelNames: []string{"code"}},
+				xmetrics.Metric{Name: "events", Type: xmetrics.CounterType},
+				xmetrics.Metric{Name: "ratio", Type: xmetrics.GaugeType},
+				xmetrics.Metric{Name: "latency", Type: xmetrics.HistogramType, Buckets: []float64{1.0}},
+			},
+		})
+
+		now     = time.Unix(1000, 0)
+		clock   = func() time.Time { return now }
+		handler http.Handler
+	)
+
+	require.NoError(err)
+	handler = newHandler(r, promhttp.HandlerOpts{EnableOpenMetrics: true}, clock)
+
+	r.NewCounter("requests_total").With("code", "200").Add(2.0)
+	r.NewCounter("events").Add(1.0)
+	r.NewGauge("ratio").Set(0.5)
+	r.NewHistogram("latency", 0).Observe(0.5)
+
+	now = time.Unix(2000, 0)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, newOpenMetricsRequest())
+	assert.Equal(http.StatusOK, response.Code)
+	assert.Equal(string(expfmt.FmtOpenMetrics), response.Header().Get("Content-Type"))
+
+	body := response.Body.String()
+	assert.Contains(body, "# HELP test_test_requests The requests\n# TYPE test_test_requests counter\ntest_test_requests_total{code=\"200\"} 2.0\ntest_test_requests_created{code=\"200\"} 1000.000\n")
+	assert.Contains(body, "test_test_latency_count 1\ntest_test_latency_created 1000.000\n")
+	assert.Contains(body, "test_test_events 1.0\n")
+	assert.NotContains(body, "events_created")
+	assert.NotContains(body, "ratio_created")
+	assert.Equal(1, strings.Count(body, "# TYPE test_test_requests counter"))
+	assert.True(strings.HasSuffix(body, "# EOF\n"))
+
+	// a series which appears after the first scrape is created when first scraped
+	r.NewCounter("requests_total").With("code", "500").Add(1.0)
+	now = time.Unix(3000, 0)
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, newOpenMetricsRequest())
+	assert.Equal(http.StatusOK, response.Code)
+
+	body = response.Body.String()
+	assert.Contains(body, "test_test_requests_total{code=\"200\"} 2.0\ntest_test_requests_created{code=\"200\"} 1000.000\n")
+	assert.Contains(body, "test_test_requests_total{code=\"500\"} 1.0\ntest_test_requests_created{code=\"500\"} 3000.000\n")
+	assert.Equal(1, strings.Count(body, "# TYPE test_test_requests counter"))
+}
+
+func testNewHandlerGatherError(t *testing.T) {
+	var (
+		assert = assert.New(t)
+
+		gatherer = prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+			return nil, errors.New("expected")
+		})
+	)
+
+	response := httptest.NewRecorder()
+	NewHandler(gatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}).ServeHTTP(response, newOpenMetricsRequest())
+	assert.Equal(http.StatusInternalServerError, response.Code)
+
+	assert.Panics(func() {
+		NewHandler(gatherer, promhttp.HandlerOpts{EnableOpenMetrics: true, ErrorHandling: promhttp.PanicOnError}).
+			ServeHTTP(httptest.NewRecorder(), newOpenMetricsRequest())
+	})
+}
+
+func TestNewHandler(t *testing.T) {
+	t.Run("Disabled", testNewHandlerDisabled)
+	t.Run("PrometheusClient", testNewHandlerPrometheusClient)
+	t.Run("OpenMetrics", testNewHandlerOpenMetrics)
+	t.Run("GatherError", testNewHandlerGatherError)
+}
@@ -0,0 +1,169 @@
+package xmetricshttp
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/xmidt-org/webpa-common/xmetrics"
+)
+
+// UnknownRoute is the route label used when a request's route cannot be determined
+const UnknownRoute = "unknown"
+
+// RouteFunc produces the route label for a request.  The route must have low cardinality,
+// so it should be a path template rather than the request's actual path.
+type RouteFunc func(*http.Request) string
+
+// TraceIDFunc produces the trace ID for a request.  An empty trace ID means the request is not traced.
+type TraceIDFunc func(*http.Request) string
+
+// MuxRoute is the default RouteFunc.  It uses the path template of the gorilla/mux route that matched the request,
+// or UnknownRoute if the request was not routed by gorilla/mux.  Note that the route is only available
+// when the server middleware decorates handlers inside the router, e.g. via Router.Use.
+func MuxRoute(request *http.Request) string {
+	if route := mux.CurrentRoute(request); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+
+	return UnknownRoute
+}
+
+// ServerOptions holds the configurable options for the server middleware
+type ServerOptions struct {
+	// Route produces the route label for each request.  If unset, MuxRoute is used.
+	Route RouteFunc
+
+	// TraceID is the optional source of trace IDs for requests.  If set, the duration and response size observations
+	// of traced requests carry an exemplar with the TraceIDLabel.  Exemplars are only exposed by the Prometheus
+	// handler when OpenMetrics is enabled, e.g. via promhttp.HandlerOpts.EnableOpenMetrics.
+	TraceID TraceIDFunc
+}
+
+func (o ServerOptions) route() RouteFunc {
+	if o.Route != nil {
+		return o.Route
+	}
+
+	return MuxRoute
+}
+
+// serverHandler is the decorator that instruments a handler
+type serverHandler struct {
+	next     http.Handler
+	route    RouteFunc
+	traceID  TraceIDFunc
+	count    *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight *prometheus.GaugeVec
+	size     *prometheus.HistogramVec
+}
+
+func (sh *serverHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	var (
+		start  = time.Now()
+		route  = sh.route(request)
+		method = request.Method
+		writer = &recordingWriter{ResponseWriter: response, code: http.StatusOK}
+	)
+
+	inFlight := sh.inFlight.WithLabelValues(route, method)
+	inFlight.Inc()
+	defer inFlight.Dec()
+
+	sh.next.ServeHTTP(writer, request)
+
+	code := strconv.Itoa(writer.code)
+	sh.count.WithLabelValues(route, method, code).Inc()
+
+	var exemplar prometheus.Labels
+	if sh.traceID != nil {
+		if id := sh.traceID(request); len(id) > 0 {
+			exemplar = prometheus.Labels{TraceIDLabel: id}
+		}
+	}
+
+	observe(sh.duration.WithLabelValues(route, method, code), time.Since(start).Seconds(), exemplar)
+	observe(sh.size.WithLabelValues(route, method, code), float64(writer.size), exemplar)
+}
+
+// observe records a value, with an exemplar if one is supplied
+func observe(o prometheus.Observer, value float64, exemplar prometheus.Labels) {
+	if eo, ok := o.(prometheus.ExemplarObserver); ok && exemplar != nil {
+		eo.ObserveWithExemplar(value, exemplar)
+		return
+	}
+
+	o.Observe(value)
+}
+
+// NewServerConstructor returns an Alice-style constructor that records the request count, request duration,
+// in-flight requests, and response size of the handlers it decorates, labeled by route, method, and code.
+// The given provider must have the metrics from this package's Metrics module.
+func NewServerConstructor(p xmetrics.PrometheusProvider, o ServerOptions) func(http.Handler) http.Handler {
+	var (
+		route    = o.route()
+		count    = p.NewCounterVec(ServerRequestCount)
+		duration = p.NewHistogramVec(ServerRequestDurationSeconds)
+		inFlight = p.NewGaugeVec(ServerInFlightRequests)
+		size     = p.NewHistogramVec(ServerResponseSizeBytes)
+	)
+
+	return func(next http.Handler) http.Handler {
+		return &serverHandler{
+			next:     next,
+			route:    route,
+			traceID:  o.TraceID,
+			count:    count,
+			duration: duration,
+			inFlight: inFlight,
+			size:     size,
+		}
+	}
+}
+
+// recordingWriter records the status code and body size of a response
+type recordingWriter struct {
+	http.ResponseWriter
+	code        int
+	size        int
+	wroteHeader bool
+}
+
+func (rw *recordingWriter) WriteHeader(code int) {
+	if !rw.wroteHeader {
+		rw.code = code
+		rw.wroteHeader = true
+	}
+
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	n, err := rw.ResponseWriter.Write(b)
+	rw.size += n
+	return n, err
+}
+
+func (rw *recordingWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		rw.wroteHeader = true
+		f.Flush()
+	}
+}
+
+func (rw *recordingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := rw.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+
+	return nil, nil, errors.New("The underlying http.ResponseWriter does not support hijacking")
+}
@@ -0,0 +1,176 @@
+package xmetricshttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/xmetrics"
+)
+
+func newTestRegistry(t *testing.T) xmetrics.Registry {
+	r, err := xmetrics.NewRegistry(
+		&xmetrics.Options{DisableGoCollector: true, DisableProcessCollector: true},
+		Metrics,
+	)
+
+	require.NoError(t, err)
+	return r
+}
+
+// findMetric returns the metric in the given family with the given label values, or nil if no such metric exists
+func findMetric(t *testing.T, r prometheus.Gatherer, name string, labels map[string]string) *dto.Metric {
+	families, err := r.Gather()
+	require.NoError(t, err)
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+
+	metrics:
+		for _, m := range family.GetMetric() {
+			for _, pair := range m.GetLabel() {
+				if labels[pair.GetName()] != pair.GetValue() {
+					continue metrics
+				}
+			}
+
+			return m
+		}
+	}
+
+	return nil
+}
+
+func TestMuxRoute(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		router = mux.NewRouter()
+		routes []string
+	)
+
+	router.HandleFunc("/devices/{id}", func(_ http.ResponseWriter, request *http.Request) {
+		routes = append(routes, MuxRoute(request))
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/devices/123", nil))
+	assert.Equal([]string{"/devices/{id}"}, routes)
+	assert.Equal(UnknownRoute, MuxRoute(httptest.NewRequest("GET", "/devices/123", nil)))
+}
+
+func testNewServerConstructorMux(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		r       = newTestRegistry(t)
+		router  = mux.NewRouter()
+		labels  = map[string]string{RouteLabel: "/devices/{id}", MethodLabel: "POST", CodeLabel: "202"}
+	)
+
+	router.Use(NewServerConstructor(r, ServerOptions{}))
+	router.HandleFunc("/devices/{id}", func(response http.ResponseWriter, _ *http.Request) {
+		m := findMetric(t, r, "test_test_server_in_flight_requests", map[string]string{RouteLabel: "/devices/{id}", MethodLabel: "POST"})
+		require.NotNil(m)
+		assert.Equal(1.0, m.GetGauge().GetValue())
+
+		response.WriteHeader(http.StatusAccepted)
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte("hello, world"))
+	})
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest("POST", "/devices/123", nil))
+	assert.Equal(http.StatusAccepted, response.Code)
+
+	count := findMetric(t, r, "test_test_server_request_count", labels)
+	require.NotNil(count)
+	assert.Equal(1.0, count.GetCounter().GetValue())
+
+	duration := findMetric(t, r, "test_test_server_request_duration_seconds", labels)
+	require.NotNil(duration)
+	assert.Equal(uint64(1), duration.GetHistogram().GetSampleCount())
+
+	size := findMetric(t, r, "test_test_server_response_size_bytes", labels)
+	require.NotNil(size)
+	assert.Equal(float64(len("hello, world")), size.GetHistogram().GetSampleSum())
+
+	inFlight := findMetric(t, r, "test_test_server_in_flight_requests", map[string]string{RouteLabel: "/devices/{id}", MethodLabel: "POST"})
+	require.NotNil(inFlight)
+	assert.Zero(inFlight.GetGauge().GetValue())
+}
+
+func testNewServerConstructorExemplars(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+		r       = newTestRegistry(t)
+
+		handler = NewServerConstructor(r, ServerOptions{
+			Route: func(*http.Request) string { return "custom" },
+			TraceID: func(request *http.Request) string {
+				return request.Header.Get("X-Trace-Id")
+			},
+		})(http.HandlerFunc(func(response http.ResponseWriter, _ *http.Request) {
+			response.Write([]byte("traced"))
+		}))
+	)
+
+	request := httptest.NewRequest("GET", "/", nil)
+	request.Header.Set("X-Trace-Id", "abc123")
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+
+	duration := findMetric(t, r, "test_test_server_request_duration_seconds", map[string]string{RouteLabel: "custom", MethodLabel: "GET", CodeLabel: "200"})
+	require.NotNil(duration)
+
+	var exemplars []*dto.Exemplar
+	for _, b := range duration.GetHistogram().GetBucket() {
+		if b.GetExemplar() != nil {
+			exemplars = append(exemplars, b.GetExemplar())
+		}
+	}
+
+	require.Len(exemplars, 1)
+	require.Len(exemplars[0].GetLabel(), 1)
+	assert.Equal(TraceIDLabel, exemplars[0].GetLabel()[0].GetName())
+	assert.Equal("abc123", exemplars[0].GetLabel()[0].GetValue())
+
+	// untraced requests carry no exemplar
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	size := findMetric(t, r, "test_test_server_response_size_bytes", map[string]string{RouteLabel: "custom", MethodLabel: "GET", CodeLabel: "200"})
+	require.NotNil(size)
+	assert.Equal(uint64(2), size.GetHistogram().GetSampleCount())
+}
+
+func testNewServerConstructorFlushHijack(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		r      = newTestRegistry(t)
+
+		handler = NewServerConstructor(r, ServerOptions{})(http.HandlerFunc(func(response http.ResponseWriter, _ *http.Request) {
+			response.(http.Flusher).Flush()
+			_, _, err := response.(http.Hijacker).Hijack()
+			assert.Error(err)
+		}))
+	)
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, httptest.NewRequest("GET", "/", nil))
+	assert.True(response.Flushed)
+
+	count := findMetric(t, r, "test_test_server_request_count", map[string]string{RouteLabel: UnknownRoute, MethodLabel: "GET", CodeLabel: "200"})
+	if assert.NotNil(count) {
+		assert.Equal(1.0, count.GetCounter().GetValue())
+	}
+}
+
+func TestNewServerConstructor(t *testing.T) {
+	t.Run("Mux", testNewServerConstructorMux)
+	t.Run("Exemplars", testNewServerConstructorExemplars)
+	t.Run("FlushHijack", testNewServerConstructorFlushHijack)
+}
@@ -0,0 +1,148 @@
+package xmetricshttp
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// SnapshotFamily is the JSON representation of a single metric family
+type SnapshotFamily struct {
+	Name    string           `json:"name"`
+	Help    string           `json:"help,omitempty"`
+	Type    string           `json:"type"`
+	Metrics []SnapshotMetric `json:"metrics"`
+}
+
+// SnapshotMetric is the JSON representation of a single time series.  Values that JSON cannot represent,
+// such as NaN or infinity, are rendered as null.
+type SnapshotMetric struct {
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Value is the current value of a counter, gauge, or untyped metric
+	Value *float64 `json:"value,omitempty"`
+
+	// Count is the number of observations of a histogram or summary
+	Count *uint64 `json:"count,omitempty"`
+
+	// Sum is the sum of observations of a histogram or summary
+	Sum *float64 `json:"sum,omitempty"`
+
+	// Buckets are the cumulative counts of a histogram, keyed by upper bound
+	Buckets map[string]uint64 `json:"buckets,omitempty"`
+
+	// Quantiles are the quantiles of a summary, keyed by quantile
+	Quantiles map[string]*float64 `json:"quantiles,omitempty"`
+}
+
+// finite returns a pointer to v, or nil if v cannot be represented in JSON
+func finite(v float64) *float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+
+	return &v
+}
+
+func formatBound(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// newSnapshotMetric converts a Prometheus metric into its JSON representation
+func newSnapshotMetric(t dto.MetricType, m *dto.Metric) SnapshotMetric {
+	var sm SnapshotMetric
+	if len(m.GetLabel()) > 0 {
+		sm.Labels = make(map[string]string, len(m.GetLabel()))
+		for _, pair := range m.GetLabel() {
+			sm.Labels[pair.GetName()] = pair.GetValue()
+		}
+	}
+
+	switch t {
+	case dto.MetricType_COUNTER:
+		sm.Value = finite(m.GetCounter().GetValue())
+
+	case dto.MetricType_GAUGE:
+		sm.Value = finite(m.GetGauge().GetValue())
+
+	case dto.MetricType_UNTYPED:
+		sm.Value = finite(m.GetUntyped().GetValue())
+
+	case dto.MetricType_HISTOGRAM:
+		h := m.GetHistogram()
+		count := h.GetSampleCount()
+		sm.Count = &count
+		sm.Sum = finite(h.GetSampleSum())
+		sm.Buckets = make(map[string]uint64, len(h.GetBucket()))
+		for _, b := range h.GetBucket() {
+			sm.Buckets[formatBound(b.GetUpperBound())] = b.GetCumulativeCount()
+		}
+
+	case dto.MetricType_SUMMARY:
+		s := m.GetSummary()
+		count := s.GetSampleCount()
+		sm.Count = &count
+		sm.Sum = finite(s.GetSampleSum())
+		sm.Quantiles = make(map[string]*float64, len(s.GetQuantile()))
+		for _, q := range s.GetQuantile() {
+			sm.Quantiles[formatBound(q.GetQuantile())] = finite(q.GetValue())
+		}
+	}
+
+	return sm
+}
+
+// snapshotHandler renders the gathered metrics as JSON
+type snapshotHandler struct {
+	gatherer prometheus.Gatherer
+}
+
+func (sh snapshotHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	families, err := sh.gatherer.Gather()
+	if err != nil {
+		http.Error(response, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var (
+		prefix   = request.URL.Query().Get("name")
+		snapshot = make([]SnapshotFamily, 0, len(families))
+	)
+
+	for _, family := range families {
+		if !strings.HasPrefix(family.GetName(), prefix) {
+			continue
+		}
+
+		sf := SnapshotFamily{
+			Name:    family.GetName(),
+			Help:    family.GetHelp(),
+			Type:    strings.ToLower(family.GetType().String()),
+			Metrics: make([]SnapshotMetric, 0, len(family.GetMetric())),
+		}
+
+		for _, m := range family.GetMetric() {
+			sf.Metrics = append(sf.Metrics, newSnapshotMetric(family.GetType(), m))
+		}
+
+		snapshot = append(snapshot, sf)
+	}
+
+	response.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(response)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(snapshot)
+}
+
+// NewSnapshotHandler creates a handler that renders the current metrics from a gatherer, such as an xmetrics.Registry,
+// as a JSON array of SnapshotFamily objects.  This format is intended for quick inspection by humans and for
+// integration tests, rather than for scraping.  The optional "name" query parameter restricts the output to metrics
+// whose names begin with its value.
+func NewSnapshotHandler(g prometheus.Gatherer) http.Handler {
+	return snapshotHandler{gatherer: g}
+}
@@ -0,0 +1,130 @@
+package xmetricshttp
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/xmetrics"
+)
+
+func testSnapshotHandlerSuccess(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		r, err = xmetrics.NewRegistry(&xmetrics.Options{
+			DisableGoCollector:      true,
+			DisableProcessCollector: true,
+			Metrics: []xmetrics.Metric{
+				xmetrics.Metric{Name: "requests", Type: xmetrics.CounterType, Help: "The requests", LabelNames: []string{"code"}},
+				xmetrics.Metric{Name: "ratio", Type: xmetrics.GaugeType},
+				xmetrics.Metric{Name: "latency", Type: xmetrics.HistogramType, Buckets: []float64{0.5, 1.0}},
+				xmetrics.Metric{Name: "size", Type: xmetrics.SummaryType, Objectives: map[float64]float64{0.5: 0.05}},
+			},
+		})
+	)
+
+	require.NoError(err)
+	r.NewCounter("requests").With("code", "200").Add(2.0)
+	r.NewGauge("ratio").Set(math.NaN())
+	r.NewHistogram("latency", 0).Observe(0.75)
+	r.NewSummaryVec("size").WithLabelValues()
+
+	response := httptest.NewRecorder()
+	NewSnapshotHandler(r).ServeHTTP(response, httptest.NewRequest("GET", "/metrics.json", nil))
+	assert.Equal(http.StatusOK, response.Code)
+	assert.Equal("application/json", response.Header().Get("Content-Type"))
+
+	var snapshot []SnapshotFamily
+	require.NoError(json.Unmarshal(response.Body.Bytes(), &snapshot))
+	require.Len(snapshot, 4)
+
+	var (
+		one      = uint64(1)
+		zero     = uint64(0)
+		two      = 2.0
+		sum      = 0.75
+		zeroSum  = 0.0
+		families = make(map[string]SnapshotFamily)
+	)
+
+	for _, sf := range snapshot {
+		families[sf.Name] = sf
+	}
+
+	assert.Equal(
+		SnapshotFamily{
+			Name:    "test_test_requests",
+			Help:    "The requests",
+			Type:    "counter",
+			Metrics: []SnapshotMetric{{Labels: map[string]string{"code": "200"}, Value: &two}},
+		},
+		families["test_test_requests"],
+	)
+
+	assert.Equal(
+		SnapshotFamily{
+			Name:    "test_test_ratio",
+			Help:    "ratio",
+			Type:    "gauge",
+			Metrics: []SnapshotMetric{{}},
+		},
+		families["test_test_ratio"],
+	)
+
+	assert.Equal(
+		SnapshotFamily{
+			Name:    "test_test_latency",
+			Help:    "latency",
+			Type:    "histogram",
+			Metrics: []SnapshotMetric{{Count: &one, Sum: &sum, Buckets: map[string]uint64{"0.5": 0, "1": 1}}},
+		},
+		families["test_test_latency"],
+	)
+
+	assert.Equal(
+		SnapshotFamily{
+			Name:    "test_test_size",
+			Help:    "size",
+			Type:    "summary",
+			Metrics: []SnapshotMetric{{Count: &zero, Sum: &zeroSum, Quantiles: map[string]*float64{"0.5": nil}}},
+		},
+		families["test_test_size"],
+	)
+
+	response = httptest.NewRecorder()
+	NewSnapshotHandler(r).ServeHTTP(response, httptest.NewRequest("GET", "/metrics.json?name=test_test_r", nil))
+	assert.Equal(http.StatusOK, response.Code)
+
+	snapshot = nil
+	require.NoError(json.Unmarshal(response.Body.Bytes(), &snapshot))
+	require.Len(snapshot, 2)
+	assert.Equal("test_test_ratio", snapshot[0].Name)
+	assert.Equal("test_test_requests", snapshot[1].Name)
+}
+
+func testSnapshotHandlerError(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		g      = prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+			return nil, errors.New("expected")
+		})
+	)
+
+	response := httptest.NewRecorder()
+	NewSnapshotHandler(g).ServeHTTP(response, httptest.NewRequest("GET", "/metrics.json", nil))
+	assert.Equal(http.StatusInternalServerError, response.Code)
+}
+
+func TestSnapshotHandler(t *testing.T) {
+	t.Run("Success", testSnapshotHandlerSuccess)
+	t.Run("Error", testSnapshotHandlerError)
+}
@@ -0,0 +1,183 @@
+package xmetricsotel
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/xmetrics"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// InstrumentationName is the name of the OpenTelemetry Meter used to mirror xmetrics
+const InstrumentationName = "github.com/xmidt-org/webpa-common/xmetrics"
+
+const (
+	// CountSuffix is appended to the name of a histogram or summary to produce the name of its observation count
+	CountSuffix = "_count"
+
+	// SumSuffix is appended to the name of a histogram or summary to produce the name of its observation sum
+	SumSuffix = "_sum"
+
+	// BucketSuffix is appended to the name of a histogram to produce the name of its cumulative bucket counts
+	BucketSuffix = "_bucket"
+
+	// BucketLabel is the label holding the upper bound of each histogram bucket
+	BucketLabel = "le"
+)
+
+// series is the most recently mirrored value of one set of label values
+type series struct {
+	labels []attribute.KeyValue
+	value  float64
+}
+
+// instrument is a single OpenTelemetry asynchronous instrument along with the series it reports.
+// An instrument which could not be created has a nil series map.
+type instrument struct {
+	series map[string]series
+}
+
+// Mirror is an xmetrics.Mirror which feeds an OpenTelemetry MeterProvider.  Assign a Mirror to
+// xmetrics.Options.Mirror so that the metrics in the Registry are exported through OpenTelemetry
+// without changing any code that creates or updates metrics.
+//
+// Counters are mirrored as Float64SumObservers and gauges as Float64ValueObservers.  The OpenTelemetry
+// metric API has no instrument for a pre-aggregated histogram, so histograms and summaries are mirrored in
+// the same form as the Prometheus exposition format:  Float64SumObservers with the CountSuffix, SumSuffix,
+// and, for histograms, BucketSuffix appended to the metric name.  Each bucket is labeled with its upper bound.
+//
+// Instruments report the values copied on the most recent mirror interval.  A series which is no longer
+// mirrored, such as an expired series, continues to report its last value.
+type Mirror struct {
+	meter    metric.Meter
+	errorLog log.Logger
+
+	lock        sync.Mutex
+	instruments map[string]*instrument
+}
+
+// NewMirror creates a Mirror which creates its instruments with a Meter from the given MeterProvider.
+// Errors creating instruments are written to the logger.  If the logger is nil, logging.DefaultLogger() is used.
+func NewMirror(mp metric.MeterProvider, logger log.Logger) *Mirror {
+	if logger == nil {
+		logger = logging.DefaultLogger()
+	}
+
+	return &Mirror{
+		meter:       mp.Meter(InstrumentationName),
+		errorLog:    log.WithPrefix(logger, level.Key(), level.ErrorValue()),
+		instruments: make(map[string]*instrument),
+	}
+}
+
+var _ xmetrics.Mirror = (*Mirror)(nil)
+
+// signature produces a unique key for a set of label values, along with the equivalent attributes
+func signature(labels map[string]string) (string, []attribute.KeyValue) {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var (
+		key        strings.Builder
+		attributes = make([]attribute.KeyValue, 0, len(names))
+	)
+
+	for _, name := range names {
+		key.WriteString(name)
+		key.WriteByte(0)
+		key.WriteString(labels[name])
+		key.WriteByte(0)
+		attributes = append(attributes, attribute.String(name, labels[name]))
+	}
+
+	return key.String(), attributes
+}
+
+// observe records the value of a series, creating the named instrument on first use
+func (m *Mirror) observe(name string, sum bool, labels map[string]string, value float64) {
+	key, attributes := signature(labels)
+
+	defer m.lock.Unlock()
+	m.lock.Lock()
+
+	i, ok := m.instruments[name]
+	if !ok {
+		i = m.newInstrument(name, sum)
+		m.instruments[name] = i
+	}
+
+	if i.series != nil {
+		i.series[key] = series{labels: attributes, value: value}
+	}
+}
+
+// newInstrument creates the OpenTelemetry instrument for a metric.  This method must be called under the lock.
+func (m *Mirror) newInstrument(name string, sum bool) *instrument {
+	var (
+		i        = &instrument{series: make(map[string]series)}
+		callback = func(_ context.Context, result metric.Float64ObserverResult) {
+			m.lock.Lock()
+			observed := make([]series, 0, len(i.series))
+			for _, s := range i.series {
+				observed = append(observed, s)
+			}
+
+			m.lock.Unlock()
+			for _, s := range observed {
+				result.Observe(s.value, s.labels...)
+			}
+		}
+
+		err error
+	)
+
+	if sum {
+		_, err = m.meter.NewFloat64SumObserver(name, callback)
+	} else {
+		_, err = m.meter.NewFloat64ValueObserver(name, callback)
+	}
+
+	if err != nil {
+		m.errorLog.Log(logging.MessageKey(), "unable to create OpenTelemetry instrument", "name", name, logging.ErrorKey(), err)
+		return new(instrument)
+	}
+
+	return i
+}
+
+// MirrorCounter records the value of a counter series
+func (m *Mirror) MirrorCounter(name string, labels map[string]string, value float64) {
+	m.observe(name, true, labels, value)
+}
+
+// MirrorGauge records the value of a gauge series
+func (m *Mirror) MirrorGauge(name string, labels map[string]string, value float64) {
+	m.observe(name, false, labels, value)
+}
+
+// MirrorHistogram records the count, sum, and buckets of a histogram or summary series
+func (m *Mirror) MirrorHistogram(name string, labels map[string]string, snapshot xmetrics.HistogramSnapshot) {
+	m.observe(name+CountSuffix, true, labels, float64(snapshot.Count))
+	m.observe(name+SumSuffix, true, labels, snapshot.Sum)
+
+	for _, b := range snapshot.Buckets {
+		bucketLabels := make(map[string]string, len(labels)+1)
+		for k, v := range labels {
+			bucketLabels[k] = v
+		}
+
+		bucketLabels[BucketLabel] = strconv.FormatFloat(b.UpperBound, 'g', -1, 64)
+		m.observe(name+BucketSuffix, true, bucketLabels, float64(b.Count))
+	}
+}
@@ -0,0 +1,188 @@
+package xmetricsotel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/logging"
+	"github.com/xmidt-org/webpa-common/xmetrics"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/oteltest"
+)
+
+type measurement struct {
+	labels map[attribute.Key]attribute.Value
+	value  float64
+}
+
+// collect runs the asynchronous instruments and returns the observations, keyed by instrument name
+func collect(impl *oteltest.MeterImpl) map[string][]measurement {
+	impl.MeasurementBatches = nil
+	impl.RunAsyncInstruments()
+
+	measurements := make(map[string][]measurement)
+	for _, m := range oteltest.AsStructs(impl.MeasurementBatches) {
+		measurements[m.Name] = append(measurements[m.Name], measurement{labels: m.Labels, value: m.Number.AsFloat64()})
+	}
+
+	return measurements
+}
+
+func labels(kvs ...string) map[attribute.Key]attribute.Value {
+	m := make(map[attribute.Key]attribute.Value, len(kvs)/2)
+	for i := 0; i < len(kvs); i += 2 {
+		m[attribute.Key(kvs[i])] = attribute.StringValue(kvs[i+1])
+	}
+
+	return m
+}
+
+func testMirrorCounter(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		impl, mp = oteltest.NewMeterProvider()
+		m        = NewMirror(mp, logging.NewTestLogger(nil, t))
+	)
+
+	m.MirrorCounter("requests", map[string]string{"code": "200"}, 3.0)
+	m.MirrorCounter("requests", map[string]string{"code": "500"}, 1.0)
+	assert.ElementsMatch(
+		[]measurement{
+			{labels: labels("code", "200"), value: 3.0},
+			{labels: labels("code", "500"), value: 1.0},
+		},
+		collect(impl)["requests"],
+	)
+
+	m.MirrorCounter("requests", map[string]string{"code": "200"}, 5.0)
+	assert.ElementsMatch(
+		[]measurement{
+			{labels: labels("code", "200"), value: 5.0},
+			{labels: labels("code", "500"), value: 1.0},
+		},
+		collect(impl)["requests"],
+	)
+
+	for _, m := range impl.MeasurementBatches {
+		for _, o := range m.Measurements {
+			assert.Equal(InstrumentationName, o.Instrument.Descriptor().InstrumentationName())
+		}
+	}
+}
+
+func testMirrorGauge(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		impl, mp = oteltest.NewMeterProvider()
+		m        = NewMirror(mp, nil)
+	)
+
+	m.MirrorGauge("connections", map[string]string{}, 12.5)
+	assert.Equal(
+		[]measurement{{labels: labels(), value: 12.5}},
+		collect(impl)["connections"],
+	)
+
+	m.MirrorGauge("connections", nil, 7.0)
+	assert.Equal(
+		[]measurement{{labels: labels(), value: 7.0}},
+		collect(impl)["connections"],
+	)
+}
+
+func testMirrorHistogram(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		impl, mp = oteltest.NewMeterProvider()
+		m        = NewMirror(mp, logging.NewTestLogger(nil, t))
+	)
+
+	m.MirrorHistogram(
+		"latency",
+		map[string]string{"event": "connect"},
+		xmetrics.HistogramSnapshot{
+			Count:   3,
+			Sum:     4.5,
+			Buckets: []xmetrics.Bucket{{UpperBound: 0.5, Count: 1}, {UpperBound: 2.0, Count: 3}},
+		},
+	)
+
+	m.MirrorHistogram("summary", nil, xmetrics.HistogramSnapshot{Count: 1, Sum: 4.0})
+
+	measurements := collect(impl)
+	assert.Equal([]measurement{{labels: labels("event", "connect"), value: 3.0}}, measurements["latency_count"])
+	assert.Equal([]measurement{{labels: labels("event", "connect"), value: 4.5}}, measurements["latency_sum"])
+	assert.ElementsMatch(
+		[]measurement{
+			{labels: labels("event", "connect", "le", "0.5"), value: 1.0},
+			{labels: labels("event", "connect", "le", "2"), value: 3.0},
+		},
+		measurements["latency_bucket"],
+	)
+
+	assert.Equal([]measurement{{labels: labels(), value: 1.0}}, measurements["summary_count"])
+	assert.Equal([]measurement{{labels: labels(), value: 4.0}}, measurements["summary_sum"])
+	assert.NotContains(measurements, "summary_bucket")
+}
+
+func testMirrorInstrumentError(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		impl, mp = oteltest.NewMeterProvider()
+		gauges   = NewMirror(mp, logging.NewTestLogger(nil, t))
+		counters = NewMirror(mp, logging.NewTestLogger(nil, t))
+	)
+
+	gauges.MirrorGauge("conflict", nil, 1.0)
+
+	// the MeterProvider rejects a second instrument of a different kind with the same name
+	counters.MirrorCounter("conflict", nil, 2.0)
+	counters.MirrorCounter("conflict", nil, 3.0)
+	assert.Equal(
+		[]measurement{{labels: labels(), value: 1.0}},
+		collect(impl)["conflict"],
+	)
+}
+
+func TestMirror(t *testing.T) {
+	t.Run("Counter", testMirrorCounter)
+	t.Run("Gauge", testMirrorGauge)
+	t.Run("Histogram", testMirrorHistogram)
+	t.Run("InstrumentError", testMirrorInstrumentError)
+}
+
+func TestRegistryMirror(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		require  = require.New(t)
+		impl, mp = oteltest.NewMeterProvider()
+
+		r, err = xmetrics.NewRegistry(&xmetrics.Options{
+			DisableGoCollector:      true,
+			DisableProcessCollector: true,
+			Metrics: []xmetrics.Metric{
+				{Name: "requests", Type: "counter", LabelNames: []string{"code"}},
+			},
+			Mirror:         NewMirror(mp, logging.NewTestLogger(nil, t)),
+			MirrorInterval: 10 * time.Millisecond,
+		})
+	)
+
+	require.NoError(err)
+	defer r.Stop()
+
+	r.NewCounter("requests").With("code", "200").Add(2.0)
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if measurements := collect(impl)["test_test_requests"]; len(measurements) > 0 {
+			assert.Equal([]measurement{{labels: labels("code", "200"), value: 2.0}}, measurements)
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.Fail("The registry did not mirror its metrics to OpenTelemetry")
+}
@@ -0,0 +1,45 @@
+package xmetricstest
+
+import "github.com/xmidt-org/webpa-common/xmetrics"
+
+// Checkpoint is a snapshot of the values of every counter and gauge in a Provider.  Use a Checkpoint
+// to assert how much a metric changed, rather than its absolute value:
+//
+//	checkpoint := provider.Checkpoint()
+//	// run the code under test
+//	provider.Assert(t, "requests", "code", "200")(xmetricstest.Counter, checkpoint.Delta(1.0))
+type Checkpoint map[interface{}]float64
+
+// baseline returns the value of a metric at the time of this checkpoint.  Metrics that did not exist
+// when the checkpoint was taken have a baseline of zero.
+func (cp Checkpoint) baseline(m interface{}) float64 {
+	if a, ok := m.(aggregate); ok {
+		var sum float64
+		for _, part := range a.parts() {
+			sum += cp[part]
+		}
+
+		return sum
+	}
+
+	return cp[m]
+}
+
+// Delta returns an expectation that a metric's value changed by exactly the expected amount since this checkpoint.
+// As with Value, the metric must implement xmetrics.Valuer.
+func (cp Checkpoint) Delta(expected float64) expectation {
+	return func(t testingT, n string, m interface{}) bool {
+		v, ok := m.(xmetrics.Valuer)
+		if !ok {
+			t.Errorf("metric %s does not expose a value (i.e. is not a counter or gauge)", n)
+			return false
+		}
+
+		if actual := v.Value() - cp.baseline(m); actual != expected {
+			t.Errorf("metric %s did not change by the expected amount %f.  actual change is %f", n, expected, actual)
+			return false
+		}
+
+		return true
+	}
+}
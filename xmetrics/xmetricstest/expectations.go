@@ -10,6 +10,11 @@ type testingT interface {
 	Errorf(string, ...interface{})
 }
 
+// discardT is a testingT that ignores all errors
+type discardT struct{}
+
+func (discardT) Errorf(string, ...interface{}) {}
+
 // expectation is a metric expectation.  The metric will implement one of the go-kit metrics interfaces, e.g. Counter.
 type expectation func(t testingT, name string, metric interface{}) bool
 
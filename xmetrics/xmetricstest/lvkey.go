@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"sort"
+	"strings"
 )
 
 const (
@@ -27,6 +28,32 @@ func (lvk LVKey) Root() bool {
 	return len(lvk) == 0
 }
 
+// pairs returns the label/value pairs in this key, each in label=value form
+func (lvk LVKey) pairs() []string {
+	if lvk.Root() {
+		return nil
+	}
+
+	return strings.Split(string(lvk), string(lvPairSeparator))
+}
+
+// Matches tests if this key has every label/value pair in the given subset.  Every key
+// matches the root key.
+func (lvk LVKey) Matches(subset LVKey) bool {
+	pairs := make(map[string]bool)
+	for _, pair := range lvk.pairs() {
+		pairs[pair] = true
+	}
+
+	for _, pair := range subset.pairs() {
+		if !pairs[pair] {
+			return false
+		}
+	}
+
+	return true
+}
+
 // NewLVKey produces a consistent, unique key for a set of label/value pairs.
 // For example, {"code", "200", "method", "POST"} will result in the same key
 // as {"method", "POST", "code", "200"}.
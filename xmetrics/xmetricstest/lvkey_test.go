@@ -74,3 +74,18 @@ func TestNewLVKey(t *testing.T) {
 		testNewLVKeySuccess(t, []string{"method", "POST", "event", "notify", "code", "500"}, "code=500,event=notify,method=POST")
 	})
 }
+
+func TestLVKeyMatches(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(rootKey.Matches(rootKey))
+	assert.True(LVKey("code=500,method=POST").Matches(rootKey))
+	assert.True(LVKey("code=500,method=POST").Matches("code=500"))
+	assert.True(LVKey("code=500,method=POST").Matches("method=POST"))
+	assert.True(LVKey("code=500,method=POST").Matches("code=500,method=POST"))
+
+	assert.False(rootKey.Matches("code=500"))
+	assert.False(LVKey("code=500,method=POST").Matches("code=200"))
+	assert.False(LVKey("code=500,method=POST").Matches("code=500,event=notify"))
+	assert.False(LVKey("code=5000").Matches("code=500"))
+}
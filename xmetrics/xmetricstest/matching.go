@@ -0,0 +1,75 @@
+package xmetricstest
+
+import (
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/xmidt-org/webpa-common/xmetrics"
+)
+
+// enumerable is implemented by the root metrics of label trees
+type enumerable interface {
+	each(func(LVKey, interface{}))
+}
+
+// aggregate is implemented by the metrics that combine all the metrics matching a subset of labels
+type aggregate interface {
+	parts() []interface{}
+}
+
+// matchingCounter is the sum of all counters in a label tree that match a subset of labels
+type matchingCounter struct {
+	*generic.Counter
+	matched []interface{}
+}
+
+func (mc *matchingCounter) parts() []interface{} {
+	return mc.matched
+}
+
+// matchingGauge is the sum of all gauges in a label tree that match a subset of labels
+type matchingGauge struct {
+	*generic.Gauge
+	matched []interface{}
+}
+
+func (mg *matchingGauge) parts() []interface{} {
+	return mg.matched
+}
+
+// match returns a metric that sums the values of all the metrics in the given label tree that match the subset.
+// Histograms cannot be summed, so false is returned for them.
+func match(name string, root interface{}, subset LVKey) (interface{}, bool) {
+	var (
+		sum     float64
+		matched []interface{}
+	)
+
+	e, ok := root.(enumerable)
+	if !ok {
+		return nil, false
+	}
+
+	e.each(func(key LVKey, metric interface{}) {
+		if key.Matches(subset) {
+			matched = append(matched, metric)
+			if v, ok := metric.(xmetrics.Valuer); ok {
+				sum += v.Value()
+			}
+		}
+	})
+
+	switch root.(type) {
+	case metrics.Counter:
+		c := generic.NewCounter(name)
+		c.Add(sum)
+		return &matchingCounter{Counter: c, matched: matched}, true
+
+	case metrics.Gauge:
+		g := generic.NewGauge(name)
+		g.Set(sum)
+		return &matchingGauge{Gauge: g, matched: matched}, true
+
+	default:
+		return nil, false
+	}
+}
@@ -65,6 +65,16 @@ func (c *counter) Get(key LVKey) interface{} {
 	return metric
 }
 
+// each invokes the given closure for every metric in this label tree, including the root
+func (c *counter) each(f func(LVKey, interface{})) {
+	defer c.lock.Unlock()
+	c.lock.Lock()
+
+	for key, metric := range c.tree {
+		f(key, metric)
+	}
+}
+
 // nestedCounter is a non-root counter created by With.
 type nestedCounter struct {
 	*generic.Counter
@@ -130,6 +140,16 @@ func (g *gauge) Get(key LVKey) interface{} {
 	return metric
 }
 
+// each invokes the given closure for every metric in this label tree, including the root
+func (g *gauge) each(f func(LVKey, interface{})) {
+	defer g.lock.Unlock()
+	g.lock.Lock()
+
+	for key, metric := range g.tree {
+		f(key, metric)
+	}
+}
+
 // nestedGauge is a non-root gauge created by With.
 type nestedGauge struct {
 	*generic.Gauge
@@ -140,6 +160,37 @@ func (nc *nestedGauge) With(labelsAndValues ...string) metrics.Gauge {
 	return nc.with(labelsAndValues...)
 }
 
+// gaugeFunc is a testing gauge whose value is computed by a function.  Since the function supplies the value,
+// Set and Add have no effect.  A gaugeFunc has no labels, so With returns the same instance.
+type gaugeFunc struct {
+	*generic.Gauge
+	f func() float64
+}
+
+// NewGaugeFunc creates a testing gauge whose value is the result of f
+func NewGaugeFunc(name string, f func() float64) metrics.Gauge {
+	return &gaugeFunc{
+		Gauge: generic.NewGauge(name),
+		f:     f,
+	}
+}
+
+func (gf *gaugeFunc) Value() float64 {
+	return gf.f()
+}
+
+func (gf *gaugeFunc) With(...string) metrics.Gauge {
+	return gf
+}
+
+func (gf *gaugeFunc) Get(LVKey) interface{} {
+	return gf
+}
+
+func (gf *gaugeFunc) each(f func(LVKey, interface{})) {
+	f(rootKey, gf)
+}
+
 // histogram is a testing metric which is the root of a label tree of histograms.
 type histogram struct {
 	*generic.Histogram
@@ -197,6 +248,16 @@ func (h *histogram) Get(key LVKey) interface{} {
 	return metric
 }
 
+// each invokes the given closure for every metric in this label tree, including the root
+func (h *histogram) each(f func(LVKey, interface{})) {
+	defer h.lock.Unlock()
+	h.lock.Lock()
+
+	for key, metric := range h.tree {
+		f(key, metric)
+	}
+}
+
 // nestedHistogram is a non-root gauge created by With.
 type nestedHistogram struct {
 	*generic.Histogram
@@ -88,6 +88,29 @@ func TestNewGauge(t *testing.T) {
 	assert.NotNil(child3)
 }
 
+func TestNewGaugeFunc(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		require = require.New(t)
+
+		value = 1.0
+		g     = NewGaugeFunc("test", func() float64 { return value })
+	)
+
+	require.NotNil(g)
+	require.Implements((*xmetrics.Valuer)(nil), g)
+	require.Implements((*Labeled)(nil), g)
+	assert.Equal(1.0, g.(xmetrics.Valuer).Value())
+
+	value = 12.5
+	assert.Equal(12.5, g.(xmetrics.Valuer).Value())
+
+	g.Set(100.0)
+	assert.Equal(12.5, g.(xmetrics.Valuer).Value())
+	assert.True(g == g.With("code", "500"))
+	assert.True(g == g.(Labeled).Get("code=500"))
+}
+
 func TestNewHistogram(t *testing.T) {
 	var (
 		assert  = assert.New(t)
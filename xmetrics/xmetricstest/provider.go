@@ -3,16 +3,27 @@ package xmetricstest
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/go-kit/kit/metrics"
 	"github.com/go-kit/kit/metrics/provider"
 	"github.com/xmidt-org/webpa-common/xmetrics"
 )
 
+const (
+	// awaitInterval is the time between attempts made by Provider.Await
+	awaitInterval = 10 * time.Millisecond
+
+	// timerBuckets is the number of buckets for histograms created by NewTimer
+	timerBuckets = 50
+)
+
 // Provider is a testing implementation of go-kit's provider.Provider.  Additionally, it provides
 // assertion and expectation functionality.
 type Provider interface {
 	provider.Provider
+	xmetrics.GaugeFuncProvider
+	xmetrics.TimerProvider
 
 	// Expect associates an expectation with a metric.  The optional list of labels and values will
 	// examine any nested metric instead of the root metric.  This method uses a Fluent Builder style:
@@ -28,6 +39,20 @@ type Provider interface {
 	// set of expectations asserted via AssertExpectations.
 	Assert(testingT, string, ...string) func(...expectation) bool
 
+	// Await is like Assert, except that the expectations are retried until they all pass or the timeout elapses.
+	// Only the final attempt reports failures to the testingT.  Use this method to test metrics updated by
+	// background goroutines, rather than sleeping or asserting immediately.
+	Await(testingT, time.Duration, string, ...string) func(...expectation) bool
+
+	// AssertMatching is like Assert, except that the label/value pairs are a subset.  The expectations are executed
+	// against the sum of every metric whose labels include all the given pairs, regardless of any other labels.  Only counters
+	// and gauges can be matched this way.
+	AssertMatching(testingT, string, ...string) func(...expectation) bool
+
+	// Checkpoint takes a snapshot of the current values of all counters and gauges, which can then be used to assert
+	// how much those metrics changed via Checkpoint.Delta.
+	Checkpoint() Checkpoint
+
 	// AssertExpectations verifies all expectations.  It returns true if and only if all
 	// expectations pass or if there were no expectations set.
 	AssertExpectations(testingT) bool
@@ -114,6 +139,17 @@ func (tp *testProvider) NewGauge(name string) metrics.Gauge {
 	return g
 }
 
+func (tp *testProvider) NewGaugeFunc(name string, f func() float64) {
+	defer tp.lock.Unlock()
+	tp.lock.Lock()
+
+	if _, ok := tp.metrics[name]; ok {
+		panic(fmt.Errorf("metric %s already exists and cannot be a gauge function", name))
+	}
+
+	tp.metrics[name] = NewGaugeFunc(name, f)
+}
+
 func (tp *testProvider) NewHistogram(name string, buckets int) metrics.Histogram {
 	defer tp.lock.Unlock()
 	tp.lock.Lock()
@@ -131,6 +167,10 @@ func (tp *testProvider) NewHistogram(name string, buckets int) metrics.Histogram
 	return h
 }
 
+func (tp *testProvider) NewTimer(name string, labelsAndValues ...string) *xmetrics.Timer {
+	return xmetrics.NewTimer(tp.NewHistogram(name, timerBuckets).With(labelsAndValues...))
+}
+
 func (tp *testProvider) Stop() {
 }
 
@@ -181,6 +221,75 @@ func (tp *testProvider) Assert(t testingT, name string, labelsAndValues ...strin
 	}
 }
 
+func (tp *testProvider) Await(t testingT, timeout time.Duration, name string, labelsAndValues ...string) func(...expectation) bool {
+	var (
+		attempt = tp.Assert(discardT{}, name, labelsAndValues...)
+		final   = tp.Assert(t, name, labelsAndValues...)
+	)
+
+	return func(e ...expectation) bool {
+		deadline := time.Now().Add(timeout)
+		for time.Now().Before(deadline) {
+			if attempt(e...) {
+				return true
+			}
+
+			time.Sleep(awaitInterval)
+		}
+
+		return final(e...)
+	}
+}
+
+func (tp *testProvider) AssertMatching(t testingT, name string, labelsAndValues ...string) func(...expectation) bool {
+	subset, err := NewLVKey(labelsAndValues)
+	if err != nil {
+		panic(err)
+	}
+
+	return func(e ...expectation) bool {
+		defer tp.lock.Unlock()
+		tp.lock.Lock()
+
+		root, ok := tp.metrics[name]
+		if !ok {
+			t.Errorf("metric %s does not exist", name)
+			return false
+		}
+
+		metric, ok := match(name, root, subset)
+		if !ok {
+			t.Errorf("metric %s cannot be matched by a subset of labels (i.e. is not a counter or gauge)", name)
+			return false
+		}
+
+		result := true
+		for _, f := range e {
+			result = f(t, name, metric) && result
+		}
+
+		return result
+	}
+}
+
+func (tp *testProvider) Checkpoint() Checkpoint {
+	defer tp.lock.Unlock()
+	tp.lock.Lock()
+
+	cp := make(Checkpoint)
+	for _, root := range tp.metrics {
+		if e, ok := root.(enumerable); ok {
+			e.each(func(_ LVKey, metric interface{}) {
+				if v, ok := metric.(xmetrics.Valuer); ok {
+					cp[metric] = v.Value()
+				}
+			})
+		}
+	}
+
+	return cp
+}
+
 func (tp *testProvider) AssertExpectations(t testingT) bool {
 	defer tp.lock.Unlock()
 	tp.lock.Lock()
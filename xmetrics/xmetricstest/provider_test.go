@@ -2,6 +2,7 @@ package xmetricstest
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -134,6 +135,39 @@ func testProviderNewGauge(t *testing.T) {
 	})
 }
 
+func testProviderNewGaugeFunc(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		require  = require.New(t)
+		testingT = new(mockTestingT)
+
+		queue = []string{"a", "b"}
+		p     = NewProvider(nil, func() []xmetrics.Metric {
+			return []xmetrics.Metric{
+				{Name: "preregistered", Type: "gauge"},
+			}
+		})
+	)
+
+	require.NotNil(p)
+	require.Implements((*xmetrics.GaugeFuncProvider)(nil), p)
+
+	p.NewGaugeFunc("queue_depth", func() float64 { return float64(len(queue)) })
+	assert.True(p.Assert(testingT, "queue_depth")(Gauge, Value(2.0)))
+
+	checkpoint := p.Checkpoint()
+	queue = append(queue, "c")
+	assert.True(p.Assert(testingT, "queue_depth")(Gauge, Value(3.0), checkpoint.Delta(1.0)))
+
+	assert.Panics(func() {
+		p.NewGaugeFunc("preregistered", func() float64 { return 0.0 })
+	})
+
+	assert.Panics(func() {
+		p.NewGaugeFunc("queue_depth", func() float64 { return 0.0 })
+	})
+}
+
 func testProviderNewHistogram(t *testing.T) {
 	var (
 		assert  = assert.New(t)
@@ -169,6 +203,22 @@ func testProviderNewHistogram(t *testing.T) {
 	})
 }
 
+func testProviderNewTimer(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		testingT = new(mockTestingT)
+		p        = NewProvider(nil)
+	)
+
+	assert.Implements((*xmetrics.TimerProvider)(nil), p)
+	timer := p.NewTimer("duration_seconds", "method", "GET")
+	assert.NotNil(timer)
+	timer.Stop()
+
+	assert.True(p.Assert(testingT, "duration_seconds", "method", "GET")(Histogram))
+	testingT.AssertExpectations(t)
+}
+
 func testProviderStop(t *testing.T) {
 	var (
 		assert = assert.New(t)
@@ -423,12 +473,109 @@ func testProviderAssertExpectations(t *testing.T) {
 	})
 }
 
+func testProviderAssertMatching(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		testingT = new(mockTestingT)
+
+		p = NewProvider(nil, func() []xmetrics.Metric {
+			return []xmetrics.Metric{
+				{Name: "counter", Type: "counter"},
+				{Name: "gauge", Type: "gauge"},
+				{Name: "histogram", Type: "histogram"},
+			}
+		})
+	)
+
+	c := p.NewCounter("counter")
+	c.With("code", "200", "method", "GET").Add(1.0)
+	c.With("code", "200", "method", "POST").Add(2.0)
+	c.With("code", "500", "method", "POST").Add(4.0)
+	p.NewGauge("gauge").With("datacenter", "dc1", "outcome", "success").Set(3.0)
+
+	assert.True(p.AssertMatching(testingT, "counter", "code", "200")(Counter, Value(3.0)))
+	assert.True(p.AssertMatching(testingT, "counter", "method", "POST")(Counter, Value(6.0)))
+	assert.True(p.AssertMatching(testingT, "counter")(Counter, Value(7.0)))
+	assert.True(p.AssertMatching(testingT, "counter", "code", "404")(Counter, Value(0.0)))
+	assert.True(p.AssertMatching(testingT, "gauge", "datacenter", "dc1")(Gauge, Value(3.0)))
+
+	assert.Panics(func() { p.AssertMatching(testingT, "counter", "code") })
+
+	testingT.On("Errorf", mock.MatchedBy(AnyMessage), mock.MatchedBy(AnyArguments)).Times(3)
+	assert.False(p.AssertMatching(testingT, "nosuch")(Counter))
+	assert.False(p.AssertMatching(testingT, "histogram")(Histogram))
+	assert.False(p.AssertMatching(testingT, "counter", "code", "200")(Value(1.0)))
+	testingT.AssertExpectations(t)
+}
+
+func testProviderCheckpoint(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		testingT = new(mockTestingT)
+		p        = NewProvider(nil)
+
+		c = p.NewCounter("counter")
+		g = p.NewGauge("gauge")
+	)
+
+	p.NewHistogram("histogram", 2).Observe(1.0)
+	c.With("code", "200").Add(5.0)
+	g.Set(10.0)
+
+	checkpoint := p.Checkpoint()
+	c.With("code", "200").Add(1.0)
+	c.With("code", "500").Add(2.0)
+	g.Set(7.5)
+
+	assert.True(p.Assert(testingT, "counter", "code", "200")(Counter, Value(6.0), checkpoint.Delta(1.0)))
+	assert.True(p.Assert(testingT, "counter", "code", "500")(Counter, checkpoint.Delta(2.0)))
+	assert.True(p.Assert(testingT, "counter", "code", "404")(Counter, checkpoint.Delta(0.0)))
+	assert.True(p.AssertMatching(testingT, "counter")(Counter, checkpoint.Delta(3.0)))
+	assert.True(p.Assert(testingT, "gauge")(Gauge, checkpoint.Delta(-2.5)))
+
+	testingT.On("Errorf", mock.MatchedBy(AnyMessage), mock.MatchedBy(AnyArguments)).Twice()
+	assert.False(p.Assert(testingT, "counter", "code", "200")(checkpoint.Delta(6.0)))
+	assert.False(p.Assert(testingT, "histogram")(checkpoint.Delta(1.0)))
+	testingT.AssertExpectations(t)
+}
+
+func testProviderAwait(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		testingT = new(mockTestingT)
+		p        = NewProvider(nil)
+
+		c    = p.NewCounter("counter")
+		done = make(chan struct{})
+	)
+
+	go func() {
+		defer close(done)
+		for i := 0; i < 3; i++ {
+			time.Sleep(5 * time.Millisecond)
+			c.With("code", "200").Add(1.0)
+		}
+	}()
+
+	assert.True(p.Await(testingT, 5*time.Second, "counter", "code", "200")(Counter, Value(3.0)))
+	<-done
+
+	testingT.On("Errorf", mock.MatchedBy(AnyMessage), mock.MatchedBy(AnyArguments)).Once()
+	assert.False(p.Await(testingT, 50*time.Millisecond, "counter", "code", "200")(Value(4.0)))
+	testingT.AssertExpectations(t)
+}
+
 func TestProvider(t *testing.T) {
 	t.Run("NewCounter", testProviderNewCounter)
 	t.Run("NewGauge", testProviderNewGauge)
+	t.Run("NewGaugeFunc", testProviderNewGaugeFunc)
 	t.Run("NewHistogram", testProviderNewHistogram)
+	t.Run("NewTimer", testProviderNewTimer)
 	t.Run("Stop", testProviderStop)
 	t.Run("Expect", testProviderExpect)
 	t.Run("Assert", testProviderAssert)
 	t.Run("AssertExpectations", testProviderAssertExpectations)
+	t.Run("AssertMatching", testProviderAssertMatching)
+	t.Run("Checkpoint", testProviderCheckpoint)
+	t.Run("Await", testProviderAwait)
 }